@@ -0,0 +1,290 @@
+// Package agent serves the HTTP endpoints of azud agent and azud serve.
+// The agent API deploys, rolls back, and reports status for authenticated
+// callers such as ChatOps bots and dashboards, which then need no SSH keys
+// to the hosts; the webhook server deploys the images a registry announces
+// in signed webhooks.
+package agent
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/lemonity-org/azud/internal/config"
+	"github.com/lemonity-org/azud/internal/deploy"
+)
+
+// Backend performs the operations the API exposes.
+type Backend interface {
+	// Deploy deploys version, or the configured image when it is empty.
+	Deploy(ctx context.Context, version, caller string) error
+	// Rollback deploys a previous version.
+	Rollback(ctx context.Context, version, caller string) error
+	// History returns the most recent deployments, newest first.
+	History(limit int) ([]*deploy.DeploymentRecord, error)
+}
+
+// Caller is an API client, identified by its bearer token.
+type Caller struct {
+	Name string
+	// config.RoleOperator or config.RoleReadOnly
+	Role  string
+	Token string
+}
+
+// Job is a deploy or rollback started through the API.
+type Job struct {
+	ID         string    `json:"id"`
+	Kind       string    `json:"kind"`
+	Version    string    `json:"version,omitempty"`
+	Caller     string    `json:"caller"`
+	Status     string    `json:"status"`
+	Error      string    `json:"error,omitempty"`
+	StartedAt  time.Time `json:"started_at"`
+	FinishedAt time.Time `json:"finished_at,omitzero"`
+}
+
+// Job statuses.
+const (
+	JobRunning   = "running"
+	JobSucceeded = "succeeded"
+	JobFailed    = "failed"
+)
+
+// maxJobs bounds the finished jobs kept in memory.
+const maxJobs = 100
+
+// Server handles the API. Jobs run one at a time.
+type Server struct {
+	service string
+	backend Backend
+	callers []Caller
+	ctx     context.Context
+
+	mu      sync.Mutex
+	jobs    []*Job
+	running *Job
+	nextID  int
+	now     func() time.Time
+	wg      sync.WaitGroup
+}
+
+// NewServer returns a server for service. Jobs run with ctx, so cancelling
+// it stops a running deployment.
+func NewServer(ctx context.Context, service string, backend Backend, callers []Caller) *Server {
+	return &Server{service: service, backend: backend, callers: callers, ctx: ctx, now: time.Now}
+}
+
+// Wait blocks until the running job, if any, has finished.
+func (s *Server) Wait() {
+	s.wg.Wait()
+}
+
+// Handler returns the HTTP handler of the API.
+func (s *Server) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /v1/status", s.authorize(config.RoleReadOnly, s.handleStatus))
+	mux.HandleFunc("GET /v1/history", s.authorize(config.RoleReadOnly, s.handleHistory))
+	mux.HandleFunc("GET /v1/jobs/{id}", s.authorize(config.RoleReadOnly, s.handleJob))
+	mux.HandleFunc("POST /v1/deploy", s.authorize(config.RoleOperator, s.handleDeploy))
+	mux.HandleFunc("POST /v1/rollback", s.authorize(config.RoleOperator, s.handleRollback))
+	mux.HandleFunc("GET /healthz", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusOK, map[string]string{"status": "ok"})
+	})
+	return mux
+}
+
+// authorize runs next for callers whose bearer token grants role.
+func (s *Server) authorize(role string, next func(http.ResponseWriter, *http.Request, Caller)) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		caller, ok := s.authenticate(r)
+		if !ok {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="azud"`)
+			writeError(w, http.StatusUnauthorized, "missing or invalid bearer token")
+			return
+		}
+		if role == config.RoleOperator && caller.Role != config.RoleOperator {
+			writeError(w, http.StatusForbidden, fmt.Sprintf("token %s is %s", caller.Name, caller.Role))
+			return
+		}
+		next(w, r, caller)
+	}
+}
+
+func (s *Server) authenticate(r *http.Request) (Caller, bool) {
+	token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if !ok || token == "" {
+		return Caller{}, false
+	}
+	// Compare against every token so the time taken reveals nothing.
+	var found Caller
+	matched := false
+	for _, caller := range s.callers {
+		if subtle.ConstantTimeCompare([]byte(token), []byte(caller.Token)) == 1 {
+			found, matched = caller, true
+		}
+	}
+	return found, matched
+}
+
+type statusResponse struct {
+	Service    string                   `json:"service"`
+	Running    *Job                     `json:"running,omitempty"`
+	Deployment *deploy.DeploymentRecord `json:"last_deployment,omitempty"`
+}
+
+func (s *Server) handleStatus(w http.ResponseWriter, r *http.Request, _ Caller) {
+	response := statusResponse{Service: s.service}
+	s.mu.Lock()
+	if s.running != nil {
+		job := *s.running
+		response.Running = &job
+	}
+	s.mu.Unlock()
+
+	records, err := s.backend.History(1)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	if len(records) > 0 {
+		response.Deployment = records[0]
+	}
+	writeJSON(w, http.StatusOK, response)
+}
+
+func (s *Server) handleHistory(w http.ResponseWriter, r *http.Request, _ Caller) {
+	limit := 20
+	if value := r.URL.Query().Get("limit"); value != "" {
+		n, err := strconv.Atoi(value)
+		if err != nil || n < 1 {
+			writeError(w, http.StatusBadRequest, "limit must be a positive integer")
+			return
+		}
+		limit = n
+	}
+	records, err := s.backend.History(limit)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	if records == nil {
+		records = []*deploy.DeploymentRecord{}
+	}
+	writeJSON(w, http.StatusOK, records)
+}
+
+func (s *Server) handleJob(w http.ResponseWriter, r *http.Request, _ Caller) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, job := range s.jobs {
+		if job.ID == r.PathValue("id") {
+			writeJSON(w, http.StatusOK, job)
+			return
+		}
+	}
+	writeError(w, http.StatusNotFound, "no such job")
+}
+
+type deployRequest struct {
+	Version string `json:"version"`
+}
+
+func (s *Server) handleDeploy(w http.ResponseWriter, r *http.Request, caller Caller) {
+	var req deployRequest
+	if !readJSON(w, r, &req) {
+		return
+	}
+	s.start(w, caller, "deploy", req.Version, func(ctx context.Context) error {
+		return s.backend.Deploy(ctx, req.Version, caller.Name)
+	})
+}
+
+func (s *Server) handleRollback(w http.ResponseWriter, r *http.Request, caller Caller) {
+	var req deployRequest
+	if !readJSON(w, r, &req) {
+		return
+	}
+	if req.Version == "" {
+		writeError(w, http.StatusBadRequest, "version is required")
+		return
+	}
+	s.start(w, caller, "rollback", req.Version, func(ctx context.Context) error {
+		return s.backend.Rollback(ctx, req.Version, caller.Name)
+	})
+}
+
+// start runs fn as a job in the background and responds with the job, or
+// with a conflict while another job runs.
+func (s *Server) start(w http.ResponseWriter, caller Caller, kind, version string, fn func(context.Context) error) {
+	s.mu.Lock()
+	if s.running != nil {
+		running := *s.running
+		s.mu.Unlock()
+		writeJSON(w, http.StatusConflict, map[string]any{"error": "another job is running", "job": running})
+		return
+	}
+	s.nextID++
+	job := &Job{
+		ID:        fmt.Sprintf("job_%d_%d", s.now().Unix(), s.nextID),
+		Kind:      kind,
+		Version:   version,
+		Caller:    caller.Name,
+		Status:    JobRunning,
+		StartedAt: s.now().UTC(),
+	}
+	s.running = job
+	s.jobs = append(s.jobs, job)
+	if len(s.jobs) > maxJobs {
+		s.jobs = s.jobs[len(s.jobs)-maxJobs:]
+	}
+	snapshot := *job
+	s.mu.Unlock()
+
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		err := fn(s.ctx)
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		job.Status, job.FinishedAt = JobSucceeded, s.now().UTC()
+		if err != nil {
+			job.Status, job.Error = JobFailed, err.Error()
+		}
+		s.running = nil
+	}()
+
+	w.Header().Set("Location", "/v1/jobs/"+job.ID)
+	writeJSON(w, http.StatusAccepted, snapshot)
+}
+
+func readJSON(w http.ResponseWriter, r *http.Request, v any) bool {
+	if r.ContentLength == 0 {
+		return true
+	}
+	decoder := json.NewDecoder(http.MaxBytesReader(w, r.Body, 1<<16))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(v); err != nil && !errors.Is(err, io.EOF) {
+		writeError(w, http.StatusBadRequest, fmt.Sprintf("invalid request body: %v", err))
+		return false
+	}
+	return true
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+func writeError(w http.ResponseWriter, status int, message string) {
+	writeJSON(w, status, map[string]string{"error": message})
+}
@@ -0,0 +1,127 @@
+package agent
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/lemonity-org/azud/internal/config"
+	"github.com/lemonity-org/azud/internal/deploy"
+)
+
+type fakeBackend struct {
+	release  chan struct{}
+	deployed []string
+	err      error
+}
+
+func (b *fakeBackend) Deploy(ctx context.Context, version, caller string) error {
+	<-b.release
+	b.deployed = append(b.deployed, version+" by "+caller)
+	return b.err
+}
+
+func (b *fakeBackend) Rollback(ctx context.Context, version, caller string) error {
+	return b.Deploy(ctx, version, caller)
+}
+
+func (b *fakeBackend) History(limit int) ([]*deploy.DeploymentRecord, error) {
+	return []*deploy.DeploymentRecord{{ID: "deploy_1", Version: "v1", Status: deploy.StatusSuccess}}, nil
+}
+
+func newTestServer(backend Backend) *Server {
+	return NewServer(context.Background(), "app", backend, []Caller{
+		{Name: "chatops", Role: config.RoleOperator, Token: "operator-token"},
+		{Name: "dashboard", Role: config.RoleReadOnly, Token: "viewer-token"},
+	})
+}
+
+func request(t *testing.T, handler http.Handler, method, path, token, body string) *httptest.ResponseRecorder {
+	t.Helper()
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestAuthorization(t *testing.T) {
+	handler := newTestServer(&fakeBackend{}).Handler()
+	tests := []struct {
+		name   string
+		method string
+		path   string
+		token  string
+		want   int
+	}{
+		{"health needs no token", http.MethodGet, "/healthz", "", http.StatusOK},
+		{"missing token", http.MethodGet, "/v1/status", "", http.StatusUnauthorized},
+		{"wrong token", http.MethodGet, "/v1/status", "nope", http.StatusUnauthorized},
+		{"read-only status", http.MethodGet, "/v1/status", "viewer-token", http.StatusOK},
+		{"read-only history", http.MethodGet, "/v1/history?limit=5", "viewer-token", http.StatusOK},
+		{"read-only cannot deploy", http.MethodPost, "/v1/deploy", "viewer-token", http.StatusForbidden},
+		{"read-only cannot roll back", http.MethodPost, "/v1/rollback", "viewer-token", http.StatusForbidden},
+		{"invalid limit", http.MethodGet, "/v1/history?limit=0", "operator-token", http.StatusBadRequest},
+		{"rollback needs a version", http.MethodPost, "/v1/rollback", "operator-token", http.StatusBadRequest},
+		{"unknown job", http.MethodGet, "/v1/jobs/job_1", "operator-token", http.StatusNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if rec := request(t, handler, tt.method, tt.path, tt.token, ""); rec.Code != tt.want {
+				t.Fatalf("%s %s = %d, want %d: %s", tt.method, tt.path, rec.Code, tt.want, rec.Body)
+			}
+		})
+	}
+}
+
+func TestDeployJob(t *testing.T) {
+	backend := &fakeBackend{release: make(chan struct{}), err: errors.New("health check failed")}
+	server := newTestServer(backend)
+	handler := server.Handler()
+
+	rec := request(t, handler, http.MethodPost, "/v1/deploy", "operator-token", `{"version":"v2"}`)
+	if rec.Code != http.StatusAccepted {
+		t.Fatalf("deploy = %d: %s", rec.Code, rec.Body)
+	}
+	var job Job
+	if err := json.Unmarshal(rec.Body.Bytes(), &job); err != nil {
+		t.Fatal(err)
+	}
+	if job.Status != JobRunning || job.Caller != "chatops" || rec.Header().Get("Location") != "/v1/jobs/"+job.ID {
+		t.Fatalf("job = %+v, location %q", job, rec.Header().Get("Location"))
+	}
+
+	if rec := request(t, handler, http.MethodPost, "/v1/rollback", "operator-token", `{"version":"v1"}`); rec.Code != http.StatusConflict {
+		t.Fatalf("second job = %d, want conflict", rec.Code)
+	}
+	if rec := request(t, handler, http.MethodGet, "/v1/status", "viewer-token", ""); !strings.Contains(rec.Body.String(), `"running"`) {
+		t.Fatalf("status while running = %s", rec.Body)
+	}
+
+	close(backend.release)
+	server.Wait()
+
+	rec = request(t, handler, http.MethodGet, "/v1/jobs/"+job.ID, "viewer-token", "")
+	if err := json.Unmarshal(rec.Body.Bytes(), &job); err != nil {
+		t.Fatal(err)
+	}
+	if job.Status != JobFailed || job.Error != "health check failed" || job.FinishedAt.IsZero() {
+		t.Fatalf("finished job = %+v", job)
+	}
+	if len(backend.deployed) != 1 || backend.deployed[0] != "v2 by chatops" {
+		t.Fatalf("deployed = %v", backend.deployed)
+	}
+}
+
+func TestDeployRejectsUnknownFields(t *testing.T) {
+	handler := newTestServer(&fakeBackend{}).Handler()
+	if rec := request(t, handler, http.MethodPost, "/v1/deploy", "operator-token", `{"verison":"v2"}`); rec.Code != http.StatusBadRequest {
+		t.Fatalf("deploy = %d, want bad request", rec.Code)
+	}
+}
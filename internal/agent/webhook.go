@@ -0,0 +1,400 @@
+package agent
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"path"
+	"regexp"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/lemonity-org/azud/internal/config"
+	"github.com/lemonity-org/azud/internal/deploy"
+	"github.com/lemonity-org/azud/internal/output"
+)
+
+// Release is an image tag pushed to a registry.
+type Release struct {
+	Repository string
+	Tag        string
+	// Digest the tag pointed to when it was pushed, if the sender reports it
+	Digest string
+}
+
+// WebhookTarget is a destination that deploys releases of its image.
+type WebhookTarget struct {
+	// Destination name, "" for the base configuration
+	Name string
+	// Image repository the destination deploys, without tag or digest
+	Repository string
+	// Tag patterns (path.Match) that trigger a deploy; empty matches all
+	Tags []string
+	// Key that signs webhooks for this destination
+	Secret []byte
+}
+
+// Matches reports whether release should be deployed to t.
+func (t WebhookTarget) Matches(release Release) bool {
+	if !strings.EqualFold(t.Repository, release.Repository) {
+		return false
+	}
+	if len(t.Tags) == 0 {
+		return true
+	}
+	return slices.ContainsFunc(t.Tags, func(pattern string) bool {
+		matched, _ := path.Match(pattern, release.Tag)
+		return matched
+	})
+}
+
+// DeployFunc deploys release to the named destination.
+type DeployFunc func(ctx context.Context, destination string, release Release) error
+
+// CurrentFunc returns the release last deployed to the named destination,
+// and false when none is known.
+type CurrentFunc func(destination string) (Release, bool)
+
+// maxDeliveries bounds how many delivery IDs are remembered to reject
+// redelivered webhooks.
+const maxDeliveries = 1024
+
+// WebhookServer turns signed registry webhooks into deploys. Deploys run
+// one at a time; while one runs, only the newest release per destination
+// stays queued. Redelivered webhooks, releases already deployed, and
+// releases older than the deployed one are ignored, so a replayed request
+// cannot roll a destination back.
+type WebhookServer struct {
+	targets []WebhookTarget
+	deploy  DeployFunc
+	current CurrentFunc
+	ctx     context.Context
+	log     *output.Logger
+
+	mu         sync.Mutex
+	pending    map[string]Release
+	running    bool
+	deliveries map[string]bool
+	delivered  []string
+	wg         sync.WaitGroup
+}
+
+// NewWebhookServer returns a webhook server for targets. Deploys run with
+// ctx; current reports what each destination runs.
+func NewWebhookServer(ctx context.Context, targets []WebhookTarget, deploy DeployFunc, current CurrentFunc, log *output.Logger) *WebhookServer {
+	return &WebhookServer{
+		targets:    targets,
+		deploy:     deploy,
+		current:    current,
+		ctx:        ctx,
+		log:        log,
+		pending:    make(map[string]Release),
+		deliveries: make(map[string]bool),
+	}
+}
+
+// Wait blocks until queued deploys have finished.
+func (s *WebhookServer) Wait() {
+	s.wg.Wait()
+}
+
+// Handler returns the HTTP handler that receives webhooks.
+func (s *WebhookServer) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /webhook", s.handleWebhook)
+	mux.HandleFunc("GET /healthz", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusOK, map[string]string{"status": "ok"})
+	})
+	return mux
+}
+
+type webhookResponse struct {
+	Status       string   `json:"status"`
+	Reason       string   `json:"reason,omitempty"`
+	Destinations []string `json:"destinations,omitempty"`
+	// Destinations that match but were not queued, with the reason
+	Skipped []string `json:"skipped,omitempty"`
+}
+
+func (s *WebhookServer) handleWebhook(w http.ResponseWriter, r *http.Request) {
+	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, 1<<20))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "failed to read body")
+		return
+	}
+	signed := s.signedTargets(r.Header.Get("X-Hub-Signature-256"), body)
+	if len(signed) == 0 {
+		writeError(w, http.StatusUnauthorized, "missing or invalid X-Hub-Signature-256")
+		return
+	}
+
+	if delivery := r.Header.Get("X-GitHub-Delivery"); delivery != "" && !s.firstDelivery(delivery) {
+		writeJSON(w, http.StatusOK, webhookResponse{Status: "ignored", Reason: fmt.Sprintf("delivery %s was already received", delivery)})
+		return
+	}
+
+	event := r.Header.Get("X-GitHub-Event")
+	if event == "ping" {
+		writeJSON(w, http.StatusOK, webhookResponse{Status: "ok"})
+		return
+	}
+	release, reason, err := parseWebhook(event, body)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if reason != "" {
+		writeJSON(w, http.StatusOK, webhookResponse{Status: "ignored", Reason: reason})
+		return
+	}
+
+	var destinations, skipped []string
+	matched := false
+	for _, target := range signed {
+		if !target.Matches(release) {
+			continue
+		}
+		matched = true
+		if reason := s.staleReason(target.Name, release); reason != "" {
+			skipped = append(skipped, fmt.Sprintf("%s: %s", deploy.DestinationLabel(target.Name), reason))
+			continue
+		}
+		destinations = append(destinations, target.Name)
+	}
+	if !matched {
+		writeJSON(w, http.StatusOK, webhookResponse{Status: "ignored", Reason: fmt.Sprintf("no destination deploys %s:%s", release.Repository, release.Tag)})
+		return
+	}
+	if len(destinations) == 0 {
+		writeJSON(w, http.StatusOK, webhookResponse{Status: "ignored", Reason: "every destination runs this or a newer release", Skipped: skipped})
+		return
+	}
+	for _, name := range destinations {
+		s.enqueue(name, release)
+	}
+	writeJSON(w, http.StatusAccepted, webhookResponse{Status: "queued", Destinations: destinations, Skipped: skipped})
+}
+
+// signedTargets returns the targets whose secret produced signature, a
+// GitHub-style "sha256=<hex HMAC>" of body.
+func (s *WebhookServer) signedTargets(signature string, body []byte) []WebhookTarget {
+	hexMAC, ok := strings.CutPrefix(signature, "sha256=")
+	if !ok {
+		return nil
+	}
+	got, err := hex.DecodeString(hexMAC)
+	if err != nil {
+		return nil
+	}
+	var signed []WebhookTarget
+	for _, target := range s.targets {
+		mac := hmac.New(sha256.New, target.Secret)
+		mac.Write(body)
+		if hmac.Equal(got, mac.Sum(nil)) {
+			signed = append(signed, target)
+		}
+	}
+	return signed
+}
+
+// firstDelivery records a webhook delivery ID and reports whether it is
+// new. Only the most recent maxDeliveries IDs are remembered.
+func (s *WebhookServer) firstDelivery(id string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.deliveries[id] {
+		return false
+	}
+	s.deliveries[id] = true
+	s.delivered = append(s.delivered, id)
+	if len(s.delivered) > maxDeliveries {
+		delete(s.deliveries, s.delivered[0])
+		s.delivered = s.delivered[1:]
+	}
+	return true
+}
+
+// staleReason returns why release must not be deployed to destination, or
+// "" when it may: it is already deployed there (the same tag, and the same
+// digest when both are known), or its version is older than the deployed
+// one. Tags that are not MAJOR.MINOR.PATCH versions are only compared for
+// equality.
+func (s *WebhookServer) staleReason(destination string, release Release) string {
+	if s.current == nil {
+		return ""
+	}
+	current, ok := s.current(destination)
+	if !ok {
+		return ""
+	}
+	if release.Tag == current.Tag && (release.Digest == "" || current.Digest == "" || release.Digest == current.Digest) {
+		return fmt.Sprintf("%s is already deployed", release.Tag)
+	}
+	if cmp, comparable := config.CompareVersions(release.Tag, current.Tag); comparable && cmp < 0 {
+		return fmt.Sprintf("%s is older than the deployed %s", release.Tag, current.Tag)
+	}
+	return ""
+}
+
+func (s *WebhookServer) enqueue(destination string, release Release) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if queued, ok := s.pending[destination]; ok {
+		s.log.Info("%s: %s replaces queued %s", deploy.DestinationLabel(destination), release.Tag, queued.Tag)
+	}
+	s.pending[destination] = release
+	if s.running {
+		return
+	}
+	s.running = true
+	s.wg.Add(1)
+	go s.work()
+}
+
+// work deploys queued releases until the queue is empty.
+func (s *WebhookServer) work() {
+	defer s.wg.Done()
+	for {
+		s.mu.Lock()
+		if len(s.pending) == 0 {
+			s.running = false
+			s.mu.Unlock()
+			return
+		}
+		names := make([]string, 0, len(s.pending))
+		for name := range s.pending {
+			names = append(names, name)
+		}
+		slices.Sort(names)
+		destination := names[0]
+		release := s.pending[destination]
+		delete(s.pending, destination)
+		s.mu.Unlock()
+
+		label := deploy.DestinationLabel(destination)
+		// Another deploy may have shipped this or a newer release since
+		// the webhook was accepted.
+		if reason := s.staleReason(destination, release); reason != "" {
+			s.log.Info("%s: skipping %s: %s", label, release.Tag, reason)
+			continue
+		}
+		s.log.Info("%s: deploying %s:%s", label, release.Repository, release.Tag)
+		if err := s.deploy(s.ctx, destination, release); err != nil {
+			s.log.Error("%s: deploy of %s failed: %v", label, release.Tag, err)
+			continue
+		}
+		s.log.Success("%s: deployed %s", label, release.Tag)
+	}
+}
+
+// githubPackage is the package object of GitHub package and
+// registry_package events.
+type githubPackage struct {
+	Name        string `json:"name"`
+	PackageType string `json:"package_type"`
+	Owner       struct {
+		Login string `json:"login"`
+	} `json:"owner"`
+	PackageVersion struct {
+		PackageURL        string `json:"package_url"`
+		ContainerMetadata struct {
+			Tag struct {
+				Name   string `json:"name"`
+				Digest string `json:"digest"`
+			} `json:"tag"`
+		} `json:"container_metadata"`
+	} `json:"package_version"`
+}
+
+var webhookDigestRegex = regexp.MustCompile(`^sha256:[a-f0-9]{64}$`)
+
+// parseWebhook returns the release a webhook announces. GitHub package and
+// registry_package events are read from their package object, other GitHub
+// events are ignored, and requests from other senders must carry
+// {"image": "<repository>:<tag>"}. A non-empty reason means the event
+// announces nothing to deploy.
+func parseWebhook(event string, body []byte) (Release, string, error) {
+	switch event {
+	case "package", "registry_package":
+		var payload struct {
+			Action          string         `json:"action"`
+			Package         *githubPackage `json:"package"`
+			RegistryPackage *githubPackage `json:"registry_package"`
+		}
+		if err := json.Unmarshal(body, &payload); err != nil {
+			return Release{}, "", fmt.Errorf("invalid %s payload: %w", event, err)
+		}
+		pkg := payload.Package
+		if pkg == nil {
+			pkg = payload.RegistryPackage
+		}
+		switch {
+		case pkg == nil:
+			return Release{}, "", fmt.Errorf("%s payload has no package", event)
+		case payload.Action != "published" && payload.Action != "updated":
+			return Release{}, fmt.Sprintf("action %s", payload.Action), nil
+		case !strings.EqualFold(pkg.PackageType, "container"):
+			return Release{}, fmt.Sprintf("%s is not a container package", pkg.Name), nil
+		}
+		tag := pkg.PackageVersion.ContainerMetadata.Tag
+		if tag.Name == "" {
+			return Release{}, "untagged push", nil
+		}
+		release := Release{Repository: "ghcr.io/" + strings.ToLower(pkg.Owner.Login) + "/" + strings.ToLower(pkg.Name), Tag: tag.Name}
+		if url := pkg.PackageVersion.PackageURL; url != "" {
+			repository, _, digest, err := parseImageReference(url)
+			if err != nil {
+				return Release{}, "", err
+			}
+			release.Repository, release.Digest = repository, digest
+		}
+		if tag.Digest != "" {
+			if !webhookDigestRegex.MatchString(tag.Digest) {
+				return Release{}, "", fmt.Errorf("invalid digest %q", tag.Digest)
+			}
+			release.Digest = tag.Digest
+		}
+		return release, "", nil
+	case "":
+		var payload struct {
+			Image string `json:"image"`
+		}
+		if err := json.Unmarshal(body, &payload); err != nil || payload.Image == "" {
+			return Release{}, "", fmt.Errorf(`expected a GitHub package event or {"image": "<repository>:<tag>"}`)
+		}
+		repository, tag, digest, err := parseImageReference(payload.Image)
+		if err != nil {
+			return Release{}, "", err
+		}
+		if tag == "" {
+			return Release{}, "", fmt.Errorf("image %s has no tag", payload.Image)
+		}
+		return Release{Repository: repository, Tag: tag, Digest: digest}, "", nil
+	default:
+		return Release{}, fmt.Sprintf("event %s", event), nil
+	}
+}
+
+// parseImageReference splits an image reference such as
+// "registry:5000/app:v1@sha256:<hex>" into its repository, tag, and digest,
+// keeping a registry port in the repository. The tag and digest are empty
+// when absent; a digest that is not sha256 is an error.
+func parseImageReference(image string) (repository, tag, digest string, err error) {
+	repository, digest, _ = strings.Cut(image, "@")
+	if digest != "" && !webhookDigestRegex.MatchString(digest) {
+		return "", "", "", fmt.Errorf("image %s has an invalid digest", image)
+	}
+	if colon := strings.LastIndex(repository, ":"); colon > strings.LastIndex(repository, "/") {
+		repository, tag = repository[:colon], repository[colon+1:]
+	}
+	if repository == "" {
+		return "", "", "", fmt.Errorf("image %s has no repository", image)
+	}
+	return repository, tag, digest, nil
+}
@@ -0,0 +1,218 @@
+package agent
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/lemonity-org/azud/internal/output"
+)
+
+const packagePayload = `{
+  "action": "published",
+  "package": {
+    "name": "App",
+    "package_type": "CONTAINER",
+    "owner": {"login": "Lemonity"},
+    "package_version": {
+      "package_url": "ghcr.io/lemonity/app:v1.2.3",
+      "container_metadata": {"tag": {"name": "v1.2.3", "digest": "sha256:` + digestHex + `"}}
+    }
+  }
+}`
+
+const digestHex = "0123456789abcdef0123456789abcdef0123456789abcdef0123456789abcdef"
+
+func sign(secret, body string) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(body))
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+func TestParseWebhook(t *testing.T) {
+	tests := []struct {
+		name       string
+		event      string
+		body       string
+		want       Release
+		wantReason string
+		wantErr    bool
+	}{
+		{name: "package published", event: "package", body: packagePayload,
+			want: Release{Repository: "ghcr.io/lemonity/app", Tag: "v1.2.3", Digest: "sha256:" + digestHex}},
+		{name: "registry package without url", event: "registry_package",
+			body: `{"action":"published","registry_package":{"name":"App","package_type":"CONTAINER","owner":{"login":"Org"},"package_version":{"container_metadata":{"tag":{"name":"main"}}}}}`,
+			want: Release{Repository: "ghcr.io/org/app", Tag: "main"}},
+		{name: "untagged push", event: "package",
+			body:       `{"action":"published","package":{"name":"app","package_type":"container","package_version":{"package_url":"ghcr.io/org/app@sha256:` + digestHex + `"}}}`,
+			wantReason: "untagged push"},
+		{name: "npm package", event: "package",
+			body: `{"action":"published","package":{"name":"lib","package_type":"npm"}}`, wantReason: "lib is not a container package"},
+		{name: "other github event", event: "push", body: `{}`, wantReason: "event push"},
+		{name: "generic", body: `{"image":"registry.example.com:5000/app:build-42"}`,
+			want: Release{Repository: "registry.example.com:5000/app", Tag: "build-42"}},
+		{name: "generic with digest", body: `{"image":"registry.example.com:5000/app:build-42@sha256:` + digestHex + `"}`,
+			want: Release{Repository: "registry.example.com:5000/app", Tag: "build-42", Digest: "sha256:" + digestHex}},
+		{name: "generic without tag", body: `{"image":"registry.example.com:5000/app"}`, wantErr: true},
+		{name: "generic digest without tag", body: `{"image":"registry.example.com:5000/app@sha256:` + digestHex + `"}`, wantErr: true},
+		{name: "generic invalid digest", body: `{"image":"ghcr.io/org/app:v1@md5:abc"}`, wantErr: true},
+		{name: "package url with digest", event: "package",
+			body: `{"action":"published","package":{"name":"app","package_type":"container","package_version":{"package_url":"ghcr.io/org/app:v2@sha256:` + digestHex + `","container_metadata":{"tag":{"name":"v2"}}}}}`,
+			want: Release{Repository: "ghcr.io/org/app", Tag: "v2", Digest: "sha256:" + digestHex}},
+		{name: "generic without image", body: `{}`, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, reason, err := parseWebhook(tt.event, []byte(tt.body))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseWebhook() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want || reason != tt.wantReason {
+				t.Fatalf("parseWebhook() = %+v, %q; want %+v, %q", got, reason, tt.want, tt.wantReason)
+			}
+		})
+	}
+}
+
+func TestWebhookTargetMatches(t *testing.T) {
+	target := WebhookTarget{Repository: "ghcr.io/lemonity/app", Tags: []string{"v*"}}
+	tests := []struct {
+		release Release
+		want    bool
+	}{
+		{Release{Repository: "ghcr.io/Lemonity/App", Tag: "v1.2.3"}, true},
+		{Release{Repository: "ghcr.io/lemonity/app", Tag: "main"}, false},
+		{Release{Repository: "ghcr.io/lemonity/other", Tag: "v1.2.3"}, false},
+	}
+	for _, tt := range tests {
+		if got := target.Matches(tt.release); got != tt.want {
+			t.Errorf("Matches(%+v) = %v, want %v", tt.release, got, tt.want)
+		}
+	}
+}
+
+func TestWebhookDeploysSignedReleases(t *testing.T) {
+	var mu sync.Mutex
+	var deployed []string
+	deploy := func(ctx context.Context, destination string, release Release) error {
+		mu.Lock()
+		defer mu.Unlock()
+		deployed = append(deployed, destination+"="+release.Tag)
+		return nil
+	}
+	server := NewWebhookServer(context.Background(), []WebhookTarget{
+		{Name: "production", Repository: "ghcr.io/lemonity/app", Tags: []string{"v*"}, Secret: []byte("prod-secret")},
+		{Name: "staging", Repository: "ghcr.io/lemonity/app", Secret: []byte("staging-secret")},
+	}, deploy, nil, output.DefaultLogger)
+	handler := server.Handler()
+
+	post := func(signature, event, body string) int {
+		req := httptest.NewRequest(http.MethodPost, "/webhook", strings.NewReader(body))
+		req.Header.Set("X-Hub-Signature-256", signature)
+		req.Header.Set("X-GitHub-Event", event)
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		return rec.Code
+	}
+
+	if code := post("", "package", packagePayload); code != http.StatusUnauthorized {
+		t.Fatalf("unsigned = %d, want 401", code)
+	}
+	if code := post(sign("wrong", packagePayload), "package", packagePayload); code != http.StatusUnauthorized {
+		t.Fatalf("wrongly signed = %d, want 401", code)
+	}
+	if code := post(sign("staging-secret", `{}`), "ping", `{}`); code != http.StatusOK {
+		t.Fatalf("ping = %d, want 200", code)
+	}
+	// Signed with the production secret, so only production deploys.
+	if code := post(sign("prod-secret", packagePayload), "package", packagePayload); code != http.StatusAccepted {
+		t.Fatalf("signed = %d, want 202", code)
+	}
+	server.Wait()
+
+	mu.Lock()
+	defer mu.Unlock()
+	if len(deployed) != 1 || deployed[0] != "production=v1.2.3" {
+		t.Fatalf("deployed = %v", deployed)
+	}
+}
+
+func TestWebhookIgnoresRedeliveredAndStaleReleases(t *testing.T) {
+	var mu sync.Mutex
+	var deployed []string
+	deploy := func(ctx context.Context, destination string, release Release) error {
+		mu.Lock()
+		defer mu.Unlock()
+		deployed = append(deployed, release.Tag)
+		return nil
+	}
+	current := func(destination string) (Release, bool) {
+		return Release{Repository: "ghcr.io/lemonity/app", Tag: "v1.2.3", Digest: "sha256:" + digestHex}, true
+	}
+	server := NewWebhookServer(context.Background(), []WebhookTarget{
+		{Name: "production", Repository: "ghcr.io/lemonity/app", Secret: []byte("secret")},
+	}, deploy, current, output.DefaultLogger)
+	handler := server.Handler()
+
+	post := func(delivery, image string) (int, string) {
+		body := `{"image":"` + image + `"}`
+		req := httptest.NewRequest(http.MethodPost, "/webhook", strings.NewReader(body))
+		req.Header.Set("X-Hub-Signature-256", sign("secret", body))
+		if delivery != "" {
+			req.Header.Set("X-GitHub-Delivery", delivery)
+		}
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		return rec.Code, rec.Body.String()
+	}
+
+	tests := []struct {
+		name     string
+		delivery string
+		image    string
+		wantCode int
+		wantBody string
+	}{
+		{"already deployed", "", "ghcr.io/lemonity/app:v1.2.3", http.StatusOK, "v1.2.3 is already deployed"},
+		{"same digest", "", "ghcr.io/lemonity/app:v1.2.3@sha256:" + digestHex, http.StatusOK, "already deployed"},
+		{"older version", "", "ghcr.io/lemonity/app:v1.2.0", http.StatusOK, "older than the deployed v1.2.3"},
+		{"same tag with a new digest", "", "ghcr.io/lemonity/app:v1.2.3@sha256:" + strings.Repeat("f", 64), http.StatusAccepted, "queued"},
+		{"newer version", "delivery-1", "ghcr.io/lemonity/app:v1.3.0", http.StatusAccepted, "queued"},
+		{"redelivered", "delivery-1", "ghcr.io/lemonity/app:v1.4.0", http.StatusOK, "delivery-1 was already received"},
+	}
+	for _, tt := range tests {
+		code, body := post(tt.delivery, tt.image)
+		if code != tt.wantCode || !strings.Contains(body, tt.wantBody) {
+			t.Errorf("%s: got %d %s, want %d containing %q", tt.name, code, body, tt.wantCode, tt.wantBody)
+		}
+		server.Wait()
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if strings.Join(deployed, ",") != "v1.2.3,v1.3.0" {
+		t.Fatalf("deployed = %v", deployed)
+	}
+}
+
+func TestFirstDeliveryForgetsOldestIDs(t *testing.T) {
+	server := NewWebhookServer(context.Background(), nil, nil, nil, output.DefaultLogger)
+	for i := range maxDeliveries + 1 {
+		if !server.firstDelivery(fmt.Sprintf("id-%d", i)) {
+			t.Fatalf("delivery %d reported as repeated", i)
+		}
+	}
+	if server.firstDelivery(fmt.Sprintf("id-%d", maxDeliveries)) {
+		t.Fatal("recent delivery not remembered")
+	}
+	if !server.firstDelivery("id-0") {
+		t.Fatal("oldest delivery still remembered past the limit")
+	}
+}
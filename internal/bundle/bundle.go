@@ -0,0 +1,258 @@
+// Package bundle reads and writes offline deployment bundles: gzipped
+// tarballs holding an image archive, a configuration snapshot, the names of
+// the secrets the deployment needs, and the hooks, so one machine can build
+// a release and another can deploy it without the source checkout or the
+// registry.
+package bundle
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+)
+
+// FormatVersion is the bundle layout this package writes and reads.
+const FormatVersion = 1
+
+// Layout of a bundle.
+const (
+	ManifestFile = "manifest.json"
+	ImageFile    = "image.tar"
+	ConfigDir    = "config"
+	HooksDir     = "hooks"
+)
+
+// Manifest describes a bundle. It is written last, after the checksums of
+// the other files are known.
+type Manifest struct {
+	Format      int       `json:"format"`
+	Service     string    `json:"service"`
+	Image       string    `json:"image"`
+	Version     string    `json:"version"`
+	Destination string    `json:"destination,omitempty"`
+	CreatedAt   time.Time `json:"created_at"`
+	CreatedBy   string    `json:"created_by"`
+
+	// Configuration file the deployment loads, relative to the bundle
+	Config string `json:"config"`
+
+	// Names of the secrets the deployment needs; values are never bundled
+	Secrets []string `json:"secrets"`
+
+	// SHA-256 of every other file, by slash-separated path
+	Files map[string]string `json:"files"`
+}
+
+// Writer writes a bundle.
+type Writer struct {
+	path  string
+	file  *os.File
+	gzip  *gzip.Writer
+	tar   *tar.Writer
+	files map[string]string
+}
+
+// Create starts a bundle at path.
+func Create(path string) (*Writer, error) {
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0600)
+	if err != nil {
+		return nil, err
+	}
+	gz := gzip.NewWriter(file)
+	return &Writer{path: path, file: file, gzip: gz, tar: tar.NewWriter(gz), files: make(map[string]string)}, nil
+}
+
+// AddFile adds the local file src to the bundle as name, keeping its
+// permission bits.
+func (w *Writer) AddFile(name, src string) error {
+	f, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = f.Close() }()
+	info, err := f.Stat()
+	if err != nil {
+		return err
+	}
+	if !info.Mode().IsRegular() {
+		return fmt.Errorf("%s is not a regular file", src)
+	}
+	if err := w.tar.WriteHeader(&tar.Header{
+		Name:    name,
+		Mode:    int64(info.Mode().Perm()),
+		Size:    info.Size(),
+		ModTime: info.ModTime(),
+	}); err != nil {
+		return err
+	}
+	hash := sha256.New()
+	if _, err := io.Copy(w.tar, io.TeeReader(f, hash)); err != nil {
+		return fmt.Errorf("failed to add %s: %w", src, err)
+	}
+	w.files[name] = hex.EncodeToString(hash.Sum(nil))
+	return nil
+}
+
+// Close writes manifest, with the checksums of the added files, and
+// finishes the bundle. The bundle is removed if it cannot be finished.
+func (w *Writer) Close(manifest *Manifest) error {
+	manifest.Format = FormatVersion
+	manifest.Files = w.files
+	data, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return err
+	}
+	err = w.tar.WriteHeader(&tar.Header{Name: ManifestFile, Mode: 0644, Size: int64(len(data)), ModTime: manifest.CreatedAt})
+	if err == nil {
+		_, err = w.tar.Write(data)
+	}
+	for _, closer := range []io.Closer{w.tar, w.gzip, w.file} {
+		if closeErr := closer.Close(); err == nil {
+			err = closeErr
+		}
+	}
+	if err != nil {
+		_ = os.Remove(w.path)
+	}
+	return err
+}
+
+// Abort discards an unfinished bundle.
+func (w *Writer) Abort() {
+	_ = w.file.Close()
+	_ = os.Remove(w.path)
+}
+
+// Extract unpacks the bundle at path into dir and verifies it against its
+// manifest: every listed file must be present with its checksum, and no
+// other file may be.
+func Extract(path, dir string) (*Manifest, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = f.Close() }()
+	gz, err := gzip.NewReader(f)
+	if err != nil {
+		return nil, fmt.Errorf("not a bundle: %w", err)
+	}
+	defer func() { _ = gz.Close() }()
+
+	sums := make(map[string]string)
+	var manifestData []byte
+	reader := tar.NewReader(gz)
+	for {
+		header, err := reader.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read bundle: %w", err)
+		}
+		name, err := entryName(header)
+		if err != nil {
+			return nil, err
+		}
+		if name == ManifestFile {
+			if manifestData, err = io.ReadAll(io.LimitReader(reader, 1<<20)); err != nil {
+				return nil, err
+			}
+			continue
+		}
+		if sums[name], err = extractFile(reader, filepath.Join(dir, filepath.FromSlash(name)), header.FileInfo().Mode().Perm()); err != nil {
+			return nil, fmt.Errorf("failed to extract %s: %w", name, err)
+		}
+	}
+
+	if manifestData == nil {
+		return nil, fmt.Errorf("bundle has no %s", ManifestFile)
+	}
+	var manifest Manifest
+	if err := json.Unmarshal(manifestData, &manifest); err != nil {
+		return nil, fmt.Errorf("invalid %s: %w", ManifestFile, err)
+	}
+	if manifest.Format != FormatVersion {
+		return nil, fmt.Errorf("unsupported bundle format %d (this azud reads format %d)", manifest.Format, FormatVersion)
+	}
+	if err := manifest.verify(sums); err != nil {
+		return nil, err
+	}
+	return &manifest, nil
+}
+
+// verify checks the extracted files, with their checksums by name, against
+// the manifest.
+func (m *Manifest) verify(sums map[string]string) error {
+	for name, want := range m.Files {
+		got, ok := sums[name]
+		if !ok {
+			return fmt.Errorf("bundle is missing %s", name)
+		}
+		if got != want {
+			return fmt.Errorf("checksum mismatch for %s: the bundle is damaged", name)
+		}
+	}
+	for name := range sums {
+		if _, ok := m.Files[name]; !ok {
+			return fmt.Errorf("bundle contains %s, which its manifest does not list", name)
+		}
+	}
+	if _, ok := m.Files[ImageFile]; !ok {
+		return fmt.Errorf("bundle has no image archive")
+	}
+	if _, ok := m.Files[m.Config]; !ok || !strings.HasPrefix(m.Config, ConfigDir+"/") {
+		return fmt.Errorf("bundle manifest names no configuration file")
+	}
+	return nil
+}
+
+// Hooks returns the hook file names in the bundle, sorted.
+func (m *Manifest) Hooks() []string {
+	var hooks []string
+	for name := range m.Files {
+		if hook, ok := strings.CutPrefix(name, HooksDir+"/"); ok {
+			hooks = append(hooks, hook)
+		}
+	}
+	slices.Sort(hooks)
+	return hooks
+}
+
+// entryName returns the cleaned name of a bundle entry, rejecting anything
+// but regular files inside the bundle.
+func entryName(header *tar.Header) (string, error) {
+	if header.Typeflag != tar.TypeReg {
+		return "", fmt.Errorf("bundle entry %s is not a regular file", header.Name)
+	}
+	name := path.Clean(header.Name)
+	if path.IsAbs(name) || name == ".." || strings.HasPrefix(name, "../") || strings.Contains(name, `\`) {
+		return "", fmt.Errorf("bundle entry %s points outside the bundle", header.Name)
+	}
+	return name, nil
+}
+
+func extractFile(r io.Reader, dest string, perm os.FileMode) (string, error) {
+	if err := os.MkdirAll(filepath.Dir(dest), 0700); err != nil {
+		return "", err
+	}
+	f, err := os.OpenFile(dest, os.O_CREATE|os.O_EXCL|os.O_WRONLY, perm|0600)
+	if err != nil {
+		return "", err
+	}
+	hash := sha256.New()
+	_, err = io.Copy(f, io.TeeReader(r, hash))
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	return hex.EncodeToString(hash.Sum(nil)), err
+}
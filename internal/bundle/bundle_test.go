@@ -0,0 +1,143 @@
+package bundle
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func writeBundle(t *testing.T, files map[string]string) string {
+	t.Helper()
+	src := t.TempDir()
+	path := filepath.Join(t.TempDir(), "bundle.tar.gz")
+	w, err := Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for name, content := range files {
+		local := filepath.Join(src, strings.ReplaceAll(name, "/", "_"))
+		if err := os.WriteFile(local, []byte(content), 0750); err != nil {
+			t.Fatal(err)
+		}
+		if err := w.AddFile(name, local); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := w.Close(&Manifest{
+		Service:   "app",
+		Image:     "registry.example.com/app:abc123",
+		Version:   "abc123",
+		CreatedAt: time.Now().UTC(),
+		CreatedBy: "builder",
+		Config:    "config/deploy.yml",
+		Secrets:   []string{"DB_PASSWORD"},
+	}); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestExtractRoundTrip(t *testing.T) {
+	path := writeBundle(t, map[string]string{
+		ImageFile:           "image",
+		"config/deploy.yml": "service: app\n",
+		"hooks/pre-deploy":  "#!/bin/sh\n",
+	})
+
+	dir := t.TempDir()
+	manifest, err := Extract(path, dir)
+	if err != nil {
+		t.Fatalf("Extract() error = %v", err)
+	}
+	if manifest.Version != "abc123" || manifest.Format != FormatVersion {
+		t.Fatalf("manifest = %+v", manifest)
+	}
+	if got := manifest.Hooks(); !reflect.DeepEqual(got, []string{"pre-deploy"}) {
+		t.Fatalf("Hooks() = %v", got)
+	}
+	data, err := os.ReadFile(filepath.Join(dir, "config", "deploy.yml"))
+	if err != nil || string(data) != "service: app\n" {
+		t.Fatalf("config = %q, %v", data, err)
+	}
+	info, err := os.Stat(filepath.Join(dir, "hooks", "pre-deploy"))
+	if err != nil || info.Mode().Perm()&0100 == 0 {
+		t.Fatalf("hook mode = %v, %v", info.Mode(), err)
+	}
+}
+
+func TestExtractRejectsInvalidBundles(t *testing.T) {
+	tests := []struct {
+		name  string
+		files map[string]string
+		want  string
+	}{
+		{"no image", map[string]string{"config/deploy.yml": "service: app\n"}, "no image archive"},
+		{"no config", map[string]string{ImageFile: "image"}, "no configuration file"},
+		{"escaping entry", map[string]string{ImageFile: "image", "config/deploy.yml": "x", "../evil": "x"}, "outside the bundle"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Extract(writeBundle(t, tt.files), t.TempDir())
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Fatalf("Extract() error = %v, want %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestExtractDetectsTampering(t *testing.T) {
+	path := writeBundle(t, map[string]string{ImageFile: "image", "config/deploy.yml": "service: app\n"})
+
+	// Rewrite the bundle with different config contents but the original
+	// manifest.
+	in, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	gz, err := gzip.NewReader(in)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tampered := filepath.Join(t.TempDir(), "tampered.tar.gz")
+	out, err := os.Create(tampered)
+	if err != nil {
+		t.Fatal(err)
+	}
+	gzOut := gzip.NewWriter(out)
+	tw := tar.NewWriter(gzOut)
+	tr := tar.NewReader(gz)
+	for {
+		header, err := tr.Next()
+		if err != nil {
+			break
+		}
+		data, err := io.ReadAll(tr)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if header.Name == "config/deploy.yml" {
+			data = []byte("service: evil\n")
+			header.Size = int64(len(data))
+		}
+		if err := tw.WriteHeader(header); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := tw.Write(data); err != nil {
+			t.Fatal(err)
+		}
+	}
+	_ = tw.Close()
+	_ = gzOut.Close()
+	_ = out.Close()
+	_ = in.Close()
+
+	if _, err := Extract(tampered, t.TempDir()); err == nil || !strings.Contains(err.Error(), "checksum mismatch") {
+		t.Fatalf("Extract() error = %v, want checksum mismatch", err)
+	}
+}
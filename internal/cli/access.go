@@ -0,0 +1,133 @@
+package cli
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+
+	"github.com/spf13/cobra"
+
+	"github.com/lemonity-org/azud/internal/config"
+	"github.com/lemonity-org/azud/internal/deploy"
+)
+
+// readOnlyEnv makes every invocation read-only when set to a true value, so
+// CI jobs and on-call shells can be restricted without changing the
+// configuration.
+const readOnlyEnv = "AZUD_READ_ONLY"
+
+var readOnly bool
+
+// readOnlyRule describes a command a read-only operator may run.
+type readOnlyRule struct {
+	// Flag that must be set for the command to change nothing
+	Requires string
+	// Flag that makes the command change something
+	Forbids string
+}
+
+// readOnlyCommands lists, by command path, the commands that change nothing
+// on the hosts, in the registry, or in deployment history. Every other
+// command is refused in read-only mode.
+var readOnlyCommands = map[string]readOnlyRule{
+	"azud accessory logs":    {},
+	"azud app details":       {},
+	"azud app inspect":       {},
+	"azud app logs":          {},
+	"azud app ps":            {},
+	"azud app stats":         {},
+	"azud bundle export":     {},
+	"azud canary status":     {},
+	"azud config":            {},
+	"azud config validate":   {},
+	"azud cron list":         {},
+	"azud cron logs":         {},
+	"azud destinations diff": {},
+	"azud destinations list": {},
+	"azud env diff":          {},
+	"azud env list":          {},
+	"azud events":            {},
+	"azud freeze status":     {},
+	"azud history list":      {},
+	"azud history show":      {},
+	"azud history timing":    {},
+	"azud hooks list":        {},
+	"azud init":              {},
+	"azud placement":         {Forbids: "apply"},
+	"azud ports":             {},
+	"azud preflight":         {},
+	"azud proxy dns":         {Requires: "check"},
+	"azud proxy logs":        {},
+	"azud proxy reconcile":   {Requires: "check"},
+	"azud proxy status":      {},
+	"azud scale status":      {},
+	"azud ssh trust":         {},
+	"azud traffic status":    {},
+	"azud trust":             {},
+	"azud upgrade":           {},
+	"azud version":           {},
+	"azud volume inspect":    {},
+	"azud volume list":       {},
+}
+
+func init() {
+	rootCmd.PersistentFlags().BoolVar(&readOnly, "read-only", false, "Refuse commands that change anything (default: $"+readOnlyEnv+")")
+}
+
+// readOnlyReason returns why the invocation is read-only, or "" if it is
+// not. loaded may be nil before the configuration is loaded.
+func readOnlyReason(loaded *config.Config) string {
+	if readOnly {
+		return "--read-only"
+	}
+	if value := os.Getenv(readOnlyEnv); value != "" {
+		if enabled, err := strconv.ParseBool(value); err != nil || enabled {
+			return "$" + readOnlyEnv
+		}
+	}
+	if loaded == nil {
+		return ""
+	}
+	user := deploy.CurrentUser()
+	if loaded.Team.RoleOf(user) != config.RoleReadOnly {
+		return ""
+	}
+	if _, listed := loaded.Team.Roles[user]; listed {
+		return fmt.Sprintf("team.roles.%s is %s", user, config.RoleReadOnly)
+	}
+	return fmt.Sprintf("team.default_role is %s", config.RoleReadOnly)
+}
+
+// checkAccess refuses cmd when the invocation is read-only and cmd could
+// change something.
+func checkAccess(cmd *cobra.Command, loaded *config.Config) error {
+	reason := readOnlyReason(loaded)
+	if reason == "" || !cmd.Runnable() || cmd.Annotations[aliasAnnotation] != "" {
+		return nil
+	}
+	switch cmd.Name() {
+	case "help", cobra.ShellCompRequestCmd, cobra.ShellCompNoDescRequestCmd:
+		return nil
+	}
+	if cmd.Parent() != nil && cmd.Parent().Name() == "completion" {
+		return nil
+	}
+
+	rule, ok := readOnlyCommands[cmd.CommandPath()]
+	switch {
+	case !ok:
+		return fmt.Errorf("%s is not allowed in read-only mode (%s)", cmd.CommandPath(), reason)
+	case rule.Requires != "" && !flagEnabled(cmd, rule.Requires):
+		return fmt.Errorf("%s is only allowed with --%s in read-only mode (%s)", cmd.CommandPath(), rule.Requires, reason)
+	case rule.Forbids != "" && flagEnabled(cmd, rule.Forbids):
+		return fmt.Errorf("%s --%s is not allowed in read-only mode (%s)", cmd.CommandPath(), rule.Forbids, reason)
+	}
+	return nil
+}
+
+// flagEnabled reports whether the flag name was given a value other than
+// false.
+func flagEnabled(cmd *cobra.Command, name string) bool {
+	flag := cmd.Flags().Lookup(name)
+	return flag != nil && flag.Changed && flag.Value.String() != "false"
+}
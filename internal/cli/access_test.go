@@ -0,0 +1,86 @@
+package cli
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/lemonity-org/azud/internal/config"
+)
+
+func TestReadOnlyCommandsExist(t *testing.T) {
+	for path, rule := range readOnlyCommands {
+		cmd, _, err := rootCmd.Find(strings.Fields(path)[1:])
+		if err != nil || cmd.CommandPath() != path {
+			t.Errorf("read-only command %q does not exist", path)
+			continue
+		}
+		for _, flag := range []string{rule.Requires, rule.Forbids} {
+			if flag != "" && cmd.Flags().Lookup(flag) == nil {
+				t.Errorf("%s has no --%s flag", path, flag)
+			}
+		}
+	}
+}
+
+func TestCheckAccess(t *testing.T) {
+	t.Setenv(readOnlyEnv, "")
+	t.Setenv("USER", "oncall")
+
+	tests := []struct {
+		name     string
+		args     []string
+		flag     bool
+		env      string
+		team     config.TeamConfig
+		wantDeny string
+	}{
+		{name: "operator deploys", args: []string{"deploy"}},
+		{name: "flag refuses deploy", args: []string{"deploy"}, flag: true, wantDeny: "--read-only"},
+		{name: "env refuses env push", args: []string{"env", "push"}, env: "1", wantDeny: "$AZUD_READ_ONLY"},
+		{name: "env false allows", args: []string{"accessory", "remove"}, env: "false"},
+		{name: "read-only logs", args: []string{"app", "logs"}, flag: true},
+		{name: "read-only history", args: []string{"history", "list"}, env: "true"},
+		{name: "team role refuses accessory remove", args: []string{"accessory", "remove"},
+			team: config.TeamConfig{Roles: map[string]string{"oncall": config.RoleReadOnly}}, wantDeny: "team.roles.oncall"},
+		{name: "team default refuses rollback", args: []string{"rollback"},
+			team: config.TeamConfig{DefaultRole: config.RoleReadOnly}, wantDeny: "team.default_role"},
+		{name: "listed operator overrides default", args: []string{"deploy"},
+			team: config.TeamConfig{DefaultRole: config.RoleReadOnly, Roles: map[string]string{"oncall": config.RoleOperator}}},
+		{name: "dns check allowed", args: []string{"proxy", "dns", "--check"}, flag: true},
+		{name: "dns without check refused", args: []string{"proxy", "dns"}, flag: true, wantDeny: "only allowed with --check"},
+		{name: "placement apply refused", args: []string{"placement", "--apply"}, flag: true, wantDeny: "--apply"},
+		{name: "placement suggestion allowed", args: []string{"placement"}, flag: true},
+		{name: "env diff allowed", args: []string{"env", "diff"}, flag: true},
+		{name: "reconcile check allowed", args: []string{"proxy", "reconcile", "--check"}, flag: true},
+		{name: "reconcile repair refused", args: []string{"proxy", "reconcile", "--repair"}, flag: true, wantDeny: "only allowed with --check"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv(readOnlyEnv, tt.env)
+			prev := readOnly
+			readOnly = tt.flag
+			defer func() { readOnly = prev }()
+
+			cmd, args, err := rootCmd.Find(tt.args)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, flag := range []string{"check", "apply", "repair"} {
+				if f := cmd.Flags().Lookup(flag); f != nil {
+					defer func(value string) { _ = f.Value.Set(value); f.Changed = false }(f.Value.String())
+				}
+			}
+			if err := cmd.ParseFlags(args); err != nil {
+				t.Fatal(err)
+			}
+
+			err = checkAccess(cmd, &config.Config{Team: tt.team})
+			switch {
+			case tt.wantDeny == "" && err != nil:
+				t.Fatalf("checkAccess() error = %v, want allowed", err)
+			case tt.wantDeny != "" && (err == nil || !strings.Contains(err.Error(), tt.wantDeny)):
+				t.Fatalf("checkAccess() error = %v, want %q", err, tt.wantDeny)
+			}
+		})
+	}
+}
@@ -1,11 +1,16 @@
 package cli
 
 import (
+	"errors"
 	"reflect"
+	"regexp"
 	"strings"
+	"sync"
 	"testing"
+	"time"
 
 	"github.com/lemonity-org/azud/internal/config"
+	"github.com/lemonity-org/azud/internal/podman"
 )
 
 func TestSelectedAccessoryHostsMakesMultiHostBehaviorExplicit(t *testing.T) {
@@ -32,3 +37,64 @@ func TestSelectedAccessoryHostsMakesMultiHostBehaviorExplicit(t *testing.T) {
 		t.Fatal("expected unconfigured accessory host to fail")
 	}
 }
+
+func TestForEachAccessoryTargetRunsInParallelAndKeepsErrorOrder(t *testing.T) {
+	targets := append(accessoryTargets("db", []string{"one", "two"}), accessoryTarget{Name: "redis", Host: "one"})
+
+	// Every call waits for all of them to start, so a sequential run would
+	// never finish.
+	var started sync.WaitGroup
+	started.Add(len(targets))
+	errs := forEachAccessoryTarget(targets, func(target accessoryTarget) error {
+		started.Done()
+		started.Wait()
+		if target.Host == "one" {
+			return errors.New("unreachable")
+		}
+		return nil
+	})
+
+	want := []string{"db@one: unreachable", "redis@one: unreachable"}
+	if !reflect.DeepEqual(errs, want) {
+		t.Fatalf("errors = %v, want %v", errs, want)
+	}
+}
+
+func TestApplyAccessoryHealthcheck(t *testing.T) {
+	bootTimeout := time.Minute
+	accessory := config.AccessoryConfig{
+		BootTimeout: &bootTimeout,
+		Healthcheck: config.AccessoryHealthcheck{Port: 5432, Interval: 3 * time.Second},
+	}
+	containerConfig := &podman.ContainerConfig{}
+	applyAccessoryHealthcheck(containerConfig, accessory)
+
+	want := "cat /proc/net/tcp /proc/net/tcp6 2>/dev/null | grep -Eq '^ *[0-9]+: [0-9A-F]+:1538 [0-9A-F]+:[0-9A-F]+ 0A '"
+	if containerConfig.HealthCmd != want {
+		t.Fatalf("port health command = %q, want %q", containerConfig.HealthCmd, want)
+	}
+	if containerConfig.HealthInterval != "3s" || containerConfig.HealthTimeout != "5s" || containerConfig.HealthStartPeriod != "1m0s" {
+		t.Fatalf("health timing = interval %q timeout %q start period %q", containerConfig.HealthInterval, containerConfig.HealthTimeout, containerConfig.HealthStartPeriod)
+	}
+
+	pattern := regexp.MustCompile(`^ *[0-9]+: [0-9A-F]+:1538 [0-9A-F]+:[0-9A-F]+ 0A `)
+	if !pattern.MatchString("   1: 00000000:1538 00000000:0000 0A 00000000:00000000 00:00000000 00000000   999") {
+		t.Fatal("port pattern does not match a listening socket")
+	}
+	if pattern.MatchString("   2: 0100007F:1538 0100007F:D2F0 01 00000000:00000000 00:00000000 00000000   999") {
+		t.Fatal("port pattern matches an established connection")
+	}
+
+	accessory.Healthcheck = config.AccessoryHealthcheck{Cmd: "redis-cli ping"}
+	containerConfig = &podman.ContainerConfig{}
+	applyAccessoryHealthcheck(containerConfig, accessory)
+	if containerConfig.HealthCmd != "redis-cli ping" || containerConfig.HealthInterval != "10s" {
+		t.Fatalf("cmd healthcheck = %q every %q", containerConfig.HealthCmd, containerConfig.HealthInterval)
+	}
+
+	containerConfig = &podman.ContainerConfig{}
+	applyAccessoryHealthcheck(containerConfig, config.AccessoryConfig{})
+	if containerConfig.HealthCmd != "" {
+		t.Fatalf("accessory without healthcheck got %q", containerConfig.HealthCmd)
+	}
+}
@@ -0,0 +1,190 @@
+package cli
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"maps"
+	"net"
+	"net/http"
+	"os"
+	"slices"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/lemonity-org/azud/internal/agent"
+	"github.com/lemonity-org/azud/internal/config"
+	"github.com/lemonity-org/azud/internal/deploy"
+	"github.com/lemonity-org/azud/internal/output"
+)
+
+var agentCmd = &cobra.Command{
+	Use:   "agent",
+	Short: "Serve an HTTP API for deploys, rollbacks, and status",
+	Long: `Run a long-lived HTTP API, typically on a bastion host, through which
+ChatOps bots, dashboards, and other tools deploy, roll back, and read status
+without holding SSH keys to the hosts. Only the agent's machine needs the
+keys and secrets.
+
+Clients authenticate with the bearer tokens configured under agent.tokens.
+Operator tokens may deploy and roll back; read_only tokens may only read
+status, history, and jobs. Deploys and rollbacks run as background jobs, one
+at a time, and are recorded in deployment history with the token name.
+
+On SIGINT or SIGTERM the agent stops accepting requests, cancels a running
+job as Ctrl-C cancels azud deploy, and waits for it to stop.
+
+Example:
+  azud agent
+  azud agent -d production --listen 10.0.0.5:8732`,
+	Args: cobra.NoArgs,
+	RunE: runAgent,
+}
+
+var agentListen string
+
+func init() {
+	agentCmd.Flags().StringVar(&agentListen, "listen", "", "Address to listen on (default: agent.listen or "+config.DefaultAgentListen+")")
+
+	rootCmd.AddCommand(agentCmd)
+}
+
+// agentListenAddress returns the address the agent listens on: flag, else
+// agent.listen, else the default.
+func agentListenAddress(flag string) string {
+	if flag != "" {
+		return flag
+	}
+	if cfg.Agent.Listen != "" {
+		return cfg.Agent.Listen
+	}
+	return config.DefaultAgentListen
+}
+
+func runAgent(cmd *cobra.Command, args []string) error {
+	output.SetVerbose(verbose)
+	log := output.DefaultLogger
+
+	callers, err := agentCallers(cfg.Agent.Tokens)
+	if err != nil {
+		return err
+	}
+
+	listen := agentListenAddress(agentListen)
+	if cfg.Agent.TLSCertificate == "" && !config.LoopbackListen(listen) {
+		log.Warn("Serving the agent API on %s without TLS; bearer tokens travel in clear text", listen)
+	}
+	listener, err := net.Listen("tcp", listen)
+	if err != nil {
+		return err
+	}
+
+	server := agent.NewServer(cmd.Context(), cfg.Service, &agentBackend{log: log}, callers)
+	httpServer := &http.Server{
+		Handler:           server.Handler(),
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+
+	errCh := make(chan error, 1)
+	scheme := "http"
+	if cfg.Agent.TLSCertificate != "" {
+		scheme = "https"
+		go func() { errCh <- httpServer.ServeTLS(listener, cfg.Agent.TLSCertificate, cfg.Agent.TLSPrivateKey) }()
+	} else {
+		go func() { errCh <- httpServer.Serve(listener) }()
+	}
+	log.Success("Agent for %s listening on %s://%s (%d token(s))", cfg.Service, scheme, listener.Addr(), len(callers))
+
+	select {
+	case err := <-errCh:
+		return err
+	case <-cmd.Context().Done():
+	}
+
+	log.Info("Shutting down...")
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	err = httpServer.Shutdown(shutdownCtx)
+	server.Wait()
+	if err != nil && !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	return nil
+}
+
+// agentCallers resolves the configured tokens from their secrets.
+func agentCallers(tokens map[string]config.AgentToken) ([]agent.Caller, error) {
+	if len(tokens) == 0 {
+		return nil, fmt.Errorf("no agent.tokens configured; the agent serves only authenticated clients")
+	}
+	var callers []agent.Caller
+	for _, name := range slices.Sorted(maps.Keys(tokens)) {
+		token := tokens[name]
+		value, ok := getSecret(token.Secret)
+		if !ok {
+			return nil, fmt.Errorf("agent.tokens.%s: secret %s is not set", name, token.Secret)
+		}
+		role := token.Role
+		if role == "" {
+			role = config.RoleOperator
+		}
+		callers = append(callers, agent.Caller{Name: name, Role: role, Token: value})
+	}
+	return callers, nil
+}
+
+// agentBackend deploys with the loaded configuration, as azud deploy and
+// azud rollback do.
+type agentBackend struct {
+	log *output.Logger
+}
+
+func (b *agentBackend) Deploy(ctx context.Context, version, caller string) error {
+	return runUnattendedDeploy(ctx, b.log, b.options(version, caller), func(deployer *deploy.Deployer, opts *deploy.DeployOptions) error {
+		return deployer.Deploy(ctx, opts)
+	})
+}
+
+func (b *agentBackend) Rollback(ctx context.Context, version, caller string) error {
+	return runUnattendedDeploy(ctx, b.log, b.options(version, caller), func(deployer *deploy.Deployer, opts *deploy.DeployOptions) error {
+		return deployer.Rollback(ctx, version, opts)
+	})
+}
+
+func (b *agentBackend) History(limit int) ([]*deploy.DeploymentRecord, error) {
+	return newHistoryStore(b.log).List(cfg.Service, limit)
+}
+
+func (b *agentBackend) options(version, caller string) *deploy.DeployOptions {
+	return &deploy.DeployOptions{
+		Version:     version,
+		Destination: GetDestination(),
+		// The operator token that started the job approves its plan.
+		Approve: func(plan *deploy.DeployPlan) (string, error) {
+			return caller + " (agent token)", nil
+		},
+		ApprovalToken: os.Getenv("AZUD_APPROVAL_TOKEN"),
+		Metadata:      map[string]string{"agent_caller": caller},
+	}
+}
+
+// runUnattendedDeploy runs fn with a deployer for the loaded configuration,
+// as azud deploy does but without a terminal: the pre-connect hook runs
+// first and proxy routes are synced after a successful deploy.
+func runUnattendedDeploy(ctx context.Context, log *output.Logger, opts *deploy.DeployOptions, fn func(*deploy.Deployer, *deploy.DeployOptions) error) error {
+	hookCtx := newHookContext()
+	hookCtx.Version = opts.Version
+	if err := newHookRunner().Run(ctx, "pre-connect", hookCtx); err != nil {
+		return fmt.Errorf("pre-connect hook failed: %w", err)
+	}
+
+	sshClient := createSSHClient()
+	defer func() { _ = sshClient.Close() }()
+
+	if err := fn(deploy.NewDeployer(cfg, sshClient, log), opts); err != nil {
+		return err
+	}
+	syncProxyRoutesAfterChange(sshClient, log)
+	return nil
+}
@@ -0,0 +1,59 @@
+package cli
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/lemonity-org/azud/internal/agent"
+	"github.com/lemonity-org/azud/internal/config"
+)
+
+func TestAgentCallers(t *testing.T) {
+	t.Setenv("CHATOPS_TOKEN", "operator-token")
+	t.Setenv("DASHBOARD_TOKEN", "viewer-token")
+
+	callers, err := agentCallers(map[string]config.AgentToken{
+		"chatops":   {Secret: "CHATOPS_TOKEN"},
+		"dashboard": {Secret: "DASHBOARD_TOKEN", Role: config.RoleReadOnly},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []agent.Caller{
+		{Name: "chatops", Role: config.RoleOperator, Token: "operator-token"},
+		{Name: "dashboard", Role: config.RoleReadOnly, Token: "viewer-token"},
+	}
+	if !reflect.DeepEqual(callers, want) {
+		t.Fatalf("agentCallers() = %+v, want %+v", callers, want)
+	}
+
+	if _, err := agentCallers(nil); err == nil {
+		t.Fatal("agentCallers(nil) succeeded, want an error")
+	}
+	if _, err := agentCallers(map[string]config.AgentToken{"bot": {Secret: "AZUD_TEST_UNSET_TOKEN"}}); err == nil {
+		t.Fatal("agentCallers() with an unset secret succeeded, want an error")
+	}
+}
+
+func TestAgentListenAddress(t *testing.T) {
+	oldCfg := cfg
+	t.Cleanup(func() { cfg = oldCfg })
+	cfg = &config.Config{}
+
+	if got := agentListenAddress(""); got != config.DefaultAgentListen {
+		t.Fatalf("agentListenAddress() = %q, want the default %q", got, config.DefaultAgentListen)
+	}
+	cfg.Agent.Listen = "127.0.0.1:9000"
+	if got := agentListenAddress(""); got != "127.0.0.1:9000" {
+		t.Fatalf("agentListenAddress() = %q, want agent.listen", got)
+	}
+	// --listen overrides a loopback agent.listen, and the resolved address
+	// is what the plain HTTP warning checks.
+	got := agentListenAddress("0.0.0.0:8732")
+	if got != "0.0.0.0:8732" {
+		t.Fatalf("agentListenAddress(--listen) = %q, want the flag", got)
+	}
+	if config.LoopbackListen(got) {
+		t.Fatalf("LoopbackListen(%q) = true, want the plain HTTP warning", got)
+	}
+}
@@ -0,0 +1,138 @@
+package cli
+
+import (
+	"slices"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/lemonity-org/azud/internal/config"
+	"github.com/lemonity-org/azud/internal/output"
+	"github.com/lemonity-org/azud/internal/shell"
+)
+
+// aliasAnnotation marks subcommands registered from config aliases; its
+// value is the expansion.
+const aliasAnnotation = "azud.alias"
+
+// prepareArgs registers the config aliases as subcommands, so they appear in
+// help and completion, and rewrites args when they invoke an alias.
+func prepareArgs(root *cobra.Command, args []string) []string {
+	aliases := aliasesForArgs(args)
+	registerAliases(root, aliases)
+	return expandAlias(args, aliases)
+}
+
+// aliasesForArgs reads the aliases from the configuration and destination
+// selected by args. Errors are ignored here; the command reports them when
+// it loads the configuration.
+func aliasesForArgs(args []string) map[string]string {
+	path, dest := globalFlagValue(args, "c", "config"), globalFlagValue(args, "d", "destination")
+	if path == "" {
+		path = findConfigFile()
+	}
+	if path == "" {
+		return nil
+	}
+	loaded, err := config.NewLoader(path, dest).LoadUnvalidated()
+	if err != nil {
+		return nil
+	}
+	return loaded.Aliases
+}
+
+// globalFlagValue returns the value of a root persistent flag from raw args.
+func globalFlagValue(args []string, short, long string) string {
+	for i, arg := range args {
+		if arg == "--" {
+			break
+		}
+		if value, ok := strings.CutPrefix(arg, "--"+long+"="); ok {
+			return value
+		}
+		if value, ok := strings.CutPrefix(arg, "-"+short+"="); ok {
+			return value
+		}
+		if (arg == "--"+long || arg == "-"+short) && i+1 < len(args) {
+			return args[i+1]
+		}
+	}
+	return ""
+}
+
+// registerAliases adds a subcommand for each alias. Aliases that shadow a
+// built-in command are skipped with a warning.
+func registerAliases(root *cobra.Command, aliases map[string]string) {
+	for name, expansion := range aliases {
+		if existing, _, err := root.Find([]string{name}); err == nil && existing != root {
+			if existing.Annotations[aliasAnnotation] == "" {
+				output.DefaultLogger.Warn("Alias %q shadows a built-in command and is ignored", name)
+			}
+			continue
+		}
+		expanded, err := shell.Split(expansion)
+		if err != nil || len(expanded) == 0 {
+			continue
+		}
+		root.AddCommand(&cobra.Command{
+			Use:                name,
+			Short:              "Alias for azud " + expansion,
+			Annotations:        map[string]string{aliasAnnotation: expansion},
+			DisableFlagParsing: true,
+			// Invocations are normally rewritten by expandAlias before
+			// cobra runs; this handles the alias when it is reached directly.
+			RunE: func(cmd *cobra.Command, args []string) error {
+				root := cmd.Root()
+				root.SetArgs(append(slices.Clone(expanded), args...))
+				return root.ExecuteContext(cmd.Context())
+			},
+			ValidArgsFunction: func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+				target, targetArgs, err := cmd.Root().Find(expanded)
+				if err != nil || target.ValidArgsFunction == nil {
+					return nil, cobra.ShellCompDirectiveDefault
+				}
+				return target.ValidArgsFunction(target, append(targetArgs, args...), toComplete)
+			},
+		})
+	}
+}
+
+// expandAlias replaces an alias in the command position of args with its
+// expansion. Global flags before the alias are kept. For completion requests
+// the alias is only expanded once it is complete, so the alias name itself
+// still completes.
+func expandAlias(args []string, aliases map[string]string) []string {
+	if len(aliases) == 0 {
+		return args
+	}
+	start := 0
+	completing := len(args) > 0 && (args[0] == cobra.ShellCompRequestCmd || args[0] == cobra.ShellCompNoDescRequestCmd)
+	if completing {
+		start = 1
+	}
+
+	for i := start; i < len(args); i++ {
+		arg := args[i]
+		switch {
+		case arg == "--":
+			return args
+		case arg == "-c" || arg == "--config" || arg == "-d" || arg == "--destination":
+			i++
+			continue
+		case strings.HasPrefix(arg, "-"):
+			continue
+		}
+
+		expansion, ok := aliases[arg]
+		if !ok || (completing && i == len(args)-1) {
+			return args
+		}
+		expanded, err := shell.Split(expansion)
+		if err != nil || len(expanded) == 0 {
+			return args
+		}
+		rewritten := append(slices.Clone(args[:i]), expanded...)
+		return append(rewritten, args[i+1:]...)
+	}
+	return args
+}
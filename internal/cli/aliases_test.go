@@ -0,0 +1,87 @@
+package cli
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"github.com/spf13/cobra"
+)
+
+func TestExpandAlias(t *testing.T) {
+	aliases := map[string]string{"console": `app exec --role web -- "bin/rails console"`}
+
+	tests := []struct {
+		name string
+		args []string
+		want []string
+	}{
+		{"plain", []string{"console"}, []string{"app", "exec", "--role", "web", "--", "bin/rails console"}},
+		{"global flags kept", []string{"-d", "staging", "console"}, []string{"-d", "staging", "app", "exec", "--role", "web", "--", "bin/rails console"}},
+		{"extra args appended", []string{"console", "--sandbox"}, []string{"app", "exec", "--role", "web", "--", "bin/rails console", "--sandbox"}},
+		{"not an alias", []string{"deploy", "console"}, []string{"deploy", "console"}},
+		{"flag value named like alias", []string{"-c", "console", "deploy"}, []string{"-c", "console", "deploy"}},
+		{"completing alias name", []string{cobra.ShellCompRequestCmd, "console"}, []string{cobra.ShellCompRequestCmd, "console"}},
+		{"completing after alias", []string{cobra.ShellCompRequestCmd, "console", ""}, []string{cobra.ShellCompRequestCmd, "app", "exec", "--role", "web", "--", "bin/rails console", ""}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := expandAlias(tt.args, aliases); !slices.Equal(got, tt.want) {
+				t.Fatalf("expandAlias(%q) = %q, want %q", tt.args, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRegisterAliasesSkipsBuiltins(t *testing.T) {
+	root := &cobra.Command{Use: "azud"}
+	root.AddCommand(&cobra.Command{Use: "deploy", Run: func(*cobra.Command, []string) {}})
+
+	registerAliases(root, map[string]string{"deploy": "app details", "console": "app exec -- bin/console"})
+
+	names := make([]string, 0, len(root.Commands()))
+	for _, command := range root.Commands() {
+		names = append(names, command.Name())
+	}
+	if !slices.Equal(names, []string{"console", "deploy"}) {
+		t.Fatalf("commands = %v", names)
+	}
+	console, _, err := root.Find([]string{"console"})
+	if err != nil || console.Annotations[aliasAnnotation] != "app exec -- bin/console" {
+		t.Fatalf("console alias not registered: %v %v", console, err)
+	}
+}
+
+func TestAliasesForArgsReadsDestination(t *testing.T) {
+	dir := t.TempDir()
+	base := filepath.Join(dir, "deploy.yml")
+	if err := os.WriteFile(base, []byte("service: app\naliases:\n  console: app details\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "deploy.staging.yml"), []byte("aliases:\n  shell: app exec -- sh\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	got := aliasesForArgs([]string{"--config=" + base, "-d", "staging", "shell"})
+	if got["shell"] != "app exec -- sh" || got["console"] != "" {
+		t.Fatalf("aliases = %v", got)
+	}
+}
+
+func TestCompleteDestinations(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{"deploy.yml", "deploy.staging.yml", "deploy.production.yml", "deploy.staging.yml.bak"} {
+		if err := os.WriteFile(filepath.Join(dir, name), nil, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	previous := configPath
+	configPath = filepath.Join(dir, "deploy.yml")
+	t.Cleanup(func() { configPath = previous })
+
+	got, _ := completeDestinations(rootCmd, nil, "")
+	if !slices.Equal(got, []string{"production", "staging"}) {
+		t.Fatalf("destinations = %v", got)
+	}
+}
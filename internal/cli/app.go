@@ -408,6 +408,21 @@ var accessoryStopCmd = &cobra.Command{
 	RunE:  runAccessoryStop,
 }
 
+var accessoryRestartCmd = &cobra.Command{
+	Use:   "restart [name]",
+	Short: "Restart accessories",
+	Long: `Restart an accessory, or every accessory with --all, on all of its hosts
+in parallel. Accessories with a boot timeout are health-checked after the
+restart.
+
+Example:
+  azud accessory restart redis
+  azud accessory restart --all
+  azud accessory restart --all --host 192.168.1.10`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: runAccessoryRestart,
+}
+
 var accessoryLogsCmd = &cobra.Command{
 	Use:   "logs <name>",
 	Short: "View accessory logs",
@@ -438,8 +453,9 @@ Example:
 }
 
 var (
-	accessoryRemoveYes bool
-	accessoryHost      string
+	accessoryRemoveYes  bool
+	accessoryRestartAll bool
+	accessoryHost       string
 )
 
 func init() {
@@ -447,14 +463,17 @@ func init() {
 	accessoryLogsCmd.Flags().StringVar(&appTail, "tail", "100", "Number of lines")
 	accessoryBootCmd.Flags().StringVar(&accessoryHost, "host", "", "Specific configured host")
 	accessoryStopCmd.Flags().StringVar(&accessoryHost, "host", "", "Specific configured host")
+	accessoryRestartCmd.Flags().StringVar(&accessoryHost, "host", "", "Specific configured host")
 	accessoryLogsCmd.Flags().StringVar(&accessoryHost, "host", "", "Specific configured host")
 	accessoryExecCmd.Flags().StringVar(&accessoryHost, "host", "", "Specific configured host")
 	accessoryRemoveCmd.Flags().StringVar(&accessoryHost, "host", "", "Specific configured host")
 
 	accessoryRemoveCmd.Flags().BoolVar(&accessoryRemoveYes, "yes", false, "Skip confirmation prompt")
+	accessoryRestartCmd.Flags().BoolVar(&accessoryRestartAll, "all", false, "Restart every accessory")
 
 	accessoryCmd.AddCommand(accessoryBootCmd)
 	accessoryCmd.AddCommand(accessoryStopCmd)
+	accessoryCmd.AddCommand(accessoryRestartCmd)
 	accessoryCmd.AddCommand(accessoryLogsCmd)
 	accessoryCmd.AddCommand(accessoryExecCmd)
 	accessoryCmd.AddCommand(accessoryRemoveCmd)
@@ -512,20 +531,78 @@ func runAccessoryStop(cmd *cobra.Command, args []string) error {
 
 	log.Info("Stopping accessory %s...", name)
 
-	var stopErrors []string
-	for _, host := range hosts {
-		if err := containerManager.Stop(host, containerName, 30); err != nil {
-			stopErrors = append(stopErrors, fmt.Sprintf("%s: %v", host, err))
-			continue
+	stopErrors := forEachAccessoryTarget(accessoryTargets(name, hosts), func(target accessoryTarget) error {
+		if err := containerManager.Stop(target.Host, containerName, accessory.GetStopTimeout()); err != nil {
+			return err
 		}
-		log.HostSuccess(host, "Accessory %s stopped", name)
-	}
+		log.HostSuccess(target.Host, "Accessory %s stopped", name)
+		return nil
+	})
 	if len(stopErrors) > 0 {
 		return fmt.Errorf("failed to stop accessory %s: %s", name, strings.Join(stopErrors, "; "))
 	}
 	return nil
 }
 
+func runAccessoryRestart(cmd *cobra.Command, args []string) error {
+	output.SetVerbose(verbose)
+	log := output.DefaultLogger
+
+	if accessoryRestartAll == (len(args) == 1) {
+		return fmt.Errorf("specify an accessory name or --all")
+	}
+	names := args
+	if accessoryRestartAll {
+		names = cfg.GetAccessoryNames()
+	}
+
+	var targets []accessoryTarget
+	for _, name := range names {
+		accessory, ok := cfg.Accessories[name]
+		if !ok {
+			return fmt.Errorf("accessory %s not found", name)
+		}
+		if accessoryRestartAll && accessoryHost != "" && !containsString(accessoryHosts(accessory), accessoryHost) {
+			continue
+		}
+		hosts, err := selectedAccessoryHosts(name, accessory, false)
+		if err != nil {
+			return err
+		}
+		targets = append(targets, accessoryTargets(name, hosts)...)
+	}
+	if len(targets) == 0 {
+		return fmt.Errorf("no accessory runs on host %s", accessoryHost)
+	}
+
+	sshClient := createSSHClient()
+	defer func() { _ = sshClient.Close() }()
+
+	containerManager := podman.NewContainerManager(podman.NewClient(sshClient))
+
+	log.Info("Restarting %d accessory container(s)...", len(targets))
+	restartErrors := forEachAccessoryTarget(targets, func(target accessoryTarget) error {
+		accessory := cfg.Accessories[target.Name]
+		containerName := fmt.Sprintf("%s-%s", cfg.Service, target.Name)
+		if err := containerManager.Restart(target.Host, containerName, accessory.GetStopTimeout()); err != nil {
+			log.HostError(target.Host, "Failed to restart %s: %v", target.Name, err)
+			return err
+		}
+		if bootTimeout := accessory.GetBootTimeout(); bootTimeout > 0 {
+			if err := verifyAccessoryHealth(containerManager, target.Host, containerName, target.Name, bootTimeout, accessory.Healthcheck.IsSet(), log); err != nil {
+				log.HostError(target.Host, "%v", err)
+				return err
+			}
+		}
+		log.HostSuccess(target.Host, "Accessory %s restarted", target.Name)
+		return nil
+	})
+	if len(restartErrors) > 0 {
+		return fmt.Errorf("%d accessory(ies) failed to restart: %s", len(restartErrors), strings.Join(restartErrors, "; "))
+	}
+	return nil
+}
+
 func runAccessoryRemove(cmd *cobra.Command, args []string) error {
 	output.SetVerbose(verbose)
 	log := output.DefaultLogger
@@ -570,21 +647,20 @@ func runAccessoryRemove(cmd *cobra.Command, args []string) error {
 	podmanClient := podman.NewClient(sshClient)
 	containerManager := podman.NewContainerManager(podmanClient)
 
-	var removeErrors []string
-	for _, host := range hosts {
+	removeErrors := forEachAccessoryTarget(accessoryTargets(name, hosts), func(target accessoryTarget) error {
+		host := target.Host
 		log.Host(host, "Stopping accessory %s...", name)
-		if err := containerManager.Stop(host, containerName, 30); err != nil && !strings.Contains(err.Error(), "No such container") {
-			removeErrors = append(removeErrors, fmt.Sprintf("%s stop: %v", host, err))
-			continue
+		if err := containerManager.Stop(host, containerName, accessory.GetStopTimeout()); err != nil && !strings.Contains(err.Error(), "No such container") {
+			return fmt.Errorf("stop: %w", err)
 		}
 
 		log.Host(host, "Removing accessory %s...", name)
 		if err := containerManager.Remove(host, containerName, true); err != nil {
-			removeErrors = append(removeErrors, fmt.Sprintf("%s remove: %v", host, err))
-			continue
+			return fmt.Errorf("remove: %w", err)
 		}
 		log.HostSuccess(host, "Accessory %s removed", name)
-	}
+		return nil
+	})
 	if len(removeErrors) > 0 {
 		return fmt.Errorf("failed to remove accessory %s: %s", name, strings.Join(removeErrors, "; "))
 	}
@@ -759,7 +835,11 @@ func runConfig(cmd *cobra.Command, args []string) error {
 		log.Println("Proxy:")
 		log.Println("  Hosts: %s", strings.Join(proxyHosts, ", "))
 		log.Println("  SSL: %v", cfg.Proxy.SSL)
-		log.Println("  App Port: %d", cfg.Proxy.AppPort)
+		if cfg.UsesAppSocket() {
+			log.Println("  App Socket: %s", cfg.Proxy.AppSocket)
+		} else {
+			log.Println("  App Port: %d", cfg.Proxy.AppPort)
+		}
 		log.Println("")
 	}
 
@@ -0,0 +1,274 @@
+package cli
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/lemonity-org/azud/internal/deploy"
+	"github.com/lemonity-org/azud/internal/output"
+	"github.com/lemonity-org/azud/internal/podman"
+	"github.com/lemonity-org/azud/internal/proxy"
+	"github.com/lemonity-org/azud/internal/ssh"
+)
+
+var appDrainCmd = &cobra.Command{
+	Use:   "drain <host>",
+	Short: "Take a web host out of rotation and stop it for maintenance",
+	Long: `Remove the web containers of a host from the proxy, wait up to
+deploy.drain_timeout for in-flight requests to finish, and stop the
+containers. The host stays out of the proxy until 'azud app undrain'.
+
+Without a load-balancer tier the host's own proxy loses the service route;
+with proxy.hosts_role every load balancer drops the host's upstreams. A
+deploy to the host brings it back into rotation.
+
+Example:
+  azud app drain 192.168.1.1
+  azud app undrain 192.168.1.1`,
+	Args: cobra.ExactArgs(1),
+	RunE: runAppDrain,
+}
+
+var appUndrainCmd = &cobra.Command{
+	Use:   "undrain <host>",
+	Short: "Start a drained web host and return it to rotation",
+	Long: `Start the web containers of a host drained with 'azud app drain', wait
+until they are ready, and add them back to the proxy.
+
+Example:
+  azud app undrain 192.168.1.1`,
+	Args: cobra.ExactArgs(1),
+	RunE: runAppUndrain,
+}
+
+func init() {
+	appCmd.AddCommand(appDrainCmd)
+	appCmd.AddCommand(appUndrainCmd)
+}
+
+// drainTarget holds the clients and web instances used to drain or undrain
+// one host.
+type drainTarget struct {
+	host       string
+	log        *output.Logger
+	sshClient  *ssh.Client
+	podman     *podman.Client
+	containers *podman.ContainerManager
+	proxy      *proxy.Manager
+	instances  []roleInstance
+}
+
+func newDrainTarget(host string) (*drainTarget, error) {
+	if !containsString(getProxyRouteHosts(""), host) {
+		return nil, fmt.Errorf("host %s is not a web role host", host)
+	}
+	if cfg.Proxy.PrimaryHost() == "" {
+		return nil, fmt.Errorf("draining requires proxy.host")
+	}
+
+	log := output.DefaultLogger
+	sshClient := createSSHClient()
+	podmanClient := podman.NewClient(sshClient)
+	containers := podman.NewContainerManager(podmanClient)
+	manager := proxy.NewManagerWithOptions(sshClient, log, cfg.SSH.User, cfg.Proxy.Rootful, cfg.UseHostPortUpstreams())
+	manager.SetProxyConfig(buildProxyConfig(log))
+
+	instances, err := listRoleInstances(containers, host, "web")
+	if err != nil {
+		_ = sshClient.Close()
+		return nil, fmt.Errorf("failed to enumerate web containers on %s: %w", host, err)
+	}
+	if len(instances) == 0 {
+		_ = sshClient.Close()
+		return nil, fmt.Errorf("no web containers found on %s", host)
+	}
+
+	return &drainTarget{
+		host:       host,
+		log:        log,
+		sshClient:  sshClient,
+		podman:     podmanClient,
+		containers: containers,
+		proxy:      manager,
+		instances:  instances,
+	}, nil
+}
+
+// proxyNodes returns the proxies routing to the target host.
+func (t *drainTarget) proxyNodes() []string {
+	if cfg.UsesProxyTier() {
+		return cfg.GetProxyHosts()
+	}
+	return []string{t.host}
+}
+
+func runAppDrain(cmd *cobra.Command, args []string) error {
+	output.SetVerbose(verbose)
+	target, err := newDrainTarget(args[0])
+	if err != nil {
+		return err
+	}
+	defer func() { _ = target.sshClient.Close() }()
+	host, log := target.host, target.log
+
+	var upstreams []string
+	for _, instance := range target.instances {
+		upstream, err := deploy.ResolveUpstream(cfg, target.containers, host, instance.Name)
+		if err != nil {
+			log.Debug("No upstream for %s: %v", instance.Name, err)
+			continue
+		}
+		upstreams = append(upstreams, upstream)
+	}
+
+	if err := takeOutOfRotation(target.proxy, target.containers, host, target.proxyNodes(), upstreams, target.instances, log); err != nil {
+		return err
+	}
+
+	log.HostSuccess(host, "Drained; run 'azud app undrain %s' to restore it", host)
+	return nil
+}
+
+func runAppUndrain(cmd *cobra.Command, args []string) error {
+	output.SetVerbose(verbose)
+	target, err := newDrainTarget(args[0])
+	if err != nil {
+		return err
+	}
+	defer func() { _ = target.sshClient.Close() }()
+	host, log := target.host, target.log
+
+	var upstreams []string
+	for _, instance := range target.instances {
+		log.Host(host, "Starting %s...", instance.Name)
+		if err := target.containers.Start(host, instance.Name); err != nil {
+			return fmt.Errorf("failed to start %s: %w", instance.Name, err)
+		}
+		var readyErr error
+		if deploy.HasReadinessProbe(cfg) {
+			readyErr = deploy.WaitForContainerReady(cfg, target.podman, target.sshClient, host, instance.Name)
+		} else {
+			readyErr = target.containers.WaitRunning(host, instance.Name, cfg.Deploy.ReadinessDelay)
+		}
+		if readyErr != nil {
+			return fmt.Errorf("%s is not ready; host left out of rotation: %w", instance.Name, readyErr)
+		}
+		// Host ports are reassigned on start, so resolve the upstream now.
+		upstream, err := deploy.ResolveUpstream(cfg, target.containers, host, instance.Name)
+		if err != nil {
+			return fmt.Errorf("failed to resolve upstream for %s: %w", instance.Name, err)
+		}
+		upstreams = append(upstreams, upstream)
+	}
+
+	var weights *deploy.TrafficWeights
+	if cfg.UsesProxyTier() {
+		lbHosts := cfg.GetProxyHosts()
+		if len(lbHosts) == 0 {
+			return fmt.Errorf("no load-balancer hosts configured for role %s", cfg.Proxy.HostsRole)
+		}
+		if weights, err = deploy.LoadTrafficWeights(target.sshClient, lbHosts[0], cfg.SSH.User, cfg.Service); err != nil {
+			return err
+		}
+	}
+	restoreWeights := func(weights *deploy.TrafficWeights) error {
+		return applyTrafficWeights(target.sshClient, target.proxy, target.containers, cfg.GetProxyHosts(), getProxyRouteHosts(""), weights)
+	}
+	added, err := returnToRotation(target.proxy, host, upstreams, weights, restoreWeights, log)
+	if err != nil || !added {
+		return err
+	}
+	log.HostSuccess(host, "Back in rotation")
+	return nil
+}
+
+// drainProxy is the part of proxy.Manager that drain and undrain use.
+type drainProxy interface {
+	RegisterService(host string, service *proxy.ServiceConfig) error
+	DeregisterService(host, serviceHost string) error
+	AddUpstream(host, serviceHost, upstream string) error
+	RemoveUpstream(host, serviceHost, upstream string) error
+	DrainUpstream(host, upstream string, timeout time.Duration) error
+}
+
+// containerStopper stops containers; podman.ContainerManager implements it.
+type containerStopper interface {
+	Stop(host, container string, timeout int) error
+}
+
+// takeOutOfRotation removes host's upstreams from the proxy nodes, waits for
+// their in-flight requests, and then stops its web containers.
+func takeOutOfRotation(manager drainProxy, containers containerStopper, host string, nodes, upstreams []string, instances []roleInstance, log *output.Logger) error {
+	serviceHost := cfg.Proxy.PrimaryHost()
+
+	log.Host(host, "Removing %d upstream(s) from the proxy...", len(upstreams))
+	for _, node := range nodes {
+		if !cfg.UsesProxyTier() {
+			if err := manager.DeregisterService(node, serviceHost); err != nil {
+				return fmt.Errorf("failed to deregister %s from the proxy on %s: %w", serviceHost, node, err)
+			}
+			continue
+		}
+		for _, upstream := range upstreams {
+			if err := manager.RemoveUpstream(node, serviceHost, upstream); err != nil {
+				return fmt.Errorf("failed to remove %s from proxy %s: %w", upstream, node, err)
+			}
+		}
+	}
+
+	if cfg.Deploy.DrainTimeout > 0 {
+		for _, node := range nodes {
+			for _, upstream := range upstreams {
+				if err := manager.DrainUpstream(node, upstream, cfg.Deploy.DrainTimeout); err != nil {
+					log.Warn("Drain failed for %s: %v", upstream, err)
+				}
+			}
+		}
+	}
+
+	stopTimeout := cfg.RoleStopTimeout("web")
+	var errs []string
+	for _, instance := range instances {
+		if err := containers.Stop(host, instance.Name, stopTimeout); err != nil {
+			errs = append(errs, fmt.Sprintf("%s: %v", instance.Name, err))
+		}
+	}
+	if len(errs) > 0 {
+		return fmt.Errorf("host %s is out of rotation but some containers did not stop: %s", host, strings.Join(errs, "; "))
+	}
+	return nil
+}
+
+// returnToRotation adds host's upstreams back to the proxy: its own proxy
+// without a load-balancer tier, every load balancer with one, after which
+// customized traffic weights are restored. A host whose traffic weight is 0
+// is left out, and added reports false.
+func returnToRotation(manager drainProxy, host string, upstreams []string, weights *deploy.TrafficWeights, restoreWeights func(*deploy.TrafficWeights) error, log *output.Logger) (added bool, err error) {
+	if !cfg.UsesProxyTier() {
+		if err := manager.RegisterService(host, deploy.BuildProxyServiceConfig(cfg, upstreams, nil)); err != nil {
+			return false, fmt.Errorf("failed to register %s with the proxy: %w", host, err)
+		}
+		return true, nil
+	}
+
+	if weights.Weight(host) == 0 {
+		log.Warn("%s is started but has traffic weight 0; run 'azud traffic set %s <weight>' to return it to rotation", host, host)
+		return false, nil
+	}
+	for _, node := range cfg.GetProxyHosts() {
+		for _, upstream := range upstreams {
+			if err := manager.AddUpstream(node, cfg.Proxy.PrimaryHost(), upstream); err != nil {
+				return false, fmt.Errorf("failed to add %s to proxy %s: %w", upstream, node, err)
+			}
+		}
+	}
+	if weights.Customized() {
+		if err := restoreWeights(weights); err != nil {
+			return false, fmt.Errorf("failed to restore traffic weights: %w", err)
+		}
+	}
+	return true, nil
+}
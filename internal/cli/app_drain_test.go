@@ -0,0 +1,167 @@
+package cli
+
+import (
+	"fmt"
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/lemonity-org/azud/internal/config"
+	"github.com/lemonity-org/azud/internal/deploy"
+	"github.com/lemonity-org/azud/internal/output"
+	"github.com/lemonity-org/azud/internal/proxy"
+)
+
+// fakeDrainProxy records the proxy and container calls of drain and undrain
+// in order.
+type fakeDrainProxy struct {
+	calls []string
+}
+
+func (f *fakeDrainProxy) RegisterService(host string, service *proxy.ServiceConfig) error {
+	f.calls = append(f.calls, fmt.Sprintf("register %s %s %s", host, service.Host, strings.Join(service.Upstreams, ",")))
+	return nil
+}
+
+func (f *fakeDrainProxy) DeregisterService(host, serviceHost string) error {
+	f.calls = append(f.calls, fmt.Sprintf("deregister %s %s", host, serviceHost))
+	return nil
+}
+
+func (f *fakeDrainProxy) AddUpstream(host, serviceHost, upstream string) error {
+	f.calls = append(f.calls, fmt.Sprintf("add %s %s", host, upstream))
+	return nil
+}
+
+func (f *fakeDrainProxy) RemoveUpstream(host, serviceHost, upstream string) error {
+	f.calls = append(f.calls, fmt.Sprintf("remove %s %s", host, upstream))
+	return nil
+}
+
+func (f *fakeDrainProxy) DrainUpstream(host, upstream string, timeout time.Duration) error {
+	f.calls = append(f.calls, fmt.Sprintf("drain %s %s", host, upstream))
+	return nil
+}
+
+func (f *fakeDrainProxy) Stop(host, container string, timeout int) error {
+	f.calls = append(f.calls, fmt.Sprintf("stop %s %s", host, container))
+	return nil
+}
+
+func drainTestConfig(tier bool) *config.Config {
+	c := &config.Config{
+		Service: "shop",
+		Servers: map[string]config.RoleConfig{
+			"web": {Hosts: []string{"web-1", "web-2"}},
+			"lb":  {Hosts: []string{"lb-1", "lb-2"}},
+		},
+		Proxy: config.ProxyConfig{Host: "shop.example.com"},
+	}
+	c.Deploy.DrainTimeout = 30 * time.Second
+	if tier {
+		c.Proxy.HostsRole = "lb"
+	}
+	return c
+}
+
+func TestDrainProxyNodes(t *testing.T) {
+	oldCfg := cfg
+	t.Cleanup(func() { cfg = oldCfg })
+	target := &drainTarget{host: "web-1"}
+
+	cfg = drainTestConfig(false)
+	if got := target.proxyNodes(); !reflect.DeepEqual(got, []string{"web-1"}) {
+		t.Fatalf("co-located proxyNodes() = %v, want the drained host", got)
+	}
+	cfg = drainTestConfig(true)
+	if got := target.proxyNodes(); !reflect.DeepEqual(got, []string{"lb-1", "lb-2"}) {
+		t.Fatalf("tier proxyNodes() = %v, want the load balancers", got)
+	}
+}
+
+func TestTakeOutOfRotation(t *testing.T) {
+	oldCfg := cfg
+	t.Cleanup(func() { cfg = oldCfg })
+	log := output.NewLogger(io.Discard, io.Discard, false)
+	instances := []roleInstance{{Name: "shop-web"}}
+
+	tests := []struct {
+		name  string
+		tier  bool
+		nodes []string
+		want  []string
+	}{
+		{name: "co-located proxy", nodes: []string{"web-1"}, want: []string{
+			"deregister web-1 shop.example.com",
+			"drain web-1 10.0.0.1:3000",
+			"stop web-1 shop-web",
+		}},
+		{name: "load-balancer tier", tier: true, nodes: []string{"lb-1", "lb-2"}, want: []string{
+			"remove lb-1 10.0.0.1:3000",
+			"remove lb-2 10.0.0.1:3000",
+			"drain lb-1 10.0.0.1:3000",
+			"drain lb-2 10.0.0.1:3000",
+			"stop web-1 shop-web",
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg = drainTestConfig(tt.tier)
+			fake := &fakeDrainProxy{}
+			if err := takeOutOfRotation(fake, fake, "web-1", tt.nodes, []string{"10.0.0.1:3000"}, instances, log); err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(fake.calls, tt.want) {
+				t.Fatalf("calls = %q, want %q", fake.calls, tt.want)
+			}
+		})
+	}
+}
+
+func TestReturnToRotation(t *testing.T) {
+	oldCfg := cfg
+	t.Cleanup(func() { cfg = oldCfg })
+	log := output.NewLogger(io.Discard, io.Discard, false)
+	upstreams := []string{"10.0.0.1:3000"}
+
+	tests := []struct {
+		name      string
+		tier      bool
+		weights   *deploy.TrafficWeights
+		wantAdded bool
+		want      []string
+	}{
+		{name: "co-located proxy", wantAdded: true, want: []string{
+			"register web-1 shop.example.com 10.0.0.1:3000",
+		}},
+		{name: "default weight", tier: true, wantAdded: true, want: []string{
+			"add lb-1 10.0.0.1:3000",
+			"add lb-2 10.0.0.1:3000",
+		}},
+		{name: "custom weights restored", tier: true, weights: &deploy.TrafficWeights{Weights: map[string]int{"web-1": 20}}, wantAdded: true, want: []string{
+			"add lb-1 10.0.0.1:3000",
+			"add lb-2 10.0.0.1:3000",
+			"weights web-1=20",
+		}},
+		{name: "weight 0 stays out", tier: true, weights: &deploy.TrafficWeights{Weights: map[string]int{"web-1": 0}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg = drainTestConfig(tt.tier)
+			fake := &fakeDrainProxy{}
+			restore := func(weights *deploy.TrafficWeights) error {
+				fake.calls = append(fake.calls, fmt.Sprintf("weights web-1=%d", weights.Weight("web-1")))
+				return nil
+			}
+			added, err := returnToRotation(fake, "web-1", upstreams, tt.weights, restore, log)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if added != tt.wantAdded || !reflect.DeepEqual(fake.calls, tt.want) {
+				t.Fatalf("returnToRotation() = %t with calls %q, want %t with %q", added, fake.calls, tt.wantAdded, tt.want)
+			}
+		})
+	}
+}
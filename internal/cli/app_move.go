@@ -0,0 +1,220 @@
+package cli
+
+import (
+	"fmt"
+	"os"
+	"slices"
+
+	"github.com/spf13/cobra"
+
+	"github.com/lemonity-org/azud/internal/deploy"
+	"github.com/lemonity-org/azud/internal/output"
+	"github.com/lemonity-org/azud/internal/podman"
+	"github.com/lemonity-org/azud/internal/proxy"
+	"github.com/lemonity-org/azud/internal/ssh"
+)
+
+var appMoveCmd = &cobra.Command{
+	Use:   "move <from-host> <to-host>",
+	Short: "Move the application to another host",
+	Long: `Move every application role from one host to another.
+
+The move:
+  1. Pushes secrets to the target host
+  2. Copies the application's named volumes to the target
+  3. Deploys the current version on the target and routes traffic to it
+  4. Drains and removes the application containers and their replicas on
+     the source
+
+The target must already be bootstrapped (azud server bootstrap <to-host>).
+Accessories and cron jobs on the source are not moved. Afterwards, replace
+the source host with the target in config/deploy.yml.
+
+Example:
+  azud app move 192.168.1.10 192.168.1.20
+  azud app move 192.168.1.10 192.168.1.20 --stop-source`,
+	Args: cobra.ExactArgs(2),
+	RunE: runAppMove,
+}
+
+var (
+	appMoveStopSource bool
+	appMoveKeepSource bool
+	appMoveVersion    string
+)
+
+func init() {
+	appMoveCmd.Flags().BoolVar(&appMoveStopSource, "stop-source", false, "Stop source containers before copying volumes for a consistent copy")
+	appMoveCmd.Flags().BoolVar(&appMoveKeepSource, "keep-source", false, "Leave the source containers and proxy route in place")
+	appMoveCmd.Flags().BoolVar(&deployOverride, "override", false, deployOverrideUsage)
+	appMoveCmd.Flags().StringVar(&appMoveVersion, "version", "", "Version to deploy on the target (default: last successful deployment)")
+
+	appCmd.AddCommand(appMoveCmd)
+}
+
+func runAppMove(cmd *cobra.Command, args []string) error {
+	output.SetVerbose(verbose)
+	log := output.DefaultLogger
+	from, to := args[0], args[1]
+
+	if err := validateAppMove(from, to); err != nil {
+		return err
+	}
+	if appMoveStopSource && appMoveKeepSource {
+		return fmt.Errorf("--stop-source and --keep-source cannot be combined")
+	}
+
+	version := appMoveVersion
+	if version == "" {
+		history := deploy.NewDurableHistoryStore(cfg.Deploy.RetainHistory, log)
+		last, err := history.GetLastSuccessful(cfg.Service)
+		if err != nil || last.Version == "" {
+			return fmt.Errorf("no successful deployment recorded; pass --version")
+		}
+		version = last.Version
+	}
+
+	moved := cfg.ReplaceHost(from, to)
+
+	sshClient := createSSHClient()
+	defer func() { _ = sshClient.Close() }()
+
+	log.Header("Move / %s -> %s", from, to)
+
+	status, err := newBootstrapper(sshClient, log).CheckPodman(to)
+	if err != nil || !status.Installed {
+		return fmt.Errorf("podman is not installed on %s; run 'azud server bootstrap %s' first", to, to)
+	}
+
+	log.Header("01 / Sync secrets")
+	previousCfg, previousHost := cfg, envHost
+	cfg, envHost = moved, to
+	err = runEnvPush(cmd, nil)
+	cfg, envHost = previousCfg, previousHost
+	if err != nil {
+		return fmt.Errorf("secret sync failed: %w", err)
+	}
+
+	source := deploy.NewDeployer(cfg, sshClient, log)
+
+	log.Header("02 / Copy volumes")
+	if appMoveStopSource {
+		if err := source.Stop([]string{from}); err != nil {
+			return err
+		}
+	}
+	copied := 0
+	for _, volume := range cfg.NamedVolumes() {
+		if volume.Accessory != "" {
+			continue
+		}
+		if err := copyVolume(sshClient, log, volume.Name, from, to, false); err != nil {
+			return fmt.Errorf("failed to copy volume %s: %w", volume.Name, err)
+		}
+		copied++
+	}
+	if copied == 0 {
+		log.Info("No application volumes to copy")
+	}
+
+	log.Header("03 / Deploy on %s", to)
+	target := deploy.NewDeployer(moved, sshClient, log)
+	if err := target.Deploy(cmd.Context(), &deploy.DeployOptions{
+		Version:       version,
+		Hosts:         []string{to},
+		Destination:   destination,
+		Approve:       deployApprover(cmd),
+		ApprovalToken: os.Getenv("AZUD_APPROVAL_TOKEN"),
+		Override:      deployOverride,
+	}); err != nil {
+		if appMoveStopSource {
+			log.Warn("Source containers on %s are stopped; run 'azud app start --host %s' to resume them", from, from)
+		}
+		return fmt.Errorf("deploy on %s failed: %w", to, err)
+	}
+
+	if appMoveKeepSource {
+		log.Info("Keeping source containers on %s (--keep-source)", from)
+	} else {
+		log.Header("04 / Decommission %s", from)
+		if err := removeAppMoveReplicas(sshClient, from, log); err != nil {
+			return err
+		}
+		if err := source.Decommission(from); err != nil {
+			return err
+		}
+	}
+
+	log.Header("Move / complete")
+	log.Info("Replace %s with %s in config/deploy.yml", from, to)
+	if !cfg.UsesProxyTier() {
+		log.Info("Point DNS for %v at %s", cfg.Proxy.AllHosts(), to)
+	}
+	for _, name := range cfg.GetAccessoryNames() {
+		if slices.Contains(accessoryHosts(cfg.Accessories[name]), from) {
+			log.Warn("Accessory %s still runs on %s and was not moved", name, from)
+		}
+	}
+	for _, name := range cfg.GetCronNames() {
+		if slices.Contains(cfg.GetCronHosts(name), from) {
+			log.Warn("Cron job %s runs on %s; run 'azud cron boot' after updating the configuration", name, from)
+		}
+	}
+	return nil
+}
+
+// validateAppMove checks that from runs the application and that to can
+// take its place.
+func validateAppMove(from, to string) error {
+	if from == to {
+		return fmt.Errorf("source and target host must differ")
+	}
+	for _, role := range cfg.GetRoles() {
+		if cfg.IsProxyTierRole(role) && slices.Contains(cfg.Servers[role].Hosts, from) {
+			return fmt.Errorf("%s is a load-balancer tier host; only application hosts can be moved", from)
+		}
+	}
+	roles := appMoveRoles(from)
+	if len(roles) == 0 {
+		return fmt.Errorf("host %s does not run the application", from)
+	}
+	for _, role := range roles {
+		if slices.Contains(cfg.Servers[role].Hosts, to) {
+			return fmt.Errorf("%s already runs the %s role", to, role)
+		}
+	}
+	if cfg.Network.WireGuard && !slices.Contains(cfg.MeshHosts(), to) {
+		return fmt.Errorf("%s is not in the WireGuard mesh; add it to the configuration and run 'azud server wireguard' first", to)
+	}
+	return nil
+}
+
+// appMoveRoles returns the application roles host runs, which a move takes
+// off it.
+func appMoveRoles(host string) []string {
+	var roles []string
+	for _, role := range cfg.GetRoles() {
+		if !cfg.IsProxyTierRole(role) && slices.Contains(cfg.Servers[role].Hosts, host) {
+			roles = append(roles, role)
+		}
+	}
+	return roles
+}
+
+// removeAppMoveReplicas removes the replicas 'azud scale' started on host,
+// taking them out of the proxy first, so only the primary containers are
+// left for Decommission.
+func removeAppMoveReplicas(sshClient *ssh.Client, host string, log *output.Logger) error {
+	if cfg.UsesProxyTier() {
+		// Scaling is not supported with a load-balancer tier.
+		return nil
+	}
+	containers := podman.NewContainerManager(podman.NewClient(sshClient))
+	manager := proxy.NewManagerWithOptions(sshClient, log, cfg.SSH.User, cfg.Proxy.Rootful, cfg.UseHostPortUpstreams())
+	for _, role := range appMoveRoles(host) {
+		if err := scaleDown(containers, manager, host, role, 0, 1, log); err != nil {
+			return fmt.Errorf("failed to remove %s replicas on %s: %w", role, host, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,82 @@
+package cli
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/lemonity-org/azud/internal/config"
+)
+
+func TestValidateAppMoveAndReplaceHost(t *testing.T) {
+	oldCfg := cfg
+	t.Cleanup(func() { cfg = oldCfg })
+	cfg = &config.Config{
+		Service: "shop",
+		Servers: map[string]config.RoleConfig{
+			"web":    {Hosts: []string{"web-1", "web-2"}},
+			"worker": {Hosts: []string{"web-1"}},
+			"lb":     {Hosts: []string{"lb-1"}},
+		},
+		Proxy: config.ProxyConfig{HostsRole: "lb"},
+		Cron: map[string]config.CronConfig{
+			"backup": {Host: "web-1", Schedule: "0 2 * * *", Command: "bin/backup"},
+		},
+	}
+
+	for _, tt := range []struct {
+		from, to, wantErr string
+	}{
+		{"web-1", "web-3", ""},
+		{"web-1", "web-1", "must differ"},
+		{"web-1", "web-2", "already runs the web role"},
+		{"db-1", "web-3", "does not run the application"},
+		{"lb-1", "lb-2", "load-balancer tier host"},
+	} {
+		err := validateAppMove(tt.from, tt.to)
+		if tt.wantErr == "" && err != nil || tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)) {
+			t.Errorf("validateAppMove(%s, %s) = %v, want %q", tt.from, tt.to, err, tt.wantErr)
+		}
+	}
+
+	moved := cfg.ReplaceHost("web-1", "web-3")
+	if !reflect.DeepEqual(moved.Servers["web"].Hosts, []string{"web-3", "web-2"}) || !reflect.DeepEqual(moved.Servers["worker"].Hosts, []string{"web-3"}) {
+		t.Fatalf("moved roles = %v", moved.Servers)
+	}
+	if moved.Cron["backup"].Host != "web-3" {
+		t.Fatalf("moved cron host = %q", moved.Cron["backup"].Host)
+	}
+	if cfg.Servers["web"].Hosts[0] != "web-1" || cfg.Cron["backup"].Host != "web-1" {
+		t.Fatal("ReplaceHost modified the original configuration")
+	}
+}
+
+func TestAppMovePlan(t *testing.T) {
+	oldCfg := cfg
+	t.Cleanup(func() { cfg = oldCfg })
+	cfg = &config.Config{
+		Service: "shop",
+		Servers: map[string]config.RoleConfig{
+			"web":    {Hosts: []string{"web-1", "web-2"}},
+			"worker": {Hosts: []string{"web-1"}},
+			"jobs":   {Hosts: []string{"web-2"}},
+		},
+	}
+
+	if err := validateAppMove("web-1", "web-3"); err != nil {
+		t.Fatal(err)
+	}
+	plan := appMoveRoles("web-1")
+	if want := []string{"web", "worker"}; !reflect.DeepEqual(plan, want) {
+		t.Fatalf("appMoveRoles(web-1) = %v, want %v", plan, want)
+	}
+
+	moved := cfg.ReplaceHost("web-1", "web-3")
+	cfg = moved
+	if got := appMoveRoles("web-3"); !reflect.DeepEqual(got, plan) {
+		t.Fatalf("after the move appMoveRoles(web-3) = %v, want %v", got, plan)
+	}
+	if err := validateAppMove("web-1", "web-4"); err == nil || !strings.Contains(err.Error(), "does not run the application") {
+		t.Fatalf("validateAppMove() from the vacated host = %v", err)
+	}
+}
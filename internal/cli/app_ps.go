@@ -0,0 +1,245 @@
+package cli
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/lemonity-org/azud/internal/output"
+	"github.com/lemonity-org/azud/internal/podman"
+)
+
+var appPsCmd = &cobra.Command{
+	Use:   "ps",
+	Short: "List Azud-managed containers on every host",
+	Long: `List every Azud-managed container on the configured hosts, including
+accessories, cron jobs, the proxy, and other services sharing the hosts, with
+their image, state, uptime, and health.
+
+Example:
+  azud app ps
+  azud app ps --host 192.168.1.1`,
+	Args: cobra.NoArgs,
+	RunE: runAppPs,
+}
+
+var appInspectCmd = &cobra.Command{
+	Use:   "inspect <host>",
+	Short: "Print podman inspect output for the application containers",
+	Long: `Print the podman inspect JSON of the service's containers on a host.
+
+Environment values, including those repeated in the container's create
+command, are replaced with <redacted>; the variable names are kept.
+
+Example:
+  azud app inspect 192.168.1.1
+  azud app inspect 192.168.1.1 --role worker`,
+	Args: cobra.ExactArgs(1),
+	RunE: runAppInspect,
+}
+
+// redactedValue replaces environment values in inspect output.
+const redactedValue = "<redacted>"
+
+func init() {
+	appPsCmd.Flags().StringVar(&appHost, "host", "", "Specific host")
+	appInspectCmd.Flags().StringVar(&appRole, "role", "", "Specific role")
+
+	appCmd.AddCommand(appPsCmd)
+	appCmd.AddCommand(appInspectCmd)
+}
+
+func runAppPs(cmd *cobra.Command, args []string) error {
+	output.SetVerbose(verbose)
+	log := output.DefaultLogger
+
+	hosts := portsHosts()
+	if appHost != "" {
+		if !containsString(hosts, appHost) {
+			return fmt.Errorf("host %s is not configured", appHost)
+		}
+		hosts = []string{appHost}
+	}
+
+	sshClient := createSSHClient()
+	defer func() { _ = sshClient.Close() }()
+	containerManager := podman.NewContainerManager(podman.NewClient(sshClient))
+
+	var rows [][]string
+	var psErrors []string
+	for _, host := range hosts {
+		containers, err := containerManager.List(host, true, map[string]string{"label": "azud.managed=true"})
+		if err != nil {
+			log.HostError(host, "%v", err)
+			psErrors = append(psErrors, fmt.Sprintf("%s: %v", host, err))
+			continue
+		}
+		for _, c := range containers {
+			service := c.Labels["azud.service"]
+			if service == "" {
+				service = "-"
+			}
+			uptime, health := splitContainerStatus(c.Status)
+			rows = append(rows, []string{host, c.Name, service, containerKind(c.Labels), c.Image, c.State, uptime, health})
+		}
+	}
+
+	if len(rows) == 0 {
+		log.Info("No Azud-managed containers")
+	} else {
+		log.Table([]string{"Host", "Container", "Service", "Kind", "Image", "State", "Uptime", "Health"}, rows)
+	}
+	if len(psErrors) > 0 {
+		return fmt.Errorf("failed to list containers: %s", strings.Join(psErrors, "; "))
+	}
+	return nil
+}
+
+func runAppInspect(cmd *cobra.Command, args []string) error {
+	output.SetVerbose(verbose)
+	host := args[0]
+
+	candidateHosts := cfg.GetAllHosts()
+	if appRole != "" {
+		candidateHosts = cfg.GetRoleHosts(appRole)
+	}
+	if !containsString(candidateHosts, host) {
+		return fmt.Errorf("host %s is not configured for %s", host, describeAppRole())
+	}
+
+	sshClient := createSSHClient()
+	defer func() { _ = sshClient.Close() }()
+	containerManager := podman.NewContainerManager(podman.NewClient(sshClient))
+
+	filters := map[string]string{"label": "azud.service=" + cfg.Service}
+	containers, err := containerManager.List(host, true, filters)
+	if err != nil {
+		return err
+	}
+	var names []string
+	for _, c := range containers {
+		if c.Labels["azud.managed"] != "true" || appRole != "" && c.Labels["azud.role"] != appRole {
+			continue
+		}
+		names = append(names, c.Name)
+	}
+	if len(names) == 0 {
+		return fmt.Errorf("no %s containers on %s", describeAppRole(), host)
+	}
+
+	inspected := make([]json.RawMessage, 0, len(names))
+	for _, name := range names {
+		data, err := containerManager.Inspect(host, name)
+		if err != nil {
+			return err
+		}
+		var entries []json.RawMessage
+		if err := json.Unmarshal([]byte(data), &entries); err != nil {
+			return fmt.Errorf("failed to parse inspect output of %s: %w", name, err)
+		}
+		inspected = append(inspected, entries...)
+	}
+
+	redacted, err := redactInspect(inspected)
+	if err != nil {
+		return err
+	}
+	fmt.Println(string(redacted))
+	return nil
+}
+
+func describeAppRole() string {
+	if appRole == "" {
+		return cfg.Service
+	}
+	return fmt.Sprintf("%s role %s", cfg.Service, appRole)
+}
+
+// containerKind names what an Azud-managed container runs, from its labels.
+func containerKind(labels map[string]string) string {
+	switch {
+	case labels["azud.type"] == "proxy":
+		return "proxy"
+	case labels["azud.accessory"] != "":
+		return "accessory " + labels["azud.accessory"]
+	case labels["azud.cron"] != "":
+		return "cron " + labels["azud.cron"]
+	case labels["azud.role"] != "":
+		return labels["azud.role"]
+	default:
+		return "-"
+	}
+}
+
+// splitContainerStatus splits a podman ps status such as "Up 2 hours
+// (healthy)" into the uptime and health. Stopped containers have neither.
+func splitContainerStatus(status string) (string, string) {
+	status = strings.TrimSpace(status)
+	rest, ok := strings.CutPrefix(status, "Up ")
+	if !ok {
+		return "-", "-"
+	}
+	health := "-"
+	if open := strings.LastIndex(rest, " ("); open >= 0 && strings.HasSuffix(rest, ")") {
+		health = rest[open+2 : len(rest)-1]
+		rest = rest[:open]
+	}
+	return rest, health
+}
+
+// redactInspect replaces environment values in podman inspect entries: the
+// Config.Env list and -e/--env arguments of Config.CreateCommand.
+func redactInspect(entries []json.RawMessage) ([]byte, error) {
+	redacted := make([]map[string]any, 0, len(entries))
+	for _, entry := range entries {
+		var container map[string]any
+		if err := json.Unmarshal(entry, &container); err != nil {
+			return nil, fmt.Errorf("failed to parse inspect output: %w", err)
+		}
+		if containerConfig, ok := container["Config"].(map[string]any); ok {
+			if env, ok := containerConfig["Env"].([]any); ok {
+				for i, value := range env {
+					if s, ok := value.(string); ok {
+						env[i] = redactEnvAssignment(s)
+					}
+				}
+			}
+			if command, ok := containerConfig["CreateCommand"].([]any); ok {
+				redactCreateCommand(command)
+			}
+		}
+		redacted = append(redacted, container)
+	}
+	return json.MarshalIndent(redacted, "", "    ")
+}
+
+func redactCreateCommand(command []any) {
+	for i := 0; i < len(command); i++ {
+		arg, _ := command[i].(string)
+		switch {
+		case arg == "-e" || arg == "--env":
+			if i+1 < len(command) {
+				if value, ok := command[i+1].(string); ok {
+					command[i+1] = redactEnvAssignment(value)
+				}
+				i++
+			}
+		case strings.HasPrefix(arg, "--env="):
+			command[i] = "--env=" + redactEnvAssignment(strings.TrimPrefix(arg, "--env="))
+		case strings.HasPrefix(arg, "-e") && len(arg) > 2:
+			command[i] = "-e" + redactEnvAssignment(arg[2:])
+		}
+	}
+}
+
+// redactEnvAssignment keeps the name of a KEY=value assignment. A bare KEY
+// inherits its value from the host environment and carries no secret.
+func redactEnvAssignment(assignment string) string {
+	name, _, ok := strings.Cut(assignment, "=")
+	if !ok {
+		return assignment
+	}
+	return name + "=" + redactedValue
+}
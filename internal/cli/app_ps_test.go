@@ -0,0 +1,82 @@
+package cli
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestSplitContainerStatus(t *testing.T) {
+	tests := []struct {
+		status, uptime, health string
+	}{
+		{"Up 2 hours (healthy)", "2 hours", "healthy"},
+		{"Up About a minute (starting)", "About a minute", "starting"},
+		{"Up 5 seconds", "5 seconds", "-"},
+		{"Exited (0) 3 minutes ago", "-", "-"},
+		{"Created", "-", "-"},
+	}
+	for _, tt := range tests {
+		uptime, health := splitContainerStatus(tt.status)
+		if uptime != tt.uptime || health != tt.health {
+			t.Errorf("splitContainerStatus(%q) = %q, %q; want %q, %q", tt.status, uptime, health, tt.uptime, tt.health)
+		}
+	}
+}
+
+func TestContainerKind(t *testing.T) {
+	tests := []struct {
+		labels map[string]string
+		want   string
+	}{
+		{map[string]string{"azud.type": "proxy"}, "proxy"},
+		{map[string]string{"azud.accessory": "db", "azud.service": "shop"}, "accessory db"},
+		{map[string]string{"azud.cron": "cleanup", "azud.service": "shop"}, "cron cleanup"},
+		{map[string]string{"azud.role": "worker"}, "worker"},
+		{map[string]string{}, "-"},
+	}
+	for _, tt := range tests {
+		if got := containerKind(tt.labels); got != tt.want {
+			t.Errorf("containerKind(%v) = %q, want %q", tt.labels, got, tt.want)
+		}
+	}
+}
+
+func TestRedactInspect(t *testing.T) {
+	entry := json.RawMessage(`{
+		"Name": "shop",
+		"Config": {
+			"Env": ["PATH=/usr/bin", "DATABASE_URL=postgres://u:secret@db/shop"],
+			"CreateCommand": ["podman", "run", "-e", "API_KEY=secret", "--env=TOKEN=secret", "-eOTHER=secret", "-e", "INHERITED", "--name", "shop", "img"]
+		}
+	}`)
+
+	out, err := redactInspect([]json.RawMessage{entry})
+	if err != nil {
+		t.Fatalf("redactInspect: %v", err)
+	}
+	if strings.Contains(string(out), "secret") {
+		t.Fatalf("secret value left in output:\n%s", out)
+	}
+
+	var containers []struct {
+		Name   string
+		Config struct {
+			Env           []string
+			CreateCommand []string
+		}
+	}
+	if err := json.Unmarshal(out, &containers); err != nil {
+		t.Fatalf("output is not JSON: %v", err)
+	}
+	if len(containers) != 1 || containers[0].Name != "shop" {
+		t.Fatalf("unexpected containers: %+v", containers)
+	}
+	if got := strings.Join(containers[0].Config.Env, " "); got != "PATH=<redacted> DATABASE_URL=<redacted>" {
+		t.Fatalf("env = %q", got)
+	}
+	want := "podman run -e API_KEY=<redacted> --env=TOKEN=<redacted> -eOTHER=<redacted> -e INHERITED --name shop img"
+	if got := strings.Join(containers[0].Config.CreateCommand, " "); got != want {
+		t.Fatalf("create command = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,331 @@
+package cli
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/lemonity-org/azud/internal/deploy"
+	"github.com/lemonity-org/azud/internal/output"
+	"github.com/lemonity-org/azud/internal/podman"
+	"github.com/lemonity-org/azud/internal/shell"
+	"github.com/lemonity-org/azud/internal/ssh"
+)
+
+var appRunCmd = &cobra.Command{
+	Use:   "run [flags] -- command",
+	Short: "Run a one-off command in a new application container",
+	Long: `Run a one-off command, such as a migration, seed, or script, in a new
+container started from the image the role currently runs on the host.
+
+The container gets the role's environment, secrets, volumes, and networks,
+streams its output, and is removed when the command exits. Azud exits with
+the command's exit code. Unlike 'azud app exec', the command does not share
+a running container, so it cannot disturb the replicas serving traffic.
+
+With --at the command is not run now: a one-shot systemd timer on the host
+runs it at the given time, with the image deployed when it was scheduled.
+A time without a zone is the host's local time. Scheduled runs are listed
+with --list and canceled with --cancel. Timers do not survive a reboot.
+
+Example:
+  azud app run -- bin/rails db:seed
+  azud app run --role worker -- ./scripts/backfill.sh
+  azud app run --at 2025-06-01T02:00 -- bin/rails reports:monthly
+  azud app run --list
+  azud app run --cancel shop-run-1748743200`,
+	RunE: runAppRun,
+}
+
+var (
+	appRunAt     string
+	appRunList   bool
+	appRunCancel string
+)
+
+// scheduledRun is a one-off command waiting on a systemd timer.
+type scheduledRun struct {
+	ID          string
+	Description string
+	Next        string
+}
+
+func init() {
+	appRunCmd.Flags().StringVar(&appHost, "host", "", "Specific host")
+	appRunCmd.Flags().StringVar(&appRole, "role", "", "Specific role (default: web)")
+	appRunCmd.Flags().StringVar(&appRunAt, "at", "", "Schedule the command for a time, e.g. 2025-06-01T02:00")
+	appRunCmd.Flags().BoolVar(&appRunList, "list", false, "List scheduled runs")
+	appRunCmd.Flags().StringVar(&appRunCancel, "cancel", "", "Cancel the scheduled run with this ID")
+	appRunCmd.MarkFlagsMutuallyExclusive("at", "list", "cancel")
+
+	appCmd.AddCommand(appRunCmd)
+}
+
+func runAppRun(cmd *cobra.Command, args []string) error {
+	output.SetVerbose(verbose)
+	log := output.DefaultLogger
+
+	role := defaultAppRole()
+	if !cfg.HasRole(role) {
+		return fmt.Errorf("role %s not found", role)
+	}
+	hosts := getSingleRoleAppHosts()
+	if len(hosts) == 0 {
+		return fmt.Errorf("no matching host configured for role %s", role)
+	}
+
+	if appRunList || appRunCancel != "" {
+		if len(args) > 0 {
+			return fmt.Errorf("--list and --cancel take no command")
+		}
+		sshClient := createSSHClient()
+		defer func() { _ = sshClient.Close() }()
+		if appRunList {
+			return listScheduledRuns(sshClient, hosts)
+		}
+		return cancelScheduledRun(sshClient, hosts, appRunCancel)
+	}
+
+	if len(args) == 0 {
+		return fmt.Errorf("no command specified")
+	}
+	var calendar string
+	if appRunAt != "" {
+		var err error
+		if calendar, err = onCalendarSpec(appRunAt, time.Now()); err != nil {
+			return err
+		}
+	}
+	host := hosts[0]
+
+	sshClient := createSSHClient()
+	defer func() { _ = sshClient.Close() }()
+
+	containerManager := podman.NewContainerManager(podman.NewClient(sshClient))
+
+	stableName := deploy.RoleContainerName(cfg, role)
+	image, err := containerManager.InspectFormat(host, stableName, "{{.ImageName}}")
+	if err != nil {
+		return fmt.Errorf("failed to find the deployed image of %s on %s: %w", stableName, host, err)
+	}
+	image = strings.TrimSpace(image)
+	if image == "" {
+		return fmt.Errorf("container %s on %s has no image", stableName, host)
+	}
+	if err := ensureRoleSecretsFile(sshClient, []string{host}, role); err != nil {
+		return err
+	}
+
+	name := fmt.Sprintf("%s-run-%d", cfg.Service, time.Now().Unix())
+	containerCfg := deploy.NewOneOffContainerConfig(cfg, image, name, role, host, args)
+
+	if calendar != "" {
+		if cfg.Podman.Rootless {
+			if err := enableLinger(sshClient, host, cfg.SSH.User); err != nil {
+				return fmt.Errorf("failed to enable linger on %s: %w", host, err)
+			}
+		}
+		description := "azud app run: " + strings.Join(args, " ")
+		result, err := sshClient.Execute(host, scheduleRunCommand(name, calendar, description, containerCfg.BuildRunCommand()))
+		if err != nil {
+			return fmt.Errorf("failed to schedule run on %s: %w", host, err)
+		}
+		if result.ExitCode != 0 {
+			return fmt.Errorf("failed to schedule run on %s: %s", host, strings.TrimSpace(result.Stderr))
+		}
+		log.HostSuccess(host, "Scheduled %s for %s as %s", strings.Join(args, " "), calendar, name)
+		log.Info("Its output goes to the journal: journalctl %s-u %s", systemdUserFlag(), name)
+		return nil
+	}
+
+	log.Host(host, "Running %s in %s", strings.Join(args, " "), image)
+	if err := containerManager.RunStream(host, containerCfg, os.Stdout, os.Stderr); err != nil {
+		if code, ok := ssh.ExitStatus(err); ok {
+			return &ExitError{Code: code}
+		}
+		return fmt.Errorf("run failed: %w", err)
+	}
+	return nil
+}
+
+func listScheduledRuns(sshClient *ssh.Client, hosts []string) error {
+	log := output.DefaultLogger
+
+	var rows [][]string
+	var listErrors []string
+	for _, host := range hosts {
+		runs, err := scheduledRuns(sshClient, host)
+		if err != nil {
+			log.HostError(host, "Failed to list scheduled runs: %v", err)
+			listErrors = append(listErrors, fmt.Sprintf("%s: %v", host, err))
+			continue
+		}
+		for _, run := range runs {
+			rows = append(rows, []string{host, run.ID, run.Next, run.Description})
+		}
+	}
+	if len(rows) == 0 {
+		log.Info("No scheduled runs")
+	} else {
+		log.Table([]string{"Host", "ID", "Runs at", "Command"}, rows)
+	}
+	if len(listErrors) > 0 {
+		return fmt.Errorf("failed to list scheduled runs: %s", strings.Join(listErrors, "; "))
+	}
+	return nil
+}
+
+func cancelScheduledRun(sshClient *ssh.Client, hosts []string, id string) error {
+	log := output.DefaultLogger
+
+	if !isScheduledRunID(id) {
+		return fmt.Errorf("invalid run ID %q; see 'azud app run --list'", id)
+	}
+	for _, host := range hosts {
+		runs, err := scheduledRuns(sshClient, host)
+		if err != nil {
+			return fmt.Errorf("failed to list scheduled runs on %s: %w", host, err)
+		}
+		found := false
+		for _, run := range runs {
+			found = found || run.ID == id
+		}
+		if !found {
+			continue
+		}
+		result, err := sshClient.Execute(host, systemctlCommand("stop "+shell.Quote(id+".timer")))
+		if err != nil {
+			return fmt.Errorf("failed to cancel %s on %s: %w", id, host, err)
+		}
+		if result.ExitCode != 0 {
+			return fmt.Errorf("failed to cancel %s on %s: %s", id, host, strings.TrimSpace(result.Stderr))
+		}
+		log.HostSuccess(host, "Canceled %s", id)
+		return nil
+	}
+	return fmt.Errorf("no scheduled run %s found", id)
+}
+
+func scheduledRuns(sshClient *ssh.Client, host string) ([]scheduledRun, error) {
+	pattern := shell.Quote(cfg.Service + "-run-*.timer")
+	result, err := sshClient.Execute(host, systemctlCommand("show --property=Id,Description,NextElapseUSecRealtime -- "+pattern))
+	if err != nil {
+		return nil, err
+	}
+	if result.ExitCode != 0 {
+		return nil, fmt.Errorf("%s", strings.TrimSpace(result.Stderr))
+	}
+	return parseScheduledRuns(result.Stdout), nil
+}
+
+// parseScheduledRuns parses the `systemctl show` output of run timers, one
+// block of properties per timer, into runs ordered by ID.
+func parseScheduledRuns(out string) []scheduledRun {
+	var runs []scheduledRun
+	for _, block := range strings.Split(strings.TrimSpace(out), "\n\n") {
+		var run scheduledRun
+		for _, line := range strings.Split(block, "\n") {
+			key, value, _ := strings.Cut(strings.TrimSpace(line), "=")
+			switch key {
+			case "Id":
+				run.ID = strings.TrimSuffix(value, ".timer")
+			case "Description":
+				run.Description = strings.TrimPrefix(value, "azud app run: ")
+			case "NextElapseUSecRealtime":
+				run.Next = value
+			}
+		}
+		if isScheduledRunID(run.ID) {
+			runs = append(runs, run)
+		}
+	}
+	sort.Slice(runs, func(i, j int) bool { return runs[i].ID < runs[j].ID })
+	return runs
+}
+
+// isScheduledRunID reports whether id names a run of this service, which
+// keeps --cancel from stopping unrelated units.
+func isScheduledRunID(id string) bool {
+	suffix, ok := strings.CutPrefix(id, cfg.Service+"-run-")
+	if !ok {
+		return false
+	}
+	_, err := strconv.ParseUint(suffix, 10, 64)
+	return err == nil
+}
+
+// scheduleRunLayouts are the --at formats; a time with a zone is converted
+// to UTC, one without is left to the host's local time.
+var scheduleRunLayouts = []struct {
+	layout string
+	zoned  bool
+}{
+	{time.RFC3339, true},
+	{"2006-01-02T15:04Z07:00", true},
+	{"2006-01-02T15:04:05", false},
+	{"2006-01-02T15:04", false},
+	{"2006-01-02 15:04:05", false},
+	{"2006-01-02 15:04", false},
+}
+
+// onCalendarSpec converts an --at time into a systemd OnCalendar value.
+func onCalendarSpec(at string, now time.Time) (string, error) {
+	at = strings.TrimSpace(at)
+	for _, l := range scheduleRunLayouts {
+		t, err := time.Parse(l.layout, at)
+		if err != nil {
+			continue
+		}
+		if !l.zoned {
+			return t.Format("2006-01-02 15:04:05"), nil
+		}
+		if !t.After(now) {
+			return "", fmt.Errorf("--at %s is in the past", at)
+		}
+		return t.UTC().Format("2006-01-02 15:04:05") + " UTC", nil
+	}
+	return "", fmt.Errorf("invalid --at %q: use a time such as 2025-06-01T02:00 or 2025-06-01T02:00:00Z", at)
+}
+
+// scheduleRunCommand returns the command that registers a transient timer
+// running runCmd once at calendar. The units are named id and are unloaded
+// once the run ends, whatever its result.
+func scheduleRunCommand(id, calendar, description, runCmd string) string {
+	args := []string{
+		"--unit=" + shell.Quote(id),
+		"--description=" + shell.Quote(description),
+		"--on-calendar=" + shell.Quote(calendar),
+		"--timer-property=AccuracySec=1s",
+		"--collect",
+	}
+	if !cfg.Podman.Rootless && cfg.SSH.User != "root" {
+		// The run command expands $HOME to the deploy user's env files.
+		args = append(args, `--setenv=HOME="$HOME"`)
+	}
+	return fmt.Sprintf("%ssystemd-run %s%s /bin/sh -c %s", systemdSudoPrefix(), systemdUserFlag(), strings.Join(args, " "), shell.Quote(runCmd))
+}
+
+// systemctlCommand returns a systemctl command acting on the service
+// manager that runs the app's containers.
+func systemctlCommand(action string) string {
+	return systemdSudoPrefix() + "systemctl " + systemdUserFlag() + action
+}
+
+func systemdUserFlag() string {
+	if cfg.Podman.Rootless {
+		return "--user "
+	}
+	return ""
+}
+
+func systemdSudoPrefix() string {
+	if !cfg.Podman.Rootless && cfg.SSH.User != "root" {
+		return "sudo -n "
+	}
+	return ""
+}
@@ -0,0 +1,72 @@
+package cli
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/lemonity-org/azud/internal/config"
+)
+
+func TestOnCalendarSpec(t *testing.T) {
+	now := time.Date(2025, 5, 1, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		at, want string
+		wantErr  bool
+	}{
+		{at: "2025-06-01T02:00", want: "2025-06-01 02:00:00"},
+		{at: "2025-06-01 02:00:30", want: "2025-06-01 02:00:30"},
+		{at: "2025-06-01T02:00:00+02:00", want: "2025-06-01 00:00:00 UTC"},
+		{at: "2025-06-01T02:00Z", want: "2025-06-01 02:00:00 UTC"},
+		{at: "2025-04-01T02:00:00Z", wantErr: true},
+		{at: "tomorrow", wantErr: true},
+		{at: "2025-06-01", wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := onCalendarSpec(tt.at, now)
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("onCalendarSpec(%q) = %q, %v; want %q, error %v", tt.at, got, err, tt.want, tt.wantErr)
+		}
+	}
+}
+
+func TestScheduledRuns(t *testing.T) {
+	oldCfg := cfg
+	t.Cleanup(func() { cfg = oldCfg })
+	cfg = &config.Config{Service: "shop", SSH: config.SSHConfig{User: "deploy"}, Podman: config.PodmanConfig{Rootless: true}}
+
+	out := "Id=shop-run-200.timer\nDescription=azud app run: bin/seed\nNextElapseUSecRealtime=Sun 2025-06-01 02:00:00 UTC\n\n" +
+		"NextElapseUSecRealtime=Sat 2025-05-31 02:00:00 UTC\nId=shop-run-100.timer\nDescription=azud app run: bin/report\n\n" +
+		"Id=shop-run-other.timer\nDescription=not ours\n"
+	want := []scheduledRun{
+		{ID: "shop-run-100", Description: "bin/report", Next: "Sat 2025-05-31 02:00:00 UTC"},
+		{ID: "shop-run-200", Description: "bin/seed", Next: "Sun 2025-06-01 02:00:00 UTC"},
+	}
+	if got := parseScheduledRuns(out); !reflect.DeepEqual(got, want) {
+		t.Errorf("parseScheduledRuns() = %+v, want %+v", got, want)
+	}
+	if got := parseScheduledRuns(""); len(got) != 0 {
+		t.Errorf("parseScheduledRuns(\"\") = %+v, want none", got)
+	}
+	for _, id := range []string{"web-run-1", "shop-run-", "shop-run-1;reboot"} {
+		if isScheduledRunID(id) {
+			t.Errorf("isScheduledRunID(%q) = true", id)
+		}
+	}
+
+	wantCmd := "systemd-run --user --unit=shop-run-100 --description='azud app run: bin/seed' --on-calendar='2025-06-01 02:00:00' " +
+		"--timer-property=AccuracySec=1s --collect /bin/sh -c 'podman run --rm x'"
+	if got := scheduleRunCommand("shop-run-100", "2025-06-01 02:00:00", "azud app run: bin/seed", "podman run --rm x"); got != wantCmd {
+		t.Errorf("scheduleRunCommand() rootless = %q, want %q", got, wantCmd)
+	}
+
+	cfg.Podman.Rootless = false
+	wantCmd = "sudo -n systemd-run --unit=shop-run-100 --description='azud app run: bin/seed' --on-calendar='2025-06-01 02:00:00' " +
+		"--timer-property=AccuracySec=1s --collect --setenv=HOME=\"$HOME\" /bin/sh -c 'podman run --rm x'"
+	if got := scheduleRunCommand("shop-run-100", "2025-06-01 02:00:00", "azud app run: bin/seed", "podman run --rm x"); got != wantCmd {
+		t.Errorf("scheduleRunCommand() rootful = %q, want %q", got, wantCmd)
+	}
+	if got := systemctlCommand("stop x.timer"); got != "sudo -n systemctl stop x.timer" {
+		t.Errorf("systemctlCommand() = %q", got)
+	}
+}
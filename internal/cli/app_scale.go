@@ -0,0 +1,71 @@
+package cli
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+
+	"github.com/lemonity-org/azud/internal/output"
+)
+
+var appScaleCmd = &cobra.Command{
+	Use:   "scale",
+	Short: "Set the number of running replicas on one host",
+	Long: `Start or stop replica containers of a role on one host until it runs
+the requested number, without a full deploy. New replicas are health-checked
+before they join the proxy upstreams; removed replicas leave the proxy before
+they stop. This is meant as a quick response to load spikes.
+
+This is the per-host form of 'azud scale role=count --host <host>'.
+
+Examples:
+  azud app scale --host 192.168.1.1 --replicas 3
+  azud app scale --host 192.168.1.2 --replicas 2 --role worker`,
+	Args: cobra.NoArgs,
+	RunE: runAppScale,
+}
+
+var (
+	appScaleHost     string
+	appScaleRole     string
+	appScaleReplicas int
+)
+
+func init() {
+	appScaleCmd.Flags().StringVar(&appScaleHost, "host", "", "Host to scale (required)")
+	appScaleCmd.Flags().StringVar(&appScaleRole, "role", "web", "Role to scale")
+	appScaleCmd.Flags().IntVar(&appScaleReplicas, "replicas", 0, "Number of replicas to run on the host (required)")
+	_ = appScaleCmd.MarkFlagRequired("host")
+	_ = appScaleCmd.MarkFlagRequired("replicas")
+
+	appCmd.AddCommand(appScaleCmd)
+}
+
+func runAppScale(cmd *cobra.Command, args []string) error {
+	output.SetVerbose(verbose)
+
+	if err := validateAppScale(appScaleHost, appScaleRole, appScaleReplicas); err != nil {
+		return err
+	}
+
+	return applyScale(map[string]scaleOperation{
+		appScaleRole: {value: appScaleReplicas},
+	}, appScaleHost)
+}
+
+// validateAppScale checks that replicas of role can be set on host.
+func validateAppScale(host, role string, replicas int) error {
+	if cfg.UsesProxyTier() {
+		return fmt.Errorf("scaling is not supported with proxy.hosts_role")
+	}
+	if replicas < 1 {
+		return fmt.Errorf("--replicas must be at least 1; use 'azud app stop' to stop a host")
+	}
+	if !cfg.HasRole(role) {
+		return fmt.Errorf("role %s not found", role)
+	}
+	if !containsString(cfg.GetRoleHosts(role), host) {
+		return fmt.Errorf("host %s is not configured for role %s", host, role)
+	}
+	return nil
+}
@@ -0,0 +1,67 @@
+package cli
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/lemonity-org/azud/internal/config"
+)
+
+func TestValidateAppScale(t *testing.T) {
+	oldCfg := cfg
+	t.Cleanup(func() { cfg = oldCfg })
+	cfg = &config.Config{
+		Service: "shop",
+		Servers: map[string]config.RoleConfig{
+			"web":    {Hosts: []string{"web-1", "web-2"}},
+			"worker": {Hosts: []string{"web-1"}},
+		},
+	}
+
+	for _, tt := range []struct {
+		host, role string
+		replicas   int
+		wantErr    string
+	}{
+		{"web-2", "web", 3, ""},
+		{"web-1", "worker", 1, ""},
+		{"web-1", "web", 0, "at least 1"},
+		{"web-1", "jobs", 2, "role jobs not found"},
+		{"web-2", "worker", 2, "host web-2 is not configured for role worker"},
+		{"db-1", "web", 2, "host db-1 is not configured for role web"},
+	} {
+		err := validateAppScale(tt.host, tt.role, tt.replicas)
+		if tt.wantErr == "" && err != nil || tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)) {
+			t.Errorf("validateAppScale(%s, %s, %d) = %v, want %q", tt.host, tt.role, tt.replicas, err, tt.wantErr)
+		}
+	}
+
+	cfg.Proxy.HostsRole = "lb"
+	if err := validateAppScale("web-1", "web", 2); err == nil || !strings.Contains(err.Error(), "proxy.hosts_role") {
+		t.Fatalf("validateAppScale() with a load-balancer tier = %v", err)
+	}
+}
+
+func TestScaleHosts(t *testing.T) {
+	oldCfg := cfg
+	t.Cleanup(func() { cfg = oldCfg })
+	cfg = &config.Config{
+		Servers: map[string]config.RoleConfig{
+			"web":    {Hosts: []string{"web-1", "web-2"}},
+			"worker": {Hosts: []string{"web-1"}},
+		},
+	}
+
+	hosts, err := scaleHosts("web", "")
+	if err != nil || !reflect.DeepEqual(hosts, []string{"web-1", "web-2"}) {
+		t.Fatalf("scaleHosts(web) = %v, %v; want every web host", hosts, err)
+	}
+	hosts, err = scaleHosts("web", "web-2")
+	if err != nil || !reflect.DeepEqual(hosts, []string{"web-2"}) {
+		t.Fatalf("scaleHosts(web, web-2) = %v, %v; want only web-2", hosts, err)
+	}
+	if hosts, err := scaleHosts("worker", "web-2"); err == nil {
+		t.Fatalf("scaleHosts(worker, web-2) = %v, want an error for a host outside the role", hosts)
+	}
+}
@@ -0,0 +1,191 @@
+package cli
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/lemonity-org/azud/internal/output"
+	"github.com/lemonity-org/azud/internal/podman"
+)
+
+var appStatsCmd = &cobra.Command{
+	Use:   "stats",
+	Short: "Show CPU, memory, and network usage of Azud-managed containers",
+	Long: `Sample podman stats for every running Azud-managed container on the
+configured hosts and show CPU, memory, network, and block I/O per container.
+
+With --watch the table is refreshed every --interval until interrupted.
+With --format json a JSON array of samples is printed instead; combined with
+--watch, each refresh prints one array per line.
+
+Example:
+  azud app stats
+  azud app stats --watch
+  azud app stats --format json --host 192.168.1.1`,
+	Args: cobra.NoArgs,
+	RunE: runAppStats,
+}
+
+var (
+	appStatsWatch    bool
+	appStatsInterval time.Duration
+	appStatsFormat   string
+)
+
+// hostContainerStats is a stats sample tagged with the host it was taken on.
+type hostContainerStats struct {
+	Host string `json:"host"`
+	podman.ContainerStats
+}
+
+func init() {
+	appStatsCmd.Flags().StringVar(&appHost, "host", "", "Specific host")
+	appStatsCmd.Flags().BoolVarP(&appStatsWatch, "watch", "w", false, "Refresh until interrupted")
+	appStatsCmd.Flags().DurationVar(&appStatsInterval, "interval", 2*time.Second, "Refresh interval with --watch")
+	appStatsCmd.Flags().StringVar(&appStatsFormat, "format", "table", "Output format (table, json)")
+
+	appCmd.AddCommand(appStatsCmd)
+}
+
+func runAppStats(cmd *cobra.Command, args []string) error {
+	output.SetVerbose(verbose)
+	log := output.DefaultLogger
+
+	if appStatsFormat != "table" && appStatsFormat != "json" {
+		return fmt.Errorf("invalid --format %q: must be table or json", appStatsFormat)
+	}
+	if appStatsWatch && appStatsInterval <= 0 {
+		return fmt.Errorf("--interval must be positive")
+	}
+
+	hosts := portsHosts()
+	if appHost != "" {
+		if !containsString(hosts, appHost) {
+			return fmt.Errorf("host %s is not configured", appHost)
+		}
+		hosts = []string{appHost}
+	}
+
+	sshClient := createSSHClient()
+	defer func() { _ = sshClient.Close() }()
+	containerManager := podman.NewContainerManager(podman.NewClient(sshClient))
+
+	if !appStatsWatch {
+		samples, statsErrors := sampleAppStats(containerManager, hosts)
+		if err := renderAppStats(log, samples, false); err != nil {
+			return err
+		}
+		if len(statsErrors) > 0 {
+			return fmt.Errorf("failed to get stats: %s", strings.Join(statsErrors, "; "))
+		}
+		return nil
+	}
+
+	ctx := cmd.Context()
+	ticker := time.NewTicker(appStatsInterval)
+	defer ticker.Stop()
+	for {
+		samples, _ := sampleAppStats(containerManager, hosts)
+		if appStatsFormat == "table" {
+			log.ClearScreen()
+			log.Info("Every %s, %s (Ctrl+C to stop)", appStatsInterval, time.Now().Format("15:04:05"))
+		}
+		if err := renderAppStats(log, samples, true); err != nil {
+			return err
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+// sampleAppStats samples every host in parallel and returns the samples in
+// host order. Failed hosts are reported and listed in the returned errors.
+func sampleAppStats(containerManager *podman.ContainerManager, hosts []string) ([]hostContainerStats, []string) {
+	results := make([][]hostContainerStats, len(hosts))
+	errs := make([]error, len(hosts))
+
+	var wg sync.WaitGroup
+	for i, host := range hosts {
+		wg.Add(1)
+		go func(i int, host string) {
+			defer wg.Done()
+			results[i], errs[i] = sampleHostStats(containerManager, host)
+		}(i, host)
+	}
+	wg.Wait()
+
+	var samples []hostContainerStats
+	var statsErrors []string
+	for i, host := range hosts {
+		if errs[i] != nil {
+			output.DefaultLogger.HostError(host, "%v", errs[i])
+			statsErrors = append(statsErrors, fmt.Sprintf("%s: %v", host, errs[i]))
+			continue
+		}
+		samples = append(samples, results[i]...)
+	}
+	return samples, statsErrors
+}
+
+func sampleHostStats(containerManager *podman.ContainerManager, host string) ([]hostContainerStats, error) {
+	containers, err := containerManager.List(host, false, map[string]string{"label": "azud.managed=true"})
+	if err != nil {
+		return nil, err
+	}
+	names := make([]string, 0, len(containers))
+	for _, c := range containers {
+		names = append(names, c.Name)
+	}
+
+	stats, err := containerManager.StatsSample(host, names)
+	if err != nil {
+		return nil, err
+	}
+	samples := make([]hostContainerStats, 0, len(stats))
+	for _, s := range stats {
+		samples = append(samples, hostContainerStats{Host: host, ContainerStats: s})
+	}
+	return samples, nil
+}
+
+// renderAppStats prints samples in the selected format. Watched JSON output
+// is one compact array per line so scripts can consume it as a stream.
+func renderAppStats(log *output.Logger, samples []hostContainerStats, watch bool) error {
+	if appStatsFormat == "json" {
+		if samples == nil {
+			samples = []hostContainerStats{}
+		}
+		var data []byte
+		var err error
+		if watch {
+			data, err = json.Marshal(samples)
+		} else {
+			data, err = json.MarshalIndent(samples, "", "  ")
+		}
+		if err != nil {
+			return err
+		}
+		log.Println("%s", data)
+		return nil
+	}
+
+	if len(samples) == 0 {
+		log.Info("No running Azud-managed containers")
+		return nil
+	}
+	rows := make([][]string, 0, len(samples))
+	for _, s := range samples {
+		rows = append(rows, []string{s.Host, s.Name, s.CPU, s.MemUsage, s.MemPercent, s.NetIO, s.BlockIO})
+	}
+	log.Table([]string{"Host", "Container", "CPU", "Memory", "Mem %", "Net I/O", "Block I/O"}, rows)
+	return nil
+}
@@ -1,6 +1,7 @@
 package cli
 
 import (
+	"context"
 	"fmt"
 	"os"
 	"os/exec"
@@ -12,6 +13,7 @@ import (
 	"github.com/spf13/cobra"
 
 	"github.com/lemonity-org/azud/internal/config"
+	"github.com/lemonity-org/azud/internal/deploy"
 	"github.com/lemonity-org/azud/internal/output"
 	"github.com/lemonity-org/azud/internal/podman"
 	"github.com/lemonity-org/azud/internal/shell"
@@ -29,32 +31,49 @@ pushes it to the configured registry.
 Example:
   azud build                    # Build and push
   azud build --no-push          # Build only, don't push
-  azud build --no-cache         # Build without cache`,
+  azud build --no-cache         # Build without cache
+  azud build --cache-only       # Push only the build cache (CI warm-up)`,
 	RunE: runBuild,
 }
 
 var (
-	buildNoPush  bool
-	buildNoCache bool
-	buildPull    bool
+	buildNoPush    bool
+	buildNoCache   bool
+	buildPull      bool
+	buildCacheOnly bool
 )
 
 func init() {
 	buildCmd.Flags().BoolVar(&buildNoPush, "no-push", false, "Don't push the image after building")
 	buildCmd.Flags().BoolVar(&buildNoCache, "no-cache", false, "Don't use cache when building")
 	buildCmd.Flags().BoolVar(&buildPull, "pull", false, "Always pull the base image")
+	buildCmd.Flags().BoolVar(&buildCacheOnly, "cache-only", false, "Build and push only the registry build cache, without tagging an image")
 
 	rootCmd.AddCommand(buildCmd)
 }
 
-func runBuild(cmd *cobra.Command, args []string) error {
+func runBuild(cmd *cobra.Command, args []string) (err error) {
 	output.SetVerbose(verbose)
 	log := output.DefaultLogger
+	dest := GetDestination()
+	placeholders := buildPlaceholders(dest)
+	resolveBuildTemplates(placeholders)
+	if buildCacheOnly {
+		return runBuildCacheOnly()
+	}
 	timer := log.NewTimer("Build")
 
-	// Generate version tag using template (supports {destination}, {version}, {timestamp})
-	dest := GetDestination()
-	imageTag, err := generateImageTag(cfg.Image, dest)
+	builtGitSHA = ""
+	sourceSHA := cleanGitHeadSHA()
+	defer func() {
+		if err == nil {
+			builtGitSHA = sourceSHA
+		}
+	}()
+
+	// Generate version tag using template (supports {destination}, {version},
+	// {timestamp}, {git_sha}, {git_branch}, {build_date})
+	imageTag, err := imageTagFromTemplate(cfg.Image, placeholders)
 	if err != nil {
 		return err
 	}
@@ -72,23 +91,49 @@ func runBuild(cmd *cobra.Command, args []string) error {
 	hooks := newHookRunner()
 	hookCtx := newHookContext()
 	hookCtx.Image = imageTag
-	hookCtx.Version = generateVersion()
+	hookCtx.Version = placeholders["version"]
 	if err := hooks.Run(cmd.Context(), "pre-build", hookCtx); err != nil {
 		return fmt.Errorf("pre-build hook failed: %w", err)
 	}
 
+	// The proxy image with Caddy plugins is pushed before the application,
+	// so a deploy of the new image finds both in the registry.
+	if cfg.Proxy.ImageBuild.Enabled() && !buildNoPush {
+		if err := buildProxyImage(false); err != nil {
+			return err
+		}
+	}
+
 	multiarch := isMultiarchBuild()
 
+	// Several remote builders each build one architecture in parallel
+	if cfg.Builder.Remote.Parallel() {
+		if err := buildRemoteParallel(imageTag, latestTag); err != nil {
+			return err
+		}
+		if err := hooks.Run(cmd.Context(), "post-build", hookCtx); err != nil {
+			log.Warn("post-build hook failed: %v", err)
+		}
+		return buildSBOM(imageTag)
+	}
+
 	// Check if we should use remote builder
 	if cfg.Builder.Remote.Host != "" {
-		version := generateVersion()
-		if err := buildRemote(imageTag, latestTag, version, multiarch); err != nil {
+		if err := buildRemote(imageTag, latestTag, placeholders["version"], multiarch); err != nil {
 			return err
 		}
 		if err := hooks.Run(cmd.Context(), "post-build", hookCtx); err != nil {
 			log.Warn("post-build hook failed: %v", err)
 		}
-		return nil
+		return buildSBOM(imageTag)
+	}
+
+	// buildx pushes as part of the build, so it needs the login first.
+	pushedByBuild := cfg.Builder.Engine == "buildx" && !cfg.Builder.IsBuildpacks()
+	if pushedByBuild && !buildNoPush && buildRegistry().Username != "" {
+		if err := loginToRegistry(); err != nil {
+			return fmt.Errorf("registry login failed: %w", err)
+		}
 	}
 
 	// Build locally
@@ -102,7 +147,7 @@ func runBuild(cmd *cobra.Command, args []string) error {
 	}
 
 	// Push to registry
-	if !buildNoPush {
+	if !buildNoPush && !pushedByBuild {
 		log.Info("Pushing image to registry...")
 		if err := pushImage(imageTag, latestTag, multiarch); err != nil {
 			return err
@@ -110,22 +155,47 @@ func runBuild(cmd *cobra.Command, args []string) error {
 		log.Success("Image pushed successfully")
 	}
 
+	if err := buildSBOM(imageTag); err != nil {
+		return err
+	}
+
 	timer.Stop()
 	log.Success("Build complete: %s", imageTag)
 	return nil
 }
 
+// buildSBOM generates and attaches the SBOM when builder.sbom is enabled. The
+// SBOM describes the pushed image, so it is skipped with --no-push.
+func buildSBOM(imageTag string) error {
+	if !cfg.Builder.SBOM {
+		return nil
+	}
+	if buildNoPush {
+		output.DefaultLogger.Warn("Skipping SBOM generation: builder.sbom attaches the SBOM to the pushed image")
+		return nil
+	}
+	if err := generateSBOM(imageTag); err != nil {
+		return fmt.Errorf("SBOM generation failed: %w", err)
+	}
+	return nil
+}
+
 func buildLocal(imageTag, latestTag string, multiarch bool) error {
 	log := output.DefaultLogger
 
+	if cfg.Builder.IsBuildpacks() {
+		return buildPackLocal(imageTag, latestTag)
+	}
+
 	platforms, err := resolveBuildPlatforms(false)
 	if err != nil {
 		return err
 	}
 
 	cacheFrom, cacheTo := resolveCacheSpecs(cfg.Builder.Cache)
+	buildx := cfg.Builder.Engine == "buildx"
 
-	if multiarch {
+	if multiarch && !buildx {
 		buildConfig := &podman.ManifestBuildConfig{
 			BuildConfig: podman.BuildConfig{
 				Context:    cfg.Builder.Context,
@@ -133,6 +203,7 @@ func buildLocal(imageTag, latestTag string, multiarch bool) error {
 				Tag:        imageTag,
 				Tags:       []string{latestTag},
 				Args:       cfg.Builder.Args,
+				Labels:     cfg.Builder.Labels,
 				NoCache:    buildNoCache,
 				Pull:       buildPull,
 				Secrets:    cfg.Builder.Secrets,
@@ -152,7 +223,7 @@ func buildLocal(imageTag, latestTag string, multiarch bool) error {
 
 		for _, cmd := range commands {
 			log.Command(cmd)
-			buildCmd := exec.Command("sh", "-c", cmd)
+			buildCmd := shell.Command(context.Background(), cmd)
 			buildCmd.Stdout = os.Stdout
 			buildCmd.Stderr = os.Stderr
 			if err := buildCmd.Run(); err != nil {
@@ -163,8 +234,34 @@ func buildLocal(imageTag, latestTag string, multiarch bool) error {
 		return nil
 	}
 
-	// Prepare build arguments
+	if buildx && multiarch && buildNoPush {
+		log.Warn("Multi-platform buildx results are only kept in the build cache with --no-push")
+	}
+	args := localBuildArgs(imageTag, latestTag, platforms, cacheFrom, cacheTo, multiarch)
+
+	engine := buildEngine()
+	log.Info("Running %s build...", engine)
+	log.Command(engine + " " + strings.Join(args, " "))
+
+	// Execute build
+	buildCmd := exec.Command(engine, args...)
+	buildCmd.Stdout = os.Stdout
+	buildCmd.Stderr = os.Stderr
+
+	if err := buildCmd.Run(); err != nil {
+		return fmt.Errorf("build failed: %w", err)
+	}
+
+	return nil
+}
+
+// localBuildArgs returns the arguments for a local single-command build with
+// the configured engine: one platform, or every platform with buildx.
+func localBuildArgs(imageTag, latestTag string, platforms, cacheFrom []string, cacheTo string, multiarch bool) []string {
 	args := []string{"build"}
+	if cfg.Builder.Engine == "buildx" {
+		args = []string{"buildx", "build"}
+	}
 
 	// Dockerfile
 	dockerfile := cfg.Builder.Dockerfile
@@ -174,8 +271,10 @@ func buildLocal(imageTag, latestTag string, multiarch bool) error {
 	args = append(args, "-f", dockerfile)
 
 	// Tags
-	args = append(args, "-t", imageTag)
-	args = append(args, "-t", latestTag)
+	if imageTag != "" {
+		args = append(args, "-t", imageTag)
+		args = append(args, "-t", latestTag)
+	}
 
 	// Build args from config
 	buildArgKeys := make([]string, 0, len(cfg.Builder.Args))
@@ -187,9 +286,19 @@ func buildLocal(imageTag, latestTag string, multiarch bool) error {
 		value := cfg.Builder.Args[key]
 		args = append(args, "--build-arg", fmt.Sprintf("%s=%s", key, value))
 	}
+	labelKeys := make([]string, 0, len(cfg.Builder.Labels))
+	for key := range cfg.Builder.Labels {
+		labelKeys = append(labelKeys, key)
+	}
+	sort.Strings(labelKeys)
+	for _, key := range labelKeys {
+		args = append(args, "--label", fmt.Sprintf("%s=%s", key, cfg.Builder.Labels[key]))
+	}
 
 	// Platform
-	if arch := effectiveBuildArch(false); arch != "" {
+	if multiarch {
+		args = append(args, "--platform", strings.Join(platforms, ","))
+	} else if arch := effectiveBuildArch(false); arch != "" {
 		args = append(args, "--platform", fmt.Sprintf("linux/%s", arch))
 	}
 
@@ -222,6 +331,16 @@ func buildLocal(imageTag, latestTag string, multiarch bool) error {
 		args = append(args, "--pull")
 	}
 
+	// buildx keeps results in its builder: push them directly, or load a
+	// single-platform image into the local engine.
+	if cfg.Builder.Engine == "buildx" && !buildCacheOnly {
+		if !buildNoPush {
+			args = append(args, "--push")
+		} else if !multiarch {
+			args = append(args, "--load")
+		}
+	}
+
 	// Context
 	context := cfg.Builder.Context
 	if context == "" {
@@ -229,19 +348,7 @@ func buildLocal(imageTag, latestTag string, multiarch bool) error {
 	}
 	args = append(args, context)
 
-	log.Info("Running podman build...")
-	log.Command("podman " + strings.Join(args, " "))
-
-	// Execute build
-	buildCmd := exec.Command("podman", args...)
-	buildCmd.Stdout = os.Stdout
-	buildCmd.Stderr = os.Stderr
-
-	if err := buildCmd.Run(); err != nil {
-		return fmt.Errorf("build failed: %w", err)
-	}
-
-	return nil
+	return args
 }
 
 func buildRemote(imageTag, latestTag, version string, multiarch bool) error {
@@ -262,12 +369,28 @@ func buildRemote(imageTag, latestTag, version string, multiarch bool) error {
 	podmanClient := podman.NewClient(sshClient)
 	imageManager := podman.NewImageManager(podmanClient)
 
-	if cfg.Registry.Username != "" {
+	if buildRegistry().Username != "" {
 		if err := loginToRegistryRemote(sshClient, cfg.Builder.Remote.Host); err != nil {
 			return fmt.Errorf("remote registry login failed: %w", err)
 		}
 	}
 
+	if cfg.Builder.IsBuildpacks() {
+		packConfig := newPackBuildConfig(remoteContext, imageTag, latestTag, effectiveBuildArch(true))
+		if err := imageManager.PackBuild(cfg.Builder.Remote.Host, packConfig); err != nil {
+			return fmt.Errorf("remote build failed: %w", err)
+		}
+
+		if !buildNoPush {
+			if err := pushRemoteImage(sshClient, cfg.Builder.Remote.Host, imageTag, latestTag, false); err != nil {
+				return fmt.Errorf("remote push failed: %w", err)
+			}
+		}
+
+		log.Success("Remote build complete")
+		return nil
+	}
+
 	platforms, err := resolveBuildPlatforms(true)
 	if err != nil {
 		return err
@@ -282,6 +405,7 @@ func buildRemote(imageTag, latestTag, version string, multiarch bool) error {
 			Tag:        imageTag,
 			Tags:       []string{latestTag},
 			Args:       cfg.Builder.Args,
+			Labels:     cfg.Builder.Labels,
 			NoCache:    buildNoCache,
 			Pull:       buildPull,
 			Secrets:    cfg.Builder.Secrets,
@@ -294,7 +418,7 @@ func buildRemote(imageTag, latestTag, version string, multiarch bool) error {
 			buildConfig.Platform = fmt.Sprintf("linux/%s", arch)
 		}
 
-		if err := imageManager.Build(cfg.Builder.Remote.Host, buildConfig); err != nil {
+		if err := imageManager.BuildWithProgress(cfg.Builder.Remote.Host, buildConfig, deploy.HostProgress(log, cfg.Builder.Remote.Host)); err != nil {
 			return fmt.Errorf("remote build failed: %w", err)
 		}
 
@@ -315,6 +439,7 @@ func buildRemote(imageTag, latestTag, version string, multiarch bool) error {
 			Tag:        imageTag,
 			Tags:       []string{latestTag},
 			Args:       cfg.Builder.Args,
+			Labels:     cfg.Builder.Labels,
 			NoCache:    buildNoCache,
 			Pull:       buildPull,
 			Secrets:    cfg.Builder.Secrets,
@@ -323,11 +448,12 @@ func buildRemote(imageTag, latestTag, version string, multiarch bool) error {
 			Target:     cfg.Builder.Target,
 			SSH:        cfg.Builder.SSH,
 		},
-		Platforms: platforms,
-		Push:      !buildNoPush,
+		Platforms:   platforms,
+		Push:        !buildNoPush,
+		Compression: cfg.Builder.Compression,
 	}
 
-	if err := imageManager.ManifestBuild(cfg.Builder.Remote.Host, buildConfig); err != nil {
+	if err := imageManager.ManifestBuildWithProgress(cfg.Builder.Remote.Host, buildConfig, deploy.HostProgress(log, cfg.Builder.Remote.Host)); err != nil {
 		return fmt.Errorf("remote build failed: %w", err)
 	}
 
@@ -339,19 +465,21 @@ func pushImage(imageTag, latestTag string, multiarch bool) error {
 	log := output.DefaultLogger
 
 	// Login to registry first
-	if cfg.Registry.Username != "" {
+	if buildRegistry().Username != "" {
 		if err := loginToRegistry(); err != nil {
 			return fmt.Errorf("registry login failed: %w", err)
 		}
 	}
 
 	// Push version tag
+	engine := buildEngine()
 	log.Info("Pushing %s...", imageTag)
-	pushArgs := []string{"push", imageTag}
+	compression := podman.CompressionArgs(cfg.Builder.Compression)
+	pushArgs := append(append([]string{"push"}, compression...), imageTag)
 	if multiarch {
-		pushArgs = []string{"manifest", "push", imageTag, imageTag}
+		pushArgs = append(append([]string{"manifest", "push"}, compression...), imageTag, imageTag)
 	}
-	pushCmd := exec.Command("podman", pushArgs...)
+	pushCmd := exec.Command(engine, pushArgs...)
 	pushCmd.Stdout = os.Stdout
 	pushCmd.Stderr = os.Stderr
 	if err := pushCmd.Run(); err != nil {
@@ -361,9 +489,9 @@ func pushImage(imageTag, latestTag string, multiarch bool) error {
 	// Push latest tag
 	log.Info("Pushing %s...", latestTag)
 	if multiarch {
-		pushCmd = exec.Command("podman", "manifest", "push", imageTag, latestTag)
+		pushCmd = exec.Command(engine, append(append([]string{"manifest", "push"}, compression...), imageTag, latestTag)...)
 	} else {
-		pushCmd = exec.Command("podman", "push", latestTag)
+		pushCmd = exec.Command(engine, append(append([]string{"push"}, compression...), latestTag)...)
 	}
 	pushCmd.Stdout = os.Stdout
 	pushCmd.Stderr = os.Stderr
@@ -375,9 +503,9 @@ func pushImage(imageTag, latestTag string, multiarch bool) error {
 }
 
 func pushRemoteImage(sshClient *ssh.Client, host, imageTag, latestTag string, multiarch bool) error {
-	pushCmd := fmt.Sprintf("podman push %s", shell.Quote(imageTag))
+	pushCmd := fmt.Sprintf("podman push %s%s", remotePushFlags(), shell.Quote(imageTag))
 	if multiarch {
-		pushCmd = fmt.Sprintf("podman manifest push %s %s", shell.Quote(imageTag), shell.Quote(imageTag))
+		pushCmd = fmt.Sprintf("podman manifest push %s%s %s", remotePushFlags(), shell.Quote(imageTag), shell.Quote(imageTag))
 	}
 	if result, err := sshClient.Execute(host, pushCmd); err != nil {
 		return err
@@ -393,7 +521,7 @@ func pushRemoteImage(sshClient *ssh.Client, host, imageTag, latestTag string, mu
 		return fmt.Errorf("failed to tag %s: %s", latestTag, result.Stderr)
 	}
 
-	pushLatestCmd := fmt.Sprintf("podman push %s", shell.Quote(latestTag))
+	pushLatestCmd := fmt.Sprintf("podman push %s%s", remotePushFlags(), shell.Quote(latestTag))
 	if result, err := sshClient.Execute(host, pushLatestCmd); err != nil {
 		return err
 	} else if result.ExitCode != 0 {
@@ -403,28 +531,32 @@ func pushRemoteImage(sshClient *ssh.Client, host, imageTag, latestTag string, mu
 	return nil
 }
 
+// remotePushFlags returns the builder.compression flags of a remote podman
+// push, each followed by a space.
+func remotePushFlags() string {
+	var flags string
+	for _, arg := range shell.QuoteAll(podman.CompressionArgs(cfg.Builder.Compression)) {
+		flags += arg + " "
+	}
+	return flags
+}
+
 func loginToRegistryRemote(sshClient *ssh.Client, host string) error {
-	server := cfg.Registry.Server
+	registry := buildRegistry()
+	server := registry.Server
 	if server == "" {
 		server = "docker.io"
 	}
 
-	password := ""
-	if len(cfg.Registry.Password) > 0 {
-		secretKey := cfg.Registry.Password[0]
-		password = os.Getenv(secretKey)
-		if password == "" {
-			if p, ok := getSecret(secretKey); ok {
-				password = p
-			}
-		}
+	password, err := registryPassword()
+	if err != nil {
+		return err
 	}
-
 	if password == "" {
 		return fmt.Errorf("registry password not found")
 	}
 
-	cmd := fmt.Sprintf("podman login --username %s --password-stdin %s", shell.Quote(cfg.Registry.Username), shell.Quote(server))
+	cmd := fmt.Sprintf("podman login --username %s --password-stdin %s", shell.Quote(registry.Username), shell.Quote(server))
 	result, err := sshClient.ExecuteWithStdin(host, cmd, strings.NewReader(password+"\n"))
 	if err != nil {
 		return err
@@ -435,6 +567,17 @@ func loginToRegistryRemote(sshClient *ssh.Client, host string) error {
 	return nil
 }
 
+// buildEngine returns the CLI that runs local builds, pushes, and registry
+// logins: docker for builder.engine docker and buildx, podman otherwise.
+func buildEngine() string {
+	switch cfg.Builder.Engine {
+	case "docker", "buildx":
+		return "docker"
+	default:
+		return "podman"
+	}
+}
+
 func isMultiarchBuild() bool {
 	return cfg.Builder.Multiarch || len(cfg.Builder.Platforms) > 0
 }
@@ -492,9 +635,35 @@ func resolveCacheSpecs(cache config.CacheConfig) ([]string, string) {
 		cacheTo = spec
 	}
 
+	// Podman takes a bare repository rather than a buildx cache spec.
+	if buildEngine() == "podman" {
+		for i, from := range cacheFrom {
+			cacheFrom[i] = podmanCacheRepository(from)
+		}
+		cacheTo = podmanCacheRepository(cacheTo)
+	}
+
 	return cacheFrom, cacheTo
 }
 
+// podmanCacheRepository converts a registry cache spec
+// (type=registry,ref=REPO,...) into the repository podman's --cache-from and
+// --cache-to expect. Other values are returned unchanged.
+func podmanCacheRepository(spec string) string {
+	fields := make(map[string]string)
+	for _, part := range strings.Split(spec, ",") {
+		key, value, ok := strings.Cut(part, "=")
+		if !ok {
+			return spec
+		}
+		fields[strings.TrimSpace(key)] = strings.TrimSpace(value)
+	}
+	if fields["type"] != "registry" || fields["ref"] == "" {
+		return spec
+	}
+	return stripImageReference(fields["ref"])
+}
+
 func buildCacheSpec(cacheType string, options map[string]string) string {
 	cacheType = strings.TrimSpace(cacheType)
 	if cacheType == "" && len(options) == 0 {
@@ -524,37 +693,47 @@ func buildCacheSpec(cacheType string, options map[string]string) string {
 }
 
 func loginToRegistry() error {
-	server := cfg.Registry.Server
+	return loginToRegistryWith(buildRegistry())
+}
+
+// loginToRegistryWith logs the build engine into registry.
+func loginToRegistryWith(registry config.RegistryConfig) error {
+	server := registry.Server
 	if server == "" {
 		server = "docker.io"
 	}
 
-	// Get password from secrets
-	password := ""
-	if len(cfg.Registry.Password) > 0 {
-		// Password is a reference to a secret
-		secretKey := cfg.Registry.Password[0]
-		password = os.Getenv(secretKey)
-		if password == "" {
-			// Try loading from secrets file
-			if p, ok := getSecret(secretKey); ok {
-				password = p
-			}
-		}
+	password, err := registry.ResolvePassword()
+	if err != nil {
+		return err
 	}
-
 	if password == "" {
 		return fmt.Errorf("registry password not found")
 	}
 
-	// Login using podman CLI
-	cmd := exec.Command("podman", "login", "--username", cfg.Registry.Username, "--password-stdin", server)
+	// Login using the build engine's CLI
+	cmd := exec.Command(buildEngine(), "login", "--username", registry.Username, "--password-stdin", server)
 	cmd.Stdin = strings.NewReader(password)
 	cmd.Stderr = os.Stderr
 
 	return cmd.Run()
 }
 
+// buildRegistry returns the credentials for the registry the image is pushed
+// to: its registries entry, or the registry section otherwise.
+func buildRegistry() config.RegistryConfig {
+	if registry, ok := cfg.RegistryFor(cfg.Image); ok {
+		return registry
+	}
+	return cfg.Registry
+}
+
+// registryPassword resolves the build registry's password from its credential
+// helper or the secret it names. It returns "" when none is configured.
+func registryPassword() (string, error) {
+	return buildRegistry().ResolvePassword()
+}
+
 func getSecret(key string) (string, bool) {
 	// Try environment first
 	if val := os.Getenv(key); val != "" {
@@ -589,11 +768,51 @@ func generateVersion() string {
 	return time.Now().Format("20060102150405")
 }
 
+// gitHeadSHA returns the commit checked out in the working directory, or ""
+// outside a git repository.
+func gitHeadSHA() string {
+	out, err := exec.Command("git", "rev-parse", "HEAD").Output()
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(out))
+}
+
+// builtGitSHA is the commit the image built by this run was built from. It
+// is "" unless a build succeeded from a clean checkout.
+var builtGitSHA string
+
+// cleanGitHeadSHA returns the commit checked out in the working directory
+// when the checkout has no uncommitted or untracked changes, and ""
+// otherwise or outside a git repository.
+func cleanGitHeadSHA() string {
+	status, err := exec.Command("git", "status", "--porcelain").Output()
+	if err != nil || strings.TrimSpace(string(status)) != "" {
+		return ""
+	}
+	return gitHeadSHA()
+}
+
+// deployMetadata returns the history metadata of a deployment started here.
+// The git commit is only recorded when this run built the image from a
+// clean checkout; a deploy of a prebuilt image or of local changes does
+// not necessarily match any commit.
+func deployMetadata() map[string]string {
+	metadata := map[string]string{}
+	if builtGitSHA != "" {
+		metadata[deploy.GitSHAMetadata] = builtGitSHA
+	}
+	return metadata
+}
+
 // generateImageTag creates an image tag using the configured template
 // Template placeholders:
 //   - {version}: Git commit hash or timestamp
 //   - {destination}: Current deployment destination (e.g., staging, production)
 //   - {timestamp}: Current timestamp (YYYYMMDDHHMMSS)
+//   - {git_sha}: Full commit hash of HEAD
+//   - {git_branch}: Checked out branch, with / replaced by -
+//   - {build_date}: Build start in RFC 3339 UTC, with : replaced by -
 //
 // Default template is "{version}" for backward compatibility.
 // Recommended for multi-environment: "{destination}-{version}"
@@ -611,7 +830,12 @@ func stripImageReference(image string) string {
 }
 
 func generateImageTag(baseImage, destination string) (string, error) {
-	version := generateVersion()
+	return imageTagFromTemplate(baseImage, buildPlaceholders(destination))
+}
+
+// imageTagFromTemplate expands builder.tag_template with the placeholder
+// values of a build.
+func imageTagFromTemplate(baseImage string, values map[string]string) (string, error) {
 	template := cfg.Builder.TagTemplate
 
 	// Use default template if not configured
@@ -619,20 +843,13 @@ func generateImageTag(baseImage, destination string) (string, error) {
 		template = "{version}"
 	}
 
-	// Replace placeholders
+	// If no destination, remove the placeholder and any trailing/leading hyphen
 	tag := template
-	tag = strings.ReplaceAll(tag, "{version}", version)
-	tag = strings.ReplaceAll(tag, "{timestamp}", time.Now().Format("20060102150405"))
-
-	// Only include destination if provided and placeholder exists
-	if destination != "" {
-		tag = strings.ReplaceAll(tag, "{destination}", destination)
-	} else {
-		// If no destination, remove the placeholder and any trailing/leading hyphen
+	if values["destination"] == "" {
 		tag = strings.ReplaceAll(tag, "{destination}-", "")
 		tag = strings.ReplaceAll(tag, "-{destination}", "")
-		tag = strings.ReplaceAll(tag, "{destination}", "")
 	}
+	tag = expandPlaceholders(tag, tagPlaceholders(values))
 
 	if !ociTagPattern.MatchString(tag) {
 		return "", fmt.Errorf("generated image tag %q is not a valid OCI tag", tag)
@@ -0,0 +1,149 @@
+package cli
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+
+	"github.com/lemonity-org/azud/internal/config"
+	"github.com/lemonity-org/azud/internal/output"
+	"github.com/lemonity-org/azud/internal/podman"
+)
+
+// runBuildCacheOnly builds the image only to export its layers to the
+// registry cache. No image tag is produced or pushed, so CI can warm the
+// cache without creating anything deployable.
+func runBuildCacheOnly() error {
+	log := output.DefaultLogger
+	timer := log.NewTimer("Cache build")
+
+	switch {
+	case buildNoPush:
+		return fmt.Errorf("--cache-only pushes the build cache and cannot be combined with --no-push")
+	case cfg.Builder.IsBuildpacks():
+		return fmt.Errorf("--cache-only is not supported for builder.type buildpacks")
+	case cfg.Builder.Remote.Parallel():
+		return fmt.Errorf("--cache-only is not supported with multiple remote builders")
+	case cfg.Builder.Engine == "docker":
+		return fmt.Errorf("--cache-only needs builder.engine podman or buildx; docker build cannot export a registry cache")
+	}
+
+	cacheFrom, cacheTo := resolveCacheSpecs(cacheOnlyConfig())
+	if cacheTo == "" {
+		return fmt.Errorf("builder.cache has no cache destination to push to")
+	}
+	platforms, err := resolveBuildPlatforms(cfg.Builder.Remote.Host != "")
+	if err != nil {
+		return err
+	}
+
+	log.Header("Building cache %s", cacheTo)
+	if cfg.Builder.Remote.Host != "" {
+		err = buildCacheRemote(platforms, cacheFrom, cacheTo)
+	} else {
+		err = buildCacheLocal(platforms, cacheFrom, cacheTo)
+	}
+	if err != nil {
+		return err
+	}
+
+	timer.Stop()
+	log.Success("Build cache pushed: %s", cacheTo)
+	return nil
+}
+
+// cacheOnlyConfig returns builder.cache, or a registry cache next to the
+// image when no cache is configured.
+func cacheOnlyConfig() config.CacheConfig {
+	if strings.TrimSpace(cfg.Builder.Cache.Type) != "" || len(cfg.Builder.Cache.Options) > 0 {
+		return cfg.Builder.Cache
+	}
+	return config.CacheConfig{
+		Type:    "registry",
+		Options: map[string]string{"ref": config.DefaultCacheRef(cfg.Image)},
+	}
+}
+
+// cacheBuildPlatforms returns the platform groups to build: every platform in
+// one buildx command, or one podman build per platform.
+func cacheBuildPlatforms(platforms []string) [][]string {
+	if len(platforms) == 0 {
+		return [][]string{nil}
+	}
+	if cfg.Builder.Engine == "buildx" {
+		return [][]string{platforms}
+	}
+	groups := make([][]string, 0, len(platforms))
+	for _, platform := range platforms {
+		groups = append(groups, []string{platform})
+	}
+	return groups
+}
+
+func buildCacheLocal(platforms, cacheFrom []string, cacheTo string) error {
+	log := output.DefaultLogger
+
+	if buildRegistry().Username != "" {
+		if err := loginToRegistry(); err != nil {
+			return fmt.Errorf("registry login failed: %w", err)
+		}
+	}
+
+	engine := buildEngine()
+	for _, group := range cacheBuildPlatforms(platforms) {
+		args := localBuildArgs("", "", group, cacheFrom, cacheTo, len(group) > 0)
+		log.Command(engine + " " + strings.Join(args, " "))
+
+		buildCmd := exec.Command(engine, args...)
+		buildCmd.Stdout = os.Stdout
+		buildCmd.Stderr = os.Stderr
+		if err := buildCmd.Run(); err != nil {
+			return fmt.Errorf("cache build failed: %w", err)
+		}
+	}
+	return nil
+}
+
+func buildCacheRemote(platforms, cacheFrom []string, cacheTo string) error {
+	host := cfg.Builder.Remote.Host
+	output.DefaultLogger.Info("Building cache on remote builder: %s", host)
+
+	sshClient := createSSHClient()
+	defer func() { _ = sshClient.Close() }()
+
+	remoteContext, err := syncBuildContext(sshClient, host, cfg.Builder.Context)
+	if err != nil {
+		return fmt.Errorf("failed to sync build context: %w", err)
+	}
+	defer cleanupBuildContext(sshClient, host, remoteContext)
+
+	if buildRegistry().Username != "" {
+		if err := loginToRegistryRemote(sshClient, host); err != nil {
+			return fmt.Errorf("remote registry login failed: %w", err)
+		}
+	}
+
+	imageManager := podman.NewImageManager(podman.NewClient(sshClient))
+	for _, group := range cacheBuildPlatforms(platforms) {
+		buildConfig := &podman.BuildConfig{
+			Context:    remoteContext,
+			Dockerfile: cfg.Builder.Dockerfile,
+			Args:       cfg.Builder.Args,
+			NoCache:    buildNoCache,
+			Pull:       buildPull,
+			Secrets:    cfg.Builder.Secrets,
+			CacheFrom:  cacheFrom,
+			CacheTo:    cacheTo,
+			Target:     cfg.Builder.Target,
+			SSH:        cfg.Builder.SSH,
+		}
+		if len(group) > 0 {
+			buildConfig.Platform = group[0]
+		}
+		if err := imageManager.Build(host, buildConfig); err != nil {
+			return fmt.Errorf("remote cache build failed: %w", err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,66 @@
+package cli
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/lemonity-org/azud/internal/config"
+)
+
+func TestPodmanCacheRepository(t *testing.T) {
+	tests := map[string]string{
+		"type=registry,ref=ghcr.io/acme/app-cache":              "ghcr.io/acme/app-cache",
+		"type=registry,mode=max,ref=ghcr.io/acme/app-cache:tag": "ghcr.io/acme/app-cache",
+		"ghcr.io/acme/app-cache":                                "ghcr.io/acme/app-cache",
+		"type=local,dest=/tmp/cache":                            "type=local,dest=/tmp/cache",
+		"":                                                      "",
+	}
+	for spec, want := range tests {
+		if got := podmanCacheRepository(spec); got != want {
+			t.Errorf("podmanCacheRepository(%q) = %q, want %q", spec, got, want)
+		}
+	}
+}
+
+func TestResolveCacheSpecsByEngine(t *testing.T) {
+	oldCfg := cfg
+	t.Cleanup(func() { cfg = oldCfg })
+	cache := config.CacheConfig{Type: "registry", Options: map[string]string{"ref": "ghcr.io/acme/app-cache"}}
+
+	cfg = &config.Config{}
+	from, to := resolveCacheSpecs(cache)
+	if !reflect.DeepEqual(from, []string{"ghcr.io/acme/app-cache"}) || to != "ghcr.io/acme/app-cache" {
+		t.Fatalf("podman cache specs = (%v, %q)", from, to)
+	}
+
+	cfg = &config.Config{Builder: config.BuilderConfig{Engine: "buildx"}}
+	from, to = resolveCacheSpecs(cache)
+	if !reflect.DeepEqual(from, []string{"type=registry,ref=ghcr.io/acme/app-cache"}) || to != "type=registry,ref=ghcr.io/acme/app-cache" {
+		t.Fatalf("buildx cache specs = (%v, %q)", from, to)
+	}
+}
+
+func TestCacheOnlyBuild(t *testing.T) {
+	oldCfg, oldCacheOnly := cfg, buildCacheOnly
+	t.Cleanup(func() { cfg, buildCacheOnly = oldCfg, oldCacheOnly })
+	buildCacheOnly = true
+
+	cfg = &config.Config{Image: "ghcr.io/acme/app:v1", Builder: config.BuilderConfig{Engine: "buildx", Context: "."}}
+	cache := cacheOnlyConfig()
+	if cache.Type != "registry" || cache.Options["ref"] != "ghcr.io/acme/app-cache" {
+		t.Fatalf("default cache-only config = %+v", cache)
+	}
+
+	from, to := resolveCacheSpecs(cache)
+	args := strings.Join(localBuildArgs("", "", []string{"linux/amd64", "linux/arm64"}, from, to, true), " ")
+	want := "buildx build -f Dockerfile --platform linux/amd64,linux/arm64 --cache-from type=registry,ref=ghcr.io/acme/app-cache --cache-to type=registry,ref=ghcr.io/acme/app-cache ."
+	if args != want {
+		t.Fatalf("cache-only args =\n  %s\nwant\n  %s", args, want)
+	}
+
+	cfg.Builder.Engine = ""
+	if groups := cacheBuildPlatforms([]string{"linux/amd64", "linux/arm64"}); len(groups) != 2 {
+		t.Fatalf("podman should build one platform at a time, got %v", groups)
+	}
+}
@@ -0,0 +1,71 @@
+package cli
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/lemonity-org/azud/internal/config"
+)
+
+func TestLocalBuildArgsByEngine(t *testing.T) {
+	oldCfg, oldNoPush := cfg, buildNoPush
+	t.Cleanup(func() { cfg, buildNoPush = oldCfg, oldNoPush })
+
+	tests := []struct {
+		name      string
+		engine    string
+		noPush    bool
+		multiarch bool
+		platforms []string
+		want      string
+		wantCLI   string
+	}{
+		{
+			name:    "podman",
+			want:    "build -f Dockerfile -t app:1 -t app:latest --platform linux/amd64 .",
+			wantCLI: "podman",
+		},
+		{
+			name:    "docker",
+			engine:  "docker",
+			want:    "build -f Dockerfile -t app:1 -t app:latest --platform linux/amd64 .",
+			wantCLI: "docker",
+		},
+		{
+			name:    "buildx pushes",
+			engine:  "buildx",
+			want:    "buildx build -f Dockerfile -t app:1 -t app:latest --platform linux/amd64 --push .",
+			wantCLI: "docker",
+		},
+		{
+			name:    "buildx loads without push",
+			engine:  "buildx",
+			noPush:  true,
+			want:    "buildx build -f Dockerfile -t app:1 -t app:latest --platform linux/amd64 --load .",
+			wantCLI: "docker",
+		},
+		{
+			name:      "buildx multi-platform",
+			engine:    "buildx",
+			multiarch: true,
+			platforms: []string{"linux/amd64", "linux/arm64"},
+			want:      "buildx build -f Dockerfile -t app:1 -t app:latest --platform linux/amd64,linux/arm64 --push .",
+			wantCLI:   "docker",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg = &config.Config{Builder: config.BuilderConfig{Engine: tt.engine, Arch: "amd64", Context: "."}}
+			buildNoPush = tt.noPush
+
+			got := strings.Join(localBuildArgs("app:1", "app:latest", tt.platforms, nil, "", tt.multiarch), " ")
+			if got != tt.want {
+				t.Errorf("localBuildArgs() =\n  %s\nwant\n  %s", got, tt.want)
+			}
+			if engine := buildEngine(); engine != tt.wantCLI {
+				t.Errorf("buildEngine() = %q, want %q", engine, tt.wantCLI)
+			}
+		})
+	}
+}
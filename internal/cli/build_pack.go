@@ -0,0 +1,76 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+
+	"github.com/lemonity-org/azud/internal/output"
+	"github.com/lemonity-org/azud/internal/podman"
+	"github.com/lemonity-org/azud/internal/shell"
+)
+
+// newPackBuildConfig maps builder.pack settings onto a pack build of context.
+func newPackBuildConfig(context, imageTag, latestTag, arch string) *podman.PackBuildConfig {
+	packConfig := &podman.PackBuildConfig{
+		Context:    context,
+		Tag:        imageTag,
+		Tags:       []string{latestTag},
+		Builder:    cfg.Builder.Pack.Builder,
+		Buildpacks: cfg.Builder.Pack.Buildpacks,
+		Env:        cfg.Builder.Pack.Env,
+		NoCache:    buildNoCache,
+		Pull:       buildPull,
+	}
+	if arch != "" {
+		packConfig.Platform = fmt.Sprintf("linux/%s", arch)
+	}
+	return packConfig
+}
+
+// buildPackLocal builds the image with pack. pack writes the image to the
+// engine DOCKER_HOST points at; with the podman engine and DOCKER_HOST unset,
+// the user's Podman socket is used if it exists so the image lands where
+// 'podman push' finds it.
+func buildPackLocal(imageTag, latestTag string) error {
+	log := output.DefaultLogger
+
+	if _, err := exec.LookPath("pack"); err != nil {
+		return fmt.Errorf("builder.type buildpacks requires the pack CLI on PATH: %w", err)
+	}
+
+	command := newPackBuildConfig(cfg.Builder.Context, imageTag, latestTag, effectiveBuildArch(false)).PackBuildCommand()
+	log.Info("Running pack build...")
+	log.Command(command)
+
+	buildCmd := shell.Command(context.Background(), command)
+	buildCmd.Stdout = os.Stdout
+	buildCmd.Stderr = os.Stderr
+	if buildEngine() == "podman" && os.Getenv("DOCKER_HOST") == "" {
+		if socket := podmanSocketPath(); socket != "" {
+			log.Debug("Using Podman socket %s for pack", socket)
+			buildCmd.Env = append(os.Environ(), "DOCKER_HOST=unix://"+socket)
+		}
+	}
+	if err := buildCmd.Run(); err != nil {
+		return fmt.Errorf("build failed: %w", err)
+	}
+	return nil
+}
+
+// podmanSocketPath returns the local Podman API socket, or "" when none is
+// listening at the standard rootless or rootful location.
+func podmanSocketPath() string {
+	candidates := []string{"/run/podman/podman.sock"}
+	if runtimeDir := os.Getenv("XDG_RUNTIME_DIR"); runtimeDir != "" && os.Getuid() != 0 {
+		candidates = []string{filepath.Join(runtimeDir, "podman", "podman.sock")}
+	}
+	for _, candidate := range candidates {
+		if info, err := os.Stat(candidate); err == nil && info.Mode()&os.ModeSocket != 0 {
+			return candidate
+		}
+	}
+	return ""
+}
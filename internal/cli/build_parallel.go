@@ -0,0 +1,123 @@
+package cli
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/lemonity-org/azud/internal/config"
+	"github.com/lemonity-org/azud/internal/deploy"
+	"github.com/lemonity-org/azud/internal/output"
+	"github.com/lemonity-org/azud/internal/podman"
+	"github.com/lemonity-org/azud/internal/shell"
+	"github.com/lemonity-org/azud/internal/ssh"
+)
+
+// buildRemoteParallel builds every architecture on its own remote builder at
+// the same time, pushes each per-architecture image, and assembles them into
+// a manifest list pushed as imageTag and latestTag.
+func buildRemoteParallel(imageTag, latestTag string) error {
+	log := output.DefaultLogger
+	if buildNoPush {
+		return fmt.Errorf("multiple remote builders push per-architecture images to assemble the manifest list; --no-push is not supported")
+	}
+
+	builders := cfg.Builder.Remote.All()
+	archs := make([]string, len(builders))
+	for i, builder := range builders {
+		archs[i] = builder.Arch
+	}
+	log.Info("Building %s on %d remote builders in parallel", strings.Join(archs, ", "), len(builders))
+
+	sshClient := createSSHClient()
+	defer func() { _ = sshClient.Close() }()
+
+	images := make([]string, len(builders))
+	errs := make([]error, len(builders))
+	var wg sync.WaitGroup
+	for i, builder := range builders {
+		images[i] = archImageTag(imageTag, builder.Arch)
+		wg.Add(1)
+		go func(i int, builder config.RemoteBuilderConfig) {
+			defer wg.Done()
+			errs[i] = buildOnRemoteBuilder(sshClient, builder, images[i])
+		}(i, builder)
+	}
+	wg.Wait()
+
+	var failed []string
+	for i, err := range errs {
+		if err != nil {
+			failed = append(failed, fmt.Sprintf("%s (%s): %v", builders[i].Host, builders[i].Arch, err))
+		}
+	}
+	if len(failed) > 0 {
+		return fmt.Errorf("remote build failed:\n  %s", strings.Join(failed, "\n  "))
+	}
+
+	log.Info("Assembling manifest list %s...", imageTag)
+	imageManager := podman.NewImageManager(podman.NewClient(sshClient))
+	if err := imageManager.ManifestAssemble(builders[0].Host, imageTag, images, []string{latestTag}); err != nil {
+		return fmt.Errorf("remote push failed: %w", err)
+	}
+
+	log.Success("Remote build complete")
+	return nil
+}
+
+// buildOnRemoteBuilder builds image for builder's architecture on builder
+// and pushes it to the registry.
+func buildOnRemoteBuilder(sshClient *ssh.Client, builder config.RemoteBuilderConfig, image string) error {
+	log := output.DefaultLogger
+	log.Info("Building linux/%s on %s", builder.Arch, builder.Host)
+
+	remoteContext, err := syncBuildContext(sshClient, builder.Host, cfg.Builder.Context)
+	if err != nil {
+		return fmt.Errorf("failed to sync build context: %w", err)
+	}
+	defer cleanupBuildContext(sshClient, builder.Host, remoteContext)
+
+	if buildRegistry().Username != "" {
+		if err := loginToRegistryRemote(sshClient, builder.Host); err != nil {
+			return fmt.Errorf("remote registry login failed: %w", err)
+		}
+	}
+
+	cacheFrom, cacheTo := resolveCacheSpecs(cfg.Builder.Cache)
+	buildConfig := &podman.BuildConfig{
+		Context:    remoteContext,
+		Dockerfile: cfg.Builder.Dockerfile,
+		Tag:        image,
+		Args:       cfg.Builder.Args,
+		Labels:     cfg.Builder.Labels,
+		NoCache:    buildNoCache,
+		Pull:       buildPull,
+		Secrets:    cfg.Builder.Secrets,
+		CacheFrom:  cacheFrom,
+		CacheTo:    cacheTo,
+		Target:     cfg.Builder.Target,
+		SSH:        cfg.Builder.SSH,
+		Platform:   fmt.Sprintf("linux/%s", builder.Arch),
+	}
+	imageManager := podman.NewImageManager(podman.NewClient(sshClient))
+	if err := imageManager.BuildWithProgress(builder.Host, buildConfig, deploy.HostProgress(log, builder.Host)); err != nil {
+		return err
+	}
+
+	result, err := sshClient.Execute(builder.Host, fmt.Sprintf("podman push %s%s", remotePushFlags(), shell.Quote(image)))
+	if err != nil {
+		return err
+	}
+	if result.ExitCode != 0 {
+		return fmt.Errorf("failed to push %s: %s", image, result.Stderr)
+	}
+
+	log.Success("Built linux/%s on %s", builder.Arch, builder.Host)
+	return nil
+}
+
+// archImageTag returns the per-architecture tag a parallel build pushes
+// before the manifest list is assembled, e.g. app:abc123-arm64.
+func archImageTag(imageTag, arch string) string {
+	return fmt.Sprintf("%s-%s", imageTag, arch)
+}
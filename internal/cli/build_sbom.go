@@ -0,0 +1,156 @@
+package cli
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+
+	"github.com/lemonity-org/azud/internal/deploy"
+	"github.com/lemonity-org/azud/internal/output"
+)
+
+// orasDescriptor is the subset of `oras attach --format json` output Azud
+// reads.
+type orasDescriptor struct {
+	Reference string `json:"reference"`
+	Digest    string `json:"digest"`
+}
+
+// generateSBOM scans the pushed image with syft and attaches the SBOM to it
+// in the registry with oras, as an OCI artifact referring to the image
+// digest. The artifact reference is recorded locally so deployments of the
+// digest can store it in their history. Both tools run on this machine
+// against the registry, for local and remote builds alike.
+func generateSBOM(imageTag string) error {
+	log := output.DefaultLogger
+	format := cfg.Builder.SBOMFormat
+	mediaType, ok := deploy.SBOMMediaTypes[format]
+	if !ok {
+		return fmt.Errorf("unsupported SBOM format %q", format)
+	}
+	for _, tool := range []string{"syft", "oras"} {
+		if _, err := exec.LookPath(tool); err != nil {
+			return fmt.Errorf("builder.sbom requires %s on PATH: %w", tool, err)
+		}
+	}
+
+	password, err := registryPassword()
+	if err != nil {
+		return err
+	}
+	digest, err := resolvePushedDigest(imageTag, password)
+	if err != nil {
+		return err
+	}
+	subject := fmt.Sprintf("%s@%s", stripImageReference(imageTag), digest)
+
+	workDir, err := os.MkdirTemp("", "azud-sbom-")
+	if err != nil {
+		return fmt.Errorf("failed to create SBOM directory: %w", err)
+	}
+	defer func() { _ = os.RemoveAll(workDir) }()
+	sbomFile := fmt.Sprintf("sbom.%s", format)
+
+	log.Info("Generating %s SBOM for %s...", format, subject)
+	scanArgs := []string{"scan", "registry:" + subject, "-o", fmt.Sprintf("%s=%s", format, sbomFile)}
+	log.Command("syft " + strings.Join(scanArgs, " "))
+	scan := exec.Command("syft", scanArgs...)
+	scan.Dir = workDir
+	scan.Env = append(os.Environ(), syftRegistryAuthEnv(password)...)
+	scan.Stderr = os.Stderr
+	if err := scan.Run(); err != nil {
+		return fmt.Errorf("syft failed: %w", err)
+	}
+
+	log.Info("Attaching SBOM to %s...", subject)
+	attachArgs := append([]string{"attach", "--artifact-type", mediaType, "--format", "json"}, orasAuthArgs(password)...)
+	attachArgs = append(attachArgs, subject, fmt.Sprintf("%s:%s", sbomFile, mediaType))
+	log.Command("oras " + strings.Join(attachArgs, " "))
+	attach := exec.Command("oras", attachArgs...)
+	attach.Dir = workDir
+	attach.Stdin = strings.NewReader(password)
+	attach.Stderr = os.Stderr
+	out, err := attach.Output()
+	if err != nil {
+		return fmt.Errorf("oras attach failed: %w", err)
+	}
+	reference, err := parseOrasAttachOutput(out, stripImageReference(imageTag))
+	if err != nil {
+		return err
+	}
+
+	if err := deploy.NewSBOMStore().Save(&deploy.SBOMRecord{
+		Service:   cfg.Service,
+		Image:     imageTag,
+		Digest:    digest,
+		Reference: reference,
+		Format:    format,
+	}); err != nil {
+		return err
+	}
+	log.Success("SBOM attached: %s", reference)
+	return nil
+}
+
+// resolvePushedDigest returns the registry digest imageTag points at.
+func resolvePushedDigest(imageTag, password string) (string, error) {
+	args := append([]string{"resolve"}, orasAuthArgs(password)...)
+	args = append(args, imageTag)
+	resolve := exec.Command("oras", args...)
+	resolve.Stdin = strings.NewReader(password)
+	resolve.Stderr = os.Stderr
+	out, err := resolve.Output()
+	if err != nil {
+		return "", fmt.Errorf("failed to resolve %s in the registry: %w", imageTag, err)
+	}
+	digest := strings.TrimSpace(string(out))
+	if !strings.HasPrefix(digest, "sha256:") {
+		return "", fmt.Errorf("unexpected digest for %s: %q", imageTag, digest)
+	}
+	return digest, nil
+}
+
+// parseOrasAttachOutput returns the reference of the attached artifact,
+// building repository@digest when oras reports only the digest.
+func parseOrasAttachOutput(out []byte, repository string) (string, error) {
+	var descriptor orasDescriptor
+	if err := json.Unmarshal(out, &descriptor); err != nil {
+		return "", fmt.Errorf("invalid oras attach output: %w", err)
+	}
+	if descriptor.Reference != "" {
+		return descriptor.Reference, nil
+	}
+	if descriptor.Digest == "" {
+		return "", fmt.Errorf("oras attach did not report the SBOM digest")
+	}
+	return fmt.Sprintf("%s@%s", repository, descriptor.Digest), nil
+}
+
+// orasAuthArgs passes the configured registry credentials to oras; the
+// password is read from stdin.
+func orasAuthArgs(password string) []string {
+	registry := buildRegistry()
+	if registry.Username == "" || password == "" {
+		return nil
+	}
+	return []string{"--username", registry.Username, "--password-stdin"}
+}
+
+// syftRegistryAuthEnv passes the configured registry credentials to syft.
+func syftRegistryAuthEnv(password string) []string {
+	registry := buildRegistry()
+	if registry.Username == "" || password == "" {
+		return nil
+	}
+	server := registry.Server
+	if server == "" {
+		server = "docker.io"
+	}
+	return []string{
+		"SYFT_REGISTRY_AUTH_AUTHORITY=" + server,
+		"SYFT_REGISTRY_AUTH_USERNAME=" + registry.Username,
+		"SYFT_REGISTRY_AUTH_PASSWORD=" + password,
+	}
+}
@@ -0,0 +1,39 @@
+package cli
+
+import (
+	"testing"
+)
+
+func TestParseOrasAttachOutput(t *testing.T) {
+	tests := []struct {
+		name    string
+		out     string
+		want    string
+		wantErr bool
+	}{
+		{
+			name: "reference",
+			out:  `{"reference":"ghcr.io/acme/app@sha256:bbb","mediaType":"application/vnd.oci.image.manifest.v1+json","digest":"sha256:bbb"}`,
+			want: "ghcr.io/acme/app@sha256:bbb",
+		},
+		{
+			name: "digest only",
+			out:  `{"digest":"sha256:bbb"}`,
+			want: "ghcr.io/acme/app@sha256:bbb",
+		},
+		{name: "empty descriptor", out: `{}`, wantErr: true},
+		{name: "not json", out: `Attached to [registry] ghcr.io/acme/app@sha256:aaa`, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseOrasAttachOutput([]byte(tt.out), "ghcr.io/acme/app")
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseOrasAttachOutput() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Fatalf("parseOrasAttachOutput() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -39,3 +39,54 @@ func TestGenerateImageTagNormalizesBaseAndValidatesTag(t *testing.T) {
 		t.Fatalf("expected invalid tag error, got %v", err)
 	}
 }
+
+func TestImageTagFromTemplateGitPlaceholders(t *testing.T) {
+	oldCfg := cfg
+	t.Cleanup(func() { cfg = oldCfg })
+	cfg = &config.Config{Builder: config.BuilderConfig{TagTemplate: "{git_branch}-{destination}-{git_sha}"}}
+
+	values := map[string]string{
+		"version":    "0123abc",
+		"git_sha":    "0123abcd",
+		"git_branch": "feature/login",
+		"build_date": "2026-03-04T05:06:07Z",
+	}
+	got, err := imageTagFromTemplate("ghcr.io/acme/app", values)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got != "ghcr.io/acme/app:feature-login-0123abcd" {
+		t.Fatalf("generated tag = %q", got)
+	}
+
+	cfg.Builder.TagTemplate = "{build_date}"
+	if got, err := imageTagFromTemplate("ghcr.io/acme/app", values); err != nil || got != "ghcr.io/acme/app:2026-03-04T05-06-07Z" {
+		t.Fatalf("generated tag = %q, %v", got, err)
+	}
+}
+
+func TestResolveBuildTemplates(t *testing.T) {
+	oldCfg := cfg
+	t.Cleanup(func() { cfg = oldCfg })
+	cfg = &config.Config{Builder: config.BuilderConfig{
+		Args:   map[string]string{"GIT_SHA": "{git_sha}", "RAILS_ENV": "production"},
+		Labels: map[string]string{"org.opencontainers.image.created": "{build_date}", "ref": "{git_branch}"},
+	}}
+
+	resolveBuildTemplates(map[string]string{
+		"git_sha":    "0123abcd",
+		"git_branch": "feature/login",
+		"build_date": "2026-03-04T05:06:07Z",
+	})
+	if cfg.Builder.Args["GIT_SHA"] != "0123abcd" || cfg.Builder.Args["RAILS_ENV"] != "production" {
+		t.Fatalf("args = %v", cfg.Builder.Args)
+	}
+	if cfg.Builder.Labels["org.opencontainers.image.created"] != "2026-03-04T05:06:07Z" || cfg.Builder.Labels["ref"] != "feature/login" {
+		t.Fatalf("labels = %v", cfg.Builder.Labels)
+	}
+
+	args := strings.Join(localBuildArgs("app:1", "app:latest", nil, nil, "", false), " ")
+	if !strings.Contains(args, "--label org.opencontainers.image.created=2026-03-04T05:06:07Z --label ref=feature/login") {
+		t.Fatalf("build args = %s", args)
+	}
+}
@@ -0,0 +1,78 @@
+package cli
+
+import (
+	"os/exec"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// buildPlaceholders returns the values of the placeholders builder.tag_template,
+// builder.args, and builder.labels may use, resolved once per build. Values
+// that cannot be determined, such as the branch outside a git repository,
+// are empty.
+func buildPlaceholders(destination string) map[string]string {
+	now := time.Now()
+	return map[string]string{
+		"version":     generateVersion(),
+		"destination": destination,
+		"timestamp":   now.Format("20060102150405"),
+		"build_date":  now.UTC().Format(time.RFC3339),
+		"git_sha":     gitHeadSHA(),
+		"git_branch":  gitBranch(),
+	}
+}
+
+// gitBranch returns the branch checked out in the working directory, or ""
+// on a detached HEAD or outside a git repository.
+func gitBranch() string {
+	out, err := exec.Command("git", "rev-parse", "--abbrev-ref", "HEAD").Output()
+	if err != nil {
+		return ""
+	}
+	branch := strings.TrimSpace(string(out))
+	if branch == "HEAD" {
+		return ""
+	}
+	return branch
+}
+
+// expandPlaceholders replaces each {name} in s with its value.
+func expandPlaceholders(s string, values map[string]string) string {
+	pairs := make([]string, 0, 2*len(values))
+	for name, value := range values {
+		pairs = append(pairs, "{"+name+"}", value)
+	}
+	return strings.NewReplacer(pairs...).Replace(s)
+}
+
+// resolveBuildTemplates replaces the placeholders in builder.args and
+// builder.labels, so every build path passes the resolved values.
+func resolveBuildTemplates(values map[string]string) {
+	cfg.Builder.Args = expandPlaceholderMap(cfg.Builder.Args, values)
+	cfg.Builder.Labels = expandPlaceholderMap(cfg.Builder.Labels, values)
+}
+
+func expandPlaceholderMap(m map[string]string, values map[string]string) map[string]string {
+	if m == nil {
+		return nil
+	}
+	expanded := make(map[string]string, len(m))
+	for key, value := range m {
+		expanded[key] = expandPlaceholders(value, values)
+	}
+	return expanded
+}
+
+// tagUnsafeChars matches characters an image tag cannot contain.
+var tagUnsafeChars = regexp.MustCompile(`[^A-Za-z0-9_.-]`)
+
+// tagPlaceholders returns values with the characters an image tag cannot
+// contain replaced by hyphens, e.g. feature-login for feature/login.
+func tagPlaceholders(values map[string]string) map[string]string {
+	safe := make(map[string]string, len(values))
+	for name, value := range values {
+		safe[name] = tagUnsafeChars.ReplaceAllString(value, "-")
+	}
+	return safe
+}
@@ -0,0 +1,354 @@
+package cli
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/lemonity-org/azud/internal/bundle"
+	"github.com/lemonity-org/azud/internal/config"
+	"github.com/lemonity-org/azud/internal/deploy"
+	"github.com/lemonity-org/azud/internal/output"
+	"github.com/lemonity-org/azud/internal/podman"
+	"github.com/lemonity-org/azud/internal/ssh"
+)
+
+var bundleCmd = &cobra.Command{
+	Use:   "bundle",
+	Short: "Export and apply offline deployment bundles",
+	Long: `Commands for moving a release between machines without the source
+checkout or registry access.
+
+A bundle is a tarball holding the application image, a snapshot of the
+configuration, the names of the secrets the deployment needs, and the hooks.
+Secret values are never bundled: the operator who applies the bundle supplies
+them from their own secrets file or environment. Accessory and proxy images
+are not bundled.`,
+}
+
+var bundleExportCmd = &cobra.Command{
+	Use:   "export",
+	Short: "Write a deployment bundle",
+	Long: `Write the application image, configuration, secret names, and hooks
+to a bundle.
+
+The image is taken from the local image store of the build engine, and pulled
+first if it is missing. Build and push it with 'azud build' before exporting.
+
+Example:
+  azud bundle export                          # Image of the current commit
+  azud bundle export --version v1.2.3
+  azud bundle export -d production -o release.tar.gz`,
+	Args: cobra.NoArgs,
+	RunE: runBundleExport,
+}
+
+var bundleApplyCmd = &cobra.Command{
+	Use:   "apply <bundle>",
+	Short: "Deploy a bundle",
+	Long: `Verify a bundle, load its image on every host, and deploy it with
+the bundled configuration and hooks.
+
+Every file is checked against the checksums in the bundle manifest before
+anything runs. The checksums catch damage, not tampering: anyone who can
+change the bundle can change its manifest too. Bundled hooks run on this
+machine, so apply refuses a bundle with hooks unless given --allow-hooks;
+check the hooks it lists first. Secrets are read from this machine's secrets file and
+environment; apply stops if any the bundle needs is missing. Images are
+loaded over SSH, so the hosts need no registry access.
+
+Example:
+  azud bundle apply azud-app-abc123.tar.gz
+  azud bundle apply release.tar.gz --yes
+  azud bundle apply release.tar.gz --allow-hooks`,
+	Args: cobra.ExactArgs(1),
+	RunE: runBundleApply,
+}
+
+var (
+	bundleVersion    string
+	bundleOutput     string
+	bundleAllowHooks bool
+)
+
+func init() {
+	bundleExportCmd.Flags().StringVar(&bundleVersion, "version", "", "Version/tag to export (default: the tag azud build gives the current commit)")
+	bundleExportCmd.Flags().StringVarP(&bundleOutput, "output", "o", "", "Bundle path (default azud-<service>-<version>.tar.gz)")
+	bundleApplyCmd.Flags().BoolVar(&bundleAllowHooks, "allow-hooks", false, "Run the hooks in the bundle")
+	bundleApplyCmd.Flags().BoolVar(&deployYes, "yes", false, "Approve the deployment plan without prompting (deploy.require_approval)")
+	bundleApplyCmd.Flags().BoolVar(&deployOverride, "override", false, deployOverrideUsage)
+	bundleApplyCmd.Flags().StringVar(&deployApprovalToken, "approval-token", os.Getenv("AZUD_APPROVAL_TOKEN"), "Token sent to deploy.approval_webhook (default: $AZUD_APPROVAL_TOKEN)")
+
+	bundleCmd.AddCommand(bundleExportCmd)
+	bundleCmd.AddCommand(bundleApplyCmd)
+	rootCmd.AddCommand(bundleCmd)
+}
+
+func runBundleExport(cmd *cobra.Command, args []string) error {
+	output.SetVerbose(verbose)
+	log := output.DefaultLogger
+
+	image := stripImageReference(cfg.Image) + ":" + bundleVersion
+	if bundleVersion == "" {
+		tag, err := generateImageTag(cfg.Image, GetDestination())
+		if err != nil {
+			return err
+		}
+		image = tag
+	}
+	version := image[strings.LastIndex(image, ":")+1:]
+
+	log.Header("Bundle / %s %s", cfg.Service, version)
+
+	localPath := bundleOutput
+	if localPath == "" {
+		localPath = fmt.Sprintf("azud-%s-%s.tar.gz", cfg.Service, version)
+	}
+
+	tmp, err := os.MkdirTemp("", "azud-bundle-*")
+	if err != nil {
+		return err
+	}
+	defer func() { _ = os.RemoveAll(tmp) }()
+
+	imagePath := filepath.Join(tmp, bundle.ImageFile)
+	if err := saveLocalImage(log, image, imagePath); err != nil {
+		return err
+	}
+
+	w, err := bundle.Create(localPath)
+	if err != nil {
+		return err
+	}
+	if err := writeBundleFiles(w, imagePath); err != nil {
+		w.Abort()
+		return err
+	}
+
+	secrets := cfg.SecretNames()
+	if err := w.Close(&bundle.Manifest{
+		Service:     cfg.Service,
+		Image:       image,
+		Version:     version,
+		Destination: GetDestination(),
+		CreatedAt:   time.Now().UTC(),
+		CreatedBy:   deploy.CurrentUser(),
+		Config:      path.Join(bundle.ConfigDir, filepath.Base(GetConfigPath())),
+		Secrets:     secrets,
+	}); err != nil {
+		return fmt.Errorf("failed to write bundle: %w", err)
+	}
+
+	log.Success("Bundle written to %s", localPath)
+	log.Info("Image %s, %d secret name(s)", image, len(secrets))
+	return nil
+}
+
+// writeBundleFiles adds the saved image, the configuration files, and the
+// hooks to w.
+func writeBundleFiles(w *bundle.Writer, imagePath string) error {
+	if err := w.AddFile(bundle.ImageFile, imagePath); err != nil {
+		return err
+	}
+
+	configFile := GetConfigPath()
+	bundledConfig := path.Join(bundle.ConfigDir, filepath.Base(configFile))
+	if err := w.AddFile(bundledConfig, configFile); err != nil {
+		return err
+	}
+	if dest := GetDestination(); dest != "" {
+		destFile := config.DestinationPath(configFile, dest)
+		if _, err := os.Stat(destFile); err == nil {
+			if err := w.AddFile(path.Join(bundle.ConfigDir, filepath.Base(destFile)), destFile); err != nil {
+				return err
+			}
+		}
+	}
+
+	hooks, err := bundleHookFiles(cfg.HooksPath)
+	if err != nil {
+		return err
+	}
+	for _, name := range hooks {
+		if err := w.AddFile(path.Join(bundle.HooksDir, name), filepath.Join(cfg.HooksPath, name)); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// saveLocalImage writes image from the build engine's local store to dest,
+// pulling it first if the store does not have it.
+func saveLocalImage(log *output.Logger, image, dest string) error {
+	engine := buildEngine()
+	if exec.Command(engine, "image", "inspect", image).Run() != nil {
+		log.Info("Pulling %s...", image)
+		pull := exec.Command(engine, "pull", image)
+		pull.Stdout = os.Stdout
+		pull.Stderr = os.Stderr
+		if err := pull.Run(); err != nil {
+			return fmt.Errorf("image %s is not available locally or in the registry; run azud build first: %w", image, err)
+		}
+	}
+
+	log.Info("Saving %s...", image)
+	save := exec.Command(engine, "save", "-o", dest, image)
+	save.Stdout = os.Stdout
+	save.Stderr = os.Stderr
+	if err := save.Run(); err != nil {
+		return fmt.Errorf("%s save failed: %w", engine, err)
+	}
+	return nil
+}
+
+// bundleHookFiles returns the regular files in hooksPath, which may not
+// exist.
+func bundleHookFiles(hooksPath string) ([]string, error) {
+	entries, err := os.ReadDir(hooksPath)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var names []string
+	for _, entry := range entries {
+		if entry.Type().IsRegular() {
+			names = append(names, entry.Name())
+		}
+	}
+	return names, nil
+}
+
+func runBundleApply(cmd *cobra.Command, args []string) error {
+	output.SetVerbose(verbose)
+	log := output.DefaultLogger
+
+	dir, err := os.MkdirTemp("", "azud-bundle-*")
+	if err != nil {
+		return err
+	}
+	defer func() { _ = os.RemoveAll(dir) }()
+
+	manifest, err := bundle.Extract(args[0], dir)
+	if err != nil {
+		return fmt.Errorf("%s: %w", args[0], err)
+	}
+	if destination != "" && destination != manifest.Destination {
+		return fmt.Errorf("-d %s conflicts with the bundle, which was exported for %s", destination, deploy.DestinationLabel(manifest.Destination))
+	}
+
+	// Deploy exactly what was exported: the bundled configuration for the
+	// bundled destination, with the bundled hooks.
+	configPath = filepath.Join(dir, filepath.FromSlash(manifest.Config))
+	destination = manifest.Destination
+	loaded, err := loadConfig()
+	if err != nil {
+		return err
+	}
+	cfg = loaded
+	if err := checkAccess(cmd, cfg); err != nil {
+		return err
+	}
+	cfg.HooksPath = filepath.Join(dir, bundle.HooksDir)
+
+	log.Header("Bundle / %s %s", cfg.Service, manifest.Version)
+	log.Info("Exported by %s at %s for %s", manifest.CreatedBy, manifest.CreatedAt.Local().Format(time.RFC822), deploy.DestinationLabel(manifest.Destination))
+	hooks := manifest.Hooks()
+	if len(hooks) > 0 {
+		log.Info("Bundled hooks: %s", strings.Join(hooks, ", "))
+	}
+	if err := checkBundledHooks(hooks, bundleAllowHooks); err != nil {
+		return err
+	}
+
+	if cfg.Service != manifest.Service || stripImageReference(cfg.Image) != stripImageReference(manifest.Image) {
+		return fmt.Errorf("bundle manifest (%s, %s) does not match its configuration (%s, %s)", manifest.Service, manifest.Image, cfg.Service, cfg.Image)
+	}
+	if cfg.Deploy.RequireDigest {
+		return fmt.Errorf("deploy.require_digest needs a registry digest, which a bundled image does not carry; disable it to apply bundles")
+	}
+
+	var missing []string
+	for _, name := range manifest.Secrets {
+		if !secretAvailable(name) {
+			missing = append(missing, name)
+		}
+	}
+	if len(missing) > 0 {
+		return fmt.Errorf("missing secrets the bundle needs: %s", strings.Join(missing, ", "))
+	}
+
+	sshClient := createSSHClient()
+	defer func() { _ = sshClient.Close() }()
+
+	imagePath := filepath.Join(dir, bundle.ImageFile)
+	for _, host := range bundleImageHosts(cfg) {
+		log.Host(host, "Loading %s...", manifest.Image)
+		if err := loadBundledImage(sshClient, host, imagePath); err != nil {
+			return err
+		}
+	}
+
+	deployer := deploy.NewDeployer(cfg, sshClient, log)
+	if err := deployer.Deploy(cmd.Context(), &deploy.DeployOptions{
+		Version:       manifest.Version,
+		SkipPull:      true,
+		Destination:   manifest.Destination,
+		Approve:       deployApprover(cmd),
+		ApprovalToken: deployApprovalToken,
+		Override:      deployOverride,
+		Metadata: map[string]string{
+			"bundle":            filepath.Base(args[0]),
+			"bundle_created_by": manifest.CreatedBy,
+		},
+	}); err != nil {
+		return err
+	}
+	syncProxyRoutesAfterChange(sshClient, log)
+	return nil
+}
+
+// checkBundledHooks refuses to run a bundle's hooks, which run on this
+// machine, unless the operator allowed them.
+func checkBundledHooks(hooks []string, allow bool) error {
+	if len(hooks) == 0 || allow {
+		return nil
+	}
+	return fmt.Errorf("bundle contains hooks (%s) that would run on this machine; review them and pass --allow-hooks to apply it", strings.Join(hooks, ", "))
+}
+
+// bundleImageHosts returns the hosts that run the application image.
+func bundleImageHosts(cfg *config.Config) []string {
+	hosts := cfg.GetAllHosts()
+	for _, host := range cfg.GetAllCronHosts() {
+		if !slices.Contains(hosts, host) {
+			hosts = append(hosts, host)
+		}
+	}
+	return hosts
+}
+
+func loadBundledImage(sshClient *ssh.Client, host, imagePath string) error {
+	remotePath, cleanup, err := remoteTempFile(sshClient, host)
+	if err != nil {
+		return err
+	}
+	defer cleanup()
+
+	if err := sshClient.Upload(host, imagePath, remotePath); err != nil {
+		return fmt.Errorf("%s: failed to upload image: %w", host, err)
+	}
+	if err := podman.NewImageManager(podman.NewClient(sshClient)).Load(host, remotePath); err != nil {
+		return fmt.Errorf("%s: %w", host, err)
+	}
+	return nil
+}
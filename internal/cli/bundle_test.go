@@ -0,0 +1,19 @@
+package cli
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestCheckBundledHooks(t *testing.T) {
+	if err := checkBundledHooks(nil, false); err != nil {
+		t.Fatalf("bundle without hooks: %v", err)
+	}
+	err := checkBundledHooks([]string{"post-deploy", "pre-deploy"}, false)
+	if err == nil || !strings.Contains(err.Error(), "post-deploy, pre-deploy") || !strings.Contains(err.Error(), "--allow-hooks") {
+		t.Fatalf("checkBundledHooks() = %v, want a refusal naming the hooks and --allow-hooks", err)
+	}
+	if err := checkBundledHooks([]string{"pre-deploy"}, true); err != nil {
+		t.Fatalf("allowed hooks: %v", err)
+	}
+}
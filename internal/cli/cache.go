@@ -0,0 +1,216 @@
+package cli
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/hex"
+	"encoding/pem"
+	"fmt"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/lemonity-org/azud/internal/config"
+	"github.com/lemonity-org/azud/internal/output"
+	"github.com/lemonity-org/azud/internal/shell"
+	"github.com/lemonity-org/azud/internal/ssh"
+)
+
+// cacheCertificateValidity is how long a generated cache certificate lasts.
+const cacheCertificateValidity = 10 * 365 * 24 * time.Hour
+
+// generateCachePasswords adds a random password to the local secrets file
+// for every cache accessory that requires one and has none yet.
+func generateCachePasswords(log *output.Logger) error {
+	var keys []string
+	for _, name := range cfg.GetAccessoryNames() {
+		if cfg.Accessories[name].Cache.Password {
+			keys = append(keys, config.CachePasswordSecret(name))
+		}
+	}
+	if len(keys) == 0 {
+		return nil
+	}
+
+	secretsPath := getSecretsFilePath()
+	secrets, err := loadSecretsFile(secretsPath)
+	if err != nil {
+		return fmt.Errorf("failed to load secrets: %w", err)
+	}
+	var generated []string
+	for _, key := range keys {
+		if _, ok := secrets[key]; ok {
+			continue
+		}
+		buf := make([]byte, 32)
+		if _, err := rand.Read(buf); err != nil {
+			return fmt.Errorf("failed to generate %s: %w", key, err)
+		}
+		secrets[key] = hex.EncodeToString(buf)
+		generated = append(generated, key)
+	}
+	if len(generated) == 0 {
+		return nil
+	}
+
+	if err := os.MkdirAll(filepath.Dir(secretsPath), 0755); err != nil {
+		return fmt.Errorf("failed to create secrets directory: %w", err)
+	}
+	if err := writeSecretsFile(secretsPath, secrets); err != nil {
+		return err
+	}
+	for _, key := range generated {
+		log.Success("Generated %s in %s", key, secretsPath)
+	}
+	return nil
+}
+
+// provisionCacheTLS installs the certificate of a cache accessory with TLS:
+// with its key on the accessory hosts and alone on the application hosts.
+// The certificate already on the first accessory host is reused, so
+// redeploying the accessory does not invalidate the copies the
+// application trusts.
+func provisionCacheTLS(sshClient *ssh.Client, log *output.Logger, name string) error {
+	accessory := cfg.Accessories[name]
+	if !accessory.Cache.TLS {
+		return nil
+	}
+	hosts := accessoryHosts(accessory)
+	if len(hosts) == 0 {
+		return nil
+	}
+	dir := config.CacheTLSDir(cfg, name)
+
+	certPEM, keyPEM, ok := readCacheCertificate(sshClient, hosts[0], dir)
+	if !ok {
+		names := append([]string{fmt.Sprintf("%s-%s", cfg.Service, name), name, "localhost"}, hosts...)
+		var err error
+		if certPEM, keyPEM, err = newCacheCertificate(names, time.Now()); err != nil {
+			return fmt.Errorf("failed to generate TLS certificate: %w", err)
+		}
+		log.Info("Generated TLS certificate for accessory %s", name)
+	}
+
+	written := make(map[string]bool)
+	for _, host := range hosts {
+		if err := writeCacheTLSFile(sshClient, host, dir, "tls.crt", certPEM); err != nil {
+			return fmt.Errorf("failed to install TLS certificate on %s: %w", host, err)
+		}
+		if err := writeCacheTLSFile(sshClient, host, dir, "tls.key", keyPEM); err != nil {
+			return fmt.Errorf("failed to install TLS key on %s: %w", host, err)
+		}
+		written[host] = true
+	}
+	for _, host := range append(cfg.GetAllHosts(), cfg.GetAllCronHosts()...) {
+		if written[host] {
+			continue
+		}
+		if err := writeCacheTLSFile(sshClient, host, dir, "tls.crt", certPEM); err != nil {
+			return fmt.Errorf("failed to install TLS certificate on %s: %w", host, err)
+		}
+		written[host] = true
+	}
+	log.Success("TLS certificate of accessory %s installed on %d host(s)", name, len(written))
+	return nil
+}
+
+// readCacheCertificate returns the certificate and key in dir on host, if
+// both are present and match.
+func readCacheCertificate(sshClient *ssh.Client, host, dir string) ([]byte, []byte, bool) {
+	result, err := sshClient.Execute(host, fmt.Sprintf(`dir=%s; cat "$dir/tls.crt" "$dir/tls.key" 2>/dev/null`, shell.QuoteRemotePath(dir)))
+	if err != nil || result.ExitCode != 0 {
+		return nil, nil, false
+	}
+	return parseCacheCertificate([]byte(result.Stdout))
+}
+
+// parseCacheCertificate splits the PEM certificate and key in data.
+func parseCacheCertificate(data []byte) ([]byte, []byte, bool) {
+	var certPEM, keyPEM []byte
+	for {
+		var block *pem.Block
+		block, data = pem.Decode(data)
+		if block == nil {
+			break
+		}
+		switch {
+		case block.Type == "CERTIFICATE" && certPEM == nil:
+			certPEM = pem.EncodeToMemory(block)
+		case strings.HasSuffix(block.Type, "PRIVATE KEY") && keyPEM == nil:
+			keyPEM = pem.EncodeToMemory(block)
+		}
+	}
+	if certPEM == nil || keyPEM == nil {
+		return nil, nil, false
+	}
+	if _, err := tls.X509KeyPair(certPEM, keyPEM); err != nil {
+		return nil, nil, false
+	}
+	return certPEM, keyPEM, true
+}
+
+// newCacheCertificate returns a self-signed certificate valid for names,
+// which are host names or IP addresses, and its key.
+func newCacheCertificate(names []string, now time.Time) ([]byte, []byte, error) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, nil, err
+	}
+	serial, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 128))
+	if err != nil {
+		return nil, nil, err
+	}
+
+	template := &x509.Certificate{
+		SerialNumber:          serial,
+		Subject:               pkix.Name{CommonName: names[0]},
+		NotBefore:             now.Add(-time.Hour),
+		NotAfter:              now.Add(cacheCertificateValidity),
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		BasicConstraintsValid: true,
+		// Clients trust the certificate itself, so it is its own CA.
+		IsCA: true,
+	}
+	for _, name := range names {
+		if ip := net.ParseIP(name); ip != nil {
+			template.IPAddresses = append(template.IPAddresses, ip)
+		} else {
+			template.DNSNames = append(template.DNSNames, name)
+		}
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		return nil, nil, err
+	}
+	keyDER, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		return nil, nil, err
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}),
+		pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: keyDER}), nil
+}
+
+// writeCacheTLSFile replaces file in dir on host with content. The parents
+// of dir stay private to the deploy user; dir and its files are readable by
+// the unprivileged user the cache server runs as in its container.
+func writeCacheTLSFile(sshClient *ssh.Client, host, dir, file string, content []byte) error {
+	cmd := fmt.Sprintf(`dir=%s; path="$dir/%s"; tmp="${path}.tmp.$$"; umask 077 && mkdir -p "$dir" && chmod 755 "$dir" && umask 022 && trap 'rm -f "$tmp"' EXIT HUP INT TERM && cat > "$tmp" && mv "$tmp" "$path" && trap - EXIT`,
+		shell.QuoteRemotePath(dir), file)
+	result, err := sshClient.ExecuteWithStdin(host, cmd, strings.NewReader(string(content)))
+	if err != nil {
+		return err
+	}
+	if result.ExitCode != 0 {
+		return fmt.Errorf("%s", strings.TrimSpace(result.Stderr))
+	}
+	return nil
+}
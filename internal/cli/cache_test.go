@@ -0,0 +1,41 @@
+package cli
+
+import (
+	"crypto/x509"
+	"encoding/pem"
+	"testing"
+	"time"
+)
+
+func TestNewCacheCertificate(t *testing.T) {
+	now := time.Date(2025, 5, 1, 12, 0, 0, 0, time.UTC)
+	certPEM, keyPEM, err := newCacheCertificate([]string{"shop-cache", "cache", "203.0.113.10"}, now)
+	if err != nil {
+		t.Fatalf("newCacheCertificate() error = %v", err)
+	}
+
+	block, _ := pem.Decode(certPEM)
+	cert, err := x509.ParseCertificate(block.Bytes)
+	if err != nil {
+		t.Fatal(err)
+	}
+	roots := x509.NewCertPool()
+	roots.AddCert(cert)
+	for _, name := range []string{"shop-cache", "cache", "203.0.113.10"} {
+		if _, err := cert.Verify(x509.VerifyOptions{DNSName: name, Roots: roots, CurrentTime: now}); err != nil {
+			t.Errorf("certificate does not verify for %s: %v", name, err)
+		}
+	}
+
+	gotCert, gotKey, ok := parseCacheCertificate(append(append([]byte{}, certPEM...), keyPEM...))
+	if !ok || string(gotCert) != string(certPEM) || string(gotKey) != string(keyPEM) {
+		t.Errorf("parseCacheCertificate() did not return the certificate and key")
+	}
+	if _, _, ok := parseCacheCertificate(certPEM); ok {
+		t.Error("parseCacheCertificate() accepted a certificate without key")
+	}
+	_, otherKey, _ := newCacheCertificate([]string{"other"}, now)
+	if _, _, ok := parseCacheCertificate(append(append([]byte{}, certPEM...), otherKey...)); ok {
+		t.Error("parseCacheCertificate() accepted a key of another certificate")
+	}
+}
@@ -38,6 +38,7 @@ var canaryDeployCmd = &cobra.Command{
 
 The canary will receive a small percentage of traffic initially.
 Monitor its performance, then use 'canary promote' or 'canary rollback'.
+Requests matching proxy.ab_tests go to the canary regardless of its weight.
 
 Example:
   azud canary deploy --version abc123             # Deploy with default 10%
@@ -117,6 +118,7 @@ func init() {
 	canaryDeployCmd.Flags().IntVar(&canaryInitialWeight, "weight", 0, "Initial traffic percentage (default: from config or 10)")
 	canaryDeployCmd.Flags().BoolVar(&canarySkipPull, "skip-pull", false, "Skip image pull")
 	canaryDeployCmd.Flags().BoolVar(&canarySkipHealth, "skip-health", false, "Skip health check")
+	canaryDeployCmd.Flags().BoolVar(&deployOverride, "override", false, deployOverrideUsage)
 	_ = canaryDeployCmd.MarkFlagRequired("version")
 
 	// Add subcommands
@@ -159,6 +161,8 @@ func runCanaryDeploy(cmd *cobra.Command, args []string) error {
 		SkipPull:        canarySkipPull,
 		SkipHealthCheck: canarySkipHealth,
 		Destination:     GetDestination(),
+		Override:        deployOverride,
+		Metadata:        deployMetadata(),
 	}
 
 	return deployer.Deploy(opts)
@@ -0,0 +1,202 @@
+package cli
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"slices"
+
+	"github.com/spf13/cobra"
+
+	"github.com/lemonity-org/azud/internal/config"
+	"github.com/lemonity-org/azud/internal/deploy"
+	"github.com/lemonity-org/azud/internal/output"
+)
+
+// completionFunc completes a positional argument or flag value.
+type completionFunc func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective)
+
+// registerCompletions wires completions that read the configuration: hosts
+// and roles for every --host and --role flag, destinations, and names for the
+// arguments of accessory, cron, env, history, rollback, and scale commands,
+// and resumable deployment IDs for deploy --resume.
+func registerCompletions(root *cobra.Command) {
+	_ = root.RegisterFlagCompletionFunc("destination", completeDestinations)
+
+	var walk func(*cobra.Command)
+	walk = func(command *cobra.Command) {
+		for name, complete := range map[string]completionFunc{"host": completeHosts, "role": completeRoles} {
+			if command.LocalNonPersistentFlags().Lookup(name) == nil {
+				continue
+			}
+			if _, registered := command.GetFlagCompletionFunc(name); !registered {
+				_ = command.RegisterFlagCompletionFunc(name, complete)
+			}
+		}
+		for _, child := range command.Commands() {
+			walk(child)
+		}
+	}
+	walk(root)
+
+	setArgsCompletion(completeFirstArg(completeAccessoryNames),
+		accessoryBootCmd, accessoryStopCmd, accessoryRestartCmd, accessoryLogsCmd, accessoryExecCmd, accessoryRemoveCmd)
+	setArgsCompletion(completeFirstArg(completeCronNames), cronBootCmd, cronStopCmd, cronLogsCmd, cronRunCmd)
+	setArgsCompletion(completeFirstArg(completeSecretKeys), envGetCmd, envSetCmd, envDeleteCmd, envRotateCmd)
+	setArgsCompletion(completeFirstArg(completeDeploymentIDs), historyShowCmd)
+	_ = deployCmd.RegisterFlagCompletionFunc("resume", completeResumableDeploymentIDs)
+	setArgsCompletion(completeFirstArg(completeVersions), rollbackCmd)
+	setArgsCompletion(completeFirstArg(completeScaleRoles), scaleCmd)
+	setArgsCompletion(completeHostArgs, serverBootstrapCmd, sshTrustCmd, appMoveCmd)
+}
+
+func setArgsCompletion(complete completionFunc, commands ...*cobra.Command) {
+	for _, command := range commands {
+		if command.ValidArgsFunction == nil {
+			command.ValidArgsFunction = complete
+		}
+	}
+}
+
+// completeFirstArg limits a completion to the first positional argument.
+func completeFirstArg(complete completionFunc) completionFunc {
+	return func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+		if len(args) > 0 {
+			return nil, cobra.ShellCompDirectiveNoFileComp
+		}
+		return complete(cmd, args, toComplete)
+	}
+}
+
+// completionConfig loads the configuration for completion. The global cfg is
+// not set because PersistentPreRunE does not run for completion requests.
+func completionConfig() *config.Config {
+	if cfg != nil {
+		return cfg
+	}
+	path := configPath
+	if path == "" {
+		path = findConfigFile()
+	}
+	if path == "" {
+		return nil
+	}
+	loaded, err := config.NewLoader(path, destination).LoadUnvalidated()
+	if err != nil {
+		return nil
+	}
+	return loaded
+}
+
+// completeNames completes from names produced by the configuration.
+func completeNames(names func(*config.Config) []string) completionFunc {
+	return func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+		loaded := completionConfig()
+		if loaded == nil {
+			return nil, cobra.ShellCompDirectiveNoFileComp
+		}
+		return names(loaded), cobra.ShellCompDirectiveNoFileComp
+	}
+}
+
+var (
+	completeHosts          = completeNames((*config.Config).GetAllSSHHosts)
+	completeRoles          = completeNames((*config.Config).GetRoles)
+	completeAccessoryNames = completeNames((*config.Config).GetAccessoryNames)
+	completeCronNames      = completeNames((*config.Config).GetCronNames)
+	completeSecretKeys     = completeNames(completionSecretKeys)
+)
+
+// completeHostArgs completes any number of host arguments, skipping hosts
+// already given.
+func completeHostArgs(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	hosts, directive := completeHosts(cmd, args, toComplete)
+	return slices.DeleteFunc(hosts, func(host string) bool { return slices.Contains(args, host) }), directive
+}
+
+// completeScaleRoles completes "role=" for scale, leaving the count to type.
+func completeScaleRoles(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	roles, _ := completeRoles(cmd, args, toComplete)
+	for i, role := range roles {
+		roles[i] = role + "="
+	}
+	return roles, cobra.ShellCompDirectiveNoFileComp | cobra.ShellCompDirectiveNoSpace
+}
+
+// completionSecretKeys returns the declared secret names and the keys in the
+// local secrets file.
+func completionSecretKeys(loaded *config.Config) []string {
+	keys := loaded.SecretNames()
+	if data, err := os.ReadFile(loaded.SecretsPath); err == nil {
+		for key := range parseSecretsContent(string(data)) {
+			keys = append(keys, key)
+		}
+	}
+	slices.Sort(keys)
+	return slices.Compact(keys)
+}
+
+// completeDestinations lists the destinations that have a config overlay,
+// such as config/deploy.staging.yml.
+func completeDestinations(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	path := configPath
+	if path == "" {
+		path = findConfigFile()
+	}
+	if path == "" {
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+	return config.Destinations(path), cobra.ShellCompDirectiveNoFileComp
+}
+
+// completionHistory returns recent deployment records for the service.
+func completionHistory() []*deploy.DeploymentRecord {
+	loaded := completionConfig()
+	if loaded == nil {
+		return nil
+	}
+	store := deploy.NewDurableHistoryStore(loaded.Deploy.RetainHistory, output.NewLogger(io.Discard, io.Discard, false))
+	records, err := store.List(loaded.Service, 50)
+	if err != nil {
+		return nil
+	}
+	return records
+}
+
+// completeDeploymentIDs completes deployment record IDs, described by
+// version and status.
+func completeDeploymentIDs(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	var ids []string
+	for _, record := range completionHistory() {
+		ids = append(ids, fmt.Sprintf("%s\t%s %s", record.ID, valueOrDash(record.Version), record.Status))
+	}
+	return ids, cobra.ShellCompDirectiveNoFileComp | cobra.ShellCompDirectiveKeepOrder
+}
+
+// completeResumableDeploymentIDs completes IDs of deployments that failed or
+// were interrupted before every target finished.
+func completeResumableDeploymentIDs(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	var ids []string
+	for _, record := range completionHistory() {
+		if record.Resumable() != nil {
+			continue
+		}
+		ids = append(ids, fmt.Sprintf("%s\t%s %d/%d remaining", record.ID, valueOrDash(record.Version), len(record.PendingTargets()), len(record.Targets)))
+	}
+	return ids, cobra.ShellCompDirectiveNoFileComp | cobra.ShellCompDirectiveKeepOrder
+}
+
+// completeVersions completes versions that were deployed successfully,
+// newest first.
+func completeVersions(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	var versions []string
+	seen := make(map[string]bool)
+	for _, record := range completionHistory() {
+		if record.Version == "" || record.Status != deploy.StatusSuccess || seen[record.Version] {
+			continue
+		}
+		seen[record.Version] = true
+		versions = append(versions, fmt.Sprintf("%s\tdeployed %s", record.Version, formatHistoryTime(record.StartedAt)))
+	}
+	return versions, cobra.ShellCompDirectiveNoFileComp | cobra.ShellCompDirectiveKeepOrder
+}
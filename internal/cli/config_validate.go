@@ -0,0 +1,118 @@
+package cli
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/spf13/cobra"
+
+	"github.com/lemonity-org/azud/internal/config"
+	"github.com/lemonity-org/azud/internal/output"
+)
+
+var configValidateCmd = &cobra.Command{
+	Use:   "validate",
+	Short: "Validate the configuration and report errors and warnings",
+	Long: `Load the configuration with the selected destination and report every
+validation problem with the file, line, and column of the offending key.
+Unknown keys, such as a misspelled healtcheck:, are always errors.
+
+Errors stop every other command from running; warnings do not. The command
+exits non-zero when there are errors.
+
+With --format json, the result is printed as one JSON object so editors
+and CI can annotate the offending lines.
+
+Example:
+  azud config validate
+  azud config validate -d staging --format json`,
+	Args: cobra.NoArgs,
+	RunE: runConfigValidate,
+}
+
+var configValidateFormat string
+
+func init() {
+	configValidateCmd.Flags().StringVar(&configValidateFormat, "format", "text", "Output format (text, json)")
+	configCmd.AddCommand(configValidateCmd)
+}
+
+// configValidation is the JSON result of azud config validate.
+type configValidation struct {
+	File     string                  `json:"file"`
+	Valid    bool                    `json:"valid"`
+	Errors   config.ValidationErrors `json:"errors"`
+	Warnings config.ValidationErrors `json:"warnings"`
+}
+
+func runConfigValidate(cmd *cobra.Command, args []string) error {
+	log := output.DefaultLogger
+
+	if configValidateFormat != "text" && configValidateFormat != "json" {
+		return fmt.Errorf("invalid --format %q: must be text or json", configValidateFormat)
+	}
+	path := configPath
+	if path == "" {
+		path = findConfigFile()
+	}
+	if path == "" {
+		return fmt.Errorf("no configuration file found. Run 'azud init' to create one")
+	}
+
+	_, problems, err := config.NewLoader(path, destination).Check()
+	var unknown *config.UnknownKeyError
+	switch {
+	case errors.As(err, &unknown):
+		// Unknown keys stop decoding, so they are the only problem reported.
+		message := "unknown configuration key"
+		if unknown.Suggestion != "" {
+			message += fmt.Sprintf("; did you mean %q?", unknown.Suggestion)
+		}
+		problems = config.ValidationErrors{{
+			Field:    unknown.Path,
+			Message:  message,
+			Severity: config.SeverityError,
+			File:     unknown.File,
+			Line:     unknown.Line,
+			Column:   unknown.Column,
+		}}
+	case err != nil:
+		return err
+	}
+	result := configValidation{
+		File:     path,
+		Errors:   problems.Errors(),
+		Warnings: problems.Warnings(),
+	}
+	result.Valid = len(result.Errors) == 0
+
+	if configValidateFormat == "json" {
+		if result.Errors == nil {
+			result.Errors = config.ValidationErrors{}
+		}
+		if result.Warnings == nil {
+			result.Warnings = config.ValidationErrors{}
+		}
+		data, err := json.MarshalIndent(result, "", "  ")
+		if err != nil {
+			return err
+		}
+		log.Println("%s", data)
+	} else {
+		for _, problem := range result.Errors {
+			log.Error("%s", problem.Error())
+		}
+		for _, problem := range result.Warnings {
+			log.Warn("%s", problem.Error())
+		}
+		if result.Valid {
+			log.Success("%s is valid (%d warning(s))", path, len(result.Warnings))
+		}
+	}
+
+	if !result.Valid {
+		return fmt.Errorf("configuration has %d error(s)", len(result.Errors))
+	}
+	return nil
+}
@@ -222,7 +222,10 @@ func readContainerIgnore(contextDir string) []ignorePattern {
 func withMandatoryContextExcludes(patterns []ignorePattern) []ignorePattern {
 	// These are appended last so user negation patterns cannot re-include
 	// sensitive Azud-local files in remote build contexts.
-	return append(patterns, ignorePattern{pattern: ".azud/secrets"})
+	return append(patterns,
+		ignorePattern{pattern: ".azud/secrets"},
+		ignorePattern{pattern: ".azud/" + proxyCertificatesBackupFile},
+	)
 }
 
 // parseIgnorePatterns parses a .dockerignore file into patterns.
@@ -106,6 +106,7 @@ func TestReadContainerIgnoreAlwaysExcludesAzudSecrets(t *testing.T) {
 	}{
 		{".azud/secrets", false, true},
 		{".azud/secrets/api", false, true},
+		{".azud/proxy-certificates.tar", false, true},
 		{".azud/hooks/post-deploy", false, false},
 		{"debug.log", false, true},
 	}
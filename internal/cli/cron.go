@@ -10,6 +10,7 @@ import (
 	"github.com/spf13/cobra"
 
 	"github.com/lemonity-org/azud/internal/config"
+	"github.com/lemonity-org/azud/internal/deploy"
 	"github.com/lemonity-org/azud/internal/output"
 	"github.com/lemonity-org/azud/internal/podman"
 	"github.com/lemonity-org/azud/internal/shell"
@@ -423,7 +424,8 @@ func buildCronRunContainerConfig(name, containerName string, cronConfig config.C
 		Image:      cfg.Image,
 		Detach:     false,
 		Remove:     true,
-		Network:    "azud",
+		Network:    cfg.RoleNetworks("web")[0],
+		Networks:   cfg.RoleNetworks("web"),
 		Entrypoint: "/bin/sh",
 		Command:    []string{"-c", command},
 		Labels: map[string]string{
@@ -432,7 +434,7 @@ func buildCronRunContainerConfig(name, containerName string, cronConfig config.C
 			"azud.cron":     name,
 			"azud.cron.run": "manual",
 		},
-		Env: make(map[string]string),
+		Env: cfg.AccessoryDiscoveryEnv("web"),
 	}
 
 	// Add environment variables
@@ -442,13 +444,10 @@ func buildCronRunContainerConfig(name, containerName string, cronConfig config.C
 	for key, value := range cronConfig.Env {
 		containerConfig.Env[key] = value
 	}
-	containerConfig.SecretEnv = cfg.Env.Secret
-	if len(containerConfig.SecretEnv) > 0 {
-		containerConfig.EnvFile = config.RemoteSecretsPath(cfg)
-	}
+	deploy.AttachSecrets(cfg, containerConfig, cfg.Env.Secret, config.RemoteSecretsPath(cfg))
 
 	// Add volumes
-	containerConfig.Volumes = cfg.Volumes
+	containerConfig.Volumes = cfg.LabelVolumes(cfg.Volumes)
 
 	return containerConfig
 }
@@ -578,7 +577,8 @@ func buildCronContainerConfig(name string, cronConfig config.CronConfig) *podman
 		Image:      cfg.Image,
 		Detach:     true,
 		Restart:    "unless-stopped",
-		Network:    "azud",
+		Network:    cfg.RoleNetworks("web")[0],
+		Networks:   cfg.RoleNetworks("web"),
 		Entrypoint: "/bin/sh",
 		Command:    []string{"-c", cronCommand},
 		Labels: map[string]string{
@@ -587,7 +587,7 @@ func buildCronContainerConfig(name string, cronConfig config.CronConfig) *podman
 			"azud.cron":          name,
 			"azud.cron.schedule": cronConfig.Schedule,
 		},
-		Env: make(map[string]string),
+		Env: cfg.AccessoryDiscoveryEnv("web"),
 	}
 
 	// Add environment variables from app config
@@ -601,10 +601,7 @@ func buildCronContainerConfig(name string, cronConfig config.CronConfig) *podman
 	}
 
 	// Add secret environment variable names
-	containerConfig.SecretEnv = cfg.Env.Secret
-	if len(containerConfig.SecretEnv) > 0 {
-		containerConfig.EnvFile = config.RemoteSecretsPath(cfg)
-	}
+	deploy.AttachSecrets(cfg, containerConfig, cfg.Env.Secret, config.RemoteSecretsPath(cfg))
 
 	// Add volumes from app config
 	containerConfig.Volumes = append([]string{}, cfg.Volumes...)
@@ -620,6 +617,7 @@ func buildCronContainerConfig(name string, cronConfig config.CronConfig) *podman
 		containerConfig.Volumes = append(containerConfig.Volumes,
 			fmt.Sprintf("%s:/var/lib/azud:rw", stateDir))
 	}
+	containerConfig.Volumes = cfg.LabelVolumes(containerConfig.Volumes)
 
 	return containerConfig
 }
@@ -1,8 +1,14 @@
 package cli
 
 import (
+	"bufio"
+	"context"
 	"fmt"
+	"os"
+	"regexp"
+	"strings"
 
+	"github.com/mattn/go-isatty"
 	"github.com/spf13/cobra"
 
 	"github.com/lemonity-org/azud/internal/deploy"
@@ -24,7 +30,11 @@ This command performs a zero-downtime deployment:
 Example:
   azud deploy                    # Deploy latest version
   azud deploy --version v1.2.3   # Deploy specific version
-  azud deploy --skip-build       # Deploy without building (image already in registry)`,
+  azud deploy --digest <digest>  # Deploy an exact image digest
+  azud deploy --skip-build       # Deploy without building (image already in registry)
+  azud deploy --if-new-digest    # Deploy only if the tag moved in the registry (cron)
+  azud deploy --if-changed       # Deploy only if builder.watch_paths changed (monorepo CI)
+  azud deploy --resume <id>      # Retry targets a failed deploy did not finish`,
 	RunE: runDeploy,
 }
 
@@ -59,8 +69,19 @@ var (
 	deploySkipBuild bool
 	deployHost      string
 	deployRole      string
+	deployResume    string
+	deployDigest    string
+	deployIfNew     bool
+	deployIfChanged bool
+
+	deployYes           bool
+	deployApprovalToken string
+	deployOverride      bool
 )
 
+// deployOverrideUsage describes --override on every command that deploys.
+const deployOverrideUsage = "Deploy during a freeze or outside deploy.windows (recorded in the audit log)"
+
 func init() {
 	// Deploy flags
 	deployCmd.Flags().StringVar(&deployVersion, "version", "", "Version/tag to deploy (default: latest)")
@@ -68,6 +89,18 @@ func init() {
 	deployCmd.Flags().BoolVar(&deploySkipBuild, "skip-build", false, "Skip building the image")
 	deployCmd.Flags().StringVar(&deployHost, "host", "", "Deploy to specific host only")
 	deployCmd.Flags().StringVar(&deployRole, "role", "", "Deploy to specific role only")
+	deployCmd.Flags().StringVar(&deployDigest, "digest", "", "Image digest to deploy (sha256:...); every host runs exactly this image")
+	deployCmd.Flags().StringVar(&deployResume, "resume", "", "Resume a failed deployment by ID, skipping hosts it already completed")
+	deployCmd.Flags().BoolVar(&deployIfNew, "if-new-digest", false, "Deploy only if the image tag points at a digest the last deployment did not run")
+	deployCmd.Flags().BoolVar(&deployIfChanged, "if-changed", false, "Build and deploy only if files under builder.watch_paths changed since the last deployed commit")
+
+	for _, command := range []*cobra.Command{deployCmd, redeployCmd, rollbackCmd, watchCmd} {
+		command.Flags().BoolVar(&deployYes, "yes", false, "Approve the deployment plan without prompting (deploy.require_approval)")
+		command.Flags().StringVar(&deployApprovalToken, "approval-token", os.Getenv("AZUD_APPROVAL_TOKEN"), "Token sent to deploy.approval_webhook (default: $AZUD_APPROVAL_TOKEN)")
+	}
+	for _, command := range []*cobra.Command{deployCmd, redeployCmd, rollbackCmd} {
+		command.Flags().BoolVar(&deployOverride, "override", false, deployOverrideUsage)
+	}
 
 	// Redeploy flags
 	redeployCmd.Flags().StringVar(&deployHost, "host", "", "Redeploy on specific host only")
@@ -81,15 +114,41 @@ func init() {
 	rootCmd.AddCommand(rollbackCmd)
 }
 
+// imageDigestRegex matches an OCI image digest accepted by --digest.
+var imageDigestRegex = regexp.MustCompile(`^sha256:[a-f0-9]{64}$`)
+
 func runDeploy(cmd *cobra.Command, args []string) error {
 	output.SetVerbose(verbose)
 	log := output.DefaultLogger
 
 	log.Header("Deploy / %s", cfg.Service)
 
-	// An explicit version refers to an already tagged image. Building the
-	// current checkout under a different generated tag would be misleading.
-	if deployVersion != "" && !deploySkipBuild {
+	if deployIfNew && (deployDigest != "" || deployResume != "") {
+		return fmt.Errorf("--if-new-digest conflicts with --digest and --resume")
+	}
+	if deployIfChanged && (deployDigest != "" || deployResume != "" || deployVersion != "" || deployIfNew) {
+		return fmt.Errorf("--if-changed conflicts with --digest, --version, --resume, and --if-new-digest")
+	}
+	if deployIfChanged && !watchedPathsChanged(log) {
+		return nil
+	}
+
+	if deployResume != "" {
+		return runDeployResume(cmd)
+	}
+
+	if deployDigest != "" && !imageDigestRegex.MatchString(deployDigest) {
+		return fmt.Errorf("invalid --digest %q (expected sha256:<64 hex characters>)", deployDigest)
+	}
+
+	// An explicit version or digest refers to an already pushed image.
+	// Building the current checkout under a different generated tag would be
+	// misleading.
+	if deployDigest != "" && !deploySkipBuild {
+		log.Info("Explicit digest %s selected; skipping local build", deployDigest)
+	} else if deployIfNew && !deploySkipBuild {
+		log.Info("Deploying the registry image; skipping local build")
+	} else if deployVersion != "" && !deploySkipBuild {
 		log.Info("Explicit version %s selected; skipping local build", deployVersion)
 	} else if !deploySkipBuild {
 		log.Info("Building image...")
@@ -106,6 +165,10 @@ func runDeploy(cmd *cobra.Command, args []string) error {
 		return fmt.Errorf("pre-connect hook failed: %w", err)
 	}
 
+	if cfg.DNS.Enabled() {
+		checkDNSRecords(cmd.Context(), log)
+	}
+
 	// Create deployer
 	sshClient := createSSHClient()
 	defer func() { _ = sshClient.Close() }()
@@ -114,9 +177,14 @@ func runDeploy(cmd *cobra.Command, args []string) error {
 
 	// Build deploy options
 	opts := &deploy.DeployOptions{
-		Version:     deployVersion,
-		SkipPull:    deploySkipPull,
-		Destination: GetDestination(),
+		Version:       deployVersion,
+		Digest:        deployDigest,
+		SkipPull:      deploySkipPull,
+		Destination:   GetDestination(),
+		Approve:       deployApprover(cmd),
+		ApprovalToken: deployApprovalToken,
+		Override:      deployOverride,
+		Metadata:      deployMetadata(),
 	}
 
 	if deployHost != "" {
@@ -128,7 +196,78 @@ func runDeploy(cmd *cobra.Command, args []string) error {
 	}
 
 	// Run deployment
-	return deployer.Deploy(cmd.Context(), opts)
+	if deployIfNew {
+		deployed, err := deployIfNewDigest(cmd.Context(), log, deployer, opts)
+		if err != nil || !deployed {
+			return err
+		}
+	} else if err := deployer.Deploy(cmd.Context(), opts); err != nil {
+		return err
+	}
+	syncProxyRoutesAfterChange(sshClient, log)
+	return nil
+}
+
+// deployIfNewDigest resolves the image tag to its registry digest and
+// deploys that digest, unless the last successful deployment to the
+// destination already ran it or a restart-loop rollback backed out of it.
+// It reports whether it deployed.
+func deployIfNewDigest(ctx context.Context, log *output.Logger, deployer *deploy.Deployer, opts *deploy.DeployOptions) (bool, error) {
+	image, digest, err := deployer.ResolveTagDigest(ctx, opts.Version)
+	if err != nil {
+		return false, err
+	}
+	if last, err := newHistoryStore(log).GetLastSuccessfulTo(cfg.Service, opts.Destination); err == nil {
+		switch digest {
+		case last.Metadata["image_digest"]:
+			log.Success("%s is up to date (%s)", image, digest)
+			return false, nil
+		case last.Metadata["rolled_back_digest"]:
+			log.Warn("Not deploying %s (%s): deployment %s rolled it back", image, digest, last.ID)
+			return false, nil
+		}
+	}
+
+	log.Info("%s now points at %s", image, digest)
+	opts.Version = ""
+	opts.Digest = digest
+	if opts.Metadata == nil {
+		opts.Metadata = map[string]string{}
+	}
+	opts.Metadata["image_tag"] = image
+	if err := deployer.Deploy(ctx, opts); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// runDeployResume continues a recorded deployment. The image, version, and
+// targets come from the record, so nothing is rebuilt or reselected.
+func runDeployResume(cmd *cobra.Command) error {
+	if deployVersion != "" || deployDigest != "" || deployHost != "" || deployRole != "" {
+		return fmt.Errorf("--resume cannot be combined with --version, --digest, --host, or --role")
+	}
+
+	hookCtx := newHookContext()
+	if err := newHookRunner().Run(cmd.Context(), "pre-connect", hookCtx); err != nil {
+		return fmt.Errorf("pre-connect hook failed: %w", err)
+	}
+
+	sshClient := createSSHClient()
+	defer func() { _ = sshClient.Close() }()
+
+	deployer := deploy.NewDeployer(cfg, sshClient, output.DefaultLogger)
+	if err := deployer.Resume(cmd.Context(), deployResume, &deploy.DeployOptions{
+		SkipPull:      deploySkipPull,
+		Destination:   GetDestination(),
+		Approve:       deployApprover(cmd),
+		ApprovalToken: deployApprovalToken,
+		Override:      deployOverride,
+	}); err != nil {
+		return err
+	}
+	syncProxyRoutesAfterChange(sshClient, output.DefaultLogger)
+	return nil
 }
 
 func runRedeploy(cmd *cobra.Command, args []string) error {
@@ -150,8 +289,11 @@ func runRedeploy(cmd *cobra.Command, args []string) error {
 	deployer := deploy.NewDeployer(cfg, sshClient, log)
 
 	opts := &deploy.DeployOptions{
-		SkipPull:    true, // Don't pull, use existing image
-		Destination: GetDestination(),
+		SkipPull:      true, // Don't pull, use existing image
+		Destination:   GetDestination(),
+		Approve:       deployApprover(cmd),
+		ApprovalToken: deployApprovalToken,
+		Override:      deployOverride,
 	}
 
 	if deployHost != "" {
@@ -162,7 +304,11 @@ func runRedeploy(cmd *cobra.Command, args []string) error {
 		opts.Roles = []string{deployRole}
 	}
 
-	return deployer.Redeploy(cmd.Context(), opts)
+	if err := deployer.Redeploy(cmd.Context(), opts); err != nil {
+		return err
+	}
+	syncProxyRoutesAfterChange(sshClient, log)
+	return nil
 }
 
 func runRollback(cmd *cobra.Command, args []string) error {
@@ -192,5 +338,35 @@ func runRollback(cmd *cobra.Command, args []string) error {
 		hosts = []string{deployHost}
 	}
 
-	return deployer.Rollback(cmd.Context(), version, GetDestination(), hosts)
+	return deployer.Rollback(cmd.Context(), version, &deploy.DeployOptions{
+		Destination:   GetDestination(),
+		Hosts:         hosts,
+		Approve:       deployApprover(cmd),
+		ApprovalToken: deployApprovalToken,
+		Override:      deployOverride,
+	})
+}
+
+// deployApprover confirms a plan for deploy.require_approval. --yes approves
+// it outright; otherwise the operator must type "yes" at the prompt.
+func deployApprover(cmd *cobra.Command) deploy.Approver {
+	return func(plan *deploy.DeployPlan) (string, error) {
+		if deployYes {
+			return plan.Performer + " (--yes)", nil
+		}
+		in := cmd.InOrStdin()
+		if in == os.Stdin && !isatty.IsTerminal(os.Stdin.Fd()) {
+			return "", fmt.Errorf("confirmation required but stdin is not a TTY (use --yes or deploy.approval_webhook)")
+		}
+
+		_, _ = fmt.Fprint(cmd.OutOrStdout(), "  CONFIRM   Type 'yes' to deploy: ")
+		line, err := bufio.NewReader(in).ReadString('\n')
+		if err != nil && strings.TrimSpace(line) == "" {
+			return "", fmt.Errorf("confirmation aborted: %w", err)
+		}
+		if !strings.EqualFold(strings.TrimSpace(line), "yes") {
+			return "", fmt.Errorf("deployment declined")
+		}
+		return plan.Performer, nil
+	}
 }
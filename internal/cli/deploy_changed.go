@@ -0,0 +1,137 @@
+package cli
+
+import (
+	"errors"
+	"fmt"
+	"os/exec"
+	"strings"
+
+	"github.com/lemonity-org/azud/internal/config"
+	"github.com/lemonity-org/azud/internal/deploy"
+	"github.com/lemonity-org/azud/internal/output"
+)
+
+// watchPaths returns the paths whose changes let 'azud deploy --if-changed'
+// go ahead: builder.watch_paths, or the build context.
+func watchPaths() []string {
+	if len(cfg.Builder.WatchPaths) > 0 {
+		return cfg.Builder.WatchPaths
+	}
+	if cfg.Builder.Context != "" {
+		return []string{cfg.Builder.Context}
+	}
+	return []string{"."}
+}
+
+// comparedPaths returns the paths --if-changed compares: the watch paths,
+// plus the loaded configuration files and the hooks, which change what a
+// deploy does without changing the image.
+func comparedPaths() []string {
+	paths := append([]string(nil), watchPaths()...)
+	if configFile := GetConfigPath(); configFile != "" {
+		paths = append(paths, configFile)
+		if dest := GetDestination(); dest != "" {
+			paths = append(paths, config.DestinationPath(configFile, dest))
+		}
+	}
+	if cfg.HooksPath != "" {
+		paths = append(paths, cfg.HooksPath)
+	}
+	return paths
+}
+
+// changeBaseline returns the deployment --if-changed compares against: the
+// newest successful deployment to destination that covered every target
+// and built its image from a recorded commit. Partial deploys (--host,
+// --role) are passed over. Deploys of prebuilt images (--version, --digest)
+// are passed over too, unless one deployed a different version than the
+// baseline; that deployment is returned as replaced, since the destination
+// no longer runs the baseline's build. records are newest first.
+func changeBaseline(records []*deploy.DeploymentRecord, destination string) (baseline, replaced *deploy.DeploymentRecord) {
+	var prebuilt []*deploy.DeploymentRecord
+	for _, record := range records {
+		if record.Status != deploy.StatusSuccess || record.Destination != destination || record.Metadata[deploy.PartialMetadata] != "" {
+			continue
+		}
+		if record.Metadata[deploy.GitSHAMetadata] == "" {
+			prebuilt = append(prebuilt, record)
+			continue
+		}
+		for _, later := range prebuilt {
+			if later.Version != record.Version {
+				return record, later
+			}
+		}
+		return record, nil
+	}
+	return nil, nil
+}
+
+// watchedPathsChanged reports whether the compared paths changed since the
+// commit the baseline deployment to the destination built its image from.
+// When there is no baseline, a later deployment replaced its image, or git
+// cannot compare against its commit (e.g. in a shallow clone), it reports a
+// change so the deploy goes ahead.
+func watchedPathsChanged(log *output.Logger) bool {
+	records, err := newHistoryStore(log).List(cfg.Service, 0)
+	if err != nil {
+		log.Warn("Cannot read deployment history: %v; deploying", err)
+		return true
+	}
+	last, replaced := changeBaseline(records, GetDestination())
+	if last == nil {
+		log.Info("No previous full deployment built from a clean checkout to compare against; deploying")
+		return true
+	}
+	if replaced != nil {
+		log.Info("Deployment %s replaced %s with %s; deploying", replaced.ID, last.Version, replaced.Version)
+		return true
+	}
+	sha := last.Metadata[deploy.GitSHAMetadata]
+
+	paths := comparedPaths()
+	changed, err := pathsChangedSince(sha, paths)
+	if err != nil {
+		log.Warn("Cannot compare against %s: %v; deploying", shortSHA(sha), err)
+		return true
+	}
+	if !changed {
+		log.Success("Nothing changed under %s since %s (deployment %s); skipping deploy", strings.Join(paths, ", "), shortSHA(sha), last.ID)
+	}
+	return changed
+}
+
+// pathsChangedSince reports whether files under paths differ from commit
+// sha, counting uncommitted and untracked files.
+func pathsChangedSince(sha string, paths []string) (bool, error) {
+	diff := exec.Command("git", append([]string{"diff", "--quiet", sha, "--"}, paths...)...)
+	if _, err := diff.Output(); err != nil {
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) && exitErr.ExitCode() == 1 {
+			return true, nil
+		}
+		return false, gitError(err)
+	}
+
+	untracked, err := exec.Command("git", append([]string{"ls-files", "--others", "--exclude-standard", "--"}, paths...)...).Output()
+	if err != nil {
+		return false, gitError(err)
+	}
+	return strings.TrimSpace(string(untracked)) != "", nil
+}
+
+// gitError returns err with git's message when it has one.
+func gitError(err error) error {
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) && len(exitErr.Stderr) > 0 {
+		return fmt.Errorf("%s", strings.TrimSpace(string(exitErr.Stderr)))
+	}
+	return err
+}
+
+func shortSHA(sha string) string {
+	if len(sha) > 12 {
+		return sha[:12]
+	}
+	return sha
+}
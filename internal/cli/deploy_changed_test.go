@@ -0,0 +1,185 @@
+package cli
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/lemonity-org/azud/internal/config"
+	"github.com/lemonity-org/azud/internal/deploy"
+)
+
+// newGitTestRepo creates a git repository in a temporary directory, makes
+// it the working directory for the test, and returns helpers that run git
+// and write files in it.
+func newGitTestRepo(t *testing.T) (git func(args ...string) string, write func(name, content string)) {
+	t.Helper()
+	dir := t.TempDir()
+	git = func(args ...string) string {
+		t.Helper()
+		cmd := exec.Command("git", append([]string{"-c", "user.name=test", "-c", "user.email=test@example.com"}, args...)...)
+		cmd.Dir = dir
+		out, err := cmd.CombinedOutput()
+		if err != nil {
+			t.Fatalf("git %v: %v\n%s", args, err, out)
+		}
+		return strings.TrimSpace(string(out))
+	}
+	write = func(name, content string) {
+		t.Helper()
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	oldDir, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chdir(dir); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = os.Chdir(oldDir) })
+	git("init", "-q")
+	return git, write
+}
+
+func TestPathsChangedSince(t *testing.T) {
+	git, write := newGitTestRepo(t)
+
+	write("services/api/main.go", "package main\n")
+	write("services/web/index.html", "<html></html>\n")
+	git("add", ".")
+	git("commit", "-q", "-m", "initial")
+	deployed := git("rev-parse", "HEAD")
+
+	check := func(want bool) {
+		t.Helper()
+		changed, err := pathsChangedSince(deployed, []string{"services/api"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if changed != want {
+			t.Fatalf("changed = %t, want %t", changed, want)
+		}
+	}
+
+	check(false)
+
+	write("services/web/index.html", "<html>v2</html>\n")
+	git("commit", "-q", "-am", "web only")
+	check(false)
+
+	write("services/api/handler.go", "package main\n")
+	check(true)
+
+	git("add", ".")
+	git("commit", "-q", "-m", "api")
+	check(true)
+
+	if _, err := pathsChangedSince("0000000000000000000000000000000000000000", []string{"services/api"}); err == nil {
+		t.Fatal("expected an error for an unknown commit")
+	}
+}
+
+func TestDeployMetadataRecordsOnlyCleanBuilds(t *testing.T) {
+	git, write := newGitTestRepo(t)
+	write("main.go", "package main\n")
+	git("add", ".")
+	git("commit", "-q", "-m", "initial")
+	head := git("rev-parse", "HEAD")
+
+	if got := cleanGitHeadSHA(); got != head {
+		t.Fatalf("cleanGitHeadSHA() = %q, want %q for a clean checkout", got, head)
+	}
+	write("main.go", "package main // changed\n")
+	if got := cleanGitHeadSHA(); got != "" {
+		t.Fatalf("cleanGitHeadSHA() = %q with uncommitted changes, want none", got)
+	}
+	git("checkout", "-q", "main.go")
+	write("notes.txt", "untracked\n")
+	if got := cleanGitHeadSHA(); got != "" {
+		t.Fatalf("cleanGitHeadSHA() = %q with untracked files, want none", got)
+	}
+
+	previous := builtGitSHA
+	t.Cleanup(func() { builtGitSHA = previous })
+	builtGitSHA = ""
+	if _, ok := deployMetadata()[deploy.GitSHAMetadata]; ok {
+		t.Fatal("deployMetadata() recorded a commit without a build")
+	}
+	builtGitSHA = head
+	if got := deployMetadata()[deploy.GitSHAMetadata]; got != head {
+		t.Fatalf("deployMetadata() git_sha = %q, want %q", got, head)
+	}
+}
+
+func TestChangeBaseline(t *testing.T) {
+	record := func(id, version string, status deploy.DeploymentStatus, metadata map[string]string) *deploy.DeploymentRecord {
+		return &deploy.DeploymentRecord{ID: id, Version: version, Destination: "production", Status: status, Metadata: metadata}
+	}
+	built := func(sha string) map[string]string { return map[string]string{deploy.GitSHAMetadata: sha} }
+	partial := map[string]string{deploy.GitSHAMetadata: "bbb", deploy.PartialMetadata: "true"}
+
+	tests := []struct {
+		name         string
+		records      []*deploy.DeploymentRecord
+		wantBaseline string
+		wantReplaced string
+	}{
+		{name: "latest build", records: []*deploy.DeploymentRecord{
+			record("d2", "v2", deploy.StatusSuccess, built("bbb")),
+			record("d1", "v1", deploy.StatusSuccess, built("aaa")),
+		}, wantBaseline: "d2"},
+		{name: "partial and failed deploys passed over", records: []*deploy.DeploymentRecord{
+			record("d3", "v3", deploy.StatusSuccess, partial),
+			record("d2", "v2", deploy.StatusFailed, built("bbb")),
+			record("d1", "v1", deploy.StatusSuccess, built("aaa")),
+		}, wantBaseline: "d1"},
+		{name: "redeploy of the same version passed over", records: []*deploy.DeploymentRecord{
+			record("d2", "v1", deploy.StatusSuccess, map[string]string{}),
+			record("d1", "v1", deploy.StatusSuccess, built("aaa")),
+		}, wantBaseline: "d1"},
+		{name: "prebuilt version replaced the build", records: []*deploy.DeploymentRecord{
+			record("d2", "v0", deploy.StatusSuccess, map[string]string{}),
+			record("d1", "v1", deploy.StatusSuccess, built("aaa")),
+		}, wantBaseline: "d1", wantReplaced: "d2"},
+		{name: "nothing built", records: []*deploy.DeploymentRecord{
+			record("d1", "v1", deploy.StatusSuccess, map[string]string{}),
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			baseline, replaced := changeBaseline(tt.records, "production")
+			id := func(r *deploy.DeploymentRecord) string {
+				if r == nil {
+					return ""
+				}
+				return r.ID
+			}
+			if id(baseline) != tt.wantBaseline || id(replaced) != tt.wantReplaced {
+				t.Fatalf("changeBaseline() = %q, %q; want %q, %q", id(baseline), id(replaced), tt.wantBaseline, tt.wantReplaced)
+			}
+		})
+	}
+}
+
+func TestComparedPathsIncludeConfigAndHooks(t *testing.T) {
+	previousCfg, previousPath, previousDest := cfg, configPath, destination
+	t.Cleanup(func() { cfg, configPath, destination = previousCfg, previousPath, previousDest })
+	cfg = &config.Config{HooksPath: ".azud/hooks"}
+	cfg.Builder.WatchPaths = []string{"services/api"}
+	configPath, destination = "config/deploy.yml", "production"
+
+	got := strings.Join(comparedPaths(), " ")
+	want := "services/api config/deploy.yml config/deploy.production.yml .azud/hooks"
+	if got != want {
+		t.Fatalf("comparedPaths() = %q, want %q", got, want)
+	}
+}
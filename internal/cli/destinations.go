@@ -0,0 +1,194 @@
+package cli
+
+import (
+	"fmt"
+	"maps"
+	"os"
+	"slices"
+
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v3"
+
+	"github.com/lemonity-org/azud/internal/config"
+	"github.com/lemonity-org/azud/internal/output"
+)
+
+var destinationsCmd = &cobra.Command{
+	Use:   "destinations",
+	Short: "List and compare destinations",
+	Long: `Work with the destinations defined by overlay files next to the base
+configuration, such as config/deploy.staging.yml for -d staging.`,
+}
+
+var destinationsListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List the destinations that have an overlay file",
+	Long: `List every destination with an overlay file next to the base
+configuration. These are the names -d completes.
+
+Example:
+  azud destinations list`,
+	Args: cobra.NoArgs,
+	RunE: runDestinationsList,
+}
+
+var destinationsDiffCmd = &cobra.Command{
+	Use:   "diff <destination> <destination>",
+	Short: "Show how the effective configuration of two destinations differs",
+	Long: `Load the configuration of two destinations, each with its overlay and
+defaults applied, and show every setting whose value differs.
+
+Secrets are not loaded, so only secret names are compared. Settings are named
+by their YAML path, such as proxy.hosts[0] or servers.web.hosts[1].
+
+Example:
+  azud destinations diff staging production`,
+	Args: cobra.ExactArgs(2),
+	ValidArgsFunction: func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+		if len(args) >= 2 {
+			return nil, cobra.ShellCompDirectiveNoFileComp
+		}
+		return completeDestinations(cmd, args, toComplete)
+	},
+	RunE: runDestinationsDiff,
+}
+
+func init() {
+	destinationsCmd.AddCommand(destinationsListCmd)
+	destinationsCmd.AddCommand(destinationsDiffCmd)
+	rootCmd.AddCommand(destinationsCmd)
+}
+
+// destinationsBasePath returns the base configuration the destinations
+// are discovered next to.
+func destinationsBasePath() (string, error) {
+	path := configPath
+	if path == "" {
+		path = findConfigFile()
+	}
+	if path == "" {
+		return "", fmt.Errorf("no configuration file found. Run 'azud init' to create one")
+	}
+	return path, nil
+}
+
+func runDestinationsList(cmd *cobra.Command, args []string) error {
+	log := output.DefaultLogger
+
+	path, err := destinationsBasePath()
+	if err != nil {
+		return err
+	}
+	names := config.Destinations(path)
+	if len(names) == 0 {
+		log.Info("No destination overlays found next to %s", path)
+		return nil
+	}
+
+	rows := make([][]string, 0, len(names))
+	for _, name := range names {
+		rows = append(rows, []string{name, config.DestinationPath(path, name)})
+	}
+	log.Table([]string{"Destination", "File"}, rows)
+	return nil
+}
+
+func runDestinationsDiff(cmd *cobra.Command, args []string) error {
+	log := output.DefaultLogger
+
+	if args[0] == args[1] {
+		return fmt.Errorf("compare two different destinations")
+	}
+	path, err := destinationsBasePath()
+	if err != nil {
+		return err
+	}
+
+	settings := make([]map[string]string, len(args))
+	for i, name := range args {
+		overlay := config.DestinationPath(path, name)
+		if _, err := os.Stat(overlay); err != nil {
+			return fmt.Errorf("destination %s has no overlay file %s", name, overlay)
+		}
+		loaded, err := config.NewLoader(path, name).LoadUnvalidated()
+		if err != nil {
+			return err
+		}
+		if settings[i], err = flattenConfig(loaded); err != nil {
+			return err
+		}
+	}
+
+	rows := diffSettings(settings[0], settings[1])
+	if len(rows) == 0 {
+		log.Success("%s and %s have the same effective configuration", args[0], args[1])
+		return nil
+	}
+	log.Table([]string{"Setting", args[0], args[1]}, rows)
+	return nil
+}
+
+// flattenConfig returns every set value of cfg keyed by its YAML path.
+func flattenConfig(cfg *config.Config) (map[string]string, error) {
+	data, err := yaml.Marshal(cfg)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode configuration: %w", err)
+	}
+	var tree any
+	if err := yaml.Unmarshal(data, &tree); err != nil {
+		return nil, fmt.Errorf("failed to decode configuration: %w", err)
+	}
+	settings := make(map[string]string)
+	flattenSetting(settings, "", tree)
+	return settings, nil
+}
+
+func flattenSetting(settings map[string]string, path string, value any) {
+	switch value := value.(type) {
+	case nil:
+	case map[string]any:
+		for key, child := range value {
+			if path != "" {
+				key = path + "." + key
+			}
+			flattenSetting(settings, key, child)
+		}
+	case []any:
+		for i, child := range value {
+			flattenSetting(settings, fmt.Sprintf("%s[%d]", path, i), child)
+		}
+	default:
+		if text := fmt.Sprint(value); text != "" {
+			settings[path] = text
+		}
+	}
+}
+
+// diffSettings returns the settings whose values differ between a and b as
+// sorted rows of path, value in a, and value in b. Unset values show as "-".
+func diffSettings(a, b map[string]string) [][]string {
+	paths := slices.Collect(maps.Keys(a))
+	for path := range b {
+		if _, ok := a[path]; !ok {
+			paths = append(paths, path)
+		}
+	}
+	slices.Sort(paths)
+
+	var rows [][]string
+	for _, path := range paths {
+		left, right := a[path], b[path]
+		if left == right {
+			continue
+		}
+		rows = append(rows, []string{path, unsetDash(left), unsetDash(right)})
+	}
+	return rows
+}
+
+func unsetDash(value string) string {
+	if value == "" {
+		return "-"
+	}
+	return value
+}
@@ -0,0 +1,63 @@
+package cli
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/lemonity-org/azud/internal/config"
+)
+
+func TestDestinationsDiffShowsEffectiveDifferences(t *testing.T) {
+	dir := t.TempDir()
+	base := filepath.Join(dir, "deploy.yml")
+	files := map[string]string{
+		"deploy.yml": `service: shop
+image: ghcr.io/acme/shop
+servers:
+  web:
+    hosts: [10.0.0.1]
+proxy:
+  host: shop.example.com
+  app_port: 3000
+`,
+		"deploy.staging.yml": `proxy:
+  host: staging.shop.example.com
+`,
+		"deploy.production.yml": `servers:
+  web:
+    hosts: [10.0.1.1, 10.0.1.2]
+`,
+		"deploy.production.bak.yml": "service: old\n",
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if got := config.Destinations(base); !reflect.DeepEqual(got, []string{"production", "staging"}) {
+		t.Fatalf("Destinations() = %v", got)
+	}
+
+	settings := make([]map[string]string, 2)
+	for i, name := range []string{"staging", "production"} {
+		loaded, err := config.NewLoader(base, name).LoadUnvalidated()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if settings[i], err = flattenConfig(loaded); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	want := [][]string{
+		{"proxy.host", "staging.shop.example.com", "shop.example.com"},
+		{"servers.web.hosts[0]", "10.0.0.1", "10.0.1.1"},
+		{"servers.web.hosts[1]", "-", "10.0.1.2"},
+	}
+	if got := diffSettings(settings[0], settings[1]); !reflect.DeepEqual(got, want) {
+		t.Errorf("diffSettings() = %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,219 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/lemonity-org/azud/internal/config"
+	"github.com/lemonity-org/azud/internal/dns"
+	"github.com/lemonity-org/azud/internal/output"
+)
+
+var proxyDNSCmd = &cobra.Command{
+	Use:   "dns",
+	Short: "Point the proxy hostnames at the proxy hosts",
+	Long: `Create or update the A and AAAA records of every proxy hostname at the
+provider configured under dns, so they point at exactly the proxy hosts.
+Records pointing elsewhere are updated or deleted. 'azud setup' runs this
+before booting the proxy.
+
+With --check nothing is changed: the hostnames are resolved and compared
+with the proxy hosts, as 'azud deploy' does before deploying.
+
+Example:
+  azud proxy dns
+  azud proxy dns --check`,
+	Args: cobra.NoArgs,
+	RunE: runProxyDNS,
+}
+
+var proxyDNSCheck bool
+
+// dnsLookupTimeout bounds each lookup of a host or hostname.
+const dnsLookupTimeout = 10 * time.Second
+
+func init() {
+	proxyDNSCmd.Flags().BoolVar(&proxyDNSCheck, "check", false, "Only report hostnames that resolve elsewhere")
+
+	proxyCmd.AddCommand(proxyDNSCmd)
+}
+
+func runProxyDNS(cmd *cobra.Command, args []string) error {
+	output.SetVerbose(verbose)
+	log := output.DefaultLogger
+
+	if !cfg.DNS.Enabled() {
+		return fmt.Errorf("no dns provider configured")
+	}
+	if proxyDNSCheck {
+		if mismatched := checkDNSRecords(cmd.Context(), log); mismatched > 0 {
+			return fmt.Errorf("%d hostname(s) do not point at the proxy hosts", mismatched)
+		}
+		return nil
+	}
+	return syncDNSRecords(cmd.Context(), log)
+}
+
+// dnsProvider returns the configured DNS provider.
+func dnsProvider() (dns.Provider, error) {
+	token, ok := config.GetSecret(cfg.DNS.TokenSecret)
+	if !ok || token == "" {
+		return nil, fmt.Errorf("secret %s with the DNS API token not found", cfg.DNS.TokenSecret)
+	}
+	return dns.NewProvider(cfg.DNS.Provider, cfg.DNS.Zone, token)
+}
+
+// syncDNSRecords points the proxy hostnames at the proxy hosts.
+func syncDNSRecords(ctx context.Context, log *output.Logger) error {
+	provider, err := dnsProvider()
+	if err != nil {
+		return err
+	}
+	addresses, err := dnsTargetAddresses(ctx, log)
+	if err != nil {
+		return err
+	}
+
+	for _, hostname := range cfg.Proxy.AllHosts() {
+		current, err := provider.Records(ctx, hostname)
+		if err != nil {
+			return fmt.Errorf("failed to read the records of %s: %w", hostname, err)
+		}
+		changes := dns.Plan(hostname, current, addresses, cfg.DNS.TTL)
+		for _, change := range changes {
+			log.Info("DNS: %s", change)
+		}
+		if err := dns.Apply(ctx, provider, changes); err != nil {
+			return err
+		}
+		log.Success("%s points at %s", hostname, joinIPs(addresses))
+	}
+	return nil
+}
+
+// checkDNSRecords warns about proxy hostnames that do not resolve to the
+// proxy hosts and returns how many there are. Wildcard hostnames are
+// skipped, as they have no name of their own to resolve, and so are
+// hostnames whose records the provider proxies, which resolve to the
+// provider's addresses.
+func checkDNSRecords(ctx context.Context, log *output.Logger) int {
+	addresses, err := dnsTargetAddresses(ctx, log)
+	if err != nil {
+		log.Warn("Skipping DNS check: %v", err)
+		return 0
+	}
+	provider, err := dnsProvider()
+	if err != nil {
+		log.Debug("Not reading DNS records: %v", err)
+	}
+
+	mismatched := 0
+	for _, hostname := range cfg.Proxy.AllHosts() {
+		if strings.HasPrefix(hostname, "*.") {
+			continue
+		}
+		if provider != nil && proxiedRecords(ctx, log, provider, hostname) {
+			log.Info("%s is proxied by %s; not checking where it resolves", hostname, cfg.DNS.Provider)
+			continue
+		}
+		lookupCtx, cancel := context.WithTimeout(ctx, dnsLookupTimeout)
+		resolved, err := net.DefaultResolver.LookupIP(lookupCtx, "ip", hostname)
+		cancel()
+		if err != nil {
+			log.Warn("%s does not resolve: %v", hostname, err)
+			mismatched++
+			continue
+		}
+		unexpected, missing := dns.Mismatch(resolved, addresses)
+		switch {
+		case len(unexpected) > 0:
+			log.Warn("%s resolves to %s, which is not a proxy host (%s); run 'azud proxy dns' to update it",
+				hostname, joinIPs(unexpected), joinIPs(addresses))
+			mismatched++
+		case len(missing) > 0:
+			log.Warn("%s does not resolve to %s yet", hostname, joinIPs(missing))
+			mismatched++
+		default:
+			log.Success("%s points at the proxy hosts", hostname)
+		}
+	}
+	return mismatched
+}
+
+// proxiedRecords reports whether provider proxies a record of hostname.
+// When the records cannot be read, it reports false so the hostname is
+// resolved as usual.
+func proxiedRecords(ctx context.Context, log *output.Logger, provider dns.Provider, hostname string) bool {
+	records, err := provider.Records(ctx, hostname)
+	if err != nil {
+		log.Debug("Failed to read the records of %s: %v", hostname, err)
+		return false
+	}
+	return slices.ContainsFunc(records, func(record dns.Record) bool { return record.Proxied })
+}
+
+// dnsTargetAddresses returns the addresses the proxy hostnames point at:
+// dns.targets, or the public addresses of the hosts running the proxy.
+func dnsTargetAddresses(ctx context.Context, log *output.Logger) ([]net.IP, error) {
+	var addresses []net.IP
+	add := func(ip net.IP) {
+		if !slices.ContainsFunc(addresses, ip.Equal) {
+			addresses = append(addresses, ip)
+		}
+	}
+	if len(cfg.DNS.Targets) > 0 {
+		for _, target := range cfg.DNS.Targets {
+			if ip := net.ParseIP(target); ip != nil {
+				add(ip)
+			}
+		}
+		return addresses, nil
+	}
+
+	hosts := cfg.GetRoleHosts("web")
+	if cfg.UsesProxyTier() {
+		hosts = cfg.GetProxyHosts()
+	}
+	for _, host := range hosts {
+		host = strings.Trim(host, "[]")
+		ips := []net.IP{net.ParseIP(host)}
+		if ips[0] == nil {
+			lookupCtx, cancel := context.WithTimeout(ctx, dnsLookupTimeout)
+			resolved, err := net.DefaultResolver.LookupIP(lookupCtx, "ip", host)
+			cancel()
+			if err != nil {
+				return nil, fmt.Errorf("failed to resolve proxy host %s: %w", host, err)
+			}
+			ips = resolved
+		}
+		for _, ip := range ips {
+			if !isPublicIP(ip) {
+				log.Warn("Ignoring %s of proxy host %s: not a public address", ip, host)
+				continue
+			}
+			add(ip)
+		}
+	}
+	if len(addresses) == 0 {
+		return nil, fmt.Errorf("no public address found for the proxy hosts; set dns.targets")
+	}
+	return addresses, nil
+}
+
+func isPublicIP(ip net.IP) bool {
+	return !ip.IsPrivate() && !ip.IsLoopback() && !ip.IsLinkLocalUnicast() && !ip.IsUnspecified()
+}
+
+func joinIPs(ips []net.IP) string {
+	parts := make([]string, len(ips))
+	for i, ip := range ips {
+		parts[i] = ip.String()
+	}
+	return strings.Join(parts, ", ")
+}
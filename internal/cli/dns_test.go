@@ -0,0 +1,44 @@
+package cli
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/lemonity-org/azud/internal/dns"
+	"github.com/lemonity-org/azud/internal/output"
+)
+
+// fakeDNSProvider serves fixed records per name.
+type fakeDNSProvider struct {
+	records map[string][]dns.Record
+}
+
+func (p *fakeDNSProvider) Records(ctx context.Context, name string) ([]dns.Record, error) {
+	records, ok := p.records[name]
+	if !ok {
+		return nil, errors.New("zone not readable")
+	}
+	return records, nil
+}
+
+func (p *fakeDNSProvider) Create(ctx context.Context, record dns.Record) error { return nil }
+func (p *fakeDNSProvider) Update(ctx context.Context, record dns.Record) error { return nil }
+func (p *fakeDNSProvider) Delete(ctx context.Context, record dns.Record) error { return nil }
+
+func TestProxiedRecords(t *testing.T) {
+	provider := &fakeDNSProvider{records: map[string][]dns.Record{
+		"app.example.com": {{Type: "A", Content: "203.0.113.10", Proxied: true}},
+		"api.example.com": {{Type: "A", Content: "203.0.113.10"}},
+	}}
+	ctx := context.Background()
+	for name, want := range map[string]bool{
+		"app.example.com":   true,
+		"api.example.com":   false,
+		"other.example.com": false,
+	} {
+		if got := proxiedRecords(ctx, output.DefaultLogger, provider, name); got != want {
+			t.Errorf("proxiedRecords(%s) = %v, want %v", name, got, want)
+		}
+	}
+}
@@ -3,8 +3,11 @@ package cli
 import (
 	"bufio"
 	"fmt"
+	"io"
+	"maps"
 	"os"
 	"path/filepath"
+	"slices"
 	"sort"
 	"strings"
 
@@ -12,7 +15,9 @@ import (
 
 	"github.com/lemonity-org/azud/internal/config"
 	"github.com/lemonity-org/azud/internal/output"
+	"github.com/lemonity-org/azud/internal/podman"
 	"github.com/lemonity-org/azud/internal/shell"
+	"github.com/lemonity-org/azud/internal/ssh"
 )
 
 var envCmd = &cobra.Command{
@@ -131,6 +136,7 @@ func init() {
 	envCmd.AddCommand(envGetCmd)
 	envCmd.AddCommand(envSetCmd)
 	envCmd.AddCommand(envDeleteCmd)
+	envCmd.AddCommand(envRotateCmd)
 
 	rootCmd.AddCommand(envCmd)
 }
@@ -139,6 +145,12 @@ func runEnvPush(cmd *cobra.Command, args []string) error {
 	output.SetVerbose(verbose)
 	log := output.DefaultLogger
 
+	if isFileSecretsProvider() {
+		if err := generateCachePasswords(log); err != nil {
+			return err
+		}
+	}
+
 	// Load secrets based on provider
 	secrets, err := loadSecretsForPush()
 	if err != nil {
@@ -163,51 +175,81 @@ func runEnvPush(cmd *cobra.Command, args []string) error {
 	defer func() { _ = sshClient.Close() }()
 
 	log.Header("Pushing Secrets")
-	log.Info("Pushing %d secrets to %d host(s)...", len(secrets), len(hosts))
-
-	// Build the secrets content
-	var content strings.Builder
-	content.WriteString("# Azud Secrets - synced from local\n")
-	content.WriteString("# Do not edit directly, use 'azud env set' instead\n\n")
+	log.Info("Pushing secrets to %d host(s)...", len(hosts))
 
-	// Sort keys for consistent output
-	var keys []string
-	for k := range secrets {
-		keys = append(keys, k)
+	secretManager := podman.NewSecretManager(podman.NewClient(sshClient))
+	writeFile := writeRemoteSecretsFile
+	if cfg.UsesEncryptedSecrets() {
+		writeFile = writeEncryptedSecretsFile
 	}
-	sort.Strings(keys)
-
-	for _, k := range keys {
-		_, _ = fmt.Fprintf(&content, "%s=%s\n", k, secrets[k])
-	}
-
-	// Push to each host
+	missing := make(map[string]bool)
 	hasErrors := false
-	remoteDir := remoteSecretsDir()
-	remoteSecrets := remoteSecretsPath()
-	remoteDirArg := remotePathShellArg(remoteDir)
-	remoteSecretsArg := remotePathShellArg(remoteSecrets)
-
 	for _, host := range hosts {
+		// Each host only receives the secrets declared for the roles, cron
+		// jobs, and accessories it runs, split across per-role files.
+		files := cfg.HostSecretFiles(host)
+		if len(files) == 0 {
+			log.Host(host, "No secrets needed")
+			continue
+		}
 		log.Host(host, "Pushing secrets...")
 
-		// Write a mode-0600 temporary file and atomically replace the final
-		// file. Paths are assigned as quoted data while documented home
-		// prefixes expand on the remote host.
-		writeCmd := fmt.Sprintf(`dir=%s; path=%s; tmp="${path}.tmp.$$"; umask 077 && mkdir -p "$dir" && chmod 700 "$dir" && trap 'rm -f "$tmp"' EXIT HUP INT TERM && cat > "$tmp" && chmod 600 "$tmp" && mv "$tmp" "$path" && chmod 600 "$path" && test "$(stat -c '%%a' "$path" 2>/dev/null || stat -f '%%Lp' "$path")" = 600 && trap - EXIT`, remoteDirArg, remoteSecretsArg)
-		result, err := sshClient.ExecuteWithStdin(host, writeCmd, strings.NewReader(content.String()))
-		if err != nil {
-			log.HostError(host, "Failed to write secrets: %v", err)
-			hasErrors = true
-			continue
+		paths := make([]string, 0, len(files))
+		for path := range files {
+			paths = append(paths, path)
 		}
-		if result.ExitCode != 0 {
-			log.HostError(host, "Failed to write secrets: %s", result.Stderr)
-			hasErrors = true
+		sort.Strings(paths)
+
+		if cfg.UsesPodmanSecrets() {
+			var keys []string
+			for _, path := range paths {
+				keys = append(keys, files[path]...)
+			}
+			slices.Sort(keys)
+			keys = slices.Compact(keys)
+
+			pushed := 0
+			for _, key := range keys {
+				value, ok := secrets[key]
+				if !ok {
+					missing[key] = true
+					continue
+				}
+				if err := secretManager.Create(host, cfg.PodmanSecretName(key), value); err != nil {
+					log.HostError(host, "%v", err)
+					hasErrors = true
+					continue
+				}
+				pushed++
+			}
+			log.HostSuccess(host, "Secrets pushed (%d podman secrets)", pushed)
 			continue
 		}
 
-		log.HostSuccess(host, "Secrets pushed (%d variables)", len(secrets))
+		pushed := 0
+		for _, path := range paths {
+			scoped := make(map[string]string, len(files[path]))
+			for _, key := range files[path] {
+				value, ok := secrets[key]
+				if !ok {
+					missing[key] = true
+					continue
+				}
+				scoped[key] = value
+			}
+			if err := writeFile(sshClient, host, path, scoped); err != nil {
+				log.HostError(host, "Failed to write secrets to %s: %v", path, err)
+				hasErrors = true
+				continue
+			}
+			pushed += len(scoped)
+		}
+
+		log.HostSuccess(host, "Secrets pushed (%d variables in %d file(s))", pushed, len(paths))
+	}
+
+	for _, key := range slices.Sorted(maps.Keys(missing)) {
+		log.Warn("Secret %s is declared but has no value", key)
 	}
 
 	if hasErrors {
@@ -218,12 +260,64 @@ func runEnvPush(cmd *cobra.Command, args []string) error {
 	return nil
 }
 
+// writeRemoteSecretsFile replaces a remote env file with secrets. A mode-0600
+// temporary file is written and atomically renamed; paths are assigned as
+// quoted data while documented home prefixes expand on the remote host.
+func writeRemoteSecretsFile(sshClient stdinExecutor, host, path string, secrets map[string]string) error {
+	writeCmd := fmt.Sprintf(`dir=%s; path=%s; tmp="${path}.tmp.$$"; umask 077 && mkdir -p "$dir" && chmod 700 "$dir" && trap 'rm -f "$tmp"' EXIT HUP INT TERM && cat > "$tmp" && chmod 600 "$tmp" && mv "$tmp" "$path" && chmod 600 "$path" && test "$(stat -c '%%a' "$path" 2>/dev/null || stat -f '%%Lp' "$path")" = 600 && trap - EXIT`, remotePathShellArg(filepath.Dir(path)), remotePathShellArg(path))
+	result, err := sshClient.ExecuteWithStdin(host, writeCmd, strings.NewReader(secretsFileContent(secrets)))
+	if err != nil {
+		return err
+	}
+	if result.ExitCode != 0 {
+		return fmt.Errorf("%s", strings.TrimSpace(result.Stderr))
+	}
+	return nil
+}
+
+// stdinExecutor runs a command with input on a host; *ssh.Client implements
+// it.
+type stdinExecutor interface {
+	ExecuteWithStdin(host, cmd string, stdin io.Reader) (*ssh.Result, error)
+}
+
+// writeEncryptedSecretsFile replaces the age-encrypted counterpart of a
+// remote env file. The host's age identity is created on first use, the
+// plaintext only travels over SSH stdin, the ciphertext checksum is recorded
+// for ValidateEncryptedSecrets, and any plaintext file at path is removed.
+func writeEncryptedSecretsFile(sshClient stdinExecutor, host, path string, secrets map[string]string) error {
+	encryptedPath := config.EncryptedSecretsPath(path)
+	writeCmd := fmt.Sprintf(`dir=%s; path=%s; key=%s; plain=%s; tmp="${path}.tmp.$$"; umask 077 && { command -v age >/dev/null && command -v age-keygen >/dev/null || { echo "age is not installed (run 'azud server bootstrap')" >&2; exit 1; }; } && mkdir -p "$dir" && chmod 700 "$dir" && { test -f "$key" || age-keygen -o "$key" 2>/dev/null; } && chmod 600 "$key" && trap 'rm -f "$tmp"' EXIT HUP INT TERM && age -r "$(age-keygen -y "$key")" -o "$tmp" && chmod 600 "$tmp" && mv "$tmp" "$path" && sha256sum < "$path" | cut -d' ' -f1 > "$path.sha256" && rm -f "$plain" && trap - EXIT`,
+		remotePathShellArg(filepath.Dir(encryptedPath)), remotePathShellArg(encryptedPath), remotePathShellArg(config.RemoteSecretsIdentityPath(cfg)), remotePathShellArg(path))
+	result, err := sshClient.ExecuteWithStdin(host, writeCmd, strings.NewReader(secretsFileContent(secrets)))
+	if err != nil {
+		return err
+	}
+	if result.ExitCode != 0 {
+		return fmt.Errorf("%s", strings.TrimSpace(result.Stderr))
+	}
+	return nil
+}
+
+func secretsFileContent(secrets map[string]string) string {
+	var content strings.Builder
+	content.WriteString("# Azud Secrets - synced from local\n")
+	content.WriteString("# Do not edit directly, use 'azud env set' instead\n\n")
+	for _, k := range slices.Sorted(maps.Keys(secrets)) {
+		_, _ = fmt.Fprintf(&content, "%s=%s\n", k, secrets[k])
+	}
+	return content.String()
+}
+
 func runEnvPull(cmd *cobra.Command, args []string) error {
 	output.SetVerbose(verbose)
 	log := output.DefaultLogger
 	if !containsString(cfg.GetAllSSHHosts(), envHost) {
 		return fmt.Errorf("host %s is not configured", envHost)
 	}
+	if cfg.UsesPodmanSecrets() {
+		return fmt.Errorf("env pull is not available with secrets_storage=podman; secret values cannot be read back from hosts")
+	}
 
 	sshClient := createSSHClient()
 	defer func() { _ = sshClient.Close() }()
@@ -232,6 +326,10 @@ func runEnvPull(cmd *cobra.Command, args []string) error {
 
 	// Read secrets from remote
 	readCmd := fmt.Sprintf("cat %s 2>/dev/null || echo ''", remotePathShellArg(remoteSecretsPath()))
+	if cfg.UsesEncryptedSecrets() {
+		readCmd = fmt.Sprintf("age -d -i %s %s 2>/dev/null || echo ''",
+			remotePathShellArg(config.RemoteSecretsIdentityPath(cfg)), remotePathShellArg(config.EncryptedSecretsPath(remoteSecretsPath())))
+	}
 	result, err := sshClient.Execute(envHost, readCmd)
 	if err != nil {
 		return fmt.Errorf("failed to read secrets from server: %w", err)
@@ -325,38 +423,45 @@ func runEnvList(cmd *cobra.Command, args []string) error {
 		log.Println("")
 	}
 
+	var secrets map[string]string
+	if isFileSecretsProvider() {
+		secretsPath := getSecretsFilePath()
+		secrets, _ = loadSecretsFile(secretsPath)
+	} else {
+		secrets = config.AllSecrets()
+	}
+
 	// Show secret variables
 	if len(cfg.Env.Secret) > 0 {
 		log.Println("Secret variables:")
-
-		var secrets map[string]string
-		if isFileSecretsProvider() {
-			secretsPath := getSecretsFilePath()
-			secrets, _ = loadSecretsFile(secretsPath)
-		} else {
-			secrets = config.AllSecrets()
-		}
-
-		for _, k := range cfg.Env.Secret {
-			if envShowValues {
-				if val, ok := secrets[k]; ok {
-					log.Println("  %s=%s", k, val)
-				} else {
-					log.Println("  %s=<not set>", k)
-				}
-			} else {
-				if _, ok := secrets[k]; ok {
-					log.Println("  %s=********", k)
-				} else {
-					log.Println("  %s=<not set>", k)
-				}
-			}
+		printSecretNames(log, cfg.Env.Secret, secrets)
+	}
+	for _, role := range cfg.GetRoles() {
+		if names := cfg.Servers[role].Secrets; len(names) > 0 {
+			log.Println("Secret variables (%s role only):", role)
+			printSecretNames(log, names, secrets)
 		}
 	}
 
 	return nil
 }
 
+// printSecretNames lists secret names with their values masked unless
+// --show-values is set.
+func printSecretNames(log *output.Logger, names []string, secrets map[string]string) {
+	for _, k := range names {
+		val, ok := secrets[k]
+		switch {
+		case !ok:
+			log.Println("  %s=<not set>", k)
+		case envShowValues:
+			log.Println("  %s=%s", k, val)
+		default:
+			log.Println("  %s=********", k)
+		}
+	}
+}
+
 func runEnvEdit(cmd *cobra.Command, args []string) error {
 	if !isFileSecretsProvider() {
 		return fmt.Errorf("env edit requires secrets_provider=file")
@@ -443,22 +548,8 @@ func runEnvSet(cmd *cobra.Command, args []string) error {
 		return fmt.Errorf("failed to create secrets directory: %w", err)
 	}
 
-	// Write secrets file
-	var content strings.Builder
-	content.WriteString("# Azud Secrets\n\n")
-
-	var keys []string
-	for k := range secrets {
-		keys = append(keys, k)
-	}
-	sort.Strings(keys)
-
-	for _, k := range keys {
-		_, _ = fmt.Fprintf(&content, "%s=%s\n", k, secrets[k])
-	}
-
-	if err := os.WriteFile(secretsPath, []byte(content.String()), 0600); err != nil {
-		return fmt.Errorf("failed to write secrets file: %w", err)
+	if err := writeSecretsFile(secretsPath, secrets); err != nil {
+		return err
 	}
 
 	log.Success("Set %s in %s", key, secretsPath)
@@ -492,7 +583,18 @@ func runEnvDelete(cmd *cobra.Command, args []string) error {
 	// Remove the key
 	delete(secrets, key)
 
-	// Write secrets file
+	if err := writeSecretsFile(secretsPath, secrets); err != nil {
+		return err
+	}
+
+	log.Success("Deleted %s from %s", key, secretsPath)
+	log.Info("Run 'azud env push' to sync to servers")
+
+	return nil
+}
+
+// writeSecretsFile writes secrets to the local secrets file, sorted by key.
+func writeSecretsFile(secretsPath string, secrets map[string]string) error {
 	var content strings.Builder
 	content.WriteString("# Azud Secrets\n\n")
 
@@ -509,10 +611,6 @@ func runEnvDelete(cmd *cobra.Command, args []string) error {
 	if err := os.WriteFile(secretsPath, []byte(content.String()), 0600); err != nil {
 		return fmt.Errorf("failed to write secrets file: %w", err)
 	}
-
-	log.Success("Deleted %s from %s", key, secretsPath)
-	log.Info("Run 'azud env push' to sync to servers")
-
 	return nil
 }
 
@@ -545,10 +643,6 @@ func loadSecretsForPush() (map[string]string, error) {
 	return secrets, nil
 }
 
-func remoteSecretsDir() string {
-	return filepath.Dir(config.RemoteSecretsPath(cfg))
-}
-
 func remoteSecretsPath() string {
 	return config.RemoteSecretsPath(cfg)
 }
@@ -0,0 +1,240 @@
+package cli
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/lemonity-org/azud/internal/config"
+	"github.com/lemonity-org/azud/internal/deploy"
+	"github.com/lemonity-org/azud/internal/output"
+	"github.com/lemonity-org/azud/internal/podman"
+	"github.com/lemonity-org/azud/internal/ssh"
+)
+
+var envDiffCmd = &cobra.Command{
+	Use:   "diff",
+	Short: "Compare local secrets with servers and running containers",
+	Long: `Compare the local secrets with the secrets stored on each server and
+with the environment each running application container was started with.
+
+Values are compared but never printed. A key is:
+  ok               the same value as locally
+  stale            a different value than locally
+  missing          declared but absent
+  not set locally  present remotely but missing from the local secrets
+
+The command exits with an error when any drift is found. Push changed values
+with 'azud env push' and recreate containers with 'azud redeploy'.
+
+Example:
+  azud env diff
+  azud env diff --host 192.168.1.10`,
+	RunE: runEnvDiff,
+}
+
+func init() {
+	envDiffCmd.Flags().StringVar(&envHost, "host", "", "Specific host")
+	envCmd.AddCommand(envDiffCmd)
+}
+
+func runEnvDiff(cmd *cobra.Command, args []string) error {
+	output.SetVerbose(verbose)
+	log := output.DefaultLogger
+
+	local, err := loadSecretsForPush()
+	if err != nil {
+		return fmt.Errorf("failed to load secrets: %w", err)
+	}
+
+	hosts := cfg.GetAllSSHHosts()
+	if envHost != "" {
+		if !containsString(hosts, envHost) {
+			return fmt.Errorf("host %s is not configured", envHost)
+		}
+		hosts = []string{envHost}
+	}
+
+	sshClient := createSSHClient()
+	defer func() { _ = sshClient.Close() }()
+	containers := podman.NewContainerManager(podman.NewClient(sshClient))
+
+	log.Header("Secrets Diff")
+
+	var drifted []string
+	for _, host := range hosts {
+		files := cfg.HostSecretFiles(host)
+		if len(files) == 0 {
+			continue
+		}
+
+		remote, err := readHostSecrets(sshClient, host, files)
+		if err != nil {
+			log.HostError(host, "Failed to read secrets: %v", err)
+			drifted = append(drifted, host)
+			continue
+		}
+
+		var keys []string
+		for _, names := range files {
+			keys = append(keys, names...)
+		}
+		slices.Sort(keys)
+		keys = slices.Compact(keys)
+
+		headers := []string{"KEY", "SERVER"}
+		var roles []string
+		containerEnv := make(map[string]map[string]string)
+		for _, role := range cfg.GetRoles() {
+			if cfg.IsProxyTierRole(role) || !slices.Contains(cfg.Servers[role].Hosts, host) {
+				continue
+			}
+			roles = append(roles, role)
+			headers = append(headers, strings.ToUpper(role)+" CONTAINER")
+			if env, err := runningContainerEnv(containers, host, deploy.RoleContainerName(cfg, role)); err == nil {
+				containerEnv[role] = env
+			}
+		}
+
+		hostDrift := false
+		rows := make([][]string, 0, len(keys))
+		for _, key := range keys {
+			server := "present"
+			if !cfg.UsesPodmanSecrets() {
+				server = compareSecret(local, remote, key)
+			} else if _, ok := remote[key]; !ok {
+				server = "missing"
+			}
+			row := []string{key, server}
+			hostDrift = hostDrift || isSecretDrift(server)
+
+			for _, role := range roles {
+				status := "-"
+				env, running := containerEnv[role]
+				switch {
+				case !slices.Contains(cfg.RoleSecrets(role), key):
+				case cfg.UsesPodmanSecrets():
+					status = "n/a"
+				case !running:
+					status = "not running"
+				default:
+					status = compareSecret(local, env, key)
+				}
+				row = append(row, status)
+				hostDrift = hostDrift || isSecretDrift(status)
+			}
+			rows = append(rows, row)
+		}
+
+		log.Host(host, "%d secret(s)", len(keys))
+		log.Table(headers, rows)
+		if hostDrift {
+			drifted = append(drifted, host)
+		}
+	}
+
+	if len(drifted) > 0 {
+		return fmt.Errorf("secrets drift detected on %s", strings.Join(drifted, ", "))
+	}
+	log.Success("Secrets are in sync")
+	return nil
+}
+
+// readHostSecrets reads the secrets stored on host across its env files. With
+// Podman secret storage only the names are known and values are empty.
+func readHostSecrets(sshClient *ssh.Client, host string, files map[string][]string) (map[string]string, error) {
+	secrets := make(map[string]string)
+	if cfg.UsesPodmanSecrets() {
+		names, err := podman.NewSecretManager(podman.NewClient(sshClient)).List(host)
+		if err != nil {
+			return nil, err
+		}
+		for _, keys := range files {
+			for _, key := range keys {
+				if slices.Contains(names, cfg.PodmanSecretName(key)) {
+					secrets[key] = ""
+				}
+			}
+		}
+		return secrets, nil
+	}
+
+	return readSecretFiles(sshClient, host, files)
+}
+
+// hostExecutor runs a command on a host; *ssh.Client implements it.
+type hostExecutor interface {
+	Execute(host, cmd string) (*ssh.Result, error)
+}
+
+// readSecretFiles reads the env files on host, decrypting them with
+// encrypted secret storage, and returns the keys each file is meant to hold.
+// A file that does not exist holds no keys; one that cannot be read or
+// decrypted is an error.
+func readSecretFiles(sshClient hostExecutor, host string, files map[string][]string) (map[string]string, error) {
+	secrets := make(map[string]string)
+	for _, path := range slices.Sorted(maps.Keys(files)) {
+		readCmd := fmt.Sprintf(`path=%s; if [ -e "$path" ]; then cat "$path"; fi`, remotePathShellArg(path))
+		if cfg.UsesEncryptedSecrets() {
+			readCmd = fmt.Sprintf(`path=%s; if [ -e "$path" ]; then age -d -i %s "$path"; fi`,
+				remotePathShellArg(config.EncryptedSecretsPath(path)), remotePathShellArg(config.RemoteSecretsIdentityPath(cfg)))
+		}
+		result, err := sshClient.Execute(host, readCmd)
+		if err != nil {
+			return nil, err
+		}
+		if result.ExitCode != 0 {
+			return nil, fmt.Errorf("failed to read %s: %s", path, strings.TrimSpace(result.Stderr))
+		}
+		// Keys missing from a role's own file are reported as missing, so
+		// only take the keys that file is meant to hold.
+		values := parseSecretsContent(result.Stdout)
+		for _, key := range files[path] {
+			if value, ok := values[key]; ok {
+				secrets[key] = value
+			}
+		}
+	}
+	return secrets, nil
+}
+
+// runningContainerEnv returns the environment a running container was
+// created with.
+func runningContainerEnv(containers *podman.ContainerManager, host, name string) (map[string]string, error) {
+	running, err := containers.IsRunning(host, name)
+	if err != nil || !running {
+		return nil, fmt.Errorf("container %s is not running", name)
+	}
+	result, err := containers.InspectFormat(host, name, "{{range .Config.Env}}{{println .}}{{end}}")
+	if err != nil {
+		return nil, err
+	}
+	return parseSecretsContent(result), nil
+}
+
+// compareSecret reports how other's value for key relates to the local one.
+func compareSecret(local, other map[string]string, key string) string {
+	localValue, inLocal := local[key]
+	otherValue, inOther := other[key]
+	switch {
+	case !inOther:
+		return "missing"
+	case !inLocal:
+		return "not set locally"
+	case localValue != otherValue:
+		return "stale"
+	default:
+		return "ok"
+	}
+}
+
+func isSecretDrift(status string) bool {
+	switch status {
+	case "stale", "missing", "not set locally":
+		return true
+	}
+	return false
+}
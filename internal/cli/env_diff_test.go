@@ -0,0 +1,102 @@
+package cli
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/lemonity-org/azud/internal/config"
+	"github.com/lemonity-org/azud/internal/testutil"
+)
+
+func TestCompareSecretReportsDrift(t *testing.T) {
+	local := map[string]string{"API_KEY": "new", "DB_PASSWORD": "same", "ONLY_LOCAL": "x"}
+	remote := map[string]string{"API_KEY": "old", "DB_PASSWORD": "same", "ONLY_REMOTE": "y"}
+
+	tests := map[string]string{
+		"API_KEY":     "stale",
+		"DB_PASSWORD": "ok",
+		"ONLY_LOCAL":  "missing",
+		"ONLY_REMOTE": "not set locally",
+	}
+	for key, want := range tests {
+		got := compareSecret(local, remote, key)
+		if got != want {
+			t.Errorf("compareSecret(%s) = %q, want %q", key, got, want)
+		}
+		if isSecretDrift(got) != (want != "ok") {
+			t.Errorf("isSecretDrift(%q) = %v", got, isSecretDrift(got))
+		}
+	}
+}
+
+func TestReadSecretFiles(t *testing.T) {
+	oldCfg := cfg
+	t.Cleanup(func() { cfg = oldCfg })
+	cfg = &config.Config{}
+	dir := t.TempDir()
+	write := func(name, content string) string {
+		t.Helper()
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+	shared := write("env", "API_KEY=shared\nDB_PASSWORD=secret\nWORKER_TOKEN=from-shared-file\n")
+	worker := write("env.worker", "WORKER_TOKEN=worker\nAPI_KEY=from-worker-file\n")
+	absent := filepath.Join(dir, "env.jobs")
+	runner := testutil.LocalExecutor{Bin: t.TempDir()}
+
+	got, err := readSecretFiles(runner, "web-1", map[string][]string{
+		shared: {"API_KEY", "DB_PASSWORD"},
+		worker: {"WORKER_TOKEN"},
+		absent: {"JOBS_KEY"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]string{"API_KEY": "shared", "DB_PASSWORD": "secret", "WORKER_TOKEN": "worker"}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("readSecretFiles() = %v, want %v", got, want)
+	}
+
+	unreadable := filepath.Join(dir, "env.web")
+	if err := os.Mkdir(unreadable, 0o700); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := readSecretFiles(runner, "web-1", map[string][]string{unreadable: {"API_KEY"}}); err == nil || !strings.Contains(err.Error(), "failed to read "+unreadable) {
+		t.Fatalf("readSecretFiles() of an unreadable file = %v, want an error", err)
+	}
+}
+
+func TestReadSecretFilesEncrypted(t *testing.T) {
+	oldCfg := cfg
+	t.Cleanup(func() { cfg = oldCfg })
+	dir := t.TempDir()
+	cfg = &config.Config{SecretsRemotePath: filepath.Join(dir, "env"), SecretsStorage: config.SecretsStorageEncrypted}
+	path := config.RemoteSecretsPath(cfg)
+	if err := os.WriteFile(config.EncryptedSecretsPath(path), []byte("API_KEY=value\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	identity := config.RemoteSecretsIdentityPath(cfg)
+	if err := os.WriteFile(identity, []byte("AGE-SECRET-KEY-TEST\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	runner := testutil.LocalExecutor{Bin: testutil.FakeAge(t)}
+	files := map[string][]string{path: {"API_KEY"}}
+
+	got, err := readSecretFiles(runner, "web-1", files)
+	if err != nil || got["API_KEY"] != "value" {
+		t.Fatalf("readSecretFiles() = %v, %v; want API_KEY decrypted", got, err)
+	}
+
+	if err := os.Remove(identity); err != nil {
+		t.Fatal(err)
+	}
+	if got, err := readSecretFiles(runner, "web-1", files); err == nil {
+		t.Fatalf("readSecretFiles() without the identity = %v, want a decryption error instead of missing keys", got)
+	}
+}
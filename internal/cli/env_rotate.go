@@ -0,0 +1,201 @@
+package cli
+
+import (
+	"bufio"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/lemonity-org/azud/internal/config"
+	"github.com/lemonity-org/azud/internal/deploy"
+	"github.com/lemonity-org/azud/internal/output"
+)
+
+var envRotateCmd = &cobra.Command{
+	Use:   "rotate <key>",
+	Short: "Rotate a secret and roll the application",
+	Args:  cobra.ExactArgs(1),
+	Long: `Replace a secret, push it to every host, and roll the application so
+the new value takes effect.
+
+With secrets_provider=file the new value is read from stdin (--stdin) or
+generated (--generate). With the env or command providers, change the value
+at the source first; azud reloads it from the provider.
+
+Application containers are recreated one target at a time by redeploying the
+current version, because env files are only read when a container is
+created. The rotation is recorded in the local audit log without its value.
+
+Example:
+  azud env rotate SESSION_SECRET --generate
+  vault read -field=password secret/db | azud env rotate DATABASE_PASSWORD --stdin`,
+	RunE: runEnvRotate,
+}
+
+var (
+	envRotateStdin       bool
+	envRotateGenerate    bool
+	envRotateLength      int
+	envRotateSkipRestart bool
+)
+
+func init() {
+	envRotateCmd.Flags().BoolVar(&envRotateStdin, "stdin", false, "Read the new value from stdin (file provider)")
+	envRotateCmd.Flags().BoolVar(&envRotateGenerate, "generate", false, "Generate a random hex value (file provider)")
+	envRotateCmd.Flags().IntVar(&envRotateLength, "length", 32, "Random bytes to generate with --generate")
+	envRotateCmd.Flags().BoolVar(&envRotateSkipRestart, "skip-restart", false, "Push the new value without recreating containers")
+	envRotateCmd.Flags().BoolVar(&deployOverride, "override", false, deployOverrideUsage)
+}
+
+func runEnvRotate(cmd *cobra.Command, args []string) error {
+	output.SetVerbose(verbose)
+	log := output.DefaultLogger
+	key := args[0]
+
+	provider := strings.ToLower(strings.TrimSpace(cfg.SecretsProvider))
+	if provider == "" {
+		provider = "file"
+	}
+	entry := deploy.AuditEntry{
+		Service: cfg.Service,
+		Action:  "env.rotate",
+		Details: map[string]string{"key": key, "provider": provider},
+	}
+	audit := deploy.NewAuditLog()
+	fail := func(err error) error {
+		entry.Status = "failed"
+		entry.Error = err.Error()
+		if auditErr := audit.Record(entry); auditErr != nil {
+			log.Warn("Failed to record rotation in audit log: %v", auditErr)
+		}
+		return err
+	}
+
+	if isFileSecretsProvider() {
+		value, err := rotatedSecretValue()
+		if err != nil {
+			return err
+		}
+		secretsPath := getSecretsFilePath()
+		secrets, err := loadSecretsFile(secretsPath)
+		if err != nil {
+			return fmt.Errorf("failed to load secrets: %w", err)
+		}
+		if _, exists := secrets[key]; !exists {
+			return fmt.Errorf("secret %s not found; use 'azud env set' to add it", key)
+		}
+		secrets[key] = value
+		if err := writeSecretsFile(secretsPath, secrets); err != nil {
+			return fail(err)
+		}
+		log.Success("Updated %s in %s", key, secretsPath)
+	} else {
+		if envRotateStdin || envRotateGenerate {
+			return fmt.Errorf("--stdin and --generate require secrets_provider=file; rotate %s at the %s provider", key, provider)
+		}
+		if _, ok := config.GetSecret(key); !ok {
+			return fmt.Errorf("secret %s not provided by secrets_provider=%s", key, provider)
+		}
+	}
+
+	log.Header("Push secrets")
+	previousHost := envHost
+	envHost = ""
+	err := runEnvPush(cmd, nil)
+	envHost = previousHost
+	if err != nil {
+		return fail(fmt.Errorf("secret push failed: %w", err))
+	}
+	entry.Details["hosts"] = strconv.Itoa(len(cfg.GetAllSSHHosts()))
+
+	for _, name := range cfg.GetAccessoryNames() {
+		if slices.Contains(cfg.Accessories[name].Env.Secret, key) {
+			log.Warn("Accessory %s uses %s; recreate it to apply the new value", name, key)
+		}
+	}
+	if len(cfg.Cron) > 0 && slices.Contains(cfg.Env.Secret, key) {
+		log.Warn("Cron jobs use %s; run 'azud cron boot' to apply the new value", key)
+	}
+
+	appSecret := isAppSecret(key)
+	restart := appSecret && !envRotateSkipRestart
+	entry.Details["restarted"] = strconv.FormatBool(restart)
+	if restart {
+		history := deploy.NewDurableHistoryStore(cfg.Deploy.RetainHistory, log)
+		last, err := history.GetLastSuccessful(cfg.Service)
+		if err != nil || last.Version == "" {
+			return fail(fmt.Errorf("no successful deployment recorded to roll; deploy to apply the new value"))
+		}
+		entry.Details["version"] = last.Version
+
+		sshClient := createSSHClient()
+		defer func() { _ = sshClient.Close() }()
+		deployer := deploy.NewDeployer(cfg, sshClient, log)
+		if err := deployer.Redeploy(cmd.Context(), &deploy.DeployOptions{
+			Version:       last.Version,
+			Destination:   destination,
+			Approve:       deployApprover(cmd),
+			ApprovalToken: os.Getenv("AZUD_APPROVAL_TOKEN"),
+			Override:      deployOverride,
+		}); err != nil {
+			return fail(fmt.Errorf("rolling restart failed: %w", err))
+		}
+	} else if !appSecret {
+		log.Info("%s is not an application secret; no application restart needed", key)
+	}
+
+	entry.Status = "success"
+	if err := audit.Record(entry); err != nil {
+		log.Warn("Failed to record rotation in audit log: %v", err)
+	}
+	log.Success("Rotated %s", key)
+	return nil
+}
+
+// isAppSecret reports whether any application role receives key.
+func isAppSecret(key string) bool {
+	for _, role := range cfg.GetRoles() {
+		if !cfg.IsProxyTierRole(role) && slices.Contains(cfg.RoleSecrets(role), key) {
+			return true
+		}
+	}
+	return false
+}
+
+// rotatedSecretValue returns the new value for a file-provider rotation.
+func rotatedSecretValue() (string, error) {
+	switch {
+	case envRotateStdin && envRotateGenerate:
+		return "", fmt.Errorf("use either --stdin or --generate, not both")
+	case envRotateGenerate:
+		if envRotateLength < 16 {
+			return "", fmt.Errorf("--length must be at least 16")
+		}
+		buf := make([]byte, envRotateLength)
+		if _, err := rand.Read(buf); err != nil {
+			return "", fmt.Errorf("failed to generate value: %w", err)
+		}
+		return hex.EncodeToString(buf), nil
+	case envRotateStdin:
+		line, err := bufio.NewReader(os.Stdin).ReadString('\n')
+		if err != nil && line == "" {
+			return "", fmt.Errorf("failed to read value from stdin: %w", err)
+		}
+		value := strings.TrimRight(line, "\r\n")
+		if value == "" {
+			return "", fmt.Errorf("new value is empty")
+		}
+		if strings.ContainsAny(value, "\n\r") {
+			return "", fmt.Errorf("new value must be a single line")
+		}
+		return value, nil
+	default:
+		return "", fmt.Errorf("provide the new value with --stdin or --generate")
+	}
+}
@@ -0,0 +1,38 @@
+package cli
+
+import (
+	"encoding/hex"
+	"strings"
+	"testing"
+)
+
+func TestRotatedSecretValueGeneratesRandomHex(t *testing.T) {
+	previousStdin, previousGenerate, previousLength := envRotateStdin, envRotateGenerate, envRotateLength
+	t.Cleanup(func() {
+		envRotateStdin, envRotateGenerate, envRotateLength = previousStdin, previousGenerate, previousLength
+	})
+
+	envRotateStdin, envRotateGenerate, envRotateLength = false, true, 24
+	first, err := rotatedSecretValue()
+	if err != nil {
+		t.Fatalf("rotatedSecretValue: %v", err)
+	}
+	second, _ := rotatedSecretValue()
+	if decoded, err := hex.DecodeString(first); err != nil || len(decoded) != 24 || first == second {
+		t.Fatalf("generated values %q, %q are not distinct 24-byte hex strings", first, second)
+	}
+
+	envRotateLength = 8
+	if _, err := rotatedSecretValue(); err == nil || !strings.Contains(err.Error(), "at least 16") {
+		t.Fatalf("expected short length error, got %v", err)
+	}
+
+	envRotateStdin, envRotateGenerate = true, true
+	if _, err := rotatedSecretValue(); err == nil {
+		t.Fatal("expected --stdin with --generate to fail")
+	}
+	envRotateStdin, envRotateGenerate = false, false
+	if _, err := rotatedSecretValue(); err == nil {
+		t.Fatal("expected missing value source to fail")
+	}
+}
@@ -0,0 +1,93 @@
+package cli
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/lemonity-org/azud/internal/config"
+	"github.com/lemonity-org/azud/internal/deploy"
+	"github.com/lemonity-org/azud/internal/testutil"
+)
+
+func TestWriteEncryptedSecretsFile(t *testing.T) {
+	if _, err := exec.LookPath("sha256sum"); err != nil {
+		t.Skip("sha256sum is not installed")
+	}
+	oldCfg := cfg
+	t.Cleanup(func() { cfg = oldCfg })
+	dir := t.TempDir()
+	cfg = &config.Config{SecretsRemotePath: filepath.Join(dir, "secrets", "env")}
+	path := config.RemoteSecretsPath(cfg)
+	identity := config.RemoteSecretsIdentityPath(cfg)
+	encrypted := config.EncryptedSecretsPath(path)
+	runner := testutil.LocalExecutor{Bin: testutil.FakeAge(t)}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte("OLD=plaintext\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := writeEncryptedSecretsFile(runner, "web-1", path, map[string]string{"DATABASE_URL": "postgres://db"}); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Fatalf("plaintext file left behind: %v", err)
+	}
+	if _, err := os.Stat(identity); err != nil {
+		t.Fatalf("identity not created: %v", err)
+	}
+	ciphertext, err := os.ReadFile(encrypted)
+	if err != nil {
+		t.Fatal(err)
+	}
+	checksum, err := os.ReadFile(encrypted + ".sha256")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sum := sha256.Sum256(ciphertext); strings.TrimSpace(string(checksum)) != hex.EncodeToString(sum[:]) {
+		t.Fatalf("checksum %q does not match the encrypted file", checksum)
+	}
+
+	hosts := []string{"web-1"}
+	if err := deploy.ValidateEncryptedSecrets(runner, hosts, path, identity, []string{"DATABASE_URL"}); err != nil {
+		t.Fatalf("ValidateEncryptedSecrets() after writing = %v", err)
+	}
+	if err := deploy.ValidateEncryptedSecrets(runner, hosts, path, identity, []string{"DATABASE_URL", "API_KEY"}); err == nil || !strings.Contains(err.Error(), "API_KEY") {
+		t.Fatalf("ValidateEncryptedSecrets() = %v, want API_KEY missing", err)
+	}
+
+	if err := os.WriteFile(encrypted, append(ciphertext, "API_KEY=injected\n"...), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := deploy.ValidateEncryptedSecrets(runner, hosts, path, identity, []string{"DATABASE_URL"}); err == nil || !strings.Contains(err.Error(), "does not match its checksum") {
+		t.Fatalf("ValidateEncryptedSecrets() = %v, want a checksum mismatch", err)
+	}
+
+	if err := os.Remove(identity); err != nil {
+		t.Fatal(err)
+	}
+	if err := deploy.ValidateEncryptedSecrets(runner, hosts, path, identity, []string{"DATABASE_URL"}); err == nil || !strings.Contains(err.Error(), "identity") {
+		t.Fatalf("ValidateEncryptedSecrets() = %v, want a missing identity", err)
+	}
+}
+
+func TestWriteEncryptedSecretsFileRequiresAge(t *testing.T) {
+	if _, err := exec.LookPath("age"); err == nil {
+		t.Skip("age is installed")
+	}
+	oldCfg := cfg
+	t.Cleanup(func() { cfg = oldCfg })
+	cfg = &config.Config{SecretsRemotePath: filepath.Join(t.TempDir(), "env")}
+
+	err := writeEncryptedSecretsFile(testutil.LocalExecutor{Bin: t.TempDir()}, "web-1", config.RemoteSecretsPath(cfg), map[string]string{"DATABASE_URL": "postgres://db"})
+	if err == nil || !strings.Contains(err.Error(), "age is not installed") {
+		t.Fatalf("writeEncryptedSecretsFile() = %v, want age missing", err)
+	}
+}
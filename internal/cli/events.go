@@ -0,0 +1,217 @@
+package cli
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/lemonity-org/azud/internal/deploy"
+	"github.com/lemonity-org/azud/internal/output"
+	"github.com/lemonity-org/azud/internal/podman"
+)
+
+var eventsCmd = &cobra.Command{
+	Use:   "events",
+	Short: "Show operator actions or stream container events from the hosts",
+	Long: `Show the operator actions recorded in the local audit log for the service.
+
+With --remote, follow podman events for Azud-managed containers on every host
+instead and print them as one stream, each event tagged with its host, until
+interrupted. --since replays earlier events first. --format json prints each
+event as a JSON line with a "host" field added.
+
+Example:
+  azud events
+  azud events --remote
+  azud events --remote --since 30m --host 192.168.1.1
+  azud events --remote --format json`,
+	Args: cobra.NoArgs,
+	RunE: runEvents,
+}
+
+var (
+	eventsRemote bool
+	eventsHost   string
+	eventsSince  string
+	eventsFormat string
+)
+
+func init() {
+	eventsCmd.Flags().BoolVar(&eventsRemote, "remote", false, "Stream podman events from the hosts")
+	eventsCmd.Flags().StringVar(&eventsHost, "host", "", "Specific host (with --remote)")
+	eventsCmd.Flags().StringVar(&eventsSince, "since", "", "Replay events since a time or duration, e.g. 30m (with --remote)")
+	eventsCmd.Flags().StringVar(&eventsFormat, "format", "text", "Event format with --remote (text, json)")
+
+	rootCmd.AddCommand(eventsCmd)
+}
+
+func runEvents(cmd *cobra.Command, args []string) error {
+	output.SetVerbose(verbose)
+	log := output.DefaultLogger
+
+	if !eventsRemote {
+		if eventsHost != "" || eventsSince != "" || cmd.Flags().Changed("format") {
+			return fmt.Errorf("--host, --since, and --format require --remote")
+		}
+		return showAuditEvents(log)
+	}
+	if eventsFormat != "text" && eventsFormat != "json" {
+		return fmt.Errorf("invalid --format %q: must be text or json", eventsFormat)
+	}
+
+	hosts := portsHosts()
+	if eventsHost != "" {
+		if !containsString(hosts, eventsHost) {
+			return fmt.Errorf("host %s is not configured", eventsHost)
+		}
+		hosts = []string{eventsHost}
+	}
+
+	sshClient := createSSHClient()
+	defer func() { _ = sshClient.Close() }()
+	containerManager := podman.NewContainerManager(podman.NewClient(sshClient))
+
+	log.Info("Following container events on %d host(s) (Ctrl+C to stop)", len(hosts))
+	filters := []string{"type=container", "label=azud.managed=true"}
+
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	var streamErrors []string
+	for _, host := range hosts {
+		wg.Add(1)
+		go func(host string) {
+			defer wg.Done()
+			err := streamHostEvents(log, containerManager, host, filters)
+			if err != nil && cmd.Context().Err() == nil {
+				log.HostError(host, "%v", err)
+				mu.Lock()
+				streamErrors = append(streamErrors, fmt.Sprintf("%s: %v", host, err))
+				mu.Unlock()
+			}
+		}(host)
+	}
+	wg.Wait()
+
+	if cmd.Context().Err() != nil {
+		return nil
+	}
+	if len(streamErrors) > 0 {
+		sort.Strings(streamErrors)
+		return fmt.Errorf("event stream failed: %s", strings.Join(streamErrors, "; "))
+	}
+	return nil
+}
+
+// streamHostEvents follows podman events on host and prints each event as it
+// arrives. Lines from concurrent hosts never interleave because the logger
+// writes each under its lock.
+func streamHostEvents(log *output.Logger, containerManager *podman.ContainerManager, host string, filters []string) error {
+	reader, writer := io.Pipe()
+	var stderr bytes.Buffer
+	done := make(chan error, 1)
+	go func() {
+		err := containerManager.EventsStream(host, filters, eventsSince, writer, &stderr)
+		_ = writer.CloseWithError(err)
+		done <- err
+	}()
+
+	scanner := bufio.NewScanner(reader)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := bytes.TrimSpace(scanner.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+		formatted, err := formatHostEvent(host, line, eventsFormat)
+		if err != nil {
+			log.Debug("%s: %v", host, err)
+			continue
+		}
+		log.Println("%s", formatted)
+	}
+	// Drain the pipe so the stream can finish if scanning stopped early.
+	_, _ = io.Copy(io.Discard, reader)
+
+	if err := <-done; err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return fmt.Errorf("%w: %s", err, msg)
+		}
+		return err
+	}
+	return nil
+}
+
+// formatHostEvent renders a podman JSON event from host. The text format is
+// one aligned line; the json format is the podman event with a host field.
+func formatHostEvent(host string, line []byte, format string) (string, error) {
+	if format == "json" {
+		var fields map[string]any
+		if err := json.Unmarshal(line, &fields); err != nil {
+			return "", fmt.Errorf("invalid podman event: %w", err)
+		}
+		fields["host"] = host
+		data, err := json.Marshal(fields)
+		if err != nil {
+			return "", err
+		}
+		return string(data), nil
+	}
+
+	event, err := podman.ParseEvent(line)
+	if err != nil {
+		return "", err
+	}
+	when := "-"
+	if !event.Time.IsZero() {
+		when = event.Time.Local().Format(time.DateTime)
+	}
+	text := fmt.Sprintf("%s  %s  %-10s %s", when, host, event.Status, event.Name)
+	if event.ExitCode != nil && (event.Status == "died" || event.Status == "exited") {
+		text += fmt.Sprintf(" (exit %d)", *event.ExitCode)
+	}
+	if event.Image != "" {
+		text += "  " + event.Image
+	}
+	return text, nil
+}
+
+// showAuditEvents prints the service's entries in the local audit log.
+func showAuditEvents(log *output.Logger) error {
+	entries, err := deploy.NewAuditLog().Entries(cfg.Service)
+	if err != nil {
+		return err
+	}
+	if len(entries) == 0 {
+		log.Info("No recorded events for %s", cfg.Service)
+		return nil
+	}
+
+	rows := make([][]string, 0, len(entries))
+	for _, entry := range entries {
+		details := make([]string, 0, len(entry.Details))
+		for key, value := range entry.Details {
+			details = append(details, key+"="+value)
+		}
+		sort.Strings(details)
+		if entry.Error != "" {
+			details = append(details, "error="+entry.Error)
+		}
+		rows = append(rows, []string{
+			entry.Time.Local().Format(time.DateTime),
+			entry.Action,
+			entry.Status,
+			entry.Actor,
+			strings.Join(details, " "),
+		})
+	}
+	log.Table([]string{"Time", "Action", "Status", "Actor", "Details"}, rows)
+	return nil
+}
@@ -0,0 +1,37 @@
+package cli
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestFormatHostEvent(t *testing.T) {
+	line := []byte(`{"Name":"shop","Status":"died","Type":"container","Image":"ghcr.io/acme/shop:v2","ContainerExitCode":137,"timeNano":1767319445000000000}`)
+
+	text, err := formatHostEvent("app-01", line, "text")
+	if err != nil {
+		t.Fatalf("formatHostEvent: %v", err)
+	}
+	for _, want := range []string{"app-01", "died", "shop (exit 137)", "ghcr.io/acme/shop:v2"} {
+		if !strings.Contains(text, want) {
+			t.Fatalf("text event %q missing %q", text, want)
+		}
+	}
+
+	data, err := formatHostEvent("app-01", line, "json")
+	if err != nil {
+		t.Fatalf("formatHostEvent: %v", err)
+	}
+	var fields map[string]any
+	if err := json.Unmarshal([]byte(data), &fields); err != nil {
+		t.Fatalf("json event is not JSON: %v", err)
+	}
+	if fields["host"] != "app-01" || fields["Status"] != "died" {
+		t.Fatalf("unexpected json event: %v", fields)
+	}
+
+	if _, err := formatHostEvent("app-01", []byte("garbage"), "text"); err == nil {
+		t.Fatal("expected error for invalid event")
+	}
+}
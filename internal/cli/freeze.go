@@ -0,0 +1,201 @@
+package cli
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/lemonity-org/azud/internal/deploy"
+	"github.com/lemonity-org/azud/internal/output"
+)
+
+var freezeCmd = &cobra.Command{
+	Use:   "freeze",
+	Short: "Block deploys until a time",
+	Long: `Freeze deploys of the service to a destination (-d, or the base
+configuration) until a time. While frozen, deploy, redeploy, rollback,
+promote, and every other command that deploys refuse to run unless given
+--override, which is recorded in the audit log. Deploys through azud agent,
+azud serve, and azud watch are refused outright.
+
+Freezes are local to this machine: they are kept in its durable state
+directory (or AZUD_STATE_DIR), next to deployment history, and are not seen
+by deploys run from other machines, such as CI runners or a teammate's
+checkout, unless they share that directory.
+
+Examples:
+  azud freeze until 2026-12-28 --reason "Holiday freeze" -d production
+  azud freeze until 48h --reason "Launch weekend"
+  azud freeze status -d production
+  azud freeze lift -d production`,
+}
+
+var freezeUntilCmd = &cobra.Command{
+	Use:   "until <time>",
+	Short: "Freeze deploys until a time or for a duration",
+	Long: `Freeze deploys until a time such as 2026-12-28, 2026-12-28 09:00, or
+2026-12-28T09:00:00Z, or for a duration such as 48h. Times without a zone are
+read in deploy.windows.timezone, or in local time. An existing freeze of the
+destination is replaced.`,
+	Args: cobra.ExactArgs(1),
+	RunE: runFreezeUntil,
+}
+
+var freezeStatusCmd = &cobra.Command{
+	Use:   "status",
+	Short: "Show the freeze and deployment windows",
+	Args:  cobra.NoArgs,
+	RunE:  runFreezeStatus,
+}
+
+var freezeLiftCmd = &cobra.Command{
+	Use:   "lift",
+	Short: "Lift the freeze before it ends",
+	Args:  cobra.NoArgs,
+	RunE:  runFreezeLift,
+}
+
+var freezeReason string
+
+func init() {
+	freezeUntilCmd.Flags().StringVar(&freezeReason, "reason", "", "Why deploys are frozen (required)")
+	_ = freezeUntilCmd.MarkFlagRequired("reason")
+
+	freezeCmd.AddCommand(freezeUntilCmd)
+	freezeCmd.AddCommand(freezeStatusCmd)
+	freezeCmd.AddCommand(freezeLiftCmd)
+
+	rootCmd.AddCommand(freezeCmd)
+}
+
+// freezeUntilLayouts are the accepted <time> formats without a zone.
+var freezeUntilLayouts = []string{
+	"2006-01-02",
+	"2006-01-02 15:04",
+	"2006-01-02T15:04",
+	"2006-01-02 15:04:05",
+	"2006-01-02T15:04:05",
+}
+
+// parseFreezeUntil reads <time> as a duration from now, an RFC 3339 time,
+// or a time without a zone in loc.
+func parseFreezeUntil(value string, now time.Time, loc *time.Location) (time.Time, error) {
+	value = strings.TrimSpace(value)
+	until, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		if d, durErr := time.ParseDuration(value); durErr == nil {
+			until, err = now.Add(d), nil
+		}
+	}
+	for _, layout := range freezeUntilLayouts {
+		if err == nil {
+			break
+		}
+		until, err = time.ParseInLocation(layout, value, loc)
+	}
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid time %q: use a time such as 2026-12-28 09:00 or a duration such as 48h", value)
+	}
+	if !until.After(now) {
+		return time.Time{}, fmt.Errorf("%s is in the past", value)
+	}
+	return until, nil
+}
+
+func runFreezeUntil(cmd *cobra.Command, args []string) error {
+	output.SetVerbose(verbose)
+	log := output.DefaultLogger
+
+	reason := strings.TrimSpace(freezeReason)
+	if reason == "" {
+		return fmt.Errorf("--reason must not be empty")
+	}
+	loc, err := cfg.Deploy.Windows.Location()
+	if err != nil {
+		return err
+	}
+	until, err := parseFreezeUntil(args[0], time.Now(), loc)
+	if err != nil {
+		return err
+	}
+
+	freeze := &deploy.Freeze{
+		Service:     cfg.Service,
+		Destination: GetDestination(),
+		Until:       until.UTC(),
+		Reason:      reason,
+		By:          deploy.CurrentUser(),
+	}
+	if err := deploy.NewFreezeStore().Set(freeze); err != nil {
+		return err
+	}
+	recordFreezeAudit(log, "freeze", freeze)
+	log.Success("Deploys of %s to %s are frozen until %s", cfg.Service, deploy.DestinationLabel(freeze.Destination), until.In(loc).Format(time.DateTime+" MST"))
+	return nil
+}
+
+func runFreezeStatus(cmd *cobra.Command, args []string) error {
+	output.SetVerbose(verbose)
+	log := output.DefaultLogger
+
+	now := time.Now()
+	freeze, err := deploy.NewFreezeStore().Active(cfg.Service, GetDestination(), now)
+	if err != nil {
+		return err
+	}
+	if freeze != nil {
+		log.Warn("Frozen until %s by %s: %s", freeze.Until.Local().Format(time.DateTime), freeze.By, freeze.Reason)
+	} else {
+		log.Info("No freeze of %s to %s", cfg.Service, deploy.DestinationLabel(GetDestination()))
+	}
+
+	windows := cfg.Deploy.Windows
+	if len(windows.Allowed) == 0 {
+		log.Info("No deployment windows; deploys are allowed at any time")
+		return nil
+	}
+	allowed, err := windows.Allows(now)
+	if err != nil {
+		return err
+	}
+	if allowed {
+		log.Success("Inside the deployment windows %s", windows)
+	} else {
+		log.Warn("Outside the deployment windows %s", windows)
+	}
+	return nil
+}
+
+func runFreezeLift(cmd *cobra.Command, args []string) error {
+	output.SetVerbose(verbose)
+	log := output.DefaultLogger
+
+	freeze, err := deploy.NewFreezeStore().Lift(cfg.Service, GetDestination())
+	if err != nil {
+		return err
+	}
+	if freeze == nil {
+		log.Info("No freeze of %s to %s", cfg.Service, deploy.DestinationLabel(GetDestination()))
+		return nil
+	}
+	recordFreezeAudit(log, "freeze.lift", freeze)
+	log.Success("Lifted the freeze of %s to %s", cfg.Service, deploy.DestinationLabel(freeze.Destination))
+	return nil
+}
+
+func recordFreezeAudit(log *output.Logger, action string, freeze *deploy.Freeze) {
+	if err := deploy.NewAuditLog().Record(deploy.AuditEntry{
+		Service: cfg.Service,
+		Action:  action,
+		Status:  "success",
+		Details: map[string]string{
+			"destination": freeze.Destination,
+			"until":       freeze.Until.Format(time.RFC3339),
+			"reason":      freeze.Reason,
+		},
+	}); err != nil {
+		log.Warn("Failed to record %s in audit log: %v", action, err)
+	}
+}
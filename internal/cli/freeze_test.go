@@ -0,0 +1,38 @@
+package cli
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseFreezeUntil(t *testing.T) {
+	berlin, err := time.LoadLocation("Europe/Berlin")
+	if err != nil {
+		t.Fatal(err)
+	}
+	now := time.Date(2026, 10, 17, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		value   string
+		want    time.Time
+		wantErr bool
+	}{
+		{value: "48h", want: now.Add(48 * time.Hour)},
+		{value: "2026-12-28", want: time.Date(2026, 12, 28, 0, 0, 0, 0, berlin)},
+		{value: "2026-12-28 09:30", want: time.Date(2026, 12, 28, 9, 30, 0, 0, berlin)},
+		{value: "2026-12-28T09:30:00Z", want: time.Date(2026, 12, 28, 9, 30, 0, 0, time.UTC)},
+		{value: "2026-10-01", wantErr: true},
+		{value: "-1h", wantErr: true},
+		{value: "next friday", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			got, err := parseFreezeUntil(tt.value, now, berlin)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseFreezeUntil(%q) error = %v, wantErr %v", tt.value, err, tt.wantErr)
+			}
+			if !tt.wantErr && !got.Equal(tt.want) {
+				t.Errorf("parseFreezeUntil(%q) = %s, want %s", tt.value, got, tt.want)
+			}
+		})
+	}
+}
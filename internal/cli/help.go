@@ -93,13 +93,15 @@ func writeCommandIndex(writer io.Writer, command *cobra.Command, widthLimit int)
 			continue
 		}
 		group := "COMMANDS"
-		if command == command.Root() {
+		if child.Annotations[aliasAnnotation] != "" {
+			group = "ALIASES"
+		} else if command == command.Root() {
 			group = rootCommandGroup(child.Name())
 		}
 		groups[group] = append(groups[group], child)
 	}
 
-	order := []string{"DEPLOY", "OPERATE", "SYSTEM", "REFERENCE", "COMMANDS"}
+	order := []string{"DEPLOY", "OPERATE", "SYSTEM", "REFERENCE", "COMMANDS", "ALIASES"}
 	for _, group := range order {
 		commands := groups[group]
 		if len(commands) == 0 {
@@ -129,11 +131,11 @@ func writeCommandIndex(writer io.Writer, command *cobra.Command, widthLimit int)
 
 func rootCommandGroup(name string) string {
 	switch name {
-	case "build", "deploy", "history", "preflight", "redeploy", "rollback", "setup":
+	case "build", "bundle", "deploy", "freeze", "history", "preflight", "promote", "redeploy", "rollback", "serve", "setup":
 		return "DEPLOY"
-	case "accessory", "app", "canary", "cron", "proxy", "scale":
+	case "accessory", "app", "canary", "cron", "events", "placement", "ports", "proxy", "scale", "traffic", "volume", "watch":
 		return "OPERATE"
-	case "config", "env", "hooks", "init", "registry", "server", "ssh", "systemd":
+	case "agent", "config", "env", "hooks", "init", "registry", "server", "ssh", "systemd", "trust", "upgrade":
 		return "SYSTEM"
 	default:
 		return "REFERENCE"
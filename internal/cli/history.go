@@ -2,6 +2,7 @@ package cli
 
 import (
 	"fmt"
+	"slices"
 	"sort"
 	"strings"
 	"time"
@@ -14,14 +15,15 @@ import (
 
 var historyCmd = &cobra.Command{
 	Use:     "history",
-	Aliases: []string{"releases"},
+	Aliases: []string{"releases", "deployments"},
 	Short:   "Show deployment history",
 	Long: `View deployment history records stored in Azud's durable state directory.
 
 Examples:
   azud history list
   azud history list --limit 50
-  azud history show deploy_123456789`,
+  azud history show deploy_123456789
+  azud history timing deploy_123456789`,
 }
 
 var historyListCmd = &cobra.Command{
@@ -46,13 +48,33 @@ Example:
 	RunE: runHistoryShow,
 }
 
-var historyLimit int
+var historyTimingCmd = &cobra.Command{
+	Use:   "timing [id]",
+	Short: "Show how long each deployment phase took",
+	Long: `Show how long each phase (pull, container start, health wait, proxy
+switch, drain, finalize) took on each host of a deployment. Without an ID,
+show percentiles of each phase over the recent successful deployments to the
+destination, to find what is worth optimizing.
+
+Examples:
+  azud history timing deploy_123456789
+  azud history timing --limit 50 -d production`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: runHistoryTiming,
+}
+
+var (
+	historyLimit       int
+	historyTimingLimit int
+)
 
 func init() {
 	historyListCmd.Flags().IntVar(&historyLimit, "limit", 20, "Maximum number of records to show (0 = all)")
 
 	historyCmd.AddCommand(historyListCmd)
 	historyCmd.AddCommand(historyShowCmd)
+	historyCmd.AddCommand(historyTimingCmd)
+	historyTimingCmd.Flags().IntVar(&historyTimingLimit, "limit", 20, "Successful deployments to aggregate without an ID (0 = all)")
 
 	rootCmd.AddCommand(historyCmd)
 }
@@ -131,12 +153,28 @@ func runHistoryShow(cmd *cobra.Command, args []string) error {
 		log.Println("Error: %s", record.Error)
 	}
 
+	if len(record.Targets) > 0 {
+		log.Println("")
+		log.Println("Targets:")
+
+		rows := make([][]string, 0, len(record.Targets))
+		for _, target := range record.Targets {
+			rows = append(rows, []string{target.Host, target.Role, string(target.Status), valueOrDash(target.Error)})
+		}
+		log.Table([]string{"Host", "Role", "Status", "Error"}, rows)
+	}
+
 	if len(record.Metadata) > 0 {
 		log.Println("")
 		log.Println("Metadata:")
 
 		keys := make([]string, 0, len(record.Metadata))
+		timed := false
 		for key := range record.Metadata {
+			if strings.HasPrefix(key, deploy.TimingMetadataPrefix) {
+				timed = true
+				continue
+			}
 			keys = append(keys, key)
 		}
 		sort.Strings(keys)
@@ -147,11 +185,120 @@ func runHistoryShow(cmd *cobra.Command, args []string) error {
 		}
 
 		log.Table([]string{"Key", "Value"}, rows)
+		if timed {
+			log.Info("Show phase timings with: azud history timing %s", record.ID)
+		}
+	}
+
+	return nil
+}
+
+func runHistoryTiming(cmd *cobra.Command, args []string) error {
+	output.SetVerbose(verbose)
+	log := output.DefaultLogger
+
+	if historyTimingLimit < 0 {
+		return fmt.Errorf("--limit must be >= 0")
+	}
+	history := newHistoryStore(log)
+
+	if len(args) == 1 {
+		record, err := history.Get(args[0])
+		if err != nil {
+			if strings.Contains(err.Error(), "deployment record not found") {
+				return fmt.Errorf("deployment record %s not found", args[0])
+			}
+			return fmt.Errorf("failed to load deployment history: %w", err)
+		}
+		log.Header("Deployment %s timing", record.ID)
+		headers, rows := phaseTimingRows(record.PhaseTimings())
+		if len(rows) == 0 {
+			log.Info("No phase timings recorded for %s", record.ID)
+			return nil
+		}
+		log.Table(headers, rows)
+		return nil
 	}
 
+	records, err := history.List(cfg.Service, 0)
+	if err != nil {
+		return fmt.Errorf("failed to list deployment history: %w", err)
+	}
+	var successful []*deploy.DeploymentRecord
+	for _, record := range records {
+		if record.Status != deploy.StatusSuccess || record.Destination != GetDestination() {
+			continue
+		}
+		successful = append(successful, record)
+		if historyTimingLimit > 0 && len(successful) == historyTimingLimit {
+			break
+		}
+	}
+
+	log.Header("Deployment phase timing / %s", deploy.DestinationLabel(GetDestination()))
+	summaries := deploy.SummarizePhaseTimings(successful)
+	if len(summaries) == 0 {
+		log.Info("No phase timings recorded for successful deployments of %s", cfg.Service)
+		return nil
+	}
+	rows := make([][]string, 0, len(summaries))
+	for _, summary := range summaries {
+		rows = append(rows, []string{
+			summary.Phase,
+			fmt.Sprintf("%d", summary.Count),
+			formatPhaseDuration(summary.P50),
+			formatPhaseDuration(summary.P90),
+			formatPhaseDuration(summary.P99),
+			formatPhaseDuration(summary.Max),
+		})
+	}
+	log.Table([]string{"Phase", "Samples", "p50", "p90", "p99", "Max"}, rows)
+	log.Info("Across %d successful deployment(s); samples count each host and role", len(successful))
 	return nil
 }
 
+// phaseTimingRows lays timings out with one row per target and one column
+// per phase, in the order the timings are given, plus the total.
+func phaseTimingRows(timings []deploy.PhaseTiming) ([]string, [][]string) {
+	var phases, targets []string
+	byTarget := make(map[string]map[string]time.Duration)
+	for _, timing := range timings {
+		target := timing.Host + "\x00" + timing.Role
+		if byTarget[target] == nil {
+			byTarget[target] = make(map[string]time.Duration)
+			targets = append(targets, target)
+		}
+		byTarget[target][timing.Phase] = timing.Duration
+		if !slices.Contains(phases, timing.Phase) {
+			phases = append(phases, timing.Phase)
+		}
+	}
+
+	headers := append([]string{"Host", "Role"}, phases...)
+	headers = append(headers, "Total")
+	rows := make([][]string, 0, len(targets))
+	for _, target := range targets {
+		host, role, _ := strings.Cut(target, "\x00")
+		row := []string{host, role}
+		var total time.Duration
+		for _, phase := range phases {
+			d, ok := byTarget[target][phase]
+			if !ok {
+				row = append(row, "-")
+				continue
+			}
+			total += d
+			row = append(row, formatPhaseDuration(d))
+		}
+		rows = append(rows, append(row, formatPhaseDuration(total)))
+	}
+	return headers, rows
+}
+
+func formatPhaseDuration(d time.Duration) string {
+	return fmt.Sprintf("%.2fs", d.Seconds())
+}
+
 func newHistoryStore(log *output.Logger) *deploy.HistoryStore {
 	return deploy.NewDurableHistoryStore(cfg.Deploy.RetainHistory, log)
 }
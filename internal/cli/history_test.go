@@ -2,6 +2,7 @@ package cli
 
 import (
 	"bytes"
+	"fmt"
 	"os"
 	"strings"
 	"testing"
@@ -146,6 +147,76 @@ func TestRunHistoryShowNotFound(t *testing.T) {
 	}
 }
 
+func TestRunHistoryTiming(t *testing.T) {
+	cwd, err := os.Getwd()
+	if err != nil {
+		t.Fatalf("getwd: %v", err)
+	}
+	t.Cleanup(func() {
+		_ = os.Chdir(cwd)
+	})
+
+	tempDir := t.TempDir()
+	t.Setenv("AZUD_STATE_DIR", tempDir)
+	if err := os.Chdir(tempDir); err != nil {
+		t.Fatalf("chdir: %v", err)
+	}
+
+	buf := setupHistoryTestState(t)
+	history := deploy.NewDurableHistoryStore(20, output.DefaultLogger)
+
+	base := time.Date(2026, 2, 8, 12, 0, 0, 0, time.UTC)
+	for i, pull := range []string{"2s", "4s"} {
+		id := fmt.Sprintf("deploy_%d", i+1)
+		started := base.Add(time.Duration(i) * time.Hour)
+		record := newHistoryRecord(
+			id, "test-service", "v1", "ghcr.io/acme/test:v1",
+			started, started.Add(time.Minute), deploy.StatusSuccess, []string{"10.0.0.1"},
+		)
+		record.Metadata["timing.10.0.0.1/web.pull"] = pull
+		record.Metadata["timing.10.0.0.1/web.health"] = "3s"
+		if err := history.Record(record); err != nil {
+			t.Fatalf("record history entry: %v", err)
+		}
+	}
+	failed := newHistoryRecord(
+		"deploy_3", "test-service", "v2", "ghcr.io/acme/test:v2",
+		base.Add(3*time.Hour), base.Add(3*time.Hour+time.Minute), deploy.StatusFailed, []string{"10.0.0.1"},
+	)
+	failed.Metadata["timing.10.0.0.1/web.pull"] = "90s"
+	if err := history.Record(failed); err != nil {
+		t.Fatalf("record history entry: %v", err)
+	}
+
+	if err := runHistoryTiming(nil, []string{"deploy_2"}); err != nil {
+		t.Fatalf("runHistoryTiming(id): %v", err)
+	}
+	out := buf.String()
+	for _, want := range []string{"Deployment deploy_2 timing", "10.0.0.1", "web", "4.00s", "3.00s", "7.00s"} {
+		if !strings.Contains(out, want) {
+			t.Fatalf("expected output to contain %q, got:\n%s", want, out)
+		}
+	}
+
+	oldDestination := destination
+	destination = "production"
+	t.Cleanup(func() { destination = oldDestination })
+
+	buf.Reset()
+	if err := runHistoryTiming(nil, nil); err != nil {
+		t.Fatalf("runHistoryTiming(): %v", err)
+	}
+	out = buf.String()
+	for _, want := range []string{"Samples", "p50", "pull", "2.00s", "4.00s", "Across 2 successful deployment(s)"} {
+		if !strings.Contains(out, want) {
+			t.Fatalf("expected output to contain %q, got:\n%s", want, out)
+		}
+	}
+	if strings.Contains(out, "90.00s") {
+		t.Fatalf("expected failed deployments to be left out, got:\n%s", out)
+	}
+}
+
 func TestFormatHistoryHosts(t *testing.T) {
 	tests := []struct {
 		name  string
@@ -174,6 +245,7 @@ func setupHistoryTestState(t *testing.T) *bytes.Buffer {
 	oldCfg := cfg
 	oldVerbose := verbose
 	oldLimit := historyLimit
+	oldTimingLimit := historyTimingLimit
 
 	var buf bytes.Buffer
 	output.DefaultLogger = output.NewLogger(&buf, &buf, false)
@@ -185,12 +257,14 @@ func setupHistoryTestState(t *testing.T) *bytes.Buffer {
 	}
 	verbose = false
 	historyLimit = 20
+	historyTimingLimit = 20
 
 	t.Cleanup(func() {
 		output.DefaultLogger = oldLogger
 		cfg = oldCfg
 		verbose = oldVerbose
 		historyLimit = oldLimit
+		historyTimingLimit = oldTimingLimit
 	})
 
 	return &buf
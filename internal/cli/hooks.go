@@ -92,7 +92,13 @@ func runHooksList(cmd *cobra.Command, args []string) error {
 }
 
 func hookStatus(hooksPath, name string) string {
-	info, err := os.Lstat(filepath.Join(hooksPath, name))
+	var info os.FileInfo
+	err := os.ErrNotExist
+	for _, fileName := range deploy.HookFileNames(name) {
+		if info, err = os.Lstat(filepath.Join(hooksPath, fileName)); !os.IsNotExist(err) {
+			break
+		}
+	}
 	if os.IsNotExist(err) {
 		return "missing"
 	}
@@ -105,7 +111,7 @@ func hookStatus(hooksPath, name string) string {
 	if info.Mode()&os.ModeSymlink != 0 {
 		return "symlink"
 	}
-	if info.Mode()&0111 == 0 {
+	if !deploy.HookExecutable(info) {
 		return "not executable"
 	}
 	return "ready"
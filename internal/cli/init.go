@@ -6,6 +6,7 @@ import (
 	"path/filepath"
 	"strings"
 
+	"github.com/mattn/go-isatty"
 	"github.com/spf13/cobra"
 
 	"github.com/lemonity-org/azud/internal/output"
@@ -16,32 +17,53 @@ var initCmd = &cobra.Command{
 	Short: "Create a new deployment configuration",
 	Long: `Initialize a new Azud deployment configuration.
 
+In a terminal, init asks for the service name, image, registry, hosts, SSL,
+and accessories, and generates a deploy.yml for those answers. With
+--defaults, or when stdin is not a terminal, it writes a fully commented
+template instead.
+
 This creates:
   - config/deploy.yml: Main deployment configuration
   - .azud/secrets: File to store secrets
   - .azud/hooks/: Directory for hook scripts
 
 Options:
-  --bundle        Use a template bundle (e.g., rails, node)
+  --bundle        Use a built-in bundle: rails, django, node, laravel, static
   --github-actions Create a GitHub Actions workflow for CI/CD
+  --defaults      Skip the questions and write the commented template
+  --from          Import an existing kamal or compose file given as argument
+
+With --from, init translates an existing Kamal config/deploy.yml or Docker
+Compose file into deploy.yml: servers, proxy, environment, and accessories.
+Settings without an azud equivalent are listed for manual follow-up.
 
-You can specify a bundle to use a template from the Azud registry.
+A bundle sets the application port, healthcheck path, environment, and
+accessories for that kind of application and writes a starting Dockerfile
+(an existing Dockerfile is kept).
 
 Example:
   azud init
+  azud init --defaults
   azud init --github-actions
-  azud init --bundle rails`,
+  azud init --bundle rails
+  azud init --from kamal config/deploy.yml
+  azud init --from compose docker-compose.yml`,
+	Args: cobra.MaximumNArgs(1),
 	RunE: runInit,
 }
 
 var (
 	initBundle        string
 	initGitHubActions bool
+	initDefaults      bool
+	initFrom          string
 )
 
 func init() {
-	initCmd.Flags().StringVar(&initBundle, "bundle", "", "Template bundle to use (e.g., rails, node)")
+	initCmd.Flags().StringVar(&initBundle, "bundle", "", "Template bundle to use (rails, django, node, laravel, static)")
 	initCmd.Flags().BoolVar(&initGitHubActions, "github-actions", false, "Include GitHub Actions workflow")
+	initCmd.Flags().StringVar(&initFrom, "from", "", "Import an existing configuration (kamal, compose)")
+	initCmd.Flags().BoolVar(&initDefaults, "defaults", false, "Write the commented template without prompting")
 }
 
 func runInit(cmd *cobra.Command, args []string) error {
@@ -52,7 +74,60 @@ func runInit(cmd *cobra.Command, args []string) error {
 		return fmt.Errorf("configuration already exists at %s", existingConfig)
 	}
 
+	if initFrom == "" && len(args) > 0 {
+		return fmt.Errorf("unexpected argument %q; a file is only accepted with --from", args[0])
+	}
+	if initFrom != "" {
+		if len(args) == 0 {
+			return fmt.Errorf("--from %s requires the file to import", initFrom)
+		}
+		if initBundle != "" {
+			return fmt.Errorf("--from cannot be combined with --bundle")
+		}
+	}
+
+	answers := defaultInitAnswers()
+	var bundle *initBundleSpec
+	if initBundle != "" {
+		b, err := lookupInitBundle(initBundle)
+		if err != nil {
+			return err
+		}
+		b.apply(answers)
+		bundle = &b
+	}
+
+	configContent := getConfigTemplate(initGitHubActions)
+	secretsContent := getSecretsTemplate()
+	var importNotes []string
+	if initFrom != "" {
+		imported, notes, err := importConfig(initFrom, args[0])
+		if err != nil {
+			return err
+		}
+		configContent, err = renderImportedConfig(imported, args[0], initGitHubActions)
+		if err != nil {
+			return fmt.Errorf("failed to render imported configuration: %w", err)
+		}
+		secretsContent = renderImportedSecrets(imported)
+		importNotes = notes
+	} else if !initDefaults && cmd.InOrStdin() == os.Stdin && isatty.IsTerminal(os.Stdin.Fd()) {
+		var err error
+		answers, err = promptInitAnswers(os.Stdin, cmd.OutOrStdout(), answers)
+		if err != nil {
+			return err
+		}
+		configContent = renderInitConfig(answers, initGitHubActions)
+		secretsContent = renderInitSecrets(answers)
+	} else if bundle != nil {
+		configContent = renderInitConfig(answers, initGitHubActions)
+		secretsContent = renderInitSecrets(answers)
+	}
+
 	log.Header("Initialize / configuration")
+	if bundle != nil {
+		log.Info("Using %s bundle: %s", strings.ToLower(initBundle), bundle.Description)
+	}
 
 	// Create directories
 	dirs := []string{
@@ -73,18 +148,30 @@ func runInit(cmd *cobra.Command, args []string) error {
 
 	// Create deploy.yml
 	configPath := filepath.Join("config", "deploy.yml")
-	if err := os.WriteFile(configPath, []byte(getConfigTemplate(initGitHubActions)), 0644); err != nil {
+	if err := os.WriteFile(configPath, []byte(configContent), 0644); err != nil {
 		return fmt.Errorf("failed to create %s: %w", configPath, err)
 	}
 	log.Success("Created %s", configPath)
 
 	// Create secrets file
 	secretsPath := filepath.Join(".azud", "secrets")
-	if err := os.WriteFile(secretsPath, []byte(getSecretsTemplate()), 0600); err != nil {
+	if err := os.WriteFile(secretsPath, []byte(secretsContent), 0600); err != nil {
 		return fmt.Errorf("failed to create %s: %w", secretsPath, err)
 	}
 	log.Success("Created %s", secretsPath)
 
+	// Create the bundle's Dockerfile unless the project already has one
+	if bundle != nil {
+		if _, err := os.Stat("Dockerfile"); err == nil {
+			log.Info("Keeping existing Dockerfile")
+		} else {
+			if err := os.WriteFile("Dockerfile", []byte(bundle.Dockerfile), 0644); err != nil {
+				return fmt.Errorf("failed to create Dockerfile: %w", err)
+			}
+			log.Success("Created Dockerfile")
+		}
+	}
+
 	// Create sample hook scripts
 	hooks := map[string]string{
 		"pre-connect":       getPreConnectHook(),
@@ -97,6 +184,7 @@ func runInit(cmd *cobra.Command, args []string) error {
 		"pre-proxy-reboot":  getPreProxyRebootHook(),
 		"post-proxy-reboot": getPostProxyRebootHook(),
 		"post-rollback":     getPostRollbackHook(),
+		"restart-loop":      getRestartLoopHook(),
 	}
 
 	for name, content := range hooks {
@@ -121,6 +209,13 @@ func runInit(cmd *cobra.Command, args []string) error {
 		return fmt.Errorf("failed to protect .azud/secrets in .gitignore: %w", err)
 	}
 
+	if len(importNotes) > 0 {
+		log.Header("Review imported configuration")
+		for _, note := range importNotes {
+			log.Warn("%s", note)
+		}
+	}
+
 	log.Header("Next actions")
 	total := 3
 	if initGitHubActions {
@@ -188,7 +283,8 @@ proxy:
   # rootful: true
   # Application port inside the container
   app_port: 3000
-  # Protocol from Caddy to the application: http, h2c, or https
+  # Protocol from Caddy to the application: http, h2c, h2, or https
+  # (h2c for plaintext gRPC)
   # upstream_protocol: http
   # Health check configuration
   healthcheck:
@@ -537,6 +633,27 @@ echo "Running post-rollback hook..."
 `
 }
 
+func getRestartLoopHook() string {
+	return `#!/bin/sh
+# Restart-loop hook — runs when azud watch sees a container restarting
+# repeatedly
+#
+# Environment:
+#   AZUD_SERVICE      Service name
+#   AZUD_IMAGE        Full image reference
+#   AZUD_HOSTS        Host running the container
+#   AZUD_DESTINATION  Deployment destination
+#   AZUD_PERFORMER    User running the command
+#   AZUD_ROLE         Role of the container
+#   AZUD_CONTAINER    Container name
+#   AZUD_RESTARTS     Restarts within deploy.restart_loop.window
+#   AZUD_HOOK         This hook's name
+#   AZUD_RECORDED_AT  Timestamp (RFC3339)
+
+echo "Running restart-loop hook..."
+`
+}
+
 func appendToGitignore(log *output.Logger) error {
 	content, err := os.ReadFile(".gitignore")
 	if err != nil && !os.IsNotExist(err) {
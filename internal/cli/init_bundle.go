@@ -0,0 +1,191 @@
+package cli
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+)
+
+// initBundleSpec is a built-in application template for init --bundle. It sets
+// the port, healthcheck, environment, and accessories a typical application
+// of that kind needs, and provides a starting Dockerfile.
+type initBundleSpec struct {
+	Description string
+	AppPort     int
+	HealthPath  string
+	Clear       map[string]string
+	Secrets     []string
+	Accessories []string
+	Dockerfile  string
+}
+
+// initBundles lists the bundles available to init --bundle. Environment
+// values may use the {{service}} and {{database}} placeholders.
+var initBundles = map[string]initBundleSpec{
+	"rails": {
+		Description: "Ruby on Rails with Puma, PostgreSQL, and Redis",
+		AppPort:     3000,
+		HealthPath:  "/up",
+		Clear: map[string]string{
+			"RAILS_ENV":                "production",
+			"RAILS_LOG_TO_STDOUT":      "1",
+			"RAILS_SERVE_STATIC_FILES": "1",
+			"REDIS_URL":                "redis://{{service}}-redis:6379/0",
+		},
+		Secrets:     []string{"RAILS_MASTER_KEY", "DATABASE_URL"},
+		Accessories: []string{"postgres", "redis"},
+		Dockerfile: `# syntax=docker/dockerfile:1
+ARG RUBY_VERSION=3.3
+FROM docker.io/library/ruby:${RUBY_VERSION}-slim AS build
+RUN apt-get update -qq && \
+    apt-get install --no-install-recommends -y build-essential git libpq-dev libyaml-dev pkg-config && \
+    rm -rf /var/lib/apt/lists/*
+WORKDIR /rails
+ENV RAILS_ENV=production BUNDLE_DEPLOYMENT=1 BUNDLE_WITHOUT=development:test
+COPY Gemfile Gemfile.lock ./
+RUN bundle install && rm -rf ~/.bundle "${BUNDLE_PATH}"/ruby/*/cache
+COPY . .
+RUN SECRET_KEY_BASE_DUMMY=1 ./bin/rails assets:precompile
+
+FROM docker.io/library/ruby:${RUBY_VERSION}-slim
+RUN apt-get update -qq && \
+    apt-get install --no-install-recommends -y curl libpq5 libyaml-0-2 && \
+    rm -rf /var/lib/apt/lists/*
+WORKDIR /rails
+ENV RAILS_ENV=production BUNDLE_DEPLOYMENT=1 BUNDLE_WITHOUT=development:test
+COPY --from=build /usr/local/bundle /usr/local/bundle
+COPY --from=build /rails /rails
+RUN useradd rails --create-home --shell /bin/bash && chown -R rails:rails db log storage tmp
+USER rails
+EXPOSE 3000
+CMD ["./bin/rails", "server", "-b", "0.0.0.0", "-p", "3000"]
+`,
+	},
+	"django": {
+		Description: "Django with Gunicorn, PostgreSQL, and Redis",
+		AppPort:     8000,
+		HealthPath:  "/health/",
+		Clear: map[string]string{
+			"DJANGO_SETTINGS_MODULE": "config.settings",
+			"DJANGO_ALLOWED_HOSTS":   "*",
+			"REDIS_URL":              "redis://{{service}}-redis:6379/0",
+		},
+		Secrets:     []string{"DJANGO_SECRET_KEY", "DATABASE_URL"},
+		Accessories: []string{"postgres", "redis"},
+		Dockerfile: `# syntax=docker/dockerfile:1
+FROM docker.io/library/python:3.12-slim
+ENV PYTHONDONTWRITEBYTECODE=1 PYTHONUNBUFFERED=1
+RUN apt-get update -qq && \
+    apt-get install --no-install-recommends -y curl && \
+    rm -rf /var/lib/apt/lists/*
+WORKDIR /app
+COPY requirements.txt .
+RUN pip install --no-cache-dir -r requirements.txt gunicorn
+COPY . .
+RUN python manage.py collectstatic --noinput
+RUN useradd app --create-home && chown -R app:app /app
+USER app
+EXPOSE 8000
+# Serve a 200 response at /health/ for the azud healthcheck.
+CMD ["gunicorn", "config.wsgi:application", "--bind", "0.0.0.0:8000", "--workers", "3"]
+`,
+	},
+	"node": {
+		Description: "Node.js with PostgreSQL and Redis",
+		AppPort:     3000,
+		HealthPath:  "/health",
+		Clear: map[string]string{
+			"NODE_ENV":  "production",
+			"PORT":      "3000",
+			"REDIS_URL": "redis://{{service}}-redis:6379",
+		},
+		Secrets:     []string{"DATABASE_URL"},
+		Accessories: []string{"postgres", "redis"},
+		Dockerfile: `# syntax=docker/dockerfile:1
+FROM docker.io/library/node:22-slim AS build
+WORKDIR /app
+COPY package*.json ./
+RUN npm ci
+COPY . .
+RUN npm run build --if-present && npm prune --omit=dev
+
+FROM docker.io/library/node:22-slim
+RUN apt-get update -qq && \
+    apt-get install --no-install-recommends -y curl && \
+    rm -rf /var/lib/apt/lists/*
+WORKDIR /app
+ENV NODE_ENV=production
+COPY --from=build --chown=node:node /app /app
+USER node
+EXPOSE 3000
+# Serve a 200 response at /health for the azud healthcheck.
+CMD ["npm", "start"]
+`,
+	},
+	"laravel": {
+		Description: "Laravel with PHP-FPM and nginx, PostgreSQL, and Redis",
+		AppPort:     8080,
+		HealthPath:  "/up",
+		Clear: map[string]string{
+			"APP_ENV":       "production",
+			"APP_DEBUG":     "false",
+			"LOG_CHANNEL":   "stderr",
+			"DB_CONNECTION": "pgsql",
+			"DB_HOST":       "{{service}}-postgres",
+			"DB_DATABASE":   "{{database}}_production",
+			"DB_USERNAME":   "postgres",
+			"REDIS_HOST":    "{{service}}-redis",
+		},
+		Secrets:     []string{"APP_KEY", "DB_PASSWORD"},
+		Accessories: []string{"postgres", "redis"},
+		Dockerfile: `# syntax=docker/dockerfile:1
+FROM docker.io/composer:2 AS vendor
+WORKDIR /app
+COPY composer.json composer.lock ./
+RUN composer install --no-dev --no-scripts --no-autoloader --prefer-dist
+COPY . .
+RUN composer dump-autoload --optimize --no-dev
+
+FROM docker.io/serversideup/php:8.3-fpm-nginx
+ENV PHP_OPCACHE_ENABLE=1 AUTORUN_ENABLED=true
+COPY --from=vendor --chown=www-data:www-data /app /var/www/html
+EXPOSE 8080
+`,
+	},
+	"static": {
+		Description: "Static site served by nginx",
+		AppPort:     80,
+		HealthPath:  "/",
+		Dockerfile: `# syntax=docker/dockerfile:1
+FROM docker.io/library/nginx:1.27-alpine
+# Copy the built site (for example the output of "npm run build").
+COPY public/ /usr/share/nginx/html/
+EXPOSE 80
+`,
+	},
+}
+
+// initBundleNames returns the bundle names in sorted order.
+func initBundleNames() []string {
+	return slices.Sorted(maps.Keys(initBundles))
+}
+
+// lookupInitBundle returns the named bundle or an error listing the
+// available bundles.
+func lookupInitBundle(name string) (initBundleSpec, error) {
+	bundle, ok := initBundles[strings.ToLower(name)]
+	if !ok {
+		return initBundleSpec{}, fmt.Errorf("unknown bundle %q (available: %s)", name, strings.Join(initBundleNames(), ", "))
+	}
+	return bundle, nil
+}
+
+// apply sets the bundle's application defaults on a.
+func (b initBundleSpec) apply(a *initAnswers) {
+	a.AppPort = b.AppPort
+	a.HealthPath = b.HealthPath
+	a.Clear = maps.Clone(b.Clear)
+	a.Secrets = slices.Clone(b.Secrets)
+	a.Accessories = slices.Clone(b.Accessories)
+}
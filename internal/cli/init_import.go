@@ -0,0 +1,729 @@
+package cli
+
+import (
+	"bytes"
+	"fmt"
+	"maps"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Formats accepted by init --from.
+const (
+	importFormatKamal   = "kamal"
+	importFormatCompose = "compose"
+)
+
+// importPlaceholderHost is used when the imported file names no servers.
+const importPlaceholderHost = "192.168.1.1"
+
+// importedConfig is the subset of the azud schema an import fills in. Empty
+// sections are omitted from the generated deploy.yml.
+type importedConfig struct {
+	Service          string                       `yaml:"service"`
+	Image            string                       `yaml:"image"`
+	Registry         importedRegistry             `yaml:"registry,omitempty"`
+	Servers          map[string]importedRole      `yaml:"servers"`
+	Builder          importedBuilder              `yaml:"builder,omitempty"`
+	Proxy            importedProxy                `yaml:"proxy"`
+	Env              importedEnv                  `yaml:"env,omitempty"`
+	Accessories      map[string]importedAccessory `yaml:"accessories,omitempty"`
+	Volumes          []string                     `yaml:"volumes,omitempty"`
+	SSH              importedSSH                  `yaml:"ssh,omitempty"`
+	SecretsProvider  string                       `yaml:"secrets_provider,omitempty"`
+	SecretsEnvPrefix string                       `yaml:"secrets_env_prefix,omitempty"`
+}
+
+type importedRegistry struct {
+	Server   string   `yaml:"server,omitempty"`
+	Username string   `yaml:"username,omitempty"`
+	Password []string `yaml:"password,omitempty"`
+}
+
+type importedRole struct {
+	Hosts   []string          `yaml:"hosts"`
+	Cmd     string            `yaml:"cmd,omitempty"`
+	Labels  map[string]string `yaml:"labels,omitempty"`
+	Options map[string]string `yaml:"options,omitempty"`
+	Env     map[string]string `yaml:"env,omitempty"`
+	Secrets []string          `yaml:"secrets,omitempty"`
+}
+
+type importedBuilder struct {
+	Arch       string            `yaml:"arch,omitempty"`
+	Dockerfile string            `yaml:"dockerfile,omitempty"`
+	Context    string            `yaml:"context,omitempty"`
+	Args       map[string]string `yaml:"args,omitempty"`
+}
+
+type importedProxy struct {
+	Host        string              `yaml:"host"`
+	SSL         bool                `yaml:"ssl,omitempty"`
+	ACMEEmail   string              `yaml:"acme_email,omitempty"`
+	AppPort     int                 `yaml:"app_port,omitempty"`
+	Healthcheck importedHealthcheck `yaml:"healthcheck,omitempty"`
+}
+
+type importedHealthcheck struct {
+	Path string `yaml:"path,omitempty"`
+}
+
+type importedEnv struct {
+	Clear  map[string]string `yaml:"clear,omitempty"`
+	Secret []string          `yaml:"secret,omitempty"`
+}
+
+type importedAccessory struct {
+	Image       string            `yaml:"image"`
+	Host        string            `yaml:"host,omitempty"`
+	Hosts       []string          `yaml:"hosts,omitempty"`
+	Roles       []string          `yaml:"roles,omitempty"`
+	Port        string            `yaml:"port,omitempty"`
+	Cmd         string            `yaml:"cmd,omitempty"`
+	Env         importedEnv       `yaml:"env,omitempty"`
+	Volumes     []string          `yaml:"volumes,omitempty"`
+	Directories []string          `yaml:"directories,omitempty"`
+	Files       []importedFile    `yaml:"files,omitempty"`
+	Options     map[string]string `yaml:"options,omitempty"`
+}
+
+type importedFile struct {
+	Local  string `yaml:"local"`
+	Remote string `yaml:"remote"`
+}
+
+type importedSSH struct {
+	User  string           `yaml:"user,omitempty"`
+	Port  int              `yaml:"port,omitempty"`
+	Proxy importedSSHProxy `yaml:"proxy,omitempty"`
+}
+
+type importedSSHProxy struct {
+	Host string `yaml:"host,omitempty"`
+	User string `yaml:"user,omitempty"`
+}
+
+// stringList decodes a YAML scalar or sequence of scalars.
+type stringList []string
+
+func (l *stringList) UnmarshalYAML(node *yaml.Node) error {
+	if node.Kind == yaml.ScalarNode {
+		*l = stringList{node.Value}
+		return nil
+	}
+	var values []string
+	if err := node.Decode(&values); err != nil {
+		return err
+	}
+	*l = values
+	return nil
+}
+
+// hostList decodes a Kamal host list, whose entries are either a host or a
+// single-key map from host to tags.
+type hostList []string
+
+func (l *hostList) UnmarshalYAML(node *yaml.Node) error {
+	if node.Kind != yaml.SequenceNode {
+		return fmt.Errorf("line %d: hosts must be a list", node.Line)
+	}
+	for _, item := range node.Content {
+		switch {
+		case item.Kind == yaml.ScalarNode:
+			*l = append(*l, item.Value)
+		case item.Kind == yaml.MappingNode && len(item.Content) >= 2:
+			*l = append(*l, item.Content[0].Value)
+		default:
+			return fmt.Errorf("line %d: unsupported host entry", item.Line)
+		}
+	}
+	return nil
+}
+
+// importConfig converts a Kamal or Docker Compose file into an azud
+// configuration. The notes list what could not be translated and needs a
+// manual follow-up.
+func importConfig(format, path string) (*importedConfig, []string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to read %s: %w", path, err)
+	}
+	switch strings.ToLower(format) {
+	case importFormatKamal:
+		return importKamalConfig(data)
+	case importFormatCompose:
+		return importComposeConfig(data, filepath.Dir(path))
+	default:
+		return nil, nil, fmt.Errorf("unknown import format %q (use %s or %s)", format, importFormatKamal, importFormatCompose)
+	}
+}
+
+type kamalConfig struct {
+	Service  string    `yaml:"service"`
+	Image    string    `yaml:"image"`
+	Servers  yaml.Node `yaml:"servers"`
+	Registry struct {
+		Server   string     `yaml:"server"`
+		Username stringList `yaml:"username"`
+		Password stringList `yaml:"password"`
+	} `yaml:"registry"`
+	Env   yaml.Node `yaml:"env"`
+	Proxy struct {
+		Host        string   `yaml:"host"`
+		Hosts       []string `yaml:"hosts"`
+		SSL         any      `yaml:"ssl"`
+		AppPort     int      `yaml:"app_port"`
+		Healthcheck struct {
+			Path string `yaml:"path"`
+		} `yaml:"healthcheck"`
+	} `yaml:"proxy"`
+	Healthcheck struct {
+		Path string `yaml:"path"`
+		Port int    `yaml:"port"`
+	} `yaml:"healthcheck"`
+	Traefik     yaml.Node                 `yaml:"traefik"`
+	Accessories map[string]kamalAccessory `yaml:"accessories"`
+	Volumes     []string                  `yaml:"volumes"`
+	SSH         struct {
+		User  string `yaml:"user"`
+		Port  int    `yaml:"port"`
+		Proxy string `yaml:"proxy"`
+	} `yaml:"ssh"`
+	Builder struct {
+		Arch       stringList        `yaml:"arch"`
+		Dockerfile string            `yaml:"dockerfile"`
+		Context    string            `yaml:"context"`
+		Args       map[string]string `yaml:"args"`
+	} `yaml:"builder"`
+}
+
+type kamalRole struct {
+	Hosts   hostList          `yaml:"hosts"`
+	Cmd     string            `yaml:"cmd"`
+	Labels  map[string]string `yaml:"labels"`
+	Options map[string]string `yaml:"options"`
+	Env     yaml.Node         `yaml:"env"`
+}
+
+type kamalAccessory struct {
+	Image       string            `yaml:"image"`
+	Host        string            `yaml:"host"`
+	Hosts       []string          `yaml:"hosts"`
+	Roles       []string          `yaml:"roles"`
+	Port        string            `yaml:"port"`
+	Cmd         string            `yaml:"cmd"`
+	Env         yaml.Node         `yaml:"env"`
+	Volumes     []string          `yaml:"volumes"`
+	Directories []string          `yaml:"directories"`
+	Files       []string          `yaml:"files"`
+	Options     map[string]string `yaml:"options"`
+}
+
+// importKamalConfig translates a Kamal config/deploy.yml.
+func importKamalConfig(data []byte) (*importedConfig, []string, error) {
+	var k kamalConfig
+	if err := yaml.Unmarshal(data, &k); err != nil {
+		return nil, nil, fmt.Errorf("failed to parse Kamal configuration: %w", err)
+	}
+	if k.Service == "" || k.Image == "" {
+		return nil, nil, fmt.Errorf("kamal configuration must set service and image")
+	}
+
+	var notes []string
+	c := &importedConfig{
+		Service:     k.Service,
+		Image:       k.Image,
+		Servers:     make(map[string]importedRole),
+		Volumes:     k.Volumes,
+		Accessories: make(map[string]importedAccessory),
+	}
+
+	c.Registry = importedRegistry{Server: k.Registry.Server, Password: k.Registry.Password}
+	if len(k.Registry.Username) > 0 {
+		c.Registry.Username = k.Registry.Username[0]
+	}
+
+	switch k.Servers.Kind {
+	case yaml.SequenceNode:
+		var hosts hostList
+		if err := k.Servers.Decode(&hosts); err != nil {
+			return nil, nil, fmt.Errorf("servers: %w", err)
+		}
+		c.Servers["web"] = importedRole{Hosts: hosts}
+	case yaml.MappingNode:
+		for i := 0; i+1 < len(k.Servers.Content); i += 2 {
+			name, value := k.Servers.Content[i].Value, k.Servers.Content[i+1]
+			var role kamalRole
+			if value.Kind == yaml.SequenceNode {
+				if err := value.Decode(&role.Hosts); err != nil {
+					return nil, nil, fmt.Errorf("servers.%s: %w", name, err)
+				}
+			} else if err := value.Decode(&role); err != nil {
+				return nil, nil, fmt.Errorf("servers.%s: %w", name, err)
+			}
+			env, envNotes := decodeKamalEnv(&role.Env, "servers."+name+".env")
+			notes = append(notes, envNotes...)
+			c.Servers[name] = importedRole{
+				Hosts:   role.Hosts,
+				Cmd:     role.Cmd,
+				Labels:  role.Labels,
+				Options: role.Options,
+				Env:     env.Clear,
+				Secrets: env.Secret,
+			}
+		}
+	}
+	if len(c.Servers) == 0 {
+		c.Servers["web"] = importedRole{Hosts: []string{importPlaceholderHost}}
+		notes = append(notes, "No servers found; replace the placeholder host "+importPlaceholderHost)
+	}
+
+	var envNotes []string
+	c.Env, envNotes = decodeKamalEnv(&k.Env, "env")
+	notes = append(notes, envNotes...)
+
+	c.Proxy = importedProxy{
+		Host:        k.Proxy.Host,
+		AppPort:     k.Proxy.AppPort,
+		Healthcheck: importedHealthcheck{Path: k.Proxy.Healthcheck.Path},
+	}
+	if c.Proxy.Host == "" && len(k.Proxy.Hosts) > 0 {
+		c.Proxy.Host = k.Proxy.Hosts[0]
+		if len(k.Proxy.Hosts) > 1 {
+			notes = append(notes, "Only the first proxy host was imported; azud serves one hostname per service")
+		}
+	}
+	switch ssl := k.Proxy.SSL.(type) {
+	case bool:
+		c.Proxy.SSL = ssl
+	case map[string]any:
+		notes = append(notes, "Custom proxy certificates were not imported; set proxy.ssl_certificate and proxy.ssl_private_key")
+	}
+	// Kamal 1 configured the healthcheck at the top level.
+	if c.Proxy.Healthcheck.Path == "" {
+		c.Proxy.Healthcheck.Path = k.Healthcheck.Path
+	}
+	if c.Proxy.AppPort == 0 {
+		c.Proxy.AppPort = k.Healthcheck.Port
+	}
+	if !k.Traefik.IsZero() {
+		notes = append(notes, "Traefik settings were not imported; azud uses Caddy configured under proxy")
+	}
+	notes = append(notes, finishImportedProxy(c)...)
+
+	for name, accessory := range k.Accessories {
+		env, envNotes := decodeKamalEnv(&accessory.Env, "accessories."+name+".env")
+		notes = append(notes, envNotes...)
+		imported := importedAccessory{
+			Image:   accessory.Image,
+			Host:    accessory.Host,
+			Hosts:   accessory.Hosts,
+			Roles:   accessory.Roles,
+			Port:    accessory.Port,
+			Cmd:     accessory.Cmd,
+			Env:     env,
+			Volumes: accessory.Volumes,
+			Options: accessory.Options,
+		}
+		// Kamal directories are "host:container" mounts. azud creates
+		// absolute host paths before mounting them; relative ones, which
+		// Kamal kept under its own directory, become named volumes.
+		for _, directory := range accessory.Directories {
+			hostPath, _, _ := strings.Cut(directory, ":")
+			if strings.HasPrefix(hostPath, "/") {
+				imported.Directories = append(imported.Directories, hostPath)
+			} else {
+				notes = append(notes, fmt.Sprintf("accessories.%s: directory %s is mounted as the named volume %s; migrate existing data", name, directory, hostPath))
+			}
+			imported.Volumes = append(imported.Volumes, directory)
+		}
+		for _, file := range accessory.Files {
+			local, remote, ok := strings.Cut(file, ":")
+			if !ok {
+				notes = append(notes, fmt.Sprintf("accessories.%s: file %q has no container path and was skipped", name, file))
+				continue
+			}
+			imported.Files = append(imported.Files, importedFile{Local: local, Remote: remote})
+		}
+		if imported.Host == "" && len(imported.Hosts) == 0 && len(imported.Roles) == 0 {
+			imported.Host = firstImportedHost(c)
+		}
+		c.Accessories[name] = imported
+	}
+
+	c.SSH = importedSSH{User: k.SSH.User, Port: k.SSH.Port}
+	if k.SSH.Proxy != "" {
+		user, host, ok := strings.Cut(k.SSH.Proxy, "@")
+		if !ok {
+			user, host = "", k.SSH.Proxy
+		}
+		c.SSH.Proxy = importedSSHProxy{Host: host, User: user}
+	}
+
+	c.Builder = importedBuilder{Dockerfile: k.Builder.Dockerfile, Context: k.Builder.Context, Args: k.Builder.Args}
+	if len(k.Builder.Arch) == 1 {
+		c.Builder.Arch = k.Builder.Arch[0]
+	} else if len(k.Builder.Arch) > 1 {
+		notes = append(notes, "Multi-architecture builds were not imported; set builder.multiarch and builder.platforms")
+	}
+
+	notes = append(notes, "Copy secret values from .kamal/secrets into .azud/secrets")
+	return c, notes, nil
+}
+
+// decodeKamalEnv reads a Kamal env section, which is either a plain map of
+// clear values or a map with clear and secret keys.
+func decodeKamalEnv(node *yaml.Node, field string) (importedEnv, []string) {
+	var env importedEnv
+	if node.IsZero() || node.Kind != yaml.MappingNode {
+		return env, nil
+	}
+	var raw map[string]yaml.Node
+	if err := node.Decode(&raw); err != nil {
+		return env, []string{fmt.Sprintf("%s could not be read: %v", field, err)}
+	}
+	_, hasClear := raw["clear"]
+	_, hasSecret := raw["secret"]
+	if !hasClear && !hasSecret {
+		_ = node.Decode(&env.Clear)
+		return env, nil
+	}
+
+	var notes []string
+	if clear, ok := raw["clear"]; ok {
+		_ = clear.Decode(&env.Clear)
+	}
+	if secret, ok := raw["secret"]; ok {
+		var names []string
+		_ = secret.Decode(&names)
+		for _, name := range names {
+			// Kamal aliases secrets as NAME:SECRET_NAME; azud reads each
+			// secret under the name the container sees.
+			if envName, secretName, ok := strings.Cut(name, ":"); ok {
+				notes = append(notes, fmt.Sprintf("%s: set %s in .azud/secrets to the value of %s", field, envName, secretName))
+				name = envName
+			}
+			env.Secret = append(env.Secret, name)
+		}
+	}
+	if _, ok := raw["tags"]; ok {
+		notes = append(notes, field+".tags were not imported")
+	}
+	return env, notes
+}
+
+type composeFile struct {
+	Services map[string]composeService `yaml:"services"`
+}
+
+type composeService struct {
+	Image       string      `yaml:"image"`
+	Build       yaml.Node   `yaml:"build"`
+	Ports       []yaml.Node `yaml:"ports"`
+	Environment yaml.Node   `yaml:"environment"`
+	EnvFile     stringList  `yaml:"env_file"`
+	Volumes     []yaml.Node `yaml:"volumes"`
+	Command     yaml.Node   `yaml:"command"`
+}
+
+// composeSecretKeyRegex matches variable names that usually hold credentials.
+var composeSecretKeyRegex = regexp.MustCompile(`(?i)(PASSWORD|SECRET|TOKEN|_KEY|API_KEY|PRIVATE)`)
+
+// importComposeConfig translates a docker-compose.yml. The service with a
+// build section (or, failing that, the first with published ports) becomes
+// the application; every other service with an image becomes an accessory.
+func importComposeConfig(data []byte, baseDir string) (*importedConfig, []string, error) {
+	var compose composeFile
+	if err := yaml.Unmarshal(data, &compose); err != nil {
+		return nil, nil, fmt.Errorf("failed to parse Compose file: %w", err)
+	}
+	if len(compose.Services) == 0 {
+		return nil, nil, fmt.Errorf("compose file defines no services")
+	}
+
+	names := slices.Sorted(maps.Keys(compose.Services))
+	appName := ""
+	for _, name := range names {
+		if compose.Services[name].Build.Kind != 0 {
+			appName = name
+			break
+		}
+	}
+	if appName == "" {
+		for _, name := range names {
+			if len(compose.Services[name].Ports) > 0 {
+				appName = name
+				break
+			}
+		}
+	}
+	if appName == "" {
+		return nil, nil, fmt.Errorf("cannot tell which Compose service is the application: none has a build section or published ports")
+	}
+
+	var notes []string
+	app := compose.Services[appName]
+	c := &importedConfig{
+		Service:     appName,
+		Image:       app.Image,
+		Servers:     map[string]importedRole{"web": {Hosts: []string{importPlaceholderHost}, Cmd: composeCommand(app.Command)}},
+		Accessories: make(map[string]importedAccessory),
+	}
+	notes = append(notes, "Compose has no server list; replace the placeholder host "+importPlaceholderHost)
+	if c.Image == "" {
+		c.Image = "my-user/" + appName
+		notes = append(notes, "Set image to the registry path the application is pushed to")
+	}
+	c.Registry.Username, _, _ = strings.Cut(c.Image, "/")
+	c.Registry.Password = []string{"AZUD_REGISTRY_PASSWORD"}
+
+	switch app.Build.Kind {
+	case yaml.ScalarNode:
+		c.Builder.Context = app.Build.Value
+	case yaml.MappingNode:
+		var build struct {
+			Context    string    `yaml:"context"`
+			Dockerfile string    `yaml:"dockerfile"`
+			Args       yaml.Node `yaml:"args"`
+		}
+		if err := app.Build.Decode(&build); err != nil {
+			return nil, nil, fmt.Errorf("services.%s.build: %w", appName, err)
+		}
+		c.Builder.Context = build.Context
+		c.Builder.Dockerfile = build.Dockerfile
+		c.Builder.Args = composeMapping(&build.Args)
+	}
+
+	if len(app.Ports) > 0 {
+		c.Proxy.AppPort = composeContainerPort(&app.Ports[0])
+	}
+	if len(app.Ports) > 1 {
+		notes = append(notes, fmt.Sprintf("Only port %d of %s is proxied; other published ports were dropped", c.Proxy.AppPort, appName))
+	}
+	notes = append(notes, finishImportedProxy(c)...)
+
+	var envNotes []string
+	c.Env, envNotes = composeEnv(&app, baseDir, "services."+appName)
+	notes = append(notes, envNotes...)
+	c.Volumes = composeVolumes(app.Volumes)
+
+	for _, name := range names {
+		if name == appName {
+			continue
+		}
+		service := compose.Services[name]
+		if service.Image == "" {
+			notes = append(notes, fmt.Sprintf("Service %s builds its own image and was not imported", name))
+			continue
+		}
+		env, envNotes := composeEnv(&service, baseDir, "services."+name)
+		notes = append(notes, envNotes...)
+		accessory := importedAccessory{
+			Image:   service.Image,
+			Host:    importPlaceholderHost,
+			Cmd:     composeCommand(service.Command),
+			Env:     env,
+			Volumes: composeVolumes(service.Volumes),
+		}
+		if len(service.Ports) > 0 && service.Ports[0].Kind == yaml.ScalarNode {
+			accessory.Port = service.Ports[0].Value
+		}
+		c.Accessories[name] = accessory
+		notes = append(notes, fmt.Sprintf("The application reaches %s as %s-%s on the azud network; update connection settings that use %q",
+			name, c.Service, name, name))
+	}
+
+	return c, notes, nil
+}
+
+// composeEnv splits a Compose service's environment into clear values and
+// secret names. Values that are empty, interpolated, or named like
+// credentials become secrets, as do all keys from env_file.
+func composeEnv(service *composeService, baseDir, field string) (importedEnv, []string) {
+	var env importedEnv
+	var notes []string
+	for key, value := range composeMapping(&service.Environment) {
+		if value == "" || strings.Contains(value, "${") || composeSecretKeyRegex.MatchString(key) {
+			env.Secret = append(env.Secret, key)
+			continue
+		}
+		if env.Clear == nil {
+			env.Clear = make(map[string]string)
+		}
+		env.Clear[key] = value
+	}
+	for _, file := range service.EnvFile {
+		data, err := os.ReadFile(filepath.Join(baseDir, file))
+		if err != nil {
+			notes = append(notes, fmt.Sprintf("%s.env_file %s could not be read; add its keys to env.secret", field, file))
+			continue
+		}
+		for key := range parseSecretsContent(string(data)) {
+			if !slices.Contains(env.Secret, key) {
+				env.Secret = append(env.Secret, key)
+			}
+		}
+	}
+	slices.Sort(env.Secret)
+	return env, notes
+}
+
+// composeMapping reads a Compose map or list of KEY=VALUE entries.
+func composeMapping(node *yaml.Node) map[string]string {
+	values := make(map[string]string)
+	switch node.Kind {
+	case yaml.MappingNode:
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			values[node.Content[i].Value] = node.Content[i+1].Value
+		}
+	case yaml.SequenceNode:
+		for _, item := range node.Content {
+			key, value, _ := strings.Cut(item.Value, "=")
+			values[key] = value
+		}
+	}
+	if len(values) == 0 {
+		return nil
+	}
+	return values
+}
+
+// composeCommand joins a Compose command given as a string or list.
+func composeCommand(node yaml.Node) string {
+	switch node.Kind {
+	case yaml.ScalarNode:
+		return node.Value
+	case yaml.SequenceNode:
+		parts := make([]string, 0, len(node.Content))
+		for _, item := range node.Content {
+			parts = append(parts, item.Value)
+		}
+		return strings.Join(parts, " ")
+	}
+	return ""
+}
+
+// composeContainerPort returns the container side of a Compose port mapping
+// such as "8080:80", "127.0.0.1:8080:80/tcp", or {target: 80}.
+func composeContainerPort(node *yaml.Node) int {
+	if node.Kind == yaml.MappingNode {
+		var long struct {
+			Target int `yaml:"target"`
+		}
+		_ = node.Decode(&long)
+		return long.Target
+	}
+	mapping, _, _ := strings.Cut(node.Value, "/")
+	port, _ := strconv.Atoi(mapping[strings.LastIndex(mapping, ":")+1:])
+	return port
+}
+
+// composeVolumes converts short and long form Compose volumes to
+// source:target[:ro] strings.
+func composeVolumes(nodes []yaml.Node) []string {
+	var volumes []string
+	for _, node := range nodes {
+		if node.Kind == yaml.ScalarNode {
+			volumes = append(volumes, node.Value)
+			continue
+		}
+		var long struct {
+			Source   string `yaml:"source"`
+			Target   string `yaml:"target"`
+			ReadOnly bool   `yaml:"read_only"`
+		}
+		if err := node.Decode(&long); err != nil || long.Source == "" || long.Target == "" {
+			continue
+		}
+		volume := long.Source + ":" + long.Target
+		if long.ReadOnly {
+			volume += ":ro"
+		}
+		volumes = append(volumes, volume)
+	}
+	return volumes
+}
+
+// finishImportedProxy fills in proxy settings azud requires but the source
+// format may not carry.
+func finishImportedProxy(c *importedConfig) []string {
+	var notes []string
+	if c.Proxy.Host == "" {
+		c.Proxy.Host = c.Service + ".example.com"
+		notes = append(notes, "Set proxy.host to the application's hostname (placeholder "+c.Proxy.Host+")")
+	}
+	if c.Proxy.SSL && c.Proxy.ACMEEmail == "" {
+		c.Proxy.ACMEEmail = "ops@example.com"
+		notes = append(notes, "Set proxy.acme_email to a real contact address for Let's Encrypt")
+	}
+	if c.Proxy.AppPort == 0 {
+		c.Proxy.AppPort = 80
+	}
+	if c.Proxy.Healthcheck.Path == "" {
+		c.Proxy.Healthcheck.Path = "/up"
+		notes = append(notes, "Check that the application answers proxy.healthcheck.path (defaulted to /up)")
+	}
+	return notes
+}
+
+// firstImportedHost returns the first host of the first role, in name order.
+func firstImportedHost(c *importedConfig) string {
+	for _, role := range slices.Sorted(maps.Keys(c.Servers)) {
+		if hosts := c.Servers[role].Hosts; len(hosts) > 0 {
+			return hosts[0]
+		}
+	}
+	return importPlaceholderHost
+}
+
+// renderImportedConfig renders an imported configuration as deploy.yml.
+func renderImportedConfig(c *importedConfig, source string, githubActions bool) (string, error) {
+	if githubActions {
+		c.SecretsProvider = "env"
+		c.SecretsEnvPrefix = "AZUD_SECRET_"
+	}
+	var b bytes.Buffer
+	_, _ = fmt.Fprintf(&b, "# Azud Deployment Configuration\n# Imported from %s\n# Documentation: https://github.com/lemonity-org/azud\n\n", source)
+	encoder := yaml.NewEncoder(&b)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(c); err != nil {
+		return "", err
+	}
+	if err := encoder.Close(); err != nil {
+		return "", err
+	}
+	return b.String(), nil
+}
+
+// renderImportedSecrets generates an empty secrets file listing every secret
+// the imported configuration references.
+func renderImportedSecrets(c *importedConfig) string {
+	secrets := slices.Clone(c.Registry.Password)
+	add := func(names []string) {
+		for _, name := range names {
+			if !slices.Contains(secrets, name) {
+				secrets = append(secrets, name)
+			}
+		}
+	}
+	add(c.Env.Secret)
+	for _, role := range slices.Sorted(maps.Keys(c.Servers)) {
+		add(c.Servers[role].Secrets)
+	}
+	for _, name := range slices.Sorted(maps.Keys(c.Accessories)) {
+		add(c.Accessories[name].Env.Secret)
+	}
+
+	var b strings.Builder
+	b.WriteString("# Azud Secrets\n# This file should NOT be committed to version control\n\n")
+	for _, secret := range secrets {
+		_, _ = fmt.Fprintf(&b, "%s=\n", secret)
+	}
+	return b.String()
+}
@@ -0,0 +1,194 @@
+package cli
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/lemonity-org/azud/internal/config"
+)
+
+const kamalFixture = `service: shop
+image: acme/shop
+servers:
+  web:
+    - 10.0.0.1
+    - 10.0.0.2: [primary]
+  job:
+    hosts:
+      - 10.0.0.3
+    cmd: bin/jobs
+    env:
+      secret:
+        - QUEUE_TOKEN
+proxy:
+  ssl: true
+  host: shop.example.com
+  app_port: 4000
+  healthcheck:
+    path: /health
+registry:
+  server: ghcr.io
+  username: acme
+  password:
+    - KAMAL_REGISTRY_PASSWORD
+env:
+  clear:
+    RAILS_ENV: production
+  secret:
+    - RAILS_MASTER_KEY
+    - DB_PASSWORD:POSTGRES_PASSWORD
+accessories:
+  db:
+    image: postgres:16
+    host: 10.0.0.1
+    port: 5432
+    env:
+      secret:
+        - POSTGRES_PASSWORD
+    directories:
+      - /srv/db:/var/lib/postgresql/data
+ssh:
+  user: deploy
+  proxy: jump@bastion.example.com
+builder:
+  arch: amd64
+`
+
+const composeFixture = `services:
+  web:
+    build:
+      context: .
+      args:
+        - NODE_VERSION=22
+    ports:
+      - "8080:3000"
+    command: ["npm", "start"]
+    environment:
+      NODE_ENV: production
+      DATABASE_URL: ${DATABASE_URL}
+      API_KEY: abc
+    env_file: .env.production
+    volumes:
+      - uploads:/app/uploads
+  redis:
+    image: redis:7
+    command: redis-server --appendonly yes
+    volumes:
+      - type: volume
+        source: redis-data
+        target: /data
+  db:
+    image: postgres:16
+    environment:
+      - POSTGRES_DB=shop
+      - POSTGRES_PASSWORD
+`
+
+func TestImportKamalConfig(t *testing.T) {
+	imported, notes, err := importKamalConfig([]byte(kamalFixture))
+	if err != nil {
+		t.Fatalf("importKamalConfig: %v", err)
+	}
+
+	if got := imported.Servers["web"].Hosts; !slices.Equal(got, []string{"10.0.0.1", "10.0.0.2"}) {
+		t.Fatalf("web hosts = %v", got)
+	}
+	if job := imported.Servers["job"]; job.Cmd != "bin/jobs" || !slices.Equal(job.Secrets, []string{"QUEUE_TOKEN"}) {
+		t.Fatalf("job role = %+v", job)
+	}
+	if imported.Proxy.AppPort != 4000 || imported.Proxy.Healthcheck.Path != "/health" || imported.Proxy.ACMEEmail == "" {
+		t.Fatalf("proxy = %+v", imported.Proxy)
+	}
+	if !slices.Equal(imported.Env.Secret, []string{"RAILS_MASTER_KEY", "DB_PASSWORD"}) {
+		t.Fatalf("env secrets = %v", imported.Env.Secret)
+	}
+	db := imported.Accessories["db"]
+	if db.Port != "5432" || !slices.Equal(db.Directories, []string{"/srv/db"}) || !slices.Contains(db.Volumes, "/srv/db:/var/lib/postgresql/data") {
+		t.Fatalf("db accessory = %+v", db)
+	}
+	if imported.SSH.Proxy.Host != "bastion.example.com" || imported.SSH.Proxy.User != "jump" {
+		t.Fatalf("ssh proxy = %+v", imported.SSH.Proxy)
+	}
+	if !slices.ContainsFunc(notes, func(note string) bool { return strings.Contains(note, "acme_email") }) {
+		t.Fatalf("expected a note about the placeholder ACME email, got %v", notes)
+	}
+}
+
+func TestImportComposeConfig(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, ".env.production"), []byte("SESSION_SECRET=x\n"), 0600); err != nil {
+		t.Fatalf("write env file: %v", err)
+	}
+
+	imported, notes, err := importComposeConfig([]byte(composeFixture), dir)
+	if err != nil {
+		t.Fatalf("importComposeConfig: %v", err)
+	}
+
+	if imported.Service != "web" || imported.Proxy.AppPort != 3000 || imported.Servers["web"].Cmd != "npm start" {
+		t.Fatalf("application = service %q port %d cmd %q", imported.Service, imported.Proxy.AppPort, imported.Servers["web"].Cmd)
+	}
+	if imported.Builder.Context != "." || imported.Builder.Args["NODE_VERSION"] != "22" {
+		t.Fatalf("builder = %+v", imported.Builder)
+	}
+	if !slices.Equal(imported.Env.Secret, []string{"API_KEY", "DATABASE_URL", "SESSION_SECRET"}) {
+		t.Fatalf("env secrets = %v", imported.Env.Secret)
+	}
+	if imported.Env.Clear["NODE_ENV"] != "production" {
+		t.Fatalf("env clear = %v", imported.Env.Clear)
+	}
+	if got := imported.Accessories["redis"].Volumes; !slices.Equal(got, []string{"redis-data:/data"}) {
+		t.Fatalf("redis volumes = %v", got)
+	}
+	db := imported.Accessories["db"]
+	if db.Env.Clear["POSTGRES_DB"] != "shop" || !slices.Equal(db.Env.Secret, []string{"POSTGRES_PASSWORD"}) {
+		t.Fatalf("db env = %+v", db.Env)
+	}
+	if !slices.ContainsFunc(notes, func(note string) bool { return strings.Contains(note, "web-db") }) {
+		t.Fatalf("expected a note about accessory hostnames, got %v", notes)
+	}
+}
+
+func TestInitFromImportsValidConfig(t *testing.T) {
+	cwd, err := os.Getwd()
+	if err != nil {
+		t.Fatalf("getwd: %v", err)
+	}
+	t.Cleanup(func() {
+		_ = os.Chdir(cwd)
+	})
+
+	for format, fixture := range map[string]string{"kamal": kamalFixture, "compose": composeFixture} {
+		t.Run(format, func(t *testing.T) {
+			if err := os.Chdir(t.TempDir()); err != nil {
+				t.Fatalf("chdir: %v", err)
+			}
+			if err := os.WriteFile("source.yml", []byte(fixture), 0644); err != nil {
+				t.Fatalf("write fixture: %v", err)
+			}
+
+			resetCLIState()
+			rootCmd.SetArgs([]string{"init", "--from", format, "source.yml"})
+			if err := rootCmd.Execute(); err != nil {
+				t.Fatalf("init --from %s failed: %v", format, err)
+			}
+
+			loaded, err := config.NewLoader("config/deploy.yml", "").Load()
+			if err != nil {
+				t.Fatalf("imported %s configuration must load and validate: %v", format, err)
+			}
+			secrets, err := os.ReadFile(filepath.Join(".azud", "secrets"))
+			if err != nil {
+				t.Fatalf("read secrets: %v", err)
+			}
+			for _, name := range loaded.Env.Secret {
+				if !strings.Contains(string(secrets), name+"=") {
+					t.Fatalf("secrets file missing %s:\n%s", name, secrets)
+				}
+			}
+		})
+	}
+}
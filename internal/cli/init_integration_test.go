@@ -115,12 +115,85 @@ func TestInitCreatesGitHubWorkflowAndGitignoreEntry(t *testing.T) {
 	}
 }
 
+func TestInitBundlesGenerateValidConfig(t *testing.T) {
+	cwd, err := os.Getwd()
+	if err != nil {
+		t.Fatalf("getwd: %v", err)
+	}
+	t.Cleanup(func() {
+		_ = os.Chdir(cwd)
+	})
+
+	for _, name := range initBundleNames() {
+		t.Run(name, func(t *testing.T) {
+			if err := os.Chdir(t.TempDir()); err != nil {
+				t.Fatalf("chdir: %v", err)
+			}
+
+			resetCLIState()
+			rootCmd.SetArgs([]string{"init", "--bundle", name})
+			if err := rootCmd.Execute(); err != nil {
+				t.Fatalf("init --bundle %s failed: %v", name, err)
+			}
+
+			assertFileExists(t, "Dockerfile")
+			loaded, err := config.NewLoader("config/deploy.yml", "").Load()
+			if err != nil {
+				t.Fatalf("generated %s configuration must load and validate: %v", name, err)
+			}
+			bundle := initBundles[name]
+			if loaded.Proxy.AppPort != bundle.AppPort || loaded.Proxy.Healthcheck.Path != bundle.HealthPath {
+				t.Fatalf("port/healthcheck = %d %s, want %d %s",
+					loaded.Proxy.AppPort, loaded.Proxy.Healthcheck.Path, bundle.AppPort, bundle.HealthPath)
+			}
+			if len(loaded.Accessories) != len(bundle.Accessories) {
+				t.Fatalf("accessories = %v, want %v", loaded.GetAccessoryNames(), bundle.Accessories)
+			}
+
+			secrets, err := os.ReadFile(filepath.Join(".azud", "secrets"))
+			if err != nil {
+				t.Fatalf("read secrets: %v", err)
+			}
+			for _, secret := range loaded.Env.Secret {
+				if !strings.Contains(string(secrets), secret+"=") {
+					t.Fatalf("secrets file missing %s:\n%s", secret, secrets)
+				}
+			}
+		})
+	}
+}
+
+func TestInitRejectsUnknownBundle(t *testing.T) {
+	cwd, err := os.Getwd()
+	if err != nil {
+		t.Fatalf("getwd: %v", err)
+	}
+	t.Cleanup(func() {
+		_ = os.Chdir(cwd)
+	})
+	if err := os.Chdir(t.TempDir()); err != nil {
+		t.Fatalf("chdir: %v", err)
+	}
+
+	resetCLIState()
+	rootCmd.SetArgs([]string{"init", "--bundle", "cobol"})
+	if err := rootCmd.Execute(); err == nil || !strings.Contains(err.Error(), "unknown bundle") {
+		t.Fatalf("expected unknown bundle error, got %v", err)
+	}
+	if _, err := os.Stat(filepath.Join("config", "deploy.yml")); !os.IsNotExist(err) {
+		t.Fatalf("no configuration should be written for an unknown bundle")
+	}
+}
+
 func resetCLIState() {
 	initBundle = ""
 	initGitHubActions = false
+	initDefaults = false
+	initFrom = ""
 	configPath = ""
 	destination = ""
 	verbose = false
+	quiet = false
 }
 
 func assertFileExists(t *testing.T, path string) {
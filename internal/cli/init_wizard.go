@@ -0,0 +1,332 @@
+package cli
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"maps"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// initAnswers holds the choices collected by the interactive init wizard.
+type initAnswers struct {
+	Service          string
+	Image            string
+	RegistryServer   string
+	RegistryUsername string
+	Hosts            []string
+	ProxyHost        string
+	AppPort          int
+	HealthPath       string
+	SSL              bool
+	ACMEEmail        string
+	SSHUser          string
+	Accessories      []string
+	Clear            map[string]string
+	Secrets          []string
+}
+
+// defaultInitAnswers returns the placeholder answers used when init does not
+// prompt, matching the values in the commented template.
+func defaultInitAnswers() *initAnswers {
+	return &initAnswers{
+		Service:          "my-app",
+		Image:            "my-user/my-app",
+		RegistryUsername: "my-user",
+		Hosts:            []string{"192.168.1.1"},
+		ProxyHost:        "my-app.example.com",
+		AppPort:          3000,
+		HealthPath:       "/up",
+		SSHUser:          "root",
+	}
+}
+
+// expandInitPlaceholders replaces {{service}} with the service name and
+// {{database}} with a database-safe form of it.
+func expandInitPlaceholders(value, service string) string {
+	value = strings.ReplaceAll(value, "{{service}}", service)
+	return strings.ReplaceAll(value, "{{database}}", strings.ReplaceAll(service, "-", "_"))
+}
+
+// initAccessory is an accessory the wizard can add to deploy.yml.
+type initAccessory struct {
+	Image   string
+	Clear   map[string]string
+	Secrets []string
+	Volume  string
+	Cmd     string
+}
+
+// initAccessoryCatalog lists the accessories offered by the wizard. They are
+// reachable from the application by name on the azud network, so no host
+// port is published.
+var initAccessoryCatalog = map[string]initAccessory{
+	"postgres": {
+		Image:   "docker.io/library/postgres:16",
+		Clear:   map[string]string{"POSTGRES_DB": "{{database}}_production"},
+		Secrets: []string{"POSTGRES_PASSWORD"},
+		Volume:  "postgres-data:/var/lib/postgresql/data",
+	},
+	"mysql": {
+		Image:   "docker.io/library/mysql:8.0",
+		Clear:   map[string]string{"MYSQL_DATABASE": "{{database}}_production"},
+		Secrets: []string{"MYSQL_ROOT_PASSWORD"},
+		Volume:  "mysql-data:/var/lib/mysql",
+	},
+	"redis": {
+		Image:  "docker.io/library/redis:7-alpine",
+		Volume: "redis-data:/data",
+		Cmd:    "redis-server --appendonly yes",
+	},
+}
+
+// initPrompter asks questions on out and reads answers from in.
+type initPrompter struct {
+	in  *bufio.Reader
+	out io.Writer
+}
+
+// ask prompts for a value, returning def when the answer is empty.
+func (p *initPrompter) ask(question, def string) (string, error) {
+	if def != "" {
+		_, _ = fmt.Fprintf(p.out, "  %s [%s]: ", question, def)
+	} else {
+		_, _ = fmt.Fprintf(p.out, "  %s: ", question)
+	}
+	line, err := p.in.ReadString('\n')
+	if err != nil && err != io.EOF {
+		return "", err
+	}
+	if answer := strings.TrimSpace(line); answer != "" {
+		return answer, nil
+	}
+	return def, nil
+}
+
+// askRequired repeats a prompt until a non-empty answer is given.
+func (p *initPrompter) askRequired(question, def string) (string, error) {
+	for attempt := 0; attempt < 3; attempt++ {
+		answer, err := p.ask(question, def)
+		if err != nil || answer != "" {
+			return answer, err
+		}
+		_, _ = fmt.Fprintln(p.out, "  A value is required.")
+	}
+	return "", fmt.Errorf("%s is required", strings.ToLower(question))
+}
+
+func (p *initPrompter) confirm(question string, def bool) (bool, error) {
+	hint := "y/N"
+	if def {
+		hint = "Y/n"
+	}
+	answer, err := p.ask(question+" ("+hint+")", "")
+	if err != nil {
+		return false, err
+	}
+	switch strings.ToLower(answer) {
+	case "":
+		return def, nil
+	case "y", "yes":
+		return true, nil
+	default:
+		return false, nil
+	}
+}
+
+// promptInitAnswers runs the interactive init questions. The application
+// port, accessories, healthcheck path, and environment are taken from
+// defaults, which a template bundle may have filled in.
+func promptInitAnswers(in io.Reader, out io.Writer, defaults *initAnswers) (*initAnswers, error) {
+	p := &initPrompter{in: bufio.NewReader(in), out: out}
+	a := &initAnswers{
+		HealthPath: defaults.HealthPath,
+		Clear:      defaults.Clear,
+		Secrets:    defaults.Secrets,
+	}
+	var err error
+
+	_, _ = fmt.Fprintln(out, "Answer a few questions to generate config/deploy.yml. Press Enter to accept the default.")
+
+	if a.Service, err = p.askRequired("Service name", "my-app"); err != nil {
+		return nil, err
+	}
+	if a.Image, err = p.askRequired("Image (user/name)", "my-user/"+a.Service); err != nil {
+		return nil, err
+	}
+	if a.RegistryServer, err = p.ask("Registry server (empty for Docker Hub)", ""); err != nil {
+		return nil, err
+	}
+	defaultUser, _, _ := strings.Cut(a.Image, "/")
+	if a.RegistryUsername, err = p.askRequired("Registry username", defaultUser); err != nil {
+		return nil, err
+	}
+
+	hosts, err := p.askRequired("Server hosts (comma-separated)", "")
+	if err != nil {
+		return nil, err
+	}
+	for _, host := range strings.Split(hosts, ",") {
+		if host = strings.TrimSpace(host); host != "" && !slices.Contains(a.Hosts, host) {
+			a.Hosts = append(a.Hosts, host)
+		}
+	}
+	if a.SSHUser, err = p.askRequired("SSH user", "root"); err != nil {
+		return nil, err
+	}
+
+	if a.ProxyHost, err = p.askRequired("Application hostname", a.Service+".example.com"); err != nil {
+		return nil, err
+	}
+	port, err := p.askRequired("Application port inside the container", strconv.Itoa(defaults.AppPort))
+	if err != nil {
+		return nil, err
+	}
+	if a.AppPort, err = strconv.Atoi(port); err != nil || a.AppPort < 1 || a.AppPort > 65535 {
+		return nil, fmt.Errorf("invalid application port %q", port)
+	}
+	if a.SSL, err = p.confirm("Enable automatic HTTPS with Let's Encrypt?", true); err != nil {
+		return nil, err
+	}
+	if a.SSL {
+		if a.ACMEEmail, err = p.askRequired("ACME contact email", ""); err != nil {
+			return nil, err
+		}
+	}
+
+	names := make([]string, 0, len(initAccessoryCatalog))
+	for name := range initAccessoryCatalog {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	selected, err := p.ask("Accessories ("+strings.Join(names, ", ")+"; comma-separated, empty for none)", strings.Join(defaults.Accessories, ","))
+	if err != nil {
+		return nil, err
+	}
+	for _, name := range strings.Split(selected, ",") {
+		name = strings.ToLower(strings.TrimSpace(name))
+		if name == "" || slices.Contains(a.Accessories, name) {
+			continue
+		}
+		if _, ok := initAccessoryCatalog[name]; !ok {
+			return nil, fmt.Errorf("unknown accessory %q (choose from %s)", name, strings.Join(names, ", "))
+		}
+		a.Accessories = append(a.Accessories, name)
+	}
+	slices.Sort(a.Accessories)
+
+	return a, nil
+}
+
+// renderInitConfig generates a deploy.yml containing only what the answers
+// call for.
+func renderInitConfig(a *initAnswers, githubActions bool) string {
+	var b strings.Builder
+	q := strconv.Quote
+
+	b.WriteString("# Azud Deployment Configuration\n")
+	b.WriteString("# Documentation: https://github.com/lemonity-org/azud\n\n")
+	_, _ = fmt.Fprintf(&b, "service: %s\n", q(a.Service))
+	_, _ = fmt.Fprintf(&b, "image: %s\n\n", q(a.Image))
+
+	b.WriteString("registry:\n")
+	if a.RegistryServer != "" {
+		_, _ = fmt.Fprintf(&b, "  server: %s\n", q(a.RegistryServer))
+	}
+	_, _ = fmt.Fprintf(&b, "  username: %s\n", q(a.RegistryUsername))
+	b.WriteString("  password:\n    - AZUD_REGISTRY_PASSWORD\n\n")
+
+	b.WriteString("servers:\n  web:\n    hosts:\n")
+	for _, host := range a.Hosts {
+		_, _ = fmt.Fprintf(&b, "      - %s\n", q(host))
+	}
+	b.WriteString("\n")
+
+	b.WriteString("proxy:\n")
+	_, _ = fmt.Fprintf(&b, "  host: %s\n", q(a.ProxyHost))
+	_, _ = fmt.Fprintf(&b, "  ssl: %t\n", a.SSL)
+	if a.SSL {
+		_, _ = fmt.Fprintf(&b, "  acme_email: %s\n", q(a.ACMEEmail))
+	}
+	_, _ = fmt.Fprintf(&b, "  app_port: %d\n", a.AppPort)
+	_, _ = fmt.Fprintf(&b, "  healthcheck:\n    path: %s\n\n", a.HealthPath)
+
+	b.WriteString("env:\n")
+	if len(a.Clear) > 0 {
+		b.WriteString("  clear:\n")
+		for _, key := range slices.Sorted(maps.Keys(a.Clear)) {
+			_, _ = fmt.Fprintf(&b, "    %s: %s\n", key, q(expandInitPlaceholders(a.Clear[key], a.Service)))
+		}
+	} else {
+		b.WriteString("  clear: {}\n")
+	}
+	if len(a.Secrets) > 0 {
+		b.WriteString("  secret:\n")
+		for _, secret := range a.Secrets {
+			_, _ = fmt.Fprintf(&b, "    - %s\n", secret)
+		}
+	} else {
+		b.WriteString("  secret: []\n")
+	}
+	b.WriteString("\n")
+
+	if len(a.Accessories) > 0 {
+		b.WriteString("accessories:\n")
+		for _, name := range a.Accessories {
+			accessory := initAccessoryCatalog[name]
+			_, _ = fmt.Fprintf(&b, "  %s:\n", name)
+			_, _ = fmt.Fprintf(&b, "    image: %s\n", accessory.Image)
+			_, _ = fmt.Fprintf(&b, "    host: %s\n", q(a.Hosts[0]))
+			if accessory.Cmd != "" {
+				_, _ = fmt.Fprintf(&b, "    cmd: %s\n", q(accessory.Cmd))
+			}
+			if len(accessory.Clear) > 0 || len(accessory.Secrets) > 0 {
+				b.WriteString("    env:\n")
+			}
+			if len(accessory.Clear) > 0 {
+				b.WriteString("      clear:\n")
+				for _, key := range slices.Sorted(maps.Keys(accessory.Clear)) {
+					_, _ = fmt.Fprintf(&b, "        %s: %s\n", key, q(expandInitPlaceholders(accessory.Clear[key], a.Service)))
+				}
+			}
+			if len(accessory.Secrets) > 0 {
+				b.WriteString("      secret:\n")
+				for _, secret := range accessory.Secrets {
+					_, _ = fmt.Fprintf(&b, "        - %s\n", secret)
+				}
+			}
+			_, _ = fmt.Fprintf(&b, "    volumes:\n      - %s\n", accessory.Volume)
+		}
+		b.WriteString("\n")
+	}
+
+	_, _ = fmt.Fprintf(&b, "ssh:\n  user: %s\n\n", q(a.SSHUser))
+	if githubActions {
+		b.WriteString("secrets_provider: env\nsecrets_env_prefix: AZUD_SECRET_\n\n")
+	}
+
+	b.WriteString("deploy:\n  readiness_delay: 7s\n  deploy_timeout: 30s\n  drain_timeout: 30s\n  retain_containers: 5\n")
+	return b.String()
+}
+
+// renderInitSecrets generates an empty secrets file listing every secret the
+// generated configuration references.
+func renderInitSecrets(a *initAnswers) string {
+	var b strings.Builder
+	b.WriteString("# Azud Secrets\n# This file should NOT be committed to version control\n\n")
+	b.WriteString("AZUD_REGISTRY_PASSWORD=\n")
+	secrets := slices.Clone(a.Secrets)
+	for _, name := range a.Accessories {
+		for _, secret := range initAccessoryCatalog[name].Secrets {
+			if !slices.Contains(secrets, secret) {
+				secrets = append(secrets, secret)
+			}
+		}
+	}
+	for _, secret := range secrets {
+		_, _ = fmt.Fprintf(&b, "%s=\n", secret)
+	}
+	return b.String()
+}
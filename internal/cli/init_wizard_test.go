@@ -0,0 +1,69 @@
+package cli
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/lemonity-org/azud/internal/config"
+)
+
+func TestInitWizardGeneratesValidConfig(t *testing.T) {
+	input := strings.Join([]string{
+		"shop",               // service
+		"",                   // image (default)
+		"ghcr.io",            // registry server
+		"acme",               // registry username
+		"10.0.0.1, 10.0.0.2", // hosts
+		"",                   // ssh user (default)
+		"shop.example.com",   // proxy host
+		"8080",               // app port
+		"",                   // ssl (default yes)
+		"ops@example.com",    // acme email
+		"redis, postgres",    // accessories
+	}, "\n") + "\n"
+
+	answers, err := promptInitAnswers(strings.NewReader(input), io.Discard, defaultInitAnswers())
+	if err != nil {
+		t.Fatalf("promptInitAnswers: %v", err)
+	}
+	if answers.Image != "my-user/shop" || answers.SSHUser != "root" || !answers.SSL {
+		t.Fatalf("defaults not applied: %+v", answers)
+	}
+	if !slices.Equal(answers.Hosts, []string{"10.0.0.1", "10.0.0.2"}) {
+		t.Fatalf("hosts = %v", answers.Hosts)
+	}
+	if !slices.Equal(answers.Accessories, []string{"postgres", "redis"}) {
+		t.Fatalf("accessories = %v", answers.Accessories)
+	}
+
+	path := filepath.Join(t.TempDir(), "deploy.yml")
+	if err := os.WriteFile(path, []byte(renderInitConfig(answers, true)), 0644); err != nil {
+		t.Fatalf("write config: %v", err)
+	}
+	cfg, err := config.NewLoader(path, "").Load()
+	if err != nil {
+		t.Fatalf("generated configuration must load and validate: %v", err)
+	}
+	if cfg.Proxy.AppPort != 8080 || cfg.Registry.Server != "ghcr.io" || cfg.SecretsProvider != "env" {
+		t.Fatalf("unexpected config: port=%d registry=%q provider=%q", cfg.Proxy.AppPort, cfg.Registry.Server, cfg.SecretsProvider)
+	}
+	if got := cfg.Accessories["postgres"].Env.Clear["POSTGRES_DB"]; got != "shop_production" {
+		t.Fatalf("POSTGRES_DB = %q", got)
+	}
+
+	secrets := renderInitSecrets(answers)
+	if !strings.Contains(secrets, "POSTGRES_PASSWORD=") || !strings.Contains(secrets, "AZUD_REGISTRY_PASSWORD=") {
+		t.Fatalf("secrets template missing keys:\n%s", secrets)
+	}
+}
+
+func TestInitWizardRejectsUnknownAccessory(t *testing.T) {
+	input := "app\n\n\n\nhost1\n\n\n\nn\nmongodb\n"
+	if _, err := promptInitAnswers(strings.NewReader(input), io.Discard, defaultInitAnswers()); err == nil {
+		t.Fatal("expected an error for an unknown accessory")
+	}
+}
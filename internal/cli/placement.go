@@ -0,0 +1,347 @@
+package cli
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/lemonity-org/azud/internal/output"
+	"github.com/lemonity-org/azud/internal/podman"
+	"github.com/lemonity-org/azud/internal/ssh"
+)
+
+var placementCmd = &cobra.Command{
+	Use:   "placement",
+	Short: "Suggest which hosts should receive additional replicas",
+	Long: `Gather the CPU count, load average, and available memory of every host
+of a role and suggest where to start additional replicas.
+
+The cost of one replica is the role's memory option when set, otherwise the
+average memory of its running replicas, plus their average CPU use. Each
+replica goes to the host with the most headroom left, and a host only takes
+a replica whose memory fits in what it has available.
+
+With --apply the suggested replicas are started as with 'azud app scale'.
+
+Examples:
+  azud placement
+  azud placement --role worker --add 3
+  azud placement --add 2 --apply`,
+	Args: cobra.NoArgs,
+	RunE: runPlacement,
+}
+
+var (
+	placementRole  string
+	placementAdd   int
+	placementApply bool
+)
+
+// hostCapacity is the free CPU and memory of a host and the replicas of
+// the role it runs.
+type hostCapacity struct {
+	Host     string
+	CPUs     int
+	Load     float64
+	MemTotal int64
+	MemFree  int64
+	Replicas int
+}
+
+// replicaCost is the estimated CPU, in cores, and memory of one replica.
+type replicaCost struct {
+	CPU    float64
+	Memory int64
+}
+
+// hostCapacityCommand prints the CPU count, load averages, and memory
+// totals parsed by parseHostCapacity.
+const hostCapacityCommand = "nproc && cat /proc/loadavg && grep -E '^(MemTotal|MemAvailable):' /proc/meminfo"
+
+func init() {
+	placementCmd.Flags().StringVar(&placementRole, "role", "web", "Role to place replicas of")
+	placementCmd.Flags().IntVar(&placementAdd, "add", 1, "Number of additional replicas to place")
+	placementCmd.Flags().BoolVar(&placementApply, "apply", false, "Start the suggested replicas")
+
+	rootCmd.AddCommand(placementCmd)
+}
+
+func runPlacement(cmd *cobra.Command, args []string) error {
+	output.SetVerbose(verbose)
+	log := output.DefaultLogger
+
+	if placementAdd < 1 {
+		return fmt.Errorf("--add must be at least 1")
+	}
+	if !cfg.HasRole(placementRole) {
+		return fmt.Errorf("role %s not found", placementRole)
+	}
+	if placementApply && cfg.UsesProxyTier() {
+		return fmt.Errorf("scaling is not supported with proxy.hosts_role")
+	}
+
+	sshClient := createSSHClient()
+	containerManager := podman.NewContainerManager(podman.NewClient(sshClient))
+
+	var hosts []hostCapacity
+	var samples []podman.ContainerStats
+	var gatherErrors []string
+	for _, host := range cfg.GetRoleHosts(placementRole) {
+		capacity, err := gatherHostCapacity(sshClient, host)
+		if err != nil {
+			log.HostError(host, "Failed to read capacity: %v", err)
+			gatherErrors = append(gatherErrors, fmt.Sprintf("%s: %v", host, err))
+			continue
+		}
+		instances, err := listRoleInstances(containerManager, host, placementRole)
+		if err != nil {
+			log.HostError(host, "Failed to list replicas: %v", err)
+			gatherErrors = append(gatherErrors, fmt.Sprintf("%s: %v", host, err))
+			continue
+		}
+		capacity.Replicas = len(instances)
+		hosts = append(hosts, capacity)
+
+		names := make([]string, 0, len(instances))
+		for _, instance := range instances {
+			names = append(names, instance.Name)
+		}
+		stats, err := containerManager.StatsSample(host, names)
+		if err != nil {
+			log.Warn("Failed to sample replicas on %s: %v", host, err)
+			continue
+		}
+		samples = append(samples, stats...)
+	}
+	_ = sshClient.Close()
+	if len(gatherErrors) > 0 {
+		return fmt.Errorf("failed to gather host capacity: %s", strings.Join(gatherErrors, "; "))
+	}
+
+	cost := estimateReplicaCost(cfg.Servers[placementRole].Options["memory"], samples)
+	plan, placed := planPlacement(hosts, cost, placementAdd)
+
+	rows := make([][]string, 0, len(hosts))
+	for _, host := range hosts {
+		suggested := "-"
+		if plan[host.Host] > 0 {
+			suggested = fmt.Sprintf("+%d", plan[host.Host])
+		}
+		rows = append(rows, []string{
+			host.Host,
+			strconv.Itoa(host.CPUs),
+			strconv.FormatFloat(host.Load, 'f', 2, 64),
+			formatMemory(host.MemFree) + " / " + formatMemory(host.MemTotal),
+			strconv.Itoa(host.Replicas),
+			suggested,
+		})
+	}
+	log.Info("Estimated cost per %s replica: %.2f CPU, %s memory", placementRole, cost.CPU, formatMemory(cost.Memory))
+	log.Table([]string{"Host", "CPUs", "Load", "Free memory", "Replicas", "Suggested"}, rows)
+
+	if placed < placementAdd {
+		log.Warn("Only %d of %d replicas fit in the available memory", placed, placementAdd)
+	}
+	if !placementApply {
+		if placed > 0 {
+			log.Info("Run with --apply to start the suggested replicas")
+		}
+		return nil
+	}
+
+	var applyErrors []string
+	for _, host := range hosts {
+		if plan[host.Host] == 0 {
+			continue
+		}
+		target := host.Replicas + plan[host.Host]
+		if err := applyScale(map[string]scaleOperation{placementRole: {value: target}}, host.Host); err != nil {
+			applyErrors = append(applyErrors, err.Error())
+		}
+	}
+	if len(applyErrors) > 0 {
+		return fmt.Errorf("placement failed: %s", strings.Join(applyErrors, "; "))
+	}
+	return nil
+}
+
+func gatherHostCapacity(sshClient *ssh.Client, host string) (hostCapacity, error) {
+	result, err := sshClient.Execute(host, hostCapacityCommand)
+	if err != nil {
+		return hostCapacity{}, err
+	}
+	if result.ExitCode != 0 {
+		return hostCapacity{}, fmt.Errorf("%s", strings.TrimSpace(result.Stderr))
+	}
+	capacity, err := parseHostCapacity(result.Stdout)
+	if err != nil {
+		return hostCapacity{}, err
+	}
+	capacity.Host = host
+	return capacity, nil
+}
+
+// parseHostCapacity parses the output of hostCapacityCommand.
+func parseHostCapacity(out string) (hostCapacity, error) {
+	var capacity hostCapacity
+	lines := strings.Split(strings.TrimSpace(out), "\n")
+	if len(lines) < 4 {
+		return capacity, fmt.Errorf("unexpected capacity output %q", out)
+	}
+
+	cpus, err := strconv.Atoi(strings.TrimSpace(lines[0]))
+	if err != nil || cpus < 1 {
+		return capacity, fmt.Errorf("invalid CPU count %q", lines[0])
+	}
+	capacity.CPUs = cpus
+
+	loads := strings.Fields(lines[1])
+	if len(loads) == 0 {
+		return capacity, fmt.Errorf("invalid load average %q", lines[1])
+	}
+	if capacity.Load, err = strconv.ParseFloat(loads[0], 64); err != nil {
+		return capacity, fmt.Errorf("invalid load average %q", lines[1])
+	}
+
+	for _, line := range lines[2:] {
+		fields := strings.Fields(line)
+		if len(fields) < 2 {
+			continue
+		}
+		kb, err := strconv.ParseInt(fields[1], 10, 64)
+		if err != nil {
+			return capacity, fmt.Errorf("invalid memory line %q", line)
+		}
+		switch fields[0] {
+		case "MemTotal:":
+			capacity.MemTotal = kb * 1024
+		case "MemAvailable:":
+			capacity.MemFree = kb * 1024
+		}
+	}
+	if capacity.MemTotal == 0 {
+		return capacity, fmt.Errorf("memory totals missing from capacity output")
+	}
+	return capacity, nil
+}
+
+// estimateReplicaCost returns the cost of one replica from the role's
+// memory option, if set, and the average use of the sampled replicas.
+func estimateReplicaCost(memoryOption string, samples []podman.ContainerStats) replicaCost {
+	var cost replicaCost
+	var memory int64
+	var measured int
+	for _, sample := range samples {
+		usage, _, _ := strings.Cut(sample.MemUsage, "/")
+		bytes, ok := parseMemory(usage)
+		if !ok {
+			continue
+		}
+		cpu, err := strconv.ParseFloat(strings.TrimSuffix(strings.TrimSpace(sample.CPU), "%"), 64)
+		if err != nil {
+			continue
+		}
+		memory += bytes
+		cost.CPU += cpu / 100
+		measured++
+	}
+	if measured > 0 {
+		cost.Memory = memory / int64(measured)
+		cost.CPU /= float64(measured)
+	}
+	if limit, ok := parseMemory(memoryOption); ok {
+		cost.Memory = limit
+	}
+	return cost
+}
+
+// planPlacement assigns up to count replicas to hosts one at a time, each
+// to the host with the largest share of CPU and memory left after taking
+// it. It returns the replicas added per host and how many were placed.
+func planPlacement(hosts []hostCapacity, cost replicaCost, count int) (map[string]int, int) {
+	plan := make(map[string]int)
+	free := make([]hostCapacity, len(hosts))
+	copy(free, hosts)
+
+	placed := 0
+	for ; placed < count; placed++ {
+		best := -1
+		bestScore := math.Inf(-1)
+		for i, host := range free {
+			if host.MemFree < cost.Memory {
+				continue
+			}
+			score := placementHeadroom(host, cost)
+			if best < 0 || score > bestScore || (score == bestScore && host.Replicas < free[best].Replicas) {
+				best, bestScore = i, score
+			}
+		}
+		if best < 0 {
+			break
+		}
+		free[best].MemFree -= cost.Memory
+		free[best].Load += cost.CPU
+		free[best].Replicas++
+		plan[free[best].Host]++
+	}
+	return plan, placed
+}
+
+// placementHeadroom is the smaller of the CPU and memory shares host has
+// left after taking one more replica.
+func placementHeadroom(host hostCapacity, cost replicaCost) float64 {
+	cpu := 1 - (host.Load+cost.CPU)/float64(host.CPUs)
+	memory := float64(host.MemFree-cost.Memory) / float64(host.MemTotal)
+	return math.Min(cpu, memory)
+}
+
+var memoryUnits = map[string]int64{
+	"": 1, "b": 1,
+	"k": 1 << 10, "kb": 1000, "kib": 1 << 10,
+	"m": 1 << 20, "mb": 1000 * 1000, "mib": 1 << 20,
+	"g": 1 << 30, "gb": 1000 * 1000 * 1000, "gib": 1 << 30,
+	"t": 1 << 40, "tb": 1000 * 1000 * 1000 * 1000, "tib": 1 << 40,
+}
+
+// parseMemory parses a memory size as written in podman options, such as
+// 512m, or printed by podman stats, such as 12.5MB or 1.2GiB.
+func parseMemory(s string) (int64, bool) {
+	s = strings.ToLower(strings.TrimSpace(s))
+	if s == "" {
+		return 0, false
+	}
+	split := strings.IndexFunc(s, func(r rune) bool {
+		return (r < '0' || r > '9') && r != '.'
+	})
+	number, unit := s, ""
+	if split >= 0 {
+		number, unit = s[:split], strings.TrimSpace(s[split:])
+	}
+	multiplier, ok := memoryUnits[unit]
+	if !ok {
+		return 0, false
+	}
+	value, err := strconv.ParseFloat(number, 64)
+	if err != nil || value < 0 {
+		return 0, false
+	}
+	return int64(value * float64(multiplier)), true
+}
+
+// formatMemory formats bytes in binary units, such as 1.5 GiB.
+func formatMemory(bytes int64) string {
+	units := []string{"B", "KiB", "MiB", "GiB", "TiB"}
+	value := float64(bytes)
+	unit := 0
+	for value >= 1024 && unit < len(units)-1 {
+		value /= 1024
+		unit++
+	}
+	if unit == 0 {
+		return fmt.Sprintf("%d B", bytes)
+	}
+	return fmt.Sprintf("%.1f %s", value, units[unit])
+}
@@ -0,0 +1,94 @@
+package cli
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/lemonity-org/azud/internal/podman"
+)
+
+func TestParseHostCapacity(t *testing.T) {
+	out := "4\n1.50 1.20 0.90 2/345 6789\nMemTotal:        8000000 kB\nMemAvailable:    2000000 kB\n"
+	got, err := parseHostCapacity(out)
+	if err != nil {
+		t.Fatalf("parseHostCapacity() error = %v", err)
+	}
+	want := hostCapacity{CPUs: 4, Load: 1.5, MemTotal: 8000000 * 1024, MemFree: 2000000 * 1024}
+	if got != want {
+		t.Errorf("parseHostCapacity() = %+v, want %+v", got, want)
+	}
+
+	for _, bad := range []string{"", "x\n0.1 0.1 0.1\nMemTotal: 1 kB\nMemAvailable: 1 kB", "2\n0.1 0.1 0.1\nSwapTotal: 1 kB\nSwapFree: 1 kB"} {
+		if _, err := parseHostCapacity(bad); err == nil {
+			t.Errorf("parseHostCapacity(%q) expected error", bad)
+		}
+	}
+}
+
+func TestParseMemory(t *testing.T) {
+	tests := []struct {
+		in   string
+		want int64
+		ok   bool
+	}{
+		{"512m", 512 << 20, true},
+		{"1g", 1 << 30, true},
+		{"12.5MB", 12500000, true},
+		{" 1.5GiB ", 3 << 29, true},
+		{"100 kB", 100000, true},
+		{"1024", 1024, true},
+		{"", 0, false},
+		{"lots", 0, false},
+		{"5xb", 0, false},
+	}
+	for _, tt := range tests {
+		got, ok := parseMemory(tt.in)
+		if got != tt.want || ok != tt.ok {
+			t.Errorf("parseMemory(%q) = %d, %v; want %d, %v", tt.in, got, ok, tt.want, tt.ok)
+		}
+	}
+}
+
+func TestEstimateReplicaCost(t *testing.T) {
+	samples := []podman.ContainerStats{
+		{MemUsage: "100MB / 2GB", CPU: "50.00%"},
+		{MemUsage: "300MB / 2GB", CPU: "10.00%"},
+		{MemUsage: "--", CPU: "--"},
+	}
+	got := estimateReplicaCost("", samples)
+	if got.Memory != 200000000 || got.CPU < 0.299 || got.CPU > 0.301 {
+		t.Errorf("estimateReplicaCost() = %+v, want 200MB and 0.3 CPU", got)
+	}
+	if got := estimateReplicaCost("1g", samples); got.Memory != 1<<30 {
+		t.Errorf("estimateReplicaCost() with memory option = %d, want %d", got.Memory, 1<<30)
+	}
+}
+
+func TestPlanPlacement(t *testing.T) {
+	const gib = 1 << 30
+	hosts := []hostCapacity{
+		{Host: "a", CPUs: 4, Load: 3, MemTotal: 8 * gib, MemFree: 6 * gib, Replicas: 2},
+		{Host: "b", CPUs: 4, Load: 0.5, MemTotal: 8 * gib, MemFree: 5 * gib, Replicas: 1},
+		{Host: "c", CPUs: 8, Load: 0, MemTotal: 4 * gib, MemFree: gib / 2, Replicas: 0},
+	}
+	cost := replicaCost{CPU: 0.5, Memory: gib}
+
+	plan, placed := planPlacement(hosts, cost, 3)
+	if placed != 3 {
+		t.Fatalf("placed = %d, want 3", placed)
+	}
+	if want := map[string]int{"b": 3}; !reflect.DeepEqual(plan, want) {
+		t.Errorf("plan = %v, want %v", plan, want)
+	}
+	if hosts[1].Replicas != 1 || hosts[1].MemFree != 5*gib {
+		t.Errorf("planPlacement modified its input: %+v", hosts[1])
+	}
+
+	plan, placed = planPlacement(hosts, cost, 20)
+	if placed != 11 {
+		t.Errorf("placed = %d, want 11 (6 on a, 5 on b, none on c)", placed)
+	}
+	if plan["c"] != 0 {
+		t.Errorf("host without enough memory received %d replicas", plan["c"])
+	}
+}
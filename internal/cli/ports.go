@@ -0,0 +1,129 @@
+package cli
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/lemonity-org/azud/internal/deploy"
+	"github.com/lemonity-org/azud/internal/output"
+	"github.com/lemonity-org/azud/internal/podman"
+	"github.com/lemonity-org/azud/internal/proxy"
+)
+
+var portsCmd = &cobra.Command{
+	Use:   "ports",
+	Short: "List published host ports and the containers that own them",
+	Long: `List the host ports published by Azud-managed containers on each host.
+
+With proxy.rootful and podman.rootless, Caddy reaches web containers on
+loopback host ports. The ports are then also audited against the port
+registry recorded at the last deploy and against the service's proxy route,
+and the command fails if a port changed hands or a route points at a port
+the service no longer publishes.
+
+Example:
+  azud ports
+  azud ports --host 192.168.1.1`,
+	Args: cobra.NoArgs,
+	RunE: runPorts,
+}
+
+var portsHost string
+
+func init() {
+	portsCmd.Flags().StringVar(&portsHost, "host", "", "Specific host to list")
+
+	rootCmd.AddCommand(portsCmd)
+}
+
+func runPorts(cmd *cobra.Command, args []string) error {
+	output.SetVerbose(verbose)
+	log := output.DefaultLogger
+
+	hosts := portsHosts()
+	if portsHost != "" {
+		if !containsString(hosts, portsHost) {
+			return fmt.Errorf("host %s is not configured", portsHost)
+		}
+		hosts = []string{portsHost}
+	}
+
+	sshClient := createSSHClient()
+	defer func() { _ = sshClient.Close() }()
+	containers := podman.NewContainerManager(podman.NewClient(sshClient))
+
+	var rows [][]string
+	for _, host := range hosts {
+		allocations, err := deploy.ListHostPorts(containers, host)
+		if err != nil {
+			log.HostError(host, "%v", err)
+			continue
+		}
+		for _, allocation := range allocations {
+			service := allocation.Service
+			if service == "" {
+				service = "-"
+			}
+			role := allocation.Role
+			if role == "" {
+				role = "-"
+			}
+			address := strconv.Itoa(allocation.Port)
+			if allocation.HostIP != "" {
+				address = allocation.HostIP + ":" + address
+			}
+			rows = append(rows, []string{
+				host,
+				address,
+				fmt.Sprintf("%d/%s", allocation.ContainerPort, allocation.Protocol),
+				allocation.Container,
+				service,
+				role,
+			})
+		}
+	}
+	if len(rows) == 0 {
+		log.Info("No published ports")
+	} else {
+		log.Table([]string{"Host", "Host Port", "Container Port", "Container", "Service", "Role"}, rows)
+	}
+
+	if !cfg.UseHostPortUpstreams() {
+		return nil
+	}
+	proxyManager := proxy.NewManagerWithOptions(sshClient, log, cfg.SSH.User, cfg.Proxy.Rootful, cfg.UseHostPortUpstreams())
+	webHosts := cfg.GetRoleHosts("web")
+	var conflicted []string
+	for _, host := range hosts {
+		if !containsString(webHosts, host) {
+			continue
+		}
+		if status := checkHostPorts(sshClient, host, proxyManager); status != "ok" {
+			conflicted = append(conflicted, fmt.Sprintf("%s=%s", host, status))
+		}
+	}
+	if len(conflicted) > 0 {
+		return fmt.Errorf("host port audit failed: %s", strings.Join(conflicted, ", "))
+	}
+	log.Success("No host port conflicts")
+	return nil
+}
+
+// portsHosts returns every host that runs app, accessory, or cron
+// containers, in configuration order.
+func portsHosts() []string {
+	var hosts []string
+	seen := make(map[string]bool)
+	for _, group := range [][]string{cfg.GetAllHosts(), cfg.GetAccessoryHosts(), cfg.GetAllCronHosts()} {
+		for _, host := range group {
+			if !seen[host] {
+				seen[host] = true
+				hosts = append(hosts, host)
+			}
+		}
+	}
+	return hosts
+}
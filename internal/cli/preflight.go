@@ -6,7 +6,6 @@ import (
 	"net"
 	"os"
 	"os/exec"
-	"path/filepath"
 	"sort"
 	"strings"
 	"sync"
@@ -14,7 +13,9 @@ import (
 	"github.com/spf13/cobra"
 
 	"github.com/lemonity-org/azud/internal/config"
+	"github.com/lemonity-org/azud/internal/deploy"
 	"github.com/lemonity-org/azud/internal/output"
+	"github.com/lemonity-org/azud/internal/podman"
 	"github.com/lemonity-org/azud/internal/proxy"
 	"github.com/lemonity-org/azud/internal/server"
 	"github.com/lemonity-org/azud/internal/ssh"
@@ -30,6 +31,7 @@ Checks:
   - Podman installation and rootless mode (if required)
   - Secrets file presence on hosts (if required)
   - Proxy status (if configured)
+  - Host port conflicts (with proxy.rootful and podman.rootless)
   - DNS resolution for proxy host
 `,
 	RunE: runPreflight,
@@ -92,7 +94,7 @@ func runPreflight(cmd *cobra.Command, args []string) error {
 		return err
 	}
 
-	bootstrapper := server.NewBootstrapper(sshClient, log, cfg.Podman.NetworkBackend)
+	bootstrapper := newBootstrapper(sshClient, log)
 	proxyManager := proxy.NewManagerWithOptions(sshClient, log, cfg.SSH.User, cfg.Proxy.Rootful, cfg.UseHostPortUpstreams())
 
 	rows := make([][]string, len(hosts))
@@ -108,7 +110,7 @@ func runPreflight(cmd *cobra.Command, args []string) error {
 
 	wg.Wait()
 
-	headings := []string{"Host", "SSH", "Trust", "Podman", "Rootless", "Secrets", "Proxy", "Helper", "Curl", "SSHD", "Firewall", "Cron"}
+	headings := []string{"Host", "SSH", "Trust", "Podman", "Rootless", "Secrets", "Proxy", "Ports", "Helper", "Curl", "SSHD", "Firewall", "SELinux", "Cron"}
 	log.Table(headings, rows)
 	var warnings []string
 	for _, row := range rows {
@@ -143,6 +145,13 @@ func validateLocalSecretRefs() error {
 			missing = append(missing, fmt.Sprintf("registry.password:%s", key))
 		}
 	}
+	for _, server := range cfg.RegistryServers() {
+		for _, key := range cfg.Registries[server].Password {
+			if !secretAvailable(key) {
+				missing = append(missing, fmt.Sprintf("registries.%s.password:%s", server, key))
+			}
+		}
+	}
 
 	// SSL cert/key references
 	if cfg.Proxy.SSLCertificate != "" && !secretAvailable(cfg.Proxy.SSLCertificate) {
@@ -315,6 +324,7 @@ func preflightHostRow(sshClient *ssh.Client, host string, bootstrapper *server.B
 	secretsStatus := "n/a"
 	var podmanStatus string
 	proxyStatus := "n/a"
+	portsStatus := "n/a"
 	helperStatus := "n/a"
 	var curlStatus string
 	var sshdStatus string
@@ -384,8 +394,8 @@ func preflightHostRow(sshClient *ssh.Client, host string, bootstrapper *server.B
 	}
 
 	// Secrets file
-	if isAppHost && len(cfg.Env.Secret) > 0 {
-		if err := ensureRemoteSecretsFile(sshClient, []string{host}, cfg.Env.Secret); err != nil {
+	if isAppHost && len(cfg.HostSecretFiles(host)) > 0 {
+		if err := ensureHostSecretFiles(sshClient, host); err != nil {
 			secretsStatus = "missing"
 		} else {
 			secretsStatus = "ok"
@@ -401,6 +411,11 @@ func preflightHostRow(sshClient *ssh.Client, host string, bootstrapper *server.B
 		}
 	}
 
+	// Host ports of loopback upstreams
+	if cfg.UseHostPortUpstreams() && isProxyHost && !isBastion {
+		portsStatus = checkHostPorts(sshClient, host, proxyManager)
+	}
+
 	// Helper image presence when pulls are disabled
 	readinessPath := cfg.Proxy.Healthcheck.GetReadinessPath()
 	helperPull := strings.TrimSpace(cfg.Proxy.Healthcheck.HelperPull)
@@ -429,6 +444,12 @@ func preflightHostRow(sshClient *ssh.Client, host string, bootstrapper *server.B
 	// Firewall status
 	firewallStatus = checkFirewall(bootstrapper, host)
 
+	// SELinux labels of host path volumes
+	selinuxStatus := "n/a"
+	if !isBastion {
+		selinuxStatus = checkSELinux(bootstrapper, host)
+	}
+
 	// Cron runtime deps check
 	cronStatus := "n/a"
 	cronHostSet := make(map[string]bool)
@@ -439,14 +460,41 @@ func preflightHostRow(sshClient *ssh.Client, host string, bootstrapper *server.B
 		cronStatus = checkCronDeps(bootstrapper, host)
 	}
 
-	return []string{host, sshStatus, trustStatus, podmanStatus, rootlessStatus, secretsStatus, proxyStatus, helperStatus, curlStatus, sshdStatus, firewallStatus, cronStatus}
+	return []string{host, sshStatus, trustStatus, podmanStatus, rootlessStatus, secretsStatus, proxyStatus, portsStatus, helperStatus, curlStatus, sshdStatus, firewallStatus, selinuxStatus, cronStatus}
 }
 
-func verifyTrustedHost(host string) bool {
-	knownHosts := cfg.SSH.KnownHostsFile
-	if knownHosts == "" {
-		knownHosts = filepath.Join(os.Getenv("HOME"), ".ssh", "known_hosts")
+// checkHostPorts audits the published host ports on host against its port
+// registry and the service's proxy route, logging each conflict.
+func checkHostPorts(sshClient *ssh.Client, host string, proxyManager *proxy.Manager) string {
+	live, err := deploy.ListHostPorts(podman.NewContainerManager(podman.NewClient(sshClient)), host)
+	if err != nil {
+		return "unknown"
 	}
+	registry, err := deploy.LoadPortRegistry(sshClient, host, cfg.SSH.User)
+	if err != nil {
+		return "unknown"
+	}
+	var upstreams []string
+	if serviceHost := cfg.Proxy.PrimaryHost(); serviceHost != "" {
+		if weights, err := proxyManager.GetUpstreamWeights(host, serviceHost); err == nil {
+			for _, weighted := range weights {
+				upstreams = append(upstreams, weighted.Dial)
+			}
+		}
+	}
+
+	conflicts := deploy.PortConflicts(registry.Ports, live, cfg.Service, upstreams)
+	for _, conflict := range conflicts {
+		output.DefaultLogger.HostError(host, "Port conflict: %s", conflict)
+	}
+	if len(conflicts) > 0 {
+		return "conflict"
+	}
+	return "ok"
+}
+
+func verifyTrustedHost(host string) bool {
+	knownHosts := ssh.KnownHostsPath(cfg.SSH.KnownHostsFile)
 
 	target := host
 	port := cfg.SSH.Port
@@ -543,6 +591,59 @@ func checkFirewall(bootstrapper *server.Bootstrapper, host string) string {
 	}
 }
 
+// checkSELinux warns when host enforces SELinux and a container placed on
+// it mounts a host path without an SELinux label.
+func checkSELinux(bootstrapper *server.Bootstrapper, host string) string {
+	results := bootstrapper.ExecuteOnAll([]string{host}, "if command -v getenforce >/dev/null 2>&1; then getenforce; else echo Disabled; fi")
+	if len(results) == 0 || !results[0].Success() {
+		return "unknown"
+	}
+	switch strings.ToLower(strings.TrimSpace(results[0].Stdout)) {
+	case "enforcing":
+	case "permissive", "disabled":
+		return "n/a"
+	default:
+		return "unknown"
+	}
+
+	unlabeled := unlabeledBindMounts(host)
+	if len(unlabeled) == 0 {
+		return "ok"
+	}
+	output.DefaultLogger.Warn("%s enforces SELinux; add :z or :Z to %s or set podman.selinux_label", host, strings.Join(unlabeled, ", "))
+	return "warn"
+}
+
+// unlabeledBindMounts returns the host path volumes of the containers placed
+// on host that carry no SELinux label once podman.selinux_label is applied.
+// Uploaded accessory files count as they are mounted from host paths too.
+func unlabeledBindMounts(host string) []string {
+	var specs []string
+	if containsString(cfg.GetAllHosts(), host) || containsString(cfg.GetAllCronHosts(), host) {
+		specs = append(specs, cfg.Volumes...)
+	}
+	for _, name := range cfg.GetAccessoryNames() {
+		accessory := cfg.Accessories[name]
+		if !containsString(accessoryHosts(accessory), host) {
+			continue
+		}
+		specs = append(specs, accessory.Volumes...)
+		for _, file := range accessory.Files {
+			specs = append(specs, fmt.Sprintf("%s:%s:ro", file.Remote, file.Remote))
+		}
+	}
+
+	var unlabeled []string
+	for _, spec := range cfg.LabelVolumes(specs) {
+		volume, err := config.ParseVolume(spec)
+		if err != nil || config.IsNamedVolumeSource(volume.Source) || volume.Relabeled() {
+			continue
+		}
+		unlabeled = append(unlabeled, spec)
+	}
+	return unlabeled
+}
+
 func checkCronDeps(bootstrapper *server.Bootstrapper, host string) string {
 	required := map[string]bool{"crond": true, "crontab": true}
 	for _, name := range cfg.GetCronNames() {
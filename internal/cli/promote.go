@@ -0,0 +1,140 @@
+package cli
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+
+	"github.com/lemonity-org/azud/internal/config"
+	"github.com/lemonity-org/azud/internal/deploy"
+	"github.com/lemonity-org/azud/internal/output"
+)
+
+var promoteCmd = &cobra.Command{
+	Use:   "promote --from <destination> --to <destination>",
+	Short: "Deploy the image tested in one destination to another",
+	Long: `Promote the last successful deployment of one destination to another.
+
+The exact image digest recorded by the source destination's last successful
+deployment is deployed to the target destination. Nothing is rebuilt, and the
+pulled image must match the recorded digest, so production runs precisely
+what was tested. The target destination's configuration is used for hosts,
+roles, and secrets.
+
+Promotion is refused while the target destination has a deployment in
+progress or one that failed after updating some of its hosts. History is read
+from this machine's durable state directory, so the source deployment must
+have been made from here.
+
+Example:
+  azud promote --from staging --to production
+  azud promote --from staging --to production --yes`,
+	Args: cobra.NoArgs,
+	RunE: runPromote,
+}
+
+var (
+	promoteFrom string
+	promoteTo   string
+)
+
+func init() {
+	promoteCmd.Flags().StringVar(&promoteFrom, "from", "", "Destination whose last successful deployment is promoted")
+	promoteCmd.Flags().StringVar(&promoteTo, "to", "", "Destination to deploy to")
+	promoteCmd.Flags().BoolVar(&deployYes, "yes", false, "Approve the deployment plan without prompting (deploy.require_approval)")
+	promoteCmd.Flags().BoolVar(&deployOverride, "override", false, deployOverrideUsage)
+	promoteCmd.Flags().StringVar(&deployApprovalToken, "approval-token", os.Getenv("AZUD_APPROVAL_TOKEN"), "Token sent to deploy.approval_webhook (default: $AZUD_APPROVAL_TOKEN)")
+	_ = promoteCmd.MarkFlagRequired("from")
+	_ = promoteCmd.MarkFlagRequired("to")
+	_ = promoteCmd.RegisterFlagCompletionFunc("from", completeDestinations)
+	_ = promoteCmd.RegisterFlagCompletionFunc("to", completeDestinations)
+
+	rootCmd.AddCommand(promoteCmd)
+}
+
+func runPromote(cmd *cobra.Command, args []string) error {
+	output.SetVerbose(verbose)
+	log := output.DefaultLogger
+
+	if promoteFrom == promoteTo {
+		return fmt.Errorf("--from and --to must name different destinations")
+	}
+	if destination != "" && destination != promoteTo {
+		return fmt.Errorf("-d %s conflicts with --to %s", destination, promoteTo)
+	}
+
+	// Load the target destination's configuration; the source only supplies
+	// the deployment record.
+	destination = promoteTo
+	loaded, err := loadConfig()
+	if err != nil {
+		return err
+	}
+	cfg = loaded
+	if err := checkAccess(cmd, cfg); err != nil {
+		return err
+	}
+
+	log.Header("Promote / %s %s -> %s", cfg.Service, promoteFrom, promoteTo)
+
+	history := newHistoryStore(log)
+	source, err := promotionSource(history, cfg, promoteFrom, promoteTo)
+	if err != nil {
+		return err
+	}
+	digest := source.Metadata["image_digest"]
+	if current, err := history.GetLastDeploymentTo(cfg.Service, promoteTo); err == nil && current.Status == deploy.StatusSuccess && current.Metadata["image_digest"] == digest {
+		log.Success("%s already runs %s (deployment %s)", promoteTo, digest, current.ID)
+		return nil
+	}
+	log.Info("Promoting %s (%s) from deployment %s", source.Version, digest, source.ID)
+
+	sshClient := createSSHClient()
+	defer func() { _ = sshClient.Close() }()
+
+	deployer := deploy.NewDeployer(cfg, sshClient, log)
+	return deployer.Deploy(cmd.Context(), &deploy.DeployOptions{
+		Version:       source.Version,
+		Digest:        digest,
+		Destination:   promoteTo,
+		Approve:       deployApprover(cmd),
+		ApprovalToken: deployApprovalToken,
+		Override:      deployOverride,
+		Metadata: map[string]string{
+			"promoted_from":            promoteFrom,
+			"promoted_from_deployment": source.ID,
+		},
+	})
+}
+
+// promotionSource returns the deployment to promote: the source destination's
+// last successful deployment, which must carry a verified digest of the image
+// repository the target deploys. The target must not have a deployment still
+// in progress or one that failed after updating some of its hosts.
+func promotionSource(history *deploy.HistoryStore, target *config.Config, from, to string) (*deploy.DeploymentRecord, error) {
+	source, err := history.GetLastSuccessfulTo(target.Service, from)
+	if err != nil {
+		return nil, err
+	}
+	if source.Metadata["image_digest"] == "" {
+		return nil, fmt.Errorf("deployment %s to %s has no verified image digest; deploy %s again with image pulls enabled before promoting", source.ID, from, from)
+	}
+	if repo := stripImageReference(source.Image); repo != stripImageReference(target.Image) {
+		return nil, fmt.Errorf("deployment %s used image %s, but %s deploys %s", source.ID, repo, to, stripImageReference(target.Image))
+	}
+
+	if latest, err := history.GetLastDeploymentTo(target.Service, from); err == nil && latest.ID != source.ID {
+		output.DefaultLogger.Warn("The latest deployment to %s (%s) is %s; promoting the last successful one", from, latest.ID, latest.Status)
+	}
+
+	if current, err := history.GetLastDeploymentTo(target.Service, to); err == nil {
+		switch {
+		case current.Status == deploy.StatusRunning:
+			return nil, fmt.Errorf("deployment %s to %s is still in progress; wait for it or run 'azud deploy --resume %s -d %s'", current.ID, to, current.ID, to)
+		case current.Resumable() == nil && len(current.PendingTargets()) < len(current.Targets):
+			return nil, fmt.Errorf("deployment %s to %s failed partway; finish it with 'azud deploy --resume %s -d %s' or roll back before promoting", current.ID, to, current.ID, to)
+		}
+	}
+	return source, nil
+}
@@ -0,0 +1,75 @@
+package cli
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/lemonity-org/azud/internal/config"
+	"github.com/lemonity-org/azud/internal/deploy"
+)
+
+func TestPromotionSource(t *testing.T) {
+	history := deploy.NewHistoryStore(t.TempDir(), 20, nil)
+	target := &config.Config{Service: "shop", Image: "ghcr.io/acme/shop:latest"}
+	base := time.Date(2026, 9, 1, 12, 0, 0, 0, time.UTC)
+
+	record := func(id, destination string, offset time.Duration, status deploy.DeploymentStatus, digest string, targets ...deploy.TargetProgress) {
+		t.Helper()
+		r := &deploy.DeploymentRecord{
+			ID: id, Service: "shop", Image: "ghcr.io/acme/shop:" + id, Version: id,
+			Destination: destination, Status: status, StartedAt: base.Add(offset),
+			Metadata: map[string]string{}, Targets: targets,
+		}
+		if digest != "" {
+			r.Metadata["image_digest"] = digest
+		}
+		if err := history.Record(r); err != nil {
+			t.Fatalf("record %s: %v", id, err)
+		}
+	}
+
+	if _, err := promotionSource(history, target, "staging", "production"); err == nil {
+		t.Fatal("expected promotion without staging history to fail")
+	}
+
+	record("s1", "staging", 0, deploy.StatusSuccess, "")
+	if _, err := promotionSource(history, target, "staging", "production"); err == nil || !strings.Contains(err.Error(), "no verified image digest") {
+		t.Fatalf("expected unverified digest to be refused, got %v", err)
+	}
+
+	record("s2", "staging", time.Minute, deploy.StatusSuccess, "sha256:abc")
+	record("s3", "staging", 2*time.Minute, deploy.StatusFailed, "sha256:def")
+	source, err := promotionSource(history, target, "staging", "production")
+	if err != nil {
+		t.Fatalf("promotionSource: %v", err)
+	}
+	if source.ID != "s2" {
+		t.Fatalf("promoted %s, want the last successful staging deployment s2", source.ID)
+	}
+
+	other := &config.Config{Service: "shop", Image: "docker.io/acme/shop:latest"}
+	if _, err := promotionSource(history, other, "staging", "production"); err == nil || !strings.Contains(err.Error(), "deploys docker.io/acme/shop") {
+		t.Fatalf("expected a different image repository to be refused, got %v", err)
+	}
+
+	record("p1", "production", 3*time.Minute, deploy.StatusFailed, "sha256:abc",
+		deploy.TargetProgress{Host: "one", Role: "web", Status: deploy.TargetSuccess},
+		deploy.TargetProgress{Host: "two", Role: "web", Status: deploy.TargetFailed},
+	)
+	if _, err := promotionSource(history, target, "staging", "production"); err == nil || !strings.Contains(err.Error(), "failed partway") {
+		t.Fatalf("expected a partially applied production deployment to block promotion, got %v", err)
+	}
+
+	record("p2", "production", 4*time.Minute, deploy.StatusFailed, "sha256:abc",
+		deploy.TargetProgress{Host: "one", Role: "web", Status: deploy.TargetFailed},
+	)
+	if _, err := promotionSource(history, target, "staging", "production"); err != nil {
+		t.Fatalf("a deployment that changed no hosts should not block promotion: %v", err)
+	}
+
+	record("p3", "production", 5*time.Minute, deploy.StatusRunning, "")
+	if _, err := promotionSource(history, target, "staging", "production"); err == nil || !strings.Contains(err.Error(), "in progress") {
+		t.Fatalf("expected a running production deployment to block promotion, got %v", err)
+	}
+}
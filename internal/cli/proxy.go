@@ -8,6 +8,7 @@ import (
 	"github.com/spf13/cobra"
 
 	"github.com/lemonity-org/azud/internal/config"
+	"github.com/lemonity-org/azud/internal/deploy"
 	"github.com/lemonity-org/azud/internal/output"
 	"github.com/lemonity-org/azud/internal/proxy"
 )
@@ -63,7 +64,13 @@ var proxyLogsCmd = &cobra.Command{
 Example:
   azud proxy logs              # View recent logs
   azud proxy logs -f           # Follow logs
-  azud proxy logs --tail 100   # Last 100 lines`,
+  azud proxy logs --tail 100   # Last 100 lines
+
+Caddy logs JSON entries. The filters keep the entries of one service or
+failure mode on a shared proxy; --tail counts lines before filtering:
+  azud proxy logs --request-host shop.example.com --status 5xx
+  azud proxy logs -f --errors-only
+  azud proxy logs --upstream shop-web-1a2b3c:3000`,
 	RunE: runProxyLogs,
 }
 
@@ -94,6 +101,7 @@ var (
 	proxyFollow      bool
 	proxyTail        string
 	proxyForceRemove bool
+	proxyLogFilter   proxy.LogFilter
 )
 
 func init() {
@@ -110,6 +118,10 @@ func init() {
 	proxyLogsCmd.Flags().StringVar(&proxyHost, "host", "", "Specific host to get logs from")
 	proxyLogsCmd.Flags().BoolVarP(&proxyFollow, "follow", "f", false, "Follow log output")
 	proxyLogsCmd.Flags().StringVar(&proxyTail, "tail", "100", "Number of lines to show")
+	proxyLogsCmd.Flags().StringSliceVar(&proxyLogFilter.Hosts, "request-host", nil, "Only entries for requests to this host (repeatable)")
+	proxyLogsCmd.Flags().StringVar(&proxyLogFilter.Status, "status", "", "Only entries with this status code (502) or class (5xx)")
+	proxyLogsCmd.Flags().StringVar(&proxyLogFilter.Upstream, "upstream", "", "Only entries served by this upstream (host or host:port)")
+	proxyLogsCmd.Flags().BoolVar(&proxyLogFilter.ErrorsOnly, "errors-only", false, "Only error entries and 5xx responses")
 
 	// Status flags
 	proxyStatusCmd.Flags().StringVar(&proxyHost, "host", "", "Specific host to check")
@@ -142,6 +154,18 @@ func buildProxyConfig(log *output.Logger) *proxy.ProxyConfig {
 		LoggingEnabled:        cfg.Proxy.Logging.Enabled,
 		RedactRequestHeaders:  cfg.Proxy.Logging.RedactRequestHeaders,
 		RedactResponseHeaders: cfg.Proxy.Logging.RedactResponseHeaders,
+		ProxyProtocol:         cfg.Proxy.ProxyProtocol,
+		ProxyProtocolAllow:    cfg.Proxy.ProxyProtocolAllow,
+		AppSocketsDir:         deploy.AppSocketsDir(cfg),
+		Image:                 deploy.ProxyImage(cfg),
+		Streams:               deploy.ProxyStreams(cfg),
+		Bind:                  deploy.ProxyBind(cfg),
+		GeoIP:                 deploy.ProxyGeoIP(cfg),
+		BootTimeout:           cfg.Proxy.GetBootTimeout(),
+		Memory:                cfg.Proxy.Resources.Memory,
+		CPUs:                  cfg.Proxy.Resources.CPUs,
+		LogDriver:             cfg.Proxy.LogDriver,
+		LogOpts:               cfg.Proxy.LogOpts(),
 	}
 
 	if hosts := cfg.Proxy.AllHosts(); len(hosts) > 0 {
@@ -186,6 +210,8 @@ func runProxyBoot(cmd *cobra.Command, args []string) error {
 	manager := proxy.NewManagerWithOptions(sshClient, log, cfg.SSH.User, cfg.Proxy.Rootful, cfg.UseHostPortUpstreams())
 	proxyConfig := buildProxyConfig(log)
 
+	seedProxyCertificates(manager, hosts, log)
+
 	var bootErrors []string
 	var succeededHosts []string
 	for _, host := range hosts {
@@ -295,11 +321,14 @@ func runProxyReboot(cmd *cobra.Command, args []string) error {
 func runProxyLogs(cmd *cobra.Command, args []string) error {
 	output.SetVerbose(verbose)
 	log := output.DefaultLogger
+	if err := proxyLogFilter.Validate(); err != nil {
+		return err
+	}
 
 	// For logs, we need a single host
 	host := proxyHost
 	if host == "" {
-		hosts := cfg.GetAllHosts()
+		hosts := cfg.GetProxyHosts()
 		if len(hosts) == 0 {
 			return fmt.Errorf("no hosts configured")
 		}
@@ -314,7 +343,14 @@ func runProxyLogs(cmd *cobra.Command, args []string) error {
 
 	manager := proxy.NewManagerWithOptions(sshClient, log, cfg.SSH.User, cfg.Proxy.Rootful, cfg.UseHostPortUpstreams())
 	if proxyFollow {
-		if err := manager.LogsStream(host, true, proxyTail, os.Stdout, os.Stderr); err != nil {
+		// Caddy writes access logs to stdout and its own logs to stderr;
+		// both are filtered.
+		stdout := proxy.NewLogFilterWriter(os.Stdout, proxyLogFilter)
+		stderr := proxy.NewLogFilterWriter(os.Stderr, proxyLogFilter)
+		err := manager.LogsStream(host, true, proxyTail, stdout, stderr)
+		_ = stdout.Close()
+		_ = stderr.Close()
+		if err != nil {
 			return fmt.Errorf("failed to follow logs: %w", err)
 		}
 		return nil
@@ -325,9 +361,9 @@ func runProxyLogs(cmd *cobra.Command, args []string) error {
 		return fmt.Errorf("failed to get logs: %w", err)
 	}
 
-	fmt.Print(result.Stdout)
-	if result.Stderr != "" {
-		fmt.Fprint(os.Stderr, result.Stderr)
+	fmt.Print(proxyLogFilter.Lines(result.Stdout))
+	if stderr := proxyLogFilter.Lines(result.Stderr); stderr != "" {
+		fmt.Fprint(os.Stderr, stderr)
 	}
 	if result.ExitCode != 0 {
 		return fmt.Errorf("proxy logs exited with status %d", result.ExitCode)
@@ -411,10 +447,10 @@ func runProxyRemove(cmd *cobra.Command, args []string) error {
 // getTargetHosts returns the hosts to operate on
 func getTargetHosts(specificHost string) []string {
 	if specificHost != "" {
-		if containsString(cfg.GetAllHosts(), specificHost) {
+		if containsString(cfg.GetProxyHosts(), specificHost) {
 			return []string{specificHost}
 		}
 		return nil
 	}
-	return cfg.GetAllHosts()
+	return cfg.GetProxyHosts()
 }
@@ -0,0 +1,113 @@
+package cli
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/lemonity-org/azud/internal/deploy"
+	"github.com/lemonity-org/azud/internal/output"
+	"github.com/lemonity-org/azud/internal/proxy"
+)
+
+var proxyBuildCmd = &cobra.Command{
+	Use:   "build",
+	Short: "Build and push the proxy image with Caddy plugins",
+	Long: `Build a Caddy image with the plugins of proxy.image_build.modules using
+xcaddy, and push it to the registry. The proxy runs this image.
+
+The image is tagged with a digest of the Caddy release and the module list,
+so it is only rebuilt when they change. 'azud build' runs this step too.
+The build uses the local build engine, also with a remote builder.
+
+Example:
+  azud proxy build
+  azud proxy build --force   # Rebuild even if the registry has the image`,
+	Args: cobra.NoArgs,
+	RunE: runProxyBuild,
+}
+
+var proxyBuildForce bool
+
+func init() {
+	proxyBuildCmd.Flags().BoolVar(&proxyBuildForce, "force", false, "Rebuild and push even if the registry has the image")
+	proxyCmd.AddCommand(proxyBuildCmd)
+}
+
+func runProxyBuild(cmd *cobra.Command, args []string) error {
+	output.SetVerbose(verbose)
+	if !cfg.Proxy.ImageBuild.Enabled() {
+		return fmt.Errorf("proxy.image_build.modules is not configured")
+	}
+	return buildProxyImage(proxyBuildForce)
+}
+
+// buildProxyImage builds the proxy.image_build image and pushes it, unless
+// the registry already has it and force is not set.
+func buildProxyImage(force bool) error {
+	log := output.DefaultLogger
+	image := deploy.ProxyImage(cfg)
+	engine := buildEngine()
+
+	registry, ok := cfg.RegistryFor(image)
+	if !ok {
+		registry = cfg.Registry
+	}
+	if registry.Username != "" {
+		if err := loginToRegistryWith(registry); err != nil {
+			return fmt.Errorf("registry login failed: %w", err)
+		}
+	}
+
+	if !force && exec.Command(engine, "manifest", "inspect", image).Run() == nil {
+		log.Info("Proxy image %s is up to date", image)
+		return nil
+	}
+
+	workDir, err := os.MkdirTemp("", "azud-caddy-")
+	if err != nil {
+		return fmt.Errorf("failed to create proxy build directory: %w", err)
+	}
+	defer func() { _ = os.RemoveAll(workDir) }()
+	containerfile := filepath.Join(workDir, "Containerfile")
+	if err := os.WriteFile(containerfile, []byte(proxy.CaddyBuildfile(cfg.Proxy.ImageBuild.Modules)), 0600); err != nil {
+		return fmt.Errorf("failed to write proxy Containerfile: %w", err)
+	}
+
+	log.Info("Building proxy image %s with %s...", image, strings.Join(cfg.Proxy.ImageBuild.Modules, ", "))
+	buildArgs := proxyImageBuildArgs(image, containerfile, workDir)
+	log.Command(engine + " " + strings.Join(buildArgs, " "))
+	build := exec.Command(engine, buildArgs...)
+	build.Stdout = os.Stdout
+	build.Stderr = os.Stderr
+	if err := build.Run(); err != nil {
+		return fmt.Errorf("proxy image build failed: %w", err)
+	}
+
+	log.Info("Pushing %s...", image)
+	push := exec.Command(engine, "push", image)
+	push.Stdout = os.Stdout
+	push.Stderr = os.Stderr
+	if err := push.Run(); err != nil {
+		return fmt.Errorf("failed to push %s: %w", image, err)
+	}
+	log.Success("Proxy image pushed: %s", image)
+	return nil
+}
+
+// proxyImageBuildArgs returns the build engine arguments building image from
+// containerfile, for the architecture of the application image.
+func proxyImageBuildArgs(image, containerfile, context string) []string {
+	args := []string{"build", "-f", containerfile, "-t", image}
+	if arch := effectiveBuildArch(false); arch != "" {
+		args = append(args, "--platform", fmt.Sprintf("linux/%s", arch))
+	}
+	if buildPull {
+		args = append(args, "--pull")
+	}
+	return append(args, context)
+}
@@ -0,0 +1,248 @@
+package cli
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/spf13/cobra"
+
+	"github.com/lemonity-org/azud/internal/output"
+	"github.com/lemonity-org/azud/internal/proxy"
+)
+
+var proxyCertsCmd = &cobra.Command{
+	Use:   "certs",
+	Short: "Back up and restore the proxy's certificates",
+	Long: `Commands for the ACME account and certificates the proxy keeps in its
+caddy_data volume.
+
+'azud setup' and 'azud proxy boot' seed a proxy host without that volume, a
+new or rebuilt one, from another proxy host or from the local backup, so it
+does not request every certificate again and run into rate limits.`,
+}
+
+var proxyCertsBackupCmd = &cobra.Command{
+	Use:   "backup",
+	Short: "Save the proxy's certificates to a local tarball",
+	Long: `Save the ACME account and certificates of a running proxy to a tarball
+on this machine, by default .azud/proxy-certificates.tar next to the secrets.
+The tarball holds private keys: keep it out of version control.
+
+Example:
+  azud proxy certs backup
+  azud proxy certs backup --host 192.168.1.1 --output certs.tar`,
+	Args: cobra.NoArgs,
+	RunE: runProxyCertsBackup,
+}
+
+var proxyCertsRestoreCmd = &cobra.Command{
+	Use:   "restore",
+	Short: "Load saved certificates into the proxy hosts",
+	Long: `Load a tarball saved by 'azud proxy certs backup' into the caddy_data
+volume of the proxy hosts.
+
+Example:
+  azud proxy certs restore
+  azud proxy certs restore --host 192.168.1.2 --input certs.tar`,
+	Args: cobra.NoArgs,
+	RunE: runProxyCertsRestore,
+}
+
+var (
+	proxyCertsHost string
+	proxyCertsFile string
+)
+
+// proxyCertificatesBackupFile is the name of the default backup in .azud.
+const proxyCertificatesBackupFile = "proxy-certificates.tar"
+
+func init() {
+	proxyCertsBackupCmd.Flags().StringVar(&proxyCertsHost, "host", "", "Proxy host to back up (default: the first with certificates)")
+	proxyCertsBackupCmd.Flags().StringVarP(&proxyCertsFile, "output", "o", "", "Tarball to write (default: .azud/"+proxyCertificatesBackupFile+")")
+	proxyCertsRestoreCmd.Flags().StringVar(&proxyCertsHost, "host", "", "Proxy host to restore (default: all)")
+	proxyCertsRestoreCmd.Flags().StringVarP(&proxyCertsFile, "input", "i", "", "Tarball to read (default: .azud/"+proxyCertificatesBackupFile+")")
+
+	proxyCertsCmd.AddCommand(proxyCertsBackupCmd)
+	proxyCertsCmd.AddCommand(proxyCertsRestoreCmd)
+	proxyCmd.AddCommand(proxyCertsCmd)
+}
+
+func runProxyCertsBackup(cmd *cobra.Command, args []string) error {
+	output.SetVerbose(verbose)
+	log := output.DefaultLogger
+
+	hosts := getTargetHosts(proxyCertsHost)
+	if len(hosts) == 0 {
+		return fmt.Errorf("no proxy hosts configured")
+	}
+
+	sshClient := createSSHClient()
+	defer func() { _ = sshClient.Close() }()
+
+	manager := proxy.NewManagerWithOptions(sshClient, log, cfg.SSH.User, cfg.Proxy.Rootful, cfg.UseHostPortUpstreams())
+	data, source := exportProxyCertificates(manager, hosts, log)
+	if data == nil {
+		return fmt.Errorf("no running proxy with certificates found")
+	}
+
+	path := proxyCertsFile
+	if path == "" {
+		path = proxyCertificatesBackupPath()
+	}
+	if err := writeProxyCertificatesBackup(path, data); err != nil {
+		return err
+	}
+	log.Success("Saved the certificates of %s to %s", source, path)
+	return nil
+}
+
+func runProxyCertsRestore(cmd *cobra.Command, args []string) error {
+	output.SetVerbose(verbose)
+	log := output.DefaultLogger
+
+	hosts := getTargetHosts(proxyCertsHost)
+	if len(hosts) == 0 {
+		return fmt.Errorf("no proxy hosts configured")
+	}
+
+	path := proxyCertsFile
+	if path == "" {
+		path = proxyCertificatesBackupPath()
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("failed to read certificate backup: %w", err)
+	}
+
+	sshClient := createSSHClient()
+	defer func() { _ = sshClient.Close() }()
+
+	manager := proxy.NewManagerWithOptions(sshClient, log, cfg.SSH.User, cfg.Proxy.Rootful, cfg.UseHostPortUpstreams())
+	failed := 0
+	for _, host := range hosts {
+		if err := manager.ImportCertificates(host, bytes.NewReader(data)); err != nil {
+			log.HostError(host, "%v", err)
+			failed++
+			continue
+		}
+		log.HostSuccess(host, "Certificates restored from %s", path)
+	}
+	if failed > 0 {
+		return fmt.Errorf("certificate restore failed on %d host(s)", failed)
+	}
+	return nil
+}
+
+// certificateStore is the part of proxy.Manager that reads and writes the
+// proxy's certificate storage.
+type certificateStore interface {
+	HasDataVolume(host string) (bool, error)
+	HasCertificates(host string) (bool, error)
+	ExportCertificates(host string, w io.Writer) error
+	ImportCertificates(host string, r io.Reader) error
+}
+
+// seedProxyCertificates gives proxy hosts without a Caddy data volume the
+// certificates of another proxy host, or of the local backup, before the
+// proxy boots on them. Hosts that cannot be seeded request their own
+// certificates, so failures are only reported.
+func seedProxyCertificates(manager certificateStore, hosts []string, log *output.Logger) {
+	if !cfg.Proxy.SSL || cfg.Proxy.SSLCertificate != "" {
+		return
+	}
+
+	var fresh []string
+	for _, host := range hosts {
+		exists, err := manager.HasDataVolume(host)
+		if err != nil {
+			log.Warn("Skipping certificate seeding on %s: %v", host, err)
+			continue
+		}
+		if !exists {
+			fresh = append(fresh, host)
+		}
+	}
+	if len(fresh) == 0 {
+		return
+	}
+
+	var sources []string
+	for _, host := range getTargetHosts("") {
+		if !containsString(fresh, host) {
+			sources = append(sources, host)
+		}
+	}
+	data, source := exportProxyCertificates(manager, sources, log)
+	if data == nil {
+		path := proxyCertificatesBackupPath()
+		backup, err := os.ReadFile(path)
+		if err != nil {
+			log.Info("No certificates to reuse; new proxy hosts will request their own")
+			return
+		}
+		data, source = backup, path
+	}
+
+	for _, host := range fresh {
+		if err := manager.ImportCertificates(host, bytes.NewReader(data)); err != nil {
+			log.Warn("Proxy on %s will request its own certificates: %v", host, err)
+			continue
+		}
+		log.HostSuccess(host, "Reusing the certificates of %s", source)
+	}
+}
+
+// exportProxyCertificates returns the certificate storage of the first of
+// hosts whose proxy has certificates, and that host.
+func exportProxyCertificates(manager certificateStore, hosts []string, log *output.Logger) ([]byte, string) {
+	for _, host := range hosts {
+		ok, err := manager.HasCertificates(host)
+		if err != nil {
+			log.Warn("Cannot read certificates on %s: %v", host, err)
+			continue
+		}
+		if !ok {
+			continue
+		}
+		var buf bytes.Buffer
+		if err := manager.ExportCertificates(host, &buf); err != nil {
+			log.Warn("%v", err)
+			continue
+		}
+		return buf.Bytes(), host
+	}
+	return nil, ""
+}
+
+// proxyCertificatesBackupPath returns the default certificate backup, kept
+// in .azud next to the default secrets file.
+func proxyCertificatesBackupPath() string {
+	return filepath.Join(filepath.Dir(getConfigDir()), ".azud", proxyCertificatesBackupFile)
+}
+
+// writeProxyCertificatesBackup replaces the backup at path atomically,
+// readable only by the owner.
+func writeProxyCertificatesBackup(path string, data []byte) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return fmt.Errorf("failed to create backup directory: %w", err)
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".proxy-certificates-*")
+	if err != nil {
+		return fmt.Errorf("failed to write certificate backup: %w", err)
+	}
+	defer func() { _ = os.Remove(tmp.Name()) }()
+	if _, err := tmp.Write(data); err != nil {
+		_ = tmp.Close()
+		return fmt.Errorf("failed to write certificate backup: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to write certificate backup: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return fmt.Errorf("failed to write certificate backup: %w", err)
+	}
+	return nil
+}
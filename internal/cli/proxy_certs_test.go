@@ -0,0 +1,142 @@
+package cli
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/lemonity-org/azud/internal/config"
+	"github.com/lemonity-org/azud/internal/output"
+)
+
+// fakeCertificateStore keeps each host's data volume and certificates in
+// memory and records the certificates imported into each host.
+type fakeCertificateStore struct {
+	volumes  map[string]bool
+	certs    map[string]string
+	imported map[string]string
+}
+
+func (f *fakeCertificateStore) HasDataVolume(host string) (bool, error) {
+	return f.volumes[host], nil
+}
+
+func (f *fakeCertificateStore) HasCertificates(host string) (bool, error) {
+	return f.certs[host] != "", nil
+}
+
+func (f *fakeCertificateStore) ExportCertificates(host string, w io.Writer) error {
+	_, err := io.WriteString(w, f.certs[host])
+	return err
+}
+
+func (f *fakeCertificateStore) ImportCertificates(host string, r io.Reader) error {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return err
+	}
+	if f.imported == nil {
+		f.imported = make(map[string]string)
+	}
+	f.imported[host] = string(data)
+	return nil
+}
+
+func TestSeedProxyCertificates(t *testing.T) {
+	oldCfg, oldPath := cfg, configPath
+	t.Cleanup(func() { cfg, configPath = oldCfg, oldPath })
+	log := output.NewLogger(io.Discard, io.Discard, false)
+
+	setup := func(t *testing.T, backup string) {
+		t.Helper()
+		dir := t.TempDir()
+		configPath = filepath.Join(dir, "config", "deploy.yml")
+		cfg = &config.Config{
+			Servers: map[string]config.RoleConfig{"web": {Hosts: []string{"web-1", "web-2", "web-3"}}},
+			Proxy:   config.ProxyConfig{Host: "app.example.com", SSL: true},
+		}
+		if backup != "" {
+			if err := writeProxyCertificatesBackup(proxyCertificatesBackupPath(), []byte(backup)); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+
+	t.Run("fresh hosts seeded from another proxy host", func(t *testing.T) {
+		setup(t, "backup")
+		store := &fakeCertificateStore{
+			volumes: map[string]bool{"web-1": true, "web-2": true},
+			certs:   map[string]string{"web-2": "live"},
+		}
+		seedProxyCertificates(store, []string{"web-1", "web-2", "web-3"}, log)
+		if want := map[string]string{"web-3": "live"}; !reflect.DeepEqual(store.imported, want) {
+			t.Fatalf("imported = %v, want %v", store.imported, want)
+		}
+	})
+
+	t.Run("falls back to the local backup", func(t *testing.T) {
+		setup(t, "backup")
+		store := &fakeCertificateStore{volumes: map[string]bool{"web-1": true}}
+		seedProxyCertificates(store, []string{"web-1", "web-2", "web-3"}, log)
+		if want := map[string]string{"web-2": "backup", "web-3": "backup"}; !reflect.DeepEqual(store.imported, want) {
+			t.Fatalf("imported = %v, want %v", store.imported, want)
+		}
+	})
+
+	t.Run("nothing to reuse", func(t *testing.T) {
+		setup(t, "")
+		store := &fakeCertificateStore{}
+		seedProxyCertificates(store, []string{"web-1", "web-2", "web-3"}, log)
+		if len(store.imported) != 0 {
+			t.Fatalf("imported = %v, want none", store.imported)
+		}
+	})
+
+	t.Run("custom certificate", func(t *testing.T) {
+		setup(t, "backup")
+		cfg.Proxy.SSLCertificate = "app"
+		store := &fakeCertificateStore{
+			volumes: map[string]bool{"web-1": true},
+			certs:   map[string]string{"web-1": "live"},
+		}
+		seedProxyCertificates(store, []string{"web-1", "web-2", "web-3"}, log)
+		if len(store.imported) != 0 {
+			t.Fatalf("imported = %v with ssl_certificate set, want none", store.imported)
+		}
+	})
+}
+
+func TestWriteProxyCertificatesBackup(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), ".azud")
+	path := filepath.Join(dir, proxyCertificatesBackupFile)
+
+	for _, data := range []string{"first", "second"} {
+		if err := writeProxyCertificatesBackup(path, []byte(data)); err != nil {
+			t.Fatal(err)
+		}
+		got, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(got) != data {
+			t.Fatalf("backup = %q, want %q", got, data)
+		}
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if perm := info.Mode().Perm(); perm != 0o600 {
+		t.Fatalf("backup mode = %o, want 600", perm)
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 1 {
+		t.Fatalf("backup directory holds %d entries, want only the backup (no temporary files)", len(entries))
+	}
+}
@@ -45,6 +45,15 @@ func init() {
 
 func runProxyReconcile(cmd *cobra.Command, args []string) error {
 	output.SetVerbose(verbose)
+	return reconcileProxyRoutes()
+}
+
+// reconcileProxyRoutes checks or repairs the service route on the proxy
+// hosts selected by --host, as --check and --repair ask.
+func reconcileProxyRoutes() error {
+	if cfg.UsesProxyTier() {
+		return runProxyTierReconcile()
+	}
 	hosts := getProxyRouteHosts(proxyHost)
 	if len(hosts) == 0 {
 		return fmt.Errorf("no matching web hosts configured")
@@ -68,26 +77,62 @@ func runProxyReconcile(cmd *cobra.Command, args []string) error {
 			output.DefaultLogger.HostError(host, "%v", err)
 			continue
 		}
-		if proxyReconcileRepair {
-			if err := manager.Boot(host, proxyConfig); err != nil {
-				failures = append(failures, fmt.Sprintf("%s: boot: %v", host, err))
-				continue
-			}
+		if failure := reconcileProxyRoute(manager, proxyConfig, host, upstreams, weights); failure != "" {
+			failures = append(failures, failure)
+		}
+	}
+	if len(failures) > 0 {
+		return fmt.Errorf("proxy reconciliation failed: %s", strings.Join(failures, "; "))
+	}
+	return nil
+}
+
+// runProxyTierReconcile aggregates the upstreams of every web host into one
+// desired route and reconciles it on each load-balancer host, so all proxies
+// balance across the whole fleet.
+func runProxyTierReconcile() error {
+	lbHosts := getTargetHosts(proxyHost)
+	if len(lbHosts) == 0 {
+		return fmt.Errorf("no matching load-balancer hosts configured")
+	}
+
+	sshClient := createSSHClient()
+	defer func() { _ = sshClient.Close() }()
+	cm := podman.NewContainerManager(podman.NewClient(sshClient))
+	manager := proxy.NewManagerWithOptions(sshClient, output.DefaultLogger, cfg.SSH.User, cfg.Proxy.Rootful, cfg.UseHostPortUpstreams())
+	proxyConfig := buildProxyConfig(output.DefaultLogger)
+	manager.SetProxyConfig(proxyConfig)
+
+	webHosts := getProxyRouteHosts("")
+	var upstreams []string
+	byHost := make(map[string]string, len(webHosts))
+	for _, host := range webHosts {
+		hostUpstreams, _, err := desiredProxyUpstreams(cm, host, nil)
+		if err != nil {
+			output.DefaultLogger.HostError(host, "%v", err)
+			return fmt.Errorf("proxy reconciliation failed: %s: %v", host, err)
 		}
-		desired := deploy.BuildProxyServiceConfig(cfg, upstreams, weights)
-		status, err := manager.ReconcileService(host, desired, proxyReconcileRepair)
+		upstreams = append(upstreams, hostUpstreams...)
+		if len(hostUpstreams) > 0 {
+			byHost[host] = hostUpstreams[0]
+		}
+	}
+
+	var failures []string
+	for _, host := range lbHosts {
+		// Keep the per-host weights set with azud traffic set.
+		traffic, err := deploy.LoadTrafficWeights(sshClient, host, cfg.SSH.User, cfg.Service)
 		if err != nil {
-			failures = append(failures, fmt.Sprintf("%s: %v", host, err))
 			output.DefaultLogger.HostError(host, "%v", err)
+			failures = append(failures, fmt.Sprintf("%s: %v", host, err))
 			continue
 		}
-		if proxyReconcileCheck && status != proxy.ReconcileInSync {
-			output.DefaultLogger.HostError(host, "route drift: %s", status)
-			failures = append(failures, fmt.Sprintf("%s: %s", host, status))
-		} else if proxyReconcileRepair && status != proxy.ReconcileInSync {
-			output.DefaultLogger.HostSuccess(host, "repaired (%s)", status)
-		} else {
-			output.DefaultLogger.HostSuccess(host, "in-sync")
+		var weights []proxy.UpstreamWeight
+		if traffic.Customized() {
+			weights = deploy.WeightedHostUpstreams(webHosts, byHost, traffic)
+		}
+		if failure := reconcileProxyRoute(manager, proxyConfig, host, upstreams, weights); failure != "" {
+			failures = append(failures, failure)
 		}
 	}
 	if len(failures) > 0 {
@@ -96,6 +141,31 @@ func runProxyReconcile(cmd *cobra.Command, args []string) error {
 	return nil
 }
 
+// reconcileProxyRoute checks or repairs the service route on one proxy host
+// and returns a failure description, or "" when the host is healthy.
+func reconcileProxyRoute(manager *proxy.Manager, proxyConfig *proxy.ProxyConfig, host string, upstreams []string, weights []proxy.UpstreamWeight) string {
+	if proxyReconcileRepair {
+		if err := manager.Boot(host, proxyConfig); err != nil {
+			return fmt.Sprintf("%s: boot: %v", host, err)
+		}
+	}
+	desired := deploy.BuildProxyServiceConfig(cfg, upstreams, weights)
+	status, err := manager.ReconcileService(host, desired, proxyReconcileRepair)
+	if err != nil {
+		output.DefaultLogger.HostError(host, "%v", err)
+		return fmt.Sprintf("%s: %v", host, err)
+	}
+	if proxyReconcileCheck && status != proxy.ReconcileInSync {
+		output.DefaultLogger.HostError(host, "route drift: %s", status)
+		return fmt.Sprintf("%s: %s", host, status)
+	} else if proxyReconcileRepair && status != proxy.ReconcileInSync {
+		output.DefaultLogger.HostSuccess(host, "repaired (%s)", status)
+	} else {
+		output.DefaultLogger.HostSuccess(host, "in-sync")
+	}
+	return ""
+}
+
 func readCanaryState() (*deploy.CanaryState, error) {
 	dir, err := state.LocalDir()
 	if err != nil {
@@ -151,15 +221,11 @@ func desiredProxyUpstreams(cm *podman.ContainerManager, host string, canary *dep
 	}
 	dials := make(map[string]string, len(names))
 	for _, name := range names {
-		if cfg.UseHostPortUpstreams() {
-			port, err := cm.HostPort(host, name, cfg.Proxy.AppPort)
-			if err != nil {
-				return nil, nil, err
-			}
-			dials[name] = fmt.Sprintf("127.0.0.1:%d", port)
-		} else {
-			dials[name] = fmt.Sprintf("%s:%d", name, cfg.Proxy.AppPort)
+		dial, err := deploy.ResolveUpstream(cfg, cm, host, name)
+		if err != nil {
+			return nil, nil, err
 		}
+		dials[name] = dial
 	}
 	if allowedCanary != "" {
 		return nil, []proxy.UpstreamWeight{{Dial: dials[stable], Weight: 100 - canary.CurrentWeight}, {Dial: dials[allowedCanary], Weight: canary.CurrentWeight}}, nil
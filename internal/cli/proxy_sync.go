@@ -0,0 +1,153 @@
+package cli
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/lemonity-org/azud/internal/output"
+	"github.com/lemonity-org/azud/internal/proxy"
+	"github.com/lemonity-org/azud/internal/ssh"
+)
+
+var proxySyncCmd = &cobra.Command{
+	Use:   "sync",
+	Short: "Give every proxy host the same route set",
+	Long: `Compare the routes of the proxy on every host and repair the ones that
+diverged, e.g. because a host was offline during a deploy.
+
+The service's own route is reconciled as 'azud proxy reconcile --repair'
+does. With a load-balancer tier (proxy.hosts_role) every proxy must hold
+identical routes, so other routes that differ are copied from the copy most
+hosts have. Otherwise each proxy balances to its own containers: routes of
+other services are only compared, ignoring upstreams, and reported.
+
+'azud deploy' runs the comparison after a successful deploy.
+
+Example:
+  azud proxy sync
+  azud proxy sync --check`,
+	Args: cobra.NoArgs,
+	RunE: runProxySync,
+}
+
+var proxySyncCheck bool
+
+func init() {
+	proxySyncCmd.Flags().BoolVar(&proxySyncCheck, "check", false, "Only report diverged routes")
+	proxyCmd.AddCommand(proxySyncCmd)
+}
+
+func runProxySync(cmd *cobra.Command, args []string) error {
+	output.SetVerbose(verbose)
+	log := output.DefaultLogger
+
+	var failures []string
+	proxyHost = ""
+	proxyReconcileCheck, proxyReconcileRepair = proxySyncCheck, !proxySyncCheck
+	log.Info("Reconciling the %s route...", cfg.Service)
+	if err := reconcileProxyRoutes(); err != nil {
+		failures = append(failures, err.Error())
+	}
+
+	sshClient := createSSHClient()
+	defer func() { _ = sshClient.Close() }()
+
+	diverged, err := syncProxyRouteSets(sshClient, log, !proxySyncCheck)
+	if err != nil {
+		failures = append(failures, err.Error())
+	}
+	if diverged > 0 {
+		failures = append(failures, fmt.Sprintf("%d route(s) differ between proxy hosts", diverged))
+	}
+	if len(failures) > 0 {
+		return fmt.Errorf("proxy sync failed: %s", strings.Join(failures, "; "))
+	}
+	log.Success("Proxy hosts are in sync")
+	return nil
+}
+
+// proxyRouteNodes returns the hosts whose proxy holds the service routes:
+// the load-balancer tier, or the web hosts.
+func proxyRouteNodes() []string {
+	if cfg.UsesProxyTier() {
+		return cfg.GetProxyHosts()
+	}
+	return getProxyRouteHosts("")
+}
+
+// syncProxyRouteSets compares the routes of the proxy hosts and, with repair
+// and a load-balancer tier, copies the reference copy of each diverged route
+// to the hosts that lack it. It returns how many routes still differ. The
+// service's own route is left to reconciliation when each proxy balances to
+// its own containers, as its upstreams and weights are host-specific.
+func syncProxyRouteSets(sshClient *ssh.Client, log *output.Logger, repair bool) (int, error) {
+	hosts := proxyRouteNodes()
+	if len(hosts) < 2 {
+		return 0, nil
+	}
+	manager := proxy.NewManagerWithOptions(sshClient, log, cfg.SSH.User, cfg.Proxy.Rootful, cfg.UseHostPortUpstreams())
+
+	var reachable, unreachable []string
+	sets := make(map[string]proxy.RouteSet, len(hosts))
+	for _, host := range hosts {
+		routes, err := manager.Routes(host)
+		if err != nil {
+			log.HostError(host, "cannot read proxy routes: %v", err)
+			unreachable = append(unreachable, host)
+			continue
+		}
+		sets[host] = routes
+		reachable = append(reachable, host)
+	}
+
+	tier := cfg.UsesProxyTier()
+	diverged := 0
+	for _, drift := range proxy.CompareRouteSets(reachable, sets, tier) {
+		if !tier && drift.Key == proxy.ServiceRouteID(cfg.Service) {
+			continue
+		}
+		var problems []string
+		if len(drift.Missing) > 0 {
+			problems = append(problems, "missing on "+strings.Join(drift.Missing, ", "))
+		}
+		if len(drift.Differs) > 0 {
+			problems = append(problems, "different on "+strings.Join(drift.Differs, ", "))
+		}
+		log.Warn("Route %s is %s (reference: %s)", drift.Key, strings.Join(problems, " and "), drift.Source)
+		if !repair || !tier {
+			diverged++
+			continue
+		}
+		repaired := true
+		for _, host := range append(drift.Missing, drift.Differs...) {
+			if err := manager.CopyRoute(host, drift.Route); err != nil {
+				log.HostError(host, "failed to copy route %s: %v", drift.Key, err)
+				repaired = false
+				continue
+			}
+			log.HostSuccess(host, "Route %s copied from %s", drift.Key, drift.Source)
+		}
+		if !repaired {
+			diverged++
+		}
+	}
+	if len(unreachable) > 0 {
+		return diverged, fmt.Errorf("proxy routes unreadable on %s; run 'azud proxy sync' once reachable", strings.Join(unreachable, ", "))
+	}
+	return diverged, nil
+}
+
+// syncProxyRoutesAfterChange brings the proxy hosts back in line after a
+// deploy changed routes. Divergence is reported, never fatal: the deploy
+// itself succeeded.
+func syncProxyRoutesAfterChange(sshClient *ssh.Client, log *output.Logger) {
+	diverged, err := syncProxyRouteSets(sshClient, log, true)
+	if err != nil {
+		log.Warn("%v", err)
+	}
+	if diverged > 0 {
+		log.Warn("%d route(s) differ between proxy hosts; see 'azud proxy sync --check'", diverged)
+	}
+}
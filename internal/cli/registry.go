@@ -2,7 +2,6 @@ package cli
 
 import (
 	"fmt"
-	"os"
 
 	"github.com/spf13/cobra"
 
@@ -18,14 +17,21 @@ var registryCmd = &cobra.Command{
 
 var registryLoginCmd = &cobra.Command{
 	Use:   "login",
-	Short: "Login to container registry on all servers",
-	Long: `Login to the configured container registry on all deployment servers.
+	Short: "Login to container registries on all servers",
+	Long: `Login to the configured container registries on all deployment servers.
 
-The registry credentials are read from the configuration file and secrets.
+Each server is logged into the registry section's server and into the
+registries entries for the images it runs. The credentials are read from the
+configuration file and secrets.
+
+Registries configured with oauth are authorized first with the OAuth device
+flow when their token secret is missing (or with --refresh): open the printed
+URL, enter the code, and the token is stored in the secrets file.
 
 Example:
   azud registry login              # Login on all servers
-  azud registry login --host x.x.x # Login on specific host`,
+  azud registry login --host x.x.x # Login on specific host
+  azud registry login --refresh    # Authorize OAuth registries again`,
 	RunE: runRegistryLogin,
 }
 
@@ -40,11 +46,13 @@ Example:
 }
 
 var (
-	registryHost string
+	registryHost    string
+	registryRefresh bool
 )
 
 func init() {
 	registryLoginCmd.Flags().StringVar(&registryHost, "host", "", "Specific host to login on")
+	registryLoginCmd.Flags().BoolVar(&registryRefresh, "refresh", false, "Repeat OAuth device authorization even when a token is stored")
 	registryLogoutCmd.Flags().StringVar(&registryHost, "host", "", "Specific host to logout from")
 
 	registryCmd.AddCommand(registryLoginCmd)
@@ -56,46 +64,32 @@ func runRegistryLogin(cmd *cobra.Command, args []string) error {
 	output.SetVerbose(verbose)
 	log := output.DefaultLogger
 
-	// Get registry configuration
-	server := cfg.Registry.Server
-	if server == "" {
-		server = "docker.io"
-	}
-
-	username := cfg.Registry.Username
-	if username == "" {
-		return fmt.Errorf("registry username not configured")
-	}
-
-	// Get password from secrets
-	password := ""
-	if len(cfg.Registry.Password) > 0 {
-		secretKey := cfg.Registry.Password[0]
-		password = os.Getenv(secretKey)
-		if password == "" {
-			// Try loading from secrets file
-			if p, ok := getSecret(secretKey); ok {
-				password = p
-			}
-		}
-	}
-
-	if password == "" {
-		return fmt.Errorf("registry password not found (secret: %v)", cfg.Registry.Password)
-	}
-
 	// Determine hosts
 	var hosts []string
 	if registryHost != "" {
 		hosts = []string{registryHost}
 	} else {
-		hosts = cfg.GetAllHosts()
+		hosts = setupRuntimeHosts()
 		if len(hosts) == 0 {
 			return fmt.Errorf("no hosts configured")
 		}
 	}
 
-	log.Info("Logging into %s on %d host(s)...", server, len(hosts))
+	// Registries that issue tokens through OAuth are authorized first, so
+	// their tokens are available for the host logins below.
+	if err := authorizeRegistries(cmd.Context(), log, registryRefresh); err != nil {
+		return err
+	}
+
+	logins, err := hostRegistryLogins(hosts)
+	if err != nil {
+		return err
+	}
+	if len(logins) == 0 {
+		return fmt.Errorf("registry username not configured")
+	}
+
+	log.Info("Logging into registries on %d host(s)...", len(logins))
 
 	// Create SSH client
 	sshClient := createSSHClient()
@@ -105,29 +99,53 @@ func runRegistryLogin(cmd *cobra.Command, args []string) error {
 	podmanClient := podman.NewClient(sshClient)
 	registryManager := podman.NewRegistryManager(podmanClient)
 
-	// Login on all hosts
-	registryConfig := &podman.RegistryConfig{
-		Server:   server,
-		Username: username,
-		Password: password,
-	}
-
-	errors := registryManager.LoginAll(hosts, registryConfig)
+	errors := registryManager.LoginHosts(logins)
 
 	// Report results
-	successCount := len(hosts) - len(errors)
+	successCount := len(logins) - len(errors)
 	for host, err := range errors {
 		log.HostError(host, "login failed: %v", err)
 	}
 
 	if len(errors) > 0 {
-		return fmt.Errorf("login failed on %d/%d hosts", len(errors), len(hosts))
+		return fmt.Errorf("login failed on %d/%d hosts", len(errors), len(logins))
 	}
 
 	log.Success("Logged in on %d host(s)", successCount)
 	return nil
 }
 
+// hostRegistryLogins returns the registries each host must be logged into
+// for the images it runs, with passwords resolved from secrets. Hosts that
+// need no login are omitted.
+func hostRegistryLogins(hosts []string) (map[string][]*podman.RegistryConfig, error) {
+	logins := make(map[string][]*podman.RegistryConfig)
+	for _, host := range hosts {
+		for _, registry := range cfg.RegistryLogins(cfg.HostImages(host)...) {
+			server := registry.Server
+			if server == "" {
+				server = "docker.io"
+			}
+			password, err := registry.ResolvePassword()
+			if err != nil {
+				return nil, fmt.Errorf("registry credentials for %s: %w", server, err)
+			}
+			if password == "" && registry.OAuth != nil {
+				return nil, fmt.Errorf("registry token not found for %s (secret: %v); run 'azud registry login' to authorize", server, registry.Password)
+			}
+			if password == "" {
+				return nil, fmt.Errorf("registry password not found for %s (secret: %v)", server, registry.Password)
+			}
+			logins[host] = append(logins[host], &podman.RegistryConfig{
+				Server:   registry.Server,
+				Username: registry.Username,
+				Password: password,
+			})
+		}
+	}
+	return logins, nil
+}
+
 func runRegistryLogout(cmd *cobra.Command, args []string) error {
 	output.SetVerbose(verbose)
 	log := output.DefaultLogger
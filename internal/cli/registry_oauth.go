@@ -0,0 +1,203 @@
+package cli
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/lemonity-org/azud/internal/config"
+	"github.com/lemonity-org/azud/internal/output"
+)
+
+// deviceGrantType is the OAuth grant type of RFC 8628 token requests.
+const deviceGrantType = "urn:ietf:params:oauth:grant-type:device_code"
+
+// deviceFlowDefaultInterval is the polling interval used when the
+// authorization server does not name one.
+var deviceFlowDefaultInterval = 5 * time.Second
+
+// deviceAuthorization is the device authorization endpoint's response.
+type deviceAuthorization struct {
+	DeviceCode              string `json:"device_code"`
+	UserCode                string `json:"user_code"`
+	VerificationURI         string `json:"verification_uri"`
+	VerificationURIComplete string `json:"verification_uri_complete"`
+	ExpiresIn               int    `json:"expires_in"`
+	Interval                int    `json:"interval"`
+}
+
+// deviceToken is the token endpoint's response, successful or not.
+type deviceToken struct {
+	AccessToken      string `json:"access_token"`
+	Error            string `json:"error"`
+	ErrorDescription string `json:"error_description"`
+}
+
+// authorizeRegistries runs the device authorization flow for each registry
+// configured with oauth whose token secret is not available yet, or for all of
+// them with refresh. Tokens are written to the secrets file and loaded for the
+// rest of the command.
+func authorizeRegistries(ctx context.Context, log *output.Logger, refresh bool) error {
+	registries := []config.RegistryConfig{cfg.Registry}
+	for _, server := range cfg.RegistryServers() {
+		registry := cfg.Registries[server]
+		registry.Server = server
+		registries = append(registries, registry)
+	}
+
+	for _, registry := range registries {
+		if registry.OAuth == nil || len(registry.Password) == 0 {
+			continue
+		}
+		key := registry.Password[0]
+		if !refresh && secretAvailable(key) {
+			continue
+		}
+
+		server := registry.Server
+		if server == "" {
+			server = "docker.io"
+		}
+		log.Info("Authorizing %s...", server)
+		token, err := deviceLogin(ctx, registry.OAuth, os.Stdout)
+		if err != nil {
+			return fmt.Errorf("%s authorization failed: %w", server, err)
+		}
+		if err := storeRegistryToken(log, key, token); err != nil {
+			return err
+		}
+		log.Success("Authorized %s", server)
+	}
+	return nil
+}
+
+// storeRegistryToken saves token as secret key. With the file secrets
+// provider it is written to the secrets file; other providers cannot be
+// written to, so the token is only used by this command.
+func storeRegistryToken(log *output.Logger, key, token string) error {
+	secrets := config.AllSecrets()
+	secrets[key] = token
+	config.SetLoadedSecrets(secrets)
+
+	if !isFileSecretsProvider() {
+		log.Warn("secrets_provider %s is read-only; store the token as %s there to reuse it", cfg.SecretsProvider, key)
+		return nil
+	}
+
+	secretsPath := getSecretsFilePath()
+	stored, err := loadSecretsFile(secretsPath)
+	if err != nil {
+		return fmt.Errorf("failed to load secrets: %w", err)
+	}
+	stored[key] = token
+	if err := os.MkdirAll(filepath.Dir(secretsPath), 0755); err != nil {
+		return fmt.Errorf("failed to create secrets directory: %w", err)
+	}
+	if err := writeSecretsFile(secretsPath, stored); err != nil {
+		return err
+	}
+	log.Info("Stored %s in %s", key, secretsPath)
+	return nil
+}
+
+// deviceLogin performs the OAuth 2.0 device authorization grant: it prints
+// the verification URL and user code to out, then polls the token endpoint
+// until the user approves or denies the request, or the code expires.
+func deviceLogin(ctx context.Context, oauth *config.RegistryOAuthConfig, out io.Writer) (string, error) {
+	client := &http.Client{Timeout: 30 * time.Second}
+
+	form := url.Values{"client_id": {oauth.ClientID}}
+	if len(oauth.Scopes) > 0 {
+		form.Set("scope", strings.Join(oauth.Scopes, " "))
+	}
+	var authorization deviceAuthorization
+	if status, err := postOAuthForm(ctx, client, oauth.DeviceAuthorizationURL, form, &authorization); err != nil {
+		return "", err
+	} else if status != http.StatusOK || authorization.DeviceCode == "" {
+		return "", fmt.Errorf("device authorization returned HTTP %d without a device code", status)
+	}
+
+	if authorization.VerificationURIComplete != "" {
+		_, _ = fmt.Fprintf(out, "Open %s to authorize this device (code %s).\n", authorization.VerificationURIComplete, authorization.UserCode)
+	} else {
+		_, _ = fmt.Fprintf(out, "Open %s and enter code %s.\n", authorization.VerificationURI, authorization.UserCode)
+	}
+
+	interval := time.Duration(authorization.Interval) * time.Second
+	if interval <= 0 {
+		interval = deviceFlowDefaultInterval
+	}
+	expiresIn := time.Duration(authorization.ExpiresIn) * time.Second
+	if expiresIn <= 0 {
+		expiresIn = 15 * time.Minute
+	}
+	ctx, cancel := context.WithTimeout(ctx, expiresIn)
+	defer cancel()
+
+	form = url.Values{
+		"client_id":   {oauth.ClientID},
+		"device_code": {authorization.DeviceCode},
+		"grant_type":  {deviceGrantType},
+	}
+	for {
+		select {
+		case <-ctx.Done():
+			return "", fmt.Errorf("device code expired before it was approved")
+		case <-time.After(interval):
+		}
+
+		var token deviceToken
+		status, err := postOAuthForm(ctx, client, oauth.TokenURL, form, &token)
+		if err != nil {
+			return "", err
+		}
+		switch {
+		case token.AccessToken != "":
+			return token.AccessToken, nil
+		case token.Error == "authorization_pending":
+		case token.Error == "slow_down":
+			interval += 5 * time.Second
+		case token.Error == "access_denied":
+			return "", fmt.Errorf("authorization was denied")
+		case token.Error == "expired_token":
+			return "", fmt.Errorf("device code expired before it was approved")
+		case token.Error != "":
+			return "", fmt.Errorf("token request failed: %s %s", token.Error, token.ErrorDescription)
+		default:
+			return "", fmt.Errorf("token endpoint returned HTTP %d without a token", status)
+		}
+	}
+}
+
+// postOAuthForm posts form to endpoint and decodes the JSON response into v.
+// OAuth error responses are JSON as well, so v is decoded for any status.
+func postOAuthForm(ctx context.Context, client *http.Client, endpoint string, form url.Values, v any) (int, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return resp.StatusCode, err
+	}
+	if err := json.Unmarshal(body, v); err != nil {
+		return resp.StatusCode, fmt.Errorf("invalid response from %s (HTTP %d): %w", endpoint, resp.StatusCode, err)
+	}
+	return resp.StatusCode, nil
+}
@@ -0,0 +1,113 @@
+package cli
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/lemonity-org/azud/internal/config"
+)
+
+func newDeviceFlowServer(t *testing.T, tokenResponses ...map[string]string) *httptest.Server {
+	t.Helper()
+	mux := http.NewServeMux()
+	mux.HandleFunc("/device", func(w http.ResponseWriter, r *http.Request) {
+		if err := r.ParseForm(); err != nil {
+			t.Fatal(err)
+		}
+		if r.Form.Get("client_id") != "azud-test" || r.Form.Get("scope") != "read:packages" {
+			t.Errorf("device request form = %v", r.Form)
+		}
+		_ = json.NewEncoder(w).Encode(map[string]any{
+			"device_code":      "dev-123",
+			"user_code":        "ABCD-1234",
+			"verification_uri": "https://example.com/device",
+			"expires_in":       60,
+		})
+	})
+	mux.HandleFunc("/token", func(w http.ResponseWriter, r *http.Request) {
+		if err := r.ParseForm(); err != nil {
+			t.Fatal(err)
+		}
+		if r.Form.Get("grant_type") != deviceGrantType || r.Form.Get("device_code") != "dev-123" {
+			t.Errorf("token request form = %v", r.Form)
+		}
+		if len(tokenResponses) == 0 {
+			t.Fatal("unexpected token request")
+		}
+		response := tokenResponses[0]
+		tokenResponses = tokenResponses[1:]
+		if response["error"] != "" {
+			w.WriteHeader(http.StatusBadRequest)
+		}
+		_ = json.NewEncoder(w).Encode(response)
+	})
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestDeviceLogin(t *testing.T) {
+	previous := deviceFlowDefaultInterval
+	deviceFlowDefaultInterval = time.Millisecond
+	t.Cleanup(func() { deviceFlowDefaultInterval = previous })
+
+	tests := []struct {
+		name      string
+		responses []map[string]string
+		want      string
+		wantErr   string
+	}{
+		{
+			name: "approved after pending",
+			responses: []map[string]string{
+				{"error": "authorization_pending"},
+				{"access_token": "tok-456", "token_type": "bearer"},
+			},
+			want: "tok-456",
+		},
+		{
+			name:      "denied",
+			responses: []map[string]string{{"error": "access_denied"}},
+			wantErr:   "authorization was denied",
+		},
+		{
+			name:      "expired",
+			responses: []map[string]string{{"error": "expired_token"}},
+			wantErr:   "device code expired",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := newDeviceFlowServer(t, tt.responses...)
+			oauth := &config.RegistryOAuthConfig{
+				DeviceAuthorizationURL: server.URL + "/device",
+				TokenURL:               server.URL + "/token",
+				ClientID:               "azud-test",
+				Scopes:                 []string{"read:packages"},
+			}
+			var out bytes.Buffer
+			token, err := deviceLogin(context.Background(), oauth, &out)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("expected error containing %q, got %v", tt.wantErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("deviceLogin() error: %v", err)
+			}
+			if token != tt.want {
+				t.Fatalf("deviceLogin() = %q, want %q", token, tt.want)
+			}
+			if !strings.Contains(out.String(), "https://example.com/device") || !strings.Contains(out.String(), "ABCD-1234") {
+				t.Fatalf("instructions = %q, want the verification URL and user code", out.String())
+			}
+		})
+	}
+}
@@ -2,13 +2,16 @@ package cli
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"os"
 	"path/filepath"
+	"strings"
 
 	"github.com/spf13/cobra"
 
 	"github.com/lemonity-org/azud/internal/config"
+	"github.com/lemonity-org/azud/internal/output"
 	"github.com/lemonity-org/azud/pkg/version"
 )
 
@@ -17,6 +20,7 @@ var (
 	configPath  string
 	destination string
 	verbose     bool
+	quiet       bool
 
 	// Config instance
 	cfg *config.Config
@@ -32,8 +36,14 @@ configuration.`,
 		SilenceUsage:  true,
 		SilenceErrors: true,
 		PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+			output.SetQuiet(quiet)
+
+			if err := checkAccess(cmd, nil); err != nil {
+				return err
+			}
+
 			// Skip config loading for commands that don't need it
-			if cmd.Name() == "init" || cmd.Name() == "version" || cmd.Name() == "help" {
+			if !needsConfig(cmd) {
 				return nil
 			}
 
@@ -43,7 +53,7 @@ configuration.`,
 			if err != nil {
 				return err
 			}
-			return nil
+			return checkAccess(cmd, cfg)
 		},
 	}
 )
@@ -54,19 +64,54 @@ func init() {
 	rootCmd.PersistentFlags().StringVarP(&configPath, "config", "c", "", "Path to config file (default: config/deploy.yml)")
 	rootCmd.PersistentFlags().StringVarP(&destination, "destination", "d", "", "Destination environment (e.g., staging, production)")
 	rootCmd.PersistentFlags().BoolVarP(&verbose, "verbose", "v", false, "Enable verbose output")
+	rootCmd.PersistentFlags().BoolVarP(&quiet, "quiet", "q", false, "Only print outcomes, warnings, and errors (for CI)")
 
 	// Add subcommands
 	rootCmd.AddCommand(versionCmd)
 	rootCmd.AddCommand(initCmd)
 }
 
+// needsConfig reports whether cmd loads the configuration before running.
+// Shell completion loads what it needs itself, without secrets, promote
+// loads the configuration of its --to destination, destinations and serve
+// load each destination they work with, and config validate reports
+// problems instead of failing on them.
+func needsConfig(cmd *cobra.Command) bool {
+	if cmd == configValidateCmd {
+		return false
+	}
+	// bundle apply loads the configuration from the bundle.
+	if cmd.Name() == "apply" && cmd.Parent() != nil && cmd.Parent().Name() == "bundle" {
+		return false
+	}
+	for c := cmd; c != nil; c = c.Parent() {
+		switch c.Name() {
+		case "init", "promote", "destinations", "serve", "upgrade", "version", "help", "completion", cobra.ShellCompRequestCmd, cobra.ShellCompNoDescRequestCmd:
+			return false
+		}
+	}
+	return true
+}
+
+// ExitError reports that a command run on a host exited with a non-zero
+// code. The CLI exits with the same code.
+type ExitError struct {
+	Code int
+}
+
+func (e *ExitError) Error() string {
+	return fmt.Sprintf("command exited with code %d", e.Code)
+}
+
 func Execute() error {
-	return rootCmd.Execute()
+	return ExecuteContext(context.Background())
 }
 
 // ExecuteContext runs the CLI with cancellation propagated to remote SSH
 // connections and commands.
 func ExecuteContext(ctx context.Context) error {
+	registerCompletions(rootCmd)
+	rootCmd.SetArgs(prepareArgs(rootCmd, os.Args[1:]))
 	return rootCmd.ExecuteContext(ctx)
 }
 
@@ -86,6 +131,11 @@ func loadConfig() (*config.Config, error) {
 		return nil, err
 	}
 	if err := config.ValidateMinimumVersion(loaded, version.Version); err != nil {
+		var tooOld *config.MinimumVersionError
+		if errors.As(err, &tooOld) {
+			output.DefaultLogger.Warn("This configuration requires azud %s or newer", tooOld.Required)
+			output.DefaultLogger.Info("Upgrade with: azud upgrade --version v%s", strings.TrimPrefix(tooOld.Required, "v"))
+		}
 		return nil, err
 	}
 	return loaded, nil
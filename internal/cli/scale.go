@@ -56,7 +56,10 @@ func init() {
 
 func runScale(cmd *cobra.Command, args []string) error {
 	output.SetVerbose(verbose)
-	log := output.DefaultLogger
+
+	if cfg.UsesProxyTier() {
+		return fmt.Errorf("scaling is not supported with proxy.hosts_role")
+	}
 
 	// Parse scale arguments
 	scales := make(map[string]scaleOperation)
@@ -78,6 +81,14 @@ func runScale(cmd *cobra.Command, args []string) error {
 
 		scales[role] = op
 	}
+	return applyScale(scales, scaleHost)
+}
+
+// applyScale brings each role in scales to its target instance count on
+// the role's hosts, or only on onlyHost when it is set, keeping the proxy
+// upstreams in sync.
+func applyScale(scales map[string]scaleOperation, onlyHost string) error {
+	log := output.DefaultLogger
 
 	sshClient := createSSHClient()
 	defer func() { _ = sshClient.Close() }()
@@ -97,19 +108,16 @@ func runScale(cmd *cobra.Command, args []string) error {
 	var operationErrors []string
 	for _, role := range roles {
 		op := scales[role]
-		hosts := cfg.GetRoleHosts(role)
-		if scaleHost != "" {
-			if !containsString(hosts, scaleHost) {
-				operationErrors = append(operationErrors, fmt.Sprintf("%s/%s: host is not configured for role", scaleHost, role))
-				continue
-			}
-			hosts = []string{scaleHost}
+		hosts, err := scaleHosts(role, onlyHost)
+		if err != nil {
+			operationErrors = append(operationErrors, fmt.Sprintf("%s/%s: %v", onlyHost, role, err))
+			continue
 		}
 
 		for _, host := range hosts {
 			log.Host(host, "Scaling role %s", role)
 
-			if err := ensureRemoteSecretsFile(sshClient, []string{host}, cfg.Env.Secret); err != nil {
+			if err := ensureRoleSecretsFile(sshClient, []string{host}, role); err != nil {
 				log.HostError(host, "Missing secrets: %v", err)
 				operationErrors = append(operationErrors, fmt.Sprintf("%s/%s: missing secrets: %v", host, role, err))
 				continue
@@ -152,6 +160,11 @@ func runScale(cmd *cobra.Command, args []string) error {
 			}
 
 			log.HostSuccess(host, "Scaled %s to %d instances", role, targetCount)
+			if cfg.UseHostPortUpstreams() && deploy.IsProxyRole(role) {
+				if err := deploy.RecordHostPorts(sshClient, containerManager, host, cfg.SSH.User); err != nil {
+					log.Warn("Failed to update port registry on %s: %v", host, err)
+				}
+			}
 		}
 	}
 
@@ -162,6 +175,19 @@ func runScale(cmd *cobra.Command, args []string) error {
 	return nil
 }
 
+// scaleHosts returns the hosts of role to scale: all of them, or only
+// onlyHost when it is set, which must run the role.
+func scaleHosts(role, onlyHost string) ([]string, error) {
+	hosts := cfg.GetRoleHosts(role)
+	if onlyHost == "" {
+		return hosts, nil
+	}
+	if !containsString(hosts, onlyHost) {
+		return nil, fmt.Errorf("host is not configured for role")
+	}
+	return []string{onlyHost}, nil
+}
+
 func runScaleStatus(cmd *cobra.Command, args []string) error {
 	output.SetVerbose(verbose)
 	log := output.DefaultLogger
@@ -357,7 +383,7 @@ func scaleUp(cm *podman.ContainerManager, im *podman.ImageManager, pm *proxy.Man
 		used[index] = struct{}{}
 		containerName := fmt.Sprintf("%s-%d", deploy.RoleContainerName(cfg, role), index)
 		log.Host(host, "Starting instance %s", containerName)
-		containerConfig := deploy.NewAppContainerConfig(cfg, cfg.Image, containerName, role, map[string]string{
+		containerConfig := deploy.NewAppContainerConfig(cfg, cfg.Image, containerName, role, host, map[string]string{
 			"azud.instance": strconv.Itoa(index),
 		})
 		if _, err := cm.Run(host, containerConfig); err != nil {
@@ -439,12 +465,5 @@ func scaleDown(cm *podman.ContainerManager, pm *proxy.Manager, host, role string
 }
 
 func scaleUpstreamForContainer(cm *podman.ContainerManager, host, container string) (string, error) {
-	if !cfg.UseHostPortUpstreams() {
-		return fmt.Sprintf("%s:%d", container, cfg.Proxy.AppPort), nil
-	}
-	port, err := cm.HostPort(host, container, cfg.Proxy.AppPort)
-	if err != nil {
-		return "", err
-	}
-	return fmt.Sprintf("127.0.0.1:%d", port), nil
+	return deploy.ResolveUpstream(cfg, cm, host, container)
 }
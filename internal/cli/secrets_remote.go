@@ -7,5 +7,21 @@ import (
 )
 
 func ensureRemoteSecretsFile(sshClient *ssh.Client, hosts []string, requiredKeys []string) error {
-	return deploy.ValidateRemoteSecrets(sshClient, hosts, config.RemoteSecretsPath(cfg), requiredKeys)
+	return deploy.ValidateSecrets(cfg, sshClient, hosts, config.RemoteSecretsPath(cfg), requiredKeys)
+}
+
+// ensureRoleSecretsFile checks a role's env file on hosts for the role's
+// secrets.
+func ensureRoleSecretsFile(sshClient *ssh.Client, hosts []string, role string) error {
+	return deploy.ValidateSecrets(cfg, sshClient, hosts, config.RoleSecretsPath(cfg, role), cfg.RoleSecrets(role))
+}
+
+// ensureHostSecretFiles checks every env file host needs.
+func ensureHostSecretFiles(sshClient *ssh.Client, host string) error {
+	for path, keys := range cfg.HostSecretFiles(host) {
+		if err := deploy.ValidateSecrets(cfg, sshClient, []string{host}, path, keys); err != nil {
+			return err
+		}
+	}
+	return nil
 }
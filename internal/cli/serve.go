@@ -0,0 +1,213 @@
+package cli
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/lemonity-org/azud/internal/agent"
+	"github.com/lemonity-org/azud/internal/config"
+	"github.com/lemonity-org/azud/internal/deploy"
+	"github.com/lemonity-org/azud/internal/output"
+)
+
+var serveCmd = &cobra.Command{
+	Use:   "serve",
+	Short: "Deploy new image tags announced by registry webhooks",
+	Long: `Listen for signed registry webhooks and deploy the announced image tag
+to every destination that deploys that image, turning azud into a minimal
+pull-based CD endpoint.
+
+GitHub package and registry_package events (for example a push to GHCR) are
+understood, as is {"image": "<repository>:<tag>"} from any other sender.
+Requests must carry an X-Hub-Signature-256 HMAC of the body keyed with the
+secret named by serve.secret; a destination only deploys webhooks signed
+with its own key, and only tags matching serve.tags.
+
+Deploys run one at a time. While one runs, only the newest tag per
+destination stays queued. Redelivered webhooks (by X-GitHub-Delivery), tags
+the destination already runs, and versions older than the deployed one are
+ignored. Each deploy reloads the destination's configuration; restart azud
+serve to apply changes to the serve section.
+
+The server listens on loopback by default. To accept webhooks from the
+registry directly, listen on a public address with --tls-certificate and
+--tls-private-key, or put it behind a TLS-terminating proxy.
+
+Example:
+  azud serve
+  azud serve --listen :8443 --tls-certificate cert.pem --tls-private-key key.pem
+  azud serve --destinations staging,production`,
+	Args: cobra.NoArgs,
+	RunE: runServe,
+}
+
+// defaultServeListen is the address azud serve listens on by default.
+const defaultServeListen = "127.0.0.1:8080"
+
+var (
+	serveListen         string
+	serveDestinations   []string
+	serveTLSCertificate string
+	serveTLSPrivateKey  string
+)
+
+func init() {
+	serveCmd.Flags().StringVar(&serveListen, "listen", defaultServeListen, "Address to listen on")
+	serveCmd.Flags().StringVar(&serveTLSCertificate, "tls-certificate", "", "TLS certificate file; webhooks are received over plain HTTP without it")
+	serveCmd.Flags().StringVar(&serveTLSPrivateKey, "tls-private-key", "", "TLS private key file")
+	serveCmd.Flags().StringSliceVar(&serveDestinations, "destinations", nil, "Destinations to deploy (default: the -d destination, or the base configuration)")
+	_ = serveCmd.RegisterFlagCompletionFunc("destinations", completeDestinations)
+
+	rootCmd.AddCommand(serveCmd)
+}
+
+func runServe(cmd *cobra.Command, args []string) error {
+	output.SetVerbose(verbose)
+	log := output.DefaultLogger
+
+	if (serveTLSCertificate == "") != (serveTLSPrivateKey == "") {
+		return fmt.Errorf("--tls-certificate and --tls-private-key must be set together")
+	}
+
+	destinations := serveDestinations
+	if len(destinations) == 0 {
+		destinations = []string{destination}
+	} else if destination != "" {
+		return fmt.Errorf("-d conflicts with --destinations; list every destination in --destinations")
+	}
+
+	var targets []agent.WebhookTarget
+	services := make(map[string]string)
+	for _, name := range destinations {
+		target, service, err := serveTarget(cmd, name)
+		if err != nil {
+			return err
+		}
+		targets = append(targets, target)
+		services[name] = service
+		log.Info("Deploying %s tags %s to %s", target.Repository, serveTagsLabel(target.Tags), deploy.DestinationLabel(name))
+	}
+
+	if serveTLSCertificate == "" && !config.LoopbackListen(serveListen) {
+		log.Warn("Receiving webhooks on %s without TLS; payloads travel in clear text", serveListen)
+	}
+	listener, err := net.Listen("tcp", serveListen)
+	if err != nil {
+		return err
+	}
+	server := agent.NewWebhookServer(cmd.Context(), targets, serveDeploy, serveCurrent(services, log), log)
+	httpServer := &http.Server{
+		Handler:           server.Handler(),
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+	errCh := make(chan error, 1)
+	scheme := "http"
+	if serveTLSCertificate != "" {
+		scheme = "https"
+		go func() { errCh <- httpServer.ServeTLS(listener, serveTLSCertificate, serveTLSPrivateKey) }()
+	} else {
+		go func() { errCh <- httpServer.Serve(listener) }()
+	}
+	log.Success("Listening for webhooks on %s://%s/webhook", scheme, listener.Addr())
+
+	select {
+	case err := <-errCh:
+		return err
+	case <-cmd.Context().Done():
+	}
+
+	log.Info("Shutting down...")
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	err = httpServer.Shutdown(shutdownCtx)
+	server.Wait()
+	if err != nil && !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	return nil
+}
+
+// serveTarget loads the configuration of destination name and returns what
+// its webhooks must match and the service it deploys.
+func serveTarget(cmd *cobra.Command, name string) (agent.WebhookTarget, string, error) {
+	destination = name
+	loaded, err := loadConfig()
+	if err != nil {
+		return agent.WebhookTarget{}, "", err
+	}
+	if err := checkAccess(cmd, loaded); err != nil {
+		return agent.WebhookTarget{}, "", err
+	}
+	if loaded.Serve.Secret == "" {
+		return agent.WebhookTarget{}, "", fmt.Errorf("serve.secret is not set for %s; webhooks must be signed", deploy.DestinationLabel(name))
+	}
+	key, ok := getSecret(loaded.Serve.Secret)
+	if !ok {
+		return agent.WebhookTarget{}, "", fmt.Errorf("secret %s is not set for %s", loaded.Serve.Secret, deploy.DestinationLabel(name))
+	}
+	return agent.WebhookTarget{
+		Name:       name,
+		Repository: stripImageReference(loaded.Image),
+		Tags:       loaded.Serve.Tags,
+		Secret:     []byte(key),
+	}, loaded.Service, nil
+}
+
+// serveCurrent reports the release last deployed successfully to each
+// destination, from deployment history. It does not touch the shared
+// configuration, which a running deploy may be replacing.
+func serveCurrent(services map[string]string, log *output.Logger) agent.CurrentFunc {
+	return func(name string) (agent.Release, bool) {
+		record, err := deploy.NewDurableHistoryStore(0, log).GetLastSuccessfulTo(services[name], name)
+		if err != nil || record == nil || record.Version == "" {
+			return agent.Release{}, false
+		}
+		return agent.Release{
+			Repository: stripImageReference(record.Image),
+			Tag:        record.Version,
+			Digest:     record.Metadata["image_digest"],
+		}, true
+	}
+}
+
+// serveDeploy deploys release to destination name with its freshly loaded
+// configuration. Deploys never overlap, so the shared configuration is
+// safe to replace.
+func serveDeploy(ctx context.Context, name string, release agent.Release) error {
+	destination = name
+	loaded, err := loadConfig()
+	if err != nil {
+		return err
+	}
+	cfg = loaded
+
+	image := release.Repository + ":" + release.Tag
+	log := output.DefaultLogger
+	return runUnattendedDeploy(ctx, log, &deploy.DeployOptions{
+		Version:     release.Tag,
+		Digest:      release.Digest,
+		Destination: name,
+		Approve: func(plan *deploy.DeployPlan) (string, error) {
+			return "", fmt.Errorf("deploy.require_approval needs deploy.approval_webhook for webhook-triggered deploys")
+		},
+		ApprovalToken: os.Getenv("AZUD_APPROVAL_TOKEN"),
+		Metadata:      map[string]string{"webhook_image": image},
+	}, func(deployer *deploy.Deployer, opts *deploy.DeployOptions) error {
+		return deployer.Deploy(ctx, opts)
+	})
+}
+
+func serveTagsLabel(tags []string) string {
+	if len(tags) == 0 {
+		return "*"
+	}
+	return strings.Join(tags, ", ")
+}
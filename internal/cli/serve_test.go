@@ -0,0 +1,43 @@
+package cli
+
+import (
+	"testing"
+	"time"
+
+	"github.com/lemonity-org/azud/internal/agent"
+	"github.com/lemonity-org/azud/internal/config"
+	"github.com/lemonity-org/azud/internal/deploy"
+	"github.com/lemonity-org/azud/internal/output"
+)
+
+func TestServeListensOnLoopbackByDefault(t *testing.T) {
+	if listen := serveCmd.Flags().Lookup("listen").DefValue; !config.LoopbackListen(listen) {
+		t.Fatalf("azud serve listens on %s by default, want a loopback address", listen)
+	}
+}
+
+func TestServeCurrent(t *testing.T) {
+	t.Setenv("AZUD_STATE_DIR", t.TempDir())
+	history := deploy.NewDurableHistoryStore(20, output.DefaultLogger)
+	base := time.Date(2026, 2, 8, 12, 0, 0, 0, time.UTC)
+	deployed := newHistoryRecord("deploy_1", "app", "v1.2.3", "ghcr.io/acme/app:v1.2.3",
+		base, base.Add(time.Minute), deploy.StatusSuccess, []string{"10.0.0.1"})
+	deployed.Metadata["image_digest"] = "sha256:abc"
+	failed := newHistoryRecord("deploy_2", "app", "v1.3.0", "ghcr.io/acme/app:v1.3.0",
+		base.Add(time.Hour), base.Add(time.Hour+time.Minute), deploy.StatusFailed, []string{"10.0.0.1"})
+	for _, record := range []*deploy.DeploymentRecord{deployed, failed} {
+		if err := history.Record(record); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	current := serveCurrent(map[string]string{"production": "app", "staging": "app"}, output.DefaultLogger)
+	got, ok := current("production")
+	want := agent.Release{Repository: "ghcr.io/acme/app", Tag: "v1.2.3", Digest: "sha256:abc"}
+	if !ok || got != want {
+		t.Fatalf("current(production) = %+v, %v; want %+v", got, ok, want)
+	}
+	if _, ok := current("staging"); ok {
+		t.Fatal("current(staging) reported a release, want none")
+	}
+}
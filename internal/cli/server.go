@@ -2,11 +2,17 @@ package cli
 
 import (
 	"fmt"
+	"maps"
+	"os"
+	"slices"
 	"strings"
 
 	"github.com/spf13/cobra"
+	"golang.org/x/term"
 
+	"github.com/lemonity-org/azud/internal/config"
 	"github.com/lemonity-org/azud/internal/output"
+	"github.com/lemonity-org/azud/internal/podman"
 	"github.com/lemonity-org/azud/internal/server"
 	"github.com/lemonity-org/azud/internal/ssh"
 )
@@ -44,6 +50,20 @@ Example:
 	RunE: runServerExec,
 }
 
+var serverWireGuardCmd = &cobra.Command{
+	Use:   "wireguard",
+	Short: "Configure the WireGuard mesh between servers",
+	Long: `Install WireGuard and connect every application, accessory, and cron
+host in an encrypted wg0 mesh. Requires network.wireguard: true.
+
+Keys are generated on each host and never leave it. Re-run after adding or
+removing hosts to update peers everywhere.
+
+Example:
+  azud server wireguard`,
+	RunE: runServerWireGuard,
+}
+
 var (
 	serverExecHost string
 	serverExecRole string
@@ -53,6 +73,7 @@ func init() {
 	// Add server subcommands
 	serverCmd.AddCommand(serverBootstrapCmd)
 	serverCmd.AddCommand(serverExecCmd)
+	serverCmd.AddCommand(serverWireGuardCmd)
 
 	// Exec flags
 	serverExecCmd.Flags().StringVar(&serverExecHost, "host", "", "Specific host to execute on")
@@ -80,7 +101,7 @@ func runServerBootstrap(cmd *cobra.Command, args []string) error {
 	sshClient := createSSHClient()
 	defer func() { _ = sshClient.Close() }()
 
-	bootstrapper := server.NewBootstrapper(sshClient, output.DefaultLogger, cfg.Podman.NetworkBackend)
+	bootstrapper := newBootstrapper(sshClient, output.DefaultLogger)
 	if err := bootstrapper.BootstrapAll(hosts); err != nil {
 		return err
 	}
@@ -96,6 +117,43 @@ func runServerBootstrap(cmd *cobra.Command, args []string) error {
 	return nil
 }
 
+func runServerWireGuard(cmd *cobra.Command, args []string) error {
+	output.SetVerbose(verbose)
+
+	if !cfg.Network.WireGuard {
+		return fmt.Errorf("network.wireguard is not enabled")
+	}
+
+	sshClient := createSSHClient()
+	defer func() { _ = sshClient.Close() }()
+
+	return setupWireGuardMesh(sshClient, output.DefaultLogger)
+}
+
+func setupWireGuardMesh(sshClient *ssh.Client, log *output.Logger) error {
+	addresses, prefixLen, err := cfg.MeshAddresses()
+	if err != nil {
+		return err
+	}
+	if len(addresses) < 2 {
+		log.Info("WireGuard mesh needs at least two hosts, skipping")
+		return nil
+	}
+
+	bootstrapper := server.NewBootstrapper(sshClient, log, cfg.Podman.NetworkBackend)
+	if err := bootstrapper.SetupWireGuard(addresses, prefixLen, cfg.Network.Port); err != nil {
+		return err
+	}
+	if len(cfg.Network.Addresses) < len(addresses) {
+		log.Info("Pin these addresses so adding or removing hosts keeps them:")
+		log.Println("network:\n  addresses:")
+		for _, host := range slices.Sorted(maps.Keys(addresses)) {
+			log.Println("    %s: %s", host, addresses[host])
+		}
+	}
+	return nil
+}
+
 func runServerExec(cmd *cobra.Command, args []string) error {
 	output.SetVerbose(verbose)
 
@@ -148,6 +206,33 @@ func runServerExec(cmd *cobra.Command, args []string) error {
 	return nil
 }
 
+// newBootstrapper returns a Bootstrapper configured with the Podman network
+// settings from the loaded configuration.
+func newBootstrapper(sshClient *ssh.Client, log *output.Logger) *server.Bootstrapper {
+	bootstrapper := server.NewBootstrapper(sshClient, log, cfg.Podman.NetworkBackend)
+	bootstrapper.SetIPv6(cfg.Podman.IPv6)
+	bootstrapper.SetAge(cfg.UsesEncryptedSecrets())
+
+	var networks []podman.NetworkConfig
+	for _, name := range cfg.PodmanNetworkNames() {
+		network, ok := cfg.Podman.Networks[name]
+		if !ok {
+			continue
+		}
+		networks = append(networks, podman.NetworkConfig{
+			Name:       name,
+			Subnet:     network.Subnet,
+			Gateway:    network.Gateway,
+			Internal:   network.Internal,
+			IPv6:       network.IPv6,
+			DNS:        network.DNS,
+			DisableDNS: network.DisableDNS,
+		})
+	}
+	bootstrapper.SetNetworks(networks)
+	return bootstrapper
+}
+
 func createSSHClient() *ssh.Client {
 	sshConfig := &ssh.Config{
 		Context:                    rootCmd.Context(),
@@ -160,6 +245,18 @@ func createSSHClient() *ssh.Client {
 		InsecureIgnoreHostKey:      cfg.SSH.InsecureIgnoreHostKey,
 		TrustedHostFingerprints:    cfg.SSH.TrustedHostFingerprints,
 		RequireTrustedFingerprints: cfg.Security.RequireTrustedFingerprints,
+		PreferIPv6:                 cfg.SSH.PreferIPv6,
+		MaxSessions:                cfg.SSH.MaxConcurrent,
+		MaxSessionsPerHost:         cfg.SSH.MaxConcurrentPerHost,
+		Retry: ssh.RetryPolicy{
+			Attempts:   cfg.SSH.Retry.Attempts,
+			Backoff:    cfg.SSH.Retry.Backoff,
+			MaxBackoff: cfg.SSH.Retry.MaxBackoff,
+			Jitter:     cfg.SSH.Retry.Jitter,
+		},
+		Passphrase:     sshKeyPassphrase,
+		AddKeysToAgent: cfg.SSH.AddKeysToAgent,
+		AgentSocket:    cfg.SSH.AgentSocket,
 	}
 
 	// Add proxy configuration if present
@@ -172,3 +269,22 @@ func createSSHClient() *ssh.Client {
 
 	return ssh.NewClient(sshConfig)
 }
+
+// sshKeyPassphrase returns the passphrase of the encrypted SSH key at path:
+// the ssh.key_passphrase_secret secret, or one typed in the terminal.
+func sshKeyPassphrase(path string) ([]byte, error) {
+	if name := cfg.SSH.KeyPassphraseSecret; name != "" {
+		if value, ok := config.GetSecret(name); ok && value != "" {
+			return []byte(value), nil
+		}
+		return nil, fmt.Errorf("secret %s with the key passphrase not found", name)
+	}
+	fd := int(os.Stdin.Fd())
+	if !term.IsTerminal(fd) {
+		return nil, fmt.Errorf("key is encrypted: set ssh.key_passphrase_secret, add the key to ssh-agent, or run in a terminal")
+	}
+	_, _ = fmt.Fprintf(os.Stderr, "Enter passphrase for %s: ", path)
+	passphrase, err := term.ReadPassword(fd)
+	_, _ = fmt.Fprintln(os.Stderr)
+	return passphrase, err
+}
@@ -4,8 +4,10 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"slices"
 	"sort"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/spf13/cobra"
@@ -15,7 +17,6 @@ import (
 	"github.com/lemonity-org/azud/internal/output"
 	"github.com/lemonity-org/azud/internal/podman"
 	"github.com/lemonity-org/azud/internal/proxy"
-	"github.com/lemonity-org/azud/internal/server"
 	"github.com/lemonity-org/azud/internal/shell"
 	"github.com/lemonity-org/azud/internal/ssh"
 )
@@ -26,7 +27,8 @@ var setupCmd = &cobra.Command{
 	Long: `Setup servers and deploy the application.
 
 This command performs a complete setup:
-  1. Bootstraps servers (installs Podman)
+  1. Bootstraps servers (installs Podman, and WireGuard when
+     network.wireguard is enabled)
   2. Logs into the container registry
   3. Starts the Caddy proxy
   4. Deploys accessories (databases, caches)
@@ -49,6 +51,7 @@ func init() {
 	setupCmd.Flags().BoolVar(&setupSkipBootstrap, "skip-bootstrap", false, "Skip server bootstrap")
 	setupCmd.Flags().BoolVar(&setupSkipProxy, "skip-proxy", false, "Skip proxy setup")
 	setupCmd.Flags().BoolVar(&setupSkipPush, "skip-push", false, "Skip pushing the image")
+	setupCmd.Flags().BoolVar(&deployOverride, "override", false, deployOverrideUsage)
 
 	rootCmd.AddCommand(setupCmd)
 }
@@ -72,7 +75,7 @@ func runSetup(cmd *cobra.Command, args []string) error {
 	// Step 1: Bootstrap servers
 	if !setupSkipBootstrap {
 		log.Header("01 / Bootstrap servers")
-		bootstrapper := server.NewBootstrapper(sshClient, log, cfg.Podman.NetworkBackend)
+		bootstrapper := newBootstrapper(sshClient, log)
 		if err := bootstrapper.BootstrapAll(hosts); err != nil {
 			return fmt.Errorf("bootstrap failed: %w", err)
 		}
@@ -89,6 +92,12 @@ func runSetup(cmd *cobra.Command, args []string) error {
 				return fmt.Errorf("failed to enable rootless Podman persistence: %s", strings.Join(lingerErrors, "; "))
 			}
 		}
+
+		if cfg.Network.WireGuard {
+			if err := setupWireGuardMesh(sshClient, log); err != nil {
+				return fmt.Errorf("wireguard setup failed: %w", err)
+			}
+		}
 	} else {
 		log.Info("Skipping bootstrap (--skip-bootstrap)")
 	}
@@ -102,33 +111,25 @@ func runSetup(cmd *cobra.Command, args []string) error {
 
 	// Step 3: Registry login
 	log.Header("03 / Registry login")
-	if cfg.Registry.Username != "" {
+	logins, err := hostRegistryLogins(hosts)
+	if err != nil {
+		return err
+	}
+	if len(logins) > 0 {
 		podmanClient := podman.NewClient(sshClient)
 		registryManager := podman.NewRegistryManager(podmanClient)
 
-		password := getRegistryPassword()
-		if password == "" {
-			return fmt.Errorf("registry password not found for configured registry user")
-		} else {
-			regConfig := &podman.RegistryConfig{
-				Server:   cfg.Registry.Server,
-				Username: cfg.Registry.Username,
-				Password: password,
-			}
-
-			errors := registryManager.LoginAll(hosts, regConfig)
-			if len(errors) > 0 {
-				var loginErrors []string
-				for host, err := range errors {
-					log.HostError(host, "login failed: %v", err)
-					loginErrors = append(loginErrors, fmt.Sprintf("%s: %v", host, err))
-				}
-				sort.Strings(loginErrors)
-				return fmt.Errorf("registry login failed: %s", strings.Join(loginErrors, "; "))
-			} else {
-				log.Success("Registry login complete")
+		errors := registryManager.LoginHosts(logins)
+		if len(errors) > 0 {
+			var loginErrors []string
+			for host, err := range errors {
+				log.HostError(host, "login failed: %v", err)
+				loginErrors = append(loginErrors, fmt.Sprintf("%s: %v", host, err))
 			}
+			sort.Strings(loginErrors)
+			return fmt.Errorf("registry login failed: %s", strings.Join(loginErrors, "; "))
 		}
+		log.Success("Registry login complete")
 	} else {
 		log.Info("No registry configured, skipping login")
 	}
@@ -136,6 +137,12 @@ func runSetup(cmd *cobra.Command, args []string) error {
 	// Step 4: Start proxy
 	if !setupSkipProxy {
 		log.Header("04 / Start proxy")
+		// Records go first so the proxy's ACME challenges reach it.
+		if cfg.DNS.Enabled() {
+			if err := syncDNSRecords(cmd.Context(), log); err != nil {
+				return fmt.Errorf("DNS setup failed: %w", err)
+			}
+		}
 		proxyManager := proxy.NewManagerWithOptions(sshClient, log, cfg.SSH.User, cfg.Proxy.Rootful, cfg.UseHostPortUpstreams())
 
 		proxyConfig := &proxy.ProxyConfig{
@@ -148,6 +155,18 @@ func runSetup(cmd *cobra.Command, args []string) error {
 			LoggingEnabled:        cfg.Proxy.Logging.Enabled,
 			RedactRequestHeaders:  cfg.Proxy.Logging.RedactRequestHeaders,
 			RedactResponseHeaders: cfg.Proxy.Logging.RedactResponseHeaders,
+			ProxyProtocol:         cfg.Proxy.ProxyProtocol,
+			ProxyProtocolAllow:    cfg.Proxy.ProxyProtocolAllow,
+			AppSocketsDir:         deploy.AppSocketsDir(cfg),
+			Image:                 deploy.ProxyImage(cfg),
+			Streams:               deploy.ProxyStreams(cfg),
+			Bind:                  deploy.ProxyBind(cfg),
+			GeoIP:                 deploy.ProxyGeoIP(cfg),
+			BootTimeout:           cfg.Proxy.GetBootTimeout(),
+			Memory:                cfg.Proxy.Resources.Memory,
+			CPUs:                  cfg.Proxy.Resources.CPUs,
+			LogDriver:             cfg.Proxy.LogDriver,
+			LogOpts:               cfg.Proxy.LogOpts(),
 		}
 		if hosts := cfg.Proxy.AllHosts(); len(hosts) > 0 {
 			proxyConfig.Hosts = hosts
@@ -168,9 +187,13 @@ func runSetup(cmd *cobra.Command, args []string) error {
 
 		var proxyErrors []string
 		proxyHosts := cfg.GetRoleHosts("web")
+		if cfg.UsesProxyTier() {
+			proxyHosts = cfg.GetProxyHosts()
+		}
 		if len(proxyHosts) == 0 {
 			return fmt.Errorf("proxy setup requires a web role")
 		}
+		seedProxyCertificates(proxyManager, proxyHosts, log)
 		for _, host := range proxyHosts {
 			if err := proxyManager.Boot(host, proxyConfig); err != nil {
 				log.HostError(host, "proxy boot failed: %v", err)
@@ -207,7 +230,10 @@ func runSetup(cmd *cobra.Command, args []string) error {
 	deployer := deploy.NewDeployer(cfg, sshClient, log)
 
 	opts := &deploy.DeployOptions{
-		SkipPull: false,
+		SkipPull:      false,
+		Approve:       deployApprover(cmd),
+		ApprovalToken: os.Getenv("AZUD_APPROVAL_TOKEN"),
+		Override:      deployOverride,
 	}
 
 	if err := deployer.Deploy(cmd.Context(), opts); err != nil {
@@ -246,26 +272,6 @@ func setupRuntimeHosts() []string {
 	return hosts
 }
 
-func getRegistryPassword() string {
-	if len(cfg.Registry.Password) == 0 {
-		return ""
-	}
-
-	secretKey := cfg.Registry.Password[0]
-
-	// Try environment first
-	if val := os.Getenv(secretKey); val != "" {
-		return val
-	}
-
-	// Try secrets file (loaded by config loader)
-	if val, ok := config.GetSecret(secretKey); ok && val != "" {
-		return val
-	}
-
-	return ""
-}
-
 func deployAccessories(sshClient *ssh.Client, log *output.Logger, selectedNames ...string) error {
 	return deployAccessoriesOnHost(sshClient, log, "", selectedNames...)
 }
@@ -280,6 +286,7 @@ func deployAccessoriesOnHost(sshClient *ssh.Client, log *output.Logger, selected
 		names = cfg.GetAccessoryNames()
 	}
 	var errs []string
+	var targets []accessoryTarget
 	for _, name := range names {
 		accessory, ok := cfg.Accessories[name]
 		if !ok {
@@ -308,139 +315,191 @@ func deployAccessoriesOnHost(sshClient *ssh.Client, log *output.Logger, selected
 			errs = append(errs, fmt.Sprintf("%s: no hosts configured", name))
 			continue
 		}
-		for _, host := range hosts {
+		if err := provisionCacheTLS(sshClient, log, name); err != nil {
+			log.Error("Accessory %s: %v", name, err)
+			errs = append(errs, fmt.Sprintf("%s: %v", name, err))
+			continue
+		}
+		targets = append(targets, accessoryTargets(name, hosts)...)
+	}
 
-			if len(accessory.Env.Secret) > 0 {
-				if err := ensureRemoteSecretsFile(sshClient, []string{host}, accessory.Env.Secret); err != nil {
-					log.HostError(host, "Missing secrets for accessory %s: %v", name, err)
-					errs = append(errs, fmt.Sprintf("%s@%s: missing secrets: %v", name, host, err))
-					continue
-				}
-			}
+	errs = append(errs, forEachAccessoryTarget(targets, func(target accessoryTarget) error {
+		return bootAccessory(sshClient, containerManager, imageManager, log, target.Name, target.Host)
+	})...)
 
-			// Check if already running
-			containerName := fmt.Sprintf("%s-%s", cfg.Service, name)
-			running, err := containerManager.IsRunning(host, containerName)
-			if err != nil {
-				errs = append(errs, fmt.Sprintf("%s@%s inspect: %v", name, host, err))
-				continue
-			}
-			if running {
-				log.HostSuccess(host, "Accessory %s already running", name)
-				continue
-			}
+	if len(errs) > 0 {
+		return fmt.Errorf("%d accessory(ies) failed: %s", len(errs), strings.Join(errs, "; "))
+	}
+	return nil
+}
 
-			// `accessory boot` means start, not recreate. A stopped container still
-			// owns its name, so attempting `podman run` would fail and, more
-			// importantly, would discard the accessory's existing writable layer.
-			exists, err := containerManager.Exists(host, containerName)
-			if err != nil {
-				errs = append(errs, fmt.Sprintf("%s@%s inspect: %v", name, host, err))
-				continue
-			}
-			if exists {
-				if err := containerManager.Start(host, containerName); err != nil {
-					log.HostError(host, "Failed to start %s: %v", name, err)
-					errs = append(errs, fmt.Sprintf("%s@%s: %v", name, host, err))
-					continue
-				}
-				if bootTimeout := accessory.GetBootTimeout(); bootTimeout > 0 {
-					if err := verifyAccessoryHealth(containerManager, host, containerName, name, bootTimeout, log); err != nil {
-						log.HostError(host, "%v", err)
-						errs = append(errs, fmt.Sprintf("%s@%s: %v", name, host, err))
-						continue
-					}
-				}
-				log.HostSuccess(host, "Accessory %s started", name)
-				continue
-			}
+// bootAccessory starts an accessory on host, creating its container when
+// none exists yet.
+func bootAccessory(sshClient *ssh.Client, containerManager *podman.ContainerManager, imageManager *podman.ImageManager, log *output.Logger, name, host string) error {
+	accessory := cfg.Accessories[name]
+	// Uploaded files add volumes; keep them out of the shared configuration.
+	accessory.Volumes = slices.Clone(accessory.Volumes)
+
+	if len(accessory.Env.Secret) > 0 {
+		if err := ensureRemoteSecretsFile(sshClient, []string{host}, accessory.Env.Secret); err != nil {
+			log.HostError(host, "Missing secrets for accessory %s: %v", name, err)
+			return fmt.Errorf("missing secrets: %w", err)
+		}
+	}
 
-			if err := provisionAccessoryDirectories(sshClient, host, accessory.Directories); err != nil {
-				log.HostError(host, "Failed to provision directories for %s: %v", name, err)
-				errs = append(errs, fmt.Sprintf("%s@%s: %v", name, host, err))
-				continue
-			}
-			// Upload files and add as volume mounts
-			if err := uploadAccessoryFiles(sshClient, host, name, &accessory, log); err != nil {
-				log.HostError(host, "Failed to provision files for %s: %v", name, err)
-				errs = append(errs, fmt.Sprintf("%s@%s: %v", name, host, err))
-				continue
-			}
+	// Check if already running
+	containerName := fmt.Sprintf("%s-%s", cfg.Service, name)
+	running, err := containerManager.IsRunning(host, containerName)
+	if err != nil {
+		return fmt.Errorf("inspect: %w", err)
+	}
+	if running {
+		log.HostSuccess(host, "Accessory %s already running", name)
+		return nil
+	}
 
-			// Build container config
-			containerConfig := &podman.ContainerConfig{
-				Name:    containerName,
-				Image:   accessory.Image,
-				Detach:  true,
-				Restart: "unless-stopped",
-				Network: "azud",
-				Labels: map[string]string{
-					"azud.managed":   "true",
-					"azud.service":   cfg.Service,
-					"azud.accessory": name,
-				},
-				Env:     make(map[string]string),
-				Volumes: accessory.Volumes,
+	// `accessory boot` means start, not recreate. A stopped container still
+	// owns its name, so attempting `podman run` would fail and, more
+	// importantly, would discard the accessory's existing writable layer.
+	exists, err := containerManager.Exists(host, containerName)
+	if err != nil {
+		return fmt.Errorf("inspect: %w", err)
+	}
+	if exists {
+		if err := containerManager.Start(host, containerName); err != nil {
+			log.HostError(host, "Failed to start %s: %v", name, err)
+			return err
+		}
+		if bootTimeout := accessory.GetBootTimeout(); bootTimeout > 0 {
+			if err := verifyAccessoryHealth(containerManager, host, containerName, name, bootTimeout, accessory.Healthcheck.IsSet(), log); err != nil {
+				log.HostError(host, "%v", err)
+				return err
 			}
+		}
+		log.HostSuccess(host, "Accessory %s started", name)
+		return nil
+	}
 
-			// Add port mapping
-			if accessory.Port != "" {
-				containerConfig.Ports = []string{accessory.Port}
-			}
+	if err := provisionAccessoryDirectories(sshClient, host, accessory.Directories); err != nil {
+		log.HostError(host, "Failed to provision directories for %s: %v", name, err)
+		return err
+	}
+	// Upload files and add as volume mounts
+	if err := uploadAccessoryFiles(sshClient, host, name, &accessory, log); err != nil {
+		log.HostError(host, "Failed to provision files for %s: %v", name, err)
+		return err
+	}
 
-			// Add environment variables
-			for key, value := range accessory.Env.Clear {
-				containerConfig.Env[key] = value
-			}
-			containerConfig.SecretEnv = accessory.Env.Secret
-			if len(containerConfig.SecretEnv) > 0 {
-				containerConfig.EnvFile = config.RemoteSecretsPath(cfg)
-			}
+	// Build container config
+	containerConfig := &podman.ContainerConfig{
+		Name:     containerName,
+		Image:    accessory.Image,
+		Detach:   true,
+		Restart:  "unless-stopped",
+		Network:  cfg.AccessoryNetworks(name)[0],
+		Networks: cfg.AccessoryNetworks(name),
+		Labels: map[string]string{
+			"azud.managed":   "true",
+			"azud.service":   cfg.Service,
+			"azud.accessory": name,
+		},
+		Env:     make(map[string]string),
+		Volumes: cfg.LabelVolumes(accessory.Volumes),
+	}
 
-			// Add command if specified
-			// Split command into arguments to preserve proper entrypoint behavior
-			// (e.g., postgres needs to detect it's being run as 'postgres' to drop privileges)
-			if accessory.Cmd != "" {
-				containerConfig.Command = deploy.ParseCommandArgs(accessory.Cmd)
-			}
-			containerConfig.Memory = accessory.Options["memory"]
-			containerConfig.CPUs = accessory.Options["cpus"]
+	// Add port mapping
+	if accessory.Port != "" {
+		containerConfig.Ports = []string{accessory.Port}
+	}
 
-			// Pull image
-			if err := imageManager.Pull(host, accessory.Image); err != nil {
-				log.HostError(host, "Failed to pull image for %s: %v", name, err)
-				errs = append(errs, fmt.Sprintf("%s@%s: %v", name, host, err))
-				continue
-			}
+	// Add environment variables
+	for key, value := range accessory.Env.Clear {
+		containerConfig.Env[key] = value
+	}
+	deploy.AttachSecrets(cfg, containerConfig, accessory.Env.Secret, config.RemoteSecretsPath(cfg))
+
+	// Add command if specified
+	// Split command into arguments to preserve proper entrypoint behavior
+	// (e.g., postgres needs to detect it's being run as 'postgres' to drop privileges)
+	if accessory.Cmd != "" {
+		containerConfig.Command = deploy.ParseCommandArgs(accessory.Cmd)
+	} else if accessory.Profile != "" {
+		containerConfig.Command = accessory.ProfileCommand(profileMemory(sshClient, host, accessory, log))
+	}
+	containerConfig.Memory = accessory.Options["memory"]
+	containerConfig.CPUs = accessory.Options["cpus"]
+	containerConfig.User = accessory.User
+	containerConfig.Entrypoint = accessory.Entrypoint
+	containerConfig.Init = accessory.Init
+	containerConfig.StopSignal = accessory.StopSignal
+	if accessory.StopTimeout > 0 {
+		containerConfig.StopTimeout = accessory.GetStopTimeout()
+	}
+	applyAccessoryHealthcheck(containerConfig, accessory)
 
-			// Run container
-			_, err = containerManager.Run(host, containerConfig)
-			if err != nil {
-				log.HostError(host, "Failed to start %s: %v", name, err)
-				errs = append(errs, fmt.Sprintf("%s@%s: %v", name, host, err))
-				continue
-			}
+	// Pull image
+	if err := imageManager.Pull(host, accessory.Image); err != nil {
+		log.HostError(host, "Failed to pull image for %s: %v", name, err)
+		return err
+	}
 
-			// Verify accessory is running and healthy
-			bootTimeout := accessory.GetBootTimeout()
-			if bootTimeout > 0 {
-				if err := verifyAccessoryHealth(containerManager, host, containerName, name, bootTimeout, log); err != nil {
-					log.HostError(host, "%v", err)
-					errs = append(errs, fmt.Sprintf("%s@%s: %v", name, host, err))
-					continue
-				}
-			}
+	// Run container
+	_, err = containerManager.Run(host, containerConfig)
+	if err != nil {
+		log.HostError(host, "Failed to start %s: %v", name, err)
+		return err
+	}
 
-			log.HostSuccess(host, "Accessory %s deployed", name)
+	// Verify accessory is running and healthy
+	bootTimeout := accessory.GetBootTimeout()
+	if bootTimeout > 0 {
+		if err := verifyAccessoryHealth(containerManager, host, containerName, name, bootTimeout, accessory.Healthcheck.IsSet(), log); err != nil {
+			log.HostError(host, "%v", err)
+			return err
 		}
 	}
 
-	if len(errs) > 0 {
-		return fmt.Errorf("%d accessory(ies) failed: %s", len(errs), strings.Join(errs, "; "))
-	}
+	log.HostSuccess(host, "Accessory %s deployed", name)
 	return nil
 }
 
+// accessoryTarget is one accessory on one of its hosts.
+type accessoryTarget struct {
+	Name string
+	Host string
+}
+
+func accessoryTargets(name string, hosts []string) []accessoryTarget {
+	targets := make([]accessoryTarget, len(hosts))
+	for i, host := range hosts {
+		targets[i] = accessoryTarget{Name: name, Host: host}
+	}
+	return targets
+}
+
+// forEachAccessoryTarget runs fn for every target in parallel and returns
+// the failures, in target order, as name@host: error.
+func forEachAccessoryTarget(targets []accessoryTarget, fn func(accessoryTarget) error) []string {
+	results := make([]error, len(targets))
+	var wg sync.WaitGroup
+	for i, target := range targets {
+		wg.Add(1)
+		go func(i int, target accessoryTarget) {
+			defer wg.Done()
+			results[i] = fn(target)
+		}(i, target)
+	}
+	wg.Wait()
+
+	var errs []string
+	for i, err := range results {
+		if err != nil {
+			errs = append(errs, fmt.Sprintf("%s@%s: %v", targets[i].Name, targets[i].Host, err))
+		}
+	}
+	return errs
+}
+
 func accessoryHosts(accessory config.AccessoryConfig) []string {
 	seen := make(map[string]struct{})
 	var hosts []string
@@ -473,10 +532,61 @@ func provisionAccessoryDirectories(sshClient *ssh.Client, host string, directori
 // verifyAccessoryHealth checks that an accessory container is running and healthy.
 // It first waits for a brief stabilization period to catch immediate crashes,
 // then checks for a Podman HEALTHCHECK and waits for it if present.
+// profileMemory returns the memory a profiled accessory is tuned for: its
+// memory option, or else the host's RAM. It returns 0 when neither is known.
+func profileMemory(sshClient *ssh.Client, host string, accessory config.AccessoryConfig, log *output.Logger) int64 {
+	if memory, ok := parseMemory(accessory.Options["memory"]); ok {
+		return memory
+	}
+	capacity, err := gatherHostCapacity(sshClient, host)
+	if err != nil {
+		log.Warn("Failed to read memory of %s, keeping default Postgres memory settings: %v", host, err)
+		return 0
+	}
+	return capacity.MemTotal
+}
+
+// applyAccessoryHealthcheck sets an accessory's configured healthcheck as
+// the container's. Failures during the boot timeout do not count, so a slow
+// first start is not marked unhealthy.
+func applyAccessoryHealthcheck(containerConfig *podman.ContainerConfig, accessory config.AccessoryConfig) {
+	h := accessory.Healthcheck
+	if !h.IsSet() {
+		return
+	}
+	containerConfig.HealthCmd = accessoryHealthCommand(h)
+	containerConfig.HealthInterval = "10s"
+	if h.Interval > 0 {
+		containerConfig.HealthInterval = h.Interval.String()
+	}
+	containerConfig.HealthTimeout = "5s"
+	if h.Timeout > 0 {
+		containerConfig.HealthTimeout = h.Timeout.String()
+	}
+	containerConfig.HealthRetries = 3
+	if bootTimeout := accessory.GetBootTimeout(); bootTimeout > 0 {
+		containerConfig.HealthStartPeriod = bootTimeout.String()
+	}
+}
+
+// accessoryHealthCommand returns the healthcheck command for h. A port
+// check looks for a listening socket in /proc/net, which needs only sh,
+// cat, and grep in the image.
+func accessoryHealthCommand(h config.AccessoryHealthcheck) string {
+	if h.Cmd != "" {
+		return h.Cmd
+	}
+	return fmt.Sprintf("cat /proc/net/tcp /proc/net/tcp6 2>/dev/null | grep -Eq '^ *[0-9]+: [0-9A-F]+:%04X [0-9A-F]+:[0-9A-F]+ 0A '", h.Port)
+}
+
+// verifyAccessoryHealth waits for an accessory to keep running and then to
+// pass its healthcheck. A configured healthcheck is run until it passes;
+// one defined by the image is awaited on Podman's schedule.
 func verifyAccessoryHealth(
 	containerManager *podman.ContainerManager,
 	host, containerName, accessoryName string,
 	timeout time.Duration,
+	configuredCheck bool,
 	log *output.Logger,
 ) error {
 	// Phase 1: Stabilization check
@@ -503,6 +613,12 @@ func verifyAccessoryHealth(
 		remaining = 1 * time.Second
 	}
 	log.Host(host, "Waiting for %s healthcheck (timeout: %s)...", accessoryName, remaining)
+	if configuredCheck {
+		if err := containerManager.WaitHealthcheck(host, containerName, remaining); err != nil {
+			return fmt.Errorf("accessory %s health check failed: %w", accessoryName, err)
+		}
+		return nil
+	}
 	if err := containerManager.WaitHealthy(host, containerName, remaining); err != nil {
 		return fmt.Errorf("accessory %s health check failed: %w", accessoryName, err)
 	}
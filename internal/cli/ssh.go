@@ -13,7 +13,9 @@ import (
 	"github.com/mattn/go-isatty"
 	"github.com/spf13/cobra"
 
+	"github.com/lemonity-org/azud/internal/config"
 	"github.com/lemonity-org/azud/internal/output"
+	"github.com/lemonity-org/azud/internal/ssh"
 )
 
 var sshCmd = &cobra.Command{
@@ -35,23 +37,50 @@ Examples:
 	RunE: runSSHTrust,
 }
 
+var trustCmd = &cobra.Command{
+	Use:   "trust [hosts...]",
+	Short: "Learn and record SSH host keys",
+	Long: `Connect to deployment hosts, show their host key fingerprints, and record
+the confirmed keys in known_hosts and, with --save-config, in
+ssh.trusted_host_fingerprints of the configuration file. This is the same
+command as 'azud ssh trust'.
+
+With --save-config the fingerprints are written to the destination file
+when --destination is set, otherwise to the main configuration file. Only
+the lines of the trusted_host_fingerprints block change.
+
+Examples:
+  azud trust 1.2.3.4                      # Confirm and add to known_hosts
+  azud trust --save-config                # Also pin all hosts in the config
+  azud trust --save-config --skip-known-hosts
+  azud trust --save-config -d staging     # Pin in config/deploy.staging.yml`,
+	RunE: runSSHTrust,
+}
+
 var (
-	sshTrustRole     string
-	sshTrustRefresh  bool
-	sshTrustPrint    bool
-	sshTrustTemplate bool
-	sshTrustYes      bool
+	sshTrustRole           string
+	sshTrustRefresh        bool
+	sshTrustPrint          bool
+	sshTrustTemplate       bool
+	sshTrustYes            bool
+	sshTrustSaveConfig     bool
+	sshTrustSkipKnownHosts bool
 )
 
 func init() {
-	sshTrustCmd.Flags().StringVar(&sshTrustRole, "role", "", "Trust hosts for a specific role")
-	sshTrustCmd.Flags().BoolVar(&sshTrustRefresh, "refresh", false, "Refresh existing host keys")
-	sshTrustCmd.Flags().BoolVar(&sshTrustPrint, "print", false, "Print host fingerprints without writing known_hosts")
-	sshTrustCmd.Flags().BoolVar(&sshTrustTemplate, "template", false, "Print YAML snippet for trusted_host_fingerprints")
-	sshTrustCmd.Flags().BoolVar(&sshTrustYes, "yes", false, "Trust without prompting for confirmation")
+	for _, cmd := range []*cobra.Command{sshTrustCmd, trustCmd} {
+		cmd.Flags().StringVar(&sshTrustRole, "role", "", "Trust hosts for a specific role")
+		cmd.Flags().BoolVar(&sshTrustRefresh, "refresh", false, "Refresh existing host keys")
+		cmd.Flags().BoolVar(&sshTrustPrint, "print", false, "Print host fingerprints without writing known_hosts")
+		cmd.Flags().BoolVar(&sshTrustTemplate, "template", false, "Print YAML snippet for trusted_host_fingerprints")
+		cmd.Flags().BoolVar(&sshTrustYes, "yes", false, "Trust without prompting for confirmation")
+		cmd.Flags().BoolVar(&sshTrustSaveConfig, "save-config", false, "Record the fingerprints in ssh.trusted_host_fingerprints of the config file")
+		cmd.Flags().BoolVar(&sshTrustSkipKnownHosts, "skip-known-hosts", false, "Do not write known_hosts (with --save-config)")
+	}
 
 	sshCmd.AddCommand(sshTrustCmd)
 	rootCmd.AddCommand(sshCmd)
+	rootCmd.AddCommand(trustCmd)
 }
 
 func runSSHTrust(cmd *cobra.Command, args []string) error {
@@ -77,18 +106,33 @@ func runSSHTrust(cmd *cobra.Command, args []string) error {
 	if sshTrustPrint || sshTrustTemplate {
 		return printSSHTrust(hosts, sshTrustTemplate)
 	}
+	if sshTrustSkipKnownHosts && !sshTrustSaveConfig {
+		return fmt.Errorf("--skip-known-hosts requires --save-config")
+	}
+
+	knownHosts := ssh.KnownHostsPath(cfg.SSH.KnownHostsFile)
 
-	knownHosts := cfg.SSH.KnownHostsFile
-	if knownHosts == "" {
-		knownHosts = filepath.Join(os.Getenv("HOME"), ".ssh", "known_hosts")
+	if !sshTrustSkipKnownHosts {
+		if err := os.MkdirAll(filepath.Dir(knownHosts), 0700); err != nil {
+			return fmt.Errorf("failed to create known_hosts directory: %w", err)
+		}
 	}
 
-	if err := os.MkdirAll(filepath.Dir(knownHosts), 0700); err != nil {
-		return fmt.Errorf("failed to create known_hosts directory: %w", err)
+	configFile := ""
+	if sshTrustSaveConfig {
+		configFile = trustConfigFile()
+		if configFile == "" {
+			return fmt.Errorf("no configuration file found to record fingerprints in")
+		}
 	}
 
 	log.Header("Trusting SSH Hosts")
-	log.Info("known_hosts: %s", knownHosts)
+	if !sshTrustSkipKnownHosts {
+		log.Info("known_hosts: %s", knownHosts)
+	}
+	if configFile != "" {
+		log.Info("config: %s", configFile)
+	}
 	var trustErrors []string
 
 	for _, host := range hosts {
@@ -97,15 +141,21 @@ func runSSHTrust(cmd *cobra.Command, args []string) error {
 			target = fmt.Sprintf("[%s]:%d", host, cfg.SSH.Port) // safe: known_hosts lookup key, not a shell command
 		}
 
+		// Learning fingerprints into the config is how hosts get pinned, so
+		// it is allowed for hosts that have none yet.
 		expected := expectedFingerprints(target, host)
-		if cfg.Security.RequireTrustedFingerprints && len(expected) == 0 {
+		if cfg.Security.RequireTrustedFingerprints && len(expected) == 0 && !sshTrustSaveConfig {
 			log.HostError(host, "No trusted fingerprint configured for %s", target)
 			trustErrors = append(trustErrors, fmt.Sprintf("%s: no trusted fingerprint configured", host))
 			continue
 		}
 
-		if !sshTrustRefresh {
-			if exists, _ := knownHostExists(knownHosts, target); exists {
+		if !sshTrustRefresh && (!sshTrustSaveConfig || len(expected) > 0) {
+			known := sshTrustSkipKnownHosts
+			if !known {
+				known, _ = knownHostExists(knownHosts, target)
+			}
+			if known {
 				log.HostSuccess(host, "Already trusted")
 				continue
 			}
@@ -153,18 +203,20 @@ func runSSHTrust(cmd *cobra.Command, args []string) error {
 			}
 		}
 
-		if sshTrustRefresh {
-			if err := removeKnownHost(knownHosts, target); err != nil {
-				log.HostError(host, "failed to remove existing key: %v", err)
-				trustErrors = append(trustErrors, fmt.Sprintf("%s: remove existing key: %v", host, err))
+		if configFile != "" && len(expected) == 0 {
+			if err := config.SetTrustedHostFingerprints(configFile, target, fingerprints); err != nil {
+				log.HostError(host, "failed to record fingerprints: %v", err)
+				trustErrors = append(trustErrors, fmt.Sprintf("%s: record fingerprints: %v", host, err))
 				continue
 			}
 		}
 
-		if err := appendKnownHost(knownHosts, key); err != nil {
-			log.HostError(host, "failed to write known_hosts: %v", err)
-			trustErrors = append(trustErrors, fmt.Sprintf("%s: write known_hosts: %v", host, err))
-			continue
+		if !sshTrustSkipKnownHosts {
+			if err := writeKnownHost(knownHosts, target, key); err != nil {
+				log.HostError(host, "%v", err)
+				trustErrors = append(trustErrors, fmt.Sprintf("%s: %v", host, err))
+				continue
+			}
 		}
 
 		log.HostSuccess(host, "Trusted")
@@ -177,6 +229,39 @@ func runSSHTrust(cmd *cobra.Command, args []string) error {
 	return nil
 }
 
+// writeKnownHost adds key to known_hosts, replacing the keys of target with
+// --refresh.
+func writeKnownHost(knownHosts, target, key string) error {
+	if sshTrustRefresh {
+		if err := removeKnownHost(knownHosts, target); err != nil {
+			return fmt.Errorf("failed to remove existing key: %w", err)
+		}
+	} else if exists, _ := knownHostExists(knownHosts, target); exists {
+		return nil
+	}
+	if err := appendKnownHost(knownHosts, key); err != nil {
+		return fmt.Errorf("failed to write known_hosts: %w", err)
+	}
+	return nil
+}
+
+// trustConfigFile returns the configuration file --save-config records
+// fingerprints in: the destination file when a destination is selected and
+// has one, otherwise the main file.
+func trustConfigFile() string {
+	path := GetConfigPath()
+	if path == "" {
+		return ""
+	}
+	if destination != "" {
+		destPath := config.DestinationPath(path, destination)
+		if _, err := os.Stat(destPath); err == nil {
+			return destPath
+		}
+	}
+	return path
+}
+
 func confirmTrust(host, target string, fingerprints []string) (bool, error) {
 	reader := bufio.NewReader(os.Stdin)
 
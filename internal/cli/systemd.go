@@ -2,11 +2,15 @@ package cli
 
 import (
 	"fmt"
+	"maps"
+	"path"
+	"slices"
 	"sort"
 	"strings"
 
 	"github.com/spf13/cobra"
 
+	"github.com/lemonity-org/azud/internal/config"
 	"github.com/lemonity-org/azud/internal/deploy"
 	"github.com/lemonity-org/azud/internal/output"
 	"github.com/lemonity-org/azud/internal/podman"
@@ -53,6 +57,10 @@ func runSystemdEnable(cmd *cobra.Command, args []string) error {
 	log := output.DefaultLogger
 	hasErrors := false
 
+	if cfg.UsesEncryptedSecrets() && !systemdSkipApp {
+		return fmt.Errorf("systemd units need a plaintext env file; use secrets_storage env_file or podman, or pass --skip-app")
+	}
+
 	targets, err := getSystemdTargets()
 	if err != nil {
 		return err
@@ -101,19 +109,21 @@ func runSystemdEnable(cmd *cobra.Command, args []string) error {
 	// proxy units run in bridge mode and need azud DNS/network access.
 	needsAzudNetwork := needsAzudNetworkUnit(systemdSkipApp, systemdSkipProxy)
 	if needsAzudNetwork {
-		networkUnitName := "azud.network"
-		networkServiceName := "azud-network"
-		netFile := quadlet.GenerateNetworkFile("azud", true)
-		for _, host := range hosts {
-			if err := appDeployer.Deploy(host, networkUnitName, netFile); err != nil {
-				log.HostError(host, "Failed to deploy network unit: %v", err)
-				hasErrors = true
-				continue
-			}
-			if !systemdNoStart {
-				if err := appDeployer.Start(host, networkServiceName); err != nil {
-					log.HostError(host, "Failed to start network unit: %v", err)
+		for _, network := range cfg.PodmanNetworkNames() {
+			networkUnitName := network + ".network"
+			networkServiceName := network + "-network"
+			netFile := quadletNetworkFile(network)
+			for _, host := range hosts {
+				if err := appDeployer.Deploy(host, networkUnitName, netFile); err != nil {
+					log.HostError(host, "Failed to deploy network unit %s: %v", network, err)
 					hasErrors = true
+					continue
+				}
+				if !systemdNoStart {
+					if err := appDeployer.Start(host, networkServiceName); err != nil {
+						log.HostError(host, "Failed to start network unit %s: %v", network, err)
+						hasErrors = true
+					}
 				}
 			}
 		}
@@ -123,11 +133,19 @@ func runSystemdEnable(cmd *cobra.Command, args []string) error {
 	appContainers := podman.NewContainerManager(podman.NewClient(sshClient))
 
 	if !systemdSkipApp {
-		if err := ensureRemoteSecretsFile(sshClient, hosts, cfg.Env.Secret); err != nil {
-			return err
+		for _, target := range targets {
+			// systemd reads the env file itself, so nothing could compose
+			// values that reference secrets.
+			containerCfg := deploy.NewAppContainerConfig(cfg, image, deploy.RoleContainerName(cfg, target.Role), target.Role, target.Host, nil)
+			if len(containerCfg.EnvTemplates) > 0 {
+				return fmt.Errorf("the %s role's environment references secrets (%s), which Quadlet units cannot resolve", target.Role, strings.Join(slices.Sorted(maps.Keys(containerCfg.EnvTemplates)), ", "))
+			}
+			if err := ensureRoleSecretsFile(sshClient, []string{target.Host}, target.Role); err != nil {
+				return err
+			}
 		}
 		for _, target := range targets {
-			appUnit := buildAppQuadletUnit(image, target.Role)
+			appUnit := buildAppQuadletUnit(image, target.Host, target.Role)
 			serviceName := deploy.RoleContainerName(cfg, target.Role)
 			if cfg.UseHostPortUpstreams() && deploy.IsProxyRole(target.Role) {
 				hostPort, err := appContainers.HostPort(target.Host, serviceName, cfg.Proxy.AppPort)
@@ -138,6 +156,27 @@ func runSystemdEnable(cmd *cobra.Command, args []string) error {
 				}
 				pinQuadletHostPort(appUnit, hostPort, cfg.Proxy.AppPort)
 			}
+			if cfg.UsesAppSocket() && deploy.IsProxyRole(target.Role) {
+				socketDir, err := appContainers.MountSource(target.Host, serviceName, path.Dir(cfg.Proxy.AppSocket))
+				if err == nil && socketDir == "" {
+					err = fmt.Errorf("no socket directory is mounted at %s", path.Dir(cfg.Proxy.AppSocket))
+				}
+				if err != nil {
+					log.HostError(target.Host, "Failed to preserve socket directory for %s: %v", target.Role, err)
+					hasErrors = true
+					continue
+				}
+				pinQuadletSocketDir(appUnit, socketDir)
+			}
+			if cfg.UsesProxyTier() && deploy.IsProxyRole(target.Role) {
+				hostPort, err := appContainers.HostPort(target.Host, serviceName, cfg.Proxy.AppPort)
+				if err != nil {
+					log.HostError(target.Host, "Failed to preserve private port for %s: %v", target.Role, err)
+					hasErrors = true
+					continue
+				}
+				appUnit.PublishPort = []string{podman.PortSpec(cfg.PrivateAddress(target.Host), hostPort, cfg.Proxy.AppPort)}
+			}
 			unitName := fmt.Sprintf("%s.container", serviceName)
 			if err := appDeployer.Deploy(target.Host, unitName, quadlet.GenerateContainerFile(appUnit)); err != nil {
 				log.HostError(target.Host, "Failed to deploy %s app unit: %v", target.Role, err)
@@ -154,12 +193,12 @@ func runSystemdEnable(cmd *cobra.Command, args []string) error {
 	}
 
 	if !systemdSkipProxy && (systemdRole == "" || systemdRole == "web") && len(cfg.Proxy.AllHosts()) > 0 {
-		proxyUnit := buildProxyQuadletUnit()
 		unitName := fmt.Sprintf("%s.container", proxy.CaddyContainerName)
 		for _, host := range cfg.GetRoleHosts("web") {
 			if systemdHost != "" && host != systemdHost {
 				continue
 			}
+			proxyUnit := buildProxyQuadletUnit(host)
 			if err := proxyDeployer.Deploy(host, unitName, quadlet.GenerateContainerFile(proxyUnit)); err != nil {
 				log.HostError(host, "Failed to deploy proxy unit: %v", err)
 				hasErrors = true
@@ -258,8 +297,8 @@ func systemdSecretsPath(path string, rootless bool, user string) string {
 	return path
 }
 
-func buildAppQuadletUnit(image, role string) *quadlet.ContainerUnit {
-	containerCfg := deploy.NewAppContainerConfig(cfg, image, deploy.RoleContainerName(cfg, role), role, nil)
+func buildAppQuadletUnit(image, host, role string) *quadlet.ContainerUnit {
+	containerCfg := deploy.NewAppContainerConfig(cfg, image, deploy.RoleContainerName(cfg, role), role, host, nil)
 	after, requires := quadletNetworkOnlineDependencies(cfg.Podman.Rootless)
 
 	unit := &quadlet.ContainerUnit{
@@ -271,13 +310,14 @@ func buildAppQuadletUnit(image, role string) *quadlet.ContainerUnit {
 		Environment:   containerCfg.Env,
 		PublishPort:   containerCfg.Ports,
 		Volume:        containerCfg.Volumes,
-		// Refer to the Quadlet filename so systemd orders the container after
-		// the generated network unit. NetworkName=azud preserves the runtime
-		// network name used by imperative deployments.
-		Network:        []string{"azud.network"},
+		// Refer to the Quadlet filenames so systemd orders the container after
+		// the generated network units. NetworkName= preserves the runtime
+		// network names used by imperative deployments.
+		Network:        quadletNetworkUnits(cfg.RoleNetworks(role)),
 		Label:          containerCfg.Labels,
+		Secret:         containerCfg.Secrets,
 		Restart:        "always",
-		TimeoutStopSec: cfg.Deploy.GetStopTimeout(),
+		TimeoutStopSec: cfg.RoleStopTimeout(role),
 		WantedBy:       "default.target",
 	}
 
@@ -295,23 +335,42 @@ func buildAppQuadletUnit(image, role string) *quadlet.ContainerUnit {
 			unit.PodmanArgs = append(unit.PodmanArgs, "--cpus="+cpus)
 		}
 	}
+	unit.Entrypoint = containerCfg.Entrypoint
+	unit.PodmanArgs = append(unit.PodmanArgs, containerCfg.RuntimeArgs()...)
+	unit.PodmanArgs = append(unit.PodmanArgs, containerCfg.SecurityArgs()...)
 
 	return unit
 }
 
-func buildProxyQuadletUnit() *quadlet.ContainerUnit {
+func buildProxyQuadletUnit(host string) *quadlet.ContainerUnit {
 	proxyRootless := cfg.Podman.Rootless && !cfg.Proxy.Rootful
 	after, requires := quadletNetworkOnlineDependencies(proxyRootless)
 	network := []string{"azud.network"}
-	publishPorts := []string{
-		fmt.Sprintf("%d:80", cfg.Proxy.EffectiveHTTPPort()),
-		fmt.Sprintf("%d:443", cfg.Proxy.EffectiveHTTPSPort()),
-		fmt.Sprintf("127.0.0.1:%d:%d", proxy.CaddyAdminPort, proxy.CaddyAdminPort),
+	binds := cfg.ProxyBindAddresses(host)
+	if len(binds) == 0 {
+		binds = []string{""}
+	}
+	var publishPorts []string
+	for _, ip := range binds {
+		publishPorts = append(publishPorts,
+			podman.PortSpec(ip, cfg.Proxy.EffectiveHTTPPort(), proxy.CaddyHTTPPort),
+			podman.PortSpec(ip, cfg.Proxy.EffectiveHTTPSPort(), proxy.CaddyHTTPSPort),
+		)
+	}
+	publishPorts = append(publishPorts, fmt.Sprintf("127.0.0.1:%d:%d", proxy.CaddyAdminPort, proxy.CaddyAdminPort))
+	for _, stream := range cfg.Proxy.Streams {
+		for _, ip := range binds {
+			publishPorts = append(publishPorts, fmt.Sprintf("%s/%s", podman.PortSpec(ip, stream.Listen, stream.Listen), stream.Protocol))
+		}
 	}
 	if cfg.UseHostPortUpstreams() {
 		network = []string{"host"}
 		publishPorts = []string{}
 	}
+	image := proxy.CaddyImage
+	if custom := deploy.ProxyImage(cfg); custom != "" {
+		image = custom
+	}
 	adminListen := "0.0.0.0:2019" // safe: container-only; Quadlet publishes the admin port to host loopback
 	if cfg.UseHostPortUpstreams() {
 		adminListen = "127.0.0.1:2019"
@@ -323,15 +382,30 @@ func buildProxyQuadletUnit() *quadlet.ContainerUnit {
 		stateDir = "/home/" + cfg.SSH.User + "/.local/share/azud"
 	}
 
+	volumes := []string{proxy.CaddyDataVolume + ":/data", "caddy_config:/config", stateDir + ":/azud-state:ro,Z"}
+	if cfg.UsesAppSocket() {
+		volumes = append(volumes, stateDir+"/sockets:"+config.AppSocketsMount+":z")
+	}
+	if geo := cfg.Proxy.GeoIP; geo.Enabled() {
+		geoIPDir := stateDir + "/geoip"
+		if geo.Database != "" {
+			geoIPDir = path.Dir(geo.Database)
+		}
+		volumes = append(volumes, geoIPDir+":"+config.GeoIPMount+":ro,z")
+	}
+	if cfg.UsesStatic() {
+		volumes = append(volumes, stateDir+"/static:"+config.StaticMount+":ro,z")
+	}
+
 	unit := &quadlet.ContainerUnit{
 		Description:    "Azud Caddy proxy",
 		After:          after,
 		Requires:       requires,
-		Image:          proxy.CaddyImage,
+		Image:          image,
 		ContainerName:  proxy.CaddyContainerName,
 		Environment:    map[string]string{"CADDY_ADMIN": adminListen},
 		PublishPort:    publishPorts,
-		Volume:         []string{"caddy_data:/data", "caddy_config:/config", stateDir + ":/azud-state:ro,Z"},
+		Volume:         volumes,
 		Network:        network,
 		Label:          map[string]string{"azud.managed": "true", "azud.type": "proxy"},
 		Exec:           fmt.Sprintf("/bin/sh -c 'if [ -s /azud-state/%s ]; then exec caddy run --config /azud-state/%s --watch; else exec caddy run --config /etc/caddy/Caddyfile --adapter caddyfile --watch; fi'", proxy.CaddyConfigFileName, proxy.CaddyConfigFileName),
@@ -339,10 +413,52 @@ func buildProxyQuadletUnit() *quadlet.ContainerUnit {
 		TimeoutStopSec: 30,
 		WantedBy:       "default.target",
 	}
+	if memory := cfg.Proxy.Resources.Memory; memory != "" {
+		unit.PodmanArgs = append(unit.PodmanArgs, "--memory="+memory)
+	}
+	if cpus := cfg.Proxy.Resources.CPUs; cpus != "" {
+		unit.PodmanArgs = append(unit.PodmanArgs, "--cpus="+cpus)
+	}
+	logs := podman.ContainerConfig{LogDriver: cfg.Proxy.LogDriver, LogOpts: cfg.Proxy.LogOpts()}
+	unit.PodmanArgs = append(unit.PodmanArgs, logs.LogArgs()...)
 
 	return unit
 }
 
+// quadletNetworkFile renders the .network unit for a Podman network,
+// including any settings from podman.networks.
+func quadletNetworkFile(name string) string {
+	network := cfg.Podman.Networks[name]
+	netFile := quadlet.GenerateNetworkFile(name, !network.DisableDNS && len(network.DNS) == 0)
+	if network.Subnet != "" {
+		netFile += fmt.Sprintf("Subnet=%s\n", network.Subnet)
+	}
+	if network.Gateway != "" {
+		netFile += fmt.Sprintf("Gateway=%s\n", network.Gateway)
+	}
+	if network.Internal {
+		netFile += "Internal=true\n"
+	}
+	if network.IPv6 || name == config.DefaultNetworkName && cfg.Podman.IPv6 {
+		netFile += "IPv6=true\n"
+	}
+	if network.DisableDNS {
+		netFile += "DisableDNS=true\n"
+	}
+	for _, server := range network.DNS {
+		netFile += fmt.Sprintf("DNS=%s\n", server)
+	}
+	return netFile
+}
+
+func quadletNetworkUnits(networks []string) []string {
+	units := make([]string, 0, len(networks))
+	for _, network := range networks {
+		units = append(units, network+".network")
+	}
+	return units
+}
+
 // network-online.target belongs to the system manager and is not generally
 // available in a user's systemd manager. Quadlet already derives the ordering
 // for Network=*.network; keep the host-online dependency only for rootful
@@ -358,6 +474,17 @@ func pinQuadletHostPort(unit *quadlet.ContainerUnit, hostPort, containerPort int
 	unit.PublishPort = []string{fmt.Sprintf("127.0.0.1:%d:%d", hostPort, containerPort)}
 }
 
+// pinQuadletSocketDir keeps the socket directory of the running container,
+// which the proxy route already dials.
+func pinQuadletSocketDir(unit *quadlet.ContainerUnit, socketDir string) {
+	suffix := ":" + path.Dir(cfg.Proxy.AppSocket) + ":U,z"
+	for i, volume := range unit.Volume {
+		if strings.HasSuffix(volume, suffix) {
+			unit.Volume[i] = socketDir + suffix
+		}
+	}
+}
+
 func needsAzudNetworkUnit(skipApp, skipProxy bool) bool {
 	if cfg == nil {
 		return !skipApp || !skipProxy
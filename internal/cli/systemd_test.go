@@ -24,7 +24,7 @@ func TestBuildAppQuadletUnit_MixedModePublishesLoopbackPort(t *testing.T) {
 		},
 	}
 
-	unit := buildAppQuadletUnit("ghcr.io/acme/test:latest", "web")
+	unit := buildAppQuadletUnit("ghcr.io/acme/test:latest", "", "web")
 	want := []string{"127.0.0.1::3000"}
 	if !reflect.DeepEqual(unit.PublishPort, want) {
 		t.Fatalf("unexpected publish ports: want %v got %v", want, unit.PublishPort)
@@ -64,7 +64,7 @@ func TestBuildAppQuadletUnitWorkerMatchesRole(t *testing.T) {
 		},
 	}
 
-	unit := buildAppQuadletUnit("ghcr.io/acme/test:latest", "worker")
+	unit := buildAppQuadletUnit("ghcr.io/acme/test:latest", "", "worker")
 	if unit.ContainerName != "test-app-worker" || unit.Exec != "bundle exec jobs" {
 		t.Fatalf("worker identity not preserved: name=%q exec=%q", unit.ContainerName, unit.Exec)
 	}
@@ -82,6 +82,34 @@ func TestBuildAppQuadletUnitWorkerMatchesRole(t *testing.T) {
 	}
 }
 
+func TestBuildAppQuadletUnitAttachesRoleNetworks(t *testing.T) {
+	oldCfg := cfg
+	t.Cleanup(func() { cfg = oldCfg })
+	cfg = &config.Config{
+		Service: "test-app",
+		Podman: config.PodmanConfig{
+			Networks: map[string]config.PodmanNetworkConfig{
+				"backend": {Subnet: "10.90.0.0/24", Internal: true},
+			},
+		},
+		Servers: map[string]config.RoleConfig{
+			"web": {Networks: []string{"azud", "backend"}},
+		},
+		Proxy: config.ProxyConfig{AppPort: 3000},
+	}
+
+	unit := buildAppQuadletUnit("ghcr.io/acme/test:latest", "", "web")
+	if !reflect.DeepEqual(unit.Network, []string{"azud.network", "backend.network"}) {
+		t.Fatalf("Network = %v, want azud and backend units", unit.Network)
+	}
+	netFile := quadletNetworkFile("backend")
+	for _, want := range []string{"NetworkName=backend\n", "Subnet=10.90.0.0/24\n", "Internal=true\n"} {
+		if !strings.Contains(netFile, want) {
+			t.Errorf("backend network unit missing %q:\n%s", want, netFile)
+		}
+	}
+}
+
 func TestBuildProxyQuadletUnit_MixedModeUsesHostNetwork(t *testing.T) {
 	oldCfg := cfg
 	t.Cleanup(func() { cfg = oldCfg })
@@ -93,7 +121,7 @@ func TestBuildProxyQuadletUnit_MixedModeUsesHostNetwork(t *testing.T) {
 		},
 	}
 
-	unit := buildProxyQuadletUnit()
+	unit := buildProxyQuadletUnit("192.168.1.1")
 	if !reflect.DeepEqual(unit.Network, []string{"host"}) {
 		t.Fatalf("expected host network, got %v", unit.Network)
 	}
@@ -118,7 +146,7 @@ func TestBuildProxyQuadletUnit_DefaultNetworkPublishesConfiguredPorts(t *testing
 		},
 	}
 
-	unit := buildProxyQuadletUnit()
+	unit := buildProxyQuadletUnit("192.168.1.1")
 	if !reflect.DeepEqual(unit.Network, []string{"azud.network"}) {
 		t.Fatalf("expected azud network, got %v", unit.Network)
 	}
@@ -139,6 +167,54 @@ func TestBuildProxyQuadletUnit_DefaultNetworkPublishesConfiguredPorts(t *testing
 	}
 }
 
+func TestBuildProxyQuadletUnit_BindPublishesOnBindAddresses(t *testing.T) {
+	oldCfg := cfg
+	t.Cleanup(func() { cfg = oldCfg })
+
+	cfg = &config.Config{
+		Podman: config.PodmanConfig{Rootless: true},
+		Proxy: config.ProxyConfig{
+			Bind:             []string{"fd00::1", config.ProxyBindPrivate},
+			PrivateAddresses: map[string]string{"192.168.1.1": "10.0.0.1"},
+			Streams:          []config.StreamConfig{{Name: "smtp", Listen: 2525, Protocol: "tcp"}},
+		},
+	}
+
+	unit := buildProxyQuadletUnit("192.168.1.1")
+	want := []string{
+		"[fd00::1]:80:80",
+		"[fd00::1]:443:443",
+		"10.0.0.1:80:80",
+		"10.0.0.1:443:443",
+		fmt.Sprintf("127.0.0.1:%d:%d", proxy.CaddyAdminPort, proxy.CaddyAdminPort),
+		"[fd00::1]:2525:2525/tcp",
+		"10.0.0.1:2525:2525/tcp",
+	}
+	if !reflect.DeepEqual(unit.PublishPort, want) {
+		t.Fatalf("PublishPort = %v, want %v", unit.PublishPort, want)
+	}
+}
+
+func TestBuildProxyQuadletUnit_ResourcesAndLogs(t *testing.T) {
+	oldCfg := cfg
+	t.Cleanup(func() { cfg = oldCfg })
+
+	cfg = &config.Config{
+		Podman: config.PodmanConfig{Rootless: true},
+		Proxy: config.ProxyConfig{
+			Resources:  config.ProxyResourcesConfig{Memory: "256m", CPUs: "0.5"},
+			LogDriver:  "k8s-file",
+			LogOptions: map[string]string{"tag": "proxy", "max-size": "10m"},
+		},
+	}
+
+	unit := buildProxyQuadletUnit("192.168.1.1")
+	want := []string{"--memory=256m", "--cpus=0.5", "--log-driver=k8s-file", "--log-opt=max-size=10m", "--log-opt=tag=proxy"}
+	if !reflect.DeepEqual(unit.PodmanArgs, want) {
+		t.Fatalf("PodmanArgs = %v, want %v", unit.PodmanArgs, want)
+	}
+}
+
 func TestNeedsAzudNetworkUnit(t *testing.T) {
 	oldCfg := cfg
 	t.Cleanup(func() { cfg = oldCfg })
@@ -0,0 +1,224 @@
+package cli
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/lemonity-org/azud/internal/deploy"
+	"github.com/lemonity-org/azud/internal/output"
+	"github.com/lemonity-org/azud/internal/podman"
+	"github.com/lemonity-org/azud/internal/proxy"
+	"github.com/lemonity-org/azud/internal/ssh"
+)
+
+var trafficCmd = &cobra.Command{
+	Use:   "traffic",
+	Short: "Manage per-host traffic weights on the load-balancer tier",
+	Long: `Manage how the load-balancer tier (proxy.hosts_role) spreads traffic over
+the web hosts.
+
+Every web host has a weight between 0 and 100, relative to the others. Hosts
+start at 100, an even share. A host at 0 is drained: it is left out of the
+route, also while it is deployed, so it can be taken down for maintenance.`,
+}
+
+var trafficSetCmd = &cobra.Command{
+	Use:   "set <host> <weight>",
+	Short: "Set the traffic weight of a web host",
+	Long: `Set the traffic weight of a web host on every load balancer.
+
+Setting a weight of 0 drains the host: it is removed from the route and
+in-flight requests are given deploy.drain_timeout to finish. With --step,
+the weight moves from its current value to the target in increments,
+waiting --interval between them, to ramp a new or repaired host up
+gradually.
+
+Weights are stored in the state directory of each load balancer, so later
+deploys and 'azud proxy reconcile' keep them.
+
+Example:
+  azud traffic set 203.0.113.10 0
+  azud traffic set 203.0.113.10 100 --step 10 --interval 1m`,
+	Args: cobra.ExactArgs(2),
+	RunE: runTrafficSet,
+}
+
+var trafficStatusCmd = &cobra.Command{
+	Use:   "status",
+	Short: "Show traffic weights and shares of the web hosts",
+	Long: `Show the configured weight of each web host and the share of requests
+the first load balancer currently routes to it.
+
+Example:
+  azud traffic status`,
+	Args: cobra.NoArgs,
+	RunE: runTrafficStatus,
+}
+
+var (
+	trafficStep     int
+	trafficInterval time.Duration
+)
+
+func init() {
+	trafficSetCmd.Flags().IntVar(&trafficStep, "step", 0, "Move to the target weight in increments of this size")
+	trafficSetCmd.Flags().DurationVar(&trafficInterval, "interval", 30*time.Second, "Time between increments with --step")
+
+	trafficCmd.AddCommand(trafficSetCmd)
+	trafficCmd.AddCommand(trafficStatusCmd)
+	rootCmd.AddCommand(trafficCmd)
+}
+
+func runTrafficSet(cmd *cobra.Command, args []string) error {
+	output.SetVerbose(verbose)
+	log := output.DefaultLogger
+
+	if !cfg.UsesProxyTier() {
+		return fmt.Errorf("traffic weights require a load-balancer tier (proxy.hosts_role)")
+	}
+	host := args[0]
+	webHosts := getProxyRouteHosts("")
+	if !containsString(webHosts, host) {
+		return fmt.Errorf("host %s is not a web role host", host)
+	}
+	target, err := strconv.Atoi(args[1])
+	if err != nil || target < 0 || target > 100 {
+		return fmt.Errorf("invalid weight %q: must be between 0 and 100", args[1])
+	}
+	if trafficStep < 0 || trafficStep > 100 {
+		return fmt.Errorf("--step must be between 1 and 100")
+	}
+	if trafficStep > 0 && trafficInterval <= 0 {
+		return fmt.Errorf("--interval must be positive")
+	}
+
+	lbHosts := cfg.GetProxyHosts()
+	if len(lbHosts) == 0 {
+		return fmt.Errorf("no load-balancer hosts configured for role %s", cfg.Proxy.HostsRole)
+	}
+
+	sshClient := createSSHClient()
+	defer func() { _ = sshClient.Close() }()
+	containers := podman.NewContainerManager(podman.NewClient(sshClient))
+	manager := proxy.NewManagerWithOptions(sshClient, log, cfg.SSH.User, cfg.Proxy.Rootful, cfg.UseHostPortUpstreams())
+	manager.SetProxyConfig(buildProxyConfig(log))
+
+	weights, err := deploy.LoadTrafficWeights(sshClient, lbHosts[0], cfg.SSH.User, cfg.Service)
+	if err != nil {
+		return err
+	}
+	steps := trafficSteps(weights.Weight(host), target, trafficStep)
+	for i, weight := range steps {
+		if i > 0 {
+			select {
+			case <-cmd.Context().Done():
+				return cmd.Context().Err()
+			case <-time.After(trafficInterval):
+			}
+		}
+		weights.Set(host, weight)
+		if err := applyTrafficWeights(sshClient, manager, containers, lbHosts, webHosts, weights); err != nil {
+			return err
+		}
+		log.Success("%s now at weight %d", host, weight)
+	}
+
+	if target == 0 && cfg.Deploy.DrainTimeout > 0 {
+		upstream, ok := deploy.WebHostUpstreams(cfg, containers)[host]
+		if ok {
+			for _, lbHost := range lbHosts {
+				if err := manager.DrainUpstream(lbHost, upstream, cfg.Deploy.DrainTimeout); err != nil {
+					log.HostError(lbHost, "drain %s: %v", upstream, err)
+				}
+			}
+		}
+		log.Success("%s drained", host)
+	}
+	return nil
+}
+
+// trafficSteps returns the weights to pass through when moving from current
+// to target in increments of step, ending at target. A step of 0 moves
+// directly.
+func trafficSteps(current, target, step int) []int {
+	if step <= 0 || current == target {
+		return []int{target}
+	}
+	var steps []int
+	for weight := current; weight != target; {
+		switch {
+		case weight < target:
+			weight = min(weight+step, target)
+		default:
+			weight = max(weight-step, target)
+		}
+		steps = append(steps, weight)
+	}
+	return steps
+}
+
+// applyTrafficWeights records weights on every load balancer and rebuilds
+// the route there so each web host gets its weighted share.
+func applyTrafficWeights(sshClient *ssh.Client, manager *proxy.Manager, containers *podman.ContainerManager, lbHosts, webHosts []string, weights *deploy.TrafficWeights) error {
+	upstreams := deploy.WeightedHostUpstreams(webHosts, deploy.WebHostUpstreams(cfg, containers), weights)
+	if len(upstreams) == 0 {
+		return fmt.Errorf("refusing to drain every web host: no running container would receive traffic")
+	}
+	service := deploy.BuildProxyServiceConfig(cfg, nil, upstreams)
+	for _, lbHost := range lbHosts {
+		if err := deploy.SaveTrafficWeights(sshClient, lbHost, cfg.SSH.User, cfg.Service, weights); err != nil {
+			return fmt.Errorf("%s: %w", lbHost, err)
+		}
+		if err := manager.RegisterService(lbHost, service); err != nil {
+			return fmt.Errorf("%s: %w", lbHost, err)
+		}
+	}
+	return nil
+}
+
+func runTrafficStatus(cmd *cobra.Command, args []string) error {
+	output.SetVerbose(verbose)
+	log := output.DefaultLogger
+
+	if !cfg.UsesProxyTier() {
+		return fmt.Errorf("traffic weights require a load-balancer tier (proxy.hosts_role)")
+	}
+	lbHosts := cfg.GetProxyHosts()
+	if len(lbHosts) == 0 {
+		return fmt.Errorf("no load-balancer hosts configured for role %s", cfg.Proxy.HostsRole)
+	}
+
+	sshClient := createSSHClient()
+	defer func() { _ = sshClient.Close() }()
+	containers := podman.NewContainerManager(podman.NewClient(sshClient))
+	manager := proxy.NewManagerWithOptions(sshClient, log, cfg.SSH.User, cfg.Proxy.Rootful, cfg.UseHostPortUpstreams())
+
+	weights, err := deploy.LoadTrafficWeights(sshClient, lbHosts[0], cfg.SSH.User, cfg.Service)
+	if err != nil {
+		return err
+	}
+	routeWeights, err := manager.GetUpstreamWeights(lbHosts[0], cfg.Proxy.PrimaryHost())
+	if err != nil {
+		return err
+	}
+	shares := make(map[string]int, len(routeWeights))
+	for _, weighted := range routeWeights {
+		shares[weighted.Dial] = weighted.Weight
+	}
+
+	upstreams := deploy.WebHostUpstreams(cfg, containers)
+	rows := make([][]string, 0, len(upstreams))
+	for _, host := range getProxyRouteHosts("") {
+		upstream, share := "-", "-"
+		if dial, ok := upstreams[host]; ok {
+			upstream = dial
+			share = fmt.Sprintf("%d%%", shares[dial])
+		}
+		rows = append(rows, []string{host, strconv.Itoa(weights.Weight(host)), share, upstream})
+	}
+	log.Table([]string{"Host", "Weight", "Share", "Upstream"}, rows)
+	return nil
+}
@@ -0,0 +1,23 @@
+package cli
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestTrafficSteps(t *testing.T) {
+	tests := []struct {
+		current, target, step int
+		want                  []int
+	}{
+		{current: 100, target: 0, step: 0, want: []int{0}},
+		{current: 0, target: 100, step: 30, want: []int{30, 60, 90, 100}},
+		{current: 100, target: 50, step: 25, want: []int{75, 50}},
+		{current: 40, target: 40, step: 10, want: []int{40}},
+	}
+	for _, tt := range tests {
+		if got := trafficSteps(tt.current, tt.target, tt.step); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("trafficSteps(%d, %d, %d) = %v, want %v", tt.current, tt.target, tt.step, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,293 @@
+package cli
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/lemonity-org/azud/internal/config"
+	"github.com/lemonity-org/azud/internal/output"
+	"github.com/lemonity-org/azud/pkg/version"
+)
+
+const upgradeRepo = "lemonity-org/azud"
+
+// Base URLs for release lookups and downloads; tests point them at a local
+// server.
+var (
+	upgradeAPIURL      = "https://api.github.com"
+	upgradeDownloadURL = "https://github.com"
+)
+
+// maxUpgradeDownload bounds the size of a downloaded release asset.
+const maxUpgradeDownload = 256 << 20
+
+var upgradeCmd = &cobra.Command{
+	Use:   "upgrade",
+	Short: "Update azud to the latest release",
+	Long: `Download a release of azud from GitHub and replace the running binary.
+
+The binary is verified against the release checksums.txt and, when the
+GitHub CLI is installed, its build provenance attestation is checked with
+'gh attestation verify'. The new binary must run before it is swapped in.
+
+Example:
+  azud upgrade                    # Install the latest release
+  azud upgrade --check            # Report whether a newer release exists
+  azud upgrade --version v1.4.0   # Install a specific release`,
+	Args: cobra.NoArgs,
+	RunE: runUpgrade,
+}
+
+var (
+	upgradeVersion         string
+	upgradeCheck           bool
+	upgradeSkipAttestation bool
+)
+
+func init() {
+	upgradeCmd.Flags().StringVar(&upgradeVersion, "version", "", "Release to install (default: latest)")
+	upgradeCmd.Flags().BoolVar(&upgradeCheck, "check", false, "Only check for a newer release")
+	upgradeCmd.Flags().BoolVar(&upgradeSkipAttestation, "skip-attestation", false, "Skip GitHub attestation verification")
+	rootCmd.AddCommand(upgradeCmd)
+}
+
+func runUpgrade(cmd *cobra.Command, args []string) error {
+	output.SetVerbose(verbose)
+	log := output.DefaultLogger
+	client := &http.Client{Timeout: 5 * time.Minute}
+
+	target := upgradeVersion
+	if target == "" {
+		latest, err := latestReleaseTag(client)
+		if err != nil {
+			return fmt.Errorf("failed to find the latest release: %w", err)
+		}
+		target = latest
+	}
+	if !strings.HasPrefix(target, "v") {
+		target = "v" + target
+	}
+
+	current := version.Version
+	cmp, comparable := config.CompareVersions(current, target)
+	if upgradeCheck {
+		if comparable && cmp >= 0 {
+			log.Success("azud %s is up to date (latest: %s)", current, target)
+			return nil
+		}
+		log.Info("azud %s is available (running %s)", target, current)
+		log.Info("Run 'azud upgrade' to install it")
+		return nil
+	}
+	// Only an explicit --version installs an older release; a development
+	// build newer than the latest release is kept.
+	if comparable && cmp >= 0 && upgradeVersion == "" {
+		if cmp > 0 {
+			log.Success("azud %s is newer than the latest release %s; use --version to install it", current, target)
+			return nil
+		}
+		log.Success("azud %s is already the latest release", current)
+		return nil
+	}
+
+	executable, err := os.Executable()
+	if err != nil {
+		return fmt.Errorf("failed to locate the running binary: %w", err)
+	}
+	if resolved, err := filepath.EvalSymlinks(executable); err == nil {
+		executable = resolved
+	}
+
+	log.Header("Upgrading azud %s to %s", current, target)
+
+	asset := upgradeAssetName(runtime.GOOS, runtime.GOARCH)
+	log.Step(1, 3, "Downloading %s", asset)
+	downloaded, err := downloadRelease(client, target, asset, filepath.Dir(executable))
+	if err != nil {
+		return err
+	}
+	defer func() { _ = os.Remove(downloaded) }()
+
+	log.Step(2, 3, "Verifying %s", asset)
+	if upgradeSkipAttestation {
+		log.Warn("Skipping attestation verification")
+	} else if err := verifyReleaseAttestation(downloaded); err != nil {
+		return err
+	}
+	out, err := exec.Command(downloaded, "version", "--short").Output()
+	if err != nil {
+		return fmt.Errorf("downloaded binary failed to run: %w", err)
+	}
+	log.Info("Downloaded binary reports version %s", strings.TrimSpace(string(out)))
+
+	log.Step(3, 3, "Replacing %s", executable)
+	if err := replaceExecutable(executable, downloaded); err != nil {
+		return err
+	}
+
+	log.Success("azud upgraded to %s", target)
+	return nil
+}
+
+// upgradeAssetName returns the release asset built for goos and goarch.
+func upgradeAssetName(goos, goarch string) string {
+	name := fmt.Sprintf("azud-%s-%s", goos, goarch)
+	if goos == "windows" {
+		name += ".exe"
+	}
+	return name
+}
+
+// latestReleaseTag returns the tag of the latest published release.
+func latestReleaseTag(client *http.Client) (string, error) {
+	body, err := fetchURL(client, fmt.Sprintf("%s/repos/%s/releases/latest", upgradeAPIURL, upgradeRepo), 1<<20)
+	if err != nil {
+		return "", err
+	}
+	var release struct {
+		TagName string `json:"tag_name"`
+	}
+	if err := json.Unmarshal(body, &release); err != nil {
+		return "", fmt.Errorf("failed to parse release: %w", err)
+	}
+	if release.TagName == "" {
+		return "", fmt.Errorf("release has no tag")
+	}
+	return release.TagName, nil
+}
+
+// downloadRelease downloads asset from the tag's release into dir and checks
+// it against the release checksums. It returns the path of the executable
+// temporary file.
+func downloadRelease(client *http.Client, tag, asset, dir string) (string, error) {
+	base := fmt.Sprintf("%s/%s/releases/download/%s", upgradeDownloadURL, upgradeRepo, tag)
+
+	checksums, err := fetchURL(client, base+"/checksums.txt", 1<<20)
+	if err != nil {
+		return "", fmt.Errorf("failed to download checksums: %w", err)
+	}
+	want, err := releaseChecksum(checksums, asset)
+	if err != nil {
+		return "", err
+	}
+
+	data, err := fetchURL(client, base+"/"+asset, maxUpgradeDownload)
+	if err != nil {
+		return "", fmt.Errorf("failed to download %s: %w", asset, err)
+	}
+	sum := sha256.Sum256(data)
+	if got := hex.EncodeToString(sum[:]); got != want {
+		return "", fmt.Errorf("checksum mismatch for %s: expected %s, got %s", asset, want, got)
+	}
+
+	// Write next to the running binary so the final rename stays on one
+	// filesystem.
+	file, err := os.CreateTemp(dir, ".azud-upgrade-*")
+	if err != nil {
+		return "", fmt.Errorf("failed to create temporary file: %w", err)
+	}
+	if _, err := file.Write(data); err != nil {
+		_ = file.Close()
+		_ = os.Remove(file.Name())
+		return "", fmt.Errorf("failed to write %s: %w", file.Name(), err)
+	}
+	if err := file.Close(); err != nil {
+		_ = os.Remove(file.Name())
+		return "", err
+	}
+	if err := os.Chmod(file.Name(), 0755); err != nil {
+		_ = os.Remove(file.Name())
+		return "", err
+	}
+	return file.Name(), nil
+}
+
+// releaseChecksum finds the SHA-256 of asset in a sha256sum-style listing.
+func releaseChecksum(checksums []byte, asset string) (string, error) {
+	scanner := bufio.NewScanner(bytes.NewReader(checksums))
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 2 && strings.TrimPrefix(fields[1], "*") == asset {
+			return strings.ToLower(fields[0]), nil
+		}
+	}
+	return "", fmt.Errorf("checksums.txt has no entry for %s", asset)
+}
+
+// verifyReleaseAttestation checks the build provenance of path with the
+// GitHub CLI, as the install script does.
+func verifyReleaseAttestation(path string) error {
+	if _, err := exec.LookPath("gh"); err != nil {
+		return fmt.Errorf("gh CLI is required to verify release attestations; install it or pass --skip-attestation")
+	}
+	out, err := exec.Command("gh", "attestation", "verify", path, "--repo", upgradeRepo).CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("attestation verification failed: %w\n%s", err, strings.TrimSpace(string(out)))
+	}
+	return nil
+}
+
+// replaceExecutable moves replacement over executable, keeping its mode.
+// Windows does not allow replacing a running binary, so the old one is moved
+// aside first.
+func replaceExecutable(executable, replacement string) error {
+	if info, err := os.Stat(executable); err == nil {
+		if err := os.Chmod(replacement, info.Mode().Perm()); err != nil {
+			return err
+		}
+	}
+	if runtime.GOOS == "windows" {
+		old := executable + ".old"
+		_ = os.Remove(old)
+		if err := os.Rename(executable, old); err != nil {
+			return fmt.Errorf("failed to move %s aside: %w", executable, err)
+		}
+		if err := os.Rename(replacement, executable); err != nil {
+			_ = os.Rename(old, executable)
+			return fmt.Errorf("failed to replace %s: %w", executable, err)
+		}
+		return nil
+	}
+	if err := os.Rename(replacement, executable); err != nil {
+		return fmt.Errorf("failed to replace %s: %w", executable, err)
+	}
+	return nil
+}
+
+func fetchURL(client *http.Client, url string, limit int64) ([]byte, error) {
+	req, err := http.NewRequest(http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("User-Agent", "azud/"+version.Version)
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = resp.Body.Close() }()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("GET %s: %s", url, resp.Status)
+	}
+	data, err := io.ReadAll(io.LimitReader(resp.Body, limit+1))
+	if err != nil {
+		return nil, err
+	}
+	if int64(len(data)) > limit {
+		return nil, fmt.Errorf("GET %s: response exceeds %d bytes", url, limit)
+	}
+	return data, nil
+}
@@ -0,0 +1,112 @@
+package cli
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/lemonity-org/azud/pkg/version"
+)
+
+func serveRelease(t *testing.T, asset string, binary []byte, checksum string) {
+	t.Helper()
+	mux := http.NewServeMux()
+	mux.HandleFunc("/repos/lemonity-org/azud/releases/latest", func(w http.ResponseWriter, r *http.Request) {
+		_, _ = fmt.Fprint(w, `{"tag_name":"v9.9.9"}`)
+	})
+	mux.HandleFunc("/lemonity-org/azud/releases/download/v9.9.9/checksums.txt", func(w http.ResponseWriter, r *http.Request) {
+		_, _ = fmt.Fprintf(w, "%s  %s\n%s  azud-other\n", checksum, asset, strings.Repeat("0", 64))
+	})
+	mux.HandleFunc("/lemonity-org/azud/releases/download/v9.9.9/"+asset, func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write(binary)
+	})
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+
+	previousAPI, previousDownload := upgradeAPIURL, upgradeDownloadURL
+	upgradeAPIURL, upgradeDownloadURL = server.URL, server.URL
+	t.Cleanup(func() { upgradeAPIURL, upgradeDownloadURL = previousAPI, previousDownload })
+}
+
+func TestDownloadReleaseVerifiesChecksum(t *testing.T) {
+	binary := []byte("#!/bin/sh\necho v9.9.9\n")
+	sum := sha256.Sum256(binary)
+	asset := upgradeAssetName("linux", "amd64")
+	serveRelease(t, asset, binary, hex.EncodeToString(sum[:]))
+
+	tag, err := latestReleaseTag(http.DefaultClient)
+	if err != nil || tag != "v9.9.9" {
+		t.Fatalf("latestReleaseTag = %q, %v", tag, err)
+	}
+
+	dir := t.TempDir()
+	path, err := downloadRelease(http.DefaultClient, tag, asset, dir)
+	if err != nil {
+		t.Fatalf("downloadRelease: %v", err)
+	}
+	if data, _ := os.ReadFile(path); string(data) != string(binary) {
+		t.Fatalf("downloaded content = %q", data)
+	}
+
+	executable := filepath.Join(dir, "azud")
+	if err := os.WriteFile(executable, []byte("old"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := replaceExecutable(executable, path); err != nil {
+		t.Fatalf("replaceExecutable: %v", err)
+	}
+	if data, _ := os.ReadFile(executable); string(data) != string(binary) {
+		t.Fatalf("executable not replaced: %q", data)
+	}
+}
+
+func TestDownloadReleaseRejectsChecksumMismatch(t *testing.T) {
+	asset := upgradeAssetName("linux", "arm64")
+	serveRelease(t, asset, []byte("tampered"), strings.Repeat("a", 64))
+
+	dir := t.TempDir()
+	if _, err := downloadRelease(http.DefaultClient, "v9.9.9", asset, dir); err == nil || !strings.Contains(err.Error(), "checksum mismatch") {
+		t.Fatalf("expected checksum mismatch, got %v", err)
+	}
+	if entries, _ := os.ReadDir(dir); len(entries) != 0 {
+		t.Fatalf("rejected download left files behind: %v", entries)
+	}
+	if _, err := downloadRelease(http.DefaultClient, "v9.9.9", "azud-plan9-386", dir); err == nil || !strings.Contains(err.Error(), "no entry") {
+		t.Fatalf("expected missing checksum entry error, got %v", err)
+	}
+}
+
+func TestRunUpgradeKeepsNewerBinary(t *testing.T) {
+	downloads := 0
+	asset := upgradeAssetName(runtime.GOOS, runtime.GOARCH)
+	serveRelease(t, asset, []byte("older"), strings.Repeat("a", 64))
+	downloadServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		downloads++
+		http.NotFound(w, r)
+	}))
+	t.Cleanup(downloadServer.Close)
+	previousDownload := upgradeDownloadURL
+	upgradeDownloadURL = downloadServer.URL
+
+	previousVersion := version.Version
+	version.Version = "v10.0.0"
+	upgradeVersion, upgradeCheck = "", false
+	t.Cleanup(func() {
+		version.Version = previousVersion
+		upgradeDownloadURL = previousDownload
+	})
+
+	if err := runUpgrade(upgradeCmd, nil); err != nil {
+		t.Fatalf("runUpgrade: %v", err)
+	}
+	if downloads != 0 {
+		t.Fatalf("newer running binary was replaced by an older release (%d downloads)", downloads)
+	}
+}
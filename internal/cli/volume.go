@@ -0,0 +1,394 @@
+package cli
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/lemonity-org/azud/internal/config"
+	"github.com/lemonity-org/azud/internal/output"
+	"github.com/lemonity-org/azud/internal/podman"
+	"github.com/lemonity-org/azud/internal/shell"
+	"github.com/lemonity-org/azud/internal/ssh"
+)
+
+var volumeCmd = &cobra.Command{
+	Use:   "volume",
+	Short: "Manage named volumes",
+	Long: `Commands for managing the Podman named volumes declared in the
+configuration (volumes and accessories.<name>.volumes entries whose source is
+a volume name rather than a host path).`,
+}
+
+var volumeListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List declared volumes on each host",
+	Long: `List declared named volumes and whether they exist on each host.
+
+Example:
+  azud volume list
+  azud volume list --host 192.168.1.1`,
+	Args: cobra.NoArgs,
+	RunE: runVolumeList,
+}
+
+var volumeInspectCmd = &cobra.Command{
+	Use:   "inspect <name>",
+	Short: "Show Podman's view of a volume",
+	Long: `Print podman volume inspect output for a declared volume.
+
+Example:
+  azud volume inspect pgdata`,
+	Args: cobra.ExactArgs(1),
+	RunE: runVolumeInspect,
+}
+
+var volumeSnapshotCmd = &cobra.Command{
+	Use:   "snapshot <name>",
+	Short: "Export a volume to a local tarball",
+	Long: `Export a declared volume to a tarball on this machine.
+
+The export reads the live volume. Stop containers that write to it first if
+you need a consistent snapshot.
+
+Example:
+  azud volume snapshot pgdata
+  azud volume snapshot pgdata --host 192.168.1.1 --output pgdata.tar`,
+	Args: cobra.ExactArgs(1),
+	RunE: runVolumeSnapshot,
+}
+
+var volumeRestoreCmd = &cobra.Command{
+	Use:   "restore <name> <tarball>",
+	Short: "Import a local tarball into a volume",
+	Long: `Import a tarball into a declared volume, creating it if needed.
+
+Files in the tarball overwrite existing files; other files are kept. Restore
+refuses to touch a volume mounted by a running container unless --force is
+given.
+
+Example:
+  azud volume restore pgdata pgdata.tar --host 192.168.1.1`,
+	Args: cobra.ExactArgs(2),
+	RunE: runVolumeRestore,
+}
+
+var volumeMigrateCmd = &cobra.Command{
+	Use:   "migrate <name>",
+	Short: "Copy a volume to another host",
+	Long: `Copy a declared volume from one host to another through this machine.
+
+The source volume is left untouched. The target must not be mounted by a
+running container unless --force is given.
+
+Example:
+  azud volume migrate uploads --from 192.168.1.1 --to 192.168.1.2`,
+	Args: cobra.ExactArgs(1),
+	RunE: runVolumeMigrate,
+}
+
+var (
+	volumeHost   string
+	volumeOutput string
+	volumeForce  bool
+	volumeFrom   string
+	volumeTo     string
+)
+
+func init() {
+	volumeListCmd.Flags().StringVar(&volumeHost, "host", "", "Specific host to list")
+	volumeInspectCmd.Flags().StringVar(&volumeHost, "host", "", "Specific host to inspect")
+	volumeSnapshotCmd.Flags().StringVar(&volumeHost, "host", "", "Host to export from (required when the volume is on several hosts)")
+	volumeSnapshotCmd.Flags().StringVarP(&volumeOutput, "output", "o", "", "Local tarball path (default <name>-<host>-<timestamp>.tar)")
+	volumeRestoreCmd.Flags().StringVar(&volumeHost, "host", "", "Host to import into (required when the volume is on several hosts)")
+	volumeRestoreCmd.Flags().BoolVar(&volumeForce, "force", false, "Restore even if running containers mount the volume")
+	volumeMigrateCmd.Flags().StringVar(&volumeFrom, "from", "", "Source host")
+	volumeMigrateCmd.Flags().StringVar(&volumeTo, "to", "", "Target host")
+	volumeMigrateCmd.Flags().BoolVar(&volumeForce, "force", false, "Import even if running containers mount the target volume")
+	_ = volumeMigrateCmd.MarkFlagRequired("from")
+	_ = volumeMigrateCmd.MarkFlagRequired("to")
+
+	volumeCmd.AddCommand(volumeListCmd)
+	volumeCmd.AddCommand(volumeInspectCmd)
+	volumeCmd.AddCommand(volumeSnapshotCmd)
+	volumeCmd.AddCommand(volumeRestoreCmd)
+	volumeCmd.AddCommand(volumeMigrateCmd)
+	rootCmd.AddCommand(volumeCmd)
+}
+
+func runVolumeList(cmd *cobra.Command, args []string) error {
+	output.SetVerbose(verbose)
+	log := output.DefaultLogger
+
+	volumes := cfg.NamedVolumes()
+	if len(volumes) == 0 {
+		log.Info("No named volumes declared")
+		return nil
+	}
+
+	sshClient := createSSHClient()
+	defer func() { _ = sshClient.Close() }()
+	volumeManager := podman.NewVolumeManager(podman.NewClient(sshClient))
+
+	present := make(map[string]map[string]podman.Volume)
+	var rows [][]string
+	for _, volume := range volumes {
+		owner := "app"
+		if volume.Accessory != "" {
+			owner = "accessory " + volume.Accessory
+		}
+		for _, host := range cfg.GetVolumeHosts(volume) {
+			if volumeHost != "" && host != volumeHost {
+				continue
+			}
+			if _, ok := present[host]; !ok {
+				present[host] = make(map[string]podman.Volume)
+				existing, err := volumeManager.List(host)
+				if err != nil {
+					log.HostError(host, "%v", err)
+				}
+				for _, v := range existing {
+					present[host][v.Name] = v
+				}
+			}
+			status, mountpoint := "missing", "-"
+			if v, ok := present[host][volume.Name]; ok {
+				status, mountpoint = "present", v.Mountpoint
+			}
+			rows = append(rows, []string{volume.Name, owner, host, status, mountpoint})
+		}
+	}
+
+	log.Table([]string{"Volume", "Owner", "Host", "Status", "Mountpoint"}, rows)
+	return nil
+}
+
+func runVolumeInspect(cmd *cobra.Command, args []string) error {
+	output.SetVerbose(verbose)
+	log := output.DefaultLogger
+
+	_, hosts, err := selectedVolumeHosts(args[0], volumeHost, false)
+	if err != nil {
+		return err
+	}
+
+	sshClient := createSSHClient()
+	defer func() { _ = sshClient.Close() }()
+	volumeManager := podman.NewVolumeManager(podman.NewClient(sshClient))
+
+	failed := 0
+	for _, host := range hosts {
+		log.Header("%s / %s", args[0], host)
+		inspect, err := volumeManager.Inspect(host, args[0])
+		if err != nil {
+			log.HostError(host, "%v", err)
+			failed++
+			continue
+		}
+		log.Output(inspect)
+	}
+	if failed > 0 {
+		return fmt.Errorf("volume inspect failed on %d host(s)", failed)
+	}
+	return nil
+}
+
+func runVolumeSnapshot(cmd *cobra.Command, args []string) error {
+	output.SetVerbose(verbose)
+	log := output.DefaultLogger
+	name := args[0]
+
+	_, hosts, err := selectedVolumeHosts(name, volumeHost, true)
+	if err != nil {
+		return err
+	}
+	host := hosts[0]
+
+	localPath := volumeOutput
+	if localPath == "" {
+		localPath = defaultSnapshotPath(name, host, time.Now())
+	}
+
+	sshClient := createSSHClient()
+	defer func() { _ = sshClient.Close() }()
+
+	log.Host(host, "Exporting volume %s...", name)
+	if err := exportVolume(sshClient, host, name, localPath); err != nil {
+		return err
+	}
+	log.HostSuccess(host, "Volume %s saved to %s", name, localPath)
+	return nil
+}
+
+func runVolumeRestore(cmd *cobra.Command, args []string) error {
+	output.SetVerbose(verbose)
+	log := output.DefaultLogger
+	name, localPath := args[0], args[1]
+
+	_, hosts, err := selectedVolumeHosts(name, volumeHost, true)
+	if err != nil {
+		return err
+	}
+	host := hosts[0]
+	if _, err := os.Stat(localPath); err != nil {
+		return fmt.Errorf("snapshot %s: %w", localPath, err)
+	}
+
+	sshClient := createSSHClient()
+	defer func() { _ = sshClient.Close() }()
+
+	log.Host(host, "Restoring volume %s from %s...", name, localPath)
+	if err := importVolume(sshClient, host, name, localPath, volumeForce); err != nil {
+		return err
+	}
+	log.HostSuccess(host, "Volume %s restored", name)
+	return nil
+}
+
+func runVolumeMigrate(cmd *cobra.Command, args []string) error {
+	output.SetVerbose(verbose)
+	log := output.DefaultLogger
+	name := args[0]
+
+	if volumeFrom == volumeTo {
+		return fmt.Errorf("--from and --to must be different hosts")
+	}
+	if _, _, err := selectedVolumeHosts(name, volumeFrom, true); err != nil {
+		return err
+	}
+
+	sshClient := createSSHClient()
+	defer func() { _ = sshClient.Close() }()
+
+	if err := copyVolume(sshClient, log, name, volumeFrom, volumeTo, volumeForce); err != nil {
+		return err
+	}
+	log.Success("Volume %s copied from %s to %s", name, volumeFrom, volumeTo)
+	return nil
+}
+
+// selectedVolumeHosts resolves a declared volume and the hosts to operate
+// on. When requireSingle is set, a volume on several hosts needs --host.
+func selectedVolumeHosts(name, selectedHost string, requireSingle bool) (config.NamedVolume, []string, error) {
+	volume, ok := cfg.GetNamedVolume(name)
+	if !ok {
+		return volume, nil, fmt.Errorf("volume %s is not declared in the configuration", name)
+	}
+	hosts := cfg.GetVolumeHosts(volume)
+	if selectedHost != "" {
+		for _, host := range hosts {
+			if host == selectedHost {
+				return volume, []string{host}, nil
+			}
+		}
+		return volume, nil, fmt.Errorf("volume %s is not used on host %s", name, selectedHost)
+	}
+	if len(hosts) == 0 {
+		return volume, nil, fmt.Errorf("no hosts configured for volume %s", name)
+	}
+	if requireSingle && len(hosts) > 1 {
+		return volume, nil, fmt.Errorf("volume %s is on %d hosts; select one with --host", name, len(hosts))
+	}
+	return volume, hosts, nil
+}
+
+func defaultSnapshotPath(name, host string, now time.Time) string {
+	safeHost := strings.NewReplacer(":", "_", "[", "", "]", "").Replace(host)
+	return fmt.Sprintf("%s-%s-%s.tar", name, safeHost, now.UTC().Format("20060102T150405Z"))
+}
+
+// exportVolume exports a volume on host into a local tarball.
+func exportVolume(sshClient *ssh.Client, host, name, localPath string) error {
+	remotePath, cleanup, err := remoteTempFile(sshClient, host)
+	if err != nil {
+		return err
+	}
+	defer cleanup()
+
+	volumeManager := podman.NewVolumeManager(podman.NewClient(sshClient))
+	if err := volumeManager.Export(host, name, remotePath); err != nil {
+		return fmt.Errorf("%s: %w", host, err)
+	}
+	if dir := filepath.Dir(localPath); dir != "." {
+		if err := os.MkdirAll(dir, 0700); err != nil {
+			return err
+		}
+	}
+	if err := sshClient.Download(host, remotePath, localPath); err != nil {
+		return fmt.Errorf("%s: failed to download snapshot: %w", host, err)
+	}
+	return nil
+}
+
+// importVolume imports a local tarball into a volume on host. Unless force
+// is set, it refuses when running containers mount the volume.
+func importVolume(sshClient *ssh.Client, host, name, localPath string, force bool) error {
+	volumeManager := podman.NewVolumeManager(podman.NewClient(sshClient))
+	if !force {
+		users, err := volumeManager.UsedBy(host, name)
+		if err != nil {
+			return fmt.Errorf("%s: %w", host, err)
+		}
+		if len(users) > 0 {
+			return fmt.Errorf("%s: volume %s is mounted by running containers (%s); stop them or use --force", host, name, strings.Join(users, ", "))
+		}
+	}
+
+	remotePath, cleanup, err := remoteTempFile(sshClient, host)
+	if err != nil {
+		return err
+	}
+	defer cleanup()
+
+	if err := sshClient.Upload(host, localPath, remotePath); err != nil {
+		return fmt.Errorf("%s: failed to upload snapshot: %w", host, err)
+	}
+	if err := volumeManager.Import(host, name, remotePath); err != nil {
+		return fmt.Errorf("%s: %w", host, err)
+	}
+	return nil
+}
+
+// copyVolume copies a volume between hosts through a local temporary file,
+// so the hosts never need to reach each other.
+func copyVolume(sshClient *ssh.Client, log *output.Logger, name, from, to string, force bool) error {
+	tmp, err := os.CreateTemp("", "azud-volume-*.tar")
+	if err != nil {
+		return err
+	}
+	localPath := tmp.Name()
+	_ = tmp.Close()
+	defer func() { _ = os.Remove(localPath) }()
+
+	log.Host(from, "Exporting volume %s...", name)
+	if err := exportVolume(sshClient, from, name, localPath); err != nil {
+		return err
+	}
+	log.Host(to, "Importing volume %s...", name)
+	if err := importVolume(sshClient, to, name, localPath, force); err != nil {
+		return err
+	}
+	log.HostSuccess(to, "Volume %s imported", name)
+	return nil
+}
+
+// remoteTempFile creates a private temporary file on host and returns its
+// path with a cleanup function.
+func remoteTempFile(sshClient *ssh.Client, host string) (string, func(), error) {
+	result, err := sshClient.Execute(host, "umask 077 && mktemp /tmp/azud-volume-XXXXXX")
+	if err != nil {
+		return "", nil, fmt.Errorf("%s: %w", host, err)
+	}
+	if result.ExitCode != 0 {
+		return "", nil, fmt.Errorf("%s: failed to create temp file: %s", host, result.Stderr)
+	}
+	path := strings.TrimSpace(result.Stdout)
+	cleanup := func() {
+		_, _ = sshClient.Execute(host, "rm -f "+shell.Quote(path))
+	}
+	return path, cleanup, nil
+}
@@ -0,0 +1,86 @@
+package cli
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/lemonity-org/azud/internal/config"
+)
+
+func TestSelectedVolumeHostsResolvesDeclaredVolumes(t *testing.T) {
+	oldCfg := cfg
+	t.Cleanup(func() { cfg = oldCfg })
+	cfg = &config.Config{
+		Service: "shop",
+		Servers: map[string]config.RoleConfig{
+			"web": {Hosts: []string{"web-1", "web-2"}},
+			"lb":  {Hosts: []string{"lb-1"}},
+		},
+		Proxy:   config.ProxyConfig{HostsRole: "lb"},
+		Volumes: []string{"/srv/uploads:/app/uploads", "cache:/app/cache"},
+		Accessories: map[string]config.AccessoryConfig{
+			"db": {Host: "db-1", Volumes: []string{"pgdata:/var/lib/postgresql/data:Z"}},
+		},
+	}
+
+	names := []string{}
+	for _, volume := range cfg.NamedVolumes() {
+		names = append(names, volume.Name)
+	}
+	if !reflect.DeepEqual(names, []string{"cache", "pgdata"}) {
+		t.Fatalf("named volumes = %v, want host paths skipped", names)
+	}
+
+	_, hosts, err := selectedVolumeHosts("cache", "", false)
+	if err != nil || !reflect.DeepEqual(hosts, []string{"web-1", "web-2"}) {
+		t.Fatalf("app volume hosts = (%v, %v), want web hosts without the load-balancer tier", hosts, err)
+	}
+	if _, _, err := selectedVolumeHosts("cache", "", true); err == nil || !strings.Contains(err.Error(), "select one with --host") {
+		t.Fatalf("expected ambiguous host error, got %v", err)
+	}
+	_, hosts, err = selectedVolumeHosts("pgdata", "", true)
+	if err != nil || !reflect.DeepEqual(hosts, []string{"db-1"}) {
+		t.Fatalf("accessory volume hosts = (%v, %v)", hosts, err)
+	}
+	if _, _, err := selectedVolumeHosts("uploads", "", false); err == nil {
+		t.Fatal("expected undeclared volume to fail")
+	}
+
+	got := defaultSnapshotPath("pgdata", "2001:db8::10", time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC))
+	if got != "pgdata-2001_db8__10-20260102T030405Z.tar" {
+		t.Fatalf("defaultSnapshotPath = %q", got)
+	}
+}
+
+func TestUnlabeledBindMounts(t *testing.T) {
+	oldCfg := cfg
+	t.Cleanup(func() { cfg = oldCfg })
+	cfg = &config.Config{
+		Service: "shop",
+		Servers: map[string]config.RoleConfig{
+			"web": {Hosts: []string{"web-1"}},
+		},
+		Volumes: []string{"/srv/uploads:/app/uploads", "/srv/logs:/app/logs:z", "cache:/app/cache"},
+		Accessories: map[string]config.AccessoryConfig{
+			"db": {
+				Host:    "db-1",
+				Volumes: []string{"pgdata:/var/lib/postgresql/data", "/srv/pg:/backup:Z"},
+				Files:   []config.FileMapping{{Remote: "/etc/azud/pg.conf"}},
+			},
+		},
+	}
+
+	if got := unlabeledBindMounts("web-1"); !reflect.DeepEqual(got, []string{"/srv/uploads:/app/uploads"}) {
+		t.Errorf("web-1 unlabeled = %v", got)
+	}
+	if got := unlabeledBindMounts("db-1"); !reflect.DeepEqual(got, []string{"/etc/azud/pg.conf:/etc/azud/pg.conf:ro"}) {
+		t.Errorf("db-1 unlabeled = %v", got)
+	}
+
+	cfg.Podman.SELinuxLabel = config.SELinuxLabelShared
+	if got := unlabeledBindMounts("web-1"); len(got) != 0 {
+		t.Errorf("selinux_label left volumes unlabeled: %v", got)
+	}
+}
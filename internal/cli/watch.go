@@ -0,0 +1,224 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/lemonity-org/azud/internal/deploy"
+	"github.com/lemonity-org/azud/internal/output"
+	"github.com/lemonity-org/azud/internal/podman"
+	"github.com/lemonity-org/azud/internal/ssh"
+)
+
+var watchCmd = &cobra.Command{
+	Use:   "watch",
+	Short: "Detect restart loops and redeploy moved image tags",
+	Long: `Poll the restart counts of the application containers on every host and
+report containers that restart deploy.restart_loop.threshold times within
+deploy.restart_loop.window.
+
+Each loop runs the restart-loop hook. When it is detected within
+deploy.restart_loop.rollback_within of the last successful deployment, the
+service is rolled back to the version that deployment replaced. Azud rolls
+back at most once per watch, and never rolls back a rollback it made itself.
+
+With deploy.auto.watch_tag, the image tag is also resolved in the registry
+every deploy.auto.interval and its digest is deployed when it differs from
+the one last deployed, as azud deploy --if-new-digest does. A digest backed
+out by a restart-loop rollback is not deployed again.
+
+With --once the containers are checked a single time, and the command fails
+if any is looping; run it after a deploy or from cron.
+
+Example:
+  azud watch
+  azud watch --interval 1m
+  azud watch --once`,
+	Args: cobra.NoArgs,
+	RunE: runWatch,
+}
+
+var (
+	watchInterval time.Duration
+	watchOnce     bool
+)
+
+// restartLoopRollbackReason marks rollbacks made by azud watch in the
+// rollback_reason metadata of deployment history.
+const restartLoopRollbackReason = "restart_loop"
+
+func init() {
+	watchCmd.Flags().DurationVar(&watchInterval, "interval", 30*time.Second, "Time between checks")
+	watchCmd.Flags().BoolVar(&watchOnce, "once", false, "Check once and exit non-zero if a container is looping")
+
+	rootCmd.AddCommand(watchCmd)
+}
+
+func runWatch(cmd *cobra.Command, args []string) error {
+	output.SetVerbose(verbose)
+	log := output.DefaultLogger
+
+	if watchInterval <= 0 {
+		return fmt.Errorf("--interval must be positive")
+	}
+
+	sshClient := createSSHClient()
+	defer func() { _ = sshClient.Close() }()
+	containerManager := podman.NewContainerManager(podman.NewClient(sshClient))
+
+	restartLoop := cfg.Deploy.RestartLoop
+	tracker := deploy.NewRestartTracker(restartLoop.Threshold, restartLoop.Window)
+	ctx := cmd.Context()
+	rolledBack := false
+
+	auto := cfg.Deploy.Auto
+	if !watchOnce {
+		log.Info("Watching %s for %d restarts within %s (Ctrl+C to stop)", cfg.Service, restartLoop.Threshold, restartLoop.Window)
+		if auto.WatchTag {
+			log.Info("Checking the registry for a new image digest every %s", auto.Interval)
+		}
+	}
+	ticker := time.NewTicker(watchInterval)
+	defer ticker.Stop()
+	var tagChecked time.Time
+	for {
+		if auto.WatchTag && time.Since(tagChecked) >= auto.Interval {
+			tagChecked = time.Now()
+			deployer := deploy.NewDeployer(cfg, sshClient, log)
+			deployed, err := deployIfNewDigest(ctx, log, deployer, &deploy.DeployOptions{
+				Destination:   GetDestination(),
+				Approve:       deployApprover(cmd),
+				ApprovalToken: deployApprovalToken,
+			})
+			if err != nil {
+				log.Error("Automatic redeploy failed: %v", err)
+			} else if deployed {
+				syncProxyRoutesAfterChange(sshClient, log)
+			}
+		}
+
+		loops := checkRestartLoops(ctx, log, containerManager, tracker)
+		if len(loops) > 0 && restartLoop.RollbackWithin > 0 && !rolledBack {
+			rolledBack = true
+			if err := rollbackRestartLoop(cmd, log, sshClient); err != nil {
+				log.Error("Rollback failed: %v", err)
+			}
+		}
+
+		if watchOnce {
+			if len(loops) > 0 {
+				return fmt.Errorf("restart loop detected: %s", strings.Join(loops, ", "))
+			}
+			log.Success("No restart loops")
+			return nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+// checkRestartLoops observes every application container once and returns
+// the host/role pairs that entered a restart loop.
+func checkRestartLoops(ctx context.Context, log *output.Logger, containerManager *podman.ContainerManager, tracker *deploy.RestartTracker) []string {
+	var loops []string
+	for _, role := range cfg.GetRoles() {
+		name := deploy.RoleContainerName(cfg, role)
+		for _, host := range cfg.GetRoleHosts(role) {
+			count, created, err := containerManager.RestartCount(host, name)
+			if err != nil {
+				log.HostError(host, "%s: %v", name, err)
+				continue
+			}
+
+			restarts, started := tracker.Observe(host+"/"+role, count, created, time.Now())
+			if !started {
+				continue
+			}
+			log.HostError(host, "%s restarted %d times within %s", name, restarts, cfg.Deploy.RestartLoop.Window)
+			loops = append(loops, host+"/"+role)
+
+			hookCtx := newHookContext()
+			hookCtx.Hosts = host
+			hookCtx.Role = role
+			hookCtx.Container = name
+			hookCtx.Restarts = strconv.Itoa(restarts)
+			if err := newHookRunner().Run(ctx, "restart-loop", hookCtx); err != nil {
+				log.Warn("restart-loop hook failed: %v", err)
+			}
+		}
+	}
+	return loops
+}
+
+// rollbackRestartLoop rolls the service back to the version replaced by the
+// last successful deployment, if that deployment finished within
+// deploy.restart_loop.rollback_within.
+func rollbackRestartLoop(cmd *cobra.Command, log *output.Logger, sshClient *ssh.Client) error {
+	within := cfg.Deploy.RestartLoop.RollbackWithin
+	last, err := newHistoryStore(log).GetLastSuccessfulTo(cfg.Service, GetDestination())
+	if err != nil {
+		log.Warn("Not rolling back: %v", err)
+		return nil
+	}
+	version := restartLoopRollbackVersion(log, last)
+	switch {
+	case last.Metadata["rollback_reason"] == restartLoopRollbackReason:
+		log.Warn("Not rolling back: deployment %s is already a restart-loop rollback", last.ID)
+		return nil
+	case version == "":
+		log.Warn("Not rolling back: deployment %s has no previous version", last.ID)
+		return nil
+	case time.Since(last.CompletedAt) > within:
+		log.Info("Not rolling back: deployment %s finished more than %s ago", last.ID, within)
+		return nil
+	}
+
+	log.Warn("Rolling back deployment %s (%s) to %s", last.ID, last.Version, version)
+	deployer := deploy.NewDeployer(cfg, sshClient, log)
+	return deployer.Rollback(cmd.Context(), version, &deploy.DeployOptions{
+		Destination:   GetDestination(),
+		Approve:       deployApprover(cmd),
+		ApprovalToken: deployApprovalToken,
+		// Restoring the version that ran before is allowed during a freeze
+		// and outside deploy.windows; the override is audited.
+		Override: true,
+		Metadata: map[string]string{
+			"rollback_reason":             restartLoopRollbackReason,
+			"rolled_back_from_deployment": last.ID,
+			"rolled_back_digest":          last.Metadata["image_digest"],
+		},
+	})
+}
+
+// restartLoopRollbackVersion returns the version to roll last back to. A
+// deployment resolved from a tag may have replaced an image of that same
+// tag, so it rolls back to the digest the previous deployment ran.
+func restartLoopRollbackVersion(log *output.Logger, last *deploy.DeploymentRecord) string {
+	if last.Metadata["image_tag"] == "" {
+		return last.PreviousVersion
+	}
+	records, err := newHistoryStore(log).List(cfg.Service, 0)
+	if err != nil {
+		return last.PreviousVersion
+	}
+	older := false
+	for _, record := range records {
+		if record.ID == last.ID {
+			older = true
+			continue
+		}
+		if older && record.Status == deploy.StatusSuccess && record.Destination == last.Destination && record.Metadata["image_digest"] != "" {
+			return record.Metadata["image_digest"]
+		}
+	}
+	return last.PreviousVersion
+}
@@ -0,0 +1,147 @@
+package config
+
+import "fmt"
+
+// Cache accessory engines.
+const (
+	CacheEngineRedis     = "redis"
+	CacheEngineMemcached = "memcached"
+)
+
+// CacheMemcachedUser is the user clients of a password-protected Memcached
+// accessory authenticate as.
+const CacheMemcachedUser = "azud"
+
+// cacheTLSDir is where a cache accessory reads its certificate and key.
+const cacheTLSDir = "/tls"
+
+// Port returns the port the cache server listens on in its container.
+func (c AccessoryCacheConfig) Port() int {
+	if c.Engine == CacheEngineMemcached {
+		return 11211
+	}
+	return 6379
+}
+
+// CacheEnvPrefix returns the prefix of the variables that describe the cache
+// accessory name to the application, e.g. SESSION_CACHE for session-cache.
+func CacheEnvPrefix(name string) string {
+	return AccessoryEnvName(name)
+}
+
+// CachePasswordSecret returns the secret holding the password of the cache
+// accessory name.
+func CachePasswordSecret(name string) string {
+	return CacheEnvPrefix(name) + "_PASSWORD"
+}
+
+// CacheTLSDir returns the directory on the accessory and application hosts
+// holding the certificate of the cache accessory name. Only the accessory
+// hosts receive its key.
+func CacheTLSDir(cfg *Config, name string) string {
+	return fmt.Sprintf("$HOME/.azud/tls/%s-%s", cfg.Service, name)
+}
+
+// CacheCACertPath returns where application containers find the certificate
+// of the cache accessory name.
+func CacheCACertPath(name string) string {
+	return "/etc/azud/tls/" + name + ".crt"
+}
+
+// applyCacheAccessories wires cache accessories into the application: the
+// password secret is added to both sides, the connection settings to the
+// application environment, and the certificate to the application volumes.
+// Settings made in the configuration are kept.
+func applyCacheAccessories(cfg *Config) {
+	for _, name := range cfg.GetAccessoryNames() {
+		accessory := cfg.Accessories[name]
+		cache := accessory.Cache
+		if cache.Engine != CacheEngineRedis && cache.Engine != CacheEngineMemcached {
+			continue
+		}
+
+		prefix := CacheEnvPrefix(name)
+		address := fmt.Sprintf("%s-%s:%d", cfg.Service, name, cache.Port())
+		env := make(map[string]string)
+		switch cache.Engine {
+		case CacheEngineRedis:
+			scheme, credentials := "redis", ""
+			if cache.TLS {
+				scheme = "rediss"
+			}
+			// Podman secrets cannot be composed into a value; clients read
+			// the password from <PREFIX>_PASSWORD_FILE instead.
+			if cache.Password && !cfg.UsesPodmanSecrets() {
+				credentials = fmt.Sprintf(":${%s}@", CachePasswordSecret(name))
+			}
+			env[prefix+"_URL"] = fmt.Sprintf("%s://%s%s", scheme, credentials, address)
+		case CacheEngineMemcached:
+			env[prefix+"_SERVERS"] = address
+			if cache.Password {
+				env[prefix+"_USERNAME"] = CacheMemcachedUser
+			}
+		}
+
+		if cache.Password {
+			secret := []string{CachePasswordSecret(name)}
+			accessory.Env.Secret = mergeSecretNames(accessory.Env.Secret, secret)
+			cfg.Env.Secret = mergeSecretNames(cfg.Env.Secret, secret)
+		}
+		if cache.TLS {
+			dir := CacheTLSDir(cfg, name)
+			accessory.Volumes = withVolume(accessory.Volumes, dir, cacheTLSDir, "ro")
+			cfg.Volumes = withVolume(cfg.Volumes, dir+"/tls.crt", CacheCACertPath(name), "ro")
+			env[prefix+"_TLS_CA"] = CacheCACertPath(name)
+		}
+		for key, value := range env {
+			if _, ok := cfg.Env.Clear[key]; ok {
+				continue
+			}
+			if cfg.Env.Clear == nil {
+				cfg.Env.Clear = make(map[string]string)
+			}
+			cfg.Env.Clear[key] = value
+		}
+
+		if !accessory.Healthcheck.IsSet() {
+			accessory.Healthcheck.Port = cache.Port()
+		}
+		if accessory.Cmd == "" {
+			accessory.Cmd = cacheCommand(cache, CachePasswordSecret(name))
+		}
+		cfg.Accessories[name] = accessory
+	}
+}
+
+// cacheCommand returns the command of a cache accessory that requires the
+// password in the secret and serves TLS as configured, or "" when the
+// image's own command will do. The password is read from the environment,
+// or from the mounted Podman secret, and never appears on a command line.
+func cacheCommand(cache AccessoryCacheConfig, secret string) string {
+	password := fmt.Sprintf(`"${%s:-$(cat "$%s_FILE")}"`, secret, secret)
+	switch cache.Engine {
+	case CacheEngineRedis:
+		command := "redis-server"
+		if cache.TLS {
+			command += fmt.Sprintf(" --port 0 --tls-port %d --tls-cert-file %[2]s/tls.crt --tls-key-file %[2]s/tls.key --tls-ca-cert-file %[2]s/tls.crt --tls-auth-clients no",
+				cache.Port(), cacheTLSDir)
+		}
+		if !cache.Password {
+			return command
+		}
+		// redis-server reads the trailing "-" as more configuration from
+		// stdin; the entrypoint drops privileges before exec'ing it.
+		return fmt.Sprintf("exec docker-entrypoint.sh %s - <<EOF\nrequirepass %s\nEOF", command, password)
+	case CacheEngineMemcached:
+		command := "memcached"
+		if cache.TLS {
+			command += fmt.Sprintf(" -Z -o ssl_chain_cert=%[1]s/tls.crt,ssl_key=%[1]s/tls.key", cacheTLSDir)
+		}
+		if !cache.Password {
+			return command
+		}
+		return fmt.Sprintf("umask 077 && printf '%s:%%s\\n' %s > /tmp/azud-auth && exec docker-entrypoint.sh %s -Y /tmp/azud-auth",
+			CacheMemcachedUser, password, command)
+	}
+	return ""
+}
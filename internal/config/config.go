@@ -1,7 +1,15 @@
 package config
 
 import (
+	"encoding/binary"
+	"fmt"
+	"net"
+	"os"
+	"path"
+	"slices"
 	"sort"
+	"strconv"
+	"strings"
 	"time"
 )
 
@@ -13,12 +21,19 @@ type Config struct {
 	// loadedSecrets holds secrets loaded from the secrets file (internal)
 	loadedSecrets map[string]string `yaml:"-"`
 
+	// envFileKeys maps each variable read from env.files to the index of
+	// the last file that set it (internal)
+	envFileKeys map[string]int `yaml:"-"`
+
 	// Container image name (OCI)
 	Image string `yaml:"image"`
 
 	// Container registry configuration
 	Registry RegistryConfig `yaml:"registry"`
 
+	// Credentials for further registries, keyed by server (e.g., ghcr.io)
+	Registries map[string]RegistryConfig `yaml:"registries"`
+
 	// Target servers configuration by role
 	Servers map[string]RoleConfig `yaml:"servers"`
 
@@ -31,6 +46,9 @@ type Config struct {
 	// Proxy configuration
 	Proxy ProxyConfig `yaml:"proxy"`
 
+	// DNS records of the proxy hostnames
+	DNS DNSConfig `yaml:"dns"`
+
 	// Accessories (databases, caches, etc.)
 	Accessories map[string]AccessoryConfig `yaml:"accessories"`
 
@@ -40,6 +58,9 @@ type Config struct {
 	// Podman configuration
 	Podman PodmanConfig `yaml:"podman"`
 
+	// Private network between hosts
+	Network NetworkConfig `yaml:"network"`
+
 	// SSH configuration
 	SSH SSHConfig `yaml:"ssh"`
 
@@ -73,6 +94,9 @@ type Config struct {
 	// Remote secrets file path (default: $HOME/.azud/secrets)
 	SecretsRemotePath string `yaml:"secrets_remote_path"`
 
+	// How secrets are stored on hosts: env_file (default), podman, or encrypted
+	SecretsStorage string `yaml:"secrets_storage"`
+
 	// Path to hooks directory
 	HooksPath string `yaml:"hooks_path"`
 
@@ -81,6 +105,86 @@ type Config struct {
 
 	// Command aliases
 	Aliases map[string]string `yaml:"aliases"`
+
+	// Which commands each local user may run
+	Team TeamConfig `yaml:"team"`
+
+	// HTTP API served by azud agent
+	Agent AgentConfig `yaml:"agent"`
+
+	// Registry webhooks that azud serve turns into deploys
+	Serve ServeConfig `yaml:"serve"`
+
+	// Tags of individual hosts, keyed by host; see EnvConfig.Tags
+	HostTags map[string][]string `yaml:"host_tags"`
+}
+
+// Operator roles for TeamConfig.
+const (
+	// RoleOperator may run every command.
+	RoleOperator = "operator"
+	// RoleReadOnly may only run commands that change nothing on the hosts,
+	// such as status, logs, and history.
+	RoleReadOnly = "read_only"
+)
+
+// TeamConfig assigns operator roles to the users who run azud. Users are
+// identified by $USER, so roles are a guardrail against mistakes, not a
+// security boundary: restrict what the SSH credentials can do for that.
+type TeamConfig struct {
+	// Role of users not listed in roles: operator (default) or read_only
+	DefaultRole string `yaml:"default_role"`
+
+	// Role of each user, keyed by username
+	Roles map[string]string `yaml:"roles"`
+}
+
+// RoleOf returns the role of user.
+func (t TeamConfig) RoleOf(user string) string {
+	if role, ok := t.Roles[user]; ok {
+		return role
+	}
+	if t.DefaultRole != "" {
+		return t.DefaultRole
+	}
+	return RoleOperator
+}
+
+// DefaultAgentListen is the address azud agent listens on by default.
+const DefaultAgentListen = "127.0.0.1:8732"
+
+// AgentConfig configures the HTTP API of azud agent.
+type AgentConfig struct {
+	// Address to listen on (default 127.0.0.1:8732)
+	Listen string `yaml:"listen"`
+
+	// TLS certificate and key files; the API is served over plain HTTP
+	// without them
+	TLSCertificate string `yaml:"tls_certificate"`
+	TLSPrivateKey  string `yaml:"tls_private_key"`
+
+	// API clients, keyed by name
+	Tokens map[string]AgentToken `yaml:"tokens"`
+}
+
+// AgentToken is a bearer token that grants a client access to the agent API.
+type AgentToken struct {
+	// Secret holding the token
+	Secret string `yaml:"secret"`
+
+	// operator (default) may deploy and roll back; read_only may only read
+	// status and history
+	Role string `yaml:"role"`
+}
+
+// ServeConfig configures how azud serve deploys a destination when its
+// registry announces a new image tag.
+type ServeConfig struct {
+	// Secret holding the key that signs webhooks (X-Hub-Signature-256)
+	Secret string `yaml:"secret"`
+
+	// Tag patterns that trigger a deploy, such as "v*" (default: every tag)
+	Tags []string `yaml:"tags"`
 }
 
 // PodmanConfig holds Podman runtime settings
@@ -93,8 +197,63 @@ type PodmanConfig struct {
 
 	// Network backend: netavark (default) or cni
 	NetworkBackend string `yaml:"network_backend"`
+
+	// Create the azud network dual-stack so containers get IPv6 addresses
+	IPv6 bool `yaml:"ipv6"`
+
+	// SELinux relabeling of host path volumes that set neither :z nor :Z:
+	// shared (:z) or private (:Z). Empty leaves volumes as written.
+	SELinuxLabel string `yaml:"selinux_label"`
+
+	// Additional Podman networks created during bootstrap, keyed by name. An
+	// "azud" entry customizes the default network.
+	Networks map[string]PodmanNetworkConfig `yaml:"networks"`
 }
 
+// DefaultNetworkName is the Podman network containers join when no
+// networks list is configured.
+const DefaultNetworkName = "azud"
+
+// PodmanNetworkConfig holds the settings for one Podman network.
+type PodmanNetworkConfig struct {
+	// Subnet in CIDR notation (auto-assigned if empty)
+	Subnet string `yaml:"subnet"`
+
+	// Gateway address inside the subnet
+	Gateway string `yaml:"gateway"`
+
+	// Internal networks have no outbound access and cannot publish ports
+	Internal bool `yaml:"internal"`
+
+	// Enable IPv6 on this network
+	IPv6 bool `yaml:"ipv6"`
+
+	// Upstream DNS servers for containers on this network
+	DNS []string `yaml:"dns"`
+
+	// Disable the network's container name resolution
+	DisableDNS bool `yaml:"disable_dns"`
+}
+
+// NetworkConfig holds settings for the private network between hosts.
+type NetworkConfig struct {
+	// Set up an encrypted WireGuard mesh among all hosts
+	WireGuard bool `yaml:"wireguard"`
+
+	// Mesh subnet as an IPv4 CIDR (default 10.77.0.0/24)
+	Subnet string `yaml:"subnet"`
+
+	// Mesh addresses pinned per host, which stay put when hosts are added
+	// or removed
+	Addresses map[string]string `yaml:"addresses"`
+
+	// WireGuard UDP listen port (default 51820)
+	Port int `yaml:"port"`
+}
+
+// DefaultWireGuardPort is the UDP port WireGuard listens on by default.
+const DefaultWireGuardPort = 51820
+
 // RegistryConfig holds container registry settings
 type RegistryConfig struct {
 	// Registry server (e.g., ghcr.io, docker.io)
@@ -105,6 +264,143 @@ type RegistryConfig struct {
 
 	// Password or reference to secret
 	Password []string `yaml:"password"`
+
+	// Credential helper that fetches a short-lived token instead of a
+	// password: ecr, gcr, or acr
+	Helper string `yaml:"helper"`
+
+	// OAuth device authorization used by `azud registry login` to obtain the
+	// token stored in the password secret
+	OAuth *RegistryOAuthConfig `yaml:"oauth"`
+}
+
+// RegistryOAuthConfig configures the OAuth 2.0 device authorization grant
+// (RFC 8628) for registries that issue tokens instead of passwords.
+type RegistryOAuthConfig struct {
+	// Device authorization endpoint
+	DeviceAuthorizationURL string `yaml:"device_authorization_url"`
+
+	// Token endpoint polled until the user approves the device
+	TokenURL string `yaml:"token_url"`
+
+	// OAuth client ID of the application requesting the token
+	ClientID string `yaml:"client_id"`
+
+	// Scopes requested for the token
+	Scopes []string `yaml:"scopes"`
+}
+
+// ResolvePassword returns the registry password: a token from the credential
+// helper, or the secret password names, read from the environment or the
+// secrets file. It returns "" without an error when no secret is set.
+func (r RegistryConfig) ResolvePassword() (string, error) {
+	if r.Helper != "" {
+		return r.helperToken()
+	}
+	if len(r.Password) == 0 {
+		return "", nil
+	}
+	if val := os.Getenv(r.Password[0]); val != "" {
+		return val, nil
+	}
+	if val, ok := GetSecret(r.Password[0]); ok {
+		return val, nil
+	}
+	return "", nil
+}
+
+// NormalizeRegistryServer returns the canonical form of a registry server
+// name, so docker.io aliases and scheme prefixes compare equal.
+func NormalizeRegistryServer(server string) string {
+	server = strings.TrimPrefix(server, "https://")
+	server = strings.TrimPrefix(server, "http://")
+	server = strings.ToLower(strings.TrimSuffix(server, "/"))
+	switch server {
+	case "", "index.docker.io", "registry-1.docker.io":
+		return "docker.io"
+	}
+	return server
+}
+
+// ImageRegistry returns the registry server image is pulled from. References
+// without a registry host resolve to docker.io.
+func ImageRegistry(image string) string {
+	first, _, ok := strings.Cut(image, "/")
+	if ok && (strings.ContainsAny(first, ".:") || first == "localhost") {
+		return NormalizeRegistryServer(first)
+	}
+	return "docker.io"
+}
+
+// RegistryServers returns the registries keys in sorted order.
+func (c *Config) RegistryServers() []string {
+	servers := make([]string, 0, len(c.Registries))
+	for server := range c.Registries {
+		servers = append(servers, server)
+	}
+	sort.Strings(servers)
+	return servers
+}
+
+// RegistryFor returns the credentials used for image: the registries entry
+// for its server, or the registry section when that names the same server.
+func (c *Config) RegistryFor(image string) (RegistryConfig, bool) {
+	server := ImageRegistry(image)
+	for _, name := range c.RegistryServers() {
+		if NormalizeRegistryServer(name) == server {
+			reg := c.Registries[name]
+			reg.Server = name
+			return reg, true
+		}
+	}
+	if c.Registry.Username != "" && NormalizeRegistryServer(c.Registry.Server) == server {
+		return c.Registry, true
+	}
+	return RegistryConfig{}, false
+}
+
+// RegistryLogins returns the registries a host running images must be logged
+// into: the registry section, which every host is logged into as before,
+// plus the credentials for each image's registry. Each server appears once.
+func (c *Config) RegistryLogins(images ...string) []RegistryConfig {
+	var logins []RegistryConfig
+	seen := make(map[string]bool)
+	add := func(reg RegistryConfig) {
+		server := NormalizeRegistryServer(reg.Server)
+		if reg.Username == "" || seen[server] {
+			return
+		}
+		seen[server] = true
+		logins = append(logins, reg)
+	}
+
+	add(c.Registry)
+	for _, image := range images {
+		if reg, ok := c.RegistryFor(image); ok {
+			add(reg)
+		}
+	}
+	return logins
+}
+
+// HostImages returns the images host runs: the application image on role and
+// cron hosts, each accessory's image on its hosts, and the proxy.image_build
+// image on proxy hosts.
+func (c *Config) HostImages(host string) []string {
+	var images []string
+	if slices.Contains(c.GetAllHosts(), host) || slices.Contains(c.GetAllCronHosts(), host) {
+		images = append(images, c.Image)
+	}
+	for _, name := range c.GetAccessoryNames() {
+		accessory := c.Accessories[name]
+		if accessory.Host == host || slices.Contains(accessory.Hosts, host) {
+			images = append(images, accessory.Image)
+		}
+	}
+	if c.Proxy.ImageBuild.Enabled() && slices.Contains(c.GetProxyHosts(), host) {
+		images = append(images, c.ProxyImageRepository())
+	}
+	return images
 }
 
 // RoleConfig defines servers for a specific role
@@ -121,15 +417,106 @@ type RoleConfig struct {
 	// Container options (memory, cpus, etc.)
 	Options map[string]string `yaml:"options"`
 
-	// Tags for filtering
+	// Tags of every host in the role; see EnvConfig.Tags
 	Tags []string `yaml:"tags"`
 
 	// Environment variables specific to this role
 	Env map[string]string `yaml:"env"`
+
+	// Secret names injected only into this role's containers, in addition
+	// to env.secret. Roles with their own secrets read a dedicated env file.
+	Secrets []string `yaml:"secrets"`
+
+	// Podman networks to attach (default: [azud])
+	Networks []string `yaml:"networks"`
+
+	// Security hardening of the role's containers
+	Hardening HardeningConfig `yaml:"hardening"`
+
+	// User the container runs as: user, user:group, or uid:gid
+	User string `yaml:"user"`
+
+	// Entrypoint replacing the image's
+	Entrypoint string `yaml:"entrypoint"`
+
+	// Run an init process as PID 1 that forwards signals and reaps zombies
+	Init bool `yaml:"init"`
+
+	// Signal that stops the container (default: the image's STOPSIGNAL)
+	StopSignal string `yaml:"stop_signal"`
+
+	// Time to wait after stop_signal before killing the container
+	// (default: deploy.stop_timeout)
+	StopTimeout time.Duration `yaml:"stop_timeout"`
+}
+
+// HardeningPresetStrict names the preset that turns on every hardening
+// option with defaults suitable for most stateless web applications.
+const HardeningPresetStrict = "strict"
+
+// HardeningConfig restricts what an app container can do. It is written
+// either as a mapping of options or as the name of a preset, such as
+// "hardening: strict".
+type HardeningConfig struct {
+	// Preset whose options apply under the explicit ones (strict)
+	Preset string `yaml:"preset"`
+
+	// Mount the container's root filesystem read-only
+	ReadOnly bool `yaml:"read_only"`
+
+	// Writable tmpfs mounts, as path or path:options (e.g., /tmp:size=64m)
+	Tmpfs []string `yaml:"tmpfs"`
+
+	// Keep processes from gaining privileges through setuid binaries
+	NoNewPrivileges bool `yaml:"no_new_privileges"`
+
+	// Linux capabilities to drop (e.g., [ALL] or [NET_RAW])
+	CapDrop []string `yaml:"cap_drop"`
+
+	// Path of a seccomp profile on the host
+	SeccompProfile string `yaml:"seccomp_profile"`
+}
+
+// UnmarshalYAML accepts a preset name or a mapping of options.
+func (h *HardeningConfig) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	var preset string
+	if err := unmarshal(&preset); err == nil {
+		*h = HardeningConfig{Preset: preset}
+		return nil
+	}
+	type plain HardeningConfig
+	return unmarshal((*plain)(h))
+}
+
+// Effective returns the options with the preset applied. Options set
+// explicitly replace the preset's lists and add to its switches.
+func (h HardeningConfig) Effective() HardeningConfig {
+	effective := h
+	if h.Preset != HardeningPresetStrict {
+		return effective
+	}
+	effective.ReadOnly = true
+	effective.NoNewPrivileges = true
+	if len(effective.Tmpfs) == 0 {
+		effective.Tmpfs = []string{"/tmp"}
+	}
+	if len(effective.CapDrop) == 0 {
+		effective.CapDrop = []string{"ALL"}
+	}
+	return effective
 }
 
 // BuilderConfig holds build settings
 type BuilderConfig struct {
+	// Build backend: dockerfile (default) or buildpacks
+	Type string `yaml:"type"`
+
+	// Cloud Native Buildpacks settings for type: buildpacks
+	Pack PackConfig `yaml:"pack"`
+
+	// Local build engine: podman (default), docker, or buildx
+	Engine string `yaml:"engine"`
+
 	// Build for multiple architectures
 	Multiarch bool `yaml:"multiarch"`
 
@@ -142,9 +529,17 @@ type BuilderConfig struct {
 	// Cache configuration
 	Cache CacheConfig `yaml:"cache"`
 
-	// Build arguments
+	// Layer compression used when pushing: gzip, zstd, or zstd:chunked.
+	// Default: the engine's own (gzip).
+	Compression string `yaml:"compression"`
+
+	// Build arguments; values may use the placeholders of tag_template
 	Args map[string]string `yaml:"args"`
 
+	// Labels added to the image (e.g. org.opencontainers.image.revision:
+	// "{git_sha}"); values may use the placeholders of tag_template
+	Labels map[string]string `yaml:"labels"`
+
 	// Build target (multi-stage Dockerfile)
 	Target string `yaml:"target"`
 
@@ -157,16 +552,47 @@ type BuilderConfig struct {
 	// Build context
 	Context string `yaml:"context"`
 
+	// Paths whose changes since the last deployed commit make
+	// 'azud deploy --if-changed' build and deploy (default: the context)
+	WatchPaths []string `yaml:"watch_paths"`
+
 	// Remote builder configuration
 	Remote RemoteBuilderConfig `yaml:"remote"`
 
 	// Secrets for build
 	Secrets []string `yaml:"secrets"`
 
-	// Tag template with placeholders: {destination}, {version}, {timestamp}
+	// Tag template with placeholders: {destination}, {version}, {timestamp},
+	// {git_sha}, {git_branch}, {build_date}
 	// Default: "{version}" for backward compatibility
 	// Recommended for multi-env: "{destination}-{version}"
 	TagTemplate string `yaml:"tag_template"`
+
+	// Generate an SBOM with syft after pushing and attach it to the image in
+	// the registry
+	SBOM bool `yaml:"sbom"`
+
+	// SBOM format: spdx-json (default) or cyclonedx-json
+	SBOMFormat string `yaml:"sbom_format"`
+}
+
+// PackConfig holds settings for builds with the pack CLI
+type PackConfig struct {
+	// Builder image (e.g., paketobuildpacks/builder-jammy-base); pack's
+	// default builder is used when empty
+	Builder string `yaml:"builder"`
+
+	// Buildpacks to use instead of the builder's detection
+	Buildpacks []string `yaml:"buildpacks"`
+
+	// Build-time environment variables (e.g., BP_NODE_VERSION)
+	Env map[string]string `yaml:"env"`
+}
+
+// IsBuildpacks reports whether images are built with Cloud Native Buildpacks
+// instead of a Dockerfile.
+func (b BuilderConfig) IsBuildpacks() bool {
+	return b.Type == "buildpacks"
 }
 
 // CacheConfig holds build cache settings
@@ -178,13 +604,85 @@ type CacheConfig struct {
 	Options map[string]string `yaml:"options"`
 }
 
-// RemoteBuilderConfig holds remote builder settings
+// DefaultCacheRef returns the registry cache repository used when
+// builder.cache.type is registry without a ref: the image repository with a
+// -cache suffix (e.g., ghcr.io/acme/app-cache).
+func DefaultCacheRef(image string) string {
+	return imageRepository(image) + "-cache"
+}
+
+// imageRepository strips the tag and digest from image.
+func imageRepository(image string) string {
+	if at := strings.Index(image, "@"); at >= 0 {
+		image = image[:at]
+	}
+	if colon := strings.LastIndex(image, ":"); colon > strings.LastIndex(image, "/") {
+		image = image[:colon]
+	}
+	return image
+}
+
+// RemoteBuilderConfig holds remote builder settings. builder.remote is either
+// a single builder or a list of builders, one per architecture, that build in
+// parallel.
 type RemoteBuilderConfig struct {
 	// Remote host
 	Host string `yaml:"host"`
 
 	// Target architecture
 	Arch string `yaml:"arch"`
+
+	// Builders is set when builder.remote is a list; Host and Arch then
+	// mirror the first entry
+	Builders []RemoteBuilderConfig `yaml:"-"`
+}
+
+// UnmarshalYAML accepts a single builder mapping or a list of builders.
+func (r *RemoteBuilderConfig) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	type builder struct {
+		Host string `yaml:"host"`
+		Arch string `yaml:"arch"`
+	}
+	var items []interface{}
+	if err := unmarshal(&items); err == nil {
+		var list []builder
+		if err := unmarshal(&list); err != nil {
+			return err
+		}
+		*r = RemoteBuilderConfig{}
+		for _, b := range list {
+			r.Builders = append(r.Builders, RemoteBuilderConfig{Host: b.Host, Arch: b.Arch})
+		}
+		if len(r.Builders) > 0 {
+			r.Host = r.Builders[0].Host
+			r.Arch = r.Builders[0].Arch
+		}
+		return nil
+	}
+
+	var single builder
+	if err := unmarshal(&single); err != nil {
+		return err
+	}
+	*r = RemoteBuilderConfig{Host: single.Host, Arch: single.Arch}
+	return nil
+}
+
+// All returns every configured remote builder.
+func (r RemoteBuilderConfig) All() []RemoteBuilderConfig {
+	if len(r.Builders) > 0 {
+		return r.Builders
+	}
+	if r.Host == "" {
+		return nil
+	}
+	return []RemoteBuilderConfig{{Host: r.Host, Arch: r.Arch}}
+}
+
+// Parallel reports whether several remote builders each build one
+// architecture of a manifest list.
+func (r RemoteBuilderConfig) Parallel() bool {
+	return len(r.Builders) > 1
 }
 
 // EnvConfig holds environment variable configuration
@@ -195,7 +693,14 @@ type EnvConfig struct {
 	// Secret environment variable names
 	Secret []string `yaml:"secret"`
 
-	// Tag-specific environment variables
+	// Dotenv files read locally and merged under env.clear; later files
+	// override earlier ones, and env.clear overrides them all
+	Files []string `yaml:"files"`
+
+	// Environment variables for the app containers on hosts with a tag,
+	// keyed by tag. A key=value tag also selects the block named by its
+	// value. env.clear and role env win over tag values; secrets lose to
+	// both.
 	Tags map[string]map[string]string `yaml:"tags"`
 }
 
@@ -228,8 +733,14 @@ type ProxyConfig struct {
 	// Application port inside container
 	AppPort int `yaml:"app_port"`
 
+	// Unix socket the application listens on inside the container (e.g.
+	// /run/app.sock). When set, Caddy dials the socket through a shared
+	// host directory instead of a TCP port.
+	AppSocket string `yaml:"app_socket"`
+
 	// Protocol Caddy uses to communicate with application upstreams.
-	// Supported values are http, h2c, and https.
+	// Supported values are http, h2c (plaintext HTTP/2, e.g. gRPC), h2
+	// (HTTP/2 over TLS only), and https.
 	UpstreamProtocol string `yaml:"upstream_protocol"`
 
 	// HTTP port for proxy (default 80)
@@ -238,9 +749,31 @@ type ProxyConfig struct {
 	// HTTPS port for proxy (default 443)
 	HTTPSPort int `yaml:"https_port"`
 
+	// Host addresses the proxy publishes its HTTP, HTTPS and stream ports
+	// on, instead of every interface: IP addresses, or private for each
+	// host's private address (e.g. behind a cloud load balancer)
+	Bind []string `yaml:"bind"`
+
+	// Accept PROXY protocol headers from an upstream load balancer (e.g.
+	// Hetzner LB, HAProxy) so the proxy sees real client IPs
+	ProxyProtocol bool `yaml:"proxy_protocol"`
+
+	// CIDR ranges of the load balancers allowed to send PROXY protocol
+	// headers (default: any source)
+	ProxyProtocolAllow []string `yaml:"proxy_protocol_allow"`
+
 	// Run proxy container with rootful Podman (uses sudo when ssh.user is non-root)
 	Rootful bool `yaml:"rootful"`
 
+	// Server role whose hosts run Caddy as a dedicated load-balancer tier.
+	// When set, app containers publish their port on the private network and
+	// the proxy routes to them across hosts instead of being co-located.
+	HostsRole string `yaml:"hosts_role"`
+
+	// Private addresses used to reach app hosts from the load-balancer tier,
+	// keyed by SSH host (e.g. 203.0.113.10: 10.0.0.10). Defaults to the SSH host.
+	PrivateAddresses map[string]string `yaml:"private_addresses"`
+
 	// Health check configuration
 	Healthcheck HealthcheckConfig `yaml:"healthcheck"`
 
@@ -253,40 +786,541 @@ type ProxyConfig struct {
 	// Response header timeout (time to wait for response headers only)
 	ResponseHeaderTimeout string `yaml:"response_header_timeout"`
 
-	// Forward headers to backend
-	ForwardHeaders bool `yaml:"forward_headers"`
+	// Write timeout for sending the request to the upstream (Caddy write_timeout)
+	WriteTimeout string `yaml:"write_timeout"`
+
+	// How long idle upstream keep-alive connections stay open (default: 2m)
+	IdleTimeout string `yaml:"idle_timeout"`
+
+	// Timeout for connecting to an upstream (default: 3s)
+	DialTimeout string `yaml:"dial_timeout"`
+
+	// How long to wait for the Caddy admin API after the proxy starts
+	// (default: 30s)
+	BootTimeout string `yaml:"boot_timeout"`
+
+	// Forward headers to backend
+	ForwardHeaders bool `yaml:"forward_headers"`
+
+	// Retry and passive health settings for failing upstreams
+	Failover FailoverConfig `yaml:"failover"`
+
+	// How long Caddy holds a request while no upstream accepts it, such as
+	// during a cutover, before answering 503 (raises failover.try_duration)
+	HoldTimeout string `yaml:"hold_timeout"`
+
+	// Concurrent requests each app container serves before Caddy treats it
+	// as unavailable, so further requests go to another container, wait
+	// for hold_timeout, or fail with 503 (default: no limit)
+	MaxRequests int `yaml:"max_requests"`
+
+	// Connections Caddy opens to each app container; requests beyond them
+	// wait for a free connection (default: no limit)
+	MaxConnections int `yaml:"max_connections"`
+
+	// Logging configuration
+	Logging LoggingConfig `yaml:"logging"`
+
+	// Memory and CPU limits of the proxy container
+	Resources ProxyResourcesConfig `yaml:"resources"`
+
+	// Podman log driver of the proxy container: k8s-file, json-file,
+	// journald, none, or passthrough (default: Podman's configured driver)
+	LogDriver string `yaml:"log_driver"`
+
+	// Log driver options of the proxy container (e.g. max-size: 10m)
+	LogOptions map[string]string `yaml:"log_options"`
+
+	// Proxy image to run instead of the pinned official Caddy image. Streams
+	// require a Caddy build that includes the caddy-l4 module.
+	Image string `yaml:"image"`
+
+	// Caddy plugins compiled into the proxy image with xcaddy instead of
+	// setting image: 'azud build' builds and pushes the image, and the proxy
+	// runs it
+	ImageBuild ProxyImageBuildConfig `yaml:"image_build"`
+
+	// Raw TCP/UDP streams forwarded to the app containers through caddy-l4
+	Streams []StreamConfig `yaml:"streams"`
+
+	// Requests routed to the canary by header or cookie while a canary
+	// deployment runs, independent of its traffic weight
+	ABTests []ABTestConfig `yaml:"ab_tests"`
+
+	// MaxMind country database for allow_countries, deny_countries, and
+	// country A/B tests. Requires a Caddy image that includes the
+	// caddy-maxmind-geolocation module.
+	GeoIP GeoIPConfig `yaml:"geoip"`
+
+	// ISO 3166-1 alpha-2 country codes allowed to reach the service; other
+	// countries get 403 (set either allow_countries or deny_countries)
+	AllowCountries []string `yaml:"allow_countries"`
+
+	// ISO 3166-1 alpha-2 country codes refused with 403
+	DenyCountries []string `yaml:"deny_countries"`
+
+	// Path prefixes Caddy serves straight from disk instead of the app
+	// containers, such as /assets from the image or /uploads from a
+	// directory the containers write to
+	Static []StaticConfig `yaml:"static"`
+}
+
+// StaticConfig maps a path prefix of the service to a directory Caddy
+// serves it from.
+type StaticConfig struct {
+	// URL path prefix (e.g. /assets)
+	Path string `yaml:"path"`
+
+	// Directory in the app container holding the files (e.g.
+	// /app/public/assets). Deploys copy it out of the image unless shared
+	// is set.
+	Dir string `yaml:"dir"`
+
+	// Mount a host directory at dir in every app container instead, for
+	// files the application writes at runtime such as uploads
+	Shared bool `yaml:"shared"`
+
+	// Cache-Control header of the served files (default:
+	// DefaultStaticCacheControl)
+	CacheControl string `yaml:"cache_control"`
+}
+
+// DefaultStaticCacheControl is the Cache-Control header of static files
+// when proxy.static sets none.
+const DefaultStaticCacheControl = "public, max-age=3600"
+
+// StaticMount is where the proxy container mounts the host directory
+// holding the static files of every service.
+const StaticMount = "/srv/azud/static"
+
+// GetCacheControl returns the Cache-Control header of the served files.
+func (s StaticConfig) GetCacheControl() string {
+	if s.CacheControl != "" {
+		return s.CacheControl
+	}
+	return DefaultStaticCacheControl
+}
+
+// UsesStatic reports whether the proxy serves some paths from disk.
+func (c *Config) UsesStatic() bool {
+	return c != nil && len(c.Proxy.Static) > 0
+}
+
+// ProxyImageBuildConfig lists the Caddy plugins of a custom proxy image.
+type ProxyImageBuildConfig struct {
+	// Go modules of the plugins (e.g. github.com/mholt/caddy-l4), each
+	// optionally pinned with @version
+	Modules []string `yaml:"modules"`
+
+	// Repository the image is pushed to (default: the image repository
+	// with a -caddy suffix, e.g. ghcr.io/acme/app-caddy)
+	Repository string `yaml:"repository"`
+}
+
+// Enabled reports whether the proxy image is built from plugin modules.
+func (b ProxyImageBuildConfig) Enabled() bool {
+	return len(b.Modules) > 0
+}
+
+// CustomImage reports whether the proxy runs an image other than the
+// official Caddy build, i.e. one that can include plugins.
+func (p ProxyConfig) CustomImage() bool {
+	return strings.TrimSpace(p.Image) != "" || p.ImageBuild.Enabled()
+}
+
+// ProxyImageRepository returns the repository proxy.image_build pushes to.
+func (c *Config) ProxyImageRepository() string {
+	if c.Proxy.ImageBuild.Repository != "" {
+		return c.Proxy.ImageBuild.Repository
+	}
+	return imageRepository(c.Image) + "-caddy"
+}
+
+// GeoIPConfig locates the MaxMind country database the proxy matches client
+// addresses against, and optionally downloads it.
+type GeoIPConfig struct {
+	// Absolute host path of the .mmdb database (default:
+	// <azud state dir>/geoip/<edition>.mmdb)
+	Database string `yaml:"database"`
+
+	// MaxMind edition to download (default: GeoLite2-Country)
+	Edition string `yaml:"edition"`
+
+	// MaxMind account ID; with license_key, the proxy host downloads the
+	// database when it is missing
+	AccountID string `yaml:"account_id"`
+
+	// Secret holding the MaxMind license key
+	LicenseKey string `yaml:"license_key"`
+}
+
+// DefaultGeoIPEdition is the MaxMind database downloaded when
+// proxy.geoip.edition is not set.
+const DefaultGeoIPEdition = "GeoLite2-Country"
+
+// GeoIPMount is where the proxy container mounts the directory holding the
+// GeoIP database.
+const GeoIPMount = "/usr/share/GeoIP"
+
+// Enabled reports whether a GeoIP database is configured.
+func (g GeoIPConfig) Enabled() bool {
+	return g.Database != "" || g.LicenseKey != ""
+}
+
+// GetEdition returns the MaxMind edition, GeoLite2-Country by default.
+func (g GeoIPConfig) GetEdition() string {
+	if g.Edition != "" {
+		return g.Edition
+	}
+	return DefaultGeoIPEdition
+}
+
+// FileName returns the file name of the database.
+func (g GeoIPConfig) FileName() string {
+	if g.Database != "" {
+		return path.Base(g.Database)
+	}
+	return g.GetEdition() + ".mmdb"
+}
+
+// ContainerPath returns the path of the database inside the proxy container.
+func (g GeoIPConfig) ContainerPath() string {
+	return path.Join(GeoIPMount, g.FileName())
+}
+
+// ProxyResourcesConfig limits the resources of the proxy container so a
+// misbehaving Caddy cannot starve the host.
+type ProxyResourcesConfig struct {
+	// Memory limit (e.g. 512m, 1g)
+	Memory string `yaml:"memory"`
+
+	// Number of CPUs (e.g. 0.5, 2)
+	CPUs string `yaml:"cpus"`
+}
+
+// ABTestConfig sends requests carrying a header or cookie value to the
+// canary, e.g. to let internal staff try a new version before public
+// traffic is shifted to it.
+type ABTestConfig struct {
+	// Name of the test, unique within the service (e.g. staff)
+	Name string `yaml:"name"`
+
+	// Request header to match (set either header or cookie)
+	Header string `yaml:"header"`
+
+	// Cookie to match (set either header or cookie)
+	Cookie string `yaml:"cookie"`
+
+	// Exact header or cookie value that selects the canary
+	Value string `yaml:"value"`
+
+	// ISO 3166-1 alpha-2 country codes whose clients are sent to the canary
+	// (instead of header or cookie; needs proxy.geoip)
+	Countries []string `yaml:"countries"`
+}
+
+// StreamConfig forwards a proxy listen port to a port of the app containers.
+// Stream upstreams are swapped together with the HTTP route during deploys.
+type StreamConfig struct {
+	// Name of the stream, unique within the service (e.g. smtp)
+	Name string `yaml:"name"`
+
+	// Port the proxy listens on
+	Listen int `yaml:"listen"`
+
+	// Container port connections are forwarded to (default: listen)
+	Port int `yaml:"port"`
+
+	// Network protocol: tcp (default) or udp
+	Protocol string `yaml:"protocol"`
+}
+
+const (
+	// DefaultHTTPPort is the default host HTTP port for the proxy.
+	DefaultHTTPPort = 80
+	// DefaultHTTPSPort is the default host HTTPS port for the proxy.
+	DefaultHTTPSPort = 443
+)
+
+// DefaultProxyBootTimeout is how long to wait for the Caddy admin API after
+// the proxy starts when proxy.boot_timeout is not set.
+const DefaultProxyBootTimeout = 30 * time.Second
+
+// GetBootTimeout returns proxy.boot_timeout, or the default when it is unset
+// or invalid.
+func (p ProxyConfig) GetBootTimeout() time.Duration {
+	if timeout, err := time.ParseDuration(p.BootTimeout); err == nil && timeout > 0 {
+		return timeout
+	}
+	return DefaultProxyBootTimeout
+}
+
+// LogOpts returns proxy.log_options as sorted key=value pairs.
+func (p ProxyConfig) LogOpts() []string {
+	opts := make([]string, 0, len(p.LogOptions))
+	for key, value := range p.LogOptions {
+		opts = append(opts, key+"="+value)
+	}
+	sort.Strings(opts)
+	return opts
+}
+
+// EffectiveHTTPPort returns the configured HTTP port, falling back to default.
+func (p ProxyConfig) EffectiveHTTPPort() int {
+	if p.HTTPPort > 0 {
+		return p.HTTPPort
+	}
+	return DefaultHTTPPort
+}
+
+// EffectiveHTTPSPort returns the configured HTTPS port, falling back to default.
+func (p ProxyConfig) EffectiveHTTPSPort() int {
+	if p.HTTPSPort > 0 {
+		return p.HTTPSPort
+	}
+	return DefaultHTTPSPort
+}
+
+// UseHostPortUpstreams reports whether app containers should be registered in
+// Caddy using host loopback ports instead of Podman network DNS names.
+func (c *Config) UseHostPortUpstreams() bool {
+	return c != nil && c.Podman.Rootless && c.Proxy.Rootful && !c.UsesProxyTier()
+}
+
+// UsesAppSocket reports whether app containers serve Caddy over a unix
+// socket shared through a host directory.
+func (c *Config) UsesAppSocket() bool {
+	return c != nil && c.Proxy.AppSocket != ""
+}
+
+// AppSocketsMount is where the proxy container mounts the host directory
+// holding every app container's socket directory.
+const AppSocketsMount = "/run/azud/sockets"
+
+// AppSocketKeyLength is the length of the random name of each app
+// container's socket directory.
+const AppSocketKeyLength = 16
+
+// AppSocketUpstream returns the Caddy dial address of the app socket in the
+// socket directory named key.
+func (c *Config) AppSocketUpstream(key string) string {
+	return "unix/" + path.Join(AppSocketsMount, c.Service, key, path.Base(c.Proxy.AppSocket))
+}
+
+// UsesProxyTier reports whether Caddy runs on a dedicated load-balancer role
+// and reaches app containers on other hosts over the private network.
+func (c *Config) UsesProxyTier() bool {
+	return c != nil && c.Proxy.HostsRole != ""
+}
+
+// GetProxyHosts returns the hosts that run the Caddy proxy: the hosts of
+// proxy.hosts_role when a load-balancer tier is configured, otherwise every
+// application host.
+func (c *Config) GetProxyHosts() []string {
+	if c.UsesProxyTier() {
+		return c.GetRoleHosts(c.Proxy.HostsRole)
+	}
+	return c.GetAllHosts()
+}
+
+// IsProxyTierRole reports whether role is the dedicated load-balancer role.
+// Such roles run only the proxy and never receive app containers.
+func (c *Config) IsProxyTierRole(role string) bool {
+	return c.UsesProxyTier() && role == c.Proxy.HostsRole
+}
+
+// PrivateAddress returns the address the load-balancer tier uses to reach
+// the given app host: an explicit proxy.private_addresses entry, then the
+// host's WireGuard mesh address, then the SSH host itself. Brackets around
+// IPv6 literals are removed so callers can format host:port safely.
+func (c *Config) PrivateAddress(host string) string {
+	addr := strings.TrimSpace(c.Proxy.PrivateAddresses[host])
+	if addr == "" {
+		addr = c.MeshAddress(host)
+	}
+	if addr == "" {
+		addr = host
+	}
+	return strings.TrimSuffix(strings.TrimPrefix(addr, "["), "]")
+}
+
+// ProxyBindPrivate is the proxy.bind entry standing for each host's private
+// address.
+const ProxyBindPrivate = "private"
+
+// ProxyBindAddresses returns the IP addresses the proxy on host publishes
+// its ports on, with private resolved to the host's private address. Nil
+// means every interface.
+func (c *Config) ProxyBindAddresses(host string) []string {
+	var addrs []string
+	for _, entry := range c.Proxy.Bind {
+		addr := strings.Trim(strings.TrimSpace(entry), "[]")
+		if addr == ProxyBindPrivate {
+			addr = c.PrivateAddress(host)
+		}
+		if !slices.Contains(addrs, addr) {
+			addrs = append(addrs, addr)
+		}
+	}
+	return addrs
+}
+
+// RoleNetworks returns the Podman networks a role's containers join, with
+// the primary network first.
+func (c *Config) RoleNetworks(role string) []string {
+	if roleConfig, ok := c.Servers[role]; ok && len(roleConfig.Networks) > 0 {
+		return roleConfig.Networks
+	}
+	return []string{DefaultNetworkName}
+}
+
+// AccessoryNetworks returns the Podman networks an accessory joins, with the
+// primary network first.
+func (c *Config) AccessoryNetworks(name string) []string {
+	if accessory, ok := c.Accessories[name]; ok && len(accessory.Networks) > 0 {
+		return accessory.Networks
+	}
+	return []string{DefaultNetworkName}
+}
+
+// AccessoryEnvName returns the accessory name as used in environment
+// variable names: upper case with "-" replaced by "_".
+func AccessoryEnvName(name string) string {
+	return strings.ToUpper(strings.ReplaceAll(name, "-", "_"))
+}
 
-	// Logging configuration
-	Logging LoggingConfig `yaml:"logging"`
+// AccessoryDiscoveryEnv returns the AZUD_ACCESSORY_<NAME>_HOST and _PORT
+// variables through which role's containers locate the accessories they
+// share a network with. The host is the accessory's container name, which
+// Podman resolves on the shared network; the port is omitted when unknown.
+func (c *Config) AccessoryDiscoveryEnv(role string) map[string]string {
+	env := make(map[string]string)
+	roleNetworks := c.RoleNetworks(role)
+	for _, name := range c.GetAccessoryNames() {
+		if !slices.ContainsFunc(c.AccessoryNetworks(name), func(network string) bool {
+			return slices.Contains(roleNetworks, network)
+		}) {
+			continue
+		}
+		prefix := "AZUD_ACCESSORY_" + AccessoryEnvName(name)
+		env[prefix+"_HOST"] = fmt.Sprintf("%s-%s", c.Service, name)
+		accessory := c.Accessories[name]
+		if port := accessory.ContainerPort(); port > 0 {
+			env[prefix+"_PORT"] = strconv.Itoa(port)
+		}
+	}
+	return env
 }
 
-const (
-	// DefaultHTTPPort is the default host HTTP port for the proxy.
-	DefaultHTTPPort = 80
-	// DefaultHTTPSPort is the default host HTTPS port for the proxy.
-	DefaultHTTPSPort = 443
-)
+// PodmanNetworkNames returns the default network followed by every
+// configured network in sorted order.
+func (c *Config) PodmanNetworkNames() []string {
+	names := []string{DefaultNetworkName}
+	for name := range c.Podman.Networks {
+		if name != DefaultNetworkName {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names[1:])
+	return names
+}
 
-// EffectiveHTTPPort returns the configured HTTP port, falling back to default.
-func (p ProxyConfig) EffectiveHTTPPort() int {
-	if p.HTTPPort > 0 {
-		return p.HTTPPort
+// MeshHosts returns every host that joins the WireGuard mesh (application,
+// accessory, and cron hosts) in sorted order, which fixes address assignment.
+func (c *Config) MeshHosts() []string {
+	hostSet := make(map[string]bool)
+	var hosts []string
+	for _, group := range [][]string{c.GetAllHosts(), c.GetAccessoryHosts(), c.GetAllCronHosts()} {
+		for _, host := range group {
+			if host != "" && !hostSet[host] {
+				hostSet[host] = true
+				hosts = append(hosts, host)
+			}
+		}
 	}
-	return DefaultHTTPPort
+	sort.Strings(hosts)
+	return hosts
 }
 
-// EffectiveHTTPSPort returns the configured HTTPS port, falling back to default.
-func (p ProxyConfig) EffectiveHTTPSPort() int {
-	if p.HTTPSPort > 0 {
-		return p.HTTPSPort
+// MeshAddresses assigns each mesh host an address in network.subnet: its
+// network.addresses entry when pinned, otherwise the lowest free usable
+// address in MeshHosts order. Unpinned addresses shift when hosts are added
+// or removed; pinned ones never do. It also returns the subnet prefix
+// length for interface configuration.
+func (c *Config) MeshAddresses() (map[string]string, int, error) {
+	_, subnet, err := net.ParseCIDR(c.Network.Subnet)
+	if err != nil {
+		return nil, 0, fmt.Errorf("invalid network.subnet %q: %w", c.Network.Subnet, err)
 	}
-	return DefaultHTTPSPort
+	base := subnet.IP.To4()
+	if base == nil {
+		return nil, 0, fmt.Errorf("network.subnet must be an IPv4 CIDR")
+	}
+	ones, bits := subnet.Mask.Size()
+	hosts := c.MeshHosts()
+	usable := (1 << (bits - ones)) - 2
+	if len(hosts) > usable {
+		return nil, 0, fmt.Errorf("network.subnet %s has room for %d hosts, need %d", c.Network.Subnet, usable, len(hosts))
+	}
+	start := binary.BigEndian.Uint32(base)
+	addresses := make(map[string]string, len(hosts))
+	taken := make(map[uint32]string)
+	for _, host := range hosts {
+		pinned, ok := c.Network.Addresses[host]
+		if !ok {
+			continue
+		}
+		ip := net.ParseIP(pinned).To4()
+		if ip == nil || !subnet.Contains(ip) {
+			return nil, 0, &meshAddressError{host: host, message: fmt.Sprintf("%s is not an IPv4 address in %s", pinned, c.Network.Subnet)}
+		}
+		n := binary.BigEndian.Uint32(ip)
+		if n == start || n == start+uint32(usable)+1 {
+			return nil, 0, &meshAddressError{host: host, message: fmt.Sprintf("%s is the network or broadcast address of %s", pinned, c.Network.Subnet)}
+		}
+		if other, ok := taken[n]; ok {
+			return nil, 0, &meshAddressError{host: host, message: fmt.Sprintf("%s is already pinned for %s", pinned, other)}
+		}
+		taken[n] = host
+		addresses[host] = ip.String()
+	}
+	next := start + 1
+	for _, host := range hosts {
+		if _, ok := addresses[host]; ok {
+			continue
+		}
+		for taken[next] != "" {
+			next++
+		}
+		ip := make(net.IP, 4)
+		binary.BigEndian.PutUint32(ip, next)
+		taken[next] = host
+		addresses[host] = ip.String()
+	}
+	return addresses, ones, nil
 }
 
-// UseHostPortUpstreams reports whether app containers should be registered in
-// Caddy using host loopback ports instead of Podman network DNS names.
-func (c *Config) UseHostPortUpstreams() bool {
-	return c != nil && c.Podman.Rootless && c.Proxy.Rootful
+// meshAddressError reports an invalid network.addresses entry.
+type meshAddressError struct {
+	host    string
+	message string
+}
+
+func (e *meshAddressError) Error() string {
+	return fmt.Sprintf("network.addresses.%s: %s", e.host, e.message)
+}
+
+// MeshAddress returns host's WireGuard mesh address, or "" when the mesh is
+// disabled or host is not part of it.
+func (c *Config) MeshAddress(host string) string {
+	if c == nil || !c.Network.WireGuard {
+		return ""
+	}
+	addresses, _, err := c.MeshAddresses()
+	if err != nil {
+		return ""
+	}
+	return addresses[host]
 }
 
 // PrimaryHost returns the first configured proxy host.
@@ -318,6 +1352,36 @@ func (p ProxyConfig) AllHosts() []string {
 	return hosts
 }
 
+// DNS providers.
+const (
+	DNSProviderCloudflare = "cloudflare"
+)
+
+// DNSConfig lets setup manage the A and AAAA records of the proxy hostnames
+// at a DNS provider.
+type DNSConfig struct {
+	// DNS provider: cloudflare
+	Provider string `yaml:"provider"`
+
+	// Zone holding the proxy hostnames, e.g. example.com
+	Zone string `yaml:"zone"`
+
+	// Secret holding the provider's API token
+	TokenSecret string `yaml:"token_secret"`
+
+	// Record TTL in seconds (default: the provider's automatic TTL)
+	TTL int `yaml:"ttl"`
+
+	// Addresses the records point at (default: the public addresses of
+	// the proxy hosts)
+	Targets []string `yaml:"targets"`
+}
+
+// Enabled reports whether DNS records are managed.
+func (d DNSConfig) Enabled() bool {
+	return d.Provider != ""
+}
+
 // DefaultHealthcheckHelperImage is pinned to the multi-platform index for
 // curlimages/curl 8.5.0. Update the tag and digest together after review.
 const DefaultHealthcheckHelperImage = "docker.io/curlimages/curl:8.5.0@sha256:08e466006f0860e54fc299378de998935333e0e130a15f6f98482e9f8dab3058"
@@ -391,6 +1455,43 @@ type BufferingConfig struct {
 	Memory int64 `yaml:"memory"`
 }
 
+// FailoverConfig holds retry and passive health settings. Retries move a
+// request that cannot reach an upstream to another one; passive health
+// takes an upstream out of selection after repeated failures.
+type FailoverConfig struct {
+	// How long to keep trying upstreams for one request (Caddy lb_try_duration).
+	// Empty disables retries by duration.
+	TryDuration string `yaml:"try_duration"`
+
+	// Wait between upstream attempts (Caddy lb_try_interval, default 250ms)
+	TryInterval string `yaml:"try_interval"`
+
+	// Maximum number of retries per request (Caddy lb_retries)
+	Retries int `yaml:"retries"`
+
+	// How long a failed request counts against an upstream (default: 30s)
+	FailDuration string `yaml:"fail_duration"`
+
+	// Failures within fail_duration that mark an upstream down (default: 3)
+	MaxFails int `yaml:"max_fails"`
+}
+
+// TryDuration returns how long Caddy keeps trying upstreams for one
+// request: the longer of failover.try_duration and hold_timeout.
+func (p ProxyConfig) TryDuration() string {
+	if p.HoldTimeout == "" {
+		return p.Failover.TryDuration
+	}
+	hold, err := time.ParseDuration(p.HoldTimeout)
+	if err != nil {
+		return p.Failover.TryDuration
+	}
+	if try, err := time.ParseDuration(p.Failover.TryDuration); err == nil && try >= hold {
+		return p.Failover.TryDuration
+	}
+	return p.HoldTimeout
+}
+
 // LoggingConfig holds logging settings
 type LoggingConfig struct {
 	// Enable access logging (even without header redaction)
@@ -403,6 +1504,12 @@ type LoggingConfig struct {
 	RedactResponseHeaders []string `yaml:"redact_response_headers"`
 }
 
+// AccessLog reports whether the proxy writes access logs: when enabled, or
+// when headers are redacted from them.
+func (l LoggingConfig) AccessLog() bool {
+	return l.Enabled || len(l.RedactRequestHeaders) > 0 || len(l.RedactResponseHeaders) > 0
+}
+
 // UnmarshalYAML supports both old (request_headers/response_headers) and
 // new (redact_request_headers/redact_response_headers) YAML field names.
 func (l *LoggingConfig) UnmarshalYAML(unmarshal func(interface{}) error) error {
@@ -464,9 +1571,117 @@ type AccessoryConfig struct {
 	// Roles that need access to this accessory
 	Roles []string `yaml:"roles"`
 
+	// Podman networks to attach (default: [azud])
+	Networks []string `yaml:"networks"`
+
 	// Maximum time to wait for the accessory to become healthy after start.
 	// Defaults to 30s if nil. Set to 0s to skip health monitoring.
 	BootTimeout *time.Duration `yaml:"boot_timeout"`
+
+	// Readiness check run as the container's healthcheck
+	Healthcheck AccessoryHealthcheck `yaml:"healthcheck"`
+
+	// Built-in profile filling in the accessory's defaults; "standard"
+	// configures Postgres
+	Profile string `yaml:"profile"`
+
+	// WAL archiving to S3 of a Postgres accessory with the standard profile
+	Backup AccessoryBackupConfig `yaml:"backup"`
+
+	// Password and TLS wiring of a Redis or Memcached accessory
+	Cache AccessoryCacheConfig `yaml:"cache"`
+
+	// User the container runs as: user, user:group, or uid:gid
+	User string `yaml:"user"`
+
+	// Entrypoint replacing the image's
+	Entrypoint string `yaml:"entrypoint"`
+
+	// Run an init process as PID 1 that forwards signals and reaps zombies
+	Init bool `yaml:"init"`
+
+	// Signal that stops the container (default: the image's STOPSIGNAL)
+	StopSignal string `yaml:"stop_signal"`
+
+	// Time to wait after stop_signal before killing the container
+	// (default: 30s)
+	StopTimeout time.Duration `yaml:"stop_timeout"`
+}
+
+// AccessoryHealthcheck is the readiness check of an accessory: a command
+// run in the container, or a TCP port the container must listen on.
+type AccessoryHealthcheck struct {
+	// Command that exits 0 once the accessory is ready,
+	// e.g. "pg_isready -U postgres"
+	Cmd string `yaml:"cmd"`
+
+	// Container port that must be listening, e.g. 5432
+	Port int `yaml:"port"`
+
+	// Time between checks once the accessory is up (default: 10s)
+	Interval time.Duration `yaml:"interval"`
+
+	// Time a single check may take (default: 5s)
+	Timeout time.Duration `yaml:"timeout"`
+}
+
+// IsSet reports whether a command or port check is configured.
+func (h AccessoryHealthcheck) IsSet() bool {
+	return h.Cmd != "" || h.Port != 0
+}
+
+// AccessoryBackupConfig configures the sidecar accessory that uploads the
+// WAL files of a profiled Postgres accessory to S3.
+type AccessoryBackupConfig struct {
+	// Destination of the WAL files, e.g. s3://backups/app/wal
+	S3 string `yaml:"s3"`
+
+	// Endpoint of an S3-compatible store (default: AWS)
+	Endpoint string `yaml:"endpoint"`
+
+	// Region of the bucket, passed as AWS_DEFAULT_REGION
+	Region string `yaml:"region"`
+
+	// Time between uploads (default: 60s)
+	Interval time.Duration `yaml:"interval"`
+
+	// Image of the sidecar (default: DefaultWALArchiverImage)
+	Image string `yaml:"image"`
+}
+
+// AccessoryCacheConfig wires a Redis or Memcached accessory into the
+// application: a generated password and a self-signed TLS certificate.
+type AccessoryCacheConfig struct {
+	// Cache server: redis or memcached
+	Engine string `yaml:"engine"`
+
+	// Require a password generated into the secrets store
+	Password bool `yaml:"password"`
+
+	// Serve TLS with a self-signed certificate trusted by the app hosts
+	TLS bool `yaml:"tls"`
+}
+
+// ContainerPort returns the port the accessory listens on in its container:
+// the container side of its port mapping, else the port of its cache engine,
+// healthcheck, or profile. It returns 0 when none is known.
+func (a *AccessoryConfig) ContainerPort() int {
+	if a.Port != "" {
+		spec := a.Port[strings.LastIndex(a.Port, ":")+1:]
+		spec, _, _ = strings.Cut(spec, "/")
+		if port, err := strconv.Atoi(spec); err == nil {
+			return port
+		}
+	}
+	switch {
+	case a.Cache.Engine != "":
+		return a.Cache.Port()
+	case a.Healthcheck.Port > 0:
+		return a.Healthcheck.Port
+	case a.Profile == AccessoryProfileStandard:
+		return 5432
+	}
+	return 0
 }
 
 // DefaultAccessoryBootTimeout is the default time to wait for an accessory
@@ -482,6 +1697,18 @@ func (a *AccessoryConfig) GetBootTimeout() time.Duration {
 	return DefaultAccessoryBootTimeout
 }
 
+// DefaultAccessoryStopTimeout is the default time an accessory gets to
+// stop after its stop signal.
+const DefaultAccessoryStopTimeout = 30 * time.Second
+
+// GetStopTimeout returns the accessory's stop timeout in seconds.
+func (a *AccessoryConfig) GetStopTimeout() int {
+	if a.StopTimeout > 0 {
+		return int(a.StopTimeout.Seconds())
+	}
+	return int(DefaultAccessoryStopTimeout.Seconds())
+}
+
 // FileMapping represents a file to upload and mount
 type FileMapping struct {
 	// Local file path
@@ -498,6 +1725,12 @@ type FileMapping struct {
 }
 
 // DeployConfig holds deployment settings
+// Image transfer modes for deploy.image_transfer.
+const (
+	ImageTransferRegistry = "registry"
+	ImageTransferPeer     = "peer"
+)
+
 type DeployConfig struct {
 	// Delay before starting health checks
 	ReadinessDelay time.Duration `yaml:"readiness_delay"`
@@ -514,6 +1747,13 @@ type DeployConfig struct {
 	// Number of old containers to retain
 	RetainContainers int `yaml:"retain_containers"`
 
+	// Maximum hosts pulling the image at once (0 = all)
+	PullRateLimit int `yaml:"pull_rate_limit"`
+
+	// How hosts get the image: "registry" (default) pulls it on every host,
+	// "peer" pulls it on one host and copies it from host to host
+	ImageTransfer string `yaml:"image_transfer"`
+
 	// Number of deployment history records to retain
 	RetainHistory int `yaml:"retain_history"`
 
@@ -532,10 +1772,79 @@ type DeployConfig struct {
 	// to false.
 	AllowUnverifiedImage bool `yaml:"allow_unverified_image"`
 
+	// RequireDigest resolves the image tag to a digest once, on the first
+	// host, and deploys that digest reference everywhere
+	RequireDigest bool `yaml:"require_digest"`
+
+	// Pause before touching hosts until the deployment plan is approved
+	RequireApproval bool `yaml:"require_approval"`
+
+	// Webhook that approves or rejects the plan. When set, it decides
+	// instead of an interactive prompt or --yes.
+	ApprovalWebhook string `yaml:"approval_webhook"`
+
+	// How long to wait for the approval webhook. Default: 15m.
+	ApprovalTimeout time.Duration `yaml:"approval_timeout"`
+
+	// Halt the rollout between hosts when the proxy error rate of the host
+	// just deployed exceeds a threshold
+	HaltOnErrorRate ErrorRateGateConfig `yaml:"halt_on_error_rate"`
+
+	// Restart-loop detection used by azud watch
+	RestartLoop RestartLoopConfig `yaml:"restart_loop"`
+
+	// Automatic redeploys made by azud watch
+	Auto AutoDeployConfig `yaml:"auto"`
+
+	// Days and hours deploys are allowed in
+	Windows DeployWindowsConfig `yaml:"windows"`
+
 	// Canary deployment configuration
 	Canary CanaryConfig `yaml:"canary"`
 }
 
+// ErrorRateGateConfig holds the error-rate gate applied between hosts during
+// a sequential rollout
+type ErrorRateGateConfig struct {
+	// Maximum percentage of 5xx responses (0 disables the gate)
+	Threshold float64 `yaml:"threshold"`
+
+	// How long to observe traffic after each host is deployed. Default: 30s.
+	Window time.Duration `yaml:"window"`
+
+	// Minimum number of requests in the window before the rate is judged.
+	// Default: 10.
+	MinRequests int `yaml:"min_requests"`
+}
+
+// Enabled reports whether the error-rate gate is configured.
+func (g *ErrorRateGateConfig) Enabled() bool {
+	return g.Threshold > 0
+}
+
+// RestartLoopConfig holds the restart-loop detection applied by azud watch
+type RestartLoopConfig struct {
+	// Restarts within the window that count as a loop. Default: 3.
+	Threshold int `yaml:"threshold"`
+
+	// How far back restarts are counted. Default: 10m.
+	Window time.Duration `yaml:"window"`
+
+	// Roll back to the previous version when a loop is detected this soon
+	// after the last successful deployment (0 disables)
+	RollbackWithin time.Duration `yaml:"rollback_within"`
+}
+
+// AutoDeployConfig holds the automatic redeploys made by azud watch
+type AutoDeployConfig struct {
+	// Redeploy when the configured image tag points at a new digest in the
+	// registry, as azud deploy --if-new-digest does
+	WatchTag bool `yaml:"watch_tag"`
+
+	// How often the registry is checked. Default: 5m.
+	Interval time.Duration `yaml:"interval"`
+}
+
 // GetStopTimeout returns the configured stop timeout, defaulting to 30s.
 func (d *DeployConfig) GetStopTimeout() int {
 	if d.StopTimeout > 0 {
@@ -544,6 +1853,15 @@ func (d *DeployConfig) GetStopTimeout() int {
 	return 30
 }
 
+// RoleStopTimeout returns the stop timeout in seconds for the role's
+// containers: the role's stop_timeout, or deploy.stop_timeout.
+func (c *Config) RoleStopTimeout(role string) int {
+	if timeout := c.Servers[role].StopTimeout; timeout > 0 {
+		return int(timeout.Seconds())
+	}
+	return c.Deploy.GetStopTimeout()
+}
+
 // CanaryConfig holds canary deployment settings
 type CanaryConfig struct {
 	// Enable canary deployment mode
@@ -590,6 +1908,50 @@ type SSHConfig struct {
 
 	// Skip host key verification (not recommended for production)
 	InsecureIgnoreHostKey bool `yaml:"insecure_ignore_host_key"`
+
+	// Dial IPv6 when a hostname resolves to both IPv4 and IPv6
+	PreferIPv6 bool `yaml:"prefer_ipv6"`
+
+	// Maximum concurrent SSH commands across all hosts (default: unbounded)
+	MaxConcurrent int `yaml:"max_concurrent"`
+
+	// Maximum concurrent SSH commands on one host (default: 8), e.g. lower
+	// for small VMs where many parallel Podman calls contend
+	MaxConcurrentPerHost int `yaml:"max_concurrent_per_host"`
+
+	// Retries of dropped connections and failed image pulls
+	Retry SSHRetryConfig `yaml:"retry"`
+
+	// Secret holding the passphrase of encrypted keys. Without it, azud
+	// asks for the passphrase when run in a terminal.
+	KeyPassphraseSecret string `yaml:"key_passphrase_secret"`
+
+	// Add keys decrypted with a passphrase to ssh-agent, so later runs do
+	// not ask again
+	AddKeysToAgent bool `yaml:"add_keys_to_agent"`
+
+	// SSH agent socket (default: SSH_AUTH_SOCK), e.g. of yubikey-agent or
+	// the agent of a password manager holding hardware-backed keys
+	AgentSocket string `yaml:"agent_socket"`
+}
+
+// SSHRetryConfig retries SSH connections and sessions that fail before a
+// command starts, and image pulls that fail for transient registry or
+// network errors, with exponential backoff. Commands that started are not
+// retried, since running them twice may not be safe.
+type SSHRetryConfig struct {
+	// Attempts in total, including the first (default: 3; 1 disables retries)
+	Attempts int `yaml:"attempts"`
+
+	// Wait before the first retry, doubled for each later one (default: 1s)
+	Backoff time.Duration `yaml:"backoff"`
+
+	// Upper bound of the wait (default: 30s)
+	MaxBackoff time.Duration `yaml:"max_backoff"`
+
+	// Fraction of each wait randomized, from 0 to 1, so hosts do not retry
+	// in lockstep (default: 0.2 when no retry option is set)
+	Jitter float64 `yaml:"jitter"`
 }
 
 // SecurityConfig enforces security-related policy checks.
@@ -688,6 +2050,35 @@ func (c *Config) GetRoleHosts(role string) []string {
 	return nil
 }
 
+// GetHostTags returns the tags of host when it runs role: the role's tags
+// followed by the host's own, without duplicates.
+func (c *Config) GetHostTags(host, role string) []string {
+	var tags []string
+	for _, tag := range append(slices.Clone(c.Servers[role].Tags), c.HostTags[host]...) {
+		if !slices.Contains(tags, tag) {
+			tags = append(tags, tag)
+		}
+	}
+	return tags
+}
+
+// TagEnv returns the env.tags variables for the app containers of role on
+// host. Blocks are applied in tag order, so a later tag wins. A key=value
+// tag selects the block named after it, or else the one named by its value.
+func (c *Config) TagEnv(host, role string) map[string]string {
+	env := make(map[string]string)
+	for _, tag := range c.GetHostTags(host, role) {
+		block, ok := c.Env.Tags[tag]
+		if _, value, isPair := strings.Cut(tag, "="); !ok && isPair {
+			block = c.Env.Tags[value]
+		}
+		for key, value := range block {
+			env[key] = value
+		}
+	}
+	return env
+}
+
 // GetAccessoryHosts returns all unique hosts used by accessories
 func (c *Config) GetAccessoryHosts() []string {
 	hostSet := make(map[string]bool)
@@ -710,6 +2101,229 @@ func (c *Config) GetAccessoryHosts() []string {
 	return hosts
 }
 
+// NamedVolume is a Podman named volume referenced by a volumes entry.
+type NamedVolume struct {
+	Name   string
+	Target string
+	// Accessory owning the volume; empty for application volumes.
+	Accessory string
+}
+
+// SELinux label modes for podman.selinux_label.
+const (
+	SELinuxLabelShared  = "shared"
+	SELinuxLabelPrivate = "private"
+)
+
+// volumeOptions lists the mount options Podman accepts after a volume's
+// target.
+var volumeOptions = map[string]bool{
+	"ro": true, "rw": true, "z": true, "Z": true, "U": true, "O": true,
+	"copy": true, "nocopy": true, "exec": true, "noexec": true,
+	"dev": true, "nodev": true, "suid": true, "nosuid": true,
+	"bind": true, "rbind": true, "idmap": true,
+	"shared": true, "rshared": true, "slave": true, "rslave": true,
+	"private": true, "rprivate": true, "unbindable": true, "runbindable": true,
+}
+
+// VolumeSpec is a volumes entry split into source, target, and options.
+type VolumeSpec struct {
+	Source  string
+	Target  string
+	Options []string
+}
+
+// ParseVolume parses a source:target[:options] volumes entry, where options
+// is a comma-separated list such as ro,z.
+func ParseVolume(spec string) (VolumeSpec, error) {
+	parts := strings.SplitN(spec, ":", 3)
+	if len(parts) < 2 || parts[0] == "" {
+		return VolumeSpec{}, fmt.Errorf("expected source:target[:options]")
+	}
+	volume := VolumeSpec{Source: parts[0], Target: parts[1]}
+	if !strings.HasPrefix(volume.Target, "/") {
+		return VolumeSpec{}, fmt.Errorf("target %q must be an absolute path", volume.Target)
+	}
+	if len(parts) == 3 {
+		volume.Options = strings.Split(parts[2], ",")
+	}
+	for _, option := range volume.Options {
+		if !volumeOptions[option] {
+			return VolumeSpec{}, fmt.Errorf("unknown volume option %q", option)
+		}
+	}
+	if volume.HasOption("z") && volume.HasOption("Z") {
+		return VolumeSpec{}, fmt.Errorf("options z and Z cannot be combined")
+	}
+	if volume.HasOption("ro") && volume.HasOption("rw") {
+		return VolumeSpec{}, fmt.Errorf("options ro and rw cannot be combined")
+	}
+	return volume, nil
+}
+
+// HasOption reports whether the volume sets the mount option.
+func (v VolumeSpec) HasOption(option string) bool {
+	return slices.Contains(v.Options, option)
+}
+
+// Relabeled reports whether the volume asks Podman for an SELinux label.
+func (v VolumeSpec) Relabeled() bool {
+	return v.HasOption("z") || v.HasOption("Z")
+}
+
+// String formats the volume as a source:target[:options] entry.
+func (v VolumeSpec) String() string {
+	spec := v.Source + ":" + v.Target
+	if len(v.Options) > 0 {
+		spec += ":" + strings.Join(v.Options, ",")
+	}
+	return spec
+}
+
+// LabelVolumes returns specs with the podman.selinux_label option added to
+// host path volumes that set neither z nor Z. Podman labels named volumes
+// itself, and entries that do not parse are returned unchanged.
+func (c *Config) LabelVolumes(specs []string) []string {
+	var label string
+	switch c.Podman.SELinuxLabel {
+	case SELinuxLabelShared:
+		label = "z"
+	case SELinuxLabelPrivate:
+		label = "Z"
+	default:
+		return specs
+	}
+	labeled := make([]string, len(specs))
+	for i, spec := range specs {
+		labeled[i] = spec
+		volume, err := ParseVolume(spec)
+		if err != nil || IsNamedVolumeSource(volume.Source) || volume.Relabeled() {
+			continue
+		}
+		volume.Options = append(volume.Options, label)
+		labeled[i] = volume.String()
+	}
+	return labeled
+}
+
+// IsNamedVolumeSource reports whether a volume source is a Podman named
+// volume rather than a host path.
+func IsNamedVolumeSource(source string) bool {
+	if source == "" || strings.ContainsAny(source, "/$") {
+		return false
+	}
+	return source[0] != '.' && source[0] != '~'
+}
+
+// NamedVolumes returns the named volumes declared for the application and
+// its accessories. Application volumes come first; each name appears once.
+func (c *Config) NamedVolumes() []NamedVolume {
+	seen := make(map[string]bool)
+	var volumes []NamedVolume
+	add := func(specs []string, accessory string) {
+		for _, spec := range specs {
+			parts := strings.SplitN(spec, ":", 3)
+			if len(parts) < 2 || !IsNamedVolumeSource(parts[0]) || seen[parts[0]] {
+				continue
+			}
+			seen[parts[0]] = true
+			volumes = append(volumes, NamedVolume{Name: parts[0], Target: parts[1], Accessory: accessory})
+		}
+	}
+	add(c.Volumes, "")
+	for _, name := range c.GetAccessoryNames() {
+		add(c.Accessories[name].Volumes, name)
+	}
+	return volumes
+}
+
+// GetNamedVolume returns the declared named volume with the given name.
+func (c *Config) GetNamedVolume(name string) (NamedVolume, bool) {
+	for _, volume := range c.NamedVolumes() {
+		if volume.Name == name {
+			return volume, true
+		}
+	}
+	return NamedVolume{}, false
+}
+
+// GetVolumeHosts returns the hosts where a named volume lives: the
+// accessory's hosts, or every host running application or cron containers.
+func (c *Config) GetVolumeHosts(volume NamedVolume) []string {
+	if volume.Accessory != "" {
+		accessory := c.Accessories[volume.Accessory]
+		if len(accessory.Hosts) > 0 {
+			return accessory.Hosts
+		}
+		if accessory.Host != "" {
+			return []string{accessory.Host}
+		}
+		return nil
+	}
+
+	hostSet := make(map[string]bool)
+	var hosts []string
+	for _, role := range c.GetRoles() {
+		if c.IsProxyTierRole(role) {
+			continue
+		}
+		for _, host := range c.Servers[role].Hosts {
+			if !hostSet[host] {
+				hostSet[host] = true
+				hosts = append(hosts, host)
+			}
+		}
+	}
+	for _, host := range c.GetAllCronHosts() {
+		if !hostSet[host] {
+			hostSet[host] = true
+			hosts = append(hosts, host)
+		}
+	}
+	return hosts
+}
+
+// ReplaceHost returns a copy of the configuration in which from is replaced
+// by to in every role and cron job. It lets a move deploy to the replacement
+// host before the configuration file itself is edited.
+func (c *Config) ReplaceHost(from, to string) *Config {
+	replace := func(hosts []string) []string {
+		if hosts == nil {
+			return nil
+		}
+		replaced := make([]string, 0, len(hosts))
+		seen := make(map[string]bool)
+		for _, host := range hosts {
+			if host == from {
+				host = to
+			}
+			if !seen[host] {
+				seen[host] = true
+				replaced = append(replaced, host)
+			}
+		}
+		return replaced
+	}
+
+	moved := *c
+	moved.Servers = make(map[string]RoleConfig, len(c.Servers))
+	for name, role := range c.Servers {
+		role.Hosts = replace(role.Hosts)
+		moved.Servers[name] = role
+	}
+	if c.Cron != nil {
+		moved.Cron = make(map[string]CronConfig, len(c.Cron))
+		for name, job := range c.Cron {
+			if job.Host == from {
+				job.Host = to
+			}
+			job.Hosts = replace(job.Hosts)
+			moved.Cron[name] = job
+		}
+	}
+	return &moved
+}
+
 // HasRole checks if a role is defined
 func (c *Config) HasRole(role string) bool {
 	_, ok := c.Servers[role]
@@ -825,8 +2439,8 @@ func (c *Config) GetAllSSHHosts() []string {
 	for _, host := range c.GetAllCronHosts() {
 		addHost(host)
 	}
-	if c.Builder.Remote.Host != "" {
-		addHost(c.Builder.Remote.Host)
+	for _, builder := range c.Builder.Remote.All() {
+		addHost(builder.Host)
 	}
 	if c.SSH.Proxy.Host != "" {
 		addHost(c.SSH.Proxy.Host)
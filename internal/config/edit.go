@@ -0,0 +1,149 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// SetTrustedHostFingerprints records fingerprints as the trusted host keys of
+// target in ssh.trusted_host_fingerprints of the config file at path,
+// replacing an existing entry for target. Only the lines of that block
+// change, so the comments and layout of the file are kept.
+func SetTrustedHostFingerprints(path, target string, fingerprints []string) error {
+	info, err := os.Stat(path)
+	if err != nil {
+		return err
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	updated, err := setTrustedHostFingerprints(data, target, fingerprints)
+	if err != nil {
+		return fmt.Errorf("%s: %w", path, err)
+	}
+	return os.WriteFile(path, updated, info.Mode().Perm())
+}
+
+func setTrustedHostFingerprints(data []byte, target string, fingerprints []string) ([]byte, error) {
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("failed to parse YAML: %w", err)
+	}
+
+	text := strings.TrimSuffix(string(data), "\n")
+	var lines []string
+	if text != "" {
+		lines = strings.Split(text, "\n")
+	}
+	entry := func(indent string) []string {
+		out := []string{indent + strconv.Quote(target) + ":"}
+		for _, fp := range fingerprints {
+			out = append(out, indent+"  - "+strconv.Quote(fp))
+		}
+		return out
+	}
+	done := func(lines []string) []byte {
+		return []byte(strings.Join(lines, "\n") + "\n")
+	}
+
+	var root *yaml.Node
+	if doc.Kind == yaml.DocumentNode && len(doc.Content) > 0 {
+		root = doc.Content[0]
+	}
+	if root != nil && root.Kind != yaml.MappingNode {
+		return nil, fmt.Errorf("the top level is not a mapping")
+	}
+
+	sshKey, sshValue := mappingEntry(root, "ssh")
+	if sshKey == nil {
+		lines = append(lines, "ssh:", "  trusted_host_fingerprints:")
+		return done(append(lines, entry("    ")...)), nil
+	}
+	if emptyInline(sshKey, sshValue) {
+		lines[sshKey.Line-1] = strings.Repeat(" ", sshKey.Column-1) + "ssh:"
+		sshValue = nil
+	} else if !blockMapping(sshValue) {
+		return nil, fmt.Errorf("ssh is not a block mapping; add the fingerprints by hand")
+	}
+
+	sectionIndent := strings.Repeat(" ", sshKey.Column+1)
+	if sshValue != nil {
+		sectionIndent = strings.Repeat(" ", sshValue.Content[0].Column-1)
+	}
+	fpKey, fpValue := mappingEntry(sshValue, "trusted_host_fingerprints")
+	if fpKey == nil {
+		block := append([]string{sectionIndent + "trusted_host_fingerprints:"}, entry(sectionIndent+"  ")...)
+		return done(insertLines(lines, sshKey.Line, block)), nil
+	}
+	if emptyInline(fpKey, fpValue) {
+		lines[fpKey.Line-1] = sectionIndent + "trusted_host_fingerprints:"
+		fpValue = nil
+	} else if !blockMapping(fpValue) {
+		return nil, fmt.Errorf("ssh.trusted_host_fingerprints is not a block mapping; add the fingerprints by hand")
+	}
+
+	entryIndent := sectionIndent + "  "
+	if fpValue != nil {
+		entryIndent = strings.Repeat(" ", fpValue.Content[0].Column-1)
+		if key, value := mappingEntry(fpValue, target); key != nil {
+			lines = append(lines[:key.Line-1], lines[lastLine(value):]...)
+		}
+	}
+	return done(insertLines(lines, fpKey.Line, entry(entryIndent))), nil
+}
+
+// mappingEntry returns the key and value nodes of key in mapping.
+func mappingEntry(mapping *yaml.Node, key string) (*yaml.Node, *yaml.Node) {
+	if mapping == nil || mapping.Kind != yaml.MappingNode {
+		return nil, nil
+	}
+	for i := 0; i+1 < len(mapping.Content); i += 2 {
+		if mapping.Content[i].Value == key {
+			return mapping.Content[i], mapping.Content[i+1]
+		}
+	}
+	return nil, nil
+}
+
+// blockMapping reports whether node is a non-empty mapping in block style,
+// whose entries sit on lines of their own.
+func blockMapping(node *yaml.Node) bool {
+	return node != nil && node.Kind == yaml.MappingNode && node.Style&yaml.FlowStyle == 0 && len(node.Content) > 0
+}
+
+// emptyInline reports whether value is empty (null or {}) and written on
+// the line of key.
+func emptyInline(key, value *yaml.Node) bool {
+	if value.Line != key.Line {
+		return false
+	}
+	switch value.Kind {
+	case yaml.ScalarNode:
+		return value.Tag == "!!null"
+	case yaml.MappingNode:
+		return len(value.Content) == 0
+	}
+	return false
+}
+
+// lastLine returns the last line node and its children occupy.
+func lastLine(node *yaml.Node) int {
+	last := node.Line
+	for _, child := range node.Content {
+		last = max(last, lastLine(child))
+	}
+	return last
+}
+
+// insertLines inserts block after the first n lines.
+func insertLines(lines []string, n int, block []string) []string {
+	out := make([]string, 0, len(lines)+len(block))
+	out = append(out, lines[:n]...)
+	out = append(out, block...)
+	return append(out, lines[n:]...)
+}
@@ -0,0 +1,62 @@
+package config
+
+import (
+	"testing"
+)
+
+func TestSetTrustedHostFingerprints(t *testing.T) {
+	tests := []struct {
+		name string
+		in   string
+		want string
+	}{
+		{
+			name: "no ssh section",
+			in:   "service: app\n",
+			want: "service: app\nssh:\n  trusted_host_fingerprints:\n    \"10.0.0.1\":\n      - \"SHA256:new\"\n",
+		},
+		{
+			name: "ssh without fingerprints",
+			in:   "ssh:\n    user: deploy # login\nservice: app\n",
+			want: "ssh:\n    trusted_host_fingerprints:\n      \"10.0.0.1\":\n        - \"SHA256:new\"\n    user: deploy # login\nservice: app\n",
+		},
+		{
+			name: "empty ssh section",
+			in:   "ssh:\nservice: app\n",
+			want: "ssh:\n  trusted_host_fingerprints:\n    \"10.0.0.1\":\n      - \"SHA256:new\"\nservice: app\n",
+		},
+		{
+			name: "other hosts kept",
+			in:   "ssh:\n  trusted_host_fingerprints:\n    10.0.0.2:\n      - SHA256:other\n",
+			want: "ssh:\n  trusted_host_fingerprints:\n    \"10.0.0.1\":\n      - \"SHA256:new\"\n    10.0.0.2:\n      - SHA256:other\n",
+		},
+		{
+			name: "existing entry replaced",
+			in:   "# hosts\nssh:\n  trusted_host_fingerprints:\n    10.0.0.1:\n      - SHA256:old\n      - SHA256:older\n    10.0.0.2: [SHA256:other]\n  port: 2222\n",
+			want: "# hosts\nssh:\n  trusted_host_fingerprints:\n    \"10.0.0.1\":\n      - \"SHA256:new\"\n    10.0.0.2: [SHA256:other]\n  port: 2222\n",
+		},
+		{
+			name: "empty fingerprints block",
+			in:   "ssh:\n  trusted_host_fingerprints: {}\n",
+			want: "ssh:\n  trusted_host_fingerprints:\n    \"10.0.0.1\":\n      - \"SHA256:new\"\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := setTrustedHostFingerprints([]byte(tt.in), "10.0.0.1", []string{"SHA256:new"})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(got) != tt.want {
+				t.Fatalf("got\n%s\nwant\n%s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSetTrustedHostFingerprintsRejectsFlowStyle(t *testing.T) {
+	if _, err := setTrustedHostFingerprints([]byte("ssh: {user: deploy}\n"), "10.0.0.1", []string{"SHA256:new"}); err == nil {
+		t.Fatal("expected an error for a flow-style ssh mapping")
+	}
+}
@@ -4,21 +4,37 @@ import (
 	"bufio"
 	"bytes"
 	"context"
+	"errors"
 	"fmt"
+	"io"
 	"os"
-	"os/exec"
 	"os/user"
 	"path/filepath"
+	"runtime"
+	"slices"
+	"strconv"
 	"strings"
 	"time"
 
 	"gopkg.in/yaml.v3"
+
+	"github.com/lemonity-org/azud/internal/shell"
 )
 
 // Loader handles configuration file loading and merging
 type Loader struct {
 	basePath    string
 	destination string
+
+	// Parsed YAML of the files read by the last load, used to locate
+	// validation problems
+	baseNode *yaml.Node
+	destPath string
+	destNode *yaml.Node
+
+	// Environment variables and secrets declared by the files read so far;
+	// references to them are not expanded from the local environment.
+	envNames map[string]bool
 }
 
 // NewLoader creates a new configuration loader
@@ -31,11 +47,131 @@ func NewLoader(basePath, destination string) *Loader {
 
 // Load reads and parses the configuration file(s)
 func (l *Loader) Load() (*Config, error) {
+	cfg, problems, err := l.Check()
+	if err != nil {
+		return nil, err
+	}
+	if errs := problems.Errors(); len(errs) > 0 {
+		return nil, fmt.Errorf("configuration validation failed: %w", errs)
+	}
+	return cfg, nil
+}
+
+// Check loads the configuration like Load but returns every validation
+// problem, warnings included, instead of failing on errors. Problems are
+// located in the destination file when it sets the field, otherwise in the
+// base file.
+func (l *Loader) Check() (*Config, ValidationErrors, error) {
+	cfg, err := l.LoadUnvalidated()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// A destination-specific secrets file (e.g. .azud/secrets.staging)
+	// replaces the shared one, so destinations never see each other's values.
+	if l.destination != "" {
+		destSecretsPath := cfg.SecretsPath + "." + l.destination
+		if _, err := os.Stat(destSecretsPath); err == nil {
+			cfg.SecretsPath = destSecretsPath
+		}
+	}
+
+	// Load secrets
+	if err := l.loadSecrets(cfg); err != nil {
+		return nil, nil, fmt.Errorf("failed to load secrets: %w", err)
+	}
+
+	problems := Check(cfg)
+	for i := range problems {
+		l.locate(&problems[i])
+	}
+	return cfg, problems, nil
+}
+
+// locate sets the file and position of problem from the parsed YAML.
+func (l *Loader) locate(problem *ValidationError) {
+	type candidate struct {
+		file string
+		node *yaml.Node
+	}
+	candidates := []candidate{{l.destPath, l.destNode}, {l.basePath, l.baseNode}}
+	for _, c := range candidates {
+		if line, column, exact := fieldPosition(c.node, problem.Field); exact {
+			problem.File, problem.Line, problem.Column = c.file, line, column
+			return
+		}
+	}
+	// Fall back to the closest enclosing key of the base file.
+	if line, column, _ := fieldPosition(l.baseNode, problem.Field); line > 0 {
+		problem.File, problem.Line, problem.Column = l.basePath, line, column
+	}
+}
+
+// fieldPosition returns the position of the YAML key named by a validation
+// field path such as proxy.streams[1].listen. Map keys may contain dots, so
+// the longest matching key wins. When the full path is not in the file, the
+// deepest enclosing key is returned with exact set to false.
+func fieldPosition(root *yaml.Node, field string) (line, column int, exact bool) {
+	node := root
+	if node != nil && node.Kind == yaml.DocumentNode && len(node.Content) > 0 {
+		node = node.Content[0]
+	}
+	rest := field
+	for rest != "" && node != nil {
+		if node.Kind == yaml.AliasNode {
+			node = node.Alias
+			continue
+		}
+		switch node.Kind {
+		case yaml.MappingNode:
+			best := -1
+			for i := 0; i+1 < len(node.Content); i += 2 {
+				key := node.Content[i].Value
+				if rest != key && !strings.HasPrefix(rest, key+".") && !strings.HasPrefix(rest, key+"[") {
+					continue
+				}
+				if best < 0 || len(key) > len(node.Content[best].Value) {
+					best = i
+				}
+			}
+			if best < 0 {
+				return line, column, false
+			}
+			key := node.Content[best]
+			line, column = key.Line, key.Column
+			rest = strings.TrimPrefix(strings.TrimPrefix(rest, key.Value), ".")
+			node = node.Content[best+1]
+		case yaml.SequenceNode:
+			end := strings.Index(rest, "]")
+			if !strings.HasPrefix(rest, "[") || end < 0 {
+				return line, column, false
+			}
+			index, err := strconv.Atoi(rest[1:end])
+			if err != nil || index < 0 || index >= len(node.Content) {
+				return line, column, false
+			}
+			node = node.Content[index]
+			line, column = node.Line, node.Column
+			rest = strings.TrimPrefix(rest[end+1:], ".")
+		default:
+			return line, column, false
+		}
+	}
+	return line, column, rest == "" && line > 0
+}
+
+// LoadUnvalidated reads the configuration with destination overrides and
+// defaults applied, but neither loads secrets nor validates. Shell completion
+// and alias registration use it because they run before every command and
+// must not execute a secrets command.
+func (l *Loader) LoadUnvalidated() (*Config, error) {
 	// Load base configuration
-	cfg, err := l.loadFile(l.basePath)
+	l.envNames = make(map[string]bool)
+	cfg, baseNode, err := l.loadFileWithNode(l.basePath)
 	if err != nil {
 		return nil, fmt.Errorf("failed to load config %s: %w", l.basePath, err)
 	}
+	l.baseNode, l.destPath, l.destNode = baseNode, "", nil
 
 	// Load destination-specific configuration if specified
 	if l.destination != "" {
@@ -46,23 +182,88 @@ func (l *Loader) Load() (*Config, error) {
 				return nil, fmt.Errorf("failed to load destination config %s: %w", destPath, err)
 			}
 			cfg = mergeConfigs(cfg, destCfg, destNode)
+			l.destPath, l.destNode = destPath, destNode
 		}
 	}
 
+	if err := loadEnvFiles(cfg); err != nil {
+		return nil, err
+	}
+
 	// Apply defaults
 	applyDefaults(cfg)
+	return cfg, nil
+}
 
-	// Load secrets
-	if err := l.loadSecrets(cfg); err != nil {
-		return nil, fmt.Errorf("failed to load secrets: %w", err)
+// loadEnvFiles merges the variables of env.files under env.clear, keeping
+// the files each came from for validation.
+func loadEnvFiles(cfg *Config) error {
+	if len(cfg.Env.Files) == 0 {
+		return nil
+	}
+	values := make(map[string]string)
+	cfg.envFileKeys = make(map[string]int)
+	for i, path := range cfg.Env.Files {
+		file, err := os.Open(path)
+		if err != nil {
+			return fmt.Errorf("failed to read env.files[%d]: %w", i, err)
+		}
+		parsed, err := parseDotenv(file)
+		_ = file.Close()
+		if err != nil {
+			return fmt.Errorf("failed to read env.files[%d] %s: %w", i, path, err)
+		}
+		for key, value := range parsed {
+			values[key] = value
+			cfg.envFileKeys[key] = i
+		}
 	}
 
-	// Validate configuration
-	if err := Validate(cfg); err != nil {
-		return nil, fmt.Errorf("configuration validation failed: %w", err)
+	if cfg.Env.Clear == nil {
+		cfg.Env.Clear = make(map[string]string)
+	}
+	for key, value := range values {
+		if _, ok := cfg.Env.Clear[key]; !ok {
+			cfg.Env.Clear[key] = value
+		}
 	}
+	return nil
+}
 
-	return cfg, nil
+// parseDotenv reads KEY=VALUE lines. Blank lines, comments, and lines
+// without '=' are skipped; an "export " prefix and matching surrounding
+// quotes are removed.
+func parseDotenv(r io.Reader) (map[string]string, error) {
+	values := make(map[string]string)
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+
+		// Skip empty lines and comments
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		// Parse KEY=VALUE
+		parts := strings.SplitN(line, "=", 2)
+		if len(parts) != 2 {
+			continue
+		}
+
+		key := strings.TrimSpace(strings.TrimPrefix(parts[0], "export "))
+		value := strings.TrimSpace(parts[1])
+
+		// Remove surrounding quotes if present
+		if len(value) >= 2 {
+			if (value[0] == '"' && value[len(value)-1] == '"') ||
+				(value[0] == '\'' && value[len(value)-1] == '\'') {
+				value = value[1 : len(value)-1]
+			}
+		}
+
+		values[key] = value
+	}
+	return values, scanner.Err()
 }
 
 // maxConfigFileSize is the maximum allowed size for a configuration file.
@@ -72,14 +273,19 @@ const maxConfigFileSize = 1 << 20 // 1 MiB
 // safeExpandEnv performs environment variable expansion only for variables
 // whose names match safe patterns (uppercase letters, digits, underscores).
 // This prevents accidental leakage of sensitive variables like AWS_SECRET_KEY
-// that might match patterns in the YAML file.
-func safeExpandEnv(s string) string {
+// that might match patterns in the YAML file. Names in keep are declared
+// environment variables or secrets; references to them are left for
+// ResolveEnv and the remote host.
+func safeExpandEnv(s string, keep map[string]bool) string {
 	return os.Expand(s, func(key string) string {
 		// $HOME in deployment configuration refers to the remote SSH user's
 		// home, not the workstation running Azud.
 		if key == "HOME" {
 			return "$HOME"
 		}
+		if keep[key] {
+			return fmt.Sprintf("${%s}", key)
+		}
 		// Only expand variables that look like intentional config references:
 		// uppercase letters, digits, and underscores (e.g., APP_NAME, PORT).
 		for _, c := range key {
@@ -91,37 +297,59 @@ func safeExpandEnv(s string) string {
 	})
 }
 
-// loadFile reads and parses a single YAML file
-func (l *Loader) loadFile(path string) (*Config, error) {
-	data, err := os.ReadFile(path)
-	if err != nil {
-		return nil, err
-	}
+// envNameSource is the part of a configuration file that declares
+// environment variables and secrets, decoded before variable expansion.
+type envNameSource struct {
+	Env struct {
+		Clear  map[string]any            `yaml:"clear"`
+		Secret []string                  `yaml:"secret"`
+		Tags   map[string]map[string]any `yaml:"tags"`
+	} `yaml:"env"`
+	Servers map[string]struct {
+		Env     map[string]any `yaml:"env"`
+		Secrets []string       `yaml:"secrets"`
+	} `yaml:"servers"`
+	Accessories map[string]struct {
+		Env struct {
+			Clear  map[string]any `yaml:"clear"`
+			Secret []string       `yaml:"secret"`
+		} `yaml:"env"`
+	} `yaml:"accessories"`
+	Cron map[string]struct {
+		Env map[string]any `yaml:"env"`
+	} `yaml:"cron"`
+}
 
-	if len(data) > maxConfigFileSize {
-		return nil, fmt.Errorf("config file exceeds maximum size (%d bytes)", maxConfigFileSize)
-	}
+// collectEnvNames adds the environment variables and secrets declared in
+// data to names. Parse errors are left to the strict decoder.
+func collectEnvNames(data []byte, names map[string]bool) {
+	var source envNameSource
+	_ = yaml.Unmarshal(data, &source)
 
-	// Expand only safe environment variables
-	data = []byte(safeExpandEnv(string(data)))
-	var node yaml.Node
-	if err := yaml.Unmarshal(data, &node); err != nil {
-		return nil, fmt.Errorf("failed to parse YAML: %w", err)
+	add := func(env map[string]any, secrets []string) {
+		for key := range env {
+			names[key] = true
+		}
+		for _, secret := range secrets {
+			names[secret] = true
+		}
 	}
-	if err := validateConfigSchema(&node); err != nil {
-		return nil, fmt.Errorf("failed to parse YAML: %w", err)
+	add(source.Env.Clear, source.Env.Secret)
+	for _, block := range source.Env.Tags {
+		add(block, nil)
 	}
-
-	var cfg Config
-	decoder := yaml.NewDecoder(bytes.NewReader(data))
-	decoder.KnownFields(true)
-	if err := decoder.Decode(&cfg); err != nil {
-		return nil, fmt.Errorf("failed to parse YAML: %w", err)
+	for _, role := range source.Servers {
+		add(role.Env, role.Secrets)
+	}
+	for _, accessory := range source.Accessories {
+		add(accessory.Env.Clear, accessory.Env.Secret)
+	}
+	for _, job := range source.Cron {
+		add(job.Env, nil)
 	}
-
-	return &cfg, nil
 }
 
+// loadFileWithNode reads and parses a single YAML file, returning its node tree
 func (l *Loader) loadFileWithNode(path string) (*Config, *yaml.Node, error) {
 	data, err := os.ReadFile(path)
 	if err != nil {
@@ -132,12 +360,17 @@ func (l *Loader) loadFileWithNode(path string) (*Config, *yaml.Node, error) {
 		return nil, nil, fmt.Errorf("config file exceeds maximum size (%d bytes)", maxConfigFileSize)
 	}
 
-	data = []byte(safeExpandEnv(string(data)))
+	collectEnvNames(data, l.envNames)
+	data = []byte(safeExpandEnv(string(data), l.envNames))
 	var node yaml.Node
 	if err := yaml.Unmarshal(data, &node); err != nil {
 		return nil, nil, fmt.Errorf("failed to parse YAML nodes: %w", err)
 	}
 	if err := validateConfigSchema(&node); err != nil {
+		var unknown *UnknownKeyError
+		if errors.As(err, &unknown) {
+			unknown.File = path
+		}
 		return nil, nil, fmt.Errorf("failed to parse YAML: %w", err)
 	}
 
@@ -153,11 +386,35 @@ func (l *Loader) loadFileWithNode(path string) (*Config, *yaml.Node, error) {
 
 // getDestinationPath returns the path for destination-specific config
 func (l *Loader) getDestinationPath() string {
-	dir := filepath.Dir(l.basePath)
-	ext := filepath.Ext(l.basePath)
-	base := strings.TrimSuffix(filepath.Base(l.basePath), ext)
+	return DestinationPath(l.basePath, l.destination)
+}
+
+// DestinationPath returns the overlay file of destination next to the base
+// configuration, such as config/deploy.staging.yml.
+func DestinationPath(basePath, destination string) string {
+	dir := filepath.Dir(basePath)
+	ext := filepath.Ext(basePath)
+	base := strings.TrimSuffix(filepath.Base(basePath), ext)
 
-	return filepath.Join(dir, fmt.Sprintf("%s.%s%s", base, l.destination, ext))
+	return filepath.Join(dir, fmt.Sprintf("%s.%s%s", base, destination, ext))
+}
+
+// Destinations returns the sorted names of the destinations that have an
+// overlay file next to the base configuration.
+func Destinations(basePath string) []string {
+	ext := filepath.Ext(basePath)
+	base := strings.TrimSuffix(filepath.Base(basePath), ext)
+	matches, _ := filepath.Glob(filepath.Join(filepath.Dir(basePath), base+".*"+ext))
+
+	var destinations []string
+	for _, match := range matches {
+		name := strings.TrimSuffix(strings.TrimPrefix(filepath.Base(match), base+"."), ext)
+		if name != "" && !strings.Contains(name, ".") {
+			destinations = append(destinations, name)
+		}
+	}
+	slices.Sort(destinations)
+	return destinations
 }
 
 // loadSecrets loads secrets from the secrets file
@@ -200,8 +457,9 @@ func (l *Loader) loadSecretsFromFile(cfg *Config) error {
 		return err
 	}
 
-	// Warn if the secrets file is readable by group or others
-	if perm := info.Mode().Perm(); perm&0077 != 0 {
+	// Warn if the secrets file is readable by group or others. Windows
+	// files carry ACLs instead of these bits, so there is nothing to check.
+	if perm := info.Mode().Perm(); perm&0077 != 0 && runtime.GOOS != "windows" {
 		fmt.Fprintf(os.Stderr, "  WARN   secrets file %s has insecure permissions %04o (recommended: 0600)\n", secretsPath, perm)
 	}
 
@@ -211,38 +469,8 @@ func (l *Loader) loadSecretsFromFile(cfg *Config) error {
 	}
 	defer func() { _ = file.Close() }()
 
-	secrets := make(map[string]string)
-	scanner := bufio.NewScanner(file)
-
-	for scanner.Scan() {
-		line := strings.TrimSpace(scanner.Text())
-
-		// Skip empty lines and comments
-		if line == "" || strings.HasPrefix(line, "#") {
-			continue
-		}
-
-		// Parse KEY=VALUE
-		parts := strings.SplitN(line, "=", 2)
-		if len(parts) != 2 {
-			continue
-		}
-
-		key := strings.TrimSpace(parts[0])
-		value := strings.TrimSpace(parts[1])
-
-		// Remove surrounding quotes if present
-		if len(value) >= 2 {
-			if (value[0] == '"' && value[len(value)-1] == '"') ||
-				(value[0] == '\'' && value[len(value)-1] == '\'') {
-				value = value[1 : len(value)-1]
-			}
-		}
-
-		secrets[key] = value
-	}
-
-	if err := scanner.Err(); err != nil {
+	secrets, err := parseDotenv(file)
+	if err != nil {
 		return err
 	}
 
@@ -291,7 +519,9 @@ func (l *Loader) loadSecretsFromCommand(cfg *Config) error {
 	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
 	defer cancel()
 
-	cmd := exec.CommandContext(ctx, "sh", "-c", cfg.SecretsCommand)
+	cmd := shell.Command(ctx, cfg.SecretsCommand)
+	// Let the command scope its lookup, e.g. to a per-destination vault path.
+	cmd.Env = append(os.Environ(), "AZUD_DESTINATION="+l.destination)
 	out, err := cmd.CombinedOutput()
 	if err != nil {
 		if ctx.Err() == context.DeadlineExceeded {
@@ -360,6 +590,22 @@ func mergeConfigs(base, dest *Config, destNode *yaml.Node) *Config {
 	if len(dest.Registry.Password) > 0 {
 		merged.Registry.Password = dest.Registry.Password
 	}
+	if dest.Registry.Helper != "" {
+		merged.Registry.Helper = dest.Registry.Helper
+	}
+	if dest.Registry.OAuth != nil {
+		merged.Registry.OAuth = dest.Registry.OAuth
+	}
+	if has("registries") {
+		merged.Registries = dest.Registries // replace (empty map clears)
+	} else if len(dest.Registries) > 0 {
+		if merged.Registries == nil {
+			merged.Registries = make(map[string]RegistryConfig)
+		}
+		for server, registry := range dest.Registries {
+			merged.Registries[server] = registry
+		}
+	}
 
 	// Merge env
 	if has("env", "clear") {
@@ -377,6 +623,11 @@ func mergeConfigs(base, dest *Config, destNode *yaml.Node) *Config {
 	} else if len(dest.Env.Secret) > 0 {
 		merged.Env.Secret = append(merged.Env.Secret, dest.Env.Secret...)
 	}
+	if has("env", "files") {
+		merged.Env.Files = dest.Env.Files // replace (empty list clears)
+	} else if len(dest.Env.Files) > 0 {
+		merged.Env.Files = append(merged.Env.Files, dest.Env.Files...)
+	}
 	if len(dest.Env.Tags) > 0 {
 		if merged.Env.Tags == nil {
 			merged.Env.Tags = make(map[string]map[string]string)
@@ -401,6 +652,9 @@ func mergeConfigs(base, dest *Config, destNode *yaml.Node) *Config {
 	if has("proxy", "app_port") || destNode == nil && dest.Proxy.AppPort != 0 {
 		merged.Proxy.AppPort = dest.Proxy.AppPort
 	}
+	if has("proxy", "app_socket") || destNode == nil && dest.Proxy.AppSocket != "" {
+		merged.Proxy.AppSocket = dest.Proxy.AppSocket
+	}
 	if dest.Proxy.UpstreamProtocol != "" {
 		merged.Proxy.UpstreamProtocol = dest.Proxy.UpstreamProtocol
 	}
@@ -410,9 +664,67 @@ func mergeConfigs(base, dest *Config, destNode *yaml.Node) *Config {
 	if has("proxy", "https_port") || destNode == nil && dest.Proxy.HTTPSPort != 0 {
 		merged.Proxy.HTTPSPort = dest.Proxy.HTTPSPort
 	}
+	if has("proxy", "bind") || destNode == nil && len(dest.Proxy.Bind) > 0 {
+		merged.Proxy.Bind = dest.Proxy.Bind
+	}
+	if has("proxy", "proxy_protocol") || destNode == nil && dest.Proxy.ProxyProtocol {
+		merged.Proxy.ProxyProtocol = dest.Proxy.ProxyProtocol
+	}
+	if has("proxy", "proxy_protocol_allow") || destNode == nil && len(dest.Proxy.ProxyProtocolAllow) > 0 {
+		merged.Proxy.ProxyProtocolAllow = dest.Proxy.ProxyProtocolAllow
+	}
 	if has("proxy", "rootful") || destNode == nil && dest.Proxy.Rootful {
 		merged.Proxy.Rootful = dest.Proxy.Rootful
 	}
+	if has("proxy", "image") || destNode == nil && dest.Proxy.Image != "" {
+		merged.Proxy.Image = dest.Proxy.Image
+	}
+	if len(dest.Proxy.ImageBuild.Modules) > 0 {
+		merged.Proxy.ImageBuild.Modules = dest.Proxy.ImageBuild.Modules
+	}
+	if dest.Proxy.ImageBuild.Repository != "" {
+		merged.Proxy.ImageBuild.Repository = dest.Proxy.ImageBuild.Repository
+	}
+	if has("proxy", "streams") || destNode == nil && len(dest.Proxy.Streams) > 0 {
+		merged.Proxy.Streams = dest.Proxy.Streams
+	}
+	if has("proxy", "ab_tests") || destNode == nil && len(dest.Proxy.ABTests) > 0 {
+		merged.Proxy.ABTests = dest.Proxy.ABTests
+	}
+	if dest.Proxy.GeoIP.Database != "" {
+		merged.Proxy.GeoIP.Database = dest.Proxy.GeoIP.Database
+	}
+	if dest.Proxy.GeoIP.Edition != "" {
+		merged.Proxy.GeoIP.Edition = dest.Proxy.GeoIP.Edition
+	}
+	if dest.Proxy.GeoIP.AccountID != "" {
+		merged.Proxy.GeoIP.AccountID = dest.Proxy.GeoIP.AccountID
+	}
+	if dest.Proxy.GeoIP.LicenseKey != "" {
+		merged.Proxy.GeoIP.LicenseKey = dest.Proxy.GeoIP.LicenseKey
+	}
+	if has("proxy", "allow_countries") || destNode == nil && len(dest.Proxy.AllowCountries) > 0 {
+		merged.Proxy.AllowCountries = dest.Proxy.AllowCountries
+	}
+	if has("proxy", "deny_countries") || destNode == nil && len(dest.Proxy.DenyCountries) > 0 {
+		merged.Proxy.DenyCountries = dest.Proxy.DenyCountries
+	}
+	if has("proxy", "static") || destNode == nil && len(dest.Proxy.Static) > 0 {
+		merged.Proxy.Static = dest.Proxy.Static
+	}
+	if has("proxy", "hosts_role") || destNode == nil && dest.Proxy.HostsRole != "" {
+		merged.Proxy.HostsRole = dest.Proxy.HostsRole
+	}
+	if has("proxy", "private_addresses") {
+		merged.Proxy.PrivateAddresses = dest.Proxy.PrivateAddresses // replace (empty map clears)
+	} else if len(dest.Proxy.PrivateAddresses) > 0 {
+		if merged.Proxy.PrivateAddresses == nil {
+			merged.Proxy.PrivateAddresses = make(map[string]string)
+		}
+		for k, v := range dest.Proxy.PrivateAddresses {
+			merged.Proxy.PrivateAddresses[k] = v
+		}
+	}
 	if has("proxy", "ssl") || destNode == nil && dest.Proxy.SSL {
 		merged.Proxy.SSL = dest.Proxy.SSL
 	}
@@ -479,9 +791,45 @@ func mergeConfigs(base, dest *Config, destNode *yaml.Node) *Config {
 	if dest.Proxy.ResponseHeaderTimeout != "" {
 		merged.Proxy.ResponseHeaderTimeout = dest.Proxy.ResponseHeaderTimeout
 	}
+	if dest.Proxy.WriteTimeout != "" {
+		merged.Proxy.WriteTimeout = dest.Proxy.WriteTimeout
+	}
+	if dest.Proxy.IdleTimeout != "" {
+		merged.Proxy.IdleTimeout = dest.Proxy.IdleTimeout
+	}
+	if dest.Proxy.DialTimeout != "" {
+		merged.Proxy.DialTimeout = dest.Proxy.DialTimeout
+	}
+	if dest.Proxy.BootTimeout != "" {
+		merged.Proxy.BootTimeout = dest.Proxy.BootTimeout
+	}
 	if has("proxy", "forward_headers") || destNode == nil && dest.Proxy.ForwardHeaders {
 		merged.Proxy.ForwardHeaders = dest.Proxy.ForwardHeaders
 	}
+	if has("proxy", "failover", "try_duration") || destNode == nil && dest.Proxy.Failover.TryDuration != "" {
+		merged.Proxy.Failover.TryDuration = dest.Proxy.Failover.TryDuration
+	}
+	if has("proxy", "failover", "try_interval") || destNode == nil && dest.Proxy.Failover.TryInterval != "" {
+		merged.Proxy.Failover.TryInterval = dest.Proxy.Failover.TryInterval
+	}
+	if has("proxy", "failover", "retries") || destNode == nil && dest.Proxy.Failover.Retries != 0 {
+		merged.Proxy.Failover.Retries = dest.Proxy.Failover.Retries
+	}
+	if has("proxy", "failover", "fail_duration") || destNode == nil && dest.Proxy.Failover.FailDuration != "" {
+		merged.Proxy.Failover.FailDuration = dest.Proxy.Failover.FailDuration
+	}
+	if has("proxy", "failover", "max_fails") || destNode == nil && dest.Proxy.Failover.MaxFails != 0 {
+		merged.Proxy.Failover.MaxFails = dest.Proxy.Failover.MaxFails
+	}
+	if has("proxy", "hold_timeout") || destNode == nil && dest.Proxy.HoldTimeout != "" {
+		merged.Proxy.HoldTimeout = dest.Proxy.HoldTimeout
+	}
+	if has("proxy", "max_requests") || destNode == nil && dest.Proxy.MaxRequests != 0 {
+		merged.Proxy.MaxRequests = dest.Proxy.MaxRequests
+	}
+	if has("proxy", "max_connections") || destNode == nil && dest.Proxy.MaxConnections != 0 {
+		merged.Proxy.MaxConnections = dest.Proxy.MaxConnections
+	}
 	if has("proxy", "logging", "enabled") || destNode == nil && dest.Proxy.Logging.Enabled {
 		merged.Proxy.Logging.Enabled = dest.Proxy.Logging.Enabled
 	}
@@ -491,8 +839,49 @@ func mergeConfigs(base, dest *Config, destNode *yaml.Node) *Config {
 	if len(dest.Proxy.Logging.RedactResponseHeaders) > 0 {
 		merged.Proxy.Logging.RedactResponseHeaders = dest.Proxy.Logging.RedactResponseHeaders
 	}
+	if dest.Proxy.Resources.Memory != "" {
+		merged.Proxy.Resources.Memory = dest.Proxy.Resources.Memory
+	}
+	if dest.Proxy.Resources.CPUs != "" {
+		merged.Proxy.Resources.CPUs = dest.Proxy.Resources.CPUs
+	}
+	if dest.Proxy.LogDriver != "" {
+		merged.Proxy.LogDriver = dest.Proxy.LogDriver
+	}
+	if has("proxy", "log_options") {
+		merged.Proxy.LogOptions = dest.Proxy.LogOptions // replace (empty map clears)
+	} else if len(dest.Proxy.LogOptions) > 0 {
+		if merged.Proxy.LogOptions == nil {
+			merged.Proxy.LogOptions = make(map[string]string)
+		}
+		for k, v := range dest.Proxy.LogOptions {
+			merged.Proxy.LogOptions[k] = v
+		}
+	}
 
 	// Merge builder
+	if dest.Builder.Type != "" {
+		merged.Builder.Type = dest.Builder.Type
+	}
+	if dest.Builder.Engine != "" {
+		merged.Builder.Engine = dest.Builder.Engine
+	}
+	if dest.Builder.Pack.Builder != "" {
+		merged.Builder.Pack.Builder = dest.Builder.Pack.Builder
+	}
+	if has("builder", "pack", "buildpacks") || len(dest.Builder.Pack.Buildpacks) > 0 {
+		merged.Builder.Pack.Buildpacks = dest.Builder.Pack.Buildpacks // replace (empty list clears)
+	}
+	if has("builder", "pack", "env") {
+		merged.Builder.Pack.Env = dest.Builder.Pack.Env // replace (empty map clears)
+	} else if len(dest.Builder.Pack.Env) > 0 {
+		if merged.Builder.Pack.Env == nil {
+			merged.Builder.Pack.Env = make(map[string]string)
+		}
+		for k, v := range dest.Builder.Pack.Env {
+			merged.Builder.Pack.Env[k] = v
+		}
+	}
 	if has("builder", "multiarch") || destNode == nil && dest.Builder.Multiarch {
 		merged.Builder.Multiarch = dest.Builder.Multiarch
 	}
@@ -505,6 +894,9 @@ func mergeConfigs(base, dest *Config, destNode *yaml.Node) *Config {
 	if dest.Builder.Cache.Type != "" {
 		merged.Builder.Cache.Type = dest.Builder.Cache.Type
 	}
+	if dest.Builder.Compression != "" {
+		merged.Builder.Compression = dest.Builder.Compression
+	}
 	if has("builder", "cache", "options") {
 		merged.Builder.Cache.Options = dest.Builder.Cache.Options // replace (empty map clears)
 	} else if len(dest.Builder.Cache.Options) > 0 {
@@ -525,6 +917,16 @@ func mergeConfigs(base, dest *Config, destNode *yaml.Node) *Config {
 			merged.Builder.Args[k] = v
 		}
 	}
+	if has("builder", "labels") {
+		merged.Builder.Labels = dest.Builder.Labels // replace (empty map clears)
+	} else if len(dest.Builder.Labels) > 0 {
+		if merged.Builder.Labels == nil {
+			merged.Builder.Labels = make(map[string]string)
+		}
+		for k, v := range dest.Builder.Labels {
+			merged.Builder.Labels[k] = v
+		}
+	}
 	if dest.Builder.Target != "" {
 		merged.Builder.Target = dest.Builder.Target
 	}
@@ -537,11 +939,20 @@ func mergeConfigs(base, dest *Config, destNode *yaml.Node) *Config {
 	if dest.Builder.Context != "" {
 		merged.Builder.Context = dest.Builder.Context
 	}
-	if dest.Builder.Remote.Host != "" {
-		merged.Builder.Remote.Host = dest.Builder.Remote.Host
+	if has("builder", "watch_paths") || destNode == nil && len(dest.Builder.WatchPaths) > 0 {
+		merged.Builder.WatchPaths = dest.Builder.WatchPaths
 	}
-	if dest.Builder.Remote.Arch != "" {
-		merged.Builder.Remote.Arch = dest.Builder.Remote.Arch
+	if len(dest.Builder.Remote.Builders) > 0 {
+		merged.Builder.Remote = dest.Builder.Remote // a list replaces the builders
+	} else {
+		if dest.Builder.Remote.Host != "" {
+			merged.Builder.Remote.Host = dest.Builder.Remote.Host
+			merged.Builder.Remote.Builders = nil
+		}
+		if dest.Builder.Remote.Arch != "" {
+			merged.Builder.Remote.Arch = dest.Builder.Remote.Arch
+			merged.Builder.Remote.Builders = nil
+		}
 	}
 	if has("builder", "secrets") {
 		merged.Builder.Secrets = dest.Builder.Secrets // replace (empty list clears)
@@ -551,6 +962,12 @@ func mergeConfigs(base, dest *Config, destNode *yaml.Node) *Config {
 	if dest.Builder.TagTemplate != "" {
 		merged.Builder.TagTemplate = dest.Builder.TagTemplate
 	}
+	if has("builder", "sbom") || destNode == nil && dest.Builder.SBOM {
+		merged.Builder.SBOM = dest.Builder.SBOM
+	}
+	if dest.Builder.SBOMFormat != "" {
+		merged.Builder.SBOMFormat = dest.Builder.SBOMFormat
+	}
 
 	// Merge deploy
 	if has("deploy", "readiness_delay") || destNode == nil && dest.Deploy.ReadinessDelay != 0 {
@@ -568,6 +985,12 @@ func mergeConfigs(base, dest *Config, destNode *yaml.Node) *Config {
 	if has("deploy", "retain_containers") || destNode == nil && dest.Deploy.RetainContainers != 0 {
 		merged.Deploy.RetainContainers = dest.Deploy.RetainContainers
 	}
+	if has("deploy", "pull_rate_limit") || destNode == nil && dest.Deploy.PullRateLimit != 0 {
+		merged.Deploy.PullRateLimit = dest.Deploy.PullRateLimit
+	}
+	if has("deploy", "image_transfer") || destNode == nil && dest.Deploy.ImageTransfer != "" {
+		merged.Deploy.ImageTransfer = dest.Deploy.ImageTransfer
+	}
 	if has("deploy", "retain_history") || destNode == nil && dest.Deploy.RetainHistory != 0 {
 		merged.Deploy.RetainHistory = dest.Deploy.RetainHistory
 	}
@@ -580,6 +1003,48 @@ func mergeConfigs(base, dest *Config, destNode *yaml.Node) *Config {
 	if has("deploy", "allow_unverified_image") || destNode == nil && dest.Deploy.AllowUnverifiedImage {
 		merged.Deploy.AllowUnverifiedImage = dest.Deploy.AllowUnverifiedImage
 	}
+	if has("deploy", "require_digest") || destNode == nil && dest.Deploy.RequireDigest {
+		merged.Deploy.RequireDigest = dest.Deploy.RequireDigest
+	}
+	if has("deploy", "require_approval") || destNode == nil && dest.Deploy.RequireApproval {
+		merged.Deploy.RequireApproval = dest.Deploy.RequireApproval
+	}
+	if has("deploy", "approval_webhook") || destNode == nil && dest.Deploy.ApprovalWebhook != "" {
+		merged.Deploy.ApprovalWebhook = dest.Deploy.ApprovalWebhook
+	}
+	if has("deploy", "approval_timeout") || destNode == nil && dest.Deploy.ApprovalTimeout != 0 {
+		merged.Deploy.ApprovalTimeout = dest.Deploy.ApprovalTimeout
+	}
+	if has("deploy", "halt_on_error_rate", "threshold") || destNode == nil && dest.Deploy.HaltOnErrorRate.Threshold != 0 {
+		merged.Deploy.HaltOnErrorRate.Threshold = dest.Deploy.HaltOnErrorRate.Threshold
+	}
+	if has("deploy", "halt_on_error_rate", "window") || destNode == nil && dest.Deploy.HaltOnErrorRate.Window != 0 {
+		merged.Deploy.HaltOnErrorRate.Window = dest.Deploy.HaltOnErrorRate.Window
+	}
+	if has("deploy", "halt_on_error_rate", "min_requests") || destNode == nil && dest.Deploy.HaltOnErrorRate.MinRequests != 0 {
+		merged.Deploy.HaltOnErrorRate.MinRequests = dest.Deploy.HaltOnErrorRate.MinRequests
+	}
+	if has("deploy", "restart_loop", "threshold") || destNode == nil && dest.Deploy.RestartLoop.Threshold != 0 {
+		merged.Deploy.RestartLoop.Threshold = dest.Deploy.RestartLoop.Threshold
+	}
+	if has("deploy", "restart_loop", "window") || destNode == nil && dest.Deploy.RestartLoop.Window != 0 {
+		merged.Deploy.RestartLoop.Window = dest.Deploy.RestartLoop.Window
+	}
+	if has("deploy", "restart_loop", "rollback_within") || destNode == nil && dest.Deploy.RestartLoop.RollbackWithin != 0 {
+		merged.Deploy.RestartLoop.RollbackWithin = dest.Deploy.RestartLoop.RollbackWithin
+	}
+	if has("deploy", "auto", "watch_tag") || destNode == nil && dest.Deploy.Auto.WatchTag {
+		merged.Deploy.Auto.WatchTag = dest.Deploy.Auto.WatchTag
+	}
+	if has("deploy", "auto", "interval") || destNode == nil && dest.Deploy.Auto.Interval != 0 {
+		merged.Deploy.Auto.Interval = dest.Deploy.Auto.Interval
+	}
+	if has("deploy", "windows", "timezone") || destNode == nil && dest.Deploy.Windows.Timezone != "" {
+		merged.Deploy.Windows.Timezone = dest.Deploy.Windows.Timezone
+	}
+	if has("deploy", "windows", "allowed") || destNode == nil && len(dest.Deploy.Windows.Allowed) > 0 {
+		merged.Deploy.Windows.Allowed = dest.Deploy.Windows.Allowed
+	}
 	if has("deploy", "canary", "enabled") || destNode == nil && dest.Deploy.Canary.Enabled {
 		merged.Deploy.Canary.Enabled = dest.Deploy.Canary.Enabled
 	}
@@ -606,6 +1071,38 @@ func mergeConfigs(base, dest *Config, destNode *yaml.Node) *Config {
 	if dest.Podman.NetworkBackend != "" {
 		merged.Podman.NetworkBackend = dest.Podman.NetworkBackend
 	}
+	if dest.Podman.SELinuxLabel != "" {
+		merged.Podman.SELinuxLabel = dest.Podman.SELinuxLabel
+	}
+	if has("podman", "ipv6") || destNode == nil && dest.Podman.IPv6 {
+		merged.Podman.IPv6 = dest.Podman.IPv6
+	}
+	if len(dest.Podman.Networks) > 0 {
+		if merged.Podman.Networks == nil {
+			merged.Podman.Networks = make(map[string]PodmanNetworkConfig)
+		}
+		for name, network := range dest.Podman.Networks {
+			merged.Podman.Networks[name] = network
+		}
+	}
+	if has("network", "wireguard") || destNode == nil && dest.Network.WireGuard {
+		merged.Network.WireGuard = dest.Network.WireGuard
+	}
+	if dest.Network.Subnet != "" {
+		merged.Network.Subnet = dest.Network.Subnet
+	}
+	if has("network", "addresses") || destNode == nil && len(dest.Network.Addresses) > 0 {
+		merged.Network.Addresses = dest.Network.Addresses
+	}
+	if has("network", "port") || destNode == nil && dest.Network.Port != 0 {
+		merged.Network.Port = dest.Network.Port
+	}
+
+	// A destination's dns block replaces the base one, as destinations
+	// usually serve other hostnames from another zone.
+	if has("dns") || destNode == nil && dest.DNS.Enabled() {
+		merged.DNS = dest.DNS
+	}
 
 	// Merge security
 	if has("security", "require_non_root_ssh") || destNode == nil && dest.Security.RequireNonRootSSH {
@@ -661,12 +1158,18 @@ func mergeConfigs(base, dest *Config, destNode *yaml.Node) *Config {
 	if dest.SecretsRemotePath != "" {
 		merged.SecretsRemotePath = dest.SecretsRemotePath
 	}
+	if dest.SecretsStorage != "" {
+		merged.SecretsStorage = dest.SecretsStorage
+	}
 	if dest.HooksPath != "" {
 		merged.HooksPath = dest.HooksPath
 	}
 	if dest.MinimumVersion != "" {
 		merged.MinimumVersion = dest.MinimumVersion
 	}
+	if has("host_tags") {
+		merged.HostTags = dest.HostTags // replace (empty map clears)
+	}
 	if has("aliases") {
 		merged.Aliases = dest.Aliases // replace (empty map clears)
 	} else if len(dest.Aliases) > 0 {
@@ -678,6 +1181,38 @@ func mergeConfigs(base, dest *Config, destNode *yaml.Node) *Config {
 		}
 	}
 
+	if dest.Agent.Listen != "" {
+		merged.Agent.Listen = dest.Agent.Listen
+	}
+	if dest.Agent.TLSCertificate != "" {
+		merged.Agent.TLSCertificate = dest.Agent.TLSCertificate
+	}
+	if dest.Agent.TLSPrivateKey != "" {
+		merged.Agent.TLSPrivateKey = dest.Agent.TLSPrivateKey
+	}
+	if has("agent", "tokens") || destNode == nil && len(dest.Agent.Tokens) > 0 {
+		merged.Agent.Tokens = dest.Agent.Tokens // replace (empty map clears)
+	}
+	if dest.Serve.Secret != "" {
+		merged.Serve.Secret = dest.Serve.Secret
+	}
+	if has("serve", "tags") || destNode == nil && len(dest.Serve.Tags) > 0 {
+		merged.Serve.Tags = dest.Serve.Tags
+	}
+	if dest.Team.DefaultRole != "" {
+		merged.Team.DefaultRole = dest.Team.DefaultRole
+	}
+	if has("team", "roles") {
+		merged.Team.Roles = dest.Team.Roles // replace (empty map clears)
+	} else if len(dest.Team.Roles) > 0 {
+		if merged.Team.Roles == nil {
+			merged.Team.Roles = make(map[string]string)
+		}
+		for user, role := range dest.Team.Roles {
+			merged.Team.Roles[user] = role
+		}
+	}
+
 	// Merge accessories
 	if len(dest.Accessories) > 0 {
 		if merged.Accessories == nil {
@@ -726,6 +1261,36 @@ func mergeConfigs(base, dest *Config, destNode *yaml.Node) *Config {
 	if has("ssh", "insecure_ignore_host_key") || destNode == nil && dest.SSH.InsecureIgnoreHostKey {
 		merged.SSH.InsecureIgnoreHostKey = dest.SSH.InsecureIgnoreHostKey
 	}
+	if has("ssh", "prefer_ipv6") || destNode == nil && dest.SSH.PreferIPv6 {
+		merged.SSH.PreferIPv6 = dest.SSH.PreferIPv6
+	}
+	if has("ssh", "max_concurrent") || destNode == nil && dest.SSH.MaxConcurrent != 0 {
+		merged.SSH.MaxConcurrent = dest.SSH.MaxConcurrent
+	}
+	if has("ssh", "max_concurrent_per_host") || destNode == nil && dest.SSH.MaxConcurrentPerHost != 0 {
+		merged.SSH.MaxConcurrentPerHost = dest.SSH.MaxConcurrentPerHost
+	}
+	if dest.SSH.KeyPassphraseSecret != "" {
+		merged.SSH.KeyPassphraseSecret = dest.SSH.KeyPassphraseSecret
+	}
+	if dest.SSH.AgentSocket != "" {
+		merged.SSH.AgentSocket = dest.SSH.AgentSocket
+	}
+	if has("ssh", "add_keys_to_agent") || destNode == nil && dest.SSH.AddKeysToAgent {
+		merged.SSH.AddKeysToAgent = dest.SSH.AddKeysToAgent
+	}
+	if has("ssh", "retry", "attempts") || destNode == nil && dest.SSH.Retry.Attempts != 0 {
+		merged.SSH.Retry.Attempts = dest.SSH.Retry.Attempts
+	}
+	if has("ssh", "retry", "backoff") || destNode == nil && dest.SSH.Retry.Backoff != 0 {
+		merged.SSH.Retry.Backoff = dest.SSH.Retry.Backoff
+	}
+	if has("ssh", "retry", "max_backoff") || destNode == nil && dest.SSH.Retry.MaxBackoff != 0 {
+		merged.SSH.Retry.MaxBackoff = dest.SSH.Retry.MaxBackoff
+	}
+	if has("ssh", "retry", "jitter") || destNode == nil && dest.SSH.Retry.Jitter != 0 {
+		merged.SSH.Retry.Jitter = dest.SSH.Retry.Jitter
+	}
 
 	return &merged
 }
@@ -758,6 +1323,17 @@ func nodeHasPath(node *yaml.Node, path ...string) bool {
 
 // applyDefaults sets default values for unset configuration options
 func applyDefaults(cfg *Config) {
+	// Registry credential helpers imply the username their tokens use
+	if cfg.Registry.Helper != "" && cfg.Registry.Username == "" {
+		cfg.Registry.Username = RegistryHelperUsername(cfg.Registry.Helper)
+	}
+	for server, registry := range cfg.Registries {
+		if registry.Helper != "" && registry.Username == "" {
+			registry.Username = RegistryHelperUsername(registry.Helper)
+			cfg.Registries[server] = registry
+		}
+	}
+
 	// SSH defaults - use current user instead of root for security
 	if cfg.SSH.User == "" {
 		cfg.SSH.User = currentUsername()
@@ -768,6 +1344,18 @@ func applyDefaults(cfg *Config) {
 	if cfg.SSH.ConnectTimeout == 0 {
 		cfg.SSH.ConnectTimeout = 30 * time.Second
 	}
+	if cfg.SSH.Retry == (SSHRetryConfig{}) {
+		cfg.SSH.Retry.Jitter = 0.2
+	}
+	if cfg.SSH.Retry.Attempts == 0 {
+		cfg.SSH.Retry.Attempts = 3
+	}
+	if cfg.SSH.Retry.Backoff == 0 {
+		cfg.SSH.Retry.Backoff = time.Second
+	}
+	if cfg.SSH.Retry.MaxBackoff == 0 {
+		cfg.SSH.Retry.MaxBackoff = 30 * time.Second
+	}
 
 	// Proxy defaults
 	if cfg.Proxy.AppPort == 0 {
@@ -778,6 +1366,16 @@ func applyDefaults(cfg *Config) {
 	} else {
 		cfg.Proxy.UpstreamProtocol = strings.ToLower(strings.TrimSpace(cfg.Proxy.UpstreamProtocol))
 	}
+	for i := range cfg.Proxy.Streams {
+		stream := &cfg.Proxy.Streams[i]
+		stream.Protocol = strings.ToLower(strings.TrimSpace(stream.Protocol))
+		if stream.Protocol == "" {
+			stream.Protocol = "tcp"
+		}
+		if stream.Port == 0 {
+			stream.Port = stream.Listen
+		}
+	}
 	if cfg.Proxy.Host == "" && len(cfg.Proxy.Hosts) > 0 {
 		cfg.Proxy.Host = cfg.Proxy.Hosts[0]
 	}
@@ -793,6 +1391,12 @@ func applyDefaults(cfg *Config) {
 	if cfg.Proxy.ResponseTimeout == "" {
 		cfg.Proxy.ResponseTimeout = "30s"
 	}
+	if cfg.Proxy.Failover.FailDuration == "" {
+		cfg.Proxy.Failover.FailDuration = "30s"
+	}
+	if cfg.Proxy.Failover.MaxFails == 0 {
+		cfg.Proxy.Failover.MaxFails = 3
+	}
 
 	// Deploy defaults
 	if cfg.Deploy.ReadinessDelay == 0 {
@@ -814,6 +1418,31 @@ func applyDefaults(cfg *Config) {
 		cfg.Deploy.RetainHistory = 100
 	}
 
+	if cfg.Deploy.ApprovalWebhook != "" && cfg.Deploy.ApprovalTimeout == 0 {
+		cfg.Deploy.ApprovalTimeout = 15 * time.Minute
+	}
+
+	// Error-rate gate defaults (only apply if enabled)
+	if cfg.Deploy.HaltOnErrorRate.Enabled() {
+		if cfg.Deploy.HaltOnErrorRate.Window == 0 {
+			cfg.Deploy.HaltOnErrorRate.Window = 30 * time.Second
+		}
+		if cfg.Deploy.HaltOnErrorRate.MinRequests == 0 {
+			cfg.Deploy.HaltOnErrorRate.MinRequests = 10
+		}
+	}
+
+	// Restart-loop detection defaults
+	if cfg.Deploy.RestartLoop.Threshold == 0 {
+		cfg.Deploy.RestartLoop.Threshold = 3
+	}
+	if cfg.Deploy.RestartLoop.Window == 0 {
+		cfg.Deploy.RestartLoop.Window = 10 * time.Minute
+	}
+	if cfg.Deploy.Auto.Interval == 0 {
+		cfg.Deploy.Auto.Interval = 5 * time.Minute
+	}
+
 	// Canary defaults (only apply if enabled)
 	if cfg.Deploy.Canary.Enabled {
 		if cfg.Deploy.Canary.InitialWeight == 0 {
@@ -834,11 +1463,28 @@ func applyDefaults(cfg *Config) {
 	if cfg.Builder.Context == "" {
 		cfg.Builder.Context = "."
 	}
+	if strings.TrimSpace(cfg.Builder.Cache.Type) == "registry" && cfg.Builder.Cache.Options["ref"] == "" && cfg.Image != "" {
+		if cfg.Builder.Cache.Options == nil {
+			cfg.Builder.Cache.Options = make(map[string]string)
+		}
+		cfg.Builder.Cache.Options["ref"] = DefaultCacheRef(cfg.Image)
+	}
+	if cfg.Builder.SBOM && cfg.Builder.SBOMFormat == "" {
+		cfg.Builder.SBOMFormat = "spdx-json"
+	}
 
 	// Podman defaults
 	if cfg.Podman.NetworkBackend == "" {
 		cfg.Podman.NetworkBackend = "netavark"
 	}
+
+	// Network defaults
+	if cfg.Network.Subnet == "" {
+		cfg.Network.Subnet = "10.77.0.0/24"
+	}
+	if cfg.Network.Port == 0 {
+		cfg.Network.Port = DefaultWireGuardPort
+	}
 	if cfg.Podman.QuadletPath == "" {
 		if cfg.Podman.Rootless {
 			cfg.Podman.QuadletPath = "~/.config/containers/systemd/"
@@ -851,12 +1497,18 @@ func applyDefaults(cfg *Config) {
 	if cfg.SecretsPath == "" {
 		cfg.SecretsPath = ".azud/secrets"
 	}
+	if cfg.SecretsStorage == "" {
+		cfg.SecretsStorage = SecretsStorageEnvFile
+	}
 	if cfg.HooksPath == "" {
 		cfg.HooksPath = ".azud/hooks"
 	}
 	if cfg.Hooks.Timeout == 0 {
 		cfg.Hooks.Timeout = 5 * time.Minute
 	}
+
+	applyAccessoryProfiles(cfg)
+	applyCacheAccessories(cfg)
 }
 
 // currentUsername returns the current OS user's username.
@@ -1,11 +1,15 @@
 package config
 
 import (
+	"errors"
+	"fmt"
 	"os"
 	"path/filepath"
 	"reflect"
+	"slices"
 	"strings"
 	"testing"
+	"time"
 
 	"gopkg.in/yaml.v3"
 )
@@ -38,6 +42,344 @@ deploy:
 	}
 }
 
+func TestLoaderKeepsReferencesToDeclaredSecretsAndVariables(t *testing.T) {
+	t.Setenv("DATABASE_PASSWORD", "local-value")
+	t.Setenv("APP_DOMAIN", "shop.example.com")
+	dir := t.TempDir()
+	path := filepath.Join(dir, "deploy.yml")
+	content := `
+service: test
+image: test:latest
+servers:
+  web:
+    hosts: [localhost]
+proxy:
+  host: ${APP_DOMAIN}
+env:
+  clear:
+    DB_HOST: db
+    DATABASE_URL: postgres://app:${DATABASE_PASSWORD}@${DB_HOST}:5432/app
+  secret:
+    - DATABASE_PASSWORD
+`
+	if err := os.WriteFile(path, []byte(content), 0600); err != nil {
+		t.Fatal(err)
+	}
+	cfg, err := NewLoader(path, "").LoadUnvalidated()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.Proxy.Host != "shop.example.com" {
+		t.Errorf("proxy.host = %q, want the local environment value", cfg.Proxy.Host)
+	}
+	if got := cfg.Env.Clear["DATABASE_URL"]; got != "postgres://app:${DATABASE_PASSWORD}@${DB_HOST}:5432/app" {
+		t.Errorf("DATABASE_URL = %q, want its references kept", got)
+	}
+}
+
+func TestLoaderAppliesPostgresProfile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "deploy.yml")
+	content := `
+service: shop
+image: shop:latest
+servers:
+  web:
+    hosts: [localhost]
+proxy:
+  host: shop.example.com
+accessories:
+  db:
+    image: postgres:16
+    host: db-1
+    profile: standard
+    volumes:
+      - /srv/pg:/var/lib/postgresql/data
+    backup:
+      s3: s3://backups/shop/wal
+      region: eu-west-1
+`
+	if err := os.WriteFile(path, []byte(content), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg, err := NewLoader(path, "").Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	db := cfg.Accessories["db"]
+	if want := []string{"/srv/pg:/var/lib/postgresql/data", "shop-db-wal:/var/lib/postgresql/wal-archive"}; !reflect.DeepEqual(db.Volumes, want) {
+		t.Errorf("db volumes = %v, want %v", db.Volumes, want)
+	}
+	if !strings.HasPrefix(db.Healthcheck.Cmd, "pg_isready") || db.GetBootTimeout() != 2*time.Minute || db.GetStopTimeout() != 60 {
+		t.Errorf("db defaults = healthcheck %q boot %s stop %d", db.Healthcheck.Cmd, db.GetBootTimeout(), db.GetStopTimeout())
+	}
+
+	archiver, ok := cfg.Accessories["db-wal-archiver"]
+	if !ok {
+		t.Fatal("WAL archiver sidecar was not added")
+	}
+	if archiver.Host != "db-1" || archiver.Image != DefaultWALArchiverImage || !reflect.DeepEqual(archiver.Volumes, []string{"shop-db-wal:/wal-archive"}) {
+		t.Errorf("archiver = host %q image %q volumes %v", archiver.Host, archiver.Image, archiver.Volumes)
+	}
+	if !strings.Contains(archiver.Cmd, "aws s3 mv /wal-archive s3://backups/shop/wal") || archiver.Env.Clear["AWS_DEFAULT_REGION"] != "eu-west-1" {
+		t.Errorf("archiver cmd = %q env = %v", archiver.Cmd, archiver.Env.Clear)
+	}
+
+	command := db.ProfileCommand(8 << 30)
+	for _, setting := range []string{"shared_buffers=2048MB", "effective_cache_size=6144MB", "maintenance_work_mem=512MB", "work_mem=32MB", "archive_mode=on"} {
+		if !slices.Contains(command, setting) {
+			t.Errorf("ProfileCommand() = %v, missing %s", command, setting)
+		}
+	}
+	if command := db.ProfileCommand(0); slices.ContainsFunc(command, func(arg string) bool { return strings.HasPrefix(arg, "shared_buffers") }) {
+		t.Errorf("ProfileCommand(0) tuned memory: %v", command)
+	}
+	db.Cmd = "postgres -c max_connections=500"
+	if command := db.ProfileCommand(8 << 30); command != nil {
+		t.Errorf("ProfileCommand() with cmd = %v, want nil", command)
+	}
+}
+
+func TestLoaderWiresCacheAccessories(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "deploy.yml")
+	content := `
+service: shop
+image: shop:latest
+servers:
+  web:
+    hosts: [localhost]
+proxy:
+  host: shop.example.com
+env:
+  clear:
+    SESSIONS_URL: redis://elsewhere:6379
+accessories:
+  cache:
+    image: redis:7
+    host: cache-1
+    cache:
+      engine: redis
+      password: true
+      tls: true
+  sessions:
+    image: memcached:1.6
+    host: cache-1
+    cache:
+      engine: memcached
+      password: true
+`
+	if err := os.WriteFile(path, []byte(content), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg, err := NewLoader(path, "").Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	wantClear := map[string]string{
+		"CACHE_URL":         "rediss://:${CACHE_PASSWORD}@shop-cache:6379",
+		"CACHE_TLS_CA":      "/etc/azud/tls/cache.crt",
+		"SESSIONS_URL":      "redis://elsewhere:6379",
+		"SESSIONS_SERVERS":  "shop-sessions:11211",
+		"SESSIONS_USERNAME": "azud",
+	}
+	if !reflect.DeepEqual(cfg.Env.Clear, wantClear) {
+		t.Errorf("env.clear = %v, want %v", cfg.Env.Clear, wantClear)
+	}
+	if want := []string{"CACHE_PASSWORD", "SESSIONS_PASSWORD"}; !reflect.DeepEqual(cfg.Env.Secret, want) {
+		t.Errorf("env.secret = %v, want %v", cfg.Env.Secret, want)
+	}
+	if want := []string{"$HOME/.azud/tls/shop-cache/tls.crt:/etc/azud/tls/cache.crt:ro"}; !reflect.DeepEqual(cfg.Volumes, want) {
+		t.Errorf("volumes = %v, want %v", cfg.Volumes, want)
+	}
+
+	cache := cfg.Accessories["cache"]
+	if want := []string{"$HOME/.azud/tls/shop-cache:/tls:ro"}; !reflect.DeepEqual(cache.Volumes, want) {
+		t.Errorf("cache volumes = %v, want %v", cache.Volumes, want)
+	}
+	if !reflect.DeepEqual(cache.Env.Secret, []string{"CACHE_PASSWORD"}) || cache.Healthcheck.Port != 6379 {
+		t.Errorf("cache secrets = %v, healthcheck port = %d", cache.Env.Secret, cache.Healthcheck.Port)
+	}
+	if !strings.Contains(cache.Cmd, "--tls-port 6379") || !strings.Contains(cache.Cmd, `requirepass "${CACHE_PASSWORD:-`) {
+		t.Errorf("cache cmd = %q", cache.Cmd)
+	}
+	sessions := cfg.Accessories["sessions"]
+	if !strings.Contains(sessions.Cmd, "memcached -Y /tmp/azud-auth") || strings.Contains(sessions.Cmd, "-Z") {
+		t.Errorf("sessions cmd = %q", sessions.Cmd)
+	}
+}
+
+func TestLoaderMergesEnvFiles(t *testing.T) {
+	dir := t.TempDir()
+	base := filepath.Join(dir, ".env")
+	production := filepath.Join(dir, ".env.production")
+	files := map[string]string{
+		base:       "# shared\nLOG_LEVEL=info\nexport REGION='eu'\nCACHE_URL=redis://cache\n",
+		production: "LOG_LEVEL=warn\nRAILS_ENV=\"production\"\nnot a variable\n",
+	}
+	for path, content := range files {
+		if err := os.WriteFile(path, []byte(content), 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	path := filepath.Join(dir, "deploy.yml")
+	content := fmt.Sprintf(`
+service: test
+image: test:latest
+servers:
+  web:
+    hosts: [localhost]
+proxy:
+  host: test.example.com
+env:
+  files: [%s, %s]
+  clear:
+    CACHE_URL: redis://override
+`, base, production)
+	if err := os.WriteFile(path, []byte(content), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg, err := NewLoader(path, "").Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]string{"LOG_LEVEL": "warn", "REGION": "eu", "RAILS_ENV": "production", "CACHE_URL": "redis://override"}
+	if !reflect.DeepEqual(cfg.Env.Clear, want) {
+		t.Errorf("env.clear = %v, want %v", cfg.Env.Clear, want)
+	}
+
+	secret := strings.Replace(content, "  clear:", "  secret: [REGION]\n  clear:", 1)
+	if err := os.WriteFile(path, []byte(secret), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := NewLoader(path, "").Load(); err == nil || !strings.Contains(err.Error(), "env.files[0]") || !strings.Contains(err.Error(), "REGION") {
+		t.Errorf("expected a secret collision error for REGION, got %v", err)
+	}
+
+	missing := strings.Replace(content, production, filepath.Join(dir, ".env.missing"), 1)
+	if err := os.WriteFile(path, []byte(missing), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := NewLoader(path, "").LoadUnvalidated(); err == nil || !strings.Contains(err.Error(), "env.files[1]") {
+		t.Errorf("expected a missing file error, got %v", err)
+	}
+}
+
+func TestLoaderRejectsMisspelledNestedKeyWithPosition(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "deploy.yml")
+	content := `service: test
+image: test:latest
+servers:
+  web:
+    hosts: [localhost]
+proxy:
+  host: test.example.com
+  healtcheck:
+    path: /up
+`
+	if err := os.WriteFile(path, []byte(content), 0600); err != nil {
+		t.Fatal(err)
+	}
+	_, err := NewLoader(path, "").Load()
+	var unknown *UnknownKeyError
+	if !errors.As(err, &unknown) {
+		t.Fatalf("expected UnknownKeyError, got %v", err)
+	}
+	want := UnknownKeyError{Path: "proxy.healtcheck", Suggestion: "proxy.healthcheck", File: path, Line: 8, Column: 3}
+	if *unknown != want {
+		t.Fatalf("unknown key = %+v, want %+v", *unknown, want)
+	}
+}
+
+func TestLoaderCheckLocatesProblemsInBaseAndDestination(t *testing.T) {
+	dir := t.TempDir()
+	base := filepath.Join(dir, "deploy.yml")
+	dest := filepath.Join(dir, "deploy.staging.yml")
+	baseContent := `
+service: test
+image: test:latest
+servers:
+  web:
+    hosts: [localhost]
+proxy:
+  host: test.example.com
+  streams:
+    - name: smtp
+      listen: 25
+ssh:
+  insecure_ignore_host_key: true
+`
+	destContent := `
+proxy:
+  response_timeout: soon
+`
+	if err := os.WriteFile(base, []byte(baseContent), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(dest, []byte(destContent), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	_, problems, err := NewLoader(base, "staging").Check()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]ValidationError{
+		"proxy.image":                  {Severity: SeverityError, File: base, Line: 7, Column: 1},
+		"proxy.response_timeout":       {Severity: SeverityError, File: dest, Line: 3, Column: 3},
+		"ssh.insecure_ignore_host_key": {Severity: SeverityWarning, File: base, Line: 13, Column: 3},
+	}
+	for _, problem := range problems {
+		expected, ok := want[problem.Field]
+		if !ok {
+			continue
+		}
+		if problem.Severity != expected.Severity || problem.File != expected.File || problem.Line != expected.Line || problem.Column != expected.Column {
+			t.Errorf("%s located at %s:%d:%d (%s), want %s:%d:%d (%s)", problem.Field,
+				problem.File, problem.Line, problem.Column, problem.Severity,
+				expected.File, expected.Line, expected.Column, expected.Severity)
+		}
+		delete(want, problem.Field)
+	}
+	for field := range want {
+		t.Errorf("missing problem for %s in %v", field, problems)
+	}
+}
+
+func TestFieldPositionFollowsSequencesAndDottedKeys(t *testing.T) {
+	var node yaml.Node
+	content := `
+proxy:
+  private_addresses:
+    10.0.0.1: 192.168.0.1
+  streams:
+    - name: smtp
+      listen: 25
+`
+	if err := yaml.Unmarshal([]byte(content), &node); err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		field        string
+		line, column int
+		exact        bool
+	}{
+		{field: "proxy.private_addresses.10.0.0.1", line: 4, column: 5, exact: true},
+		{field: "proxy.streams[0].listen", line: 7, column: 7, exact: true},
+		{field: "proxy.streams[0].port", line: 6, column: 7},
+		{field: "proxy.streams[3].name", line: 5, column: 3},
+		{field: "deploy.drain_timeout"},
+	}
+	for _, tt := range tests {
+		line, column, exact := fieldPosition(&node, tt.field)
+		if line != tt.line || column != tt.column || exact != tt.exact {
+			t.Errorf("fieldPosition(%q) = %d:%d exact=%v, want %d:%d exact=%v", tt.field, line, column, exact, tt.line, tt.column, tt.exact)
+		}
+	}
+}
+
 func TestMergeConfigs_AllowsExplicitFalseAndZero(t *testing.T) {
 	base := &Config{
 		Proxy: ProxyConfig{
@@ -531,3 +873,219 @@ request_headers:
 		t.Fatalf("expected new field to take precedence, got %v", cfg2.RedactRequestHeaders)
 	}
 }
+
+func TestRemoteBuilderConfig_SingleOrList(t *testing.T) {
+	var single BuilderConfig
+	if err := yaml.Unmarshal([]byte(`
+remote:
+  host: 203.0.113.12
+  arch: amd64
+`), &single); err != nil {
+		t.Fatalf("failed to unmarshal single remote builder: %v", err)
+	}
+	if single.Remote.Host != "203.0.113.12" || single.Remote.Parallel() || len(single.Remote.All()) != 1 {
+		t.Fatalf("unexpected single remote builder: %+v", single.Remote)
+	}
+
+	var list BuilderConfig
+	if err := yaml.Unmarshal([]byte(`
+remote:
+  - host: 203.0.113.12
+    arch: amd64
+  - host: 203.0.113.13
+    arch: arm64
+`), &list); err != nil {
+		t.Fatalf("failed to unmarshal remote builder list: %v", err)
+	}
+	want := []RemoteBuilderConfig{{Host: "203.0.113.12", Arch: "amd64"}, {Host: "203.0.113.13", Arch: "arm64"}}
+	if !reflect.DeepEqual(list.Remote.All(), want) || !list.Remote.Parallel() {
+		t.Fatalf("unexpected remote builders: %+v", list.Remote)
+	}
+	if list.Remote.Host != "203.0.113.12" || list.Remote.Arch != "amd64" {
+		t.Fatalf("expected host and arch to mirror the first builder, got %+v", list.Remote)
+	}
+}
+
+func TestHardeningConfig_PresetOrOptions(t *testing.T) {
+	var role RoleConfig
+	if err := yaml.Unmarshal([]byte("hardening: strict\n"), &role); err != nil {
+		t.Fatalf("failed to unmarshal hardening preset: %v", err)
+	}
+	want := HardeningConfig{Preset: "strict", ReadOnly: true, Tmpfs: []string{"/tmp"}, NoNewPrivileges: true, CapDrop: []string{"ALL"}}
+	if got := role.Hardening.Effective(); !reflect.DeepEqual(got, want) {
+		t.Fatalf("strict preset = %+v, want %+v", got, want)
+	}
+
+	role = RoleConfig{}
+	if err := yaml.Unmarshal([]byte(`
+hardening:
+  preset: strict
+  tmpfs: [/tmp, /app/cache]
+  seccomp_profile: /etc/azud/seccomp.json
+`), &role); err != nil {
+		t.Fatalf("failed to unmarshal hardening options: %v", err)
+	}
+	got := role.Hardening.Effective()
+	if !reflect.DeepEqual(got.Tmpfs, []string{"/tmp", "/app/cache"}) || !reflect.DeepEqual(got.CapDrop, []string{"ALL"}) {
+		t.Fatalf("explicit lists should replace only their preset defaults, got %+v", got)
+	}
+	if !got.ReadOnly || got.SeccompProfile != "/etc/azud/seccomp.json" {
+		t.Fatalf("unexpected hardening options: %+v", got)
+	}
+
+	role = RoleConfig{}
+	if err := yaml.Unmarshal([]byte("hardening:\n  no_new_privileges: true\n"), &role); err != nil {
+		t.Fatalf("failed to unmarshal hardening options: %v", err)
+	}
+	if got := role.Hardening.Effective(); got.ReadOnly || !got.NoNewPrivileges || len(got.CapDrop) != 0 {
+		t.Fatalf("options without a preset gained preset defaults: %+v", got)
+	}
+}
+
+func TestLoaderRejectsUnknownRemoteBuilderListKeys(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "deploy.yml")
+	content := `
+service: test
+image: test:latest
+servers:
+  web:
+    hosts: [localhost]
+proxy:
+  host: test.example.com
+builder:
+  remote:
+    - host: 203.0.113.12
+      arhc: amd64
+`
+	if err := os.WriteFile(path, []byte(content), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := NewLoader(path, "").Load(); err == nil || !strings.Contains(err.Error(), "arhc") {
+		t.Fatalf("expected unknown key error for arhc, got %v", err)
+	}
+}
+
+func TestApplyDefaults_RegistryCacheRef(t *testing.T) {
+	cfg := &Config{
+		Image:   "ghcr.io/acme/app:v1",
+		Builder: BuilderConfig{Cache: CacheConfig{Type: "registry", Options: map[string]string{"mode": "max"}}},
+	}
+	applyDefaults(cfg)
+	if got := cfg.Builder.Cache.Options["ref"]; got != "ghcr.io/acme/app-cache" {
+		t.Fatalf("default cache ref = %q, want ghcr.io/acme/app-cache", got)
+	}
+
+	cfg = &Config{
+		Image:   "localhost:5000/app@sha256:abcd",
+		Builder: BuilderConfig{Cache: CacheConfig{Type: "registry"}},
+	}
+	applyDefaults(cfg)
+	if got := cfg.Builder.Cache.Options["ref"]; got != "localhost:5000/app-cache" {
+		t.Fatalf("default cache ref = %q, want localhost:5000/app-cache", got)
+	}
+}
+
+func TestLoaderPrefersDestinationSecretsFile(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "deploy.yml")
+	secretsPath := filepath.Join(dir, "secrets")
+	content := `
+service: test
+image: test:latest
+servers:
+  web:
+    hosts: [localhost]
+proxy:
+  host: test.example.com
+secrets_path: ` + secretsPath + `
+`
+	files := map[string]string{
+		path:                     content,
+		secretsPath:              "API_KEY=shared\n",
+		secretsPath + ".staging": "API_KEY=staging\n",
+	}
+	for name, data := range files {
+		if err := os.WriteFile(name, []byte(data), 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	t.Cleanup(func() { SetLoadedSecrets(map[string]string{}) })
+
+	for destination, want := range map[string]string{"": "shared", "staging": "staging", "production": "shared"} {
+		cfg, err := NewLoader(path, destination).Load()
+		if err != nil {
+			t.Fatalf("Load(%q) failed: %v", destination, err)
+		}
+		if got, _ := GetSecret("API_KEY"); got != want {
+			t.Errorf("destination %q: API_KEY = %q, want %q (secrets_path %s)", destination, got, want, cfg.SecretsPath)
+		}
+	}
+}
+
+func TestRegistryLogins(t *testing.T) {
+	cfg := &Config{
+		Image: "ghcr.io/acme/app:v1",
+		Servers: map[string]RoleConfig{
+			"web": {Hosts: []string{"web1"}},
+		},
+		Accessories: map[string]AccessoryConfig{
+			"db":    {Image: "postgres:18", Host: "db1"},
+			"cache": {Image: "registry.local:5000/redis:7", Hosts: []string{"web1", "db1"}},
+		},
+		Registry: RegistryConfig{Server: "ghcr.io", Username: "acme", Password: []string{"GHCR_TOKEN"}},
+		Registries: map[string]RegistryConfig{
+			"index.docker.io":     {Username: "hub", Password: []string{"DOCKERHUB_TOKEN"}},
+			"registry.local:5000": {Username: "ci", Password: []string{"LOCAL_TOKEN"}},
+		},
+	}
+
+	servers := func(logins []RegistryConfig) []string {
+		var got []string
+		for _, login := range logins {
+			got = append(got, login.Server)
+		}
+		return got
+	}
+
+	tests := []struct {
+		host string
+		want []string
+	}{
+		{"web1", []string{"ghcr.io", "registry.local:5000"}},
+		{"db1", []string{"ghcr.io", "registry.local:5000", "index.docker.io"}},
+		{"other", []string{"ghcr.io"}},
+	}
+	for _, tt := range tests {
+		got := servers(cfg.RegistryLogins(cfg.HostImages(tt.host)...))
+		if !slices.Equal(got, tt.want) {
+			t.Errorf("RegistryLogins(%s) = %v, want %v", tt.host, got, tt.want)
+		}
+	}
+
+	if reg, ok := cfg.RegistryFor("docker.io/library/postgres:18"); !ok || reg.Username != "hub" {
+		t.Errorf("RegistryFor(docker.io image) = %+v, %v; want the index.docker.io entry", reg, ok)
+	}
+	if _, ok := cfg.RegistryFor("quay.io/acme/tool:1"); ok {
+		t.Error("RegistryFor(quay.io image) found credentials, want none")
+	}
+}
+
+func TestProxyImageRepository(t *testing.T) {
+	cfg := &Config{
+		Image:   "ghcr.io/acme/app:v1",
+		Servers: map[string]RoleConfig{"web": {Hosts: []string{"web1"}}},
+		Proxy:   ProxyConfig{ImageBuild: ProxyImageBuildConfig{Modules: []string{"github.com/mholt/caddy-l4"}}},
+	}
+	if got := cfg.ProxyImageRepository(); got != "ghcr.io/acme/app-caddy" {
+		t.Errorf("ProxyImageRepository() = %q, want ghcr.io/acme/app-caddy", got)
+	}
+	if got := cfg.HostImages("web1"); !slices.Equal(got, []string{"ghcr.io/acme/app:v1", "ghcr.io/acme/app-caddy"}) {
+		t.Errorf("HostImages(web1) = %v, want the application and proxy images", got)
+	}
+
+	cfg.Proxy.ImageBuild.Repository = "registry.local:5000/caddy"
+	if got := cfg.ProxyImageRepository(); got != "registry.local:5000/caddy" {
+		t.Errorf("ProxyImageRepository() = %q, want the configured repository", got)
+	}
+}
@@ -0,0 +1,146 @@
+package config
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/lemonity-org/azud/internal/shell"
+)
+
+// AccessoryProfileStandard is the Postgres accessory profile.
+const AccessoryProfileStandard = "standard"
+
+// DefaultWALArchiverImage runs the sidecar that uploads WAL files to S3.
+const DefaultWALArchiverImage = "docker.io/amazon/aws-cli:2.17.0"
+
+const (
+	postgresDataDir       = "/var/lib/postgresql/data"
+	postgresWALArchiveDir = "/var/lib/postgresql/wal-archive"
+	walArchiverDir        = "/wal-archive"
+)
+
+// WALArchiverName returns the name of the sidecar accessory that uploads
+// the WAL files of the Postgres accessory name.
+func WALArchiverName(name string) string {
+	return name + "-wal-archiver"
+}
+
+// applyAccessoryProfiles fills in the defaults of accessories with the
+// standard profile and adds their WAL archiver sidecars. Settings made in
+// the configuration are kept.
+func applyAccessoryProfiles(cfg *Config) {
+	for _, name := range cfg.GetAccessoryNames() {
+		accessory := cfg.Accessories[name]
+		if accessory.Profile != AccessoryProfileStandard {
+			continue
+		}
+
+		accessory.Volumes = withVolume(accessory.Volumes, fmt.Sprintf("%s-%s-data", cfg.Service, name), postgresDataDir)
+		if !accessory.Healthcheck.IsSet() {
+			accessory.Healthcheck.Cmd = `pg_isready -U "${POSTGRES_USER:-postgres}"`
+		}
+		if accessory.BootTimeout == nil {
+			// initdb on first boot takes longer than the default.
+			bootTimeout := 2 * time.Minute
+			accessory.BootTimeout = &bootTimeout
+		}
+		if accessory.StopTimeout == 0 {
+			accessory.StopTimeout = time.Minute
+		}
+
+		if accessory.Backup.S3 != "" {
+			archive := fmt.Sprintf("%s-%s-wal", cfg.Service, name)
+			accessory.Volumes = withVolume(accessory.Volumes, archive, postgresWALArchiveDir)
+			if _, ok := cfg.Accessories[WALArchiverName(name)]; !ok {
+				cfg.Accessories[WALArchiverName(name)] = walArchiver(accessory, archive)
+			}
+		}
+		cfg.Accessories[name] = accessory
+	}
+}
+
+// withVolume returns volumes with source mounted at target, unless a
+// volume already mounts something there.
+func withVolume(volumes []string, source, target string, options ...string) []string {
+	for _, spec := range volumes {
+		if volume, err := ParseVolume(spec); err == nil && volume.Target == target {
+			return volumes
+		}
+	}
+	volume := VolumeSpec{Source: source, Target: target, Options: options}
+	return append(slices.Clone(volumes), volume.String())
+}
+
+// walArchiver returns the sidecar accessory that moves the WAL files
+// Postgres archives into archiveVolume to S3.
+func walArchiver(postgres AccessoryConfig, archiveVolume string) AccessoryConfig {
+	backup := postgres.Backup
+	image := backup.Image
+	if image == "" {
+		image = DefaultWALArchiverImage
+	}
+	interval := backup.Interval
+	if interval <= 0 {
+		interval = time.Minute
+	}
+
+	upload := fmt.Sprintf("aws s3 mv %s %s --recursive --exclude '*.tmp' --only-show-errors",
+		walArchiverDir, shell.Quote(strings.TrimSuffix(backup.S3, "/")))
+	if backup.Endpoint != "" {
+		upload += " --endpoint-url " + shell.Quote(backup.Endpoint)
+	}
+	env := EnvConfig{Secret: []string{"AWS_ACCESS_KEY_ID", "AWS_SECRET_ACCESS_KEY"}}
+	if backup.Region != "" {
+		env.Clear = map[string]string{"AWS_DEFAULT_REGION": backup.Region}
+	}
+
+	return AccessoryConfig{
+		Image:   image,
+		Host:    postgres.Host,
+		Hosts:   slices.Clone(postgres.Hosts),
+		Env:     env,
+		Volumes: []string{archiveVolume + ":" + walArchiverDir},
+		// The image's entrypoint is aws; env runs the shell loop instead.
+		Entrypoint: "/usr/bin/env",
+		// Postgres archives as its own user into a volume created by root.
+		Cmd: fmt.Sprintf("chmod 1777 %s && while true; do %s; sleep %d; done", walArchiverDir, upload, int(interval.Seconds())),
+	}
+}
+
+// ProfileCommand returns the command of an accessory with the standard
+// profile, with Postgres tuned for memory bytes of RAM. It returns nil when
+// the accessory has no profile or sets its own cmd. Without a known memory
+// size, Postgres keeps its own memory settings.
+func (a *AccessoryConfig) ProfileCommand(memory int64) []string {
+	if a.Profile != AccessoryProfileStandard || a.Cmd != "" {
+		return nil
+	}
+
+	var settings []string
+	if mb := memory >> 20; mb > 0 {
+		settings = append(settings,
+			fmt.Sprintf("shared_buffers=%dMB", max(mb/4, 32)),
+			fmt.Sprintf("effective_cache_size=%dMB", max(mb*3/4, 64)),
+			fmt.Sprintf("maintenance_work_mem=%dMB", min(max(mb/16, 16), 2048)),
+			fmt.Sprintf("work_mem=%dMB", min(max(mb/256, 4), 64)),
+		)
+	}
+	settings = append(settings, "wal_compression=on")
+	if a.Backup.S3 != "" {
+		archive := postgresWALArchiveDir + "/%f"
+		settings = append(settings,
+			"archive_mode=on",
+			"archive_timeout=300",
+			// The upload skips *.tmp, so it never sees a partial file.
+			fmt.Sprintf("archive_command=cp %%p %s.tmp && mv %s.tmp %s", archive, archive, archive),
+		)
+	}
+
+	command := []string{"postgres"}
+	for _, setting := range settings {
+		command = append(command, "-c", setting)
+	}
+	return command
+}
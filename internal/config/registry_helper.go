@@ -0,0 +1,111 @@
+package config
+
+import (
+	"context"
+	"fmt"
+	"os/exec"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Registry credential helpers exchange the cloud credentials of the machine
+// running azud for a short-lived registry token, so no long-lived password has
+// to be stored in secrets.
+const (
+	RegistryHelperECR = "ecr"
+	RegistryHelperGCR = "gcr"
+	RegistryHelperACR = "acr"
+)
+
+// registryHelperUsernames are the usernames each registry expects alongside
+// a helper token.
+var registryHelperUsernames = map[string]string{
+	RegistryHelperECR: "AWS",
+	RegistryHelperGCR: "oauth2accesstoken",
+	RegistryHelperACR: "00000000-0000-0000-0000-000000000000",
+}
+
+var (
+	ecrServerRegex = regexp.MustCompile(`^[0-9]{12}\.dkr\.ecr\.([a-z0-9-]+)\.amazonaws\.com(\.cn)?$`)
+	gcrServerRegex = regexp.MustCompile(`^([a-z0-9-]+\.)?gcr\.io$|^[a-z0-9-]+-docker\.pkg\.dev$`)
+	acrServerRegex = regexp.MustCompile(`^([a-z0-9]+)\.azurecr\.(io|cn|us)$`)
+)
+
+// registryHelperTimeout bounds each token exchange.
+const registryHelperTimeout = 60 * time.Second
+
+var (
+	registryTokensMu sync.Mutex
+	registryTokens   = make(map[string]string)
+)
+
+// RegistryHelperUsername returns the username registries expect with a token
+// from helper, or "" for an unknown helper.
+func RegistryHelperUsername(helper string) string {
+	return registryHelperUsernames[helper]
+}
+
+// registryHelperCommand returns the CLI invocation that prints a registry token
+// for server on stdout.
+func registryHelperCommand(helper, server string) ([]string, error) {
+	server = NormalizeRegistryServer(server)
+	switch helper {
+	case RegistryHelperECR:
+		match := ecrServerRegex.FindStringSubmatch(server)
+		if match == nil {
+			return nil, fmt.Errorf("helper ecr requires an ECR server (<account>.dkr.ecr.<region>.amazonaws.com), got %q", server)
+		}
+		return []string{"aws", "ecr", "get-login-password", "--region", match[1]}, nil
+	case RegistryHelperGCR:
+		if !gcrServerRegex.MatchString(server) {
+			return nil, fmt.Errorf("helper gcr requires a gcr.io or <region>-docker.pkg.dev server, got %q", server)
+		}
+		return []string{"gcloud", "auth", "print-access-token"}, nil
+	case RegistryHelperACR:
+		match := acrServerRegex.FindStringSubmatch(server)
+		if match == nil {
+			return nil, fmt.Errorf("helper acr requires an <name>.azurecr.io server, got %q", server)
+		}
+		return []string{"az", "acr", "login", "--name", match[1], "--expose-token", "--output", "tsv", "--query", "accessToken"}, nil
+	default:
+		return nil, fmt.Errorf("unknown registry helper %q (expected ecr, gcr, or acr)", helper)
+	}
+}
+
+// helperToken runs the registry's credential helper. Tokens are cached for
+// the life of the process, which is far shorter than their validity.
+func (r RegistryConfig) helperToken() (string, error) {
+	args, err := registryHelperCommand(r.Helper, r.Server)
+	if err != nil {
+		return "", err
+	}
+
+	key := r.Helper + "\x00" + NormalizeRegistryServer(r.Server)
+	registryTokensMu.Lock()
+	defer registryTokensMu.Unlock()
+	if token, ok := registryTokens[key]; ok {
+		return token, nil
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), registryHelperTimeout)
+	defer cancel()
+
+	cmd := exec.CommandContext(ctx, args[0], args[1:]...)
+	var stderr strings.Builder
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		if ctx.Err() == context.DeadlineExceeded {
+			return "", fmt.Errorf("%s timed out after %s", args[0], registryHelperTimeout)
+		}
+		return "", fmt.Errorf("%s failed: %w (%s)", strings.Join(args[:3], " "), err, strings.TrimSpace(stderr.String()))
+	}
+	token := strings.TrimSpace(string(out))
+	if token == "" {
+		return "", fmt.Errorf("%s returned an empty token", strings.Join(args[:3], " "))
+	}
+	registryTokens[key] = token
+	return token, nil
+}
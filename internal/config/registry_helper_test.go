@@ -0,0 +1,95 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestRegistryHelperCommand(t *testing.T) {
+	tests := []struct {
+		helper  string
+		server  string
+		want    []string
+		wantErr string
+	}{
+		{"ecr", "123456789012.dkr.ecr.eu-west-1.amazonaws.com", []string{"aws", "ecr", "get-login-password", "--region", "eu-west-1"}, ""},
+		{"ecr", "ghcr.io", nil, "requires an ECR server"},
+		{"gcr", "gcr.io", []string{"gcloud", "auth", "print-access-token"}, ""},
+		{"gcr", "eu.gcr.io", []string{"gcloud", "auth", "print-access-token"}, ""},
+		{"gcr", "europe-west1-docker.pkg.dev", []string{"gcloud", "auth", "print-access-token"}, ""},
+		{"gcr", "docker.io", nil, "requires a gcr.io"},
+		{"acr", "https://myregistry.azurecr.io/", []string{"az", "acr", "login", "--name", "myregistry", "--expose-token", "--output", "tsv", "--query", "accessToken"}, ""},
+		{"acr", "myregistry.example.com", nil, "requires an <name>.azurecr.io"},
+		{"vault", "ghcr.io", nil, "unknown registry helper"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.helper+"/"+tt.server, func(t *testing.T) {
+			got, err := registryHelperCommand(tt.helper, tt.server)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("expected error containing %q, got %v", tt.wantErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Fatalf("command = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestResolvePasswordRunsHelperOnce(t *testing.T) {
+	dir := t.TempDir()
+	counter := filepath.Join(dir, "calls")
+	script := "#!/bin/sh\necho x >> " + counter + "\necho ' token-123 '\n"
+	if err := os.WriteFile(filepath.Join(dir, "gcloud"), []byte(script), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("PATH", dir+string(os.PathListSeparator)+os.Getenv("PATH"))
+
+	registry := RegistryConfig{Server: "test-helper.gcr.io", Helper: RegistryHelperGCR}
+	for range 2 {
+		password, err := registry.ResolvePassword()
+		if err != nil {
+			t.Fatalf("ResolvePassword() error: %v", err)
+		}
+		if password != "token-123" {
+			t.Fatalf("ResolvePassword() = %q, want token-123", password)
+		}
+	}
+
+	calls, err := os.ReadFile(counter)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n := strings.Count(string(calls), "x"); n != 1 {
+		t.Fatalf("helper ran %d times, want 1", n)
+	}
+}
+
+func TestApplyDefaults_RegistryHelperUsername(t *testing.T) {
+	cfg := &Config{
+		Registry: RegistryConfig{Server: "123456789012.dkr.ecr.us-east-1.amazonaws.com", Helper: RegistryHelperECR},
+		Registries: map[string]RegistryConfig{
+			"myregistry.azurecr.io": {Helper: RegistryHelperACR},
+			"gcr.io":                {Username: "custom", Helper: RegistryHelperGCR},
+		},
+	}
+	applyDefaults(cfg)
+
+	if cfg.Registry.Username != "AWS" {
+		t.Errorf("registry.username = %q, want AWS", cfg.Registry.Username)
+	}
+	if got := cfg.Registries["myregistry.azurecr.io"].Username; got != "00000000-0000-0000-0000-000000000000" {
+		t.Errorf("acr username = %q, want the ACR token user", got)
+	}
+	if got := cfg.Registries["gcr.io"].Username; got != "custom" {
+		t.Errorf("explicit username = %q, want custom", got)
+	}
+}
@@ -16,6 +16,26 @@ var yamlFieldAliases = map[reflect.Type]map[string]string{
 	},
 }
 
+// UnknownKeyError reports a configuration key that no field accepts.
+type UnknownKeyError struct {
+	// Complete config path of the key, e.g. proxy.healtcheck
+	Path string
+	// Closest known path, if any
+	Suggestion string
+	// File the key was read from, when known
+	File   string
+	Line   int
+	Column int
+}
+
+func (e *UnknownKeyError) Error() string {
+	message := fmt.Sprintf("line %d: unknown configuration key %q", e.Line, e.Path)
+	if e.Suggestion != "" {
+		message += fmt.Sprintf("; did you mean %q?", e.Suggestion)
+	}
+	return message
+}
+
 // validateConfigSchema reports unknown YAML keys with their complete config
 // path before decoding. yaml.Decoder.KnownFields remains enabled as a second
 // line of defense, while this pass supplies the actionable path and typo hint.
@@ -57,11 +77,11 @@ func validateConfigNode(node *yaml.Node, expected reflect.Type, configPath strin
 			}
 			currentPath := joinConfigPath(configPath, key)
 			if !ok {
-				message := fmt.Sprintf("line %d: unknown configuration key %q", keyNode.Line, currentPath)
+				unknown := &UnknownKeyError{Path: currentPath, Line: keyNode.Line, Column: keyNode.Column}
 				if suggestion := closestYAMLField(key, fields); suggestion != "" {
-					message += fmt.Sprintf("; did you mean %q?", joinConfigPath(configPath, suggestion))
+					unknown.Suggestion = joinConfigPath(configPath, suggestion)
 				}
-				return fmt.Errorf("%s", message)
+				return unknown
 			}
 			if err := validateConfigNode(valueNode, fieldType, currentPath); err != nil {
 				return err
@@ -1,6 +1,12 @@
 package config
 
-import "sync"
+import (
+	"fmt"
+	"path"
+	"regexp"
+	"slices"
+	"sync"
+)
 
 var (
 	secretsMu          sync.RWMutex
@@ -43,6 +49,33 @@ func AllSecrets() map[string]string {
 	return out
 }
 
+// Secret storage modes on remote hosts.
+const (
+	// SecretsStorageEnvFile writes secrets to an env file read at container start.
+	SecretsStorageEnvFile = "env_file"
+	// SecretsStoragePodman stores each secret as a Podman secret mounted
+	// into containers.
+	SecretsStoragePodman = "podman"
+	// SecretsStorageEncrypted writes the env file encrypted with age to a
+	// host-specific key and decrypts it only while a container is created.
+	SecretsStorageEncrypted = "encrypted"
+)
+
+// UsesPodmanSecrets reports whether secrets are stored as Podman secrets.
+func (c *Config) UsesPodmanSecrets() bool {
+	return c.SecretsStorage == SecretsStoragePodman
+}
+
+// UsesEncryptedSecrets reports whether remote env files are age-encrypted.
+func (c *Config) UsesEncryptedSecrets() bool {
+	return c.SecretsStorage == SecretsStorageEncrypted
+}
+
+// PodmanSecretName returns the Podman secret that holds key for the service.
+func (c *Config) PodmanSecretName(key string) string {
+	return fmt.Sprintf("azud-%s-%s", c.Service, key)
+}
+
 // DefaultRemoteSecretsPath is the default secrets file path on remote hosts.
 func DefaultRemoteSecretsPath() string {
 	return "$HOME/.azud/secrets"
@@ -55,3 +88,161 @@ func RemoteSecretsPath(cfg *Config) string {
 	}
 	return DefaultRemoteSecretsPath()
 }
+
+// EncryptedSecretsPath returns the age-encrypted counterpart of a remote env
+// file; its SHA-256 checksum is kept next to it with a .sha256 suffix.
+func EncryptedSecretsPath(envFile string) string {
+	return envFile + ".age"
+}
+
+// RemoteSecretsIdentityPath returns the host-specific age identity that
+// decrypts the remote env files. It lives next to the default secrets file.
+func RemoteSecretsIdentityPath(cfg *Config) string {
+	return path.Join(path.Dir(RemoteSecretsPath(cfg)), "age-identity")
+}
+
+// RoleSecretsPath returns the remote env file for a role's containers. Roles
+// that declare their own secrets use "<remote path>.<role>" so other roles on
+// the same host never receive them; all other roles use the default file.
+func RoleSecretsPath(cfg *Config, role string) string {
+	if cfg != nil && len(cfg.Servers[role].Secrets) > 0 {
+		return RemoteSecretsPath(cfg) + "." + role
+	}
+	return RemoteSecretsPath(cfg)
+}
+
+// RoleSecrets returns the secret names a role's containers receive: the
+// shared env.secret list followed by the role's own secrets.
+func (c *Config) RoleSecrets(role string) []string {
+	return mergeSecretNames(c.Env.Secret, c.Servers[role].Secrets)
+}
+
+// HostSecretFiles returns the remote env files a host needs, keyed by path,
+// with the secret names each must hold. Roles with their own secrets get a
+// dedicated file; other roles, cron jobs, and accessories share the default
+// file, which holds only the secrets declared for what runs on the host.
+func (c *Config) HostSecretFiles(host string) map[string][]string {
+	files := make(map[string][]string)
+	var shared []string
+	for _, role := range c.GetRoles() {
+		if c.IsProxyTierRole(role) || !slices.Contains(c.Servers[role].Hosts, host) {
+			continue
+		}
+		if len(c.Servers[role].Secrets) > 0 {
+			files[RoleSecretsPath(c, role)] = c.RoleSecrets(role)
+			continue
+		}
+		shared = mergeSecretNames(shared, c.Env.Secret)
+	}
+	if slices.Contains(c.GetAllCronHosts(), host) {
+		shared = mergeSecretNames(shared, c.Env.Secret)
+	}
+	for _, name := range c.GetAccessoryNames() {
+		accessory := c.Accessories[name]
+		if accessory.Host == host || slices.Contains(accessory.Hosts, host) {
+			shared = mergeSecretNames(shared, accessory.Env.Secret)
+		}
+	}
+	if len(shared) > 0 {
+		files[RemoteSecretsPath(c)] = shared
+	}
+	return files
+}
+
+// SecretNames returns every secret name the configuration declares, sorted:
+// env.secret, each role's secrets, each accessory's env.secret, and the
+// proxy's custom certificate and key.
+func (c *Config) SecretNames() []string {
+	names := slices.Clone(c.Env.Secret)
+	for _, role := range c.GetRoles() {
+		names = append(names, c.Servers[role].Secrets...)
+	}
+	for _, name := range c.GetAccessoryNames() {
+		names = append(names, c.Accessories[name].Env.Secret...)
+	}
+	for _, name := range []string{c.Proxy.SSLCertificate, c.Proxy.SSLPrivateKey} {
+		if name != "" {
+			names = append(names, name)
+		}
+	}
+	slices.Sort(names)
+	return slices.Compact(names)
+}
+
+// mergeSecretNames appends the names in extra that are not already in base.
+func mergeSecretNames(base, extra []string) []string {
+	merged := append([]string(nil), base...)
+	for _, name := range extra {
+		if !slices.Contains(merged, name) {
+			merged = append(merged, name)
+		}
+	}
+	return merged
+}
+
+// envReferenceRegex matches a ${NAME} reference in an environment value.
+var envReferenceRegex = regexp.MustCompile(`\$\{([A-Z_][A-Z0-9_]*)\}`)
+
+// EnvReferences returns the names value references as ${NAME}.
+func EnvReferences(value string) []string {
+	var names []string
+	for _, match := range envReferenceRegex.FindAllStringSubmatch(value, -1) {
+		names = append(names, match[1])
+	}
+	return names
+}
+
+// ResolveEnv resolves ${NAME} references between the values of env. Values
+// that still reference one of secrets are returned as templates instead and
+// resolved on the host from the secrets env file when the container starts,
+// so composed secret values never appear in the configuration or on a
+// command line. References in a cycle are left as written.
+func ResolveEnv(env map[string]string, secrets []string) (map[string]string, map[string]string) {
+	resolved := make(map[string]string, len(env))
+	for key, value := range env {
+		resolved[key] = value
+	}
+	pending := func(value string) bool {
+		for _, name := range EnvReferences(value) {
+			if _, ok := resolved[name]; ok {
+				return true
+			}
+		}
+		return false
+	}
+	// Each pass substitutes the values that are already final, so a chain
+	// resolves in at most len(env) passes.
+	for range len(env) {
+		changed := false
+		for key, value := range resolved {
+			next := envReferenceRegex.ReplaceAllStringFunc(value, func(ref string) string {
+				name := ref[2 : len(ref)-1]
+				if target, ok := resolved[name]; ok && name != key && !pending(target) {
+					return target
+				}
+				return ref
+			})
+			if next != value {
+				resolved[key], changed = next, true
+			}
+		}
+		if !changed {
+			break
+		}
+	}
+
+	var templates map[string]string
+	for key, value := range resolved {
+		for _, name := range EnvReferences(value) {
+			if _, ok := resolved[name]; !ok && slices.Contains(secrets, name) {
+				if templates == nil {
+					templates = make(map[string]string)
+				}
+				templates[key] = value
+				delete(resolved, key)
+				break
+			}
+		}
+	}
+	return resolved, templates
+}
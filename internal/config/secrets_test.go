@@ -0,0 +1,94 @@
+package config
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestHostSecretFilesScopesSecretsByRole(t *testing.T) {
+	cfg := &Config{
+		Servers: map[string]RoleConfig{
+			"web":    {Hosts: []string{"app1", "app2"}},
+			"worker": {Hosts: []string{"app2"}, Secrets: []string{"QUEUE_TOKEN", "DB_PASSWORD"}},
+		},
+		Env: EnvConfig{Secret: []string{"DB_PASSWORD"}},
+		Accessories: map[string]AccessoryConfig{
+			"db": {Host: "db1", Env: EnvConfig{Secret: []string{"POSTGRES_PASSWORD"}}},
+		},
+	}
+	shared := RemoteSecretsPath(cfg)
+	worker := RoleSecretsPath(cfg, "worker")
+	if worker != shared+".worker" {
+		t.Fatalf("worker secrets path = %q", worker)
+	}
+	if got := RoleSecretsPath(cfg, "web"); got != shared {
+		t.Fatalf("web secrets path = %q, want shared %q", got, shared)
+	}
+
+	tests := []struct {
+		host string
+		want map[string][]string
+	}{
+		{host: "app1", want: map[string][]string{shared: {"DB_PASSWORD"}}},
+		{host: "app2", want: map[string][]string{
+			shared: {"DB_PASSWORD"},
+			worker: {"DB_PASSWORD", "QUEUE_TOKEN"},
+		}},
+		{host: "db1", want: map[string][]string{shared: {"POSTGRES_PASSWORD"}}},
+		{host: "other", want: map[string][]string{}},
+	}
+	for _, tt := range tests {
+		if got := cfg.HostSecretFiles(tt.host); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("HostSecretFiles(%s) = %v, want %v", tt.host, got, tt.want)
+		}
+	}
+}
+
+func TestSecretNames(t *testing.T) {
+	cfg := &Config{
+		Servers: map[string]RoleConfig{
+			"web":    {Hosts: []string{"app1"}},
+			"worker": {Hosts: []string{"app2"}, Secrets: []string{"QUEUE_TOKEN", "DB_PASSWORD"}},
+		},
+		Env: EnvConfig{Secret: []string{"DB_PASSWORD"}},
+		Accessories: map[string]AccessoryConfig{
+			"db": {Host: "db1", Env: EnvConfig{Secret: []string{"POSTGRES_PASSWORD"}}},
+		},
+		Proxy: ProxyConfig{SSLCertificate: "TLS_CERT", SSLPrivateKey: "TLS_KEY"},
+	}
+	want := []string{"DB_PASSWORD", "POSTGRES_PASSWORD", "QUEUE_TOKEN", "TLS_CERT", "TLS_KEY"}
+	if got := cfg.SecretNames(); !reflect.DeepEqual(got, want) {
+		t.Fatalf("SecretNames() = %v, want %v", got, want)
+	}
+}
+
+func TestResolveEnv(t *testing.T) {
+	env := map[string]string{
+		"DB_HOST":      "db",
+		"DB_ADDR":      "${DB_HOST}:5432",
+		"DATABASE_URL": "postgres://app:${DATABASE_PASSWORD}@${DB_ADDR}/app",
+		"LOOP_A":       "${LOOP_B}",
+		"LOOP_B":       "${LOOP_A}",
+		"UNKNOWN":      "${NOT_DECLARED}",
+	}
+
+	resolved, templates := ResolveEnv(env, []string{"DATABASE_PASSWORD"})
+
+	wantResolved := map[string]string{
+		"DB_HOST": "db",
+		"DB_ADDR": "db:5432",
+		"LOOP_A":  "${LOOP_B}",
+		"LOOP_B":  "${LOOP_A}",
+		"UNKNOWN": "${NOT_DECLARED}",
+	}
+	if !reflect.DeepEqual(resolved, wantResolved) {
+		t.Errorf("resolved = %v, want %v", resolved, wantResolved)
+	}
+	wantTemplates := map[string]string{"DATABASE_URL": "postgres://app:${DATABASE_PASSWORD}@db:5432/app"}
+	if !reflect.DeepEqual(templates, wantTemplates) {
+		t.Errorf("templates = %v, want %v", templates, wantTemplates)
+	}
+	if env["DB_ADDR"] != "${DB_HOST}:5432" {
+		t.Error("ResolveEnv modified its input")
+	}
+}
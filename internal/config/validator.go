@@ -1,27 +1,81 @@
 package config
 
 import (
+	"errors"
 	"fmt"
+	"maps"
 	"net"
+	"net/url"
+	"path"
 	"regexp"
+	"slices"
+	"sort"
 	"strconv"
 	"strings"
 	"time"
+
+	"github.com/lemonity-org/azud/internal/shell"
+	"github.com/lemonity-org/azud/internal/ssh"
+)
+
+// Severity classifies a validation problem.
+type Severity string
+
+const (
+	// SeverityError problems stop the configuration from loading.
+	SeverityError Severity = "error"
+	// SeverityWarning problems are reported but never block a command.
+	SeverityWarning Severity = "warning"
 )
 
-// ValidationError represents a configuration validation error
+// ValidationError represents a configuration validation problem. File, Line,
+// and Column point at the offending YAML key when the loader can locate it.
 type ValidationError struct {
-	Field   string
-	Message string
+	Field    string   `json:"field"`
+	Message  string   `json:"message"`
+	Severity Severity `json:"severity"`
+	File     string   `json:"file,omitempty"`
+	Line     int      `json:"line,omitempty"`
+	Column   int      `json:"column,omitempty"`
 }
 
 func (e ValidationError) Error() string {
+	if e.Line > 0 {
+		return fmt.Sprintf("%s:%d:%d: %s: %s", e.File, e.Line, e.Column, e.Field, e.Message)
+	}
 	return fmt.Sprintf("%s: %s", e.Field, e.Message)
 }
 
+// IsWarning reports whether the problem is a non-blocking warning.
+func (e ValidationError) IsWarning() bool {
+	return e.Severity == SeverityWarning
+}
+
 // ValidationErrors holds multiple validation errors
 type ValidationErrors []ValidationError
 
+// Errors returns the problems that block loading.
+func (e ValidationErrors) Errors() ValidationErrors {
+	var errs ValidationErrors
+	for _, problem := range e {
+		if !problem.IsWarning() {
+			errs = append(errs, problem)
+		}
+	}
+	return errs
+}
+
+// Warnings returns the non-blocking problems.
+func (e ValidationErrors) Warnings() ValidationErrors {
+	var warnings ValidationErrors
+	for _, problem := range e {
+		if problem.IsWarning() {
+			warnings = append(warnings, problem)
+		}
+	}
+	return warnings
+}
+
 func (e ValidationErrors) Error() string {
 	if len(e) == 0 {
 		return "no validation errors"
@@ -43,10 +97,45 @@ var serviceNameRegex = regexp.MustCompile(`^[a-zA-Z][a-zA-Z0-9_.-]{0,62}$`)
 // shell commands, so they must not contain shell metacharacters.
 var resourceNameRegex = regexp.MustCompile(`^[a-zA-Z][a-zA-Z0-9_.-]{0,62}$`)
 
+// countryCodeRegex matches an ISO 3166-1 alpha-2 country code.
+var countryCodeRegex = regexp.MustCompile(`^[A-Z]{2}$`)
+
+// geoIPEditionRegex matches MaxMind edition IDs such as GeoLite2-Country.
+var geoIPEditionRegex = regexp.MustCompile(`^[A-Za-z0-9-]{1,64}$`)
+
+// caddyModuleRegex matches a Go module path with an optional @version, as
+// xcaddy's --with takes it.
+var caddyModuleRegex = regexp.MustCompile(`^[a-z0-9][a-z0-9.-]*\.[a-z]{2,}(/[A-Za-z0-9._~-]+)+(@[A-Za-z0-9._+-]+)?$`)
+
 var memoryLimitRegex = regexp.MustCompile(`^[1-9][0-9]*[bBkKmMgGtTpP]?$`)
 var sshUserRegex = regexp.MustCompile(`^[a-z_][a-z0-9_-]{0,31}$`)
 var remotePathRegex = regexp.MustCompile(`^[a-zA-Z0-9_./@+,: -]+$`)
 
+// aliasNameRegex validates config alias names, which become subcommands.
+var aliasNameRegex = regexp.MustCompile(`^[a-z][a-z0-9_-]*$`)
+
+// hostTagRegex validates host tags: a name, or a key=value pair whose value
+// may also name an env.tags block.
+var hostTagRegex = regexp.MustCompile(`^[a-zA-Z0-9][a-zA-Z0-9_.-]*(=[a-zA-Z0-9][a-zA-Z0-9_.-]*)?$`)
+
+// containerUserRegex validates container users: a name or id, optionally
+// followed by :group.
+var containerUserRegex = regexp.MustCompile(`^[a-zA-Z0-9_][a-zA-Z0-9_.-]*(:[a-zA-Z0-9_][a-zA-Z0-9_.-]*)?$`)
+
+// stopSignalRegex validates stop signals by name, such as SIGQUIT or QUIT,
+// or by number.
+var stopSignalRegex = regexp.MustCompile(`^([A-Z][A-Z0-9+-]*|[1-9][0-9]?)$`)
+
+// tmpfsMountRegex validates hardening tmpfs mounts: an absolute container
+// path with optional mount options such as size=64m,mode=1777.
+var tmpfsMountRegex = regexp.MustCompile(`^/[a-zA-Z0-9_./-]*(:[a-zA-Z0-9=,_]+)?$`)
+
+// capabilityRegex validates Linux capability names, with or without CAP_.
+var capabilityRegex = regexp.MustCompile(`^[a-zA-Z][a-zA-Z_]*$`)
+
+// secretNameRegex validates secret names, which become environment variables.
+var secretNameRegex = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
 // healthPathRegex validates HTTP healthcheck paths. Paths are embedded into
 // curl/wget commands (inside shell double quotes), so the character set is
 // restricted to safe URL path/query characters and deliberately excludes shell
@@ -59,8 +148,18 @@ func isValidHealthPath(path string) bool {
 	return healthPathRegex.MatchString(path)
 }
 
-// Validate checks the configuration for errors
+// Validate checks the configuration for errors. Warnings are ignored; use
+// Check to get them as well.
 func Validate(cfg *Config) error {
+	if errs := Check(cfg).Errors(); len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
+
+// Check returns every problem in the configuration, errors and warnings,
+// each with its Severity set.
+func Check(cfg *Config) ValidationErrors {
 	var errs ValidationErrors
 
 	// Required fields
@@ -108,8 +207,27 @@ func Validate(cfg *Config) error {
 					Message: "at least one host is required",
 				})
 			}
-			if len(rc.Tags) > 0 {
-				errs = append(errs, ValidationError{Field: fmt.Sprintf("servers.%s.tags", role), Message: "role tags are not supported"})
+			for i, tag := range rc.Tags {
+				if !hostTagRegex.MatchString(tag) {
+					errs = append(errs, ValidationError{
+						Field:   fmt.Sprintf("servers.%s.tags[%d]", role, i),
+						Message: fmt.Sprintf("invalid tag %q: use a name or key=value of letters, digits, '.', '_', or '-'", tag),
+					})
+				}
+			}
+			for i, name := range rc.Secrets {
+				if !secretNameRegex.MatchString(name) {
+					errs = append(errs, ValidationError{
+						Field:   fmt.Sprintf("servers.%s.secrets[%d]", role, i),
+						Message: fmt.Sprintf("invalid secret name: %s", name),
+					})
+				}
+			}
+			if len(rc.Secrets) > 0 && cfg.IsProxyTierRole(role) {
+				errs = append(errs, ValidationError{
+					Field:   fmt.Sprintf("servers.%s.secrets", role),
+					Message: "the proxy load-balancer tier runs no app containers and cannot take secrets",
+				})
 			}
 
 			// Validate host addresses
@@ -146,6 +264,8 @@ func Validate(cfg *Config) error {
 					})
 				}
 			}
+			errs = append(errs, validateHardening(role, rc.Hardening)...)
+			errs = append(errs, validateRuntimeOptions(fmt.Sprintf("servers.%s", role), rc.User, rc.StopSignal, rc.StopTimeout)...)
 		}
 	}
 
@@ -194,10 +314,10 @@ func Validate(cfg *Config) error {
 			Message: "https_port must be between 0 and 65535",
 		})
 	}
-	if protocol := strings.ToLower(strings.TrimSpace(cfg.Proxy.UpstreamProtocol)); protocol != "" && protocol != "http" && protocol != "h2c" && protocol != "https" {
+	if protocol := strings.ToLower(strings.TrimSpace(cfg.Proxy.UpstreamProtocol)); protocol != "" && protocol != "http" && protocol != "h2c" && protocol != "h2" && protocol != "https" {
 		errs = append(errs, ValidationError{
 			Field:   "proxy.upstream_protocol",
-			Message: "upstream_protocol must be one of: http, h2c, https",
+			Message: "upstream_protocol must be one of: http, h2c, h2, https",
 		})
 	}
 	if cfg.Proxy.ResponseTimeout != "" {
@@ -216,6 +336,127 @@ func Validate(cfg *Config) error {
 			})
 		}
 	}
+	if timeout, err := time.ParseDuration(cfg.Proxy.BootTimeout); cfg.Proxy.BootTimeout != "" && err == nil && timeout <= 0 {
+		errs = append(errs, ValidationError{
+			Field:   "proxy.boot_timeout",
+			Message: "boot_timeout must be positive",
+		})
+	}
+	for _, duration := range []struct{ field, value string }{
+		{"write_timeout", cfg.Proxy.WriteTimeout},
+		{"idle_timeout", cfg.Proxy.IdleTimeout},
+		{"dial_timeout", cfg.Proxy.DialTimeout},
+		{"boot_timeout", cfg.Proxy.BootTimeout},
+	} {
+		if duration.value == "" {
+			continue
+		}
+		if _, err := time.ParseDuration(duration.value); err != nil {
+			errs = append(errs, ValidationError{
+				Field:   "proxy." + duration.field,
+				Message: fmt.Sprintf("%s must be a valid duration (e.g., 30s, 1m)", duration.field),
+			})
+		}
+	}
+	if memory := cfg.Proxy.Resources.Memory; memory != "" && !memoryLimitRegex.MatchString(strings.TrimSpace(memory)) {
+		errs = append(errs, ValidationError{
+			Field:   "proxy.resources.memory",
+			Message: "memory must be a positive integer with an optional B, K, M, G, T, or P suffix",
+		})
+	}
+	if value := cfg.Proxy.Resources.CPUs; value != "" {
+		if cpus, err := strconv.ParseFloat(strings.TrimSpace(value), 64); err != nil || cpus <= 0 {
+			errs = append(errs, ValidationError{
+				Field:   "proxy.resources.cpus",
+				Message: "cpus must be a positive number",
+			})
+		}
+	}
+	switch cfg.Proxy.LogDriver {
+	case "", "k8s-file", "json-file", "journald", "none", "passthrough":
+	default:
+		errs = append(errs, ValidationError{
+			Field:   "proxy.log_driver",
+			Message: "log_driver must be one of: k8s-file, json-file, journald, none, passthrough",
+		})
+	}
+	for option, value := range cfg.Proxy.LogOptions {
+		switch option {
+		case "max-size":
+			if !memoryLimitRegex.MatchString(strings.TrimSpace(value)) {
+				errs = append(errs, ValidationError{
+					Field:   "proxy.log_options.max-size",
+					Message: "max-size must be a positive integer with an optional B, K, M, G, T, or P suffix",
+				})
+			}
+		case "path", "tag":
+			if strings.TrimSpace(value) == "" {
+				errs = append(errs, ValidationError{
+					Field:   "proxy.log_options." + option,
+					Message: option + " must not be empty",
+				})
+			}
+		default:
+			errs = append(errs, ValidationError{
+				Field:   "proxy.log_options." + option,
+				Message: "unsupported log option (allowed: max-size, path, tag)",
+			})
+		}
+	}
+	for _, duration := range []struct{ field, value string }{
+		{"try_duration", cfg.Proxy.Failover.TryDuration},
+		{"try_interval", cfg.Proxy.Failover.TryInterval},
+		{"fail_duration", cfg.Proxy.Failover.FailDuration},
+	} {
+		if duration.value == "" {
+			continue
+		}
+		if d, err := time.ParseDuration(duration.value); err != nil || d < 0 {
+			errs = append(errs, ValidationError{
+				Field:   "proxy.failover." + duration.field,
+				Message: fmt.Sprintf("failover.%s must be a valid non-negative duration (e.g., 5s, 250ms)", duration.field),
+			})
+		}
+	}
+	if cfg.Proxy.HoldTimeout != "" {
+		if d, err := time.ParseDuration(cfg.Proxy.HoldTimeout); err != nil || d <= 0 {
+			errs = append(errs, ValidationError{
+				Field:   "proxy.hold_timeout",
+				Message: "hold_timeout must be a valid positive duration (e.g., 10s)",
+			})
+		}
+		if cfg.Proxy.Failover.Retries > 0 {
+			errs = append(errs, ValidationError{
+				Field:    "proxy.hold_timeout",
+				Message:  "failover.retries ends a held request once its retries are spent, possibly before hold_timeout",
+				Severity: SeverityWarning,
+			})
+		}
+	}
+	if cfg.Proxy.Failover.Retries < 0 {
+		errs = append(errs, ValidationError{
+			Field:   "proxy.failover.retries",
+			Message: "failover.retries must be non-negative",
+		})
+	}
+	if cfg.Proxy.MaxRequests < 0 {
+		errs = append(errs, ValidationError{
+			Field:   "proxy.max_requests",
+			Message: "max_requests must be non-negative",
+		})
+	}
+	if cfg.Proxy.MaxConnections < 0 {
+		errs = append(errs, ValidationError{
+			Field:   "proxy.max_connections",
+			Message: "max_connections must be non-negative",
+		})
+	}
+	if cfg.Proxy.Failover.MaxFails < 0 {
+		errs = append(errs, ValidationError{
+			Field:   "proxy.failover.max_fails",
+			Message: "failover.max_fails must be non-negative",
+		})
+	}
 	if cfg.Proxy.Healthcheck.Interval != "" {
 		if _, err := time.ParseDuration(cfg.Proxy.Healthcheck.Interval); err != nil {
 			errs = append(errs, ValidationError{
@@ -232,6 +473,7 @@ func Validate(cfg *Config) error {
 			})
 		}
 	}
+	errs = append(errs, validateTimeoutHierarchy(cfg)...)
 	// Healthcheck probe paths are embedded in shell commands (curl/wget) that
 	// run on the host and inside containers, so they must not contain shell
 	// metacharacters.
@@ -267,9 +509,7 @@ func Validate(cfg *Config) error {
 			Message: "buffering.memory must be non-negative",
 		})
 	}
-	if len(cfg.Env.Tags) > 0 {
-		errs = append(errs, ValidationError{Field: "env.tags", Message: "tagged environments are not supported"})
-	}
+	errs = append(errs, validateHostTags(cfg)...)
 
 	// Validate ACME email when SSL is enabled (skip if custom certificates are provided)
 	if cfg.Proxy.SSL && cfg.Proxy.ACMEEmail == "" {
@@ -300,6 +540,121 @@ func Validate(cfg *Config) error {
 		}
 	}
 
+	if cfg.Deploy.RequireDigest && cfg.Deploy.AllowUnverifiedImage {
+		errs = append(errs, ValidationError{
+			Field:   "deploy.require_digest",
+			Message: "require_digest cannot be combined with allow_unverified_image",
+		})
+	}
+
+	// Validate approval gating
+	if webhook := cfg.Deploy.ApprovalWebhook; webhook != "" {
+		if parsed, err := url.Parse(webhook); err != nil || (parsed.Scheme != "https" && parsed.Scheme != "http") || parsed.Host == "" {
+			errs = append(errs, ValidationError{
+				Field:   "deploy.approval_webhook",
+				Message: "approval_webhook must be an http or https URL",
+			})
+		}
+		if !cfg.Deploy.RequireApproval {
+			errs = append(errs, ValidationError{
+				Field:   "deploy.approval_webhook",
+				Message: "approval_webhook is only used with require_approval: true",
+			})
+		}
+	}
+	if cfg.Deploy.ApprovalTimeout < 0 {
+		errs = append(errs, ValidationError{
+			Field:   "deploy.approval_timeout",
+			Message: "approval_timeout must be non-negative",
+		})
+	}
+
+	// Validate the error-rate gate
+	gate := cfg.Deploy.HaltOnErrorRate
+	if gate.Threshold < 0 || gate.Threshold > 100 {
+		errs = append(errs, ValidationError{
+			Field:   "deploy.halt_on_error_rate.threshold",
+			Message: "threshold must be between 0 and 100",
+		})
+	}
+	if gate.Window < 0 {
+		errs = append(errs, ValidationError{
+			Field:   "deploy.halt_on_error_rate.window",
+			Message: "window must be non-negative",
+		})
+	}
+	if gate.MinRequests < 0 {
+		errs = append(errs, ValidationError{
+			Field:   "deploy.halt_on_error_rate.min_requests",
+			Message: "min_requests must be non-negative",
+		})
+	}
+	if gate.Enabled() && !cfg.Proxy.Logging.AccessLog() {
+		errs = append(errs, ValidationError{
+			Field:   "deploy.halt_on_error_rate",
+			Message: "requires proxy.logging.enabled so the proxy records the access logs the error rate is read from",
+		})
+	}
+
+	// Validate restart-loop detection
+	restartLoop := cfg.Deploy.RestartLoop
+	if restartLoop.Threshold < 0 {
+		errs = append(errs, ValidationError{
+			Field:   "deploy.restart_loop.threshold",
+			Message: "threshold must be non-negative",
+		})
+	}
+	if restartLoop.Window < 0 {
+		errs = append(errs, ValidationError{
+			Field:   "deploy.restart_loop.window",
+			Message: "window must be non-negative",
+		})
+	}
+	if restartLoop.RollbackWithin < 0 {
+		errs = append(errs, ValidationError{
+			Field:   "deploy.restart_loop.rollback_within",
+			Message: "rollback_within must be non-negative",
+		})
+	}
+
+	// Validate automatic redeploys
+	if cfg.Deploy.Auto.Interval < 0 {
+		errs = append(errs, ValidationError{
+			Field:   "deploy.auto.interval",
+			Message: "interval must be non-negative",
+		})
+	}
+	if cfg.Deploy.Auto.WatchTag && strings.Contains(cfg.Image, "@") {
+		errs = append(errs, ValidationError{
+			Field:   "deploy.auto.watch_tag",
+			Message: "image is pinned to a digest, so there is no tag to watch",
+		})
+	}
+
+	// Validate deployment windows
+	if _, err := cfg.Deploy.Windows.Location(); err != nil {
+		errs = append(errs, ValidationError{
+			Field:   "deploy.windows.timezone",
+			Message: fmt.Sprintf("unknown time zone %q", cfg.Deploy.Windows.Timezone),
+		})
+	}
+	for i, window := range cfg.Deploy.Windows.Allowed {
+		for _, day := range window.Days {
+			if _, ok := windowWeekdays[strings.ToLower(day)]; !ok {
+				errs = append(errs, ValidationError{
+					Field:   fmt.Sprintf("deploy.windows.allowed[%d].days", i),
+					Message: fmt.Sprintf("unknown day %q (use mon, tue, wed, thu, fri, sat, or sun)", day),
+				})
+			}
+		}
+		if _, _, err := parseWindowHours(window.Hours); err != nil {
+			errs = append(errs, ValidationError{
+				Field:   fmt.Sprintf("deploy.windows.allowed[%d].hours", i),
+				Message: err.Error(),
+			})
+		}
+	}
+
 	// Validate canary configuration
 	if cfg.Deploy.Canary.Enabled {
 		if cfg.Deploy.Canary.InitialWeight < 0 || cfg.Deploy.Canary.InitialWeight > 100 {
@@ -322,6 +677,28 @@ func Validate(cfg *Config) error {
 			Message: "app_port must be between 0 and 65535",
 		})
 	}
+	if cfg.Proxy.AppSocket != "" {
+		errs = append(errs, validateAppSocket(cfg)...)
+	}
+	if len(cfg.Proxy.Streams) > 0 {
+		errs = append(errs, validateStreams(cfg)...)
+	}
+	if cfg.UsesStatic() {
+		errs = append(errs, validateStatic(cfg)...)
+	}
+	if len(cfg.Proxy.Bind) > 0 {
+		errs = append(errs, validateProxyBind(cfg)...)
+	}
+	if cfg.Proxy.ProxyProtocol || len(cfg.Proxy.ProxyProtocolAllow) > 0 {
+		errs = append(errs, validateProxyProtocol(cfg)...)
+	}
+	if len(cfg.Proxy.ImageBuild.Modules) > 0 || cfg.Proxy.ImageBuild.Repository != "" {
+		errs = append(errs, validateProxyImageBuild(cfg)...)
+	}
+	errs = append(errs, validateGeoIP(cfg)...)
+	if len(cfg.Proxy.ABTests) > 0 {
+		errs = append(errs, validateABTests(cfg)...)
+	}
 
 	// Validate accessories
 	for name, acc := range cfg.Accessories {
@@ -379,6 +756,10 @@ func Validate(cfg *Config) error {
 				errs = append(errs, ValidationError{Field: fmt.Sprintf("accessories.%s.options.%s", name, option), Message: "unsupported container option (allowed: memory, cpus)"})
 			}
 		}
+		errs = append(errs, validateRuntimeOptions(fmt.Sprintf("accessories.%s", name), acc.User, acc.StopSignal, acc.StopTimeout)...)
+		errs = append(errs, validateAccessoryHealthcheck(name, acc.Healthcheck)...)
+		errs = append(errs, validateAccessoryProfile(name, acc)...)
+		errs = append(errs, validateAccessoryCache(name, acc)...)
 		if len(acc.Roles) > 0 {
 			errs = append(errs, ValidationError{
 				Field:   fmt.Sprintf("accessories.%s.roles", name),
@@ -457,6 +838,44 @@ func Validate(cfg *Config) error {
 	if cfg.SSH.CommandTimeout < 0 {
 		errs = append(errs, ValidationError{Field: "ssh.command_timeout", Message: "must be non-negative"})
 	}
+	if cfg.SSH.MaxConcurrent < 0 {
+		errs = append(errs, ValidationError{Field: "ssh.max_concurrent", Message: "must be non-negative"})
+	}
+	if cfg.SSH.MaxConcurrentPerHost < 0 {
+		errs = append(errs, ValidationError{Field: "ssh.max_concurrent_per_host", Message: "must be non-negative"})
+	}
+	if cfg.SSH.KeyPassphraseSecret != "" && !secretNameRegex.MatchString(cfg.SSH.KeyPassphraseSecret) {
+		errs = append(errs, ValidationError{
+			Field:   "ssh.key_passphrase_secret",
+			Message: "key_passphrase_secret must name the secret holding the passphrase",
+		})
+	}
+	if cfg.SSH.Retry.Attempts < 0 {
+		errs = append(errs, ValidationError{Field: "ssh.retry.attempts", Message: "must be non-negative"})
+	}
+	if cfg.SSH.Retry.Backoff < 0 {
+		errs = append(errs, ValidationError{Field: "ssh.retry.backoff", Message: "must be non-negative"})
+	}
+	if cfg.SSH.Retry.MaxBackoff < 0 {
+		errs = append(errs, ValidationError{Field: "ssh.retry.max_backoff", Message: "must be non-negative"})
+	}
+	if cfg.SSH.Retry.Jitter < 0 || cfg.SSH.Retry.Jitter > 1 {
+		errs = append(errs, ValidationError{Field: "ssh.retry.jitter", Message: "must be between 0 and 1"})
+	}
+	if cfg.SSH.Retry.MaxBackoff > 0 && cfg.SSH.Retry.Backoff > cfg.SSH.Retry.MaxBackoff {
+		errs = append(errs, ValidationError{
+			Field:    "ssh.retry.backoff",
+			Message:  "exceeds ssh.retry.max_backoff, which caps every wait",
+			Severity: SeverityWarning,
+		})
+	}
+	if cfg.SSH.MaxConcurrent > 0 && cfg.SSH.MaxConcurrentPerHost > cfg.SSH.MaxConcurrent {
+		errs = append(errs, ValidationError{
+			Field:    "ssh.max_concurrent_per_host",
+			Message:  "exceeds ssh.max_concurrent, which bounds every host as well",
+			Severity: SeverityWarning,
+		})
+	}
 	if cfg.Security.RequireNonRootSSH && cfg.SSH.User == "root" {
 		errs = append(errs, ValidationError{
 			Field:   "security.require_non_root_ssh",
@@ -501,7 +920,7 @@ func Validate(cfg *Config) error {
 	}
 
 	// Validate builder configuration
-	if cfg.Builder.Remote.Host != "" {
+	if cfg.Builder.Remote.Host != "" && len(cfg.Builder.Remote.Builders) == 0 {
 		if !isValidHost(cfg.Builder.Remote.Host) {
 			errs = append(errs, ValidationError{
 				Field:   "builder.remote.host",
@@ -511,7 +930,7 @@ func Validate(cfg *Config) error {
 	}
 
 	// Validate builder remote arch
-	if cfg.Builder.Remote.Host != "" && cfg.Builder.Remote.Arch != "" {
+	if cfg.Builder.Remote.Host != "" && cfg.Builder.Remote.Arch != "" && len(cfg.Builder.Remote.Builders) == 0 {
 		if !isValidArch(cfg.Builder.Remote.Arch) {
 			errs = append(errs, ValidationError{
 				Field:   "builder.remote.arch",
@@ -520,6 +939,52 @@ func Validate(cfg *Config) error {
 		}
 	}
 
+	// Validate a list of remote builders: one per architecture
+	if len(cfg.Builder.Remote.Builders) > 0 {
+		seenArch := make(map[string]int)
+		for i, builder := range cfg.Builder.Remote.Builders {
+			field := fmt.Sprintf("builder.remote[%d]", i)
+			if builder.Host == "" || !isValidHost(builder.Host) {
+				errs = append(errs, ValidationError{
+					Field:   field + ".host",
+					Message: fmt.Sprintf("invalid remote builder host: %q", builder.Host),
+				})
+			}
+			if cfg.Builder.Remote.Parallel() && builder.Arch == "" {
+				errs = append(errs, ValidationError{
+					Field:   field + ".arch",
+					Message: "each remote builder in a list must declare the architecture it builds",
+				})
+				continue
+			}
+			if builder.Arch != "" && !isValidArch(builder.Arch) {
+				errs = append(errs, ValidationError{
+					Field:   field + ".arch",
+					Message: fmt.Sprintf("invalid architecture: %s (expected one of: amd64, arm64, arm, 386, ppc64le, s390x, riscv64)", builder.Arch),
+				})
+			}
+			if first, ok := seenArch[builder.Arch]; ok && builder.Arch != "" {
+				errs = append(errs, ValidationError{
+					Field:   field + ".arch",
+					Message: fmt.Sprintf("architecture %s is already built by builder.remote[%d]", builder.Arch, first),
+				})
+			}
+			seenArch[builder.Arch] = i
+		}
+		if cfg.Builder.Remote.Parallel() && (len(cfg.Builder.Platforms) > 0 || cfg.Builder.Arch != "") {
+			errs = append(errs, ValidationError{
+				Field:   "builder.remote",
+				Message: "builder.platforms and builder.arch cannot be combined with multiple remote builders; each builder's arch selects its platform",
+			})
+		}
+		if cfg.Builder.Remote.Parallel() && cfg.Builder.IsBuildpacks() {
+			errs = append(errs, ValidationError{
+				Field:   "builder.remote",
+				Message: "buildpacks builds use a single remote builder",
+			})
+		}
+	}
+
 	cacheType := strings.TrimSpace(cfg.Builder.Cache.Type)
 	if cacheType == "" && len(cfg.Builder.Cache.Options) > 0 {
 		errs = append(errs, ValidationError{
@@ -606,51 +1071,232 @@ func Validate(cfg *Config) error {
 		}
 	}
 
-	// Validate Podman configuration
-	validBackends := map[string]bool{"netavark": true, "cni": true}
-	if cfg.Podman.NetworkBackend != "" && !validBackends[cfg.Podman.NetworkBackend] {
+	// Validate builder type
+	switch cfg.Builder.Type {
+	case "", "dockerfile":
+	case "buildpacks":
+		if cfg.Builder.Multiarch || len(cfg.Builder.Platforms) > 0 {
+			errs = append(errs, ValidationError{
+				Field:   "builder.type",
+				Message: "buildpacks builds produce a single-architecture image; remove builder.multiarch and builder.platforms",
+			})
+		}
+		for key := range cfg.Builder.Pack.Env {
+			if !secretNameRegex.MatchString(key) {
+				errs = append(errs, ValidationError{
+					Field:   fmt.Sprintf("builder.pack.env.%s", key),
+					Message: "invalid environment variable name",
+				})
+			}
+		}
+	default:
 		errs = append(errs, ValidationError{
-			Field:   "podman.network_backend",
-			Message: "network_backend must be 'netavark' or 'cni'",
+			Field:   "builder.type",
+			Message: fmt.Sprintf("invalid builder type: %s (expected dockerfile or buildpacks)", cfg.Builder.Type),
 		})
 	}
-	if cfg.Security.RequireRootlessPodman && !cfg.Podman.Rootless {
+
+	// Validate builder engine
+	switch cfg.Builder.Engine {
+	case "", "podman", "buildx":
+	case "docker":
+		if (cfg.Builder.Multiarch || len(cfg.Builder.Platforms) > 0) && !cfg.Builder.IsBuildpacks() {
+			errs = append(errs, ValidationError{
+				Field:   "builder.engine",
+				Message: "multi-architecture builds need engine podman or buildx",
+			})
+		}
+	default:
 		errs = append(errs, ValidationError{
-			Field:   "security.require_rootless_podman",
-			Message: "rootless Podman required (set podman.rootless: true)",
+			Field:   "builder.engine",
+			Message: fmt.Sprintf("invalid builder engine: %s (expected podman, docker, or buildx)", cfg.Builder.Engine),
 		})
 	}
-	if cfg.Podman.Rootless && !cfg.Proxy.Rootful {
-		if cfg.Proxy.EffectiveHTTPPort() < 1024 {
+	if cfg.Builder.Remote.Host != "" && cfg.Builder.Engine != "" && cfg.Builder.Engine != "podman" {
+		errs = append(errs, ValidationError{
+			Field:   "builder.engine",
+			Message: "remote builders build with Podman; builder.engine applies to local builds only",
+		})
+	}
+
+	// Validate builder layer compression
+	switch cfg.Builder.Compression {
+	case "", "gzip", "zstd", "zstd:chunked":
+		if cfg.Builder.Compression != "" && cfg.Builder.Remote.Host == "" && (cfg.Builder.Engine == "docker" || cfg.Builder.Engine == "buildx") {
 			errs = append(errs, ValidationError{
-				Field:   "proxy.http_port",
-				Message: "rootless Podman cannot bind privileged HTTP ports (<1024); use http_port >= 1024, enable proxy.rootful, or disable podman.rootless",
+				Field:   "builder.compression",
+				Message: "push compression needs engine podman or a remote builder",
 			})
 		}
-		if cfg.Proxy.EffectiveHTTPSPort() < 1024 {
+	default:
+		errs = append(errs, ValidationError{
+			Field:   "builder.compression",
+			Message: fmt.Sprintf("invalid compression: %s (expected gzip, zstd, or zstd:chunked)", cfg.Builder.Compression),
+		})
+	}
+
+	for i, watchPath := range cfg.Builder.WatchPaths {
+		if strings.TrimSpace(watchPath) == "" {
 			errs = append(errs, ValidationError{
-				Field:   "proxy.https_port",
-				Message: "rootless Podman cannot bind privileged HTTPS ports (<1024); use https_port >= 1024, enable proxy.rootful, or disable podman.rootless",
+				Field:   fmt.Sprintf("builder.watch_paths[%d]", i),
+				Message: "watch path must not be empty",
 			})
 		}
 	}
-	if cfg.UseHostPortUpstreams() {
-		if cfg.Proxy.EffectiveHTTPPort() != DefaultHTTPPort {
+
+	// Validate builder SBOM format
+	validSBOMFormats := map[string]bool{"spdx-json": true, "cyclonedx-json": true}
+	if cfg.Builder.SBOMFormat != "" && !validSBOMFormats[cfg.Builder.SBOMFormat] {
+		errs = append(errs, ValidationError{
+			Field:   "builder.sbom_format",
+			Message: fmt.Sprintf("invalid SBOM format: %s (expected spdx-json or cyclonedx-json)", cfg.Builder.SBOMFormat),
+		})
+	}
+
+	// Validate the registry credential helper
+	if cfg.Registry.Helper != "" && cfg.Registry.Server == "" {
+		errs = append(errs, ValidationError{
+			Field:   "registry.server",
+			Message: "server is required with registry.helper",
+		})
+	} else {
+		errs = append(errs, validateRegistryHelper("registry", cfg.Registry.Server, cfg.Registry)...)
+	}
+	errs = append(errs, validateRegistryOAuth("registry", cfg.Registry)...)
+
+	// Validate per-registry credentials
+	for _, server := range cfg.RegistryServers() {
+		registry := cfg.Registries[server]
+		field := fmt.Sprintf("registries.%s", server)
+		host, port, hasPort := strings.Cut(server, ":")
+		if portNumber, err := strconv.Atoi(port); !isValidHost(host) || hasPort && (err != nil || portNumber < 1 || portNumber > 65535) {
 			errs = append(errs, ValidationError{
-				Field:   "proxy.http_port",
-				Message: "proxy.rootful with podman.rootless currently requires http_port=80",
+				Field:   field,
+				Message: fmt.Sprintf("invalid registry server: %s (expected host or host:port)", server),
 			})
 		}
-		if cfg.Proxy.EffectiveHTTPSPort() != DefaultHTTPSPort {
+		if registry.Server != "" {
 			errs = append(errs, ValidationError{
-				Field:   "proxy.https_port",
-				Message: "proxy.rootful with podman.rootless currently requires https_port=443",
+				Field:   field + ".server",
+				Message: "the server is the registries key; remove server",
 			})
 		}
-	}
-
-	// Validate healthcheck helper settings
-	if cfg.Proxy.Healthcheck.HelperPull != "" {
+		if registry.Username == "" {
+			errs = append(errs, ValidationError{
+				Field:   field + ".username",
+				Message: "username is required",
+			})
+		}
+		if len(registry.Password) == 0 && registry.Helper == "" {
+			errs = append(errs, ValidationError{
+				Field:   field + ".password",
+				Message: "password must name a secret, or set helper",
+			})
+		}
+		errs = append(errs, validateRegistryHelper(field, server, registry)...)
+		errs = append(errs, validateRegistryOAuth(field, registry)...)
+		for _, key := range registry.Password {
+			if !secretNameRegex.MatchString(key) {
+				errs = append(errs, ValidationError{
+					Field:   field + ".password",
+					Message: fmt.Sprintf("invalid secret name: %s", key),
+				})
+			}
+		}
+		if cfg.Registry.Username != "" && NormalizeRegistryServer(cfg.Registry.Server) == NormalizeRegistryServer(server) {
+			errs = append(errs, ValidationError{
+				Field:   field,
+				Message: fmt.Sprintf("%s is also configured in registry; configure its credentials in one place", NormalizeRegistryServer(server)),
+			})
+		}
+	}
+
+	// Validate Podman configuration
+	validBackends := map[string]bool{"netavark": true, "cni": true}
+	if cfg.Podman.NetworkBackend != "" && !validBackends[cfg.Podman.NetworkBackend] {
+		errs = append(errs, ValidationError{
+			Field:   "podman.network_backend",
+			Message: "network_backend must be 'netavark' or 'cni'",
+		})
+	}
+	switch cfg.Podman.SELinuxLabel {
+	case "", SELinuxLabelShared, SELinuxLabelPrivate:
+	default:
+		errs = append(errs, ValidationError{
+			Field:   "podman.selinux_label",
+			Message: "selinux_label must be 'shared' or 'private'",
+		})
+	}
+	errs = append(errs, validateVolumes("volumes", cfg.Volumes)...)
+	for _, name := range cfg.GetAccessoryNames() {
+		errs = append(errs, validateVolumes(fmt.Sprintf("accessories.%s.volumes", name), cfg.Accessories[name].Volumes)...)
+	}
+	if cfg.Security.RequireRootlessPodman && !cfg.Podman.Rootless {
+		errs = append(errs, ValidationError{
+			Field:   "security.require_rootless_podman",
+			Message: "rootless Podman required (set podman.rootless: true)",
+		})
+	}
+	if cfg.Podman.Rootless && !cfg.Proxy.Rootful {
+		if cfg.Proxy.EffectiveHTTPPort() < 1024 {
+			errs = append(errs, ValidationError{
+				Field:   "proxy.http_port",
+				Message: "rootless Podman cannot bind privileged HTTP ports (<1024); use http_port >= 1024, enable proxy.rootful, or disable podman.rootless",
+			})
+		}
+		if cfg.Proxy.EffectiveHTTPSPort() < 1024 {
+			errs = append(errs, ValidationError{
+				Field:   "proxy.https_port",
+				Message: "rootless Podman cannot bind privileged HTTPS ports (<1024); use https_port >= 1024, enable proxy.rootful, or disable podman.rootless",
+			})
+		}
+	}
+	errs = append(errs, validatePodmanNetworks(cfg)...)
+	if cfg.UsesProxyTier() {
+		errs = append(errs, validateProxyTier(cfg)...)
+	}
+	if cfg.Network.WireGuard {
+		if cfg.Network.Port < 1 || cfg.Network.Port > 65535 {
+			errs = append(errs, ValidationError{
+				Field:   "network.port",
+				Message: "port must be between 1 and 65535",
+			})
+		}
+		meshHosts := cfg.MeshHosts()
+		for _, host := range slices.Sorted(maps.Keys(cfg.Network.Addresses)) {
+			if !slices.Contains(meshHosts, host) {
+				errs = append(errs, ValidationError{
+					Field:   fmt.Sprintf("network.addresses.%s", host),
+					Message: fmt.Sprintf("host %s does not join the mesh", host),
+				})
+			}
+		}
+		if _, _, err := cfg.MeshAddresses(); err != nil {
+			problem := ValidationError{Field: "network.subnet", Message: err.Error()}
+			var pinned *meshAddressError
+			if errors.As(err, &pinned) {
+				problem = ValidationError{Field: "network.addresses." + pinned.host, Message: pinned.message}
+			}
+			errs = append(errs, problem)
+		}
+	}
+	if cfg.UseHostPortUpstreams() {
+		if cfg.Proxy.EffectiveHTTPPort() != DefaultHTTPPort {
+			errs = append(errs, ValidationError{
+				Field:   "proxy.http_port",
+				Message: "proxy.rootful with podman.rootless currently requires http_port=80",
+			})
+		}
+		if cfg.Proxy.EffectiveHTTPSPort() != DefaultHTTPSPort {
+			errs = append(errs, ValidationError{
+				Field:   "proxy.https_port",
+				Message: "proxy.rootful with podman.rootless currently requires https_port=443",
+			})
+		}
+	}
+
+	// Validate healthcheck helper settings
+	if cfg.Proxy.Healthcheck.HelperPull != "" {
 		validPull := map[string]bool{"missing": true, "always": true, "never": true}
 		if !validPull[strings.ToLower(strings.TrimSpace(cfg.Proxy.Healthcheck.HelperPull))] {
 			errs = append(errs, ValidationError{
@@ -684,6 +1330,33 @@ func Validate(cfg *Config) error {
 			Message: "secrets_provider must be file, env, or command",
 		})
 	}
+	switch cfg.SecretsStorage {
+	case "", SecretsStorageEnvFile, SecretsStorageEncrypted:
+	case SecretsStoragePodman:
+		// Podman secret names are derived from the secret names.
+		secrets := map[string][]string{"env.secret": cfg.Env.Secret}
+		for name, accessory := range cfg.Accessories {
+			secrets[fmt.Sprintf("accessories.%s.env.secret", name)] = accessory.Env.Secret
+		}
+		for field, names := range secrets {
+			for i, name := range names {
+				if !secretNameRegex.MatchString(name) {
+					errs = append(errs, ValidationError{
+						Field:   fmt.Sprintf("%s[%d]", field, i),
+						Message: fmt.Sprintf("invalid secret name for secrets_storage=podman: %s", name),
+					})
+				}
+			}
+		}
+	default:
+		errs = append(errs, ValidationError{
+			Field:   "secrets_storage",
+			Message: "secrets_storage must be env_file, podman, or encrypted",
+		})
+	}
+	errs = append(errs, validateEnvReferences(cfg)...)
+	errs = append(errs, validateEnvFiles(cfg)...)
+	errs = append(errs, validateDNS(cfg)...)
 	if cfg.SecretsRemotePath != "" && !isValidRemoteSecretsPath(cfg.SecretsRemotePath) {
 		errs = append(errs, ValidationError{
 			Field:   "secrets_remote_path",
@@ -698,6 +1371,27 @@ func Validate(cfg *Config) error {
 			Message: "retain_containers must be non-negative",
 		})
 	}
+	if cfg.Deploy.PullRateLimit < 0 {
+		errs = append(errs, ValidationError{
+			Field:   "deploy.pull_rate_limit",
+			Message: "pull_rate_limit must be non-negative",
+		})
+	}
+	switch cfg.Deploy.ImageTransfer {
+	case "", ImageTransferRegistry:
+	case ImageTransferPeer:
+		if cfg.Deploy.RequireDigest {
+			errs = append(errs, ValidationError{
+				Field:   "deploy.image_transfer",
+				Message: "peer transfer cannot be combined with require_digest: a copied image has no registry digest to pin",
+			})
+		}
+	default:
+		errs = append(errs, ValidationError{
+			Field:   "deploy.image_transfer",
+			Message: fmt.Sprintf("unknown image transfer %q (expected registry or peer)", cfg.Deploy.ImageTransfer),
+		})
+	}
 
 	// Validate minimum_version format
 	if cfg.MinimumVersion != "" && !isValidSemver(cfg.MinimumVersion) {
@@ -707,17 +1401,136 @@ func Validate(cfg *Config) error {
 		})
 	}
 	if cfg.AssetPath != "" {
-		errs = append(errs, ValidationError{Field: "asset_path", Message: "asset bridging is not supported"})
+		if !path.IsAbs(cfg.AssetPath) || path.Clean(cfg.AssetPath) != cfg.AssetPath || cfg.AssetPath == "/" {
+			errs = append(errs, ValidationError{Field: "asset_path", Message: "must be a clean absolute path in the container, such as /app/public"})
+		}
+		for _, spec := range cfg.Volumes {
+			if volume, err := ParseVolume(spec); err == nil && path.Clean(volume.Target) == cfg.AssetPath {
+				errs = append(errs, ValidationError{Field: "asset_path", Message: fmt.Sprintf("volume %s is already mounted at %s", spec, cfg.AssetPath)})
+			}
+		}
 	}
-	if len(cfg.Aliases) > 0 {
-		errs = append(errs, ValidationError{Field: "aliases", Message: "command aliases are not supported"})
+	for _, name := range slices.Sorted(maps.Keys(cfg.Aliases)) {
+		field := fmt.Sprintf("aliases.%s", name)
+		if !aliasNameRegex.MatchString(name) {
+			errs = append(errs, ValidationError{Field: field, Message: "alias names must be lowercase letters, digits, '-' or '_', starting with a letter"})
+		}
+		args, err := shell.Split(cfg.Aliases[name])
+		switch {
+		case err != nil:
+			errs = append(errs, ValidationError{Field: field, Message: err.Error()})
+		case len(args) == 0:
+			errs = append(errs, ValidationError{Field: field, Message: "alias must expand to an azud command"})
+		case args[0] == name:
+			errs = append(errs, ValidationError{Field: field, Message: "alias must not expand to itself"})
+		}
 	}
 
-	if len(errs) > 0 {
-		return errs
+	validRole := func(role string) bool { return role == RoleOperator || role == RoleReadOnly }
+	if cfg.Team.DefaultRole != "" && !validRole(cfg.Team.DefaultRole) {
+		errs = append(errs, ValidationError{Field: "team.default_role", Message: "role must be operator or read_only"})
+	}
+	for _, user := range slices.Sorted(maps.Keys(cfg.Team.Roles)) {
+		if !validRole(cfg.Team.Roles[user]) {
+			errs = append(errs, ValidationError{Field: fmt.Sprintf("team.roles.%s", user), Message: "role must be operator or read_only"})
+		}
 	}
 
-	return nil
+	if (cfg.Agent.TLSCertificate == "") != (cfg.Agent.TLSPrivateKey == "") {
+		errs = append(errs, ValidationError{Field: "agent.tls_certificate", Message: "agent.tls_certificate and agent.tls_private_key must be set together"})
+	}
+	for _, name := range slices.Sorted(maps.Keys(cfg.Agent.Tokens)) {
+		token := cfg.Agent.Tokens[name]
+		field := fmt.Sprintf("agent.tokens.%s", name)
+		if !secretNameRegex.MatchString(token.Secret) {
+			errs = append(errs, ValidationError{Field: field + ".secret", Message: "secret must name the secret that holds the token"})
+		}
+		if token.Role != "" && !validRole(token.Role) {
+			errs = append(errs, ValidationError{Field: field + ".role", Message: "role must be operator or read_only"})
+		}
+	}
+
+	if cfg.Serve.Secret != "" && !secretNameRegex.MatchString(cfg.Serve.Secret) {
+		errs = append(errs, ValidationError{Field: "serve.secret", Message: "secret must name the secret that holds the webhook key"})
+	}
+	for i, pattern := range cfg.Serve.Tags {
+		if _, err := path.Match(pattern, ""); err != nil {
+			errs = append(errs, ValidationError{Field: fmt.Sprintf("serve.tags[%d]", i), Message: fmt.Sprintf("invalid pattern %q", pattern)})
+		}
+	}
+
+	errs = append(errs, validateCrossField(cfg)...)
+	errs = append(errs, checkWarnings(cfg)...)
+	for i := range errs {
+		if errs[i].Severity == "" {
+			errs[i].Severity = SeverityError
+		}
+	}
+	return errs
+}
+
+// LoopbackListen reports whether listen, a host:port address, only accepts
+// connections from this machine.
+func LoopbackListen(listen string) bool {
+	host, _, err := net.SplitHostPort(listen)
+	if err != nil {
+		return false
+	}
+	if host == "localhost" {
+		return true
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
+
+// checkWarnings reports settings that are valid but weaken safety or are
+// likely unintended.
+func checkWarnings(cfg *Config) []ValidationError {
+	var warnings []ValidationError
+	if cfg.SSH.InsecureIgnoreHostKey && !cfg.Security.RequireKnownHosts {
+		warnings = append(warnings, ValidationError{
+			Field:    "ssh.insecure_ignore_host_key",
+			Message:  "host keys are not verified, so connections are open to man-in-the-middle attacks",
+			Severity: SeverityWarning,
+		})
+	}
+	if cfg.Agent.Listen != "" && cfg.Agent.TLSCertificate == "" && !LoopbackListen(cfg.Agent.Listen) {
+		warnings = append(warnings, ValidationError{
+			Field:    "agent.listen",
+			Message:  "the agent API listens beyond loopback without TLS, so tokens travel in clear text",
+			Severity: SeverityWarning,
+		})
+	}
+	if cfg.Network.WireGuard {
+		var unpinned []string
+		for _, host := range cfg.MeshHosts() {
+			if _, ok := cfg.Network.Addresses[host]; !ok {
+				unpinned = append(unpinned, host)
+			}
+		}
+		if len(unpinned) > 0 && len(cfg.MeshHosts()) > 1 {
+			warnings = append(warnings, ValidationError{
+				Field:    "network.addresses",
+				Message:  fmt.Sprintf("mesh addresses of %s are not pinned and shift when hosts are added or removed; pin the addresses azud server wireguard prints", strings.Join(unpinned, ", ")),
+				Severity: SeverityWarning,
+			})
+		}
+	}
+	if cfg.Deploy.AllowUnverifiedImage {
+		warnings = append(warnings, ValidationError{
+			Field:    "deploy.allow_unverified_image",
+			Message:  "images are deployed without digest verification",
+			Severity: SeverityWarning,
+		})
+	}
+	if response, ok := parseTimeout(cfg.Proxy.ResponseTimeout); ok && cfg.Deploy.DrainTimeout > 0 && response > cfg.Deploy.DrainTimeout {
+		warnings = append(warnings, ValidationError{
+			Field:    "deploy.drain_timeout",
+			Message:  fmt.Sprintf("drain_timeout (%s) is shorter than proxy.response_timeout (%s), so deploys may cut off slow requests", cfg.Deploy.DrainTimeout, response),
+			Severity: SeverityWarning,
+		})
+	}
+	return warnings
 }
 
 func isValidRemoteSecretsPath(path string) bool {
@@ -747,10 +1560,14 @@ func isValidRemoteSecretsPath(path string) bool {
 
 // isValidHost checks if a string is a valid hostname or IP address
 func isValidHost(host string) bool {
-	// Check if it's an IP address
+	// Check if it's an IP address. IPv6 literals may be written bracketed.
 	if ip := net.ParseIP(host); ip != nil {
 		return true
 	}
+	if strings.HasPrefix(host, "[") && strings.HasSuffix(host, "]") {
+		ip := net.ParseIP(host[1 : len(host)-1])
+		return ip != nil && ip.To4() == nil
+	}
 
 	// Check if it's a valid hostname
 	// Hostnames can contain letters, digits, and hyphens
@@ -952,6 +1769,40 @@ func parseSemver(version string) (int, int, int, bool) {
 	return major, minor, patch, true
 }
 
+// CompareVersions compares two MAJOR.MINOR.PATCH versions (an optional "v"
+// prefix is ignored). It returns -1, 0, or 1, and false when either version
+// is not in that format.
+func CompareVersions(a, b string) (int, bool) {
+	aMajor, aMinor, aPatch, ok := parseSemver(a)
+	if !ok {
+		return 0, false
+	}
+	bMajor, bMinor, bPatch, ok := parseSemver(b)
+	if !ok {
+		return 0, false
+	}
+	for _, diff := range []int{aMajor - bMajor, aMinor - bMinor, aPatch - bPatch} {
+		if diff < 0 {
+			return -1, true
+		}
+		if diff > 0 {
+			return 1, true
+		}
+	}
+	return 0, true
+}
+
+// MinimumVersionError reports that the running azud is older than the
+// configuration's minimum_version.
+type MinimumVersionError struct {
+	Required string
+	Current  string
+}
+
+func (e *MinimumVersionError) Error() string {
+	return fmt.Sprintf("minimum version %s required, but running %s", e.Required, e.Current)
+}
+
 // ValidateMinimumVersion checks if the current version meets the minimum requirement
 func ValidateMinimumVersion(cfg *Config, currentVersion string) error {
 	if cfg.MinimumVersion == "" {
@@ -962,19 +1813,15 @@ func ValidateMinimumVersion(cfg *Config, currentVersion string) error {
 		return nil // Dev versions bypass the check
 	}
 
-	reqMajor, reqMinor, reqPatch, ok := parseSemver(cfg.MinimumVersion)
-	if !ok {
+	if _, _, _, ok := parseSemver(cfg.MinimumVersion); !ok {
 		return fmt.Errorf("invalid minimum_version format: %s", cfg.MinimumVersion)
 	}
-	curMajor, curMinor, curPatch, ok := parseSemver(currentVersion)
+	cmp, ok := CompareVersions(currentVersion, cfg.MinimumVersion)
 	if !ok {
 		return fmt.Errorf("invalid current version format: %s", currentVersion)
 	}
-
-	if curMajor < reqMajor ||
-		(curMajor == reqMajor && curMinor < reqMinor) ||
-		(curMajor == reqMajor && curMinor == reqMinor && curPatch < reqPatch) {
-		return fmt.Errorf("minimum version %s required, but running %s", cfg.MinimumVersion, currentVersion)
+	if cmp < 0 {
+		return &MinimumVersionError{Required: cfg.MinimumVersion, Current: currentVersion}
 	}
 
 	return nil
@@ -1138,20 +1985,1223 @@ func hasTrustedFingerprint(cfg *Config, host string) bool {
 		return false
 	}
 
-	if fps := cfg.SSH.TrustedHostFingerprints[host]; len(fps) > 0 {
-		return true
+	// Look the host up under the same keys the SSH host key callback tries,
+	// so IPv6 hosts may be written with or without brackets.
+	host = ssh.TrimBrackets(host)
+	keys := []string{host}
+	if strings.Contains(host, ":") {
+		keys = append(keys, "["+host+"]")
 	}
-
 	port := cfg.SSH.Port
 	if port == 0 {
 		port = 22
 	}
 	if port != 22 {
-		key := fmt.Sprintf("[%s]:%d", host, port)
+		keys = append(keys, fmt.Sprintf("[%s]:%d", host, port))
+	}
+
+	for _, key := range keys {
 		if fps := cfg.SSH.TrustedHostFingerprints[key]; len(fps) > 0 {
 			return true
 		}
 	}
-
 	return false
 }
+
+// networkNamePattern matches the network names Podman accepts.
+var networkNamePattern = regexp.MustCompile(`^[a-zA-Z0-9][a-zA-Z0-9_.-]*$`)
+
+// validateRegistryHelper checks a registry's credential helper: it must be
+// known, match the registry's server, and replace the password.
+func validateRegistryHelper(field, server string, registry RegistryConfig) []ValidationError {
+	if registry.Helper == "" {
+		return nil
+	}
+	var errs []ValidationError
+	if _, err := registryHelperCommand(registry.Helper, server); err != nil {
+		errs = append(errs, ValidationError{
+			Field:   field + ".helper",
+			Message: err.Error(),
+		})
+	}
+	if len(registry.Password) > 0 {
+		errs = append(errs, ValidationError{
+			Field:   field + ".password",
+			Message: "password cannot be combined with helper",
+		})
+	}
+	return errs
+}
+
+// validateRegistryOAuth checks a registry's device authorization settings.
+// The token is stored in the single secret password names.
+func validateRegistryOAuth(field string, registry RegistryConfig) []ValidationError {
+	oauth := registry.OAuth
+	if oauth == nil {
+		return nil
+	}
+	var errs []ValidationError
+	endpoints := []struct{ name, url string }{
+		{"device_authorization_url", oauth.DeviceAuthorizationURL},
+		{"token_url", oauth.TokenURL},
+	}
+	for _, endpoint := range endpoints {
+		if parsed, err := url.Parse(endpoint.url); err != nil || parsed.Scheme != "https" || parsed.Host == "" {
+			errs = append(errs, ValidationError{
+				Field:   field + ".oauth." + endpoint.name,
+				Message: fmt.Sprintf("%s must be an https URL", endpoint.name),
+			})
+		}
+	}
+	if oauth.ClientID == "" {
+		errs = append(errs, ValidationError{
+			Field:   field + ".oauth.client_id",
+			Message: "client_id is required",
+		})
+	}
+	if registry.Helper != "" {
+		errs = append(errs, ValidationError{
+			Field:   field + ".oauth",
+			Message: "oauth cannot be combined with helper",
+		})
+	}
+	if len(registry.Password) != 1 {
+		errs = append(errs, ValidationError{
+			Field:   field + ".password",
+			Message: "oauth requires password to name the one secret that stores the token",
+		})
+	}
+	return errs
+}
+
+// validatePodmanNetworks checks the names and options of podman.networks.
+func validatePodmanNetworks(cfg *Config) []ValidationError {
+	var errs []ValidationError
+
+	names := make([]string, 0, len(cfg.Podman.Networks))
+	for name := range cfg.Podman.Networks {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		network := cfg.Podman.Networks[name]
+		field := fmt.Sprintf("podman.networks.%s", name)
+		if !networkNamePattern.MatchString(name) {
+			errs = append(errs, ValidationError{Field: field, Message: "invalid network name"})
+		}
+		if name == DefaultNetworkName && network.Internal {
+			errs = append(errs, ValidationError{Field: field + ".internal", Message: "the azud network carries proxy traffic and cannot be internal"})
+		}
+		var subnet *net.IPNet
+		if network.Subnet != "" {
+			var err error
+			if _, subnet, err = net.ParseCIDR(network.Subnet); err != nil {
+				errs = append(errs, ValidationError{Field: field + ".subnet", Message: fmt.Sprintf("invalid subnet: %s", network.Subnet)})
+			}
+		}
+		if network.Gateway != "" {
+			gateway := net.ParseIP(network.Gateway)
+			switch {
+			case network.Subnet == "":
+				errs = append(errs, ValidationError{Field: field + ".gateway", Message: "gateway requires subnet"})
+			case gateway == nil || subnet != nil && !subnet.Contains(gateway):
+				errs = append(errs, ValidationError{Field: field + ".gateway", Message: fmt.Sprintf("gateway %s is not an address in %s", network.Gateway, network.Subnet)})
+			}
+		}
+		for i, server := range network.DNS {
+			if net.ParseIP(server) == nil {
+				errs = append(errs, ValidationError{Field: fmt.Sprintf("%s.dns[%d]", field, i), Message: fmt.Sprintf("invalid DNS server: %s", server)})
+			}
+		}
+	}
+
+	checkAttachments := func(field string, attached []string) {
+		seen := make(map[string]bool)
+		for _, name := range attached {
+			if seen[name] {
+				errs = append(errs, ValidationError{Field: field, Message: fmt.Sprintf("network %s listed twice", name)})
+			}
+			seen[name] = true
+			if _, ok := cfg.Podman.Networks[name]; !ok && name != DefaultNetworkName {
+				errs = append(errs, ValidationError{Field: field, Message: fmt.Sprintf("network %s is not defined in podman.networks", name)})
+			}
+		}
+	}
+
+	roles := make([]string, 0, len(cfg.Servers))
+	for role := range cfg.Servers {
+		roles = append(roles, role)
+	}
+	sort.Strings(roles)
+	for _, role := range roles {
+		attached := cfg.Servers[role].Networks
+		if len(attached) == 0 {
+			continue
+		}
+		field := fmt.Sprintf("servers.%s.networks", role)
+		checkAttachments(field, attached)
+		if role == "web" && !slices.Contains(attached, DefaultNetworkName) {
+			errs = append(errs, ValidationError{Field: field, Message: "the web role must stay on the azud network so the proxy can reach it"})
+		}
+	}
+
+	for _, name := range cfg.GetAccessoryNames() {
+		accessory := cfg.Accessories[name]
+		if len(accessory.Networks) == 0 {
+			continue
+		}
+		field := fmt.Sprintf("accessories.%s.networks", name)
+		checkAttachments(field, accessory.Networks)
+		if accessory.Port != "" && allNetworksInternal(cfg, accessory.Networks) {
+			errs = append(errs, ValidationError{
+				Field:   fmt.Sprintf("accessories.%s.port", name),
+				Message: "cannot publish a port from an accessory attached only to internal networks",
+			})
+		}
+	}
+
+	return errs
+}
+
+func allNetworksInternal(cfg *Config, names []string) bool {
+	for _, name := range names {
+		if !cfg.Podman.Networks[name].Internal {
+			return false
+		}
+	}
+	return true
+}
+
+// maxUnixSocketPath is the longest socket path the kernel accepts
+// (sun_path is 108 bytes including the terminating NUL).
+const maxUnixSocketPath = 107
+
+func validateAppSocket(cfg *Config) []ValidationError {
+	var errs []ValidationError
+	socket := cfg.Proxy.AppSocket
+	if !path.IsAbs(socket) || path.Clean(socket) != socket || path.Dir(socket) == "/" {
+		return append(errs, ValidationError{
+			Field:   "proxy.app_socket",
+			Message: "app_socket must be a clean absolute path below a directory other than / (e.g. /run/app/app.sock)",
+		})
+	}
+	upstream := strings.TrimPrefix(cfg.AppSocketUpstream(strings.Repeat("0", AppSocketKeyLength)), "unix/")
+	if len(upstream) > maxUnixSocketPath {
+		errs = append(errs, ValidationError{
+			Field:   "proxy.app_socket",
+			Message: fmt.Sprintf("the proxy would dial %s, which exceeds the %d-byte unix socket path limit; shorten the service name or socket file name", upstream, maxUnixSocketPath),
+		})
+	}
+	if cfg.UsesProxyTier() {
+		errs = append(errs, ValidationError{
+			Field:   "proxy.app_socket",
+			Message: "app_socket requires Caddy on the app hosts and cannot be combined with proxy.hosts_role",
+		})
+	}
+	return errs
+}
+
+func validateStatic(cfg *Config) []ValidationError {
+	var errs []ValidationError
+	if cfg.UsesProxyTier() {
+		errs = append(errs, ValidationError{
+			Field:   "proxy.static",
+			Message: "static paths are served from the app hosts' disks and cannot be combined with proxy.hosts_role",
+		})
+	}
+	var prefixes []string
+	for i, static := range cfg.Proxy.Static {
+		field := fmt.Sprintf("proxy.static[%d]", i)
+		if !path.IsAbs(static.Path) || path.Clean(static.Path) != static.Path || static.Path == "/" {
+			errs = append(errs, ValidationError{
+				Field:   field + ".path",
+				Message: "path must be a clean absolute URL path other than /, such as /assets",
+			})
+		} else {
+			for _, other := range prefixes {
+				if pathWithin(static.Path, other) || pathWithin(other, static.Path) {
+					errs = append(errs, ValidationError{
+						Field:   field + ".path",
+						Message: fmt.Sprintf("path %s overlaps %s", static.Path, other),
+					})
+				}
+			}
+			prefixes = append(prefixes, static.Path)
+		}
+		if !path.IsAbs(static.Dir) || path.Clean(static.Dir) != static.Dir || static.Dir == "/" {
+			errs = append(errs, ValidationError{
+				Field:   field + ".dir",
+				Message: "dir must be a clean absolute path in the container, such as /app/public/assets",
+			})
+			continue
+		}
+		if !static.Shared {
+			continue
+		}
+		for _, spec := range cfg.Volumes {
+			if volume, err := ParseVolume(spec); err == nil && path.Clean(volume.Target) == static.Dir {
+				errs = append(errs, ValidationError{
+					Field:   field + ".dir",
+					Message: fmt.Sprintf("volume %s is already mounted at %s", spec, static.Dir),
+				})
+			}
+		}
+		if static.Dir == cfg.AssetPath {
+			errs = append(errs, ValidationError{
+				Field:   field + ".dir",
+				Message: fmt.Sprintf("asset_path is already mounted at %s", static.Dir),
+			})
+		}
+	}
+	return errs
+}
+
+// pathWithin reports whether p is dir or below it.
+func pathWithin(p, dir string) bool {
+	return p == dir || strings.HasPrefix(p, dir+"/")
+}
+
+func validateProxyBind(cfg *Config) []ValidationError {
+	var errs []ValidationError
+	private := false
+	for i, entry := range cfg.Proxy.Bind {
+		entry = strings.TrimSpace(entry)
+		if entry == ProxyBindPrivate {
+			private = true
+			continue
+		}
+		if net.ParseIP(strings.Trim(entry, "[]")) == nil {
+			errs = append(errs, ValidationError{
+				Field:   fmt.Sprintf("proxy.bind[%d]", i),
+				Message: fmt.Sprintf("invalid bind address %q: use an IP address or %s", entry, ProxyBindPrivate),
+			})
+		}
+	}
+	if private {
+		for _, host := range cfg.GetProxyHosts() {
+			if addr := cfg.PrivateAddress(host); net.ParseIP(addr) == nil {
+				errs = append(errs, ValidationError{
+					Field:   "proxy.bind",
+					Message: fmt.Sprintf("the private address of %s is %s, not an IP address; set it in proxy.private_addresses", host, addr),
+				})
+			}
+		}
+	}
+	if cfg.UseHostPortUpstreams() {
+		errs = append(errs, ValidationError{
+			Field:   "proxy.bind",
+			Message: "bind publishes the proxy's ports, but a rootful proxy with rootless app containers uses host networking; bind the ports with a firewall instead",
+		})
+	}
+	return errs
+}
+
+func validateProxyProtocol(cfg *Config) []ValidationError {
+	var errs []ValidationError
+	for i, cidr := range cfg.Proxy.ProxyProtocolAllow {
+		if _, _, err := net.ParseCIDR(strings.TrimSpace(cidr)); err != nil {
+			errs = append(errs, ValidationError{
+				Field:   fmt.Sprintf("proxy.proxy_protocol_allow[%d]", i),
+				Message: fmt.Sprintf("invalid CIDR range %q (e.g. 10.0.0.0/8, 203.0.113.5/32)", cidr),
+			})
+		}
+	}
+	switch {
+	case !cfg.Proxy.ProxyProtocol:
+		errs = append(errs, ValidationError{
+			Field:    "proxy.proxy_protocol_allow",
+			Message:  "has no effect without proxy.proxy_protocol",
+			Severity: SeverityWarning,
+		})
+	case len(cfg.Proxy.ProxyProtocolAllow) == 0:
+		errs = append(errs, ValidationError{
+			Field:    "proxy.proxy_protocol",
+			Message:  "any client reaching the proxy directly can forge its address; list the load balancers in proxy.proxy_protocol_allow or restrict access with proxy.bind or a firewall",
+			Severity: SeverityWarning,
+		})
+	}
+	return errs
+}
+
+func validateGeoIP(cfg *Config) []ValidationError {
+	var errs []ValidationError
+	geo := cfg.Proxy.GeoIP
+	countries := len(cfg.Proxy.AllowCountries) > 0 || len(cfg.Proxy.DenyCountries) > 0
+	for _, test := range cfg.Proxy.ABTests {
+		countries = countries || len(test.Countries) > 0
+	}
+	if !geo.Enabled() {
+		if countries {
+			errs = append(errs, ValidationError{
+				Field:   "proxy.geoip",
+				Message: "country matching needs a GeoIP database: set geoip.database or geoip.license_key",
+			})
+		}
+		return errs
+	}
+
+	if !cfg.Proxy.CustomImage() {
+		errs = append(errs, ValidationError{
+			Field:   "proxy.image",
+			Message: "proxy.geoip requires a Caddy image built with the caddy-maxmind-geolocation module",
+		})
+	}
+	if geo.Database != "" && (!path.IsAbs(geo.Database) || path.Clean(geo.Database) != geo.Database || path.Dir(geo.Database) == "/") {
+		errs = append(errs, ValidationError{
+			Field:   "proxy.geoip.database",
+			Message: "database must be a clean absolute path below a directory other than / (e.g. /var/lib/GeoIP/GeoLite2-Country.mmdb)",
+		})
+	}
+	if geo.Edition != "" && !geoIPEditionRegex.MatchString(geo.Edition) {
+		errs = append(errs, ValidationError{
+			Field:   "proxy.geoip.edition",
+			Message: fmt.Sprintf("invalid MaxMind edition %q", geo.Edition),
+		})
+	}
+	if geo.LicenseKey != "" {
+		if _, err := strconv.ParseUint(geo.AccountID, 10, 64); err != nil {
+			errs = append(errs, ValidationError{
+				Field:   "proxy.geoip.account_id",
+				Message: "account_id must be the numeric MaxMind account ID when license_key is set",
+			})
+		}
+	} else if geo.AccountID != "" {
+		errs = append(errs, ValidationError{
+			Field:    "proxy.geoip.account_id",
+			Message:  "has no effect without geoip.license_key",
+			Severity: SeverityWarning,
+		})
+	}
+
+	if len(cfg.Proxy.AllowCountries) > 0 && len(cfg.Proxy.DenyCountries) > 0 {
+		errs = append(errs, ValidationError{
+			Field:   "proxy.allow_countries",
+			Message: "set either allow_countries or deny_countries, not both",
+		})
+	}
+	errs = append(errs, validateCountryCodes("proxy.allow_countries", cfg.Proxy.AllowCountries)...)
+	errs = append(errs, validateCountryCodes("proxy.deny_countries", cfg.Proxy.DenyCountries)...)
+	for i, test := range cfg.Proxy.ABTests {
+		errs = append(errs, validateCountryCodes(fmt.Sprintf("proxy.ab_tests[%d].countries", i), test.Countries)...)
+	}
+	return errs
+}
+
+func validateProxyImageBuild(cfg *Config) []ValidationError {
+	var errs []ValidationError
+	build := cfg.Proxy.ImageBuild
+	if !build.Enabled() {
+		return append(errs, ValidationError{
+			Field:    "proxy.image_build.repository",
+			Message:  "has no effect without image_build.modules",
+			Severity: SeverityWarning,
+		})
+	}
+	if strings.TrimSpace(cfg.Proxy.Image) != "" {
+		errs = append(errs, ValidationError{
+			Field:   "proxy.image_build",
+			Message: "set either proxy.image or proxy.image_build, not both",
+		})
+	}
+	for i, module := range build.Modules {
+		if !caddyModuleRegex.MatchString(module) {
+			errs = append(errs, ValidationError{
+				Field:   fmt.Sprintf("proxy.image_build.modules[%d]", i),
+				Message: fmt.Sprintf("invalid Go module %q: use a module path with an optional @version (e.g. github.com/mholt/caddy-l4)", module),
+			})
+		}
+	}
+	repository := cfg.ProxyImageRepository()
+	if !isValidImageRef(repository) || imageRepository(repository) != repository {
+		errs = append(errs, ValidationError{
+			Field:   "proxy.image_build.repository",
+			Message: fmt.Sprintf("invalid repository %q: use an image reference without tag or digest", repository),
+		})
+	}
+	return errs
+}
+
+func validateCountryCodes(field string, codes []string) []ValidationError {
+	var errs []ValidationError
+	for i, code := range codes {
+		if !countryCodeRegex.MatchString(code) {
+			errs = append(errs, ValidationError{
+				Field:   fmt.Sprintf("%s[%d]", field, i),
+				Message: fmt.Sprintf("invalid country code %q: use an uppercase ISO 3166-1 alpha-2 code such as DE", code),
+			})
+		}
+	}
+	return errs
+}
+
+func validateStreams(cfg *Config) []ValidationError {
+	var errs []ValidationError
+	if !cfg.Proxy.CustomImage() {
+		errs = append(errs, ValidationError{
+			Field:   "proxy.image",
+			Message: "proxy.streams require a Caddy image built with the caddy-l4 module",
+		})
+	}
+	switch {
+	case cfg.UsesAppSocket():
+		errs = append(errs, ValidationError{
+			Field:   "proxy.streams",
+			Message: "streams dial app container ports and cannot be combined with proxy.app_socket",
+		})
+	case cfg.UsesProxyTier():
+		errs = append(errs, ValidationError{
+			Field:   "proxy.streams",
+			Message: "streams are not supported with proxy.hosts_role",
+		})
+	case cfg.UseHostPortUpstreams():
+		errs = append(errs, ValidationError{
+			Field:   "proxy.streams",
+			Message: "streams are not supported when a rootful proxy reaches rootless app containers over host ports",
+		})
+	}
+
+	names := make(map[string]bool)
+	listeners := map[string]bool{
+		fmt.Sprintf("tcp/%d", cfg.Proxy.EffectiveHTTPPort()):  true,
+		fmt.Sprintf("tcp/%d", cfg.Proxy.EffectiveHTTPSPort()): true,
+		fmt.Sprintf("udp/%d", cfg.Proxy.EffectiveHTTPSPort()): true,
+	}
+	for i, stream := range cfg.Proxy.Streams {
+		field := fmt.Sprintf("proxy.streams[%d]", i)
+		if !resourceNameRegex.MatchString(stream.Name) {
+			errs = append(errs, ValidationError{
+				Field:   field + ".name",
+				Message: "stream name must start with a letter and contain only alphanumeric characters, underscores, hyphens, and dots (max 63 chars)",
+			})
+		} else if names[stream.Name] {
+			errs = append(errs, ValidationError{
+				Field:   field + ".name",
+				Message: fmt.Sprintf("duplicate stream name %q", stream.Name),
+			})
+		}
+		names[stream.Name] = true
+
+		if stream.Protocol != "tcp" && stream.Protocol != "udp" {
+			errs = append(errs, ValidationError{
+				Field:   field + ".protocol",
+				Message: "protocol must be tcp or udp",
+			})
+		}
+		if stream.Listen < 1 || stream.Listen > 65535 {
+			errs = append(errs, ValidationError{
+				Field:   field + ".listen",
+				Message: "listen must be between 1 and 65535",
+			})
+		} else {
+			listener := fmt.Sprintf("%s/%d", stream.Protocol, stream.Listen)
+			if listeners[listener] {
+				errs = append(errs, ValidationError{
+					Field:   field + ".listen",
+					Message: fmt.Sprintf("%s port %d is already used by the proxy", stream.Protocol, stream.Listen),
+				})
+			}
+			listeners[listener] = true
+		}
+		if stream.Port < 1 || stream.Port > 65535 {
+			errs = append(errs, ValidationError{
+				Field:   field + ".port",
+				Message: "port must be between 1 and 65535",
+			})
+		}
+	}
+	return errs
+}
+
+// parseTimeout parses an optional duration setting. Unset and unparsable
+// values report false; the latter are rejected by the format checks.
+func parseTimeout(value string) (time.Duration, bool) {
+	if value == "" {
+		return 0, false
+	}
+	d, err := time.ParseDuration(value)
+	return d, err == nil
+}
+
+// validateTimeoutHierarchy rejects timeouts that cannot take effect because
+// an enclosing timeout always expires first.
+func validateTimeoutHierarchy(cfg *Config) []ValidationError {
+	var errs []ValidationError
+	if header, ok := parseTimeout(cfg.Proxy.ResponseHeaderTimeout); ok {
+		if response, ok := parseTimeout(cfg.Proxy.ResponseTimeout); ok && response > 0 && header > response {
+			errs = append(errs, ValidationError{
+				Field:   "proxy.response_header_timeout",
+				Message: fmt.Sprintf("response_header_timeout (%s) must not exceed response_timeout (%s), which bounds the whole response", header, response),
+			})
+		}
+	}
+	if cfg.Deploy.DeployTimeout > 0 && cfg.Deploy.DrainTimeout > cfg.Deploy.DeployTimeout {
+		errs = append(errs, ValidationError{
+			Field:   "deploy.drain_timeout",
+			Message: fmt.Sprintf("drain_timeout (%s) must not exceed deploy_timeout (%s)", cfg.Deploy.DrainTimeout, cfg.Deploy.DeployTimeout),
+		})
+	}
+	return errs
+}
+
+func validateABTests(cfg *Config) []ValidationError {
+	var errs []ValidationError
+	if cfg.UsesProxyTier() {
+		errs = append(errs, ValidationError{
+			Field:   "proxy.ab_tests",
+			Message: "ab_tests route to the canary, which is not supported with proxy.hosts_role",
+		})
+	}
+
+	names := make(map[string]bool)
+	for i, test := range cfg.Proxy.ABTests {
+		field := fmt.Sprintf("proxy.ab_tests[%d]", i)
+		if !resourceNameRegex.MatchString(test.Name) {
+			errs = append(errs, ValidationError{
+				Field:   field + ".name",
+				Message: "test name must start with a letter and contain only alphanumeric characters, underscores, hyphens, and dots (max 63 chars)",
+			})
+		} else if names[test.Name] {
+			errs = append(errs, ValidationError{
+				Field:   field + ".name",
+				Message: fmt.Sprintf("duplicate test name %q", test.Name),
+			})
+		}
+		names[test.Name] = true
+
+		selectors := 0
+		for _, set := range []bool{test.Header != "", test.Cookie != "", len(test.Countries) > 0} {
+			if set {
+				selectors++
+			}
+		}
+		switch {
+		case selectors != 1:
+			errs = append(errs, ValidationError{
+				Field:   field,
+				Message: "set exactly one of header, cookie, or countries",
+			})
+		case test.Header != "" && !isValidHeaderName(test.Header):
+			errs = append(errs, ValidationError{
+				Field:   field + ".header",
+				Message: fmt.Sprintf("invalid header name %q", test.Header),
+			})
+		case test.Cookie != "" && !isValidHeaderName(test.Cookie):
+			errs = append(errs, ValidationError{
+				Field:   field + ".cookie",
+				Message: fmt.Sprintf("invalid cookie name %q", test.Cookie),
+			})
+		}
+
+		switch {
+		case len(test.Countries) > 0:
+			if test.Value != "" {
+				errs = append(errs, ValidationError{
+					Field:   field + ".value",
+					Message: "value applies to header and cookie tests only",
+				})
+			}
+		case test.Value == "":
+			errs = append(errs, ValidationError{
+				Field:   field + ".value",
+				Message: "value is required",
+			})
+		case test.Cookie != "" && strings.ContainsAny(test.Value, "; \t\",\\"):
+			errs = append(errs, ValidationError{
+				Field:   field + ".value",
+				Message: "cookie value must not contain whitespace, quotes, commas, semicolons, or backslashes",
+			})
+		}
+	}
+	return errs
+}
+
+// validateProxyTier checks a dedicated load-balancer role configuration. The
+// tier role only runs Caddy, so it must exist, must not be the web role, and
+// private addresses must belong to hosts that actually serve the app.
+func validateProxyTier(cfg *Config) []ValidationError {
+	var errs []ValidationError
+	role := cfg.Proxy.HostsRole
+	if _, ok := cfg.Servers[role]; !ok {
+		return append(errs, ValidationError{
+			Field:   "proxy.hosts_role",
+			Message: fmt.Sprintf("role %q is not defined in servers", role),
+		})
+	}
+	if role == "web" {
+		errs = append(errs, ValidationError{
+			Field:   "proxy.hosts_role",
+			Message: "hosts_role must name a dedicated load-balancer role, not the web role",
+		})
+	}
+	if len(cfg.GetRoleHosts("web")) == 0 {
+		errs = append(errs, ValidationError{
+			Field:   "proxy.hosts_role",
+			Message: "a load-balancer tier requires a web role with at least one host",
+		})
+	}
+	if cfg.Deploy.Canary.Enabled {
+		errs = append(errs, ValidationError{
+			Field:   "deploy.canary.enabled",
+			Message: "canary deployments are not supported with proxy.hosts_role",
+		})
+	}
+
+	webHosts := make(map[string]bool)
+	for _, host := range cfg.GetRoleHosts("web") {
+		webHosts[host] = true
+	}
+	for _, host := range cfg.GetProxyHosts() {
+		webHosts[host] = true
+	}
+	hosts := make([]string, 0, len(cfg.Proxy.PrivateAddresses))
+	for host := range cfg.Proxy.PrivateAddresses {
+		hosts = append(hosts, host)
+	}
+	sort.Strings(hosts)
+	for _, host := range hosts {
+		field := fmt.Sprintf("proxy.private_addresses.%s", host)
+		if !webHosts[host] {
+			errs = append(errs, ValidationError{
+				Field:   field,
+				Message: fmt.Sprintf("host %s is not a web role host or load-balancer host", host),
+			})
+		}
+		if addr := cfg.Proxy.PrivateAddresses[host]; !isValidHost(addr) {
+			errs = append(errs, ValidationError{
+				Field:   field,
+				Message: fmt.Sprintf("invalid private address: %s", addr),
+			})
+		}
+	}
+	return errs
+}
+
+// portBinding is a published host port: an optional bind address, the port,
+// and its protocol.
+type portBinding struct {
+	ip       string
+	port     int
+	protocol string
+}
+
+// conflicts reports whether b and other cannot both be bound on one host.
+func (b portBinding) conflicts(other portBinding) bool {
+	if b.port != other.port || b.protocol != other.protocol {
+		return false
+	}
+	return b.ip == "" || other.ip == "" || b.ip == other.ip
+}
+
+// parsePortBinding parses the host side of an accessory port mapping
+// (host:container or ip:host:container, with an optional /tcp or /udp
+// suffix). Mappings without a single fixed host port report false.
+func parsePortBinding(spec string) (portBinding, bool) {
+	binding := portBinding{protocol: "tcp"}
+	if mapping, protocol, ok := strings.Cut(spec, "/"); ok {
+		spec, binding.protocol = mapping, strings.ToLower(protocol)
+	}
+	parts := strings.Split(spec, ":")
+	if len(parts) < 2 {
+		return binding, false
+	}
+	port, err := strconv.Atoi(parts[len(parts)-2])
+	if err != nil || port < 1 || port > 65535 {
+		return binding, false
+	}
+	binding.port = port
+	binding.ip = strings.Trim(strings.Join(parts[:len(parts)-2], ":"), "[]")
+	if binding.ip == "0.0.0.0" || binding.ip == "::" {
+		binding.ip = ""
+	}
+	return binding, true
+}
+
+// validateVolumes checks that each volumes entry parses and names its
+// mount options correctly.
+func validateVolumes(field string, specs []string) []ValidationError {
+	var errs []ValidationError
+	for i, spec := range specs {
+		if _, err := ParseVolume(spec); err != nil {
+			errs = append(errs, ValidationError{
+				Field:   fmt.Sprintf("%s[%d]", field, i),
+				Message: fmt.Sprintf("invalid volume %q: %v", spec, err),
+			})
+		}
+	}
+	return errs
+}
+
+// validateRuntimeOptions checks the user and stop settings of a role or
+// accessory.
+func validateRuntimeOptions(field, user, stopSignal string, stopTimeout time.Duration) []ValidationError {
+	var errs []ValidationError
+	if user != "" && !containerUserRegex.MatchString(user) {
+		errs = append(errs, ValidationError{
+			Field:   field + ".user",
+			Message: fmt.Sprintf("invalid user %q: use user, user:group, or uid:gid", user),
+		})
+	}
+	if stopSignal != "" && !stopSignalRegex.MatchString(stopSignal) {
+		errs = append(errs, ValidationError{
+			Field:   field + ".stop_signal",
+			Message: fmt.Sprintf("invalid stop signal %q: use a name such as SIGQUIT or a number", stopSignal),
+		})
+	}
+	if stopTimeout < 0 || stopTimeout > 0 && stopTimeout < time.Second {
+		errs = append(errs, ValidationError{
+			Field:   field + ".stop_timeout",
+			Message: "stop_timeout must be at least 1s",
+		})
+	}
+	return errs
+}
+
+// validateAccessoryHealthcheck checks the readiness check of an accessory.
+func validateAccessoryHealthcheck(name string, h AccessoryHealthcheck) []ValidationError {
+	var errs []ValidationError
+	field := fmt.Sprintf("accessories.%s.healthcheck", name)
+
+	if h.Cmd != "" && h.Port != 0 {
+		errs = append(errs, ValidationError{
+			Field:   field,
+			Message: "set either cmd or port, not both",
+		})
+	}
+	if h.Port < 0 || h.Port > 65535 {
+		errs = append(errs, ValidationError{
+			Field:   field + ".port",
+			Message: fmt.Sprintf("invalid port %d", h.Port),
+		})
+	}
+	if !h.IsSet() && (h.Interval != 0 || h.Timeout != 0) {
+		errs = append(errs, ValidationError{
+			Field:   field,
+			Message: "interval and timeout need a cmd or port",
+		})
+	}
+	if h.Interval < 0 || h.Interval > 0 && h.Interval < time.Second {
+		errs = append(errs, ValidationError{
+			Field:   field + ".interval",
+			Message: "interval must be at least 1s",
+		})
+	}
+	if h.Timeout < 0 || h.Timeout > 0 && h.Timeout < time.Second {
+		errs = append(errs, ValidationError{
+			Field:   field + ".timeout",
+			Message: "timeout must be at least 1s",
+		})
+	}
+	return errs
+}
+
+// validateAccessoryProfile checks the profile and backup of an accessory.
+func validateAccessoryProfile(name string, acc AccessoryConfig) []ValidationError {
+	var errs []ValidationError
+	field := fmt.Sprintf("accessories.%s", name)
+
+	if acc.Profile != "" && acc.Profile != AccessoryProfileStandard {
+		errs = append(errs, ValidationError{
+			Field:   field + ".profile",
+			Message: fmt.Sprintf("unknown accessory profile %q (expected %s)", acc.Profile, AccessoryProfileStandard),
+		})
+	}
+	if acc.Backup == (AccessoryBackupConfig{}) {
+		return errs
+	}
+	if acc.Profile != AccessoryProfileStandard {
+		errs = append(errs, ValidationError{
+			Field:   field + ".backup",
+			Message: "backup needs profile: standard",
+		})
+	}
+	if !strings.HasPrefix(acc.Backup.S3, "s3://") || len(acc.Backup.S3) <= len("s3://") {
+		errs = append(errs, ValidationError{
+			Field:   field + ".backup.s3",
+			Message: "s3 must be a bucket URL such as s3://backups/app/wal",
+		})
+	}
+	if acc.Backup.Interval < 0 || acc.Backup.Interval > 0 && acc.Backup.Interval < time.Second {
+		errs = append(errs, ValidationError{
+			Field:   field + ".backup.interval",
+			Message: "interval must be at least 1s",
+		})
+	}
+	if acc.Backup.Image != "" && !isValidImageRef(acc.Backup.Image) {
+		errs = append(errs, ValidationError{
+			Field:   field + ".backup.image",
+			Message: fmt.Sprintf("invalid image reference: %s", acc.Backup.Image),
+		})
+	}
+	return errs
+}
+
+// validateAccessoryCache checks the cache wiring of an accessory.
+func validateAccessoryCache(name string, acc AccessoryConfig) []ValidationError {
+	var errs []ValidationError
+	field := fmt.Sprintf("accessories.%s.cache", name)
+	cache := acc.Cache
+
+	if cache == (AccessoryCacheConfig{}) {
+		return nil
+	}
+	switch cache.Engine {
+	case CacheEngineRedis, CacheEngineMemcached:
+	case "":
+		errs = append(errs, ValidationError{
+			Field:   field + ".engine",
+			Message: fmt.Sprintf("engine is required (expected %s or %s)", CacheEngineRedis, CacheEngineMemcached),
+		})
+	default:
+		errs = append(errs, ValidationError{
+			Field:   field + ".engine",
+			Message: fmt.Sprintf("unknown cache engine %q (expected %s or %s)", cache.Engine, CacheEngineRedis, CacheEngineMemcached),
+		})
+	}
+	if acc.Profile != "" {
+		errs = append(errs, ValidationError{
+			Field:   field,
+			Message: "cache cannot be combined with profile",
+		})
+	}
+	return errs
+}
+
+// validateDNS checks the dns block: the proxy hostnames must lie in the
+// zone it manages.
+func validateDNS(cfg *Config) []ValidationError {
+	d := cfg.DNS
+	if !d.Enabled() {
+		if d.Zone != "" || d.TokenSecret != "" || d.TTL != 0 || len(d.Targets) > 0 {
+			return []ValidationError{{Field: "dns.provider", Message: "provider is required"}}
+		}
+		return nil
+	}
+
+	var errs []ValidationError
+	if d.Provider != DNSProviderCloudflare {
+		errs = append(errs, ValidationError{
+			Field:   "dns.provider",
+			Message: fmt.Sprintf("unknown DNS provider %q (expected %s)", d.Provider, DNSProviderCloudflare),
+		})
+	}
+	zone := strings.TrimSuffix(strings.ToLower(d.Zone), ".")
+	if zone == "" {
+		errs = append(errs, ValidationError{Field: "dns.zone", Message: "zone is required"})
+	} else {
+		for _, host := range cfg.Proxy.AllHosts() {
+			host = strings.TrimSuffix(strings.ToLower(host), ".")
+			if host != zone && !strings.HasSuffix(host, "."+zone) {
+				errs = append(errs, ValidationError{
+					Field:   "dns.zone",
+					Message: fmt.Sprintf("proxy host %s is not in zone %s", host, zone),
+				})
+			}
+		}
+	}
+	if !secretNameRegex.MatchString(d.TokenSecret) {
+		errs = append(errs, ValidationError{
+			Field:   "dns.token_secret",
+			Message: "token_secret must name the secret holding the API token",
+		})
+	}
+	if d.TTL != 0 && (d.TTL < 60 || d.TTL > 86400) {
+		errs = append(errs, ValidationError{
+			Field:   "dns.ttl",
+			Message: "ttl must be between 60 and 86400 seconds",
+		})
+	}
+	for i, target := range d.Targets {
+		if net.ParseIP(target) == nil {
+			errs = append(errs, ValidationError{
+				Field:   fmt.Sprintf("dns.targets[%d]", i),
+				Message: fmt.Sprintf("invalid IP address %q", target),
+			})
+		}
+	}
+	return errs
+}
+
+// validateHardening checks the hardening options of a server role.
+func validateHardening(role string, h HardeningConfig) []ValidationError {
+	var errs []ValidationError
+	field := fmt.Sprintf("servers.%s.hardening", role)
+
+	if h.Preset != "" && h.Preset != HardeningPresetStrict {
+		errs = append(errs, ValidationError{
+			Field:   field + ".preset",
+			Message: fmt.Sprintf("unknown hardening preset %q (expected %s)", h.Preset, HardeningPresetStrict),
+		})
+	}
+	for i, mount := range h.Tmpfs {
+		if !tmpfsMountRegex.MatchString(mount) {
+			errs = append(errs, ValidationError{
+				Field:   fmt.Sprintf("%s.tmpfs[%d]", field, i),
+				Message: fmt.Sprintf("invalid tmpfs mount %q: use an absolute path with optional :options", mount),
+			})
+		}
+	}
+	for i, capability := range h.CapDrop {
+		if !capabilityRegex.MatchString(capability) {
+			errs = append(errs, ValidationError{
+				Field:   fmt.Sprintf("%s.cap_drop[%d]", field, i),
+				Message: fmt.Sprintf("invalid capability %q", capability),
+			})
+		}
+	}
+	if profile := h.SeccompProfile; profile != "" && (!strings.HasPrefix(profile, "/") || !remotePathRegex.MatchString(profile)) {
+		errs = append(errs, ValidationError{
+			Field:   field + ".seccomp_profile",
+			Message: "seccomp_profile must be an absolute path on the host",
+		})
+	}
+	return errs
+}
+
+// validateCrossField checks settings that are valid on their own but
+// conflict with each other once the containers are placed on hosts.
+func validateCrossField(cfg *Config) []ValidationError {
+	var errs []ValidationError
+	rootless := cfg.Podman.Rootless && !cfg.Proxy.Rootful
+
+	// proxy.host may repeat in proxy.hosts; any other repeat is a mistake.
+	seen := make(map[string]string)
+	if cfg.Proxy.Host != "" {
+		seen[strings.ToLower(cfg.Proxy.Host)] = cfg.Proxy.Host
+	}
+	for i, host := range cfg.Proxy.Hosts {
+		first, ok := seen[strings.ToLower(host)]
+		switch {
+		case !ok:
+			seen[strings.ToLower(host)] = host
+		case first != host:
+			errs = append(errs, ValidationError{
+				Field:    fmt.Sprintf("proxy.hosts[%d]", i),
+				Message:  fmt.Sprintf("%s duplicates %s; host names are case-insensitive", host, first),
+				Severity: SeverityWarning,
+			})
+		case slices.Index(cfg.Proxy.Hosts, host) < i:
+			errs = append(errs, ValidationError{
+				Field:    fmt.Sprintf("proxy.hosts[%d]", i),
+				Message:  fmt.Sprintf("duplicate host %s", host),
+				Severity: SeverityWarning,
+			})
+		}
+	}
+
+	if cfg.Proxy.SSL && (cfg.Proxy.SSLCertificate == "" || cfg.Proxy.SSLPrivateKey == "") &&
+		cfg.Proxy.EffectiveHTTPPort() != DefaultHTTPPort && cfg.Proxy.EffectiveHTTPSPort() != DefaultHTTPSPort {
+		errs = append(errs, ValidationError{
+			Field: "proxy.ssl",
+			Message: fmt.Sprintf("ACME challenges arrive on ports 80 and 443 but the proxy listens on %d and %d; forward those ports to the proxy or provide ssl_certificate and ssl_private_key",
+				cfg.Proxy.EffectiveHTTPPort(), cfg.Proxy.EffectiveHTTPSPort()),
+			Severity: SeverityWarning,
+		})
+	}
+
+	if rootless {
+		for i, stream := range cfg.Proxy.Streams {
+			if stream.Listen > 0 && stream.Listen < 1024 {
+				errs = append(errs, ValidationError{
+					Field:   fmt.Sprintf("proxy.streams[%d].listen", i),
+					Message: "rootless Podman cannot bind privileged ports (<1024); use a listen port >= 1024, enable proxy.rootful, or disable podman.rootless",
+				})
+			}
+		}
+	}
+
+	// Host ports the proxy binds on each of its hosts.
+	proxyBindings := func(host string) []portBinding {
+		ips := cfg.ProxyBindAddresses(host)
+		if len(ips) == 0 {
+			ips = []string{""}
+		}
+		var bindings []portBinding
+		for _, ip := range ips {
+			if ip == "0.0.0.0" || ip == "::" {
+				ip = ""
+			}
+			bindings = append(bindings,
+				portBinding{ip: ip, port: cfg.Proxy.EffectiveHTTPPort(), protocol: "tcp"},
+				portBinding{ip: ip, port: cfg.Proxy.EffectiveHTTPSPort(), protocol: "tcp"},
+			)
+			for _, stream := range cfg.Proxy.Streams {
+				bindings = append(bindings, portBinding{ip: ip, port: stream.Listen, protocol: stream.Protocol})
+			}
+		}
+		return bindings
+	}
+	proxyHosts := make(map[string]bool)
+	for _, host := range cfg.GetProxyHosts() {
+		proxyHosts[host] = true
+	}
+
+	type placedBinding struct {
+		accessory string
+		binding   portBinding
+	}
+	bound := make(map[string][]placedBinding)
+	for _, name := range cfg.GetAccessoryNames() {
+		acc := cfg.Accessories[name]
+		field := fmt.Sprintf("accessories.%s.port", name)
+		binding, ok := parsePortBinding(acc.Port)
+		if !ok {
+			continue
+		}
+		if cfg.Podman.Rootless && binding.port < 1024 {
+			errs = append(errs, ValidationError{
+				Field:   field,
+				Message: fmt.Sprintf("rootless Podman cannot publish privileged port %d (<1024); use a host port >= 1024 or disable podman.rootless", binding.port),
+			})
+		}
+
+		hosts := acc.Hosts
+		if acc.Host != "" && !slices.Contains(hosts, acc.Host) {
+			hosts = append([]string{acc.Host}, hosts...)
+		}
+		reported := false
+		for _, host := range hosts {
+			if reported {
+				break
+			}
+			if proxyHosts[host] {
+				for _, proxy := range proxyBindings(host) {
+					if binding.conflicts(proxy) {
+						errs = append(errs, ValidationError{
+							Field:   field,
+							Message: fmt.Sprintf("%s port %d is already used by the proxy on %s", binding.protocol, binding.port, host),
+						})
+						reported = true
+						break
+					}
+				}
+			}
+			for _, other := range bound[host] {
+				if !reported && binding.conflicts(other.binding) {
+					errs = append(errs, ValidationError{
+						Field:   field,
+						Message: fmt.Sprintf("%s port %d is already published by accessory %s on %s", binding.protocol, binding.port, other.accessory, host),
+					})
+					reported = true
+				}
+			}
+			bound[host] = append(bound[host], placedBinding{accessory: name, binding: binding})
+		}
+	}
+	return errs
+}
+
+// envLayer is one configured source of a container's environment, in the
+// order the layers are applied.
+type envLayer struct {
+	field string
+	env   map[string]string
+}
+
+// validateEnvReferences checks the ${NAME} references in the environment of
+// every container: references must not form a cycle, and secrets can only be
+// referenced when they are read from an env file.
+func validateEnvReferences(cfg *Config) []ValidationError {
+	var errs []ValidationError
+	reported := make(map[string]bool)
+	check := func(secrets []string, layers ...envLayer) {
+		env := make(map[string]string)
+		fields := make(map[string]string)
+		for _, layer := range layers {
+			for key, value := range layer.env {
+				env[key] = value
+				fields[key] = layer.field + "." + key
+			}
+		}
+		resolved, templates := ResolveEnv(env, secrets)
+		for _, key := range slices.Sorted(maps.Keys(env)) {
+			var message string
+			if _, ok := templates[key]; ok && cfg.UsesPodmanSecrets() {
+				message = "references a secret, which secrets_storage=podman cannot compose into a value; use env_file or encrypted"
+			}
+			for _, name := range EnvReferences(resolved[key]) {
+				if _, ok := resolved[name]; ok {
+					message = fmt.Sprintf("reference to %s is part of a cycle", name)
+					break
+				}
+			}
+			if message == "" || reported[fields[key]] {
+				continue
+			}
+			reported[fields[key]] = true
+			errs = append(errs, ValidationError{Field: fields[key], Message: message})
+		}
+	}
+
+	shared := envLayer{field: "env.clear", env: cfg.Env.Clear}
+	check(cfg.Env.Secret, shared)
+	for _, tag := range slices.Sorted(maps.Keys(cfg.Env.Tags)) {
+		check(cfg.Env.Secret, envLayer{field: "env.tags." + tag, env: cfg.Env.Tags[tag]}, shared)
+	}
+	for _, role := range cfg.GetRoles() {
+		check(cfg.RoleSecrets(role), shared, envLayer{field: fmt.Sprintf("servers.%s.env", role), env: cfg.Servers[role].Env})
+	}
+	for _, name := range cfg.GetAccessoryNames() {
+		accessory := cfg.Accessories[name]
+		check(accessory.Env.Secret, envLayer{field: fmt.Sprintf("accessories.%s.env.clear", name), env: accessory.Env.Clear})
+	}
+	for _, name := range slices.Sorted(maps.Keys(cfg.Cron)) {
+		check(cfg.Env.Secret, shared, envLayer{field: fmt.Sprintf("cron.%s.env", name), env: cfg.Cron[name].Env})
+	}
+	return errs
+}
+
+// validateEnvFiles reports variables read from env.files that are also
+// declared as secrets, which would otherwise be shadowed silently.
+func validateEnvFiles(cfg *Config) []ValidationError {
+	secrets := make(map[string]bool)
+	for _, name := range cfg.Env.Secret {
+		secrets[name] = true
+	}
+	for _, role := range cfg.GetRoles() {
+		for _, name := range cfg.Servers[role].Secrets {
+			secrets[name] = true
+		}
+	}
+
+	var errs []ValidationError
+	for _, key := range slices.Sorted(maps.Keys(cfg.envFileKeys)) {
+		if !secrets[key] {
+			continue
+		}
+		i := cfg.envFileKeys[key]
+		errs = append(errs, ValidationError{
+			Field:   fmt.Sprintf("env.files[%d]", i),
+			Message: fmt.Sprintf("%s in %s is also declared as a secret; keep it in one place", key, cfg.Env.Files[i]),
+		})
+	}
+	return errs
+}
+
+// validateHostTags checks host_tags and the env.tags blocks they select.
+func validateHostTags(cfg *Config) []ValidationError {
+	var errs []ValidationError
+	appHosts := cfg.GetAllHosts()
+	for _, host := range slices.Sorted(maps.Keys(cfg.HostTags)) {
+		field := "host_tags." + host
+		if !slices.Contains(appHosts, host) {
+			errs = append(errs, ValidationError{Field: field, Message: fmt.Sprintf("host %s is not in any server role", host)})
+		}
+		for i, tag := range cfg.HostTags[host] {
+			if !hostTagRegex.MatchString(tag) {
+				errs = append(errs, ValidationError{
+					Field:   fmt.Sprintf("%s[%d]", field, i),
+					Message: fmt.Sprintf("invalid tag %q: use a name or key=value of letters, digits, '.', '_', or '-'", tag),
+				})
+			}
+		}
+	}
+	for _, tag := range slices.Sorted(maps.Keys(cfg.Env.Tags)) {
+		if !hostTagRegex.MatchString(tag) {
+			errs = append(errs, ValidationError{
+				Field:   "env.tags." + tag,
+				Message: fmt.Sprintf("invalid tag %q: use a name or key=value of letters, digits, '.', '_', or '-'", tag),
+			})
+		}
+	}
+	return errs
+}
@@ -1,8 +1,12 @@
 package config
 
 import (
+	"errors"
+	"reflect"
+	"slices"
 	"strings"
 	"testing"
+	"time"
 )
 
 func TestValidate_RequiredFields(t *testing.T) {
@@ -90,7 +94,7 @@ func TestValidateRemoteSecretsPath(t *testing.T) {
 func TestSafeExpandEnvPreservesRemoteHome(t *testing.T) {
 	t.Setenv("HOME", "/local/home")
 	t.Setenv("AZUD_TEST_VALUE", "expanded")
-	got := safeExpandEnv(`path: $HOME/.azud/secrets value: ${AZUD_TEST_VALUE}`)
+	got := safeExpandEnv(`path: $HOME/.azud/secrets value: ${AZUD_TEST_VALUE}`, nil)
 	if got != `path: $HOME/.azud/secrets value: expanded` {
 		t.Fatalf("safeExpandEnv = %q", got)
 	}
@@ -285,6 +289,881 @@ func TestValidate_CanaryConfig(t *testing.T) {
 	}
 }
 
+func TestValidate_HaltOnErrorRate(t *testing.T) {
+	tests := []struct {
+		name    string
+		gate    ErrorRateGateConfig
+		logging bool
+		errMsg  string
+	}{
+		{name: "disabled", gate: ErrorRateGateConfig{}},
+		{name: "valid gate", gate: ErrorRateGateConfig{Threshold: 5, Window: 30 * time.Second}, logging: true},
+		{name: "threshold above 100", gate: ErrorRateGateConfig{Threshold: 150}, logging: true, errMsg: "threshold"},
+		{name: "negative min requests", gate: ErrorRateGateConfig{Threshold: 5, MinRequests: -1}, logging: true, errMsg: "min_requests"},
+		{name: "access logging disabled", gate: ErrorRateGateConfig{Threshold: 5}, errMsg: "proxy.logging.enabled"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &Config{
+				Service: "test",
+				Image:   "test:latest",
+				Servers: map[string]RoleConfig{
+					"web": {Hosts: []string{"localhost"}},
+				},
+				Proxy: ProxyConfig{
+					Host:    "test.example.com",
+					Logging: LoggingConfig{Enabled: tt.logging},
+				},
+				Deploy: DeployConfig{
+					HaltOnErrorRate: tt.gate,
+				},
+				SSH: SSHConfig{Port: 22},
+			}
+
+			err := Validate(cfg)
+			if tt.errMsg == "" {
+				if err != nil {
+					t.Errorf("expected no error, got %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.errMsg) {
+				t.Errorf("expected error containing %q, got %v", tt.errMsg, err)
+			}
+		})
+	}
+}
+
+func TestValidate_RestartLoop(t *testing.T) {
+	tests := []struct {
+		name        string
+		restartLoop RestartLoopConfig
+		errMsg      string
+	}{
+		{name: "defaults", restartLoop: RestartLoopConfig{}},
+		{name: "with rollback", restartLoop: RestartLoopConfig{Threshold: 5, Window: 5 * time.Minute, RollbackWithin: 15 * time.Minute}},
+		{name: "negative threshold", restartLoop: RestartLoopConfig{Threshold: -1}, errMsg: "deploy.restart_loop.threshold"},
+		{name: "negative rollback_within", restartLoop: RestartLoopConfig{RollbackWithin: -time.Minute}, errMsg: "deploy.restart_loop.rollback_within"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &Config{
+				Service: "test",
+				Image:   "test:latest",
+				Servers: map[string]RoleConfig{
+					"web": {Hosts: []string{"localhost"}},
+				},
+				Proxy:  ProxyConfig{Host: "test.example.com"},
+				Deploy: DeployConfig{RestartLoop: tt.restartLoop},
+				SSH:    SSHConfig{Port: 22},
+			}
+
+			err := Validate(cfg)
+			if tt.errMsg == "" {
+				if err != nil {
+					t.Errorf("expected no error, got %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.errMsg) {
+				t.Errorf("expected error containing %q, got %v", tt.errMsg, err)
+			}
+		})
+	}
+}
+
+func TestValidate_AutoDeploy(t *testing.T) {
+	tests := []struct {
+		name   string
+		image  string
+		auto   AutoDeployConfig
+		errMsg string
+	}{
+		{name: "watch tag", image: "test:production", auto: AutoDeployConfig{WatchTag: true, Interval: time.Minute}},
+		{name: "negative interval", image: "test:latest", auto: AutoDeployConfig{Interval: -time.Minute}, errMsg: "deploy.auto.interval"},
+		{name: "digest-pinned image", image: "test@sha256:" + strings.Repeat("a", 64), auto: AutoDeployConfig{WatchTag: true}, errMsg: "deploy.auto.watch_tag"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &Config{
+				Service: "test",
+				Image:   tt.image,
+				Servers: map[string]RoleConfig{
+					"web": {Hosts: []string{"localhost"}},
+				},
+				Proxy:  ProxyConfig{Host: "test.example.com"},
+				Deploy: DeployConfig{Auto: tt.auto},
+				SSH:    SSHConfig{Port: 22},
+			}
+
+			err := Validate(cfg)
+			if tt.errMsg == "" {
+				if err != nil {
+					t.Errorf("expected no error, got %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.errMsg) {
+				t.Errorf("expected error containing %q, got %v", tt.errMsg, err)
+			}
+		})
+	}
+}
+
+func TestValidate_ABTests(t *testing.T) {
+	tests := []struct {
+		name    string
+		abTests []ABTestConfig
+		errMsg  string
+	}{
+		{name: "valid", abTests: []ABTestConfig{{Name: "staff", Header: "X-Staff", Value: "1"}, {Name: "beta", Cookie: "beta", Value: "on"}}},
+		{name: "header and cookie", abTests: []ABTestConfig{{Name: "staff", Header: "X-Staff", Cookie: "staff", Value: "1"}}, errMsg: "exactly one of header, cookie, or countries"},
+		{name: "neither header nor cookie", abTests: []ABTestConfig{{Name: "staff", Value: "1"}}, errMsg: "exactly one of header, cookie, or countries"},
+		{name: "missing value", abTests: []ABTestConfig{{Name: "staff", Header: "X-Staff"}}, errMsg: "proxy.ab_tests[0].value"},
+		{name: "invalid header", abTests: []ABTestConfig{{Name: "staff", Header: "X Staff", Value: "1"}}, errMsg: "invalid header name"},
+		{name: "cookie value with separator", abTests: []ABTestConfig{{Name: "beta", Cookie: "beta", Value: "on; admin=1"}}, errMsg: "proxy.ab_tests[0].value"},
+		{name: "duplicate name", abTests: []ABTestConfig{{Name: "staff", Header: "X-Staff", Value: "1"}, {Name: "staff", Cookie: "staff", Value: "1"}}, errMsg: "duplicate test name"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &Config{
+				Service: "test",
+				Image:   "test:latest",
+				Servers: map[string]RoleConfig{
+					"web": {Hosts: []string{"localhost"}},
+				},
+				Proxy: ProxyConfig{Host: "test.example.com", ABTests: tt.abTests},
+				SSH:   SSHConfig{Port: 22},
+			}
+
+			err := Validate(cfg)
+			if tt.errMsg == "" {
+				if err != nil {
+					t.Errorf("expected no error, got %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.errMsg) {
+				t.Errorf("expected error containing %q, got %v", tt.errMsg, err)
+			}
+		})
+	}
+}
+
+func TestCheck_CrossField(t *testing.T) {
+	tests := []struct {
+		name     string
+		mutate   func(*Config)
+		field    string
+		severity Severity
+	}{
+		{name: "valid", mutate: func(c *Config) {
+			c.Accessories = map[string]AccessoryConfig{
+				"db":    {Image: "postgres:16", Host: "10.0.0.2", Port: "5432:5432"},
+				"cache": {Image: "redis:7", Host: "10.0.0.1", Port: "127.0.0.1:6379:6379"},
+			}
+		}},
+		{name: "accessories share a host port", mutate: func(c *Config) {
+			c.Accessories = map[string]AccessoryConfig{
+				"db":      {Image: "postgres:16", Hosts: []string{"10.0.0.2"}, Port: "5432:5432"},
+				"replica": {Image: "postgres:16", Hosts: []string{"10.0.0.3", "10.0.0.2"}, Port: "127.0.0.1:5432:5432"},
+			}
+		}, field: "accessories.replica.port", severity: SeverityError},
+		{name: "accessories on different addresses", mutate: func(c *Config) {
+			c.Accessories = map[string]AccessoryConfig{
+				"db":      {Image: "postgres:16", Host: "10.0.0.2", Port: "10.0.0.2:5432:5432"},
+				"replica": {Image: "postgres:16", Host: "10.0.0.2", Port: "127.0.0.1:5432:5432"},
+			}
+		}},
+		{name: "unknown team default role", mutate: func(c *Config) {
+			c.Team.DefaultRole = "admin"
+		}, field: "team.default_role", severity: SeverityError},
+		{name: "unknown team role", mutate: func(c *Config) {
+			c.Team.Roles = map[string]string{"alice": "operator", "oncall": "readonly"}
+		}, field: "team.roles.oncall", severity: SeverityError},
+		{name: "agent on loopback without tls", mutate: func(c *Config) {
+			c.Agent.Listen = "127.0.0.1:8732"
+		}},
+		{name: "agent on all interfaces without tls", mutate: func(c *Config) {
+			c.Agent.Listen = ":8732"
+		}, field: "agent.listen", severity: SeverityWarning},
+		{name: "agent tls certificate without key", mutate: func(c *Config) {
+			c.Agent.TLSCertificate = "/etc/azud/agent.crt"
+		}, field: "agent.tls_certificate", severity: SeverityError},
+		{name: "agent token with a value instead of a secret name", mutate: func(c *Config) {
+			c.Agent.Tokens = map[string]AgentToken{"chatops": {Secret: "s3cr3t-t0ken!"}}
+		}, field: "agent.tokens.chatops.secret", severity: SeverityError},
+		{name: "agent token with unknown role", mutate: func(c *Config) {
+			c.Agent.Tokens = map[string]AgentToken{"dashboard": {Secret: "DASHBOARD_TOKEN", Role: "viewer"}}
+		}, field: "agent.tokens.dashboard.role", severity: SeverityError},
+		{name: "serve secret with a value instead of a name", mutate: func(c *Config) {
+			c.Serve.Secret = "hunter2!"
+		}, field: "serve.secret", severity: SeverityError},
+		{name: "serve tag pattern is malformed", mutate: func(c *Config) {
+			c.Serve.Tags = []string{"v*", "release-["}
+		}, field: "serve.tags[1]", severity: SeverityError},
+		{name: "deploy window timezone is unknown", mutate: func(c *Config) {
+			c.Deploy.Windows.Timezone = "Mars/Olympus"
+		}, field: "deploy.windows.timezone", severity: SeverityError},
+		{name: "deploy window day is unknown", mutate: func(c *Config) {
+			c.Deploy.Windows.Allowed = []DeployWindow{{Days: []string{"mon", "funday"}, Hours: "09:00-17:00"}}
+		}, field: "deploy.windows.allowed[0].days", severity: SeverityError},
+		{name: "deploy window crosses midnight", mutate: func(c *Config) {
+			c.Deploy.Windows.Allowed = []DeployWindow{{Hours: "09:00-17:00"}, {Days: []string{"sat"}, Hours: "22:00-02:00"}}
+		}, field: "deploy.windows.allowed[1].hours", severity: SeverityError},
+		{name: "negative pull rate limit", mutate: func(c *Config) {
+			c.Deploy.PullRateLimit = -2
+		}, field: "deploy.pull_rate_limit", severity: SeverityError},
+		{name: "asset bridging", mutate: func(c *Config) {
+			c.AssetPath = "/app/public/assets"
+		}},
+		{name: "relative asset path", mutate: func(c *Config) {
+			c.AssetPath = "public/assets"
+		}, field: "asset_path", severity: SeverityError},
+		{name: "asset path mounted by a volume", mutate: func(c *Config) {
+			c.AssetPath = "/app/public"
+			c.Volumes = []string{"/srv/public:/app/public/:ro"}
+		}, field: "asset_path", severity: SeverityError},
+		{name: "static paths", mutate: func(c *Config) {
+			c.Proxy.Static = []StaticConfig{
+				{Path: "/assets", Dir: "/app/public/assets", CacheControl: "public, max-age=31536000, immutable"},
+				{Path: "/uploads", Dir: "/app/uploads", Shared: true},
+			}
+		}},
+		{name: "static path with trailing slash", mutate: func(c *Config) {
+			c.Proxy.Static = []StaticConfig{{Path: "/assets/", Dir: "/app/public/assets"}}
+		}, field: "proxy.static[0].path", severity: SeverityError},
+		{name: "overlapping static paths", mutate: func(c *Config) {
+			c.Proxy.Static = []StaticConfig{
+				{Path: "/assets", Dir: "/app/public/assets"},
+				{Path: "/assets/uploads", Dir: "/app/uploads", Shared: true},
+			}
+		}, field: "proxy.static[1].path", severity: SeverityError},
+		{name: "relative static dir", mutate: func(c *Config) {
+			c.Proxy.Static = []StaticConfig{{Path: "/assets", Dir: "public/assets"}}
+		}, field: "proxy.static[0].dir", severity: SeverityError},
+		{name: "shared static dir mounted by a volume", mutate: func(c *Config) {
+			c.Proxy.Static = []StaticConfig{{Path: "/uploads", Dir: "/app/uploads", Shared: true}}
+			c.Volumes = []string{"uploads:/app/uploads"}
+		}, field: "proxy.static[0].dir", severity: SeverityError},
+		{name: "hold timeout", mutate: func(c *Config) {
+			c.Proxy.HoldTimeout = "15s"
+		}},
+		{name: "hold timeout with failover retries", mutate: func(c *Config) {
+			c.Proxy.HoldTimeout = "15s"
+			c.Proxy.Failover.Retries = 2
+		}, field: "proxy.hold_timeout", severity: SeverityWarning},
+		{name: "watch paths", mutate: func(c *Config) {
+			c.Builder.WatchPaths = []string{"services/api", "libs/shared"}
+		}},
+		{name: "empty watch path", mutate: func(c *Config) {
+			c.Builder.WatchPaths = []string{"services/api", " "}
+		}, field: "builder.watch_paths[1]", severity: SeverityError},
+		{name: "zstd chunked compression", mutate: func(c *Config) {
+			c.Builder.Compression = "zstd:chunked"
+		}},
+		{name: "unknown compression", mutate: func(c *Config) {
+			c.Builder.Compression = "brotli"
+		}, field: "builder.compression", severity: SeverityError},
+		{name: "compression with docker engine", mutate: func(c *Config) {
+			c.Builder.Engine = "buildx"
+			c.Builder.Compression = "zstd"
+		}, field: "builder.compression", severity: SeverityError},
+		{name: "peer image transfer", mutate: func(c *Config) {
+			c.Deploy.ImageTransfer = ImageTransferPeer
+		}},
+		{name: "unknown image transfer", mutate: func(c *Config) {
+			c.Deploy.ImageTransfer = "torrent"
+		}, field: "deploy.image_transfer", severity: SeverityError},
+		{name: "peer image transfer with pinned digests", mutate: func(c *Config) {
+			c.Deploy.ImageTransfer = ImageTransferPeer
+			c.Deploy.RequireDigest = true
+		}, field: "deploy.image_transfer", severity: SeverityError},
+		{name: "negative ssh concurrency", mutate: func(c *Config) {
+			c.SSH.MaxConcurrent = -1
+		}, field: "ssh.max_concurrent", severity: SeverityError},
+		{name: "ssh key passphrase secret with a value instead of a name", mutate: func(c *Config) {
+			c.SSH.KeyPassphraseSecret = "correct horse"
+		}, field: "ssh.key_passphrase_secret", severity: SeverityError},
+		{name: "ssh retry jitter above 1", mutate: func(c *Config) {
+			c.SSH.Retry.Jitter = 1.5
+		}, field: "ssh.retry.jitter", severity: SeverityError},
+		{name: "ssh retry backoff above its cap", mutate: func(c *Config) {
+			c.SSH.Retry.Backoff, c.SSH.Retry.MaxBackoff = time.Minute, 30*time.Second
+		}, field: "ssh.retry.backoff", severity: SeverityWarning},
+		{name: "per-host ssh concurrency above the global bound", mutate: func(c *Config) {
+			c.SSH.MaxConcurrent, c.SSH.MaxConcurrentPerHost = 4, 8
+		}, field: "ssh.max_concurrent_per_host", severity: SeverityWarning},
+		{name: "accessory on the proxy port", mutate: func(c *Config) {
+			c.Proxy.HTTPPort = 8080
+			c.Accessories = map[string]AccessoryConfig{"admin": {Image: "adminer:4", Host: "10.0.0.1", Port: "8080:8080"}}
+		}, field: "accessories.admin.port", severity: SeverityError},
+		{name: "rootless accessory on a privileged port", mutate: func(c *Config) {
+			c.Podman.Rootless = true
+			c.Proxy.HTTPPort, c.Proxy.HTTPSPort = 8080, 8443
+			c.Accessories = map[string]AccessoryConfig{"dns": {Image: "coredns:1", Host: "10.0.0.2", Port: "53:53/udp"}}
+		}, field: "accessories.dns.port", severity: SeverityError},
+		{name: "rootless stream on a privileged port", mutate: func(c *Config) {
+			c.Podman.Rootless = true
+			c.Proxy.HTTPPort, c.Proxy.HTTPSPort = 8080, 8443
+			c.Proxy.Image = "caddy-l4"
+			c.Proxy.Streams = []StreamConfig{{Name: "smtp", Listen: 25, Port: 2525, Protocol: "tcp"}}
+		}, field: "proxy.streams[0].listen", severity: SeverityError},
+		{name: "ssl without ports 80 and 443", mutate: func(c *Config) {
+			c.Proxy.SSL, c.Proxy.ACMEEmail = true, "ops@example.com"
+			c.Proxy.HTTPPort, c.Proxy.HTTPSPort = 8080, 8443
+		}, field: "proxy.ssl", severity: SeverityWarning},
+		{name: "ssl on port 443 only", mutate: func(c *Config) {
+			c.Proxy.SSL, c.Proxy.ACMEEmail = true, "ops@example.com"
+			c.Proxy.HTTPPort = 8080
+		}},
+		{name: "proxy.host repeated in proxy.hosts", mutate: func(c *Config) {
+			c.Proxy.Hosts = []string{"test.example.com", "www.example.com"}
+		}},
+		{name: "duplicate proxy host", mutate: func(c *Config) {
+			c.Proxy.Hosts = []string{"www.example.com", "api.example.com", "www.example.com"}
+		}, field: "proxy.hosts[2]", severity: SeverityWarning},
+		{name: "proxy hosts differing in case", mutate: func(c *Config) {
+			c.Proxy.Hosts = []string{"Test.example.com"}
+		}, field: "proxy.hosts[0]", severity: SeverityWarning},
+		{name: "accessory on the proxy port of another address", mutate: func(c *Config) {
+			c.Proxy.HTTPPort = 8080
+			c.Proxy.Bind = []string{"10.0.0.1"}
+			c.Accessories = map[string]AccessoryConfig{"admin": {Image: "adminer:4", Host: "10.0.0.1", Port: "127.0.0.1:8080:8080"}}
+		}},
+		{name: "proxy protocol from load balancers", mutate: func(c *Config) {
+			c.Proxy.ProxyProtocol = true
+			c.Proxy.ProxyProtocolAllow = []string{"10.0.0.0/8"}
+		}},
+		{name: "proxy protocol from anywhere", mutate: func(c *Config) {
+			c.Proxy.ProxyProtocol = true
+		}, field: "proxy.proxy_protocol", severity: SeverityWarning},
+		{name: "proxy protocol allow without CIDR", mutate: func(c *Config) {
+			c.Proxy.ProxyProtocol = true
+			c.Proxy.ProxyProtocolAllow = []string{"10.0.0.5"}
+		}, field: "proxy.proxy_protocol_allow[0]", severity: SeverityError},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &Config{
+				Service: "test",
+				Image:   "test:latest",
+				Servers: map[string]RoleConfig{
+					"web": {Hosts: []string{"10.0.0.1"}},
+				},
+				Proxy: ProxyConfig{Host: "test.example.com"},
+				SSH:   SSHConfig{Port: 22},
+			}
+			tt.mutate(cfg)
+
+			problems := Check(cfg)
+			if tt.field == "" {
+				if len(problems) != 0 {
+					t.Errorf("expected no problems, got %v", problems)
+				}
+				return
+			}
+			if len(problems) != 1 || problems[0].Field != tt.field || problems[0].Severity != tt.severity {
+				t.Errorf("expected one %s on %s, got %v", tt.severity, tt.field, problems)
+			}
+		})
+	}
+}
+
+func TestValidate_EnvReferences(t *testing.T) {
+	tests := []struct {
+		name    string
+		storage string
+		clear   map[string]string
+		roleEnv map[string]string
+		errMsg  string
+	}{
+		{name: "secret reference", clear: map[string]string{"DATABASE_URL": "postgres://app:${DB_PASSWORD}@db/app"}},
+		{name: "encrypted storage", storage: SecretsStorageEncrypted, clear: map[string]string{"DATABASE_URL": "postgres://app:${DB_PASSWORD}@db/app"}},
+		{name: "podman storage", storage: SecretsStoragePodman, clear: map[string]string{"DATABASE_URL": "postgres://app:${DB_PASSWORD}@db/app"}, errMsg: "env.clear.DATABASE_URL"},
+		{name: "cycle", clear: map[string]string{"A": "${B}", "B": "${A}"}, errMsg: "part of a cycle"},
+		{name: "role env cycle", clear: map[string]string{"A": "${B}"}, roleEnv: map[string]string{"B": "${A}"}, errMsg: "servers.web.env.B"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &Config{
+				Service: "test",
+				Image:   "test:latest",
+				Servers: map[string]RoleConfig{
+					"web": {Hosts: []string{"localhost"}, Env: tt.roleEnv},
+				},
+				Proxy:          ProxyConfig{Host: "test.example.com"},
+				SSH:            SSHConfig{Port: 22},
+				SecretsStorage: tt.storage,
+				Env:            EnvConfig{Clear: tt.clear, Secret: []string{"DB_PASSWORD"}},
+			}
+
+			err := Validate(cfg)
+			if tt.errMsg == "" {
+				if err != nil {
+					t.Errorf("expected no error, got %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.errMsg) {
+				t.Errorf("expected error containing %q, got %v", tt.errMsg, err)
+			}
+		})
+	}
+}
+
+func TestTagEnv(t *testing.T) {
+	cfg := &Config{
+		Servers: map[string]RoleConfig{
+			"web":    {Hosts: []string{"eu1", "us1"}, Tags: []string{"blue"}},
+			"worker": {Hosts: []string{"eu1"}},
+		},
+		HostTags: map[string][]string{"eu1": {"region=eu", "blue"}},
+		Env: EnvConfig{Tags: map[string]map[string]string{
+			"eu":   {"REGION": "eu", "COLOR": "eu"},
+			"blue": {"COLOR": "blue"},
+		}},
+	}
+
+	if got := cfg.GetHostTags("eu1", "web"); !reflect.DeepEqual(got, []string{"blue", "region=eu"}) {
+		t.Errorf("GetHostTags(eu1, web) = %v", got)
+	}
+	if got := cfg.TagEnv("eu1", "web"); !reflect.DeepEqual(got, map[string]string{"REGION": "eu", "COLOR": "eu"}) {
+		t.Errorf("TagEnv(eu1, web) = %v", got)
+	}
+	if got := cfg.TagEnv("us1", "web"); !reflect.DeepEqual(got, map[string]string{"COLOR": "blue"}) {
+		t.Errorf("TagEnv(us1, web) = %v", got)
+	}
+}
+
+func TestValidate_HostTags(t *testing.T) {
+	tests := []struct {
+		name     string
+		hostTags map[string][]string
+		roleTags []string
+		envTags  map[string]map[string]string
+		errMsg   string
+	}{
+		{name: "valid", hostTags: map[string][]string{"localhost": {"region=eu"}}, roleTags: []string{"blue"}, envTags: map[string]map[string]string{"eu": {"REGION": "eu"}}},
+		{name: "unknown host", hostTags: map[string][]string{"10.0.0.9": {"eu"}}, errMsg: "host_tags.10.0.0.9"},
+		{name: "invalid host tag", hostTags: map[string][]string{"localhost": {"eu west"}}, errMsg: "host_tags.localhost[0]"},
+		{name: "invalid role tag", roleTags: []string{"=eu"}, errMsg: "servers.web.tags[0]"},
+		{name: "invalid env tag", envTags: map[string]map[string]string{"eu/west": {"REGION": "eu"}}, errMsg: "env.tags.eu/west"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &Config{
+				Service: "test",
+				Image:   "test:latest",
+				Servers: map[string]RoleConfig{
+					"web": {Hosts: []string{"localhost"}, Tags: tt.roleTags},
+				},
+				Proxy:    ProxyConfig{Host: "test.example.com"},
+				SSH:      SSHConfig{Port: 22},
+				HostTags: tt.hostTags,
+				Env:      EnvConfig{Tags: tt.envTags},
+			}
+
+			err := Validate(cfg)
+			if tt.errMsg == "" {
+				if err != nil {
+					t.Errorf("expected no error, got %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.errMsg) {
+				t.Errorf("expected error containing %q, got %v", tt.errMsg, err)
+			}
+		})
+	}
+}
+
+func TestLabelVolumes(t *testing.T) {
+	specs := []string{
+		"/srv/uploads:/app/uploads",
+		"/srv/config:/app/config:ro",
+		"/srv/shared:/app/shared:Z",
+		"cache:/app/cache",
+		"$HOME/data:/data:rw",
+		"broken",
+	}
+
+	cfg := &Config{}
+	if got := cfg.LabelVolumes(specs); !reflect.DeepEqual(got, specs) {
+		t.Errorf("LabelVolumes() without selinux_label = %v, want unchanged", got)
+	}
+
+	cfg.Podman.SELinuxLabel = SELinuxLabelShared
+	want := []string{
+		"/srv/uploads:/app/uploads:z",
+		"/srv/config:/app/config:ro,z",
+		"/srv/shared:/app/shared:Z",
+		"cache:/app/cache",
+		"$HOME/data:/data:rw,z",
+		"broken",
+	}
+	if got := cfg.LabelVolumes(specs); !reflect.DeepEqual(got, want) {
+		t.Errorf("LabelVolumes() = %v, want %v", got, want)
+	}
+
+	cfg.Podman.SELinuxLabel = SELinuxLabelPrivate
+	if got := cfg.LabelVolumes(specs[:1]); got[0] != "/srv/uploads:/app/uploads:Z" {
+		t.Errorf("LabelVolumes() private = %v", got)
+	}
+}
+
+func TestValidate_Volumes(t *testing.T) {
+	tests := []struct {
+		name         string
+		volumes      []string
+		accessory    []string
+		selinuxLabel string
+		errMsg       string
+	}{
+		{name: "valid", volumes: []string{"/srv/a:/a", "cache:/cache:U,z", "/srv/b:/b:ro,Z"}, accessory: []string{"pgdata:/var/lib/postgresql/data"}, selinuxLabel: "shared"},
+		{name: "missing target", volumes: []string{"/srv/a"}, errMsg: "volumes[0]"},
+		{name: "relative target", volumes: []string{"/srv/a:a"}, errMsg: "must be an absolute path"},
+		{name: "unknown option", volumes: []string{"/srv/a:/a:readonly"}, errMsg: `unknown volume option "readonly"`},
+		{name: "z and Z", accessory: []string{"/srv/a:/a:z,Z"}, errMsg: "accessories.db.volumes[0]"},
+		{name: "ro and rw", volumes: []string{"/srv/a:/a:ro,rw"}, errMsg: "ro and rw"},
+		{name: "unknown selinux label", selinuxLabel: "strict", errMsg: "podman.selinux_label"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &Config{
+				Service: "test",
+				Image:   "test:latest",
+				Servers: map[string]RoleConfig{
+					"web": {Hosts: []string{"localhost"}},
+				},
+				Proxy:   ProxyConfig{Host: "test.example.com"},
+				SSH:     SSHConfig{Port: 22},
+				Podman:  PodmanConfig{SELinuxLabel: tt.selinuxLabel},
+				Volumes: tt.volumes,
+			}
+			if tt.accessory != nil {
+				cfg.Accessories = map[string]AccessoryConfig{
+					"db": {Image: "postgres:16", Host: "localhost", Volumes: tt.accessory},
+				}
+			}
+
+			err := Validate(cfg)
+			if tt.errMsg == "" {
+				if err != nil {
+					t.Errorf("expected no error, got %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.errMsg) {
+				t.Errorf("expected error containing %q, got %v", tt.errMsg, err)
+			}
+		})
+	}
+}
+
+func TestValidate_RuntimeOptions(t *testing.T) {
+	tests := []struct {
+		name      string
+		role      RoleConfig
+		accessory AccessoryConfig
+		errMsg    string
+	}{
+		{name: "valid", role: RoleConfig{User: "1000:1000", Init: true, StopSignal: "SIGQUIT", StopTimeout: time.Minute}, accessory: AccessoryConfig{User: "postgres", StopSignal: "15"}},
+		{name: "invalid user", role: RoleConfig{User: "app user"}, errMsg: "servers.web.user"},
+		{name: "invalid signal", accessory: AccessoryConfig{StopSignal: "sigquit"}, errMsg: "accessories.db.stop_signal"},
+		{name: "sub-second timeout", role: RoleConfig{StopTimeout: 500 * time.Millisecond}, errMsg: "servers.web.stop_timeout"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			role := tt.role
+			role.Hosts = []string{"localhost"}
+			accessory := tt.accessory
+			accessory.Image = "postgres:16"
+			accessory.Host = "localhost"
+			cfg := &Config{
+				Service:     "test",
+				Image:       "test:latest",
+				Servers:     map[string]RoleConfig{"web": role},
+				Accessories: map[string]AccessoryConfig{"db": accessory},
+				Proxy:       ProxyConfig{Host: "test.example.com"},
+				SSH:         SSHConfig{Port: 22},
+			}
+
+			err := Validate(cfg)
+			if tt.errMsg == "" {
+				if err != nil {
+					t.Errorf("expected no error, got %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.errMsg) {
+				t.Errorf("expected error containing %q, got %v", tt.errMsg, err)
+			}
+		})
+	}
+}
+
+func TestValidate_AccessoryHealthcheck(t *testing.T) {
+	tests := []struct {
+		name        string
+		healthcheck AccessoryHealthcheck
+		errMsg      string
+	}{
+		{name: "cmd", healthcheck: AccessoryHealthcheck{Cmd: "pg_isready -U postgres", Interval: 5 * time.Second, Timeout: 2 * time.Second}},
+		{name: "port", healthcheck: AccessoryHealthcheck{Port: 5432}},
+		{name: "cmd and port", healthcheck: AccessoryHealthcheck{Cmd: "pg_isready", Port: 5432}, errMsg: "set either cmd or port"},
+		{name: "invalid port", healthcheck: AccessoryHealthcheck{Port: 70000}, errMsg: "accessories.db.healthcheck.port"},
+		{name: "interval without check", healthcheck: AccessoryHealthcheck{Interval: 5 * time.Second}, errMsg: "need a cmd or port"},
+		{name: "sub-second timeout", healthcheck: AccessoryHealthcheck{Port: 5432, Timeout: 100 * time.Millisecond}, errMsg: "accessories.db.healthcheck.timeout"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &Config{
+				Service: "test",
+				Image:   "test:latest",
+				Servers: map[string]RoleConfig{"web": {Hosts: []string{"localhost"}}},
+				Accessories: map[string]AccessoryConfig{
+					"db": {Image: "postgres:16", Host: "localhost", Healthcheck: tt.healthcheck},
+				},
+				Proxy: ProxyConfig{Host: "test.example.com"},
+				SSH:   SSHConfig{Port: 22},
+			}
+
+			err := Validate(cfg)
+			if tt.errMsg == "" {
+				if err != nil {
+					t.Errorf("expected no error, got %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.errMsg) {
+				t.Errorf("expected error containing %q, got %v", tt.errMsg, err)
+			}
+		})
+	}
+}
+
+func TestValidate_AccessoryProfile(t *testing.T) {
+	tests := []struct {
+		name      string
+		accessory AccessoryConfig
+		errMsg    string
+	}{
+		{name: "standard", accessory: AccessoryConfig{Profile: "standard", Backup: AccessoryBackupConfig{S3: "s3://backups/wal"}}},
+		{name: "unknown profile", accessory: AccessoryConfig{Profile: "large"}, errMsg: "accessories.db.profile"},
+		{name: "backup without profile", accessory: AccessoryConfig{Backup: AccessoryBackupConfig{S3: "s3://backups/wal"}}, errMsg: "backup needs profile"},
+		{name: "backup without bucket", accessory: AccessoryConfig{Profile: "standard", Backup: AccessoryBackupConfig{Region: "eu-west-1"}}, errMsg: "accessories.db.backup.s3"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			accessory := tt.accessory
+			accessory.Image = "postgres:16"
+			accessory.Host = "localhost"
+			cfg := &Config{
+				Service:     "test",
+				Image:       "test:latest",
+				Servers:     map[string]RoleConfig{"web": {Hosts: []string{"localhost"}}},
+				Accessories: map[string]AccessoryConfig{"db": accessory},
+				Proxy:       ProxyConfig{Host: "test.example.com"},
+				SSH:         SSHConfig{Port: 22},
+			}
+
+			err := Validate(cfg)
+			if tt.errMsg == "" {
+				if err != nil {
+					t.Errorf("expected no error, got %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.errMsg) {
+				t.Errorf("expected error containing %q, got %v", tt.errMsg, err)
+			}
+		})
+	}
+}
+
+func TestValidate_AccessoryCache(t *testing.T) {
+	tests := []struct {
+		name      string
+		accessory AccessoryConfig
+		errMsg    string
+	}{
+		{name: "redis", accessory: AccessoryConfig{Cache: AccessoryCacheConfig{Engine: "redis", Password: true, TLS: true}}},
+		{name: "memcached", accessory: AccessoryConfig{Cache: AccessoryCacheConfig{Engine: "memcached", Password: true}}},
+		{name: "missing engine", accessory: AccessoryConfig{Cache: AccessoryCacheConfig{Password: true}}, errMsg: "accessories.cache.cache.engine"},
+		{name: "unknown engine", accessory: AccessoryConfig{Cache: AccessoryCacheConfig{Engine: "valkey"}}, errMsg: "unknown cache engine"},
+		{name: "with profile", accessory: AccessoryConfig{Profile: "standard", Cache: AccessoryCacheConfig{Engine: "redis"}}, errMsg: "cannot be combined with profile"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			accessory := tt.accessory
+			accessory.Image = "redis:7"
+			accessory.Host = "localhost"
+			cfg := &Config{
+				Service:     "test",
+				Image:       "test:latest",
+				Servers:     map[string]RoleConfig{"web": {Hosts: []string{"localhost"}}},
+				Accessories: map[string]AccessoryConfig{"cache": accessory},
+				Proxy:       ProxyConfig{Host: "test.example.com"},
+				SSH:         SSHConfig{Port: 22},
+			}
+
+			err := Validate(cfg)
+			if tt.errMsg == "" {
+				if err != nil {
+					t.Errorf("expected no error, got %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.errMsg) {
+				t.Errorf("expected error containing %q, got %v", tt.errMsg, err)
+			}
+		})
+	}
+}
+
+func TestValidate_DNS(t *testing.T) {
+	valid := DNSConfig{Provider: "cloudflare", Zone: "example.com", TokenSecret: "CLOUDFLARE_API_TOKEN"}
+	tests := []struct {
+		name   string
+		modify func(*DNSConfig)
+		errMsg string
+	}{
+		{name: "valid", modify: func(d *DNSConfig) { d.TTL = 300; d.Targets = []string{"203.0.113.10", "2001:db8::10"} }},
+		{name: "zone with trailing dot", modify: func(d *DNSConfig) { d.Zone = "Example.com." }},
+		{name: "no provider", modify: func(d *DNSConfig) { d.Provider = "" }, errMsg: "dns.provider: provider is required"},
+		{name: "unknown provider", modify: func(d *DNSConfig) { d.Provider = "route53" }, errMsg: "unknown DNS provider"},
+		{name: "host outside zone", modify: func(d *DNSConfig) { d.Zone = "example.org" }, errMsg: "proxy host app.example.com is not in zone example.org"},
+		{name: "missing token", modify: func(d *DNSConfig) { d.TokenSecret = "" }, errMsg: "dns.token_secret"},
+		{name: "short ttl", modify: func(d *DNSConfig) { d.TTL = 30 }, errMsg: "dns.ttl"},
+		{name: "invalid target", modify: func(d *DNSConfig) { d.Targets = []string{"lb.example.com"} }, errMsg: "dns.targets[0]"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d := valid
+			tt.modify(&d)
+			cfg := &Config{
+				Service: "test",
+				Image:   "test:latest",
+				Servers: map[string]RoleConfig{"web": {Hosts: []string{"localhost"}}},
+				Proxy:   ProxyConfig{Host: "app.example.com"},
+				SSH:     SSHConfig{Port: 22},
+				DNS:     d,
+			}
+
+			err := Validate(cfg)
+			if tt.errMsg == "" {
+				if err != nil {
+					t.Errorf("expected no error, got %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.errMsg) {
+				t.Errorf("expected error containing %q, got %v", tt.errMsg, err)
+			}
+		})
+	}
+}
+
+func TestValidate_Hardening(t *testing.T) {
+	tests := []struct {
+		name      string
+		hardening HardeningConfig
+		errMsg    string
+	}{
+		{name: "strict preset", hardening: HardeningConfig{Preset: "strict"}},
+		{name: "options", hardening: HardeningConfig{ReadOnly: true, Tmpfs: []string{"/tmp:size=64m,mode=1777"}, CapDrop: []string{"ALL", "CAP_NET_RAW"}, SeccompProfile: "/etc/azud/seccomp.json"}},
+		{name: "unknown preset", hardening: HardeningConfig{Preset: "paranoid"}, errMsg: "servers.web.hardening.preset"},
+		{name: "relative tmpfs", hardening: HardeningConfig{Tmpfs: []string{"tmp"}}, errMsg: "servers.web.hardening.tmpfs[0]"},
+		{name: "invalid capability", hardening: HardeningConfig{CapDrop: []string{"NET RAW"}}, errMsg: "servers.web.hardening.cap_drop[0]"},
+		{name: "unconfined seccomp", hardening: HardeningConfig{SeccompProfile: "unconfined"}, errMsg: "servers.web.hardening.seccomp_profile"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &Config{
+				Service: "test",
+				Image:   "test:latest",
+				Servers: map[string]RoleConfig{
+					"web": {Hosts: []string{"localhost"}, Hardening: tt.hardening},
+				},
+				Proxy: ProxyConfig{Host: "test.example.com"},
+				SSH:   SSHConfig{Port: 22},
+			}
+
+			err := Validate(cfg)
+			if tt.errMsg == "" {
+				if err != nil {
+					t.Errorf("expected no error, got %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.errMsg) {
+				t.Errorf("expected error containing %q, got %v", tt.errMsg, err)
+			}
+		})
+	}
+}
+
+func TestValidate_Streams(t *testing.T) {
+	smtp := StreamConfig{Name: "smtp", Listen: 25, Port: 2525, Protocol: "tcp"}
+	tests := []struct {
+		name    string
+		image   string
+		streams []StreamConfig
+		socket  string
+		errMsg  string
+	}{
+		{name: "valid", image: "registry.example.com/caddy-l4:2.11", streams: []StreamConfig{smtp, {Name: "dns", Listen: 53, Port: 53, Protocol: "udp"}}},
+		{name: "tcp and udp share a port", image: "caddy-l4", streams: []StreamConfig{{Name: "dns-tcp", Listen: 53, Port: 53, Protocol: "tcp"}, {Name: "dns-udp", Listen: 53, Port: 53, Protocol: "udp"}}},
+		{name: "missing image", streams: []StreamConfig{smtp}, errMsg: "proxy.image"},
+		{name: "duplicate name", image: "caddy-l4", streams: []StreamConfig{smtp, {Name: "smtp", Listen: 587, Port: 587, Protocol: "tcp"}}, errMsg: "duplicate stream name"},
+		{name: "duplicate listener", image: "caddy-l4", streams: []StreamConfig{smtp, {Name: "submission", Listen: 25, Port: 587, Protocol: "tcp"}}, errMsg: "proxy.streams[1].listen"},
+		{name: "http port", image: "caddy-l4", streams: []StreamConfig{{Name: "web", Listen: 443, Port: 8443, Protocol: "tcp"}}, errMsg: "already used by the proxy"},
+		{name: "invalid protocol", image: "caddy-l4", streams: []StreamConfig{{Name: "mqtt", Listen: 1883, Port: 1883, Protocol: "sctp"}}, errMsg: "proxy.streams[0].protocol"},
+		{name: "invalid port", image: "caddy-l4", streams: []StreamConfig{{Name: "mqtt", Listen: 1883, Port: 70000, Protocol: "tcp"}}, errMsg: "proxy.streams[0].port"},
+		{name: "app socket", image: "caddy-l4", streams: []StreamConfig{smtp}, socket: "/run/app/app.sock", errMsg: "proxy.app_socket"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &Config{
+				Service: "test",
+				Image:   "test:latest",
+				Servers: map[string]RoleConfig{
+					"web": {Hosts: []string{"localhost"}},
+				},
+				Proxy: ProxyConfig{Host: "test.example.com", Image: tt.image, Streams: tt.streams, AppSocket: tt.socket},
+				SSH:   SSHConfig{Port: 22},
+			}
+
+			err := Validate(cfg)
+			if tt.errMsg == "" {
+				if err != nil {
+					t.Errorf("expected no error, got %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.errMsg) {
+				t.Errorf("expected error containing %q, got %v", tt.errMsg, err)
+			}
+		})
+	}
+}
+
 func TestValidate_HostAddresses(t *testing.T) {
 	tests := []struct {
 		name    string
@@ -584,6 +1463,37 @@ func TestValidate_TrustedFingerprintsIncludeAllSSHHosts(t *testing.T) {
 	}
 }
 
+func TestHasTrustedFingerprintIPv6(t *testing.T) {
+	tests := []struct {
+		name string
+		host string
+		key  string
+		port int
+		want bool
+	}{
+		{"bracketed host, bracketed key", "[2001:db8::1]", "[2001:db8::1]", 22, true},
+		{"bracketed host, unbracketed key", "[2001:db8::1]", "2001:db8::1", 22, true},
+		{"unbracketed host, bracketed key", "2001:db8::1", "[2001:db8::1]", 22, true},
+		{"unbracketed host, unbracketed key", "2001:db8::1", "2001:db8::1", 0, true},
+		{"bracketed host, key with port", "[2001:db8::1]", "[2001:db8::1]:2222", 2222, true},
+		{"unbracketed host, key with port", "2001:db8::1", "[2001:db8::1]:2222", 2222, true},
+		{"bracketed host, bracketed key, custom port", "[2001:db8::1]", "[2001:db8::1]", 2222, true},
+		{"key for another port", "[2001:db8::1]", "[2001:db8::1]:2222", 22, false},
+		{"another host", "[2001:db8::1]", "2001:db8::2", 22, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &Config{SSH: SSHConfig{
+				Port:                    tt.port,
+				TrustedHostFingerprints: map[string][]string{tt.key: {"SHA256:abc"}},
+			}}
+			if got := hasTrustedFingerprint(cfg, tt.host); got != tt.want {
+				t.Fatalf("hasTrustedFingerprint(%q) with key %q on port %d = %t, want %t", tt.host, tt.key, tt.port, got, tt.want)
+			}
+		})
+	}
+}
+
 func TestIsValidHost(t *testing.T) {
 	tests := []struct {
 		host  string
@@ -592,6 +1502,10 @@ func TestIsValidHost(t *testing.T) {
 		{"192.168.1.1", true},
 		{"10.0.0.1", true},
 		{"::1", true},
+		{"2001:db8::10", true},
+		{"[2001:db8::10]", true},
+		{"[10.0.0.1]", false},
+		{"[2001:db8::10", false},
 		{"localhost", true},
 		{"server.example.com", true},
 		{"web-server-01.prod.example.com", true},
@@ -667,6 +1581,10 @@ func TestValidate_ProxyRangesAndDurations(t *testing.T) {
 			HTTPPort:        70000,
 			HTTPSPort:       -1,
 			ResponseTimeout: "notaduration",
+			WriteTimeout:    "bad",
+			IdleTimeout:     "bad",
+			DialTimeout:     "bad",
+			BootTimeout:     "0s",
 			Healthcheck: HealthcheckConfig{
 				Interval: "bad",
 				Timeout:  "bad",
@@ -675,6 +1593,16 @@ func TestValidate_ProxyRangesAndDurations(t *testing.T) {
 				MaxRequestBody: -1,
 				Memory:         -1,
 			},
+			Failover: FailoverConfig{
+				TryDuration:  "soon",
+				TryInterval:  "-1s",
+				Retries:      -1,
+				FailDuration: "bad",
+				MaxFails:     -1,
+			},
+			HoldTimeout:    "0s",
+			MaxRequests:    -1,
+			MaxConnections: -1,
 		},
 		SSH: SSHConfig{Port: 22},
 	}
@@ -687,10 +1615,22 @@ func TestValidate_ProxyRangesAndDurations(t *testing.T) {
 		"proxy.http_port",
 		"proxy.https_port",
 		"proxy.response_timeout",
+		"proxy.write_timeout",
+		"proxy.idle_timeout",
+		"proxy.dial_timeout",
+		"proxy.boot_timeout",
 		"proxy.healthcheck.interval",
 		"proxy.healthcheck.timeout",
 		"proxy.buffering.max_request_body",
 		"proxy.buffering.memory",
+		"proxy.failover.try_duration",
+		"proxy.failover.try_interval",
+		"proxy.failover.retries",
+		"proxy.failover.fail_duration",
+		"proxy.failover.max_fails",
+		"proxy.hold_timeout",
+		"proxy.max_requests",
+		"proxy.max_connections",
 	} {
 		if !strings.Contains(err.Error(), field) {
 			t.Fatalf("expected error containing %q, got %v", field, err)
@@ -698,6 +1638,273 @@ func TestValidate_ProxyRangesAndDurations(t *testing.T) {
 	}
 }
 
+func TestValidate_ProxyContainerLimits(t *testing.T) {
+	tests := []struct {
+		name      string
+		resources ProxyResourcesConfig
+		driver    string
+		options   map[string]string
+		wantField string
+	}{
+		{name: "valid", resources: ProxyResourcesConfig{Memory: "512m", CPUs: "0.5"}, driver: "k8s-file", options: map[string]string{"max-size": "10m"}},
+		{name: "bad memory", resources: ProxyResourcesConfig{Memory: "lots"}, wantField: "proxy.resources.memory"},
+		{name: "bad cpus", resources: ProxyResourcesConfig{CPUs: "0"}, wantField: "proxy.resources.cpus"},
+		{name: "bad driver", driver: "syslog", wantField: "proxy.log_driver"},
+		{name: "bad max-size", options: map[string]string{"max-size": "10 MB"}, wantField: "proxy.log_options.max-size"},
+		{name: "unknown option", options: map[string]string{"max-file": "3"}, wantField: "proxy.log_options.max-file"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &Config{
+				Service: "test",
+				Image:   "test:latest",
+				Servers: map[string]RoleConfig{
+					"web": {Hosts: []string{"localhost"}},
+				},
+				Proxy: ProxyConfig{
+					Host:       "test.example.com",
+					Resources:  tt.resources,
+					LogDriver:  tt.driver,
+					LogOptions: tt.options,
+				},
+				SSH: SSHConfig{Port: 22},
+			}
+
+			err := Validate(cfg)
+			if tt.wantField == "" {
+				if err != nil {
+					t.Fatalf("unexpected validation error: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantField) {
+				t.Fatalf("expected error containing %q, got %v", tt.wantField, err)
+			}
+		})
+	}
+}
+
+func TestValidate_ProxyBind(t *testing.T) {
+	tests := []struct {
+		name      string
+		bind      []string
+		private   map[string]string
+		rootful   bool
+		wantField string
+		wantErr   string
+	}{
+		{name: "addresses", bind: []string{"10.0.0.5", "[fd00::5]"}},
+		{name: "private", bind: []string{ProxyBindPrivate}, private: map[string]string{"web1.example.com": "10.0.0.5"}},
+		{name: "interface name", bind: []string{"eth1"}, wantField: "proxy.bind[0]"},
+		{name: "private hostname", bind: []string{ProxyBindPrivate}, wantErr: "not an IP address"},
+		{name: "host networking", bind: []string{"10.0.0.5"}, rootful: true, wantErr: "host networking"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &Config{
+				Service: "test",
+				Image:   "test:latest",
+				Servers: map[string]RoleConfig{
+					"web": {Hosts: []string{"web1.example.com"}},
+				},
+				Podman: PodmanConfig{Rootless: tt.rootful},
+				Proxy: ProxyConfig{
+					Host:             "test.example.com",
+					Bind:             tt.bind,
+					PrivateAddresses: tt.private,
+					Rootful:          tt.rootful,
+				},
+				SSH: SSHConfig{Port: 22},
+			}
+
+			err := Validate(cfg)
+			want := tt.wantField + tt.wantErr
+			if want == "" {
+				if err != nil {
+					t.Fatalf("unexpected validation error: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), want) {
+				t.Fatalf("expected error containing %q, got %v", want, err)
+			}
+		})
+	}
+}
+
+func TestValidate_GeoIP(t *testing.T) {
+	tests := []struct {
+		name   string
+		mutate func(*Config)
+		errMsg string
+	}{
+		{name: "database", mutate: func(c *Config) {
+			c.Proxy.GeoIP.Database = "/var/lib/GeoIP/GeoLite2-Country.mmdb"
+			c.Proxy.DenyCountries = []string{"KP"}
+		}},
+		{name: "download", mutate: func(c *Config) {
+			c.Proxy.GeoIP = GeoIPConfig{AccountID: "123456", LicenseKey: "MAXMIND_LICENSE_KEY"}
+			c.Proxy.ABTests = []ABTestConfig{{Name: "dach", Countries: []string{"DE", "AT"}}}
+		}},
+		{name: "countries without database", mutate: func(c *Config) {
+			c.Proxy.DenyCountries = []string{"KP"}
+		}, errMsg: "proxy.geoip"},
+		{name: "stock image", mutate: func(c *Config) {
+			c.Proxy.Image = ""
+			c.Proxy.GeoIP.Database = "/var/lib/GeoIP/GeoLite2-Country.mmdb"
+		}, errMsg: "proxy.image"},
+		{name: "relative database", mutate: func(c *Config) {
+			c.Proxy.GeoIP.Database = "GeoLite2-Country.mmdb"
+		}, errMsg: "proxy.geoip.database"},
+		{name: "license key without account", mutate: func(c *Config) {
+			c.Proxy.GeoIP.LicenseKey = "MAXMIND_LICENSE_KEY"
+		}, errMsg: "proxy.geoip.account_id"},
+		{name: "allow and deny", mutate: func(c *Config) {
+			c.Proxy.GeoIP.Database = "/var/lib/GeoIP/GeoLite2-Country.mmdb"
+			c.Proxy.AllowCountries = []string{"DE"}
+			c.Proxy.DenyCountries = []string{"KP"}
+		}, errMsg: "proxy.allow_countries"},
+		{name: "lowercase country", mutate: func(c *Config) {
+			c.Proxy.GeoIP.Database = "/var/lib/GeoIP/GeoLite2-Country.mmdb"
+			c.Proxy.DenyCountries = []string{"kp"}
+		}, errMsg: "proxy.deny_countries[0]"},
+		{name: "country test with value", mutate: func(c *Config) {
+			c.Proxy.GeoIP.Database = "/var/lib/GeoIP/GeoLite2-Country.mmdb"
+			c.Proxy.ABTests = []ABTestConfig{{Name: "dach", Countries: []string{"DE"}, Value: "1"}}
+		}, errMsg: "proxy.ab_tests[0].value"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &Config{
+				Service: "test",
+				Image:   "test:latest",
+				Servers: map[string]RoleConfig{
+					"web": {Hosts: []string{"localhost"}},
+				},
+				Proxy: ProxyConfig{Host: "test.example.com", Image: "ghcr.io/acme/caddy-geoip:2"},
+				SSH:   SSHConfig{Port: 22},
+			}
+			tt.mutate(cfg)
+
+			err := Validate(cfg)
+			if tt.errMsg == "" {
+				if err != nil {
+					t.Fatalf("unexpected validation error: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.errMsg) {
+				t.Fatalf("expected error containing %q, got %v", tt.errMsg, err)
+			}
+		})
+	}
+}
+
+func TestValidate_ProxyImageBuild(t *testing.T) {
+	tests := []struct {
+		name   string
+		mutate func(*Config)
+		errMsg string
+	}{
+		{name: "modules", mutate: func(c *Config) {
+			c.Proxy.ImageBuild.Modules = []string{"github.com/mholt/caddy-l4", "github.com/caddy-dns/cloudflare@v0.2.1"}
+		}},
+		{name: "satisfies streams", mutate: func(c *Config) {
+			c.Proxy.ImageBuild.Modules = []string{"github.com/mholt/caddy-l4"}
+			c.Proxy.Streams = []StreamConfig{{Name: "mqtt", Listen: 1883, Port: 1883, Protocol: "tcp"}}
+		}},
+		{name: "with image", mutate: func(c *Config) {
+			c.Proxy.Image = "ghcr.io/acme/caddy:2"
+			c.Proxy.ImageBuild.Modules = []string{"github.com/mholt/caddy-l4"}
+		}, errMsg: "proxy.image_build"},
+		{name: "local replacement", mutate: func(c *Config) {
+			c.Proxy.ImageBuild.Modules = []string{"github.com/mholt/caddy-l4=../caddy-l4"}
+		}, errMsg: "proxy.image_build.modules[0]"},
+		{name: "shell in module", mutate: func(c *Config) {
+			c.Proxy.ImageBuild.Modules = []string{"github.com/x/y;id"}
+		}, errMsg: "proxy.image_build.modules[0]"},
+		{name: "tagged repository", mutate: func(c *Config) {
+			c.Proxy.ImageBuild.Modules = []string{"github.com/mholt/caddy-l4"}
+			c.Proxy.ImageBuild.Repository = "ghcr.io/acme/caddy:2"
+		}, errMsg: "proxy.image_build.repository"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &Config{
+				Service: "test",
+				Image:   "ghcr.io/acme/app:latest",
+				Servers: map[string]RoleConfig{
+					"web": {Hosts: []string{"localhost"}},
+				},
+				Proxy: ProxyConfig{Host: "test.example.com"},
+				SSH:   SSHConfig{Port: 22},
+			}
+			tt.mutate(cfg)
+
+			err := Validate(cfg)
+			if tt.errMsg == "" {
+				if err != nil {
+					t.Fatalf("unexpected validation error: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.errMsg) {
+				t.Fatalf("expected error containing %q, got %v", tt.errMsg, err)
+			}
+		})
+	}
+}
+
+func TestValidate_TimeoutHierarchy(t *testing.T) {
+	tests := []struct {
+		name           string
+		responseHeader string
+		drain          time.Duration
+		errMsg         string
+	}{
+		{name: "consistent", responseHeader: "10s", drain: 30 * time.Second},
+		{name: "header exceeds response", responseHeader: "1m", drain: 30 * time.Second, errMsg: "proxy.response_header_timeout"},
+		{name: "drain exceeds deploy", drain: time.Minute, errMsg: "deploy.drain_timeout"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &Config{
+				Service: "test",
+				Image:   "test:latest",
+				Servers: map[string]RoleConfig{
+					"web": {Hosts: []string{"localhost"}},
+				},
+				Proxy: ProxyConfig{
+					Host:                  "test.example.com",
+					ResponseTimeout:       "30s",
+					ResponseHeaderTimeout: tt.responseHeader,
+					WriteTimeout:          "30s",
+					IdleTimeout:           "2m",
+					DialTimeout:           "3s",
+				},
+				Deploy: DeployConfig{DeployTimeout: 30 * time.Second, DrainTimeout: tt.drain},
+				SSH:    SSHConfig{Port: 22},
+			}
+
+			err := Validate(cfg)
+			if tt.errMsg == "" {
+				if err != nil {
+					t.Errorf("expected no error, got %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.errMsg) {
+				t.Errorf("expected error containing %q, got %v", tt.errMsg, err)
+			}
+		})
+	}
+}
+
 func TestValidate_CronRequiredFields(t *testing.T) {
 	cfg := &Config{
 		Service: "test",
@@ -1134,6 +2341,33 @@ func TestValidateMinimumVersion(t *testing.T) {
 	}
 }
 
+func TestMinimumVersionErrorIsTyped(t *testing.T) {
+	err := ValidateMinimumVersion(&Config{MinimumVersion: "2.0.0"}, "v1.9.9")
+	var tooOld *MinimumVersionError
+	if !errors.As(err, &tooOld) || tooOld.Required != "2.0.0" || tooOld.Current != "v1.9.9" {
+		t.Fatalf("expected MinimumVersionError, got %v", err)
+	}
+}
+
+func TestCompareVersions(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want int
+		ok   bool
+	}{
+		{"1.2.3", "v1.2.3", 0, true},
+		{"v1.10.0", "v1.9.9", 1, true},
+		{"0.9.0", "1.0.0", -1, true},
+		{"dev", "1.0.0", 0, false},
+	}
+	for _, tt := range tests {
+		got, ok := CompareVersions(tt.a, tt.b)
+		if got != tt.want || ok != tt.ok {
+			t.Errorf("CompareVersions(%q, %q) = %d, %v; want %d, %v", tt.a, tt.b, got, ok, tt.want, tt.ok)
+		}
+	}
+}
+
 func TestValidate_MinimumVersionFormat(t *testing.T) {
 	cfg := &Config{
 		Service:        "test",
@@ -1270,20 +2504,219 @@ func TestValidate_BuilderSecretsFormat(t *testing.T) {
 		Servers: map[string]RoleConfig{
 			"web": {Hosts: []string{"localhost"}},
 		},
-		Proxy: ProxyConfig{Host: "test.example.com"},
-		SSH:   SSHConfig{Port: 22},
-		Builder: BuilderConfig{
-			Secrets: []string{"valid_secret", "bad secret!"},
+		Proxy: ProxyConfig{Host: "test.example.com"},
+		SSH:   SSHConfig{Port: 22},
+		Builder: BuilderConfig{
+			Secrets: []string{"valid_secret", "bad secret!"},
+		},
+	}
+
+	err := Validate(cfg)
+	if err == nil || !strings.Contains(err.Error(), "builder.secrets[1]") {
+		t.Fatalf("expected builder secrets validation error, got %v", err)
+	}
+}
+
+func TestValidate_BuilderSSHFormat(t *testing.T) {
+	cfg := &Config{
+		Service: "test",
+		Image:   "test:latest",
+		Servers: map[string]RoleConfig{
+			"web": {Hosts: []string{"localhost"}},
+		},
+		Proxy: ProxyConfig{Host: "test.example.com"},
+		SSH:   SSHConfig{Port: 22},
+		Builder: BuilderConfig{
+			SSH: []string{"default", "notaspec"},
+		},
+	}
+
+	err := Validate(cfg)
+	if err == nil || !strings.Contains(err.Error(), "builder.ssh[1]") {
+		t.Fatalf("expected builder SSH validation error, got %v", err)
+	}
+}
+
+func TestValidate_BuilderType(t *testing.T) {
+	base := func() *Config {
+		return &Config{
+			Service: "test",
+			Image:   "test:latest",
+			Servers: map[string]RoleConfig{
+				"web": {Hosts: []string{"localhost"}},
+			},
+			Proxy: ProxyConfig{Host: "test.example.com"},
+			SSH:   SSHConfig{Port: 22},
+		}
+	}
+
+	tests := []struct {
+		name    string
+		modify  func(*Config)
+		wantErr string
+	}{
+		{
+			name: "buildpacks",
+			modify: func(c *Config) {
+				c.Builder.Type = "buildpacks"
+				c.Builder.Pack = PackConfig{Builder: "heroku/builder:24", Env: map[string]string{"BP_NODE_VERSION": "20"}}
+			},
+		},
+		{
+			name:    "unknown type",
+			modify:  func(c *Config) { c.Builder.Type = "nix" },
+			wantErr: "builder.type",
+		},
+		{
+			name: "buildpacks multiarch",
+			modify: func(c *Config) {
+				c.Builder.Type = "buildpacks"
+				c.Builder.Platforms = []string{"linux/amd64", "linux/arm64"}
+			},
+			wantErr: "builder.type",
+		},
+		{
+			name: "invalid pack env name",
+			modify: func(c *Config) {
+				c.Builder.Type = "buildpacks"
+				c.Builder.Pack.Env = map[string]string{"BAD-NAME": "1"}
+			},
+			wantErr: "builder.pack.env.BAD-NAME",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := base()
+			tt.modify(cfg)
+			err := Validate(cfg)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("expected error containing %q, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestValidate_BuilderEngine(t *testing.T) {
+	tests := []struct {
+		name    string
+		builder BuilderConfig
+		wantErr bool
+	}{
+		{name: "default", builder: BuilderConfig{}},
+		{name: "docker", builder: BuilderConfig{Engine: "docker"}},
+		{name: "buildx multiarch", builder: BuilderConfig{Engine: "buildx", Platforms: []string{"linux/amd64", "linux/arm64"}}},
+		{name: "docker multiarch", builder: BuilderConfig{Engine: "docker", Multiarch: true}, wantErr: true},
+		{name: "unknown", builder: BuilderConfig{Engine: "kaniko"}, wantErr: true},
+		{name: "remote docker", builder: BuilderConfig{Engine: "docker", Remote: RemoteBuilderConfig{Host: "203.0.113.12"}}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &Config{
+				Service: "test",
+				Image:   "test:latest",
+				Servers: map[string]RoleConfig{
+					"web": {Hosts: []string{"localhost"}},
+				},
+				Proxy:   ProxyConfig{Host: "test.example.com"},
+				SSH:     SSHConfig{Port: 22},
+				Builder: tt.builder,
+			}
+			err := Validate(cfg)
+			if tt.wantErr {
+				if err == nil || !strings.Contains(err.Error(), "builder.engine") {
+					t.Fatalf("expected builder.engine error, got %v", err)
+				}
+			} else if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+		})
+	}
+}
+
+func TestValidate_ParallelRemoteBuilders(t *testing.T) {
+	tests := []struct {
+		name     string
+		builder  BuilderConfig
+		wantErrs []string
+	}{
+		{
+			name: "valid",
+			builder: BuilderConfig{Remote: RemoteBuilderConfig{Builders: []RemoteBuilderConfig{
+				{Host: "203.0.113.12", Arch: "amd64"},
+				{Host: "203.0.113.13", Arch: "arm64"},
+			}}},
+		},
+		{
+			name: "missing and duplicate arch",
+			builder: BuilderConfig{Remote: RemoteBuilderConfig{Builders: []RemoteBuilderConfig{
+				{Host: "203.0.113.12", Arch: "amd64"},
+				{Host: "203.0.113.13"},
+				{Host: "203.0.113.14", Arch: "amd64"},
+			}}},
+			wantErrs: []string{"builder.remote[1].arch", "builder.remote[2].arch"},
+		},
+		{
+			name: "platforms conflict",
+			builder: BuilderConfig{
+				Platforms: []string{"linux/amd64"},
+				Remote: RemoteBuilderConfig{Builders: []RemoteBuilderConfig{
+					{Host: "203.0.113.12", Arch: "amd64"},
+					{Host: "203.0.113.13", Arch: "arm64"},
+				}},
+			},
+			wantErrs: []string{"builder.remote:"},
+		},
+		{
+			name: "invalid host",
+			builder: BuilderConfig{Remote: RemoteBuilderConfig{Builders: []RemoteBuilderConfig{
+				{Host: "bad host", Arch: "amd64"},
+				{Host: "203.0.113.13", Arch: "arm64"},
+			}}},
+			wantErrs: []string{"builder.remote[0].host"},
 		},
 	}
 
-	err := Validate(cfg)
-	if err == nil || !strings.Contains(err.Error(), "builder.secrets[1]") {
-		t.Fatalf("expected builder secrets validation error, got %v", err)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.builder.Remote.Host = tt.builder.Remote.Builders[0].Host
+			cfg := &Config{
+				Service: "test",
+				Image:   "test:latest",
+				Servers: map[string]RoleConfig{
+					"web": {Hosts: []string{"localhost"}},
+				},
+				Proxy:   ProxyConfig{Host: "test.example.com"},
+				SSH:     SSHConfig{Port: 22},
+				Builder: tt.builder,
+			}
+			err := Validate(cfg)
+			if len(tt.wantErrs) == 0 {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil {
+				t.Fatalf("expected errors %v, got nil", tt.wantErrs)
+			}
+			for _, want := range tt.wantErrs {
+				if !strings.Contains(err.Error(), want) {
+					t.Errorf("expected %q in %v", want, err)
+				}
+			}
+		})
 	}
 }
 
-func TestValidate_BuilderSSHFormat(t *testing.T) {
+func TestValidate_BuilderSBOMFormat(t *testing.T) {
 	cfg := &Config{
 		Service: "test",
 		Image:   "test:latest",
@@ -1293,13 +2726,19 @@ func TestValidate_BuilderSSHFormat(t *testing.T) {
 		Proxy: ProxyConfig{Host: "test.example.com"},
 		SSH:   SSHConfig{Port: 22},
 		Builder: BuilderConfig{
-			SSH: []string{"default", "notaspec"},
+			SBOM:       true,
+			SBOMFormat: "syft-table",
 		},
 	}
 
 	err := Validate(cfg)
-	if err == nil || !strings.Contains(err.Error(), "builder.ssh[1]") {
-		t.Fatalf("expected builder SSH validation error, got %v", err)
+	if err == nil || !strings.Contains(err.Error(), "builder.sbom_format") {
+		t.Fatalf("expected builder SBOM format validation error, got %v", err)
+	}
+
+	cfg.Builder.SBOMFormat = "cyclonedx-json"
+	if err := Validate(cfg); err != nil {
+		t.Fatalf("expected cyclonedx-json to be valid, got %v", err)
 	}
 }
 
@@ -1460,3 +2899,413 @@ func TestValidate_RoleNameInjection(t *testing.T) {
 		t.Fatalf("expected role name validation error, got %v", err)
 	}
 }
+
+func TestValidate_ProxyHostsRole(t *testing.T) {
+	tests := []struct {
+		name    string
+		mutate  func(*Config)
+		wantErr string
+	}{
+		{name: "valid tier", mutate: func(cfg *Config) {}},
+		{name: "unknown role", mutate: func(cfg *Config) { cfg.Proxy.HostsRole = "edge" }, wantErr: "proxy.hosts_role"},
+		{name: "web role", mutate: func(cfg *Config) { cfg.Proxy.HostsRole = "web" }, wantErr: "dedicated load-balancer role"},
+		{name: "canary", mutate: func(cfg *Config) { cfg.Deploy.Canary.Enabled = true }, wantErr: "deploy.canary.enabled"},
+		{
+			name:    "private address for unknown host",
+			mutate:  func(cfg *Config) { cfg.Proxy.PrivateAddresses = map[string]string{"203.0.113.50": "10.0.0.50"} },
+			wantErr: "not a web role host",
+		},
+		{
+			name:    "static paths",
+			mutate:  func(cfg *Config) { cfg.Proxy.Static = []StaticConfig{{Path: "/assets", Dir: "/app/public/assets"}} },
+			wantErr: "proxy.static",
+		},
+		{
+			name:    "invalid private address",
+			mutate:  func(cfg *Config) { cfg.Proxy.PrivateAddresses = map[string]string{"localhost": "10.0.0.1;reboot"} },
+			wantErr: "invalid private address",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := baseValidConfig()
+			cfg.Servers["lb"] = RoleConfig{Hosts: []string{"lb.internal"}}
+			cfg.Proxy.HostsRole = "lb"
+			cfg.Proxy.PrivateAddresses = map[string]string{"localhost": "10.0.0.10"}
+			tt.mutate(cfg)
+			err := Validate(cfg)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("unexpected validation error: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("expected error containing %q, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestValidate_WireGuardMesh(t *testing.T) {
+	cfg := baseValidConfig()
+	cfg.Servers["web"] = RoleConfig{Hosts: []string{"203.0.113.20", "203.0.113.10"}}
+	cfg.Network = NetworkConfig{WireGuard: true, Subnet: "10.77.0.0/24", Port: DefaultWireGuardPort}
+	if err := Validate(cfg); err != nil {
+		t.Fatalf("unexpected validation error: %v", err)
+	}
+
+	if got := cfg.MeshAddress("203.0.113.10"); got != "10.77.0.1" {
+		t.Fatalf("MeshAddress(first sorted host) = %q, want 10.77.0.1", got)
+	}
+	if got := cfg.PrivateAddress("203.0.113.20"); got != "10.77.0.2" {
+		t.Fatalf("PrivateAddress should fall back to the mesh address, got %q", got)
+	}
+
+	cfg.Network.Subnet = "10.77.0.0/31"
+	if err := Validate(cfg); err == nil || !strings.Contains(err.Error(), "network.subnet") {
+		t.Fatalf("expected undersized subnet error, got %v", err)
+	}
+
+	cfg.Network.Subnet = "fd00::/64"
+	if err := Validate(cfg); err == nil || !strings.Contains(err.Error(), "IPv4") {
+		t.Fatalf("expected IPv4 subnet error, got %v", err)
+	}
+}
+
+func TestMeshAddressesPinnedSurviveNewHosts(t *testing.T) {
+	cfg := baseValidConfig()
+	cfg.Servers["web"] = RoleConfig{Hosts: []string{"203.0.113.20", "203.0.113.30"}}
+	cfg.Network = NetworkConfig{WireGuard: true, Subnet: "10.77.0.0/24", Port: DefaultWireGuardPort}
+	before, _, err := cfg.MeshAddresses()
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg.Network.Addresses = before
+
+	// 203.0.113.10 sorts first, so unpinned it would take 10.77.0.1.
+	cfg.Servers["web"] = RoleConfig{Hosts: []string{"203.0.113.20", "203.0.113.30", "203.0.113.10"}}
+	after, _, err := cfg.MeshAddresses()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for host, address := range before {
+		if after[host] != address {
+			t.Errorf("%s moved from %s to %s after adding a host", host, address, after[host])
+		}
+	}
+	if got := after["203.0.113.10"]; got != "10.77.0.3" {
+		t.Errorf("new host got %s, want the lowest free address 10.77.0.3", got)
+	}
+	if problems := Check(cfg); !slices.ContainsFunc(problems, func(p ValidationError) bool {
+		return p.Field == "network.addresses" && p.Severity == SeverityWarning && strings.Contains(p.Message, "203.0.113.10")
+	}) {
+		t.Errorf("expected an unpinned-address warning naming the new host, got %v", problems)
+	}
+}
+
+func TestValidate_MeshAddresses(t *testing.T) {
+	tests := []struct {
+		name      string
+		addresses map[string]string
+		wantField string
+	}{
+		{name: "outside subnet", addresses: map[string]string{"203.0.113.10": "10.78.0.1"}, wantField: "network.addresses.203.0.113.10"},
+		{name: "network address", addresses: map[string]string{"203.0.113.10": "10.77.0.0"}, wantField: "network.addresses.203.0.113.10"},
+		{name: "duplicate", addresses: map[string]string{"203.0.113.10": "10.77.0.5", "203.0.113.20": "10.77.0.5"}, wantField: "network.addresses.203.0.113.20"},
+		{name: "unknown host", addresses: map[string]string{"198.51.100.1": "10.77.0.9"}, wantField: "network.addresses.198.51.100.1"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := baseValidConfig()
+			cfg.Servers["web"] = RoleConfig{Hosts: []string{"203.0.113.20", "203.0.113.10"}}
+			cfg.Network = NetworkConfig{WireGuard: true, Subnet: "10.77.0.0/24", Port: DefaultWireGuardPort, Addresses: tt.addresses}
+			if problems := Check(cfg); !slices.ContainsFunc(problems, func(p ValidationError) bool {
+				return p.Field == tt.wantField && p.Severity == SeverityError
+			}) {
+				t.Fatalf("expected an error on %s, got %v", tt.wantField, problems)
+			}
+		})
+	}
+}
+
+func TestValidate_PodmanNetworks(t *testing.T) {
+	tests := []struct {
+		name    string
+		mutate  func(*Config)
+		wantErr string
+	}{
+		{name: "valid isolation", mutate: func(cfg *Config) {}},
+		{
+			name: "undefined network",
+			mutate: func(cfg *Config) {
+				cfg.Servers["web"] = RoleConfig{Hosts: []string{"localhost"}, Networks: []string{"azud", "cache"}}
+			},
+			wantErr: "network cache is not defined",
+		},
+		{
+			name: "web role leaves azud",
+			mutate: func(cfg *Config) {
+				cfg.Servers["web"] = RoleConfig{Hosts: []string{"localhost"}, Networks: []string{"backend"}}
+			},
+			wantErr: "must stay on the azud network",
+		},
+		{
+			name: "published port on internal network",
+			mutate: func(cfg *Config) {
+				db := cfg.Accessories["db"]
+				db.Port = "5432:5432"
+				cfg.Accessories["db"] = db
+			},
+			wantErr: "accessories.db.port",
+		},
+		{
+			name: "gateway outside subnet",
+			mutate: func(cfg *Config) {
+				cfg.Podman.Networks["backend"] = PodmanNetworkConfig{Subnet: "10.90.0.0/24", Gateway: "10.91.0.1"}
+			},
+			wantErr: "is not an address in",
+		},
+		{
+			name:    "internal azud",
+			mutate:  func(cfg *Config) { cfg.Podman.Networks["azud"] = PodmanNetworkConfig{Internal: true} },
+			wantErr: "cannot be internal",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := baseValidConfig()
+			cfg.Podman.Networks = map[string]PodmanNetworkConfig{
+				"backend": {Subnet: "10.90.0.0/24", Internal: true},
+			}
+			cfg.Servers["web"] = RoleConfig{Hosts: []string{"localhost"}, Networks: []string{"azud", "backend"}}
+			cfg.Accessories = map[string]AccessoryConfig{
+				"db": {Image: "postgres:16", Host: "localhost", Networks: []string{"backend"}},
+			}
+			tt.mutate(cfg)
+			err := Validate(cfg)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("unexpected validation error: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("expected error containing %q, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestValidate_SecretsStorage(t *testing.T) {
+	cfg := baseValidConfig()
+	cfg.SecretsStorage = SecretsStoragePodman
+	cfg.Env.Secret = []string{"DATABASE_URL"}
+	if err := Validate(cfg); err != nil {
+		t.Fatalf("unexpected validation error: %v", err)
+	}
+
+	cfg.Env.Secret = []string{"DATABASE-URL"}
+	if err := Validate(cfg); err == nil || !strings.Contains(err.Error(), "env.secret[0]") {
+		t.Fatalf("expected invalid secret name error, got %v", err)
+	}
+
+	cfg.SecretsStorage = "vault"
+	if err := Validate(cfg); err == nil || !strings.Contains(err.Error(), "secrets_storage") {
+		t.Fatalf("expected secrets_storage error, got %v", err)
+	}
+}
+
+func TestValidate_Aliases(t *testing.T) {
+	cfg := baseValidConfig()
+	cfg.Aliases = map[string]string{
+		"console": `app exec --role web -- "bin/rails console"`,
+		"logs-db": "accessory logs db",
+	}
+	if err := Validate(cfg); err != nil {
+		t.Fatalf("expected valid aliases, got %v", err)
+	}
+
+	for name, expansion := range map[string]string{
+		"Console": "app details",
+		"loop":    "loop --verbose",
+		"empty":   "  ",
+		"quote":   `app exec "unterminated`,
+	} {
+		cfg := baseValidConfig()
+		cfg.Aliases = map[string]string{name: expansion}
+		err := Validate(cfg)
+		if err == nil || !strings.Contains(err.Error(), "aliases."+name) {
+			t.Errorf("alias %s=%q: expected aliases.%s error, got %v", name, expansion, name, err)
+		}
+	}
+}
+
+func TestValidate_Registries(t *testing.T) {
+	cfg := &Config{
+		Service: "test",
+		Image:   "test:latest",
+		Servers: map[string]RoleConfig{
+			"web": {Hosts: []string{"localhost"}},
+		},
+		Proxy:    ProxyConfig{Host: "test.example.com"},
+		SSH:      SSHConfig{Port: 22},
+		Registry: RegistryConfig{Server: "ghcr.io", Username: "acme", Password: []string{"GHCR_TOKEN"}},
+		Registries: map[string]RegistryConfig{
+			"docker.io":           {Username: "acme", Password: []string{"DOCKERHUB_TOKEN"}},
+			"registry.local:5000": {Username: "ci", Password: []string{"LOCAL_REGISTRY_PASSWORD"}},
+		},
+	}
+	if err := Validate(cfg); err != nil {
+		t.Fatalf("expected registries to be valid, got %v", err)
+	}
+
+	tests := []struct {
+		name     string
+		server   string
+		registry RegistryConfig
+		want     string
+	}{
+		{"invalid port", "registry.local:99999", RegistryConfig{Username: "ci", Password: []string{"TOKEN"}}, "invalid registry server"},
+		{"path in key", "ghcr.io/acme", RegistryConfig{Username: "ci", Password: []string{"TOKEN"}}, "invalid registry server"},
+		{"server field", "quay.io", RegistryConfig{Server: "quay.io", Username: "ci", Password: []string{"TOKEN"}}, "registries.quay.io.server"},
+		{"missing username", "quay.io", RegistryConfig{Password: []string{"TOKEN"}}, "registries.quay.io.username"},
+		{"missing password", "quay.io", RegistryConfig{Username: "ci"}, "registries.quay.io.password"},
+		{"invalid secret", "quay.io", RegistryConfig{Username: "ci", Password: []string{"bad-name"}}, "invalid secret name"},
+		{"duplicates registry", "https://GHCR.io", RegistryConfig{Username: "ci", Password: []string{"TOKEN"}}, "also configured in registry"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg.Registries = map[string]RegistryConfig{tt.server: tt.registry}
+			err := Validate(cfg)
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Fatalf("expected %q validation error, got %v", tt.want, err)
+			}
+		})
+	}
+}
+
+func TestValidate_RegistryHelper(t *testing.T) {
+	cfg := &Config{
+		Service: "test",
+		Image:   "123456789012.dkr.ecr.us-east-1.amazonaws.com/app:latest",
+		Servers: map[string]RoleConfig{
+			"web": {Hosts: []string{"localhost"}},
+		},
+		Proxy:    ProxyConfig{Host: "test.example.com"},
+		SSH:      SSHConfig{Port: 22},
+		Registry: RegistryConfig{Server: "123456789012.dkr.ecr.us-east-1.amazonaws.com", Username: "AWS", Helper: "ecr"},
+		Registries: map[string]RegistryConfig{
+			"europe-docker.pkg.dev": {Username: "oauth2accesstoken", Helper: "gcr"},
+		},
+	}
+	if err := Validate(cfg); err != nil {
+		t.Fatalf("expected registry helpers to be valid, got %v", err)
+	}
+
+	cfg.Registry.Password = []string{"ECR_PASSWORD"}
+	if err := Validate(cfg); err == nil || !strings.Contains(err.Error(), "cannot be combined with helper") {
+		t.Fatalf("expected helper/password conflict, got %v", err)
+	}
+	cfg.Registry.Password = nil
+
+	cfg.Registries["europe-docker.pkg.dev"] = RegistryConfig{Username: "x", Helper: "acr"}
+	if err := Validate(cfg); err == nil || !strings.Contains(err.Error(), "registries.europe-docker.pkg.dev.helper") {
+		t.Fatalf("expected helper/server mismatch, got %v", err)
+	}
+	delete(cfg.Registries, "europe-docker.pkg.dev")
+
+	cfg.Registry.Server = ""
+	if err := Validate(cfg); err == nil || !strings.Contains(err.Error(), "server is required with registry.helper") {
+		t.Fatalf("expected missing server error, got %v", err)
+	}
+}
+
+func TestValidate_RequireDigest(t *testing.T) {
+	cfg := &Config{
+		Service: "test",
+		Image:   "test:latest",
+		Servers: map[string]RoleConfig{
+			"web": {Hosts: []string{"localhost"}},
+		},
+		Proxy:  ProxyConfig{Host: "test.example.com"},
+		SSH:    SSHConfig{Port: 22},
+		Deploy: DeployConfig{RequireDigest: true},
+	}
+	if err := Validate(cfg); err != nil {
+		t.Fatalf("expected require_digest to be valid, got %v", err)
+	}
+
+	cfg.Deploy.AllowUnverifiedImage = true
+	if err := Validate(cfg); err == nil || !strings.Contains(err.Error(), "deploy.require_digest") {
+		t.Fatalf("expected require_digest/allow_unverified_image conflict, got %v", err)
+	}
+}
+
+func TestValidate_RegistryOAuth(t *testing.T) {
+	cfg := &Config{
+		Service: "test",
+		Image:   "ghcr.io/acme/app:latest",
+		Servers: map[string]RoleConfig{
+			"web": {Hosts: []string{"localhost"}},
+		},
+		Proxy: ProxyConfig{Host: "test.example.com"},
+		SSH:   SSHConfig{Port: 22},
+		Registry: RegistryConfig{
+			Server:   "ghcr.io",
+			Username: "acme",
+			Password: []string{"GHCR_TOKEN"},
+			OAuth: &RegistryOAuthConfig{
+				DeviceAuthorizationURL: "https://github.com/login/device/code",
+				TokenURL:               "https://github.com/login/oauth/access_token",
+				ClientID:               "Iv1.abc",
+			},
+		},
+	}
+	if err := Validate(cfg); err != nil {
+		t.Fatalf("expected registry oauth to be valid, got %v", err)
+	}
+
+	cfg.Registry.OAuth.TokenURL = "http://github.com/login/oauth/access_token"
+	cfg.Registry.OAuth.ClientID = ""
+	cfg.Registry.Password = nil
+	err := Validate(cfg)
+	for _, field := range []string{"registry.oauth.token_url", "registry.oauth.client_id", "registry.password"} {
+		if err == nil || !strings.Contains(err.Error(), field) {
+			t.Errorf("expected %s validation error, got %v", field, err)
+		}
+	}
+}
+
+func TestValidate_AppSocket(t *testing.T) {
+	newConfig := func(socket string) *Config {
+		return &Config{
+			Service: "test",
+			Image:   "test:latest",
+			Servers: map[string]RoleConfig{
+				"web": {Hosts: []string{"localhost"}},
+			},
+			Proxy: ProxyConfig{Host: "test.example.com", AppSocket: socket},
+			SSH:   SSHConfig{Port: 22},
+		}
+	}
+
+	if err := Validate(newConfig("/run/app/app.sock")); err != nil {
+		t.Fatalf("expected app_socket to be valid, got %v", err)
+	}
+
+	for _, socket := range []string{"app.sock", "/app.sock", "/run/../app.sock", "/run/app/" + strings.Repeat("s", 80) + ".sock"} {
+		if err := Validate(newConfig(socket)); err == nil || !strings.Contains(err.Error(), "proxy.app_socket") {
+			t.Errorf("expected app_socket %q to be rejected, got %v", socket, err)
+		}
+	}
+
+	cfg := newConfig("/run/app/app.sock")
+	cfg.Servers["lb"] = RoleConfig{Hosts: []string{"lb1"}}
+	cfg.Proxy.HostsRole = "lb"
+	if err := Validate(cfg); err == nil || !strings.Contains(err.Error(), "cannot be combined with proxy.hosts_role") {
+		t.Fatalf("expected app_socket/hosts_role conflict, got %v", err)
+	}
+}
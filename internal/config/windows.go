@@ -0,0 +1,146 @@
+package config
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// DeployWindowsConfig restricts deploys to allowed days and hours. Outside
+// them a deploy needs --override.
+type DeployWindowsConfig struct {
+	// IANA time zone the windows are read in. Default: the local time zone.
+	Timezone string `yaml:"timezone"`
+
+	// Windows deploys are allowed in; empty allows any time
+	Allowed []DeployWindow `yaml:"allowed"`
+}
+
+// DeployWindow is a range of hours on some days of the week.
+type DeployWindow struct {
+	// Weekdays: mon, tue, wed, thu, fri, sat, sun. Empty means every day.
+	Days []string `yaml:"days"`
+
+	// Hours as "HH:MM-HH:MM", end exclusive and up to 24:00. Empty means
+	// the whole day.
+	Hours string `yaml:"hours"`
+}
+
+var windowWeekdays = map[string]time.Weekday{
+	"sun": time.Sunday,
+	"mon": time.Monday,
+	"tue": time.Tuesday,
+	"wed": time.Wednesday,
+	"thu": time.Thursday,
+	"fri": time.Friday,
+	"sat": time.Saturday,
+}
+
+// Location returns the time zone the windows are read in.
+func (w DeployWindowsConfig) Location() (*time.Location, error) {
+	if w.Timezone == "" {
+		return time.Local, nil
+	}
+	loc, err := time.LoadLocation(w.Timezone)
+	if err != nil {
+		return nil, fmt.Errorf("invalid deploy.windows.timezone %q: %w", w.Timezone, err)
+	}
+	return loc, nil
+}
+
+// Allows reports whether t falls in an allowed window. Without windows
+// every time is allowed.
+func (w DeployWindowsConfig) Allows(t time.Time) (bool, error) {
+	if len(w.Allowed) == 0 {
+		return true, nil
+	}
+	loc, err := w.Location()
+	if err != nil {
+		return false, err
+	}
+	t = t.In(loc)
+	minute := t.Hour()*60 + t.Minute()
+	for _, window := range w.Allowed {
+		start, end, err := parseWindowHours(window.Hours)
+		if err != nil {
+			return false, err
+		}
+		if window.matchesDay(t.Weekday()) && minute >= start && minute < end {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// String describes the windows, as in "mon,tue 09:00-17:00; fri 09:00-12:00
+// (Europe/Berlin)".
+func (w DeployWindowsConfig) String() string {
+	parts := make([]string, 0, len(w.Allowed))
+	for _, window := range w.Allowed {
+		parts = append(parts, window.String())
+	}
+	zone := w.Timezone
+	if zone == "" {
+		zone = "local time"
+	}
+	return fmt.Sprintf("%s (%s)", strings.Join(parts, "; "), zone)
+}
+
+func (w DeployWindow) String() string {
+	days := "every day"
+	if len(w.Days) > 0 {
+		days = strings.Join(w.Days, ",")
+	}
+	hours := w.Hours
+	if hours == "" {
+		hours = "00:00-24:00"
+	}
+	return days + " " + hours
+}
+
+func (w DeployWindow) matchesDay(day time.Weekday) bool {
+	if len(w.Days) == 0 {
+		return true
+	}
+	for _, name := range w.Days {
+		if windowWeekdays[strings.ToLower(name)] == day {
+			return true
+		}
+	}
+	return false
+}
+
+// parseWindowHours returns the minutes of the day a window starts and ends.
+func parseWindowHours(hours string) (int, int, error) {
+	if hours == "" {
+		return 0, 24 * 60, nil
+	}
+	from, to, ok := strings.Cut(hours, "-")
+	if !ok {
+		return 0, 0, fmt.Errorf("invalid hours %q (expected HH:MM-HH:MM)", hours)
+	}
+	start, err := parseWindowTime(from)
+	if err != nil {
+		return 0, 0, fmt.Errorf("invalid hours %q: %w", hours, err)
+	}
+	end, err := parseWindowTime(to)
+	if err != nil {
+		return 0, 0, fmt.Errorf("invalid hours %q: %w", hours, err)
+	}
+	if end <= start {
+		return 0, 0, fmt.Errorf("invalid hours %q: the window must end after it starts; split windows that cross midnight", hours)
+	}
+	return start, end, nil
+}
+
+func parseWindowTime(value string) (int, error) {
+	value = strings.TrimSpace(value)
+	if value == "24:00" {
+		return 24 * 60, nil
+	}
+	t, err := time.Parse("15:04", value)
+	if err != nil {
+		return 0, fmt.Errorf("%q is not a time of day", value)
+	}
+	return t.Hour()*60 + t.Minute(), nil
+}
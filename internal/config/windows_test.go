@@ -0,0 +1,78 @@
+package config
+
+import (
+	"testing"
+	"time"
+)
+
+func TestDeployWindowsAllows(t *testing.T) {
+	windows := DeployWindowsConfig{
+		Timezone: "Europe/Berlin",
+		Allowed: []DeployWindow{
+			{Days: []string{"mon", "tue", "wed", "thu"}, Hours: "09:00-17:00"},
+			{Days: []string{"Fri"}, Hours: "09:00-12:00"},
+			{Days: []string{"sat"}},
+		},
+	}
+	tests := []struct {
+		name string
+		at   string
+		want bool
+	}{
+		{"thursday afternoon", "2026-10-15T14:30:00Z", true},               // 16:30 in Berlin
+		{"thursday after hours", "2026-10-15T15:00:00Z", false},            // 17:00 in Berlin
+		{"friday morning", "2026-10-16T08:00:00Z", true},                   // 10:00 in Berlin
+		{"friday evening", "2026-10-16T16:00:00Z", false},                  // 18:00 in Berlin
+		{"saturday all day", "2026-10-17T21:59:00Z", true},                 // 23:59 in Berlin
+		{"sunday", "2026-10-18T10:00:00Z", false},                          // 12:00 in Berlin
+		{"monday before hours in UTC terms", "2026-10-19T07:30:00Z", true}, // 09:30 in Berlin
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			at, err := time.Parse(time.RFC3339, tt.at)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got, err := windows.Allows(at)
+			if err != nil {
+				t.Fatalf("Allows() error: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("Allows(%s) = %v, want %v", tt.at, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDeployWindowsAllowsWithoutWindows(t *testing.T) {
+	allowed, err := DeployWindowsConfig{}.Allows(time.Now())
+	if err != nil || !allowed {
+		t.Fatalf("Allows() = %v, %v; want true", allowed, err)
+	}
+}
+
+func TestParseWindowHours(t *testing.T) {
+	tests := []struct {
+		hours      string
+		start, end int
+		wantErr    bool
+	}{
+		{"", 0, 24 * 60, false},
+		{"09:00-17:30", 9 * 60, 17*60 + 30, false},
+		{"18:00-24:00", 18 * 60, 24 * 60, false},
+		{"17:00-09:00", 0, 0, true},
+		{"9-17", 0, 0, true},
+		{"09:00", 0, 0, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.hours, func(t *testing.T) {
+			start, end, err := parseWindowHours(tt.hours)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseWindowHours(%q) error = %v, wantErr %v", tt.hours, err, tt.wantErr)
+			}
+			if !tt.wantErr && (start != tt.start || end != tt.end) {
+				t.Errorf("parseWindowHours(%q) = (%d, %d), want (%d, %d)", tt.hours, start, end, tt.start, tt.end)
+			}
+		})
+	}
+}
@@ -0,0 +1,145 @@
+package deploy
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// DeployPlan describes the changes a deployment is about to make. It is shown
+// before deploy.require_approval pauses and sent to the approval webhook.
+type DeployPlan struct {
+	DeploymentID    string           `json:"deployment_id"`
+	Service         string           `json:"service"`
+	Destination     string           `json:"destination,omitempty"`
+	Image           string           `json:"image"`
+	Version         string           `json:"version"`
+	PreviousVersion string           `json:"previous_version,omitempty"`
+	Targets         []TargetProgress `json:"targets"`
+	Performer       string           `json:"performer"`
+	Resumed         bool             `json:"resumed,omitempty"`
+}
+
+// Approver confirms a deployment plan and returns who approved it.
+type Approver func(plan *DeployPlan) (string, error)
+
+// approvalResponse is the JSON body an approval webhook answers with.
+type approvalResponse struct {
+	Approved bool   `json:"approved"`
+	Approver string `json:"approver"`
+	Reason   string `json:"reason"`
+}
+
+// newDeployPlan builds the plan for the targets record has not completed.
+func newDeployPlan(record *DeploymentRecord) *DeployPlan {
+	return &DeployPlan{
+		DeploymentID:    record.ID,
+		Service:         record.Service,
+		Destination:     record.Destination,
+		Image:           record.Image,
+		Version:         record.Version,
+		PreviousVersion: record.PreviousVersion,
+		Targets:         record.PendingTargets(),
+		Performer:       CurrentUser(),
+		Resumed:         record.Metadata["resumed_at"] != "",
+	}
+}
+
+// approve holds the rollout until the plan is approved. A configured webhook
+// decides on its own; otherwise the caller's approver (a prompt or --yes)
+// does. The approver is recorded in the deployment's metadata.
+func (d *Deployer) approve(ctx context.Context, record *DeploymentRecord, opts *DeployOptions) error {
+	plan := newDeployPlan(record)
+	d.printPlan(plan)
+
+	var approver string
+	var err error
+	switch {
+	case d.cfg.Deploy.ApprovalWebhook != "":
+		d.log.Info("Waiting for approval from %s...", d.cfg.Deploy.ApprovalWebhook)
+		approver, err = requestWebhookApproval(ctx, d.cfg.Deploy.ApprovalWebhook, opts.ApprovalToken, d.cfg.Deploy.ApprovalTimeout, plan)
+	case opts.Approve != nil:
+		approver, err = opts.Approve(plan)
+	default:
+		err = fmt.Errorf("deploy.require_approval is set but no approval was given")
+	}
+	if err != nil {
+		return err
+	}
+
+	record.Metadata["approved_by"] = approver
+	d.log.Success("Deployment approved by %s", approver)
+	return nil
+}
+
+func (d *Deployer) printPlan(plan *DeployPlan) {
+	d.log.Info("Deployment plan for %s", plan.Service)
+	d.log.Println("  Image: %s", plan.Image)
+	if plan.Destination != "" {
+		d.log.Println("  Destination: %s", plan.Destination)
+	}
+	if plan.PreviousVersion != "" {
+		d.log.Println("  Replaces: %s", plan.PreviousVersion)
+	}
+	rows := make([][]string, 0, len(plan.Targets))
+	for _, target := range plan.Targets {
+		rows = append(rows, []string{target.Host, target.Role})
+	}
+	d.log.Table([]string{"Host", "Role"}, rows)
+}
+
+// requestWebhookApproval posts the plan to url and waits for a decision. The
+// token, when set, is sent as a bearer token so the webhook can validate an
+// approval issued out of band.
+func requestWebhookApproval(ctx context.Context, url, token string, timeout time.Duration, plan *DeployPlan) (string, error) {
+	body, err := json.Marshal(plan)
+	if err != nil {
+		return "", fmt.Errorf("failed to encode deployment plan: %w", err)
+	}
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return "", fmt.Errorf("invalid approval webhook: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("approval webhook request failed: %w", err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+	data, err := io.ReadAll(io.LimitReader(resp.Body, 64*1024))
+	if err != nil {
+		return "", fmt.Errorf("failed to read approval webhook response: %w", err)
+	}
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return "", fmt.Errorf("approval webhook returned %s: %s", resp.Status, strings.TrimSpace(string(data)))
+	}
+
+	var decision approvalResponse
+	if err := json.Unmarshal(data, &decision); err != nil {
+		return "", fmt.Errorf("invalid approval webhook response: %w", err)
+	}
+	if !decision.Approved {
+		if decision.Reason != "" {
+			return "", fmt.Errorf("deployment rejected by approval webhook: %s", decision.Reason)
+		}
+		return "", fmt.Errorf("deployment rejected by approval webhook")
+	}
+	if decision.Approver == "" {
+		decision.Approver = "approval webhook"
+	}
+	return decision.Approver, nil
+}
@@ -0,0 +1,94 @@
+package deploy
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/lemonity-org/azud/internal/config"
+	"github.com/lemonity-org/azud/internal/output"
+)
+
+func approvalTestRecord() *DeploymentRecord {
+	record := NewDeploymentRecord("shop", "example/shop:v2", "v2", "production", []string{"one"})
+	record.Targets = []TargetProgress{{Host: "one", Role: "web", Status: TargetPending}}
+	return record
+}
+
+func TestWebhookApprovalSendsPlanAndToken(t *testing.T) {
+	var gotPlan DeployPlan
+	var gotAuth string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotAuth = r.Header.Get("Authorization")
+		if err := json.NewDecoder(r.Body).Decode(&gotPlan); err != nil {
+			t.Errorf("decode plan: %v", err)
+		}
+		_, _ = io.WriteString(w, `{"approved":true,"approver":"alice"}`)
+	}))
+	defer server.Close()
+
+	d := &Deployer{
+		cfg: &config.Config{Deploy: config.DeployConfig{RequireApproval: true, ApprovalWebhook: server.URL, ApprovalTimeout: time.Minute}},
+		log: output.NewLogger(io.Discard, io.Discard, false),
+	}
+	record := approvalTestRecord()
+	opts := &DeployOptions{
+		ApprovalToken: "secret-token",
+		Approve: func(*DeployPlan) (string, error) {
+			t.Fatal("a configured webhook must decide instead of the local approver")
+			return "", nil
+		},
+	}
+	if err := d.approve(context.Background(), record, opts); err != nil {
+		t.Fatalf("approve: %v", err)
+	}
+	if gotAuth != "Bearer secret-token" {
+		t.Fatalf("authorization = %q", gotAuth)
+	}
+	if gotPlan.Image != "example/shop:v2" || gotPlan.Destination != "production" || len(gotPlan.Targets) != 1 {
+		t.Fatalf("plan = %+v", gotPlan)
+	}
+	if record.Metadata["approved_by"] != "alice" {
+		t.Fatalf("approved_by = %q", record.Metadata["approved_by"])
+	}
+}
+
+func TestWebhookApprovalRejection(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = io.WriteString(w, `{"approved":false,"reason":"change freeze"}`)
+	}))
+	defer server.Close()
+
+	_, err := requestWebhookApproval(context.Background(), server.URL, "", time.Minute, &DeployPlan{})
+	if err == nil || !strings.Contains(err.Error(), "change freeze") {
+		t.Fatalf("expected rejection reason, got %v", err)
+	}
+
+	failing := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "nope", http.StatusForbidden)
+	}))
+	defer failing.Close()
+	if _, err := requestWebhookApproval(context.Background(), failing.URL, "", time.Minute, &DeployPlan{}); err == nil {
+		t.Fatal("expected a non-2xx webhook response to fail")
+	}
+}
+
+func TestApprovalRequiresApprover(t *testing.T) {
+	d := &Deployer{
+		cfg: &config.Config{Deploy: config.DeployConfig{RequireApproval: true}},
+		log: output.NewLogger(io.Discard, io.Discard, false),
+	}
+	if err := d.approve(context.Background(), approvalTestRecord(), &DeployOptions{}); err == nil {
+		t.Fatal("expected approval without an approver to fail")
+	}
+	declined := &DeployOptions{Approve: func(*DeployPlan) (string, error) { return "", errors.New("deployment declined") }}
+	if err := d.approve(context.Background(), approvalTestRecord(), declined); err == nil {
+		t.Fatal("expected a declined plan to fail")
+	}
+}
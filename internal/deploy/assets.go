@@ -0,0 +1,91 @@
+package deploy
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/lemonity-org/azud/internal/config"
+	"github.com/lemonity-org/azud/internal/shell"
+	"github.com/lemonity-org/azud/internal/state"
+)
+
+// AssetsDir returns the host directory holding the asset directories of
+// asset_path bridging, or "" when asset_path is not set.
+func AssetsDir(cfg *config.Config) string {
+	if cfg.AssetPath == "" {
+		return ""
+	}
+	return state.Dir(cfg.SSH.User) + "/assets"
+}
+
+// bridgeAssets prepares the asset directory a new container of a proxy
+// role mounts at asset_path on host and returns it. The directory holds
+// image's assets plus those of the current container that image lacks, and
+// the current container's directory receives image's new files, so a page
+// rendered by either version finds its fingerprinted assets on both while
+// they share traffic.
+func (d *Deployer) bridgeAssets(host, image, oldContainer string, oldExists bool) (string, error) {
+	// Keys are random like socket directories, since container names are
+	// reused across renames.
+	newDir := AssetsDir(d.cfg) + "/" + d.cfg.Service + "/" + newAppSocketKey()
+	oldDir := ""
+	if oldExists {
+		source, err := d.containers.MountSource(host, oldContainer, d.cfg.AssetPath)
+		if err != nil {
+			return "", err
+		}
+		oldDir = source
+	} else {
+		oldContainer = ""
+	}
+
+	result, err := d.sshClient.Execute(host, assetBridgeScript(d.cfg, image, newDir, oldDir, oldContainer))
+	if err != nil {
+		return "", err
+	}
+	if result.ExitCode != 0 {
+		return "", fmt.Errorf("%s", strings.TrimSpace(result.Stderr))
+	}
+	return newDir, nil
+}
+
+// assetBridgeScript returns the shell script that extracts asset_path from
+// image into newDir and merges it with the assets of the current version:
+// those in oldDir, or, when the current container mounts no asset
+// directory yet, those inside oldContainer. Files are never overwritten,
+// so each version keeps its own copy of a name both use. An empty
+// oldContainer means there is no current version.
+func assetBridgeScript(cfg *config.Config, image, newDir, oldDir, oldContainer string) string {
+	// Files Podman copied out of a rootless container belong to the user
+	// namespace, which only podman unshare can write to.
+	files := ""
+	if cfg.Podman.Rootless {
+		files = "podman unshare "
+	}
+	source := shell.Quote(cfg.AssetPath + "/.")
+	dir := shell.QuoteRemotePath(newDir)
+
+	lines := []string{
+		"set -e",
+		"mkdir -p " + dir,
+		fmt.Sprintf("c=$(podman create --entrypoint true %s)", shell.Quote(image)),
+		fmt.Sprintf(`podman cp "$c":%s %s || { podman rm -f "$c" >/dev/null; exit 1; }`, source, dir),
+		`podman rm -f "$c" >/dev/null`,
+	}
+	switch {
+	case oldDir != "":
+		old := shell.QuoteRemotePath(oldDir)
+		lines = append(lines,
+			fmt.Sprintf("%scp -an %s/. %s/", files, old, dir),
+			fmt.Sprintf("%scp -an %s/. %s/", files, dir, old),
+		)
+	case oldContainer != "":
+		staging := shell.QuoteRemotePath(newDir + ".current")
+		lines = append(lines,
+			"mkdir -p "+staging,
+			fmt.Sprintf("if podman cp %s:%s %s; then %scp -an %s/. %s/; fi", shell.Quote(oldContainer), source, staging, files, staging, dir),
+			fmt.Sprintf("%srm -rf %s", files, staging),
+		)
+	}
+	return strings.Join(lines, "\n")
+}
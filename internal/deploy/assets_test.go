@@ -0,0 +1,66 @@
+package deploy
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/lemonity-org/azud/internal/config"
+)
+
+func TestAssetBridgeScript(t *testing.T) {
+	newDir := "${HOME}/.local/share/azud/assets/shop/b1"
+	tests := []struct {
+		name         string
+		rootless     bool
+		oldDir       string
+		oldContainer string
+		want         []string
+		absent       []string
+	}{
+		{
+			name: "first deploy",
+			want: []string{
+				"mkdir -p ${HOME}/.local/share/azud/assets/shop/b1",
+				"c=$(podman create --entrypoint true ghcr.io/acme/shop:v2)",
+				`podman cp "$c":/app/public/. ${HOME}/.local/share/azud/assets/shop/b1`,
+			},
+			absent: []string{"cp -an"},
+		},
+		{
+			name:         "bridges with the current asset directory",
+			oldDir:       "/home/deploy/.local/share/azud/assets/shop/a0",
+			oldContainer: "shop-web",
+			want: []string{
+				"cp -an /home/deploy/.local/share/azud/assets/shop/a0/. ${HOME}/.local/share/azud/assets/shop/b1/",
+				"cp -an ${HOME}/.local/share/azud/assets/shop/b1/. /home/deploy/.local/share/azud/assets/shop/a0/",
+			},
+			absent: []string{"podman cp shop-web"},
+		},
+		{
+			name:         "copies from a container without an asset directory",
+			rootless:     true,
+			oldContainer: "shop-web",
+			want: []string{
+				"if podman cp shop-web:/app/public/. ${HOME}/.local/share/azud/assets/shop/b1.current; then podman unshare cp -an",
+				"podman unshare rm -rf ${HOME}/.local/share/azud/assets/shop/b1.current",
+			},
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			cfg := &config.Config{AssetPath: "/app/public"}
+			cfg.Podman.Rootless = tc.rootless
+			script := assetBridgeScript(cfg, "ghcr.io/acme/shop:v2", newDir, tc.oldDir, tc.oldContainer)
+			for _, want := range tc.want {
+				if !strings.Contains(script, want) {
+					t.Errorf("script missing %q:\n%s", want, script)
+				}
+			}
+			for _, absent := range tc.absent {
+				if strings.Contains(script, absent) {
+					t.Errorf("script unexpectedly contains %q:\n%s", absent, script)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,114 @@
+package deploy
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/user"
+	"path/filepath"
+	"time"
+
+	"github.com/lemonity-org/azud/internal/state"
+)
+
+// AuditEntry records an operator action. Entries describe what changed and
+// where, never secret values.
+type AuditEntry struct {
+	Time    time.Time         `json:"time"`
+	Service string            `json:"service"`
+	Action  string            `json:"action"`
+	Actor   string            `json:"actor,omitempty"`
+	Status  string            `json:"status"`
+	Error   string            `json:"error,omitempty"`
+	Details map[string]string `json:"details,omitempty"`
+}
+
+// AuditLog is an append-only JSON Lines file in the local Azud state
+// directory (or AZUD_STATE_DIR), next to deployment history.
+type AuditLog struct {
+	path    string
+	initErr error
+}
+
+// NewAuditLog opens the audit log in the durable local state directory.
+func NewAuditLog() *AuditLog {
+	basePath, err := state.LocalDir()
+	if err != nil {
+		return &AuditLog{initErr: err}
+	}
+	return &AuditLog{path: filepath.Join(basePath, "audit.log")}
+}
+
+// Record appends an entry, filling in the time and local actor if unset.
+func (a *AuditLog) Record(entry AuditEntry) error {
+	if a.initErr != nil {
+		return fmt.Errorf("audit log unavailable: %w", a.initErr)
+	}
+	if entry.Time.IsZero() {
+		entry.Time = time.Now().UTC()
+	}
+	if entry.Actor == "" {
+		if u, err := user.Current(); err == nil {
+			entry.Actor = u.Username
+		}
+	}
+
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return fmt.Errorf("failed to marshal audit entry: %w", err)
+	}
+
+	dir := filepath.Dir(a.path)
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return fmt.Errorf("failed to create audit directory: %w", err)
+	}
+	return state.WithFileLock(filepath.Join(dir, ".audit.lock"), func() error {
+		file, err := os.OpenFile(a.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+		if err != nil {
+			return fmt.Errorf("failed to open audit log: %w", err)
+		}
+		if _, err := file.Write(append(data, '\n')); err != nil {
+			_ = file.Close()
+			return fmt.Errorf("failed to write audit log: %w", err)
+		}
+		if err := file.Sync(); err != nil {
+			_ = file.Close()
+			return fmt.Errorf("failed to sync audit log: %w", err)
+		}
+		return file.Close()
+	})
+}
+
+// Entries returns the recorded entries for service (all services when
+// empty) in the order they were written.
+func (a *AuditLog) Entries(service string) ([]AuditEntry, error) {
+	if a.initErr != nil {
+		return nil, fmt.Errorf("audit log unavailable: %w", a.initErr)
+	}
+	file, err := os.Open(a.path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to open audit log: %w", err)
+	}
+	defer func() { _ = file.Close() }()
+
+	var entries []AuditEntry
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		var entry AuditEntry
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+			continue
+		}
+		if service == "" || entry.Service == service {
+			entries = append(entries, entry)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read audit log: %w", err)
+	}
+	return entries, nil
+}
@@ -0,0 +1,46 @@
+package deploy
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestAuditLogAppendsEntriesWithoutValues(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv("AZUD_STATE_DIR", dir)
+
+	audit := NewAuditLog()
+	for _, service := range []string{"shop", "blog", "shop"} {
+		if err := audit.Record(AuditEntry{
+			Service: service,
+			Action:  "env.rotate",
+			Status:  "success",
+			Details: map[string]string{"key": "DATABASE_PASSWORD"},
+		}); err != nil {
+			t.Fatalf("Record: %v", err)
+		}
+	}
+
+	entries, err := audit.Entries("shop")
+	if err != nil {
+		t.Fatalf("Entries: %v", err)
+	}
+	if len(entries) != 2 || entries[0].Time.IsZero() || entries[0].Details["key"] != "DATABASE_PASSWORD" {
+		t.Fatalf("entries = %+v, want two timestamped shop rotations", entries)
+	}
+
+	path := filepath.Join(dir, "audit.log")
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatalf("stat audit log: %v", err)
+	}
+	if info.Mode().Perm() != 0600 {
+		t.Fatalf("audit log mode = %v, want 0600", info.Mode().Perm())
+	}
+	data, _ := os.ReadFile(path)
+	if got := strings.Count(string(data), "\n"); got != 3 {
+		t.Fatalf("audit log has %d lines, want 3 appended entries", got)
+	}
+}
@@ -53,6 +53,7 @@ type CanaryDeployer struct {
 	images     *podman.ImageManager
 	proxy      *proxy.Manager
 	history    *HistoryStore
+	freezes    *FreezeStore
 	log        *output.Logger
 	state      *CanaryState
 	stateMu    sync.RWMutex
@@ -76,6 +77,7 @@ func NewCanaryDeployer(cfg *config.Config, sshClient *ssh.Client, log *output.Lo
 		images:     podman.NewImageManager(podmanClient),
 		proxy:      proxyManager,
 		history:    NewDurableHistoryStore(cfg.Deploy.RetainHistory, log),
+		freezes:    NewFreezeStore(),
 		log:        log,
 		statePath:  statePath,
 		state: &CanaryState{
@@ -105,6 +107,12 @@ type CanaryDeployOptions struct {
 
 	// Destination environment
 	Destination string
+
+	// Deploy during a freeze or outside deploy.windows
+	Override bool
+
+	// Extra metadata recorded in deployment history
+	Metadata map[string]string
 }
 
 func (c *CanaryDeployer) Deploy(opts *CanaryDeployOptions) error {
@@ -129,6 +137,10 @@ func (c *CanaryDeployer) Deploy(opts *CanaryDeployOptions) error {
 		return fmt.Errorf("canary deployment already in progress, use 'promote' or 'rollback' first")
 	}
 
+	if _, err := enforceDeployWindow(c.cfg, c.log, c.freezes, opts.Destination, opts.Version, opts.Override); err != nil {
+		return err
+	}
+
 	c.state.Status = CanaryStatusDeploying
 	timer := c.log.NewTimer("Canary Deployment")
 	defer timer.Stop()
@@ -184,6 +196,13 @@ func (c *CanaryDeployer) Deploy(opts *CanaryDeployOptions) error {
 		return fmt.Errorf("initial canary weight must be between 1 and 99")
 	}
 
+	// The record identifies the deployment to the canary containers.
+	record := NewDeploymentRecord(c.cfg.Service, image, opts.Version, opts.Destination, hosts)
+	for key, value := range opts.Metadata {
+		record.Metadata[key] = value
+	}
+	stamp := deployStampEnv(record)
+
 	// Get current stable version
 	stableVersion := ""
 	if lastDeploy, err := c.history.GetLastSuccessful(c.cfg.Service); err == nil {
@@ -233,6 +252,10 @@ func (c *CanaryDeployer) Deploy(opts *CanaryDeployOptions) error {
 						cleanupErrors = append(cleanupErrors, fmt.Sprintf("%s restore stable traffic: proxy host is not configured", host))
 						safeToRemove = false
 					default:
+						if restoreErr := c.setABRoutes(host, ""); restoreErr != nil {
+							cleanupErrors = append(cleanupErrors, fmt.Sprintf("%s remove A/B routes: %v", host, restoreErr))
+							safeToRemove = false
+						}
 						if restoreErr := c.proxy.SetCanaryWeights(host, proxyHost, stableUpstream, 100, canaryUpstream, 0); restoreErr != nil {
 							cleanupErrors = append(cleanupErrors, fmt.Sprintf("%s restore stable traffic: %v", host, restoreErr))
 							safeToRemove = false
@@ -263,10 +286,26 @@ func (c *CanaryDeployer) Deploy(opts *CanaryDeployOptions) error {
 		return deployErr
 	}
 
+	progress := c.log.NewPhaseBoard(phasePull, phaseContainer, phaseHealth, phaseProxy)
+	for _, host := range hosts {
+		progress.Add(host, host)
+	}
+	defer progress.Close()
+
 	// Pull image on all hosts
 	if !opts.SkipPull {
 		c.log.Info("Pulling image on all hosts...")
-		errors := c.images.PullAll(hosts, image)
+		for _, host := range hosts {
+			progress.Start(host, phasePull)
+		}
+		_, errors := pullImage(c.cfg, c.images, c.log, hosts, image)
+		for _, host := range hosts {
+			if errors[host] != nil {
+				progress.Fail(host)
+			} else {
+				progress.Complete(host, phasePull)
+			}
+		}
 		if len(errors) > 0 {
 			c.state.Status = CanaryStatusNone
 			c.state.LastUpdated = time.Now()
@@ -286,84 +325,94 @@ func (c *CanaryDeployer) Deploy(opts *CanaryDeployOptions) error {
 	canaryContainerName := c.state.CanaryContainer
 
 	for _, host := range hosts {
-		phases := []output.Phase{
-			{Name: "Pull", Complete: !opts.SkipPull},
-			{Name: "Container", Complete: false},
-			{Name: "Health", Complete: false},
-			{Name: "Proxy", Complete: false},
+		fail := func(err error) error {
+			progress.Fail(host)
+			return cleanupTouched(err)
+		}
+		if opts.SkipPull {
+			progress.Skip(host, phasePull)
 		}
-		c.log.HostPhase(host, phases)
 
+		progress.Start(host, phaseContainer)
 		c.log.Host(host, "Deploying canary container...")
 		stableExists, err := c.containers.Exists(host, c.state.StableContainer)
 		if err != nil {
-			return cleanupTouched(fmt.Errorf("failed to inspect stable container on %s: %w", host, err))
+			return fail(fmt.Errorf("failed to inspect stable container on %s: %w", host, err))
 		}
 		if !stableExists {
-			return cleanupTouched(fmt.Errorf("stable container %s does not exist on %s", c.state.StableContainer, host))
+			return fail(fmt.Errorf("stable container %s does not exist on %s", c.state.StableContainer, host))
 		}
 
 		// Build container config
-		containerConfig := c.buildContainerConfig(image, canaryContainerName)
+		containerConfig := c.buildContainerConfig(image, canaryContainerName, host)
+		stampContainer(containerConfig, stamp)
 
 		// Start canary container
 		_, err = c.containers.Run(host, containerConfig)
 		if err != nil {
-			return cleanupTouched(fmt.Errorf("failed to start canary on %s: %w", host, err))
+			return fail(fmt.Errorf("failed to start canary on %s: %w", host, err))
 		}
 		touchedHosts = append(touchedHosts, host)
 
-		phases[1].Complete = true
-		c.log.HostPhase(host, phases)
+		progress.Complete(host, phaseContainer)
 
 		// Wait for readiness check
 		if !opts.SkipHealthCheck && HasReadinessProbe(c.cfg) {
+			progress.Start(host, phaseHealth)
 			c.log.Host(host, "Waiting for canary readiness check...")
 
 			if c.cfg.Deploy.ReadinessDelay > 0 {
-				time.Sleep(c.cfg.Deploy.ReadinessDelay)
+				if err := c.sshClient.Sleep(c.cfg.Deploy.ReadinessDelay); err != nil {
+					return fail(err)
+				}
 			}
 
 			if err := c.waitForHealthy(host, canaryContainerName); err != nil {
-				return cleanupTouched(fmt.Errorf("canary health check failed on %s: %w", host, err))
+				return fail(fmt.Errorf("canary health check failed on %s: %w", host, err))
 			}
+			progress.Complete(host, phaseHealth)
+		} else {
+			progress.Skip(host, phaseHealth)
 		}
 
-		phases[2].Complete = true
-		c.log.HostPhase(host, phases)
-
 		// Register canary with proxy at initial weight
 		canaryUpstream, err := c.upstreamAddr(host, canaryContainerName)
 		if err != nil {
-			return cleanupTouched(err)
+			return fail(err)
 		}
 		stableWeight := 100 - initialWeight
 
+		progress.Start(host, phaseProxy)
 		c.log.Host(host, "Registering canary with proxy (weight=%d%%, stable=%d%%)", initialWeight, stableWeight)
 
 		// Ensure the proxy container is running before admin API calls.
 		if err := c.proxy.Boot(host, newProxyConfigFromCfg(c.cfg)); err != nil {
-			return cleanupTouched(fmt.Errorf("failed to boot proxy on %s: %w", host, err))
+			return fail(fmt.Errorf("failed to boot proxy on %s: %w", host, err))
 		}
 		if err := c.proxy.EnsureConfig(host); err != nil {
-			return cleanupTouched(fmt.Errorf("failed to ensure proxy config on %s: %w", host, err))
+			return fail(fmt.Errorf("failed to ensure proxy config on %s: %w", host, err))
+		}
+		if err := syncStatic(c.sshClient, c.cfg, host, canaryContainerName); err != nil {
+			return fail(fmt.Errorf("failed to sync static files on %s: %w", host, err))
 		}
 
 		// Apply and verify the complete split atomically. Stock Caddy represents
 		// the ratio through repeated upstreams under its built-in random policy.
 		stableUpstream, err := c.upstreamAddr(host, c.cfg.Service)
 		if err != nil {
-			return cleanupTouched(fmt.Errorf("failed to resolve stable upstream on %s: %w", host, err))
+			return fail(fmt.Errorf("failed to resolve stable upstream on %s: %w", host, err))
 		}
 		proxyHost := c.proxyRouteHost()
 		if err := c.proxy.SetCanaryWeights(host, proxyHost, stableUpstream, stableWeight, canaryUpstream, initialWeight); err != nil {
-			return cleanupTouched(fmt.Errorf("failed to apply canary traffic split on %s: %w", host, err))
+			return fail(fmt.Errorf("failed to apply canary traffic split on %s: %w", host, err))
 		}
 		weightedHosts[host] = true
 		routedHosts[host] = true
+		if err := c.setABRoutes(host, canaryUpstream); err != nil {
+			return fail(fmt.Errorf("failed to route A/B tests to the canary on %s: %w", host, err))
+		}
 
-		phases[3].Complete = true
-		c.log.HostPhase(host, phases)
+		progress.Complete(host, phaseProxy)
 
 		c.log.HostSuccess(host, "Canary deployed successfully")
 	}
@@ -381,7 +430,6 @@ func (c *CanaryDeployer) Deploy(opts *CanaryDeployOptions) error {
 		fmt.Sprintf("stable (%s)", stableVersion))
 
 	// Record deployment
-	record := NewDeploymentRecord(c.cfg.Service, image, opts.Version, opts.Destination, hosts)
 	record.Metadata["type"] = "canary"
 	record.Metadata["weight"] = fmt.Sprintf("%d", initialWeight)
 	record.Complete()
@@ -462,11 +510,14 @@ func (c *CanaryDeployer) Promote() error {
 				return fmt.Errorf("failed to remove stable container on %s: %w", host, err)
 			}
 		}
+		if err := c.setABRoutes(host, ""); err != nil {
+			return fmt.Errorf("failed to remove A/B routes on %s: %w", host, err)
+		}
 
 		// In bridge mode, establish the final stable-name route while the
 		// canary's stable network alias still resolves, then remove the temp
 		// route. A rename can no longer invalidate the only working route.
-		if !c.cfg.UseHostPortUpstreams() {
+		if upstreamFollowsName(c.cfg) {
 			finalUpstream := fmt.Sprintf("%s:%d", c.state.StableContainer, c.cfg.Proxy.AppPort)
 			if err := c.proxy.AddUpstream(host, proxyHost, finalUpstream); err != nil {
 				return fmt.Errorf("failed to add final promoted upstream on %s: %w", host, err)
@@ -544,6 +595,10 @@ func (c *CanaryDeployer) Rollback() error {
 		}
 
 		// Restore stable to 100% and remove canary from selection atomically.
+		// A/B routes go first so no request is pinned to the canary.
+		if err := c.setABRoutes(host, ""); err != nil {
+			return fmt.Errorf("failed to remove A/B routes on %s: %w", host, err)
+		}
 		proxyHost := c.proxyRouteHost()
 		if err := c.proxy.SetCanaryWeights(host, proxyHost, stableUpstream, 100, canaryUpstream, 0); err != nil {
 			return fmt.Errorf("failed to restore stable traffic on %s: %w", host, err)
@@ -559,7 +614,7 @@ func (c *CanaryDeployer) Rollback() error {
 
 		// Stop and remove canary container
 		c.log.Host(host, "Removing canary container...")
-		stopTimeout := c.cfg.Deploy.GetStopTimeout()
+		stopTimeout := c.cfg.RoleStopTimeout("web")
 		if err := c.containers.Stop(host, c.state.CanaryContainer, stopTimeout); err != nil {
 			return fmt.Errorf("failed to stop canary container on %s: %w", host, err)
 		}
@@ -672,23 +727,34 @@ func (c *CanaryDeployer) SetWeight(weight int) error {
 }
 
 func (c *CanaryDeployer) upstreamAddr(host, name string) (string, error) {
-	if !c.cfg.UseHostPortUpstreams() {
-		return fmt.Sprintf("%s:%d", name, c.cfg.Proxy.AppPort), nil
-	}
-
-	port, err := c.containers.HostPort(host, name, c.cfg.Proxy.AppPort)
-	if err != nil {
-		return "", fmt.Errorf("failed to resolve host port for %s on %s: %w", name, host, err)
-	}
-	return fmt.Sprintf("127.0.0.1:%d", port), nil
+	return ResolveUpstream(c.cfg, c.containers, host, name)
 }
 
 func (c *CanaryDeployer) proxyRouteHost() string {
 	return c.cfg.Proxy.PrimaryHost()
 }
 
-func (c *CanaryDeployer) buildContainerConfig(image, name string) *podman.ContainerConfig {
-	return newAppContainerConfig(c.cfg, image, name, map[string]string{
+// setABRoutes sends the requests selected by proxy.ab_tests on host to the
+// canary upstream, or back to the weighted route when upstream is empty.
+func (c *CanaryDeployer) setABRoutes(host, upstream string) error {
+	if len(c.cfg.Proxy.ABTests) == 0 {
+		return nil
+	}
+	tests := make([]proxy.ABTest, 0, len(c.cfg.Proxy.ABTests))
+	for _, test := range c.cfg.Proxy.ABTests {
+		tests = append(tests, proxy.ABTest{
+			Name:      test.Name,
+			Header:    test.Header,
+			Cookie:    test.Cookie,
+			Value:     test.Value,
+			Countries: test.Countries,
+		})
+	}
+	return c.proxy.SetABRoutes(host, BuildProxyServiceConfig(c.cfg, nil, nil), tests, upstream)
+}
+
+func (c *CanaryDeployer) buildContainerConfig(image, name, host string) *podman.ContainerConfig {
+	return newAppContainerConfig(c.cfg, image, name, host, map[string]string{
 		"azud.canary": "true",
 	})
 }
@@ -698,7 +764,7 @@ func (c *CanaryDeployer) waitForHealthy(host, container string) error {
 }
 
 func (c *CanaryDeployer) ensureRemoteSecrets(hosts []string) error {
-	return ValidateRemoteSecrets(c.sshClient, hosts, config.RemoteSecretsPath(c.cfg), c.cfg.Env.Secret)
+	return ValidateSecrets(c.cfg, c.sshClient, hosts, config.RoleSecretsPath(c.cfg, "web"), c.cfg.RoleSecrets("web"))
 }
 
 func (c *CanaryDeployer) loadState() {
@@ -1,14 +1,21 @@
 package deploy
 
 import (
+	"crypto/rand"
+	"encoding/hex"
 	"encoding/json"
 	"fmt"
+	"net"
+	"path"
+	"slices"
+	"strconv"
 	"strings"
 	"time"
 
 	"github.com/lemonity-org/azud/internal/config"
 	"github.com/lemonity-org/azud/internal/podman"
 	"github.com/lemonity-org/azud/internal/ssh"
+	"github.com/lemonity-org/azud/internal/state"
 )
 
 // shellMetacharacters is the set of characters that indicate a command
@@ -32,6 +39,71 @@ func ParseCommandArgs(cmd string) []string {
 	return parseCommandArgs(cmd)
 }
 
+// ProxyTierPortMapping returns the port publish spec for an app container on
+// host when a dedicated load-balancer tier is configured. The app port is
+// bound to the host's private address only, so the proxy tier can reach it
+// while the public interface stays closed. Returns "" when no tier is used.
+func ProxyTierPortMapping(cfg *config.Config, host, role string) string {
+	if !cfg.UsesProxyTier() || !IsProxyRole(role) {
+		return ""
+	}
+	return podman.PortSpec(cfg.PrivateAddress(host), 0, cfg.Proxy.AppPort)
+}
+
+// ResolveUpstream returns the address Caddy dials to reach container on host.
+// Bridge mode uses the Podman DNS name; mixed rootful/rootless mode and the
+// load-balancer tier resolve the container's published host port, and
+// proxy.app_socket resolves the socket in the container's socket directory.
+func ResolveUpstream(cfg *config.Config, containers *podman.ContainerManager, host, container string) (string, error) {
+	if cfg.UsesAppSocket() {
+		source, err := containers.MountSource(host, container, path.Dir(cfg.Proxy.AppSocket))
+		if err != nil {
+			return "", fmt.Errorf("failed to resolve socket directory for %s on %s: %w", container, host, err)
+		}
+		if source == "" {
+			return "", fmt.Errorf("%s on %s has no socket directory mounted at %s", container, host, path.Dir(cfg.Proxy.AppSocket))
+		}
+		return cfg.AppSocketUpstream(path.Base(source)), nil
+	}
+	if upstreamFollowsName(cfg) {
+		return fmt.Sprintf("%s:%d", container, cfg.Proxy.AppPort), nil
+	}
+
+	port, err := containers.HostPort(host, container, cfg.Proxy.AppPort)
+	if err != nil {
+		return "", fmt.Errorf("failed to resolve host port for %s on %s: %w", container, host, err)
+	}
+	if cfg.UsesProxyTier() {
+		return net.JoinHostPort(cfg.PrivateAddress(host), strconv.Itoa(port)), nil
+	}
+	return fmt.Sprintf("127.0.0.1:%d", port), nil
+}
+
+// upstreamFollowsName reports whether upstreams address containers by name,
+// so renaming a container changes its upstream. Host ports and socket
+// directories stay with the container across a rename.
+func upstreamFollowsName(cfg *config.Config) bool {
+	return !cfg.UseHostPortUpstreams() && !cfg.UsesProxyTier() && !cfg.UsesAppSocket()
+}
+
+// AppSocketsDir returns the host directory holding the socket directories of
+// app containers, which the proxy mounts at config.AppSocketsMount. It is ""
+// unless proxy.app_socket is set.
+func AppSocketsDir(cfg *config.Config) string {
+	if !cfg.UsesAppSocket() {
+		return ""
+	}
+	return state.Dir(cfg.SSH.User) + "/sockets"
+}
+
+// newAppSocketKey names a new socket directory. Keys are random rather than
+// derived from container names, which are reused across renames.
+func newAppSocketKey() string {
+	key := make([]byte, config.AppSocketKeyLength/2)
+	_, _ = rand.Read(key)
+	return hex.EncodeToString(key)
+}
+
 // newPreDeployContainerConfig creates a minimal one-off container configuration
 // for running a pre-deploy command (e.g., database migrations) from the new
 // image. The container is created with --rm and runs in the foreground.
@@ -40,26 +112,59 @@ func newPreDeployContainerConfig(cfg *config.Config, image, name string) *podman
 		Name:    name,
 		Image:   image,
 		Remove:  true,
-		Network: "azud",
+		Network: cfg.RoleNetworks("web")[0],
+		// One-off commands such as migrations need the same networks as the
+		// application, e.g. to reach an accessory on an internal network.
+		Networks: cfg.RoleNetworks("web"),
 		Labels: map[string]string{
 			"azud.managed": "true",
 			"azud.service": cfg.Service,
 		},
-		Env: make(map[string]string),
+		Env: cfg.AccessoryDiscoveryEnv("web"),
 	}
 
 	for key, value := range cfg.Env.Clear {
 		containerCfg.Env[key] = value
 	}
 
-	containerCfg.SecretEnv = cfg.Env.Secret
-	if len(containerCfg.SecretEnv) > 0 {
-		containerCfg.EnvFile = config.RemoteSecretsPath(cfg)
-	}
+	AttachSecrets(cfg, containerCfg, cfg.RoleSecrets("web"), config.RoleSecretsPath(cfg, "web"))
 
 	return containerCfg
 }
 
+// AttachSecrets injects the named secrets into a container. With
+// secrets_storage=podman each secret is mounted at /run/secrets/<NAME> and
+// <NAME>_FILE points at it, keeping values out of the container environment
+// and `podman inspect`; otherwise the values are read from envFile, or its
+// age-encrypted counterpart with secrets_storage=encrypted.
+//
+// ${NAME} references in the container's environment are resolved first;
+// values that reference a secret become templates resolved from envFile on
+// the host.
+func AttachSecrets(cfg *config.Config, containerCfg *podman.ContainerConfig, names []string, envFile string) {
+	referable := names
+	if cfg.UsesPodmanSecrets() {
+		referable = nil
+	}
+	containerCfg.Env, containerCfg.EnvTemplates = config.ResolveEnv(containerCfg.Env, referable)
+	if len(names) == 0 {
+		return
+	}
+	if !cfg.UsesPodmanSecrets() {
+		containerCfg.SecretEnv = names
+		containerCfg.EnvFile = envFile
+		if cfg.UsesEncryptedSecrets() {
+			containerCfg.EnvFile = config.EncryptedSecretsPath(envFile)
+			containerCfg.EnvFileIdentity = config.RemoteSecretsIdentityPath(cfg)
+		}
+		return
+	}
+	for _, name := range names {
+		containerCfg.Secrets = append(containerCfg.Secrets, fmt.Sprintf("%s,target=%s", cfg.PodmanSecretName(name), name))
+		containerCfg.Env[name+"_FILE"] = "/run/secrets/" + name
+	}
+}
+
 // RoleContainerName returns the stable container name for a service role. The
 // web role retains the historical service name; every other role gets its own
 // name so multiple roles can coexist on one host.
@@ -84,7 +189,7 @@ func IsProxyRole(role string) bool {
 // probe runs continuously inside the container and determines if it is
 // still functioning. The readiness probe is checked separately during
 // deployment to gate proxy registration.
-func NewAppContainerConfig(cfg *config.Config, image, name, role string, extraLabels map[string]string) *podman.ContainerConfig {
+func NewAppContainerConfig(cfg *config.Config, image, name, role, host string, extraLabels map[string]string) *podman.ContainerConfig {
 	labels := make(map[string]string)
 	roleConfig, hasRole := cfg.Servers[role]
 	if hasRole {
@@ -103,41 +208,61 @@ func NewAppContainerConfig(cfg *config.Config, image, name, role string, extraLa
 	}
 
 	aliases := []string{RoleContainerName(cfg, role)}
+	networks := cfg.RoleNetworks(role)
 	containerCfg := &podman.ContainerConfig{
-		Name:    name,
-		Image:   image,
-		Detach:  true,
-		Restart: "unless-stopped",
-		Network: "azud",
+		Name:     name,
+		Image:    image,
+		Detach:   true,
+		Restart:  "unless-stopped",
+		Network:  networks[0],
+		Networks: networks,
 		// Register the stable role name as a network alias so DNS continues to
 		// resolve while a temporary deployment container is renamed.
 		NetworkAliases: aliases,
 		Labels:         labels,
-		Env:            make(map[string]string),
+		Env:            roleEnv(cfg, role, host),
 	}
-	if IsProxyRole(role) && cfg.UseHostPortUpstreams() {
+	if IsProxyRole(role) && cfg.UseHostPortUpstreams() && !cfg.UsesAppSocket() {
 		containerCfg.Ports = append(containerCfg.Ports, fmt.Sprintf("127.0.0.1::%d", cfg.Proxy.AppPort))
 	}
 
-	for key, value := range cfg.Env.Clear {
-		containerCfg.Env[key] = value
-	}
 	if hasRole {
-		for key, value := range roleConfig.Env {
-			containerCfg.Env[key] = value
-		}
 		containerCfg.Memory = roleConfig.Options["memory"]
 		containerCfg.CPUs = roleConfig.Options["cpus"]
+		containerCfg.User = roleConfig.User
+		containerCfg.Entrypoint = roleConfig.Entrypoint
+		containerCfg.Init = roleConfig.Init
+		containerCfg.StopSignal = roleConfig.StopSignal
+		if roleConfig.StopTimeout > 0 {
+			containerCfg.StopTimeout = cfg.RoleStopTimeout(role)
+		}
+		applyHardening(containerCfg, roleConfig.Hardening)
 		if roleConfig.Cmd != "" {
 			containerCfg.Command = parseCommandArgs(roleConfig.Cmd)
 		}
 	}
 
-	containerCfg.SecretEnv = cfg.Env.Secret
-	if len(containerCfg.SecretEnv) > 0 {
-		containerCfg.EnvFile = config.RemoteSecretsPath(cfg)
+	AttachSecrets(cfg, containerCfg, cfg.RoleSecrets(role), config.RoleSecretsPath(cfg, role))
+	containerCfg.Volumes = cfg.LabelVolumes(cfg.Volumes)
+	if IsProxyRole(role) && cfg.UsesAppSocket() {
+		// Each container gets its own socket directory so old and new
+		// containers can listen side by side during a rollout.
+		socketDir := AppSocketsDir(cfg) + "/" + cfg.Service + "/" + newAppSocketKey()
+		containerCfg.HostDirs = append(containerCfg.HostDirs, socketDir)
+		containerCfg.Volumes = append(slices.Clone(containerCfg.Volumes), socketDir+":"+path.Dir(cfg.Proxy.AppSocket)+":U,z")
+	}
+	if IsProxyRole(role) {
+		// Shared static directories hold files the application writes,
+		// such as uploads, where the proxy serves them from.
+		for _, static := range cfg.Proxy.Static {
+			if !static.Shared {
+				continue
+			}
+			dir := staticPathDir(cfg, static)
+			containerCfg.HostDirs = append(containerCfg.HostDirs, dir)
+			containerCfg.Volumes = append(slices.Clone(containerCfg.Volumes), dir+":"+static.Dir+":z")
+		}
 	}
-	containerCfg.Volumes = cfg.Volumes
 
 	// HTTP liveness/readiness settings only belong to the proxy-serving role.
 	if !IsProxyRole(role) {
@@ -159,9 +284,76 @@ func NewAppContainerConfig(cfg *config.Config, image, name, role string, extraLa
 	return containerCfg
 }
 
+// NewOneOffContainerConfig builds the config of a one-off container run
+// from role's image, such as `azud app run`. It gets the role's environment,
+// secrets, volumes, networks, and runtime options, but no network alias,
+// published port, or healthcheck, so it never receives traffic, and it is
+// removed when it exits.
+func NewOneOffContainerConfig(cfg *config.Config, image, name, role, host string, command []string) *podman.ContainerConfig {
+	networks := cfg.RoleNetworks(role)
+	containerCfg := &podman.ContainerConfig{
+		Name:     name,
+		Image:    image,
+		Command:  command,
+		Remove:   true,
+		Network:  networks[0],
+		Networks: networks,
+		Labels: map[string]string{
+			"azud.managed": "true",
+			"azud.service": cfg.Service,
+		},
+		Env: roleEnv(cfg, role, host),
+	}
+	if roleConfig, ok := cfg.Servers[role]; ok {
+		containerCfg.Memory = roleConfig.Options["memory"]
+		containerCfg.CPUs = roleConfig.Options["cpus"]
+		containerCfg.User = roleConfig.User
+		containerCfg.Entrypoint = roleConfig.Entrypoint
+		containerCfg.Init = roleConfig.Init
+		applyHardening(containerCfg, roleConfig.Hardening)
+	}
+
+	AttachSecrets(cfg, containerCfg, cfg.RoleSecrets(role), config.RoleSecretsPath(cfg, role))
+	containerCfg.Volumes = cfg.LabelVolumes(cfg.Volumes)
+	return containerCfg
+}
+
+// roleEnv returns the clear environment of role's containers on host: the
+// accessory discovery variables, the host's tag blocks, then env.clear, then
+// the role's env, each winning over the one before.
+func roleEnv(cfg *config.Config, role, host string) map[string]string {
+	env := cfg.AccessoryDiscoveryEnv(role)
+	for key, value := range cfg.TagEnv(host, role) {
+		env[key] = value
+	}
+	for key, value := range cfg.Env.Clear {
+		env[key] = value
+	}
+	if roleConfig, ok := cfg.Servers[role]; ok {
+		for key, value := range roleConfig.Env {
+			env[key] = value
+		}
+	}
+	return env
+}
+
 // newAppContainerConfig retains an internal shorthand for web-only callers.
-func newAppContainerConfig(cfg *config.Config, image, name string, extraLabels map[string]string) *podman.ContainerConfig {
-	return NewAppContainerConfig(cfg, image, name, "web", extraLabels)
+func newAppContainerConfig(cfg *config.Config, image, name, host string, extraLabels map[string]string) *podman.ContainerConfig {
+	return NewAppContainerConfig(cfg, image, name, "web", host, extraLabels)
+}
+
+// applyHardening sets the run flags for a role's hardening options.
+func applyHardening(containerCfg *podman.ContainerConfig, hardening config.HardeningConfig) {
+	h := hardening.Effective()
+	containerCfg.ReadOnly = h.ReadOnly
+	containerCfg.Tmpfs = h.Tmpfs
+	containerCfg.CapDrop = h.CapDrop
+	if h.NoNewPrivileges {
+		containerCfg.SecurityOpts = append(containerCfg.SecurityOpts, "no-new-privileges")
+	}
+	if h.SeccompProfile != "" {
+		containerCfg.SecurityOpts = append(containerCfg.SecurityOpts, "seccomp="+h.SeccompProfile)
+	}
 }
 
 // waitForContainerHealthy polls a container's health status and also
@@ -192,7 +384,10 @@ func waitForContainerHealthy(cfg *config.Config, podmanClient *podman.Client, ss
 	readinessPath := cfg.Proxy.Healthcheck.GetReadinessPath()
 	var readinessCandidates []string
 	readinessHelper := ""
-	if readinessCmd == "" {
+	if readinessCmd == "" && cfg.UsesAppSocket() {
+		readinessCandidates = BuildUnixSocketCheckExecCandidates(container, cfg.Proxy.AppSocket, readinessPath)
+		readinessHelper = BuildUnixSocketCheckHelperCommand(container, cfg.Proxy.AppSocket, readinessPath, cfg.Proxy.Healthcheck.HelperImage, cfg.Proxy.Healthcheck.HelperPull)
+	} else if readinessCmd == "" {
 		readinessCandidates = BuildHTTPCheckExecCandidates(container, cfg.Proxy.AppPort, readinessPath)
 		readinessHelper = BuildHTTPCheckHelperCommand(container, cfg.Proxy.AppPort, readinessPath, cfg.Proxy.Healthcheck.HelperImage, cfg.Proxy.Healthcheck.HelperPull)
 	}
@@ -232,7 +427,9 @@ func waitForContainerHealthy(cfg *config.Config, podmanClient *podman.Client, ss
 			return nil
 		}
 
-		time.Sleep(checkInterval)
+		if err := sshClient.Sleep(checkInterval); err != nil {
+			return err
+		}
 	}
 
 	return fmt.Errorf("timeout waiting for container to become ready")
@@ -0,0 +1,47 @@
+package deploy
+
+import (
+	"context"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/lemonity-org/azud/internal/config"
+	"github.com/lemonity-org/azud/internal/output"
+	"github.com/lemonity-org/azud/internal/ssh"
+)
+
+func TestTargetTimeout(t *testing.T) {
+	cfg := &config.Config{}
+	cfg.Deploy.ReadinessDelay = 10 * time.Second
+	cfg.Deploy.DeployTimeout = 10 * time.Minute
+	cfg.Deploy.DrainTimeout = 30 * time.Second
+	if got, want := targetTimeout(cfg), 20*time.Minute+40*time.Second; got != want {
+		t.Fatalf("targetTimeout = %s, want %s", got, want)
+	}
+
+	cfg.Deploy.DeployTimeout = 30 * time.Second
+	if got := targetTimeout(cfg); got != 5*time.Minute {
+		t.Fatalf("targetTimeout floor = %s, want 5m", got)
+	}
+}
+
+func TestWithContextBindsRemoteOperations(t *testing.T) {
+	d := NewDeployer(&config.Config{Service: "shop"}, ssh.NewClient(&ssh.Config{User: "deploy"}), output.NewLogger(io.Discard, io.Discard, false))
+	ctx, cancel := context.WithCancel(context.Background())
+	scoped := d.withContext(ctx)
+	if scoped.sshClient.Context() != ctx {
+		t.Fatal("scoped deployer must run SSH commands under the rollout context")
+	}
+	if d.sshClient.Context() == ctx {
+		t.Fatal("withContext must not rebind the original deployer")
+	}
+
+	cancel()
+	if err := scoped.detached().sshClient.Sleep(time.Millisecond); err != nil {
+		t.Fatalf("detached cleanup must survive cancellation, got %v", err)
+	}
+	if err := scoped.sshClient.Sleep(time.Hour); err == nil {
+		t.Fatal("canceled rollout must stop waiting")
+	}
+}
@@ -2,8 +2,10 @@ package deploy
 
 import (
 	"context"
+	"errors"
 	"fmt"
-	"os"
+	"path"
+	"slices"
 	"sort"
 	"strings"
 	"sync"
@@ -13,6 +15,7 @@ import (
 	"github.com/lemonity-org/azud/internal/output"
 	"github.com/lemonity-org/azud/internal/podman"
 	"github.com/lemonity-org/azud/internal/proxy"
+	"github.com/lemonity-org/azud/internal/shell"
 	"github.com/lemonity-org/azud/internal/ssh"
 	"github.com/lemonity-org/azud/internal/state"
 )
@@ -29,9 +32,32 @@ type Deployer struct {
 	proxy       *proxy.Manager
 	hooks       *HookRunner
 	history     *HistoryStore
+	sboms       *SBOMStore
+	freezes     *FreezeStore
 	log         *output.Logger
+	progress    *output.PhaseBoard
+	timings     *phaseTimer
+
+	// Variables identifying the running deployment, set on its containers
+	stamp map[string]string
+
+	// peerUpstream resolves the upstream of a running container on a peer
+	// host; nil uses runningUpstream.
+	peerUpstream func(host, container string) (string, error)
 }
 
+// Phases shown for each deployment target while a deploy runs.
+const (
+	phasePull      = "Pull"
+	phaseContainer = "Container"
+	phaseHealth    = "Health"
+	phaseProxy     = "Proxy"
+	phaseDrain     = "Drain"
+	phaseFinalize  = "Finalize"
+)
+
+var deployPhases = []string{phasePull, phaseContainer, phaseHealth, phaseProxy, phaseDrain, phaseFinalize}
+
 // newProxyConfigFromCfg builds a proxy.ProxyConfig from the deploy
 // configuration. Used by both Deployer and CanaryDeployer to avoid
 // duplicating the field mapping.
@@ -47,6 +73,19 @@ func newProxyConfigFromCfg(cfg *config.Config) *proxy.ProxyConfig {
 		LoggingEnabled:        cfg.Proxy.Logging.Enabled,
 		RedactRequestHeaders:  cfg.Proxy.Logging.RedactRequestHeaders,
 		RedactResponseHeaders: cfg.Proxy.Logging.RedactResponseHeaders,
+		ProxyProtocol:         cfg.Proxy.ProxyProtocol,
+		ProxyProtocolAllow:    cfg.Proxy.ProxyProtocolAllow,
+		AppSocketsDir:         AppSocketsDir(cfg),
+		Image:                 ProxyImage(cfg),
+		Streams:               ProxyStreams(cfg),
+		Bind:                  ProxyBind(cfg),
+		GeoIP:                 ProxyGeoIP(cfg),
+		StaticDir:             StaticDir(cfg),
+		BootTimeout:           cfg.Proxy.GetBootTimeout(),
+		Memory:                cfg.Proxy.Resources.Memory,
+		CPUs:                  cfg.Proxy.Resources.CPUs,
+		LogDriver:             cfg.Proxy.LogDriver,
+		LogOpts:               cfg.Proxy.LogOpts(),
 	}
 
 	if cfg.Proxy.SSLCertificate != "" && cfg.Proxy.SSLPrivateKey != "" {
@@ -61,27 +100,267 @@ func newProxyConfigFromCfg(cfg *config.Config) *proxy.ProxyConfig {
 	return pc
 }
 
+// ProxyStreams maps proxy.streams to the layer4 streams of the proxy. Caddy
+// server names carry the service name so streams of several services can
+// share one proxy.
+func ProxyStreams(cfg *config.Config) []proxy.StreamConfig {
+	var streams []proxy.StreamConfig
+	for _, stream := range cfg.Proxy.Streams {
+		streams = append(streams, proxy.StreamConfig{
+			Name:     "azud-stream-" + cfg.Service + "-" + stream.Name,
+			Listen:   stream.Listen,
+			Port:     stream.Port,
+			Protocol: stream.Protocol,
+		})
+	}
+	return streams
+}
+
+// ProxyGeoIP returns the GeoIP database of the proxy, with its license key
+// read from the secrets, or nil when none is configured.
+func ProxyGeoIP(cfg *config.Config) *proxy.GeoIPConfig {
+	geo := cfg.Proxy.GeoIP
+	if !geo.Enabled() {
+		return nil
+	}
+	database := geo.Database
+	if database == "" {
+		database = state.Dir(cfg.SSH.User) + "/geoip/" + geo.FileName()
+	}
+	geoIP := &proxy.GeoIPConfig{Database: database, Edition: geo.GetEdition(), AccountID: geo.AccountID}
+	if geo.LicenseKey != "" {
+		geoIP.LicenseKey, _ = config.GetSecret(geo.LicenseKey)
+	}
+	return geoIP
+}
+
+// HostProgress returns a line handler that shows the output of a long remote
+// command, such as a pull, build, or migration, as host records while it
+// runs. Blank lines are skipped.
+func HostProgress(log *output.Logger, host string) ssh.LineHandler {
+	return func(_ ssh.OutputStream, line string) {
+		if line = strings.TrimSpace(line); line != "" {
+			log.Host(host, "%s", line)
+		}
+	}
+}
+
+// PullProgress returns a line handler that reports the layers a pull copies
+// to host, parsed from Podman's output, and a summary once the manifest is
+// written. Layers already on the host are only counted.
+func PullProgress(log *output.Logger, host string) ssh.LineHandler {
+	seen := make(map[string]bool)
+	cached := 0
+	return func(_ ssh.OutputStream, line string) {
+		event, ok := podman.ParsePullLine(line)
+		if !ok {
+			return
+		}
+		if event.Done {
+			log.Host(host, "Pulled %d layer(s), %d already present", len(seen), cached)
+			return
+		}
+		// Podman names a blob by its full or abbreviated digest depending
+		// on the line, so layers are told apart by the short form.
+		layer := strings.TrimPrefix(event.Blob, "sha256:")
+		if len(layer) > 12 {
+			layer = layer[:12]
+		}
+		if seen[layer] {
+			return
+		}
+		seen[layer] = true
+		if event.Cached {
+			cached++
+			return
+		}
+		log.Host(host, "Copying layer %d: %s", len(seen)-cached, layer)
+	}
+}
+
+// pullImage pulls image on hosts in parallel, no more than
+// deploy.pull_rate_limit at once, reporting each host's layer progress.
+// With peer transfer only the first host pulls; see distributeImage.
+func pullImage(cfg *config.Config, images *podman.ImageManager, log *output.Logger, hosts []string, image string) (map[string]time.Duration, map[string]error) {
+	if peerTransfer(cfg, hosts, image) {
+		return distributeImage(cfg, images, log, hosts, image)
+	}
+	return images.PullAllWithProgress(hosts, image, cfg.Deploy.PullRateLimit, func(host string) ssh.LineHandler {
+		return PullProgress(log, host)
+	})
+}
+
+// peerTransfer reports whether image reaches hosts host-to-host rather than
+// from the registry: deploy.image_transfer is peer, there is more than one
+// host, and the image is not a digest reference, which a loaded image
+// cannot resolve.
+func peerTransfer(cfg *config.Config, hosts []string, image string) bool {
+	return cfg.Deploy.ImageTransfer == config.ImageTransferPeer && len(hosts) > 1 && !strings.Contains(image, "@")
+}
+
+// distributeImage pulls image on the first host only and copies it from
+// there to the others. Each round uses every host that already has the
+// image as a source, so n hosts are covered in about log2(n) rounds, no
+// more than deploy.pull_rate_limit copies at once. Copies are verified
+// against the first host's image ID, and a host the copy fails on pulls
+// from the registry instead.
+func distributeImage(cfg *config.Config, images *podman.ImageManager, log *output.Logger, hosts []string, image string) (map[string]time.Duration, map[string]error) {
+	seed := hosts[0]
+	durations, errors := images.PullAllWithProgress(hosts[:1], image, 0, func(host string) ssh.LineHandler {
+		return PullProgress(log, host)
+	})
+	if len(errors) > 0 {
+		return durations, errors
+	}
+	seedID, err := images.ID(seed, image)
+	if err != nil {
+		errors[seed] = err
+		return durations, errors
+	}
+
+	var mu sync.Mutex
+	sources := []string{seed}
+	pending := hosts[1:]
+	for len(pending) > 0 {
+		batch := pending[:min(len(sources), len(pending))]
+		if limit := cfg.Deploy.PullRateLimit; limit > 0 && len(batch) > limit {
+			batch = batch[:limit]
+		}
+		pending = pending[len(batch):]
+
+		var wg sync.WaitGroup
+		for i, host := range batch {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				started := time.Now()
+				err := copyImage(images, log, sources[i], host, image, seedID)
+
+				mu.Lock()
+				defer mu.Unlock()
+				durations[host] = time.Since(started)
+				if err != nil {
+					errors[host] = err
+					return
+				}
+				sources = append(sources, host)
+			}()
+		}
+		wg.Wait()
+	}
+	return durations, errors
+}
+
+// copyImage puts the image with ID id on host, copied from src, or pulled
+// from the registry when the copy fails.
+func copyImage(images *podman.ImageManager, log *output.Logger, src, host, image, id string) error {
+	if current, err := images.ID(host, image); err == nil && current == id {
+		log.Host(host, "Image already present")
+		return nil
+	}
+	err := images.Transfer(src, host, image)
+	if err == nil {
+		err = checkImageID(images, host, image, id)
+	}
+	if err == nil {
+		log.Host(host, "Copied image from %s", src)
+		return nil
+	}
+
+	log.Warn("Peer transfer from %s to %s failed, pulling from the registry: %v", src, host, err)
+	if err := images.PullWithProgress(host, image, PullProgress(log, host)); err != nil {
+		return err
+	}
+	return checkImageID(images, host, image, id)
+}
+
+// checkImageID fails unless image on host has ID id.
+func checkImageID(images *podman.ImageManager, host, image, id string) error {
+	current, err := images.ID(host, image)
+	if err != nil {
+		return err
+	}
+	if current != id {
+		return fmt.Errorf("image ID mismatch: expected %s, found %s", id, current)
+	}
+	return nil
+}
+
+// ProxyImage returns the image the proxy runs: the proxy.image_build image,
+// proxy.image, or "" for the pinned official Caddy image.
+func ProxyImage(cfg *config.Config) string {
+	if cfg.Proxy.ImageBuild.Enabled() {
+		return cfg.ProxyImageRepository() + ":" + proxy.CaddyBuildTag(cfg.Proxy.ImageBuild.Modules)
+	}
+	return cfg.Proxy.Image
+}
+
+// ProxyBind returns the proxy.bind addresses of each proxy host, or nil when
+// the proxy publishes on every interface.
+func ProxyBind(cfg *config.Config) map[string][]string {
+	if len(cfg.Proxy.Bind) == 0 {
+		return nil
+	}
+	bind := make(map[string][]string)
+	for _, host := range cfg.GetProxyHosts() {
+		bind[host] = cfg.ProxyBindAddresses(host)
+	}
+	return bind
+}
+
 func NewDeployer(cfg *config.Config, sshClient *ssh.Client, log *output.Logger) *Deployer {
 	if log == nil {
 		log = output.DefaultLogger
 	}
 
-	podmanClient := podman.NewClient(sshClient)
-	proxyManager := proxy.NewManagerWithOptions(sshClient, log, cfg.SSH.User, cfg.Proxy.Rootful, cfg.UseHostPortUpstreams())
-	proxyManager.SetProxyConfig(newProxyConfigFromCfg(cfg))
+	d := &Deployer{
+		cfg:     cfg,
+		hooks:   NewHookRunner(cfg.HooksPath, cfg.Hooks.Timeout, log),
+		history: NewDurableHistoryStore(cfg.Deploy.RetainHistory, log),
+		sboms:   NewSBOMStore(),
+		freezes: NewFreezeStore(),
+		log:     log,
+	}
+	d.bindRemote(sshClient)
+	return d
+}
+
+// bindRemote points the SSH, Podman, and proxy managers at sshClient.
+func (d *Deployer) bindRemote(sshClient *ssh.Client) {
+	d.sshClient = sshClient
+	d.podman = podman.NewClient(sshClient)
+	d.containers = podman.NewContainerManager(d.podman)
+	d.images = podman.NewImageManager(d.podman)
+	d.registry = podman.NewRegistryManager(d.podman)
+	d.proxy = proxy.NewManagerWithOptions(sshClient, d.log, d.cfg.SSH.User, d.cfg.Proxy.Rootful, d.cfg.UseHostPortUpstreams())
+	d.proxy.SetProxyConfig(newProxyConfigFromCfg(d.cfg))
+}
+
+// withContext returns a copy of the deployer whose remote commands, lock
+// waits, and polling run under ctx, so cancellation or a deadline aborts a
+// rollout mid-command.
+func (d *Deployer) withContext(ctx context.Context) *Deployer {
+	scoped := *d
+	scoped.bindRemote(d.sshClient.WithContext(ctx))
+	return &scoped
+}
 
-	return &Deployer{
-		cfg:        cfg,
-		sshClient:  sshClient,
-		podman:     podmanClient,
-		containers: podman.NewContainerManager(podmanClient),
-		images:     podman.NewImageManager(podmanClient),
-		registry:   podman.NewRegistryManager(podmanClient),
-		proxy:      proxyManager,
-		hooks:      NewHookRunner(cfg.HooksPath, cfg.Hooks.Timeout, log),
-		history:    NewDurableHistoryStore(cfg.Deploy.RetainHistory, log),
-		log:        log,
+// detached returns a copy of the deployer for restoring a host after a
+// failed rollout step. It keeps working after the rollout is canceled or
+// times out; each command is still bounded by the SSH command timeout.
+func (d *Deployer) detached() *Deployer {
+	return d.withContext(context.WithoutCancel(d.sshClient.Context()))
+}
+
+// targetTimeout bounds one role/host rollout: the readiness delay, health
+// check, drain, and finalize steps, with the same five-minute floor as the
+// deploy lock.
+func targetTimeout(cfg *config.Config) time.Duration {
+	timeout := cfg.Deploy.ReadinessDelay + 2*cfg.Deploy.DeployTimeout + cfg.Deploy.DrainTimeout
+	if timeout < 5*time.Minute {
+		timeout = 5 * time.Minute
 	}
+	return timeout
 }
 
 func (d *Deployer) hookContext(opts *DeployOptions, image, version string) *HookContext {
@@ -115,6 +394,24 @@ type DeployOptions struct {
 
 	// Destination environment (for history tracking)
 	Destination string
+
+	// Approve confirms the plan when deploy.require_approval is set and no
+	// approval webhook is configured
+	Approve Approver
+
+	// Bearer token sent to the approval webhook
+	ApprovalToken string
+
+	// Deploy during a freeze or outside deploy.windows. The override is
+	// recorded in the audit log.
+	Override bool
+
+	// Image digest to deploy. The image is pinned to it and the pulled
+	// image must match; Version then only labels the deployment.
+	Digest string
+
+	// Extra metadata recorded in deployment history
+	Metadata map[string]string
 }
 
 // deploymentTarget identifies one role instance on one host. A host may
@@ -124,31 +421,20 @@ type deploymentTarget struct {
 	Role string
 }
 
+// progressKey labels the target's row on the progress board.
+func (t deploymentTarget) progressKey() string {
+	return t.Host + "/" + t.Role
+}
+
 // Deploy pulls the image, starts new containers, health-checks them,
 // registers them with the proxy, and drains old containers.
 func (d *Deployer) Deploy(ctx context.Context, opts *DeployOptions) error {
+	d = d.withContext(ctx)
 	deployStart := time.Now()
 	timer := d.log.NewTimer("Deployment")
 	defer timer.Stop()
 
-	// Determine the image to deploy
-	image := d.cfg.Image
-	version := opts.Version
-	switch {
-	case version != "":
-		// Explicit version: replace any existing tag or digest.
-		image = fmt.Sprintf("%s:%s", stripImageTag(image), version)
-	case strings.Contains(image, "@"):
-		// Digest-pinned image (no explicit version): use the digest as version.
-		version = image[strings.Index(image, "@")+1:]
-	case hasImageTag(image):
-		// Tagged image: extract the tag as the version.
-		version = image[strings.LastIndex(image, ":")+1:]
-	default:
-		// No tag and no version: default to latest.
-		version = "latest"
-		image = fmt.Sprintf("%s:latest", image)
-	}
+	image, version := resolveImage(d.cfg.Image, opts.Version, opts.Digest)
 	d.log.Header("Deploying %s", image)
 
 	// Resolve role/host pairs before doing any remote work. A host can run
@@ -160,25 +446,152 @@ func (d *Deployer) Deploy(ctx context.Context, opts *DeployOptions) error {
 	if len(targets) == 0 {
 		return fmt.Errorf("no deployment targets")
 	}
-	hosts := targetHosts(targets)
+
 	if err := d.history.EnsureAvailable(); err != nil {
 		return fmt.Errorf("durable deployment history is unavailable: %w", err)
 	}
 
 	// Create deployment record for history
-	record := NewDeploymentRecord(d.cfg.Service, image, version, opts.Destination, hosts)
-	record.Start()
-
-	// Ensure required secrets are present on all hosts.
-	if err := d.ensureRemoteSecrets(hosts); err != nil {
-		return d.failAndRecord(record, err)
+	record := NewDeploymentRecord(d.cfg.Service, image, version, opts.Destination, targetHosts(targets))
+	for _, target := range targets {
+		record.Targets = append(record.Targets, TargetProgress{Host: target.Host, Role: target.Role, Status: TargetPending})
 	}
+	for key, value := range opts.Metadata {
+		record.Metadata[key] = value
+	}
+	if opts.Digest != "" {
+		// The pulled image is checked against this like a resumed deploy.
+		record.Metadata["image_digest"] = opts.Digest
+	}
+	if len(opts.Hosts) > 0 || len(opts.Roles) > 0 {
+		record.Metadata[PartialMetadata] = "true"
+	}
+	record.Start()
 
 	// Try to get previous version for rollback reference
 	if lastDeploy, err := d.history.GetLastSuccessful(d.cfg.Service); err == nil {
 		record.PreviousVersion = lastDeploy.Version
 	}
 
+	return d.rollout(ctx, record, opts, deployStart)
+}
+
+// Resume continues a failed or interrupted deployment recorded in history.
+// Targets that already completed are skipped; the rest are deployed with the
+// recorded image, which must still resolve to the recorded digest.
+func (d *Deployer) Resume(ctx context.Context, id string, opts *DeployOptions) error {
+	d = d.withContext(ctx)
+	deployStart := time.Now()
+	timer := d.log.NewTimer("Deployment")
+	defer timer.Stop()
+
+	if err := d.history.EnsureAvailable(); err != nil {
+		return fmt.Errorf("durable deployment history is unavailable: %w", err)
+	}
+	record, err := d.history.Get(id)
+	if err != nil {
+		return err
+	}
+	if record.Service != d.cfg.Service {
+		return fmt.Errorf("deployment %s belongs to service %s, not %s", id, record.Service, d.cfg.Service)
+	}
+	if record.Destination != opts.Destination {
+		return fmt.Errorf("deployment %s was made to destination %q, not %q", id, record.Destination, opts.Destination)
+	}
+	if err := record.Resumable(); err != nil {
+		return err
+	}
+	// Resuming an older deployment would roll back whatever was deployed
+	// to the destination since.
+	if latest, err := d.history.GetLastDeploymentTo(record.Service, record.Destination); err == nil && latest.ID != record.ID {
+		return fmt.Errorf("deployment %s was superseded by %s (%s, %s); deploy again instead of resuming", id, latest.ID, latest.Version, latest.Status)
+	}
+
+	// Every recorded target must still be deployable with the current
+	// configuration, otherwise the resumed rollout would diverge from it.
+	configured, err := d.getTargets(&DeployOptions{})
+	if err != nil {
+		return err
+	}
+	known := make(map[deploymentTarget]struct{}, len(configured))
+	for _, target := range configured {
+		known[target] = struct{}{}
+	}
+	for _, target := range record.Targets {
+		if _, ok := known[deploymentTarget{Host: target.Host, Role: target.Role}]; !ok {
+			return fmt.Errorf("deployment %s targets %s/%s, which is no longer configured", id, target.Host, target.Role)
+		}
+	}
+
+	pending := record.PendingTargets()
+	d.log.Header("Resuming %s", record.Image)
+	d.log.Info("%d of %d target(s) already deployed; resuming %d", len(record.Targets)-len(pending), len(record.Targets), len(pending))
+
+	resumeOpts := *opts
+	resumeOpts.Version = record.Version
+	resumeOpts.Hosts = nil
+	resumeOpts.Roles = nil
+	for _, target := range pending {
+		if !slices.Contains(resumeOpts.Hosts, target.Host) {
+			resumeOpts.Hosts = append(resumeOpts.Hosts, target.Host)
+		}
+		if !slices.Contains(resumeOpts.Roles, target.Role) {
+			resumeOpts.Roles = append(resumeOpts.Roles, target.Role)
+		}
+	}
+
+	record.Resume()
+	return d.rollout(ctx, record, &resumeOpts, deployStart)
+}
+
+// rollout deploys record's image to every target not yet marked successful,
+// persisting per-target progress so a failure can be resumed later.
+func (d *Deployer) rollout(ctx context.Context, record *DeploymentRecord, opts *DeployOptions, deployStart time.Time) error {
+	image, version := record.Image, record.Version
+
+	var targets, completed []deploymentTarget
+	for _, progress := range record.Targets {
+		target := deploymentTarget{Host: progress.Host, Role: progress.Role}
+		if progress.Status == TargetSuccess {
+			completed = append(completed, target)
+			continue
+		}
+		targets = append(targets, target)
+	}
+	hosts := targetHosts(targets)
+
+	if err := d.checkDeployWindow(record, opts); err != nil {
+		return err
+	}
+
+	if d.cfg.Deploy.RequireApproval {
+		if err := d.approve(ctx, record, opts); err != nil {
+			return fmt.Errorf("deployment not approved: %w", err)
+		}
+	}
+
+	d.progress = d.log.NewPhaseBoard(deployPhases...)
+	d.timings = newPhaseTimer()
+	d.stamp = deployStampEnv(record)
+	for _, target := range targets {
+		d.progress.Add(target.progressKey(), target.Host)
+	}
+	defer func() {
+		d.progress.Close()
+		d.progress = nil
+	}()
+
+	// Persist the planned targets before touching any host so an interrupted
+	// run can still be resumed.
+	if err := d.history.Record(record); err != nil {
+		return fmt.Errorf("failed to persist deployment record: %w", err)
+	}
+
+	// Ensure required secrets are present on all hosts.
+	if err := d.ensureRemoteSecrets(targets); err != nil {
+		return d.failAndRecord(record, err)
+	}
+
 	// Run pre-deploy hook
 	hookCtx := d.hookContext(opts, image, version)
 	if err := d.hooks.Run(ctx, "pre-deploy", hookCtx); err != nil {
@@ -187,40 +600,84 @@ func (d *Deployer) Deploy(ctx context.Context, opts *DeployOptions) error {
 
 	d.log.Info("Deploying to %d host(s)", len(hosts))
 
-	// Login to registry if configured
-	if !opts.SkipPull && d.cfg.Registry.Server != "" {
-		if err := d.loginToRegistry(hosts); err != nil {
+	// Login to the registries the image needs, if configured
+	if !opts.SkipPull && len(d.cfg.RegistryLogins(image)) > 0 {
+		if err := d.loginToRegistry(hosts, image); err != nil {
 			return d.failAndRecord(record, fmt.Errorf("failed to login to registry: %w", err))
 		}
 	}
 
+	// Pin a tag to one digest before the other hosts pull, so a tag moved
+	// mid-deploy cannot put different images on different hosts.
+	if d.cfg.Deploy.RequireDigest && !strings.Contains(image, "@") {
+		pinned, digest, err := d.pinImageDigest(hosts[0], image, opts.SkipPull)
+		if err != nil {
+			d.failTargets(targets)
+			return d.failAndRecord(record, fmt.Errorf("failed to resolve %s to a digest: %w", image, err))
+		}
+		d.log.Info("Pinned %s to %s", image, digest)
+		record.Metadata["image_tag"] = image
+		record.Metadata["image_digest"] = digest
+		record.Image = pinned
+		image = pinned
+		d.saveProgress(record)
+	}
+
 	// Pull image on all hosts
 	if !opts.SkipPull {
 		d.log.Info("Pulling image on all hosts...")
-		if err := d.pullImageOnHosts(hosts, image); err != nil {
+		d.setTargetsPhase(targets, d.startPhase, phasePull)
+		pullTimes, err := d.pullImageOnHosts(hosts, image)
+		if err != nil {
+			d.failTargets(targets)
 			return d.failAndRecord(record, fmt.Errorf("failed to pull image: %w", err))
 		}
 
 		// Verify image digest is consistent across all hosts to detect
-		// supply-chain attacks via mutable tag replacement.
-		if digest, err := d.verifyImageDigest(hosts, image); err != nil {
+		// supply-chain attacks via mutable tag replacement. Hosts that got
+		// the image by peer transfer have no registry digest; they were
+		// checked against the first host's image ID instead.
+		verifyHosts := hosts
+		if peerTransfer(d.cfg, hosts, image) {
+			verifyHosts = hosts[:1]
+		}
+		digest, err := d.verifyImageDigest(verifyHosts, image)
+		if err != nil {
+			d.failTargets(targets)
 			return d.failAndRecord(record, fmt.Errorf("image digest verification failed: %w", err))
-		} else if digest != "" {
+		}
+		// A resumed deployment must finish with the image the completed
+		// targets are already running, and a promotion with the digest it
+		// names.
+		if recorded := record.Metadata["image_digest"]; recorded != "" && digest != recorded {
+			d.failTargets(targets)
+			return d.failAndRecord(record, fmt.Errorf("image digest mismatch for deployment %s: expected %s, pulled %q", record.ID, recorded, digest))
+		}
+		d.setTargetsPhase(targets, d.completePhase, phasePull)
+		for _, target := range targets {
+			d.timings.set(target.progressKey(), phasePull, pullTimes[target.Host])
+		}
+		if digest != "" {
 			record.Metadata["image_digest"] = digest
 			d.log.Info("Image digest: %s", digest)
 		} else {
 			record.Metadata["image_digest_verification"] = "explicitly_skipped"
 		}
 	} else {
+		d.setTargetsPhase(targets, d.progress.Skip, phasePull)
 		d.log.Warn("Image pull and digest verification explicitly skipped")
 		record.Metadata["image_digest_verification"] = "skip_pull"
 	}
+	d.recordSBOM(record)
 
-	// Run pre-deploy command from new image (e.g., database migrations)
-	if d.cfg.Deploy.PreDeployCommand != "" {
+	// Run pre-deploy command from new image (e.g., database migrations).
+	// A resumed deployment does not repeat a command that already succeeded.
+	if d.cfg.Deploy.PreDeployCommand != "" && record.Metadata["pre_deploy_command"] != "completed" {
 		if err := d.runPreDeployCommand(hosts[0], image); err != nil {
 			return d.failAndRecord(record, fmt.Errorf("pre-deploy command failed: %w", err))
 		}
+		record.Metadata["pre_deploy_command"] = "completed"
+		d.saveProgress(record)
 	}
 
 	// Deploy to each host, tracking successes for potential fleet rollback.
@@ -228,10 +685,36 @@ func (d *Deployer) Deploy(ctx context.Context, opts *DeployOptions) error {
 		targets,
 		d.cfg.Deploy.RollbackOnFailure,
 		func(target deploymentTarget) error {
-			return d.deployToTarget(ctx, target, image, version, opts)
+			err := d.deployToTarget(ctx, target, image, version, opts)
+			// Gate between hosts; after the last one there is nothing left
+			// to halt.
+			if err == nil && d.cfg.Deploy.HaltOnErrorRate.Enabled() && IsProxyRole(target.Role) && target != targets[len(targets)-1] {
+				if err = d.checkErrorRate(target); err != nil {
+					d.progress.Fail(target.progressKey())
+				}
+			}
+			if err != nil {
+				record.SetTargetStatus(target.Host, target.Role, TargetFailed, err)
+			} else {
+				record.SetTargetStatus(target.Host, target.Role, TargetSuccess, nil)
+			}
+			d.timings.record(record, target.progressKey())
+			d.saveProgress(record)
+			return err
 		},
 		func(succeeded []deploymentTarget) error {
-			return d.rollbackTargets(ctx, succeeded, record.PreviousVersion)
+			// Rollback re-runs the deploy pipeline on the succeeded targets;
+			// keep the board showing how the deploy ended.
+			d.progress.Close()
+			d.progress = nil
+			// Targets finished by an earlier run of a resumed deployment are
+			// on the new version too.
+			succeeded = append(append([]deploymentTarget(nil), completed...), succeeded...)
+			err := d.rollbackTargets(ctx, succeeded, record.PreviousVersion)
+			for _, target := range succeeded {
+				record.SetTargetStatus(target.Host, target.Role, TargetRolledBack, nil)
+			}
+			return err
 		},
 	)
 
@@ -257,6 +740,14 @@ func (d *Deployer) Deploy(ctx context.Context, opts *DeployOptions) error {
 	return nil
 }
 
+// saveProgress persists per-target progress mid-deployment. A failure only
+// costs resumability, so it is reported without stopping the rollout.
+func (d *Deployer) saveProgress(record *DeploymentRecord) {
+	if err := d.history.Update(record); err != nil {
+		d.log.Warn("Failed to persist deployment progress: %v", err)
+	}
+}
+
 // runFleetDeployment is the scheduling boundary for a multi-target deploy.
 // With rollback enabled, the first failure stops new work and every target
 // that already succeeded is handed to the rollback callback exactly once.
@@ -270,8 +761,19 @@ func (d *Deployer) runFleetDeployment(
 	var succeededTargets []deploymentTarget
 	for _, target := range targets {
 		if err := deployTarget(target); err != nil {
+			// A halted target is running the new version, so it is rolled
+			// back along with the targets before it.
+			var halt *haltRolloutError
+			halted := errors.As(err, &halt)
+			if halted {
+				succeededTargets = append(succeededTargets, target)
+			}
 			d.log.HostError(target.Host, "%s role deployment failed: %v", target.Role, err)
 			deployErrors = append(deployErrors, fmt.Sprintf("%s/%s: %v", target.Host, target.Role, err))
+			if halted && !rollbackOnFailure {
+				d.log.Warn("Halting rollout; %d target(s) not started", len(targets)-len(succeededTargets))
+				break
+			}
 			if rollbackOnFailure {
 				if len(succeededTargets) > 0 {
 					d.log.Warn("Rolling back %d already-deployed target(s) due to failure on %s/%s...", len(succeededTargets), target.Host, target.Role)
@@ -289,61 +791,137 @@ func (d *Deployer) runFleetDeployment(
 	return succeededTargets, deployErrors
 }
 
+// haltRolloutError marks a target that deployed but failed a gate checked
+// between hosts. It stops the rollout even without rollback_on_failure.
+type haltRolloutError struct {
+	err error
+}
+
+func (e *haltRolloutError) Error() string { return e.err.Error() }
+func (e *haltRolloutError) Unwrap() error { return e.err }
+
+// checkErrorRate observes the proxy access logs for deploy.halt_on_error_rate
+// after target is deployed, and halts the rollout if the share of 5xx
+// responses exceeds the threshold. Only requests target's container served
+// count, so traffic to hosts behind the same proxy does not mask or blame
+// it. Too little traffic in the window passes.
+func (d *Deployer) checkErrorRate(target deploymentTarget) error {
+	gate := d.cfg.Deploy.HaltOnErrorRate
+	upstream, err := d.upstreamAddr(target.Host, d.cfg.Service)
+	if err != nil {
+		return &haltRolloutError{err: fmt.Errorf("error-rate gate could not resolve the upstream of %s: %w", target.Host, err)}
+	}
+	since := time.Now()
+	d.log.Host(target.Host, "Observing proxy error rate for %s...", gate.Window)
+	if err := d.sshClient.Sleep(gate.Window); err != nil {
+		return err
+	}
+
+	var stats proxy.RequestStats
+	for _, proxyNode := range d.proxyNodes(target.Host) {
+		nodeStats, err := d.proxy.RequestStatsSince(proxyNode, d.cfg.Proxy.AllHosts(), upstream, since)
+		if err != nil {
+			return &haltRolloutError{err: fmt.Errorf("error-rate gate could not read proxy logs on %s: %w", proxyNode, err)}
+		}
+		stats.Requests += nodeStats.Requests
+		stats.Errors += nodeStats.Errors
+	}
+	if stats.Requests < gate.MinRequests {
+		d.log.Host(target.Host, "Error-rate gate skipped: %d request(s) in %s, need %d", stats.Requests, gate.Window, gate.MinRequests)
+		return nil
+	}
+	rate := stats.ErrorRate()
+	if rate > gate.Threshold {
+		return &haltRolloutError{err: fmt.Errorf("error rate %.1f%% (%d/%d requests) exceeds halt_on_error_rate threshold %.1f%%", rate, stats.Errors, stats.Requests, gate.Threshold)}
+	}
+	d.log.Host(target.Host, "Error rate %.1f%% (%d/%d requests) within threshold", rate, stats.Errors, stats.Requests)
+	return nil
+}
+
+// setTargetsPhase applies a progress board update to every target.
+func (d *Deployer) setTargetsPhase(targets []deploymentTarget, update func(key, phase string), phase string) {
+	for _, target := range targets {
+		update(target.progressKey(), phase)
+	}
+}
+
+func (d *Deployer) failTargets(targets []deploymentTarget) {
+	for _, target := range targets {
+		d.progress.Fail(target.progressKey())
+	}
+}
+
 func (d *Deployer) failAndRecord(record *DeploymentRecord, cause error) error {
 	record.Fail(cause)
 	if err := d.history.Record(record); err != nil {
 		return fmt.Errorf("%w (failed to persist deployment failure: %v)", cause, err)
 	}
+	if record.Resumable() == nil {
+		resume := "azud deploy --resume " + record.ID
+		if record.Destination != "" {
+			resume += " -d " + record.Destination
+		}
+		d.log.Info("Retry the remaining targets with: %s", resume)
+	}
 	return cause
 }
 
-func (d *Deployer) ensureRemoteSecrets(hosts []string) error {
-	return ValidateRemoteSecrets(d.sshClient, hosts, config.RemoteSecretsPath(d.cfg), d.cfg.Env.Secret)
-}
-
-func (d *Deployer) loginToRegistry(hosts []string) error {
-	username := d.cfg.Registry.Username
-	if username == "" {
-		return nil
+// ensureRemoteSecrets checks each role's env file on the hosts it deploys to.
+func (d *Deployer) ensureRemoteSecrets(targets []deploymentTarget) error {
+	hostsByRole := make(map[string][]string)
+	for _, target := range targets {
+		hostsByRole[target.Role] = append(hostsByRole[target.Role], target.Host)
 	}
-
-	password := ""
-	if len(d.cfg.Registry.Password) > 0 {
-		secretKey := d.cfg.Registry.Password[0]
-		password = os.Getenv(secretKey)
-		if password == "" {
-			if p, ok := config.GetSecret(secretKey); ok {
-				password = p
-			}
+	for _, role := range targetRoles(targets) {
+		path := config.RoleSecretsPath(d.cfg, role)
+		if err := ValidateSecrets(d.cfg, d.sshClient, hostsByRole[role], path, d.cfg.RoleSecrets(role)); err != nil {
+			return err
 		}
 	}
+	return nil
+}
 
-	if password == "" {
-		return fmt.Errorf("registry password not found (secret: %v)", d.cfg.Registry.Password)
-	}
+func (d *Deployer) loginToRegistry(hosts []string, image string) error {
+	for _, registry := range d.cfg.RegistryLogins(image) {
+		password, err := registry.ResolvePassword()
+		if err != nil {
+			return err
+		}
+		if password == "" {
+			return fmt.Errorf("registry password not found (secret: %v)", registry.Password)
+		}
 
-	d.log.Info("Logging into registry %s...", d.cfg.Registry.Server)
+		server := registry.Server
+		if server == "" {
+			server = "docker.io"
+		}
+		d.log.Info("Logging into registry %s...", server)
 
-	regConfig := &podman.RegistryConfig{
-		Server:   d.cfg.Registry.Server,
-		Username: username,
-		Password: password,
-	}
+		regConfig := &podman.RegistryConfig{
+			Server:   registry.Server,
+			Username: registry.Username,
+			Password: password,
+		}
 
-	errors := d.registry.LoginAll(hosts, regConfig)
-	if len(errors) > 0 {
-		var errMsgs []string
-		for host, err := range errors {
-			errMsgs = append(errMsgs, fmt.Sprintf("%s: %v", host, err))
+		errors := d.registry.LoginAll(hosts, regConfig)
+		if len(errors) > 0 {
+			var errMsgs []string
+			for host, err := range errors {
+				errMsgs = append(errMsgs, fmt.Sprintf("%s: %v", host, err))
+			}
+			sort.Strings(errMsgs)
+			return fmt.Errorf("registry login to %s failed on hosts: %s", server, strings.Join(errMsgs, "; "))
 		}
-		sort.Strings(errMsgs)
-		return fmt.Errorf("registry login failed on hosts: %s", strings.Join(errMsgs, "; "))
 	}
 
 	return nil
 }
 
 func (d *Deployer) deployToTarget(ctx context.Context, target deploymentTarget, image, version string, opts *DeployOptions) error {
+	ctx, cancel := context.WithTimeout(ctx, targetTimeout(d.cfg))
+	defer cancel()
+	scoped := d.withContext(ctx)
+
 	// Acquire deployment lock to prevent concurrent deployments to the same host/service
 	lockFile := state.LockFile(d.cfg.SSH.User, d.cfg.Service+".deploy")
 	lockTimeout := d.cfg.Deploy.DeployTimeout * 2
@@ -352,18 +930,35 @@ func (d *Deployer) deployToTarget(ctx context.Context, target deploymentTarget,
 	}
 
 	var deployErr error
-	lockErr := d.sshClient.WithRemoteLock(target.Host, lockFile, lockTimeout, func() error {
-		deployErr = d.deployToTargetLocked(ctx, target, image, version, opts)
+	lockErr := scoped.sshClient.WithRemoteLock(target.Host, lockFile, lockTimeout, func() error {
+		deployErr = scoped.deployToTargetLocked(ctx, target, image, version, opts)
+		if deployErr == nil && d.cfg.UseHostPortUpstreams() && IsProxyRole(target.Role) {
+			if err := RecordHostPorts(scoped.sshClient, scoped.containers, target.Host, d.cfg.SSH.User); err != nil {
+				d.log.Warn("Failed to update port registry on %s: %v", target.Host, err)
+			}
+		}
 		return nil
 	})
+	if deployErr != nil && ctx.Err() == context.DeadlineExceeded {
+		deployErr = fmt.Errorf("%w (%s/%s did not finish within %s)", deployErr, target.Host, target.Role, targetTimeout(d.cfg))
+	}
 	if lockErr != nil {
-		return fmt.Errorf("failed to acquire deployment lock: %w", lockErr)
+		deployErr = fmt.Errorf("failed to acquire deployment lock: %w", lockErr)
+	}
+	if deployErr != nil {
+		d.progress.Fail(target.progressKey())
+		return deployErr
 	}
-	return deployErr
+	d.completePhase(target.progressKey(), phaseFinalize)
+	return nil
 }
 
 func (d *Deployer) deployToTargetLocked(ctx context.Context, target deploymentTarget, image, version string, opts *DeployOptions) error {
 	host, role := target.Host, target.Role
+	key := target.progressKey()
+	// Restoring the host after a failed step must not be cut short by the
+	// cancellation that caused the failure.
+	cleanup := d.detached()
 	d.log.Host(host, "Starting %s role deployment...", role)
 
 	oldContainerName := RoleContainerName(d.cfg, role)
@@ -372,7 +967,10 @@ func (d *Deployer) deployToTargetLocked(ctx context.Context, target deploymentTa
 	if err != nil {
 		return fmt.Errorf("failed to determine whether current container exists: %w", err)
 	}
-	containerConfig := d.buildContainerConfig(image, newContainerName, role)
+	containerConfig := d.buildContainerConfig(image, newContainerName, role, host)
+	if mapping := ProxyTierPortMapping(d.cfg, host, role); mapping != "" {
+		containerConfig.Ports = append(containerConfig.Ports, mapping)
+	}
 
 	// Run pre-app-boot hook
 	bootCtx := d.hookContext(opts, image, version)
@@ -382,36 +980,58 @@ func (d *Deployer) deployToTargetLocked(ctx context.Context, target deploymentTa
 		return fmt.Errorf("pre-app-boot hook failed: %w", err)
 	}
 
+	d.startPhase(key, phaseContainer)
+	if IsProxyRole(role) && d.cfg.AssetPath != "" {
+		d.log.Host(host, "Bridging assets...")
+		assetDir, err := d.bridgeAssets(host, image, oldContainerName, oldExists)
+		if err != nil {
+			return fmt.Errorf("failed to bridge assets: %w", err)
+		}
+		containerConfig.Volumes = append(slices.Clone(containerConfig.Volumes), assetDir+":"+d.cfg.AssetPath+":z")
+	}
 	d.log.Host(host, "Starting new container...")
 	_, err = d.containers.Run(host, containerConfig)
 	if err != nil {
 		return fmt.Errorf("failed to start container: %w", err)
 	}
+	d.completePhase(key, phaseContainer)
 	removeNewContainer := func(cause error) error {
-		if removeErr := d.containers.Remove(host, newContainerName, true); removeErr != nil {
+		if removeErr := cleanup.containers.Remove(host, newContainerName, true); removeErr != nil {
 			return fmt.Errorf("%w (failed to remove new container %s: %v)", cause, newContainerName, removeErr)
 		}
 		return cause
 	}
 
 	// Wait for container to pass readiness check
+	healthChecked := false
 	if IsProxyRole(role) && !opts.SkipHealthCheck && HasReadinessProbe(d.cfg) {
+		d.startPhase(key, phaseHealth)
 		d.log.Host(host, "Waiting for readiness check...")
 
 		// Wait for readiness delay
 		if d.cfg.Deploy.ReadinessDelay > 0 {
-			time.Sleep(d.cfg.Deploy.ReadinessDelay)
+			if err := d.sshClient.Sleep(d.cfg.Deploy.ReadinessDelay); err != nil {
+				return removeNewContainer(err)
+			}
 		}
 
 		if err := d.waitForHealthy(host, newContainerName); err != nil {
 			return removeNewContainer(fmt.Errorf("readiness check failed: %w", err))
 		}
+		healthChecked = true
 	}
 	if !IsProxyRole(role) && !opts.SkipHealthCheck {
+		d.startPhase(key, phaseHealth)
 		d.log.Host(host, "Waiting for %s role to stabilize...", role)
 		if err := d.containers.WaitRunning(host, newContainerName, d.cfg.Deploy.ReadinessDelay); err != nil {
 			return removeNewContainer(fmt.Errorf("container startup check failed: %w", err))
 		}
+		healthChecked = true
+	}
+	if healthChecked {
+		d.completePhase(key, phaseHealth)
+	} else {
+		d.progress.Skip(key, phaseHealth)
 	}
 
 	// Run post-app-boot hook
@@ -420,6 +1040,9 @@ func (d *Deployer) deployToTargetLocked(ctx context.Context, target deploymentTa
 	}
 
 	if !IsProxyRole(role) {
+		d.progress.Skip(key, phaseProxy)
+		d.progress.Skip(key, phaseDrain)
+		d.startPhase(key, phaseFinalize)
 		return d.finalizeStandaloneRole(host, oldContainerName, newContainerName, oldExists)
 	}
 
@@ -427,15 +1050,22 @@ func (d *Deployer) deployToTargetLocked(ctx context.Context, target deploymentTa
 	// API calls. Boot is idempotent: if the container is already running
 	// it applies config and returns quickly; if it was stopped or removed
 	// it will (re)start it and wait for the admin API to be ready.
-	if err := d.proxy.Boot(host, newProxyConfigFromCfg(d.cfg)); err != nil {
-		return removeNewContainer(fmt.Errorf("failed to boot proxy: %w", err))
+	// Ensuring config afterwards re-applies TLS/ACME settings in case the
+	// proxy was rebooted or recreated between deploys and lost its config.
+	d.startPhase(key, phaseProxy)
+	for _, proxyNode := range d.proxyNodes(host) {
+		if err := d.proxy.Boot(proxyNode, newProxyConfigFromCfg(d.cfg)); err != nil {
+			return removeNewContainer(fmt.Errorf("failed to boot proxy on %s: %w", proxyNode, err))
+		}
+		if err := d.proxy.EnsureConfig(proxyNode); err != nil {
+			return removeNewContainer(fmt.Errorf("failed to ensure proxy config on %s: %w", proxyNode, err))
+		}
 	}
-
-	// Ensure the proxy has TLS/ACME config applied before registering.
-	// This handles the case where the proxy was rebooted or recreated
-	// between deploys and lost its config.
-	if err := d.proxy.EnsureConfig(host); err != nil {
-		return removeNewContainer(fmt.Errorf("failed to ensure proxy config: %w", err))
+	if d.cfg.UsesStatic() {
+		d.log.Host(host, "Syncing static files...")
+		if err := syncStatic(d.sshClient, d.cfg, host, newContainerName); err != nil {
+			return removeNewContainer(fmt.Errorf("failed to sync static files: %w", err))
+		}
 	}
 
 	// Register new container with proxy
@@ -457,16 +1087,16 @@ func (d *Deployer) deployToTargetLocked(ctx context.Context, target deploymentTa
 	cleanupNewBeforePreserve := func(cause error, restoreOldRoute, removeNewRoute bool) error {
 		var cleanupErrors []string
 		if proxyHost != "" && restoreOldRoute && oldUpstream != "" {
-			if err := d.proxy.AddUpstream(host, proxyHost, oldUpstream); err != nil {
+			if err := cleanup.addUpstream(host, proxyHost, oldUpstream); err != nil {
 				cleanupErrors = append(cleanupErrors, fmt.Sprintf("restore old route: %v", err))
 			}
 		}
 		if proxyHost != "" && removeNewRoute {
-			if err := d.proxy.RemoveUpstream(host, proxyHost, newUpstream); err != nil {
+			if err := cleanup.removeUpstream(host, proxyHost, newUpstream); err != nil {
 				cleanupErrors = append(cleanupErrors, fmt.Sprintf("remove new route: %v", err))
 			}
 		}
-		if err := d.containers.Remove(host, newContainerName, true); err != nil {
+		if err := cleanup.containers.Remove(host, newContainerName, true); err != nil {
 			cleanupErrors = append(cleanupErrors, fmt.Sprintf("remove new container: %v", err))
 		}
 		if len(cleanupErrors) > 0 {
@@ -481,12 +1111,18 @@ func (d *Deployer) deployToTargetLocked(ctx context.Context, target deploymentTa
 	// while the deploy still reports success (masking the outage from
 	// rollback_on_failure).
 	var regErr error
-	if oldExists && proxyHost != "" {
+	traffic := d.trafficWeights()
+	// With a load-balancer tier the route is shared by every app host, so
+	// always add alongside existing upstreams rather than replacing them.
+	// A host drained with azud traffic set stays out of the route.
+	if traffic.Weight(host) == 0 {
+		d.log.Host(host, "Host is drained; not adding it to the load balancers")
+	} else if (oldExists || d.cfg.UsesProxyTier()) && proxyHost != "" {
 		// Add new upstream alongside the old one so both receive traffic
 		// during the transition. Using AddUpstream (not RegisterService)
 		// preserves the old upstream in the route, which is required for
 		// connection-aware draining to work.
-		if err := d.proxy.AddUpstream(host, proxyHost, newUpstream); err != nil {
+		if err := d.addUpstream(host, proxyHost, newUpstream); err != nil {
 			// AddUpstream may fail if there's no existing route (e.g., proxy
 			// was rebooted). Fall back to a full RegisterService.
 			d.log.Debug("Failed to add upstream alongside old: %v", err)
@@ -505,18 +1141,20 @@ func (d *Deployer) deployToTargetLocked(ctx context.Context, target deploymentTa
 			oldExists,
 		)
 	}
+	d.completePhase(key, phaseProxy)
 
 	var backupName string
 	oldPreserved := false
 	// If an old container exists, take it out of rotation but preserve it
 	// under a backup name until the new name and route are confirmed.
 	if oldExists {
+		d.startPhase(key, phaseDrain)
 		// Remove old container from proxy so no new requests are routed to it.
 		// The old upstream is still tracked by Caddy until its in-flight
 		// requests complete, allowing the drain step to poll accurately.
 		d.log.Host(host, "Removing old upstream from proxy...")
 		if proxyHost != "" {
-			if err := d.proxy.RemoveUpstream(host, proxyHost, oldUpstream); err != nil {
+			if err := d.removeUpstream(host, proxyHost, oldUpstream); err != nil {
 				return cleanupNewBeforePreserve(
 					fmt.Errorf("failed to remove old upstream before drain: %w", err), true, true,
 				)
@@ -526,7 +1164,7 @@ func (d *Deployer) deployToTargetLocked(ctx context.Context, target deploymentTa
 		// Drain: poll Caddy for in-flight requests on the old upstream,
 		// falling back to a sleep if the API is unavailable.
 		if d.cfg.Deploy.DrainTimeout > 0 {
-			if err := d.proxy.DrainUpstream(host, oldUpstream, d.cfg.Deploy.DrainTimeout); err != nil {
+			if err := d.drainUpstream(host, oldUpstream, d.cfg.Deploy.DrainTimeout); err != nil {
 				return cleanupNewBeforePreserve(
 					fmt.Errorf("failed to drain old upstream: %w", err), true, true,
 				)
@@ -540,24 +1178,25 @@ func (d *Deployer) deployToTargetLocked(ctx context.Context, target deploymentTa
 			)
 		}
 		oldPreserved = true
+		d.completePhase(key, phaseDrain)
+	} else {
+		d.progress.Skip(key, phaseDrain)
 	}
 
 	rollbackSwap := func(cause error, newHasStableName bool) error {
 		var rollbackErrors []string
 		if newHasStableName {
-			if err := d.containers.Rename(host, oldContainerName, newContainerName); err != nil {
+			if err := cleanup.containers.Rename(host, oldContainerName, newContainerName); err != nil {
 				rollbackErrors = append(rollbackErrors, fmt.Sprintf("rename new container back: %v", err))
 			}
 		}
 		if oldPreserved {
-			if err := d.containers.Rename(host, backupName, oldContainerName); err != nil {
+			if err := cleanup.containers.Rename(host, backupName, oldContainerName); err != nil {
 				rollbackErrors = append(rollbackErrors, fmt.Sprintf("restore old container name: %v", err))
 			}
-			if err := d.proxy.AddUpstream(host, proxyHost, oldUpstream); err != nil {
+			if err := cleanup.swapUpstream(host, proxyHost, oldUpstream, newUpstream); err != nil {
 				rollbackErrors = append(rollbackErrors, fmt.Sprintf("restore old route: %v", err))
-			} else if err := d.proxy.RemoveUpstream(host, proxyHost, newUpstream); err != nil {
-				rollbackErrors = append(rollbackErrors, fmt.Sprintf("remove new route: %v", err))
-			} else if err := d.containers.Remove(host, newContainerName, true); err != nil {
+			} else if err := cleanup.containers.Remove(host, newContainerName, true); err != nil {
 				rollbackErrors = append(rollbackErrors, fmt.Sprintf("remove new container: %v", err))
 			}
 		}
@@ -570,6 +1209,7 @@ func (d *Deployer) deployToTargetLocked(ctx context.Context, target deploymentTa
 	// Finalize: rename the new container to the service name and swap
 	// the proxy upstream using add-then-remove so at least one upstream
 	// is always present (no gap = no dropped requests).
+	d.startPhase(key, phaseFinalize)
 	d.log.Host(host, "Finalizing deployment...")
 	if err := d.containers.Rename(host, newContainerName, oldContainerName); err != nil {
 		return rollbackSwap(fmt.Errorf("failed to assign stable container name: %w", err), false)
@@ -578,40 +1218,39 @@ func (d *Deployer) deployToTargetLocked(ctx context.Context, target deploymentTa
 	// The current container is now named oldContainerName (the service name),
 	// so any remaining "<service>-new-*" container is an orphan from a prior
 	// failed rename. Reap them now that we're holding the deploy lock.
-	// In mixed rootless/rootful mode, upstreams are host loopback ports.
-	// Renaming a container does not change its published host port, so the
-	// upstream address stays the same and there is nothing to swap.
-	if d.cfg.UseHostPortUpstreams() {
+	// In mixed rootless/rootful mode, upstreams are host loopback ports, with
+	// a load-balancer tier they are private-address host ports, and with
+	// proxy.app_socket they are socket directories. Renaming a container
+	// changes none of these, so there is nothing to swap.
+	if !upstreamFollowsName(d.cfg) {
 		if oldPreserved {
 			if err := d.containers.Remove(host, backupName, true); err != nil {
 				return rollbackSwap(fmt.Errorf("failed to remove preserved old container: %w", err), true)
 			}
 		}
 		d.reapStaleTempContainers(host, oldContainerName)
+		if traffic.Customized() {
+			if err := d.applyTrafficWeights(traffic); err != nil {
+				d.log.Warn("Failed to restore traffic weights: %v; run 'azud traffic set' to reapply them", err)
+			}
+		}
 		return nil
 	}
 
-	// Swap the upstream from the temporary name to the final service name.
-	// Add the final upstream first, then remove the temporary one. This
-	// ensures there is always at least one healthy upstream in the route,
-	// eliminating the brief gap that a full route replacement would cause.
+	// Swap the upstream from the temporary name to the final service name
+	// in one proxy change, so the route never lacks a healthy upstream.
 	finalUpstream, finalUpstreamErr := d.upstreamAddr(host, oldContainerName)
 	if finalUpstreamErr != nil {
 		d.log.Debug("Failed to resolve final upstream after rename: %v", finalUpstreamErr)
 		finalUpstream = fmt.Sprintf("%s:%d", oldContainerName, d.cfg.Proxy.AppPort)
 	}
 	if proxyHost != "" {
-		if err := d.proxy.AddUpstream(host, proxyHost, finalUpstream); err != nil {
-			// Fallback: if add fails, do a full route replacement
-			d.log.Debug("Failed to add final upstream, falling back to full replace: %v", err)
+		if err := d.swapUpstream(host, proxyHost, finalUpstream, newUpstream); err != nil {
+			// Fallback: if the swap fails, do a full route replacement
+			d.log.Debug("Failed to swap to final upstream, falling back to full replace: %v", err)
 			if regErr := d.registerWithProxy(host, finalUpstream); regErr != nil {
 				return rollbackSwap(fmt.Errorf("failed to update proxy to final container name: %w", regErr), true)
 			}
-		} else if err := d.proxy.RemoveUpstream(host, proxyHost, newUpstream); err != nil {
-			// The final upstream was added successfully, so traffic remains
-			// available. Treat cleanup failure as a deployment failure so it is
-			// visible and rollback policy can act on it.
-			return rollbackSwap(fmt.Errorf("failed to remove temporary proxy upstream: %w", err), true)
 		}
 	}
 	if oldPreserved {
@@ -630,8 +1269,9 @@ func (d *Deployer) deployToTargetLocked(ctx context.Context, target deploymentTa
 // running duplicate workers.
 func (d *Deployer) finalizeStandaloneRole(host, stableName, newName string, oldExists bool) error {
 	d.log.Host(host, "Finalizing %s container...", stableName)
+	cleanup := d.detached()
 	removeNew := func(cause error) error {
-		if removeErr := d.containers.Remove(host, newName, true); removeErr != nil {
+		if removeErr := cleanup.containers.Remove(host, newName, true); removeErr != nil {
 			return fmt.Errorf("%w (failed to remove new container %s: %v)", cause, newName, removeErr)
 		}
 		return cause
@@ -649,7 +1289,7 @@ func (d *Deployer) finalizeStandaloneRole(host, stableName, newName string, oldE
 		return removeNew(fmt.Errorf("failed to preserve current container: %w", err))
 	}
 	if err := d.containers.Rename(host, newName, stableName); err != nil {
-		restoreErr := d.containers.Rename(host, backupName, stableName)
+		restoreErr := cleanup.containers.Rename(host, backupName, stableName)
 		cause := fmt.Errorf("failed to activate new container: %w", err)
 		if restoreErr != nil {
 			cause = fmt.Errorf("failed to activate new container: %v (also failed to restore current container: %v)", err, restoreErr)
@@ -658,8 +1298,8 @@ func (d *Deployer) finalizeStandaloneRole(host, stableName, newName string, oldE
 	}
 
 	if err := d.containers.Remove(host, backupName, true); err != nil {
-		rollbackRenameErr := d.containers.Rename(host, stableName, newName)
-		restoreErr := d.containers.Rename(host, backupName, stableName)
+		rollbackRenameErr := cleanup.containers.Rename(host, stableName, newName)
+		restoreErr := cleanup.containers.Rename(host, backupName, stableName)
 		cause := fmt.Errorf("failed to remove previous container; restored old container: %w", err)
 		if rollbackRenameErr != nil || restoreErr != nil {
 			cause = fmt.Errorf("failed to remove previous container: %v (failed to restore cleanly: rename new=%v, restore old=%v)", err, rollbackRenameErr, restoreErr)
@@ -671,8 +1311,10 @@ func (d *Deployer) finalizeStandaloneRole(host, stableName, newName string, oldE
 	return nil
 }
 
-func (d *Deployer) buildContainerConfig(image, name, role string) *podman.ContainerConfig {
-	return NewAppContainerConfig(d.cfg, image, name, role, nil)
+func (d *Deployer) buildContainerConfig(image, name, role, host string) *podman.ContainerConfig {
+	containerConfig := NewAppContainerConfig(d.cfg, image, name, role, host, nil)
+	stampContainer(containerConfig, d.stamp)
+	return containerConfig
 }
 
 // runPreDeployCommand runs the configured pre_deploy_command in a one-off
@@ -686,7 +1328,7 @@ func (d *Deployer) runPreDeployCommand(host, image string) error {
 	containerCfg := newPreDeployContainerConfig(d.cfg, image, name)
 	containerCfg.Command = parseCommandArgs(cmd)
 
-	_, err := d.containers.Run(host, containerCfg)
+	_, err := d.containers.RunWithProgress(host, containerCfg, HostProgress(d.log, host))
 	if err != nil {
 		return fmt.Errorf("command exited with error: %w", err)
 	}
@@ -700,7 +1342,146 @@ func (d *Deployer) waitForHealthy(host, container string) error {
 }
 
 func (d *Deployer) registerWithProxy(host, upstream string) error {
-	return d.proxy.RegisterService(host, BuildProxyServiceConfig(d.cfg, []string{upstream}, nil))
+	service := d.proxyRoute(host, upstream)
+	return d.forEachProxyNode(host, func(proxyNode string) error {
+		return d.proxy.RegisterService(proxyNode, service)
+	})
+}
+
+// proxyRoute returns the route registered for upstream on host, carrying
+// the upstreams of its peers when a load-balancer tier is used.
+func (d *Deployer) proxyRoute(host, upstream string) *proxy.ServiceConfig {
+	upstreams := append(d.peerUpstreams(host), upstream)
+	return BuildProxyServiceConfig(d.cfg, upstreams, nil)
+}
+
+// peerUpstreams returns the live upstreams of every other web host when a
+// load-balancer tier is used. A full route registration from one host must
+// carry the whole fleet, otherwise it would drop traffic to its peers. Hosts
+// whose container is missing or unresolvable are left out of the route.
+func (d *Deployer) peerUpstreams(host string) []string {
+	if !d.cfg.UsesProxyTier() {
+		return nil
+	}
+	resolve := d.peerUpstream
+	if resolve == nil {
+		resolve = d.runningUpstream
+	}
+	stableName := RoleContainerName(d.cfg, "web")
+	var upstreams []string
+	for _, peer := range d.cfg.GetRoleHosts("web") {
+		if peer == host {
+			continue
+		}
+		upstream, err := resolve(peer, stableName)
+		if err != nil {
+			d.log.Debug("Skipping %s upstream on %s: %v", stableName, peer, err)
+			continue
+		}
+		upstreams = append(upstreams, upstream)
+	}
+	return upstreams
+}
+
+// runningUpstream returns the upstream of container on host, which must be
+// running.
+func (d *Deployer) runningUpstream(host, container string) (string, error) {
+	running, err := d.containers.IsRunning(host, container)
+	if err != nil {
+		return "", err
+	}
+	if !running {
+		return "", fmt.Errorf("not running")
+	}
+	return d.upstreamAddr(host, container)
+}
+
+// trafficWeights returns the per-host traffic weights recorded on the first
+// load balancer. Without a load-balancer tier, or when they cannot be read,
+// every host has the default weight.
+func (d *Deployer) trafficWeights() *TrafficWeights {
+	if !d.cfg.UsesProxyTier() {
+		return nil
+	}
+	lbHosts := d.cfg.GetProxyHosts()
+	if len(lbHosts) == 0 {
+		return nil
+	}
+	weights, err := LoadTrafficWeights(d.sshClient, lbHosts[0], d.cfg.SSH.User, d.cfg.Service)
+	if err != nil {
+		d.log.Warn("Failed to read traffic weights from %s: %v", lbHosts[0], err)
+		return nil
+	}
+	return weights
+}
+
+// applyTrafficWeights rebuilds the route on every load balancer so each web
+// host receives traffic in proportion to its weight. Add and remove swaps
+// during a rollout leave a single entry per upstream, so the route is
+// rebuilt once the host is finalized.
+func (d *Deployer) applyTrafficWeights(weights *TrafficWeights) error {
+	hosts := d.cfg.GetRoleHosts("web")
+	upstreams := WeightedHostUpstreams(hosts, WebHostUpstreams(d.cfg, d.containers), weights)
+	if len(upstreams) == 0 {
+		return fmt.Errorf("no web host has a running container with a non-zero weight")
+	}
+	service := BuildProxyServiceConfig(d.cfg, nil, upstreams)
+	for _, lbHost := range d.cfg.GetProxyHosts() {
+		if err := d.proxy.RegisterService(lbHost, service); err != nil {
+			return fmt.Errorf("proxy %s: %w", lbHost, err)
+		}
+	}
+	return nil
+}
+
+// proxyNodes returns the hosts whose Caddy routes traffic to app containers
+// on host: the host itself when co-located, or every load-balancer host when
+// proxy.hosts_role is set.
+func (d *Deployer) proxyNodes(host string) []string {
+	if d.cfg.UsesProxyTier() {
+		return d.cfg.GetProxyHosts()
+	}
+	return []string{host}
+}
+
+// forEachProxyNode applies fn to each proxy serving host, stopping at the
+// first failure so callers can run their existing restore logic.
+func (d *Deployer) forEachProxyNode(host string, fn func(proxyNode string) error) error {
+	for _, proxyNode := range d.proxyNodes(host) {
+		if err := fn(proxyNode); err != nil {
+			if proxyNode != host {
+				return fmt.Errorf("proxy %s: %w", proxyNode, err)
+			}
+			return err
+		}
+	}
+	return nil
+}
+
+func (d *Deployer) addUpstream(host, proxyHost, upstream string) error {
+	return d.forEachProxyNode(host, func(proxyNode string) error {
+		return d.proxy.AddUpstream(proxyNode, proxyHost, upstream)
+	})
+}
+
+func (d *Deployer) removeUpstream(host, proxyHost, upstream string) error {
+	return d.forEachProxyNode(host, func(proxyNode string) error {
+		return d.proxy.RemoveUpstream(proxyNode, proxyHost, upstream)
+	})
+}
+
+// swapUpstream replaces remove with add in the route of proxyHost on every
+// proxy serving host, each proxy in a single batch.
+func (d *Deployer) swapUpstream(host, proxyHost, add, remove string) error {
+	return d.forEachProxyNode(host, func(proxyNode string) error {
+		return d.proxy.Batch(proxyNode).AddUpstream(proxyHost, add).RemoveUpstream(proxyHost, remove).Apply()
+	})
+}
+
+func (d *Deployer) drainUpstream(host, upstream string, timeout time.Duration) error {
+	return d.forEachProxyNode(host, func(proxyNode string) error {
+		return d.proxy.DrainUpstream(proxyNode, upstream, timeout)
+	})
 }
 
 // BuildProxyServiceConfig maps application configuration and discovered
@@ -710,7 +1491,7 @@ func BuildProxyServiceConfig(cfg *config.Config, upstreams []string, weights []p
 	if !cfg.Proxy.Healthcheck.DisableLiveness && strings.TrimSpace(cfg.Proxy.Healthcheck.LivenessCmd) == "" {
 		livenessPath = cfg.Proxy.Healthcheck.GetLivenessPath()
 	}
-	return &proxy.ServiceConfig{
+	service := &proxy.ServiceConfig{
 		Name:                  cfg.Service,
 		Host:                  cfg.Proxy.PrimaryHost(),
 		Hosts:                 cfg.Proxy.AllHosts(),
@@ -722,25 +1503,87 @@ func BuildProxyServiceConfig(cfg *config.Config, upstreams []string, weights []p
 		HealthTimeout:         cfg.Proxy.Healthcheck.Timeout,
 		ResponseTimeout:       cfg.Proxy.ResponseTimeout,
 		ResponseHeaderTimeout: cfg.Proxy.ResponseHeaderTimeout,
+		WriteTimeout:          cfg.Proxy.WriteTimeout,
+		IdleTimeout:           cfg.Proxy.IdleTimeout,
+		DialTimeout:           cfg.Proxy.DialTimeout,
+		TryDuration:           cfg.Proxy.TryDuration(),
+		TryInterval:           cfg.Proxy.Failover.TryInterval,
+		Retries:               cfg.Proxy.Failover.Retries,
+		FailDuration:          cfg.Proxy.Failover.FailDuration,
+		MaxFails:              cfg.Proxy.Failover.MaxFails,
 		ForwardHeaders:        cfg.Proxy.ForwardHeaders,
 		BufferRequests:        cfg.Proxy.Buffering.Requests,
 		BufferResponses:       cfg.Proxy.Buffering.Responses,
+		MaxRequests:           cfg.Proxy.MaxRequests,
+		MaxConnections:        cfg.Proxy.MaxConnections,
 		MaxRequestBody:        cfg.Proxy.Buffering.MaxRequestBody,
 		BufferMemory:          cfg.Proxy.Buffering.Memory,
 		HTTPS:                 cfg.Proxy.SSL,
+		AllowCountries:        cfg.Proxy.AllowCountries,
+		DenyCountries:         cfg.Proxy.DenyCountries,
+		LogUpstream:           cfg.Proxy.Logging.AccessLog(),
+		Static:                ProxyStatic(cfg),
 	}
+	if cfg.Proxy.GeoIP.Enabled() {
+		service.GeoIPDatabase = cfg.Proxy.GeoIP.ContainerPath()
+	}
+	return service
 }
 
 func (d *Deployer) upstreamAddr(host, container string) (string, error) {
-	if !d.cfg.UseHostPortUpstreams() {
-		return fmt.Sprintf("%s:%d", container, d.cfg.Proxy.AppPort), nil
+	return ResolveUpstream(d.cfg, d.containers, host, container)
+}
+
+// pinImageDigest resolves image's tag to the digest it points at on host,
+// pulling it first unless skipPull is set, and returns the digest reference.
+func (d *Deployer) pinImageDigest(host, image string, skipPull bool) (string, string, error) {
+	if !skipPull {
+		if err := d.images.PullWithProgress(host, image, PullProgress(d.log, host)); err != nil {
+			return "", "", fmt.Errorf("pull failed on %s: %w", host, err)
+		}
 	}
 
-	port, err := d.containers.HostPort(host, container, d.cfg.Proxy.AppPort)
+	getDigest := d.imageDigest
+	if getDigest == nil {
+		getDigest = d.images.GetDigest
+	}
+	digest, err := getDigest(host, image)
 	if err != nil {
-		return "", fmt.Errorf("failed to resolve host port for %s on %s: %w", container, host, err)
+		return "", "", err
 	}
-	return fmt.Sprintf("127.0.0.1:%d", port), nil
+	if !strings.HasPrefix(digest, "sha256:") {
+		return "", "", fmt.Errorf("%s reported no registry digest for %s", host, image)
+	}
+	return fmt.Sprintf("%s@%s", stripImageTag(image), digest), digest, nil
+}
+
+// ResolveTagDigest pulls the image tag that a deploy of version would use
+// on the first target host and returns the tag and the registry digest it
+// points at.
+func (d *Deployer) ResolveTagDigest(ctx context.Context, version string) (string, string, error) {
+	d = d.withContext(ctx)
+	image, _ := resolveImage(d.cfg.Image, version, "")
+	if strings.Contains(image, "@") {
+		return "", "", fmt.Errorf("%s is pinned to a digest; there is no tag to resolve", image)
+	}
+	targets, err := d.getTargets(nil)
+	if err != nil {
+		return "", "", err
+	}
+	if len(targets) == 0 {
+		return "", "", fmt.Errorf("no deployment targets")
+	}
+	host := targets[0].Host
+	if len(d.cfg.RegistryLogins(image)) > 0 {
+		if err := d.loginToRegistry([]string{host}, image); err != nil {
+			return "", "", fmt.Errorf("failed to login to registry: %w", err)
+		}
+	}
+	_, digest, err := d.pinImageDigest(host, image, false)
+	if err != nil {
+		return "", "", fmt.Errorf("failed to resolve %s to a digest: %w", image, err)
+	}
+	return image, digest, nil
 }
 
 // verifyImageDigest checks that the pulled image has the same digest on all
@@ -783,16 +1626,17 @@ func (d *Deployer) verifyImageDigest(hosts []string, image string) (string, erro
 	return refDigest, nil
 }
 
-func (d *Deployer) pullImageOnHosts(hosts []string, image string) error {
-	errors := d.images.PullAll(hosts, image)
+// pullImageOnHosts pulls image on hosts and returns how long each host took.
+func (d *Deployer) pullImageOnHosts(hosts []string, image string) (map[string]time.Duration, error) {
+	durations, errors := pullImage(d.cfg, d.images, d.log, hosts, image)
 	if len(errors) > 0 {
 		var errMsgs []string
 		for host, err := range errors {
 			errMsgs = append(errMsgs, fmt.Sprintf("%s: %v", host, err))
 		}
-		return fmt.Errorf("pull failed on hosts: %s", strings.Join(errMsgs, "; "))
+		return nil, fmt.Errorf("pull failed on hosts: %s", strings.Join(errMsgs, "; "))
 	}
-	return nil
+	return durations, nil
 }
 
 func (d *Deployer) getTargets(opts *DeployOptions) ([]deploymentTarget, error) {
@@ -803,6 +1647,9 @@ func (d *Deployer) getTargets(opts *DeployOptions) ([]deploymentTarget, error) {
 	roles := append([]string(nil), opts.Roles...)
 	if len(roles) == 0 {
 		for role := range d.cfg.Servers {
+			if d.cfg.IsProxyTierRole(role) {
+				continue
+			}
 			roles = append(roles, role)
 		}
 		sort.Strings(roles)
@@ -821,6 +1668,9 @@ func (d *Deployer) getTargets(opts *DeployOptions) ([]deploymentTarget, error) {
 		if !ok {
 			return nil, fmt.Errorf("unknown role %q", role)
 		}
+		if d.cfg.IsProxyTierRole(role) {
+			return nil, fmt.Errorf("role %q is the proxy load-balancer tier and runs no app containers", role)
+		}
 		for _, host := range roleCfg.Hosts {
 			if len(requestedHosts) > 0 {
 				if _, ok := requestedHosts[host]; !ok {
@@ -906,6 +1756,76 @@ func (d *Deployer) reapStaleTempContainers(host, stableName string) {
 			d.log.Debug("Orphan cleanup: failed to remove %s: %v", c.Name, err)
 		}
 	}
+	d.pruneAppSocketDirs(host)
+	d.pruneAssetDirs(host)
+}
+
+// pruneAppSocketDirs removes the service's socket directories that no
+// container mounts anymore, such as those of replaced containers. Like
+// reapStaleTempContainers it is best-effort and expects the deploy lock.
+func (d *Deployer) pruneAppSocketDirs(host string) {
+	if !d.cfg.UsesAppSocket() {
+		return
+	}
+	d.pruneUnmountedDirs(host, "Socket cleanup", AppSocketsDir(d.cfg)+"/"+d.cfg.Service, path.Dir(d.cfg.Proxy.AppSocket))
+}
+
+// pruneAssetDirs removes the service's asset directories that no container
+// mounts anymore. Directories of retained containers are kept, so a
+// rollback to one still serves its assets.
+func (d *Deployer) pruneAssetDirs(host string) {
+	if d.cfg.AssetPath == "" {
+		return
+	}
+	d.pruneUnmountedDirs(host, "Asset cleanup", AssetsDir(d.cfg)+"/"+d.cfg.Service, d.cfg.AssetPath)
+}
+
+// pruneUnmountedDirs removes the entries of dir that no container of the
+// service mounts at destination. It is best-effort; failures are logged at
+// debug level under topic.
+func (d *Deployer) pruneUnmountedDirs(host, topic, dir, destination string) {
+	containers, err := d.containers.List(host, true, map[string]string{"label": "azud.service=" + d.cfg.Service})
+	if err != nil {
+		d.log.Debug("%s: failed to list containers on %s: %v", topic, host, err)
+		return
+	}
+	inUse := make(map[string]bool)
+	for _, c := range containers {
+		source, err := d.containers.MountSource(host, c.Name, destination)
+		if err != nil {
+			// Without every mount known, an in-use directory could be removed.
+			d.log.Debug("%s: failed to inspect %s: %v", topic, c.Name, err)
+			return
+		}
+		if source != "" {
+			inUse[path.Base(source)] = true
+		}
+	}
+
+	result, err := d.sshClient.Execute(host, "ls -1 "+shell.QuoteRemotePath(dir))
+	if err != nil || result.ExitCode != 0 {
+		return
+	}
+	var stale []string
+	for _, key := range strings.Fields(result.Stdout) {
+		if !inUse[key] {
+			stale = append(stale, shell.QuoteRemotePath(dir+"/"+key))
+		}
+	}
+	if len(stale) == 0 {
+		return
+	}
+	// Socket directories are chowned to the container user (:U), and
+	// assets keep the owners they had in the image, which only the user
+	// namespace can undo under rootless Podman.
+	rm := "rm -rf "
+	if d.cfg.Podman.Rootless {
+		rm = "podman unshare rm -rf "
+	}
+	d.log.Debug("%s: removing %d stale directories on %s", topic, len(stale), host)
+	if result, err := d.sshClient.Execute(host, rm+strings.Join(stale, " ")); err != nil || result.ExitCode != 0 {
+		d.log.Debug("%s: failed to remove stale directories on %s", topic, host)
+	}
 }
 
 // stripImageTag removes a trailing :tag or @digest from an image reference,
@@ -921,6 +1841,36 @@ func stripImageTag(image string) string {
 	return image
 }
 
+// resolveImage returns the image reference to deploy for the configured
+// image and the requested version or digest, and the version it is recorded
+// under.
+func resolveImage(configured, version, digest string) (string, string) {
+	switch {
+	case digest != "":
+		// Promoted digest: pin the image regardless of tag.
+		if version == "" {
+			version = digest
+		}
+		return fmt.Sprintf("%s@%s", stripImageTag(configured), digest), version
+	case strings.HasPrefix(version, "sha256:"):
+		// A digest recorded as the version, as when rolling back to a
+		// digest deploy.
+		return fmt.Sprintf("%s@%s", stripImageTag(configured), version), version
+	case version != "":
+		// Explicit version: replace any existing tag or digest.
+		return fmt.Sprintf("%s:%s", stripImageTag(configured), version), version
+	case strings.Contains(configured, "@"):
+		// Digest-pinned image (no explicit version): use the digest as version.
+		return configured, configured[strings.Index(configured, "@")+1:]
+	case hasImageTag(configured):
+		// Tagged image: extract the tag as the version.
+		return configured, configured[strings.LastIndex(configured, ":")+1:]
+	default:
+		// No tag and no version: default to latest.
+		return configured + ":latest", "latest"
+	}
+}
+
 // hasImageTag reports whether an image reference carries an explicit :tag
 // (ignoring a registry port such as localhost:5000/img).
 func hasImageTag(image string) bool {
@@ -929,16 +1879,12 @@ func hasImageTag(image string) bool {
 }
 
 // Rollback re-deploys a previous version.
-func (d *Deployer) Rollback(ctx context.Context, version, destination string, hosts []string) error {
+func (d *Deployer) Rollback(ctx context.Context, version string, opts *DeployOptions) error {
 	d.log.Header("Rolling back to %s", version)
 
-	opts := &DeployOptions{
-		Version:     version,
-		Destination: destination,
-		Hosts:       hosts,
-	}
-
-	return d.Deploy(ctx, opts)
+	rollbackOpts := *opts
+	rollbackOpts.Version = version
+	return d.Deploy(ctx, &rollbackOpts)
 }
 
 func (d *Deployer) Redeploy(ctx context.Context, opts *DeployOptions) error {
@@ -951,9 +1897,8 @@ func (d *Deployer) Stop(hosts []string) error {
 }
 
 func (d *Deployer) StopRoles(hosts, roles []string) error {
-	stopTimeout := d.cfg.Deploy.GetStopTimeout()
 	return d.runOnTargets("stop", hosts, roles, func(target deploymentTarget) error {
-		return d.containers.Stop(target.Host, RoleContainerName(d.cfg, target.Role), stopTimeout)
+		return d.containers.Stop(target.Host, RoleContainerName(d.cfg, target.Role), d.cfg.RoleStopTimeout(target.Role))
 	})
 }
 
@@ -972,12 +1917,73 @@ func (d *Deployer) Restart(hosts []string) error {
 }
 
 func (d *Deployer) RestartRoles(hosts, roles []string) error {
-	stopTimeout := d.cfg.Deploy.GetStopTimeout()
 	return d.runOnTargets("restart", hosts, roles, func(target deploymentTarget) error {
-		return d.containers.Restart(target.Host, RoleContainerName(d.cfg, target.Role), stopTimeout)
+		return d.containers.Restart(target.Host, RoleContainerName(d.cfg, target.Role), d.cfg.RoleStopTimeout(target.Role))
 	})
 }
 
+// Decommission takes host out of service after the application has moved
+// elsewhere. Proxies stop sending it traffic (a co-located proxy loses the
+// service route, a load-balancer tier loses the host's upstream), in-flight
+// requests drain, and the host's application containers are removed.
+func (d *Deployer) Decommission(host string) error {
+	targets, err := d.getTargets(&DeployOptions{Hosts: []string{host}})
+	if err != nil {
+		return err
+	}
+
+	var errs []string
+	for _, target := range targets {
+		stableName := RoleContainerName(d.cfg, target.Role)
+		exists, err := d.containers.Exists(host, stableName)
+		if err != nil {
+			errs = append(errs, fmt.Sprintf("%s/%s: %v", host, target.Role, err))
+			continue
+		}
+		if !exists {
+			continue
+		}
+
+		if IsProxyRole(target.Role) {
+			upstream, upstreamErr := d.upstreamAddr(host, stableName)
+			if d.cfg.UsesProxyTier() {
+				if upstreamErr == nil {
+					for _, serviceHost := range d.cfg.Proxy.AllHosts() {
+						if err := d.removeUpstream(host, serviceHost, upstream); err != nil {
+							errs = append(errs, fmt.Sprintf("%s/%s: %v", host, target.Role, err))
+						}
+					}
+				}
+			} else {
+				for _, serviceHost := range d.cfg.Proxy.AllHosts() {
+					if err := d.proxy.DeregisterService(host, serviceHost); err != nil {
+						errs = append(errs, fmt.Sprintf("%s/%s: %v", host, target.Role, err))
+					}
+				}
+			}
+			if upstreamErr == nil && d.cfg.Deploy.DrainTimeout > 0 {
+				if err := d.drainUpstream(host, upstream, d.cfg.Deploy.DrainTimeout); err != nil {
+					d.log.Warn("Drain failed for %s: %v", upstream, err)
+				}
+			}
+		}
+
+		if err := d.containers.Stop(host, stableName, d.cfg.RoleStopTimeout(target.Role)); err != nil {
+			d.log.Debug("Stop %s on %s: %v", stableName, host, err)
+		}
+		if err := d.containers.Remove(host, stableName, true); err != nil {
+			errs = append(errs, fmt.Sprintf("%s/%s: %v", host, target.Role, err))
+			continue
+		}
+		d.log.HostSuccess(host, "%s role removed", target.Role)
+	}
+
+	if len(errs) > 0 {
+		return fmt.Errorf("decommission failed: %s", strings.Join(errs, "; "))
+	}
+	return nil
+}
+
 // rollbackTargets reverts role/host pairs that succeeded, restoring the
 // previous version. Every target is attempted and failures are aggregated.
 func (d *Deployer) rollbackTargets(ctx context.Context, targets []deploymentTarget, previousVersion string) error {
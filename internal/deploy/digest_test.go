@@ -60,3 +60,29 @@ func TestVerifyImageDigestAcrossHosts(t *testing.T) {
 		t.Fatalf("expected mismatch, got %v", err)
 	}
 }
+
+func TestPinImageDigest(t *testing.T) {
+	d := &Deployer{
+		cfg: &config.Config{},
+		log: output.DefaultLogger,
+		imageDigest: func(host, image string) (string, error) {
+			if image != "localhost:5000/acme/app:v1" {
+				t.Fatalf("digest requested for %q", image)
+			}
+			return "sha256:aaa", nil
+		},
+	}
+
+	pinned, digest, err := d.pinImageDigest("one", "localhost:5000/acme/app:v1", true)
+	if err != nil {
+		t.Fatalf("pinImageDigest() error: %v", err)
+	}
+	if pinned != "localhost:5000/acme/app@sha256:aaa" || digest != "sha256:aaa" {
+		t.Fatalf("pinImageDigest() = (%q, %q)", pinned, digest)
+	}
+
+	d.imageDigest = func(host, image string) (string, error) { return "", nil }
+	if _, _, err := d.pinImageDigest("one", "localhost:5000/acme/app:v1", true); err == nil || !strings.Contains(err.Error(), "no registry digest") {
+		t.Fatalf("expected missing digest error, got %v", err)
+	}
+}
@@ -0,0 +1,206 @@
+package deploy
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"time"
+
+	"github.com/lemonity-org/azud/internal/config"
+	"github.com/lemonity-org/azud/internal/output"
+	"github.com/lemonity-org/azud/internal/state"
+)
+
+// Freeze blocks deploys of a service to a destination until a time.
+type Freeze struct {
+	Service     string    `json:"service"`
+	Destination string    `json:"destination,omitempty"`
+	Until       time.Time `json:"until"`
+	Reason      string    `json:"reason"`
+	By          string    `json:"by"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+// FreezeStore keeps deploy freezes in the local Azud state directory (or
+// AZUD_STATE_DIR), next to deployment history. Freezes are not shared with
+// deployers on other machines.
+type FreezeStore struct {
+	path    string
+	initErr error
+}
+
+// NewFreezeStore opens the freeze store in the durable local state
+// directory.
+func NewFreezeStore() *FreezeStore {
+	basePath, err := state.LocalDir()
+	if err != nil {
+		return &FreezeStore{initErr: err}
+	}
+	return &FreezeStore{path: filepath.Join(basePath, "freezes.json")}
+}
+
+// Set records freeze, replacing any freeze of the same service and
+// destination. Expired freezes are dropped.
+func (s *FreezeStore) Set(freeze *Freeze) error {
+	if freeze.CreatedAt.IsZero() {
+		freeze.CreatedAt = time.Now().UTC()
+	}
+	return s.update(func(freezes []*Freeze) []*Freeze {
+		freezes = slices.DeleteFunc(freezes, func(f *Freeze) bool {
+			return f.Service == freeze.Service && f.Destination == freeze.Destination
+		})
+		return append(freezes, freeze)
+	})
+}
+
+// Lift removes the freeze of service to destination and returns it, or nil
+// when none is active.
+func (s *FreezeStore) Lift(service, destination string) (*Freeze, error) {
+	var lifted *Freeze
+	err := s.update(func(freezes []*Freeze) []*Freeze {
+		return slices.DeleteFunc(freezes, func(f *Freeze) bool {
+			if f.Service == service && f.Destination == destination {
+				lifted = f
+				return true
+			}
+			return false
+		})
+	})
+	return lifted, err
+}
+
+// Active returns the freeze of service to destination in effect at now, or
+// nil when there is none.
+func (s *FreezeStore) Active(service, destination string, now time.Time) (*Freeze, error) {
+	if s.initErr != nil {
+		return nil, fmt.Errorf("freeze state unavailable: %w", s.initErr)
+	}
+	freezes, err := s.read()
+	if err != nil {
+		return nil, err
+	}
+	for _, freeze := range freezes {
+		if freeze.Service == service && freeze.Destination == destination && now.Before(freeze.Until) {
+			return freeze, nil
+		}
+	}
+	return nil, nil
+}
+
+// update rewrites the store with fn's result under the store lock.
+func (s *FreezeStore) update(fn func([]*Freeze) []*Freeze) error {
+	if s.initErr != nil {
+		return fmt.Errorf("freeze state unavailable: %w", s.initErr)
+	}
+	dir := filepath.Dir(s.path)
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return fmt.Errorf("failed to create freeze directory: %w", err)
+	}
+	return state.WithFileLock(filepath.Join(dir, ".freezes.lock"), func() error {
+		freezes, err := s.read()
+		if err != nil {
+			return err
+		}
+		now := time.Now()
+		freezes = slices.DeleteFunc(fn(freezes), func(f *Freeze) bool {
+			return !now.Before(f.Until)
+		})
+
+		data, err := json.MarshalIndent(freezes, "", "  ")
+		if err != nil {
+			return fmt.Errorf("failed to marshal freezes: %w", err)
+		}
+		tmp := s.path + ".tmp"
+		if err := os.WriteFile(tmp, data, 0600); err != nil {
+			return fmt.Errorf("failed to write freezes: %w", err)
+		}
+		if err := os.Rename(tmp, s.path); err != nil {
+			_ = os.Remove(tmp)
+			return fmt.Errorf("failed to save freezes: %w", err)
+		}
+		return nil
+	})
+}
+
+func (s *FreezeStore) read() ([]*Freeze, error) {
+	data, err := os.ReadFile(s.path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read freezes: %w", err)
+	}
+	var freezes []*Freeze
+	if err := json.Unmarshal(data, &freezes); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", s.path, err)
+	}
+	return freezes, nil
+}
+
+// deployBlocked returns why a deploy of cfg's service to destination may not
+// run at now: an active freeze or a time outside deploy.windows. It returns
+// "" when the deploy may run.
+func deployBlocked(cfg *config.Config, freezes *FreezeStore, destination string, now time.Time) (string, error) {
+	freeze, err := freezes.Active(cfg.Service, destination, now)
+	if err != nil {
+		return "", err
+	}
+	if freeze != nil {
+		return fmt.Sprintf("deploys of %s to %s are frozen until %s by %s: %s",
+			cfg.Service, DestinationLabel(destination), freeze.Until.Local().Format(time.DateTime), freeze.By, freeze.Reason), nil
+	}
+
+	allowed, err := cfg.Deploy.Windows.Allows(now)
+	if err != nil {
+		return "", err
+	}
+	if !allowed {
+		return fmt.Sprintf("%s is outside the deployment windows %s", now.Local().Format("Mon 15:04"), cfg.Deploy.Windows), nil
+	}
+	return "", nil
+}
+
+// enforceDeployWindow stops a deploy that deployBlocked rejects unless
+// override is set. It returns the reason an override bypassed, after
+// recording the override in the audit log; if the audit log cannot be
+// written the deploy does not run.
+func enforceDeployWindow(cfg *config.Config, log *output.Logger, freezes *FreezeStore, destination, version string, override bool) (string, error) {
+	reason, err := deployBlocked(cfg, freezes, destination, time.Now())
+	if err != nil || reason == "" {
+		return "", err
+	}
+	if !override {
+		return "", fmt.Errorf("%s; pass --override to deploy anyway", reason)
+	}
+
+	log.Warn("Overriding: %s", reason)
+	if err := NewAuditLog().Record(AuditEntry{
+		Service: cfg.Service,
+		Action:  "deploy.override",
+		Actor:   CurrentUser(),
+		Status:  "success",
+		Details: map[string]string{
+			"destination": destination,
+			"version":     version,
+			"reason":      reason,
+		},
+	}); err != nil {
+		return "", fmt.Errorf("failed to record the override in the audit log: %w", err)
+	}
+	return reason, nil
+}
+
+// checkDeployWindow enforces the freeze and deployment windows on record,
+// noting an override in its metadata.
+func (d *Deployer) checkDeployWindow(record *DeploymentRecord, opts *DeployOptions) error {
+	overridden, err := enforceDeployWindow(d.cfg, d.log, d.freezes, record.Destination, record.Version, opts.Override)
+	if err != nil {
+		return err
+	}
+	if overridden != "" {
+		record.Metadata["override"] = overridden
+	}
+	return nil
+}
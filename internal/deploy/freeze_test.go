@@ -0,0 +1,75 @@
+package deploy
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/lemonity-org/azud/internal/config"
+	"github.com/lemonity-org/azud/internal/output"
+)
+
+func TestFreezeStore(t *testing.T) {
+	t.Setenv("AZUD_STATE_DIR", t.TempDir())
+	store := NewFreezeStore()
+	now := time.Now()
+
+	if err := store.Set(&Freeze{Service: "shop", Destination: "production", Until: now.Add(time.Hour), Reason: "holiday", By: "alice"}); err != nil {
+		t.Fatalf("Set: %v", err)
+	}
+	if err := store.Set(&Freeze{Service: "shop", Until: now.Add(-time.Hour), Reason: "expired"}); err != nil {
+		t.Fatalf("Set: %v", err)
+	}
+
+	freeze, err := store.Active("shop", "production", now)
+	if err != nil || freeze == nil || freeze.Reason != "holiday" {
+		t.Fatalf("Active(production) = %+v, %v; want the holiday freeze", freeze, err)
+	}
+	if freeze, err := store.Active("shop", "", now); err != nil || freeze != nil {
+		t.Fatalf("Active(base) = %+v, %v; want none", freeze, err)
+	}
+	if freeze, err := store.Active("shop", "production", now.Add(2*time.Hour)); err != nil || freeze != nil {
+		t.Fatalf("Active after expiry = %+v, %v; want none", freeze, err)
+	}
+
+	lifted, err := store.Lift("shop", "production")
+	if err != nil || lifted == nil || lifted.By != "alice" {
+		t.Fatalf("Lift = %+v, %v", lifted, err)
+	}
+	if freeze, err := store.Active("shop", "production", now); err != nil || freeze != nil {
+		t.Fatalf("Active after lift = %+v, %v; want none", freeze, err)
+	}
+}
+
+func TestEnforceDeployWindow(t *testing.T) {
+	t.Setenv("AZUD_STATE_DIR", t.TempDir())
+	freezes := NewFreezeStore()
+	cfg := &config.Config{Service: "shop"}
+
+	if reason, err := enforceDeployWindow(cfg, output.DefaultLogger, freezes, "production", "v2", false); err != nil || reason != "" {
+		t.Fatalf("unrestricted deploy = %q, %v", reason, err)
+	}
+
+	if err := freezes.Set(&Freeze{Service: "shop", Destination: "production", Until: time.Now().Add(time.Hour), Reason: "launch", By: "alice"}); err != nil {
+		t.Fatal(err)
+	}
+	_, err := enforceDeployWindow(cfg, output.DefaultLogger, freezes, "production", "v2", false)
+	if err == nil || !strings.Contains(err.Error(), "frozen") || !strings.Contains(err.Error(), "launch") {
+		t.Fatalf("frozen deploy error = %v", err)
+	}
+
+	reason, err := enforceDeployWindow(cfg, output.DefaultLogger, freezes, "production", "v2", true)
+	if err != nil || !strings.Contains(reason, "launch") {
+		t.Fatalf("override = %q, %v", reason, err)
+	}
+	entries, err := NewAuditLog().Entries("shop")
+	if err != nil || len(entries) != 1 || entries[0].Action != "deploy.override" || entries[0].Details["version"] != "v2" {
+		t.Fatalf("audit entries = %+v, %v; want one override", entries, err)
+	}
+
+	// A destination without a freeze is still held to deploy.windows.
+	cfg.Deploy.Windows.Allowed = []config.DeployWindow{{Days: []string{"mon"}, Hours: "00:00-00:01"}, {Days: []string{"tue"}, Hours: "00:00-00:01"}}
+	if reason, err := deployBlocked(cfg, freezes, "staging", time.Date(2026, 10, 16, 12, 0, 0, 0, time.Local)); err != nil || !strings.Contains(reason, "outside the deployment windows") {
+		t.Fatalf("deployBlocked outside windows = %q, %v", reason, err)
+	}
+}
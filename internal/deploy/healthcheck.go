@@ -37,6 +37,15 @@ func BuildHTTPCheckCommand(port int, path string) string {
 	)
 }
 
+// BuildUnixSocketCheckCommand builds the HTTP GET of BuildHTTPCheckCommand for
+// an app listening on a unix socket. Only curl can dial a unix socket.
+func BuildUnixSocketCheckCommand(socket, path string) string {
+	if socket == "" || path == "" {
+		return ""
+	}
+	return fmt.Sprintf("curl -fsS --unix-socket %s %s >/dev/null", strconv.Quote(socket), strconv.Quote("http://localhost"+path))
+}
+
 // LivenessCommand returns the configured healthcheck command or empty if disabled.
 func LivenessCommand(cfg *config.Config) string {
 	if cfg == nil {
@@ -57,6 +66,9 @@ func LivenessCommand(cfg *config.Config) string {
 		return ""
 	}
 
+	if cfg.UsesAppSocket() {
+		return BuildUnixSocketCheckCommand(cfg.Proxy.AppSocket, path)
+	}
 	return BuildHTTPCheckCommand(cfg.Proxy.AppPort, path)
 }
 
@@ -129,3 +141,47 @@ func BuildHTTPCheckHelperCommand(container string, port int, path, image, pullPo
 		quotedURL,
 	)
 }
+
+// BuildUnixSocketCheckExecCandidates builds the podman exec readiness check
+// for an app listening on a unix socket.
+func BuildUnixSocketCheckExecCandidates(container, socket, path string) []string {
+	if container == "" || socket == "" || path == "" {
+		return nil
+	}
+	return []string{
+		fmt.Sprintf("podman exec %s curl -fsS --unix-socket %s %s", shell.Quote(container), strconv.Quote(socket), strconv.Quote("http://localhost"+path)),
+	}
+}
+
+// BuildUnixSocketCheckHelperCommand builds the helper container readiness
+// check for an app listening on a unix socket. The helper mounts the target
+// container's volumes to reach the socket.
+func BuildUnixSocketCheckHelperCommand(container, socket, path, image, pullPolicy string) string {
+	if strings.TrimSpace(container) == "" || socket == "" || path == "" {
+		return ""
+	}
+
+	image = strings.TrimSpace(image)
+	if image == "" {
+		image = config.DefaultHealthcheckHelperImage
+	}
+
+	pullPolicy = strings.TrimSpace(pullPolicy)
+	if pullPolicy == "" {
+		pullPolicy = defaultHealthcheckPull
+	}
+
+	name := fmt.Sprintf("azud-hc-%s", container)
+	name = strings.ReplaceAll(name, "_", "-")
+	name = strings.ReplaceAll(name, ".", "-")
+
+	return fmt.Sprintf(
+		"podman run --rm --pull=%s --volumes-from %s --name %s %s -fsS -o /dev/null --unix-socket %s %s",
+		shell.Quote(pullPolicy),
+		shell.Quote(container),
+		shell.Quote(name),
+		shell.Quote(image),
+		strconv.Quote(socket),
+		strconv.Quote("http://localhost"+path),
+	)
+}
@@ -45,6 +45,27 @@ func TestBuildHTTPCheckHelperCommandUsesPinnedDefault(t *testing.T) {
 	}
 }
 
+func TestUnixSocketChecks(t *testing.T) {
+	cfg := &config.Config{}
+	cfg.Proxy.AppSocket = "/run/app/app.sock"
+	cfg.Proxy.Healthcheck.Path = "/up"
+
+	want := `--unix-socket "/run/app/app.sock" "http://localhost/up"`
+	if got := LivenessCommand(cfg); !strings.Contains(got, "curl -fsS "+want) {
+		t.Fatalf("socket liveness command = %q", got)
+	}
+	candidates := BuildUnixSocketCheckExecCandidates("app", cfg.Proxy.AppSocket, "/up")
+	if len(candidates) != 1 || candidates[0] != "podman exec app curl -fsS "+want {
+		t.Fatalf("socket exec candidates = %v", candidates)
+	}
+	helper := BuildUnixSocketCheckHelperCommand("app", cfg.Proxy.AppSocket, "/up", "", "")
+	for _, fragment := range []string{"--volumes-from app", config.DefaultHealthcheckHelperImage, want} {
+		if !strings.Contains(helper, fragment) {
+			t.Fatalf("socket helper command %q does not contain %q", helper, fragment)
+		}
+	}
+}
+
 func TestLivenessCommandModes(t *testing.T) {
 	cfg := &config.Config{}
 	cfg.Proxy.AppPort = 3000
@@ -69,6 +69,33 @@ type DeploymentRecord struct {
 
 	// Additional metadata
 	Metadata map[string]string `json:"metadata,omitempty"`
+
+	// Per role/host progress, persisted as each target finishes so a
+	// failed deployment can be resumed
+	Targets []TargetProgress `json:"targets,omitempty"`
+}
+
+// PartialMetadata is the deployment metadata key set on deployments
+// limited to some hosts or roles (--host, --role).
+const PartialMetadata = "partial"
+
+// TargetStatus is the outcome of one role/host pair within a deployment.
+type TargetStatus string
+
+const (
+	TargetPending    TargetStatus = "pending"
+	TargetSuccess    TargetStatus = "success"
+	TargetFailed     TargetStatus = "failed"
+	TargetRolledBack TargetStatus = "rolled_back"
+)
+
+// TargetProgress records how far a deployment got on one role/host pair.
+type TargetProgress struct {
+	Host        string       `json:"host"`
+	Role        string       `json:"role"`
+	Status      TargetStatus `json:"status"`
+	Error       string       `json:"error,omitempty"`
+	CompletedAt time.Time    `json:"completed_at,omitempty"`
 }
 
 // HistoryStore manages deployment history persistence
@@ -290,6 +317,49 @@ func (h *HistoryStore) GetLastSuccessful(service string) (*DeploymentRecord, err
 	return nil, fmt.Errorf("no successful deployments found for %s", service)
 }
 
+// GetLastSuccessfulTo returns the most recent successful deployment of a
+// service to destination ("" is the default destination).
+func (h *HistoryStore) GetLastSuccessfulTo(service, destination string) (*DeploymentRecord, error) {
+	records, err := h.List(service, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, record := range records {
+		if record.Status == StatusSuccess && record.Destination == destination {
+			return record, nil
+		}
+	}
+
+	return nil, fmt.Errorf("no successful deployments of %s to %s found", service, DestinationLabel(destination))
+}
+
+// GetLastDeploymentTo returns the most recent deployment of a service to
+// destination, whatever its status.
+func (h *HistoryStore) GetLastDeploymentTo(service, destination string) (*DeploymentRecord, error) {
+	records, err := h.List(service, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, record := range records {
+		if record.Destination == destination {
+			return record, nil
+		}
+	}
+
+	return nil, fmt.Errorf("no deployments of %s to %s found", service, DestinationLabel(destination))
+}
+
+// DestinationLabel names a destination in messages; the empty destination
+// is the base configuration.
+func DestinationLabel(destination string) string {
+	if destination == "" {
+		return "the default destination"
+	}
+	return destination
+}
+
 // GetLastDeployment returns the most recent deployment for a service
 func (h *HistoryStore) GetLastDeployment(service string) (*DeploymentRecord, error) {
 	records, err := h.List(service, 1)
@@ -393,6 +463,69 @@ func (r *DeploymentRecord) Fail(err error) {
 	}
 }
 
+// Resume marks a failed or interrupted deployment as running again. The
+// original start time is kept so the record is updated in place.
+func (r *DeploymentRecord) Resume() {
+	r.Status = StatusRunning
+	r.CompletedAt = time.Time{}
+	r.Duration = 0
+	r.Error = ""
+	if r.Metadata == nil {
+		r.Metadata = make(map[string]string)
+	}
+	r.Metadata["resumed_at"] = time.Now().Format(time.RFC3339)
+}
+
+// Resumable reports why the deployment cannot be resumed, or nil if it can.
+func (r *DeploymentRecord) Resumable() error {
+	switch r.Status {
+	case StatusFailed, StatusRunning:
+	case StatusSuccess:
+		return fmt.Errorf("deployment %s already completed successfully", r.ID)
+	default:
+		return fmt.Errorf("deployment %s is %s and cannot be resumed", r.ID, r.Status)
+	}
+	if len(r.Targets) == 0 {
+		return fmt.Errorf("deployment %s has no per-host progress recorded", r.ID)
+	}
+	if len(r.PendingTargets()) == 0 {
+		return fmt.Errorf("deployment %s has no remaining targets", r.ID)
+	}
+	return nil
+}
+
+// SetTargetStatus updates the progress of one role/host pair.
+func (r *DeploymentRecord) SetTargetStatus(host, role string, status TargetStatus, err error) {
+	for i := range r.Targets {
+		target := &r.Targets[i]
+		if target.Host != host || target.Role != role {
+			continue
+		}
+		target.Status = status
+		target.Error = ""
+		if err != nil {
+			target.Error = err.Error()
+		}
+		target.CompletedAt = time.Time{}
+		if status != TargetPending {
+			target.CompletedAt = time.Now()
+		}
+		return
+	}
+}
+
+// PendingTargets returns the role/host pairs that have not been deployed
+// successfully, in their original order.
+func (r *DeploymentRecord) PendingTargets() []TargetProgress {
+	var pending []TargetProgress
+	for _, target := range r.Targets {
+		if target.Status != TargetSuccess {
+			pending = append(pending, target)
+		}
+	}
+	return pending
+}
+
 // MarkRolledBack marks the deployment as rolled back
 func (r *DeploymentRecord) MarkRolledBack() {
 	r.Status = StatusRolledBack
@@ -1,12 +1,19 @@
 package deploy
 
 import (
+	"context"
 	"fmt"
+	"io"
 	"os"
 	"path/filepath"
+	"strings"
 	"sync"
 	"testing"
 	"time"
+
+	"github.com/lemonity-org/azud/internal/config"
+	"github.com/lemonity-org/azud/internal/output"
+	"github.com/lemonity-org/azud/internal/ssh"
 )
 
 func TestHistoryStore_Record(t *testing.T) {
@@ -261,6 +268,97 @@ func TestDeploymentRecord_Failure(t *testing.T) {
 	}
 }
 
+func TestDeploymentRecord_ResumeKeepsCompletedTargets(t *testing.T) {
+	tmpDir := t.TempDir()
+	store := NewHistoryStore(tmpDir, 10, nil)
+
+	record := NewDeploymentRecord("test-service", "test:v1", "v1", "", []string{"host1", "host2"})
+	record.Targets = []TargetProgress{
+		{Host: "host1", Role: "web", Status: TargetPending},
+		{Host: "host2", Role: "web", Status: TargetPending},
+	}
+	record.Start()
+	if err := store.Record(record); err != nil {
+		t.Fatalf("record running deployment: %v", err)
+	}
+	if err := record.Resumable(); err != nil {
+		t.Fatalf("interrupted deployment should be resumable: %v", err)
+	}
+
+	record.SetTargetStatus("host1", "web", TargetSuccess, nil)
+	record.SetTargetStatus("host2", "web", TargetFailed, fmt.Errorf("ssh: connection reset"))
+	record.Fail(fmt.Errorf("deployment failed on 1 host(s)"))
+	if err := store.Update(record); err != nil {
+		t.Fatalf("update failed deployment: %v", err)
+	}
+
+	loaded, err := store.Get(record.ID)
+	if err != nil {
+		t.Fatalf("load record: %v", err)
+	}
+	pending := loaded.PendingTargets()
+	if len(pending) != 1 || pending[0].Host != "host2" || pending[0].Error != "ssh: connection reset" {
+		t.Fatalf("pending targets = %#v", pending)
+	}
+
+	loaded.Resume()
+	if loaded.Status != StatusRunning || loaded.Error != "" || !loaded.StartedAt.Equal(record.StartedAt) {
+		t.Fatalf("resumed record = status %s error %q started %s", loaded.Status, loaded.Error, loaded.StartedAt)
+	}
+	loaded.SetTargetStatus("host2", "web", TargetSuccess, nil)
+	loaded.Complete()
+	if err := store.Update(loaded); err != nil {
+		t.Fatalf("update resumed deployment: %v", err)
+	}
+	records, err := store.List("test-service", 0)
+	if err != nil {
+		t.Fatalf("list records: %v", err)
+	}
+	if len(records) != 1 {
+		t.Fatalf("resume should update the record in place, got %d records", len(records))
+	}
+	if err := records[0].Resumable(); err == nil {
+		t.Fatal("completed deployment should not be resumable")
+	}
+}
+
+func TestResumeRefusesSupersededDeployment(t *testing.T) {
+	store := NewHistoryStore(t.TempDir(), 10, nil)
+	started := time.Now().Add(-time.Hour)
+	newRecord := func(version string, started time.Time) *DeploymentRecord {
+		record := NewDeploymentRecord("test-service", "test:"+version, version, "production", []string{"host1"})
+		record.Targets = []TargetProgress{{Host: "host1", Role: "web", Status: TargetPending}}
+		record.Start()
+		record.StartedAt = started
+		return record
+	}
+	failed := newRecord("v1", started)
+	failed.Fail(fmt.Errorf("boom"))
+	newer := newRecord("v2", started.Add(time.Minute))
+	newer.SetTargetStatus("host1", "web", TargetSuccess, nil)
+	newer.Complete()
+	for _, record := range []*DeploymentRecord{failed, newer} {
+		if err := store.Record(record); err != nil {
+			t.Fatalf("record deployment: %v", err)
+		}
+	}
+
+	d := NewDeployer(&config.Config{Service: "test-service"}, ssh.NewClient(&ssh.Config{User: "deploy"}), output.NewLogger(io.Discard, io.Discard, false))
+	d.history = store
+	err := d.Resume(context.Background(), failed.ID, &DeployOptions{Destination: "production"})
+	if err == nil || !strings.Contains(err.Error(), "superseded by "+newer.ID) {
+		t.Fatalf("Resume() error = %v, want superseded by %s", err, newer.ID)
+	}
+}
+
+func TestDeploymentRecord_ResumableRequiresTargetProgress(t *testing.T) {
+	record := NewDeploymentRecord("test-service", "test:v1", "v1", "", []string{"host1"})
+	record.Fail(fmt.Errorf("boom"))
+	if err := record.Resumable(); err == nil {
+		t.Fatal("record without per-target progress should not be resumable")
+	}
+}
+
 func TestGenerateDeploymentID(t *testing.T) {
 	id1 := GenerateDeploymentID()
 	id2 := GenerateDeploymentID()
@@ -24,6 +24,8 @@ type HookContext struct {
 	HookName    string // AZUD_HOOK
 	RecordedAt  string // AZUD_RECORDED_AT (RFC3339)
 	Runtime     string // AZUD_RUNTIME (seconds, post-deploy only)
+	Container   string // AZUD_CONTAINER (restart-loop only)
+	Restarts    string // AZUD_RESTARTS (restart-loop only)
 }
 
 // Environ returns os.Environ() with AZUD_* entries appended. Empty fields are omitted.
@@ -51,6 +53,8 @@ func (ctx *HookContext) Environ() []string {
 	add("AZUD_HOOK", ctx.HookName)
 	add("AZUD_RECORDED_AT", ctx.RecordedAt)
 	add("AZUD_RUNTIME", ctx.Runtime)
+	add("AZUD_CONTAINER", ctx.Container)
+	add("AZUD_RESTARTS", ctx.Restarts)
 
 	return env
 }
@@ -114,18 +118,28 @@ func (h *HookRunner) insideHooksDir(name string) bool {
 // replaced with a symlink between the path check and open, O_NOFOLLOW causes
 // the open to fail with ELOOP.
 func (h *HookRunner) resolveHook(name string) (string, error) {
-	hookPath := filepath.Join(h.hooksPath, name)
-
 	if !h.insideHooksDir(name) {
 		return "", fmt.Errorf("hook name %q escapes hooks directory", name)
 	}
 
+	for _, fileName := range HookFileNames(name) {
+		hookPath, err := h.resolveHookFile(name, filepath.Join(h.hooksPath, fileName))
+		if hookPath != "" || err != nil {
+			return hookPath, err
+		}
+	}
+	h.log.Debug("Hook %s not found, skipping", name)
+	return "", nil
+}
+
+// resolveHookFile validates the hook file at hookPath, returning "" without
+// an error when it does not exist or is skipped.
+func (h *HookRunner) resolveHookFile(name, hookPath string) (string, error) {
 	// O_NOFOLLOW prevents following symlinks on the final path component.
 	// If the file was swapped for a symlink after insideHooksDir, this fails
 	// with ELOOP instead of silently following the link.
 	f, err := os.OpenFile(hookPath, os.O_RDONLY|oNofollow, 0)
 	if os.IsNotExist(err) {
-		h.log.Debug("Hook %s not found, skipping", name)
 		return "", nil
 	}
 	if err != nil {
@@ -148,7 +162,7 @@ func (h *HookRunner) resolveHook(name string) (string, error) {
 		return "", nil
 	}
 
-	if info.Mode()&0111 == 0 {
+	if !HookExecutable(info) {
 		h.log.Warn("Hook %s is not executable, skipping", name)
 		return "", nil
 	}
@@ -173,7 +187,7 @@ func (h *HookRunner) prepareCmd(parent context.Context, hookPath, name string, c
 	}
 
 	runCtx, cancel := context.WithTimeout(parent, h.timeout)
-	cmd := exec.CommandContext(runCtx, hookPath)
+	cmd := hookCommand(runCtx, hookPath)
 
 	if ctx != nil {
 		cmd.Env = ctx.Environ()
@@ -255,7 +269,15 @@ func (h *HookRunner) Exists(name string) bool {
 	if !h.insideHooksDir(name) {
 		return false
 	}
-	hookPath := filepath.Join(h.hooksPath, name)
+	for _, fileName := range HookFileNames(name) {
+		if h.hookFileExists(filepath.Join(h.hooksPath, fileName)) {
+			return true
+		}
+	}
+	return false
+}
+
+func (h *HookRunner) hookFileExists(hookPath string) bool {
 	f, err := os.OpenFile(hookPath, os.O_RDONLY|oNofollow, 0)
 	if err != nil {
 		return false
@@ -265,7 +287,7 @@ func (h *HookRunner) Exists(name string) bool {
 	if err != nil {
 		return false
 	}
-	return !info.IsDir() && info.Mode()&0111 != 0
+	return !info.IsDir() && HookExecutable(info)
 }
 
 // List returns all available hooks
@@ -300,4 +322,5 @@ var StandardHooks = []string{
 	"pre-proxy-reboot",
 	"post-proxy-reboot",
 	"post-rollback",
+	"restart-loop",
 }
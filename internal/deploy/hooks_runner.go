@@ -0,0 +1,79 @@
+package deploy
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// windowsHookExtensions are the hook file extensions run on Windows besides
+// scripts with a #! line.
+var windowsHookExtensions = []string{".ps1", ".cmd", ".bat", ".exe"}
+
+// windowsHookArgv returns the command line running the hook file at
+// hookPath on Windows, which runs neither scripts by their #! line nor files
+// by an executable bit. Executables run directly, batch files in cmd.exe,
+// PowerShell scripts in pwsh or powershell.exe, and other scripts in the
+// interpreter their #! line names, looked up on PATH (Git for Windows
+// provides sh and bash).
+func windowsHookArgv(hookPath string, lookPath func(string) (string, error)) ([]string, error) {
+	switch strings.ToLower(filepath.Ext(hookPath)) {
+	case ".exe", ".com":
+		return []string{hookPath}, nil
+	case ".cmd", ".bat":
+		return []string{"cmd.exe", "/d", "/c", hookPath}, nil
+	case ".ps1":
+		powershell := "powershell.exe"
+		if pwsh, err := lookPath("pwsh"); err == nil {
+			powershell = pwsh
+		}
+		return []string{powershell, "-NoProfile", "-NonInteractive", "-ExecutionPolicy", "Bypass", "-File", hookPath}, nil
+	}
+
+	interpreter, args, err := readShebang(hookPath)
+	if err != nil {
+		return nil, err
+	}
+	if interpreter == "" {
+		return nil, fmt.Errorf("hook %s has no #! line or known extension (%s)", filepath.Base(hookPath), strings.Join(windowsHookExtensions, ", "))
+	}
+	// Interpreters are named by their Unix path, e.g. /bin/sh or
+	// /usr/bin/env bash; Windows finds them by name.
+	name := path.Base(interpreter)
+	if name == "env" && len(args) > 0 {
+		name, args = args[0], args[1:]
+	}
+	resolved, err := lookPath(name)
+	if err != nil {
+		return nil, fmt.Errorf("hook %s needs %s, which is not on PATH; install Git for Windows or add a .ps1 or .cmd hook: %w", filepath.Base(hookPath), name, err)
+	}
+	argv := append([]string{resolved}, args...)
+	return append(argv, hookPath), nil
+}
+
+// readShebang returns the interpreter and arguments of the #! line of the
+// file at path, or "" without one.
+func readShebang(path string) (string, []string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", nil, err
+	}
+	defer func() { _ = f.Close() }()
+
+	line, err := bufio.NewReaderSize(f, 256).ReadString('\n')
+	if err != nil && line == "" {
+		return "", nil, nil
+	}
+	rest, ok := strings.CutPrefix(line, "#!")
+	if !ok {
+		return "", nil, nil
+	}
+	fields := strings.Fields(rest)
+	if len(fields) == 0 {
+		return "", nil, nil
+	}
+	return fields[0], fields[1:], nil
+}
@@ -478,3 +478,48 @@ func TestHookRunner_RunWithOutput_Role(t *testing.T) {
 		t.Errorf("output should contain role, got: %q", out)
 	}
 }
+
+func TestWindowsHookArgv(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name, content string) string {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+	lookPath := func(name string) (string, error) {
+		if name == "bash" || name == "sh" {
+			return `C:\Program Files\Git\bin\` + name + ".exe", nil
+		}
+		return "", os.ErrNotExist
+	}
+
+	tests := []struct {
+		name    string
+		path    string
+		want    []string
+		wantErr string
+	}{
+		{name: "env shebang", path: write("pre-deploy", "#!/usr/bin/env bash\necho hi\n"), want: []string{`C:\Program Files\Git\bin\bash.exe`, filepath.Join(dir, "pre-deploy")}},
+		{name: "sh shebang with args", path: write("post-deploy", "#!/bin/sh -e\necho hi\n"), want: []string{`C:\Program Files\Git\bin\sh.exe`, "-e", filepath.Join(dir, "post-deploy")}},
+		{name: "batch file", path: write("pre-build.cmd", "@echo off\n"), want: []string{"cmd.exe", "/d", "/c", filepath.Join(dir, "pre-build.cmd")}},
+		{name: "powershell", path: write("post-build.ps1", "Write-Host hi\n"), want: []string{"powershell.exe", "-NoProfile", "-NonInteractive", "-ExecutionPolicy", "Bypass", "-File", filepath.Join(dir, "post-build.ps1")}},
+		{name: "missing interpreter", path: write("pre-connect", "#!/usr/bin/env python3\n"), wantErr: "needs python3"},
+		{name: "no shebang", path: write("restart-loop", "echo hi\n"), wantErr: "no #! line"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := windowsHookArgv(tt.path, lookPath)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("err = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil || strings.Join(got, "\x00") != strings.Join(tt.want, "\x00") {
+				t.Fatalf("windowsHookArgv = %q, %v; want %q", got, err, tt.want)
+			}
+		})
+	}
+}
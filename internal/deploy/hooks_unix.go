@@ -3,7 +3,10 @@
 package deploy
 
 import (
+	"context"
 	"errors"
+	"os"
+	"os/exec"
 	"syscall"
 )
 
@@ -12,3 +15,19 @@ const oNofollow = syscall.O_NOFOLLOW
 func isSymlinkError(err error) bool {
 	return errors.Is(err, syscall.ELOOP)
 }
+
+// HookFileNames returns the file names a hook may have in the hooks
+// directory, in order of preference.
+func HookFileNames(name string) []string {
+	return []string{name}
+}
+
+// HookExecutable reports whether the hook file described by info may run.
+func HookExecutable(info os.FileInfo) bool {
+	return info.Mode()&0111 != 0
+}
+
+// hookCommand returns the command running the hook file at path.
+func hookCommand(ctx context.Context, path string) *exec.Cmd {
+	return exec.CommandContext(ctx, path)
+}
@@ -2,9 +2,44 @@
 
 package deploy
 
+import (
+	"context"
+	"os"
+	"os/exec"
+)
+
 // Windows does not support O_NOFOLLOW; set to 0 (no-op flag).
 const oNofollow = 0
 
 func isSymlinkError(_ error) bool {
 	return false
 }
+
+// HookFileNames returns the file names a hook may have in the hooks
+// directory, in order of preference: the plain name for scripts with a #!
+// line, then the extensions Windows runs.
+func HookFileNames(name string) []string {
+	names := []string{name}
+	for _, ext := range windowsHookExtensions {
+		names = append(names, name+ext)
+	}
+	return names
+}
+
+// HookExecutable reports whether the hook file described by info may run.
+// Windows has no executable bit; how a hook runs follows from its name.
+func HookExecutable(_ os.FileInfo) bool {
+	return true
+}
+
+// hookCommand returns the command running the hook file at path. When the
+// hook cannot run, Start reports why.
+func hookCommand(ctx context.Context, path string) *exec.Cmd {
+	argv, err := windowsHookArgv(path, exec.LookPath)
+	if err != nil {
+		cmd := exec.CommandContext(ctx, path)
+		cmd.Err = err
+		return cmd
+	}
+	return exec.CommandContext(ctx, argv[0], argv[1:]...)
+}
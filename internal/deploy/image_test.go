@@ -1,6 +1,14 @@
 package deploy
 
-import "testing"
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/lemonity-org/azud/internal/config"
+	"github.com/lemonity-org/azud/internal/output"
+	"github.com/lemonity-org/azud/internal/ssh"
+)
 
 func TestStripImageTag(t *testing.T) {
 	tests := []struct {
@@ -44,3 +52,87 @@ func TestHasImageTag(t *testing.T) {
 		})
 	}
 }
+
+func TestResolveImage(t *testing.T) {
+	digest := "sha256:" + strings.Repeat("a", 64)
+	tests := []struct {
+		name        string
+		configured  string
+		version     string
+		digest      string
+		wantImage   string
+		wantVersion string
+	}{
+		{"configured tag", "ghcr.io/org/app:production", "", "", "ghcr.io/org/app:production", "production"},
+		{"no tag", "ghcr.io/org/app", "", "", "ghcr.io/org/app:latest", "latest"},
+		{"explicit version", "ghcr.io/org/app:production", "v2", "", "ghcr.io/org/app:v2", "v2"},
+		{"pinned image", "ghcr.io/org/app@" + digest, "", "", "ghcr.io/org/app@" + digest, digest},
+		{"digest", "ghcr.io/org/app:production", "", digest, "ghcr.io/org/app@" + digest, digest},
+		{"digest with version", "ghcr.io/org/app:production", "v2", digest, "ghcr.io/org/app@" + digest, "v2"},
+		{"digest as version", "localhost:5000/app:production", digest, "", "localhost:5000/app@" + digest, digest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			image, version := resolveImage(tt.configured, tt.version, tt.digest)
+			if image != tt.wantImage || version != tt.wantVersion {
+				t.Errorf("resolveImage() = (%q, %q), want (%q, %q)", image, version, tt.wantImage, tt.wantVersion)
+			}
+		})
+	}
+}
+
+func TestPullProgress(t *testing.T) {
+	var buf bytes.Buffer
+	log := output.NewLogger(&buf, &buf, false)
+	onLine := PullProgress(log, "10.0.0.1")
+	for _, line := range []string{
+		"Trying to pull ghcr.io/acme/app:v2...",
+		"Getting image source signatures",
+		"Copying blob sha256:59bf1c3509f33515622619af21ed55bbe26d24913cedbca106468a5fb37a50c3",
+		"Copying blob 8ca4688f4f35 skipped: already exists",
+		"Copying blob 4abcf2066143",
+		"Copying blob 59bf1c3509f3 done   | ",
+		"Copying config sha256:0aa1c4a6e8f1",
+		"Writing manifest to image destination",
+	} {
+		onLine(ssh.Stdout, line)
+	}
+
+	out := buf.String()
+	for _, want := range []string{
+		"Copying layer 1: 59bf1c3509f3",
+		"Copying layer 2: 4abcf2066143",
+		"Pulled 3 layer(s), 1 already present",
+	} {
+		if !strings.Contains(out, want) {
+			t.Fatalf("expected output to contain %q, got:\n%s", want, out)
+		}
+	}
+	if strings.Contains(out, "Trying to pull") || strings.Contains(out, "8ca4688f4f35") {
+		t.Fatalf("expected only layer progress, got:\n%s", out)
+	}
+}
+
+func TestPeerTransfer(t *testing.T) {
+	hosts := []string{"10.0.0.1", "10.0.0.2"}
+	tests := []struct {
+		name     string
+		transfer string
+		hosts    []string
+		image    string
+		want     bool
+	}{
+		{name: "registry by default", hosts: hosts, image: "ghcr.io/acme/app:v1", want: false},
+		{name: "peer", transfer: config.ImageTransferPeer, hosts: hosts, image: "ghcr.io/acme/app:v1", want: true},
+		{name: "single host pulls", transfer: config.ImageTransferPeer, hosts: hosts[:1], image: "ghcr.io/acme/app:v1", want: false},
+		{name: "digest reference pulls", transfer: config.ImageTransferPeer, hosts: hosts, image: "ghcr.io/acme/app@sha256:abc", want: false},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			cfg := &config.Config{Deploy: config.DeployConfig{ImageTransfer: tc.transfer}}
+			if got := peerTransfer(cfg, tc.hosts, tc.image); got != tc.want {
+				t.Fatalf("peerTransfer() = %v, want %v", got, tc.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,222 @@
+package deploy
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/lemonity-org/azud/internal/podman"
+	"github.com/lemonity-org/azud/internal/ssh"
+	"github.com/lemonity-org/azud/internal/state"
+)
+
+// PortRegistryFileName is the host-port registry in each host's state
+// directory. It records which container owned each published port when it
+// was last written, so later audits can tell when a port changed hands.
+const PortRegistryFileName = "ports.json"
+
+// PortAllocation is a host port published by an Azud-managed container.
+type PortAllocation struct {
+	Port          int    `json:"port"`
+	HostIP        string `json:"host_ip,omitempty"`
+	ContainerPort int    `json:"container_port"`
+	Protocol      string `json:"protocol"`
+	Container     string `json:"container"`
+	Service       string `json:"service,omitempty"`
+	Role          string `json:"role,omitempty"`
+}
+
+// Owner describes the container holding the port for messages.
+func (a PortAllocation) Owner() string {
+	if a.Service == "" {
+		return a.Container
+	}
+	return fmt.Sprintf("%s (service %s)", a.Container, a.Service)
+}
+
+// PortRegistry is the content of PortRegistryFileName.
+type PortRegistry struct {
+	UpdatedAt time.Time        `json:"updated_at"`
+	Ports     []PortAllocation `json:"ports"`
+}
+
+// ListHostPorts returns the host ports published by Azud-managed containers
+// on host, sorted by port.
+func ListHostPorts(containers *podman.ContainerManager, host string) ([]PortAllocation, error) {
+	list, err := containers.List(host, true, map[string]string{"label": "azud.managed=true"})
+	if err != nil {
+		return nil, err
+	}
+
+	var allocations []PortAllocation
+	for _, c := range list {
+		for _, spec := range c.Ports {
+			for _, allocation := range parsePublishedPorts(spec) {
+				allocation.Container = c.Name
+				allocation.Service = c.Labels["azud.service"]
+				allocation.Role = c.Labels["azud.role"]
+				allocations = append(allocations, allocation)
+			}
+		}
+	}
+	sort.SliceStable(allocations, func(i, j int) bool {
+		if allocations[i].Port != allocations[j].Port {
+			return allocations[i].Port < allocations[j].Port
+		}
+		return allocations[i].Container < allocations[j].Container
+	})
+	return allocations, nil
+}
+
+// parsePublishedPorts parses one port entry of `podman ps`, such as
+// "127.0.0.1:41873->3000/tcp" or "0.0.0.0:8000-8001->8000-8001/tcp".
+// Entries without a host side are exposed-only and yield nothing.
+func parsePublishedPorts(spec string) []PortAllocation {
+	hostSide, containerSide, ok := strings.Cut(strings.TrimSpace(spec), "->")
+	if !ok {
+		return nil
+	}
+	containerSide, protocol, _ := strings.Cut(containerSide, "/")
+	if protocol == "" {
+		protocol = "tcp"
+	}
+	sep := strings.LastIndex(hostSide, ":")
+	if sep < 0 {
+		return nil
+	}
+	hostIP := strings.Trim(hostSide[:sep], "[]")
+
+	hostFirst, hostLast, err := parsePortRange(hostSide[sep+1:])
+	if err != nil {
+		return nil
+	}
+	containerFirst, containerLast, err := parsePortRange(containerSide)
+	if err != nil || containerLast-containerFirst != hostLast-hostFirst {
+		return nil
+	}
+
+	allocations := make([]PortAllocation, 0, hostLast-hostFirst+1)
+	for offset := 0; hostFirst+offset <= hostLast; offset++ {
+		allocations = append(allocations, PortAllocation{
+			Port:          hostFirst + offset,
+			HostIP:        hostIP,
+			ContainerPort: containerFirst + offset,
+			Protocol:      protocol,
+		})
+	}
+	return allocations
+}
+
+func parsePortRange(value string) (int, int, error) {
+	first, last, isRange := strings.Cut(value, "-")
+	start, err := strconv.Atoi(first)
+	if err != nil {
+		return 0, 0, err
+	}
+	if !isRange {
+		return start, start, nil
+	}
+	end, err := strconv.Atoi(last)
+	if err != nil || end < start {
+		return 0, 0, fmt.Errorf("invalid port range %q", value)
+	}
+	return start, end, nil
+}
+
+// LoadPortRegistry reads the host-port registry on host. A host without one
+// yields an empty registry.
+func LoadPortRegistry(sshClient *ssh.Client, host, user string) (*PortRegistry, error) {
+	cmd := fmt.Sprintf("if [ -f %s ]; then cat %s; fi", // safe: path from state.ConfigFileQuoted
+		state.ConfigFileQuoted(user, PortRegistryFileName), state.ConfigFileQuoted(user, PortRegistryFileName))
+	result, err := sshClient.Execute(host, cmd)
+	if err != nil {
+		return nil, err
+	}
+	if result.ExitCode != 0 {
+		return nil, fmt.Errorf("failed to read port registry: %s", result.Stderr)
+	}
+
+	registry := &PortRegistry{}
+	if strings.TrimSpace(result.Stdout) == "" {
+		return registry, nil
+	}
+	if err := json.Unmarshal([]byte(result.Stdout), registry); err != nil {
+		return nil, fmt.Errorf("port registry on %s is invalid JSON: %w", host, err)
+	}
+	return registry, nil
+}
+
+// RecordHostPorts snapshots the ports published on host into its registry.
+// The snapshot covers every service, so concurrent writers for different
+// services only ever replace it with an equally current view.
+func RecordHostPorts(sshClient *ssh.Client, containers *podman.ContainerManager, host, user string) error {
+	allocations, err := ListHostPorts(containers, host)
+	if err != nil {
+		return fmt.Errorf("failed to list published ports: %w", err)
+	}
+	data, err := json.MarshalIndent(PortRegistry{UpdatedAt: time.Now().UTC(), Ports: allocations}, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	configFile := state.ConfigFileQuoted(user, PortRegistryFileName)
+	cmd := fmt.Sprintf(`umask 077 && mkdir -p %s && tmp=$(mktemp %s) && cat > "$tmp" && mv "$tmp" %s`, // safe: paths from state.*Quoted
+		state.DirQuoted(user), state.ConfigFileQuoted(user, PortRegistryFileName+".XXXXXX"), configFile)
+	result, err := sshClient.ExecuteWithStdin(host, cmd, bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	if result.ExitCode != 0 {
+		return fmt.Errorf("failed to write port registry: %s", result.Stderr)
+	}
+	return nil
+}
+
+// PortConflicts audits the live allocations on a host against its recorded
+// registry and against service's route upstreams. It reports ports published
+// twice, ports that passed to a different service since they were recorded,
+// and loopback upstreams that no container of service publishes.
+func PortConflicts(recorded, live []PortAllocation, service string, upstreams []string) []string {
+	var conflicts []string
+	owners := make(map[int]PortAllocation)
+	for _, allocation := range live {
+		if owner, ok := owners[allocation.Port]; ok {
+			if owner.Container != allocation.Container {
+				conflicts = append(conflicts, fmt.Sprintf("port %d is published by both %s and %s", allocation.Port, owner.Owner(), allocation.Owner()))
+			}
+			continue
+		}
+		owners[allocation.Port] = allocation
+	}
+
+	for _, previous := range recorded {
+		owner, ok := owners[previous.Port]
+		if !ok || owner.Service == previous.Service && owner.Role == previous.Role {
+			continue
+		}
+		conflicts = append(conflicts, fmt.Sprintf("port %d was recorded for %s but is now published by %s", previous.Port, previous.Owner(), owner.Owner()))
+	}
+
+	for _, upstream := range upstreams {
+		hostPart, portPart, found := strings.Cut(upstream, ":")
+		if !found || hostPart != "127.0.0.1" {
+			continue
+		}
+		port, err := strconv.Atoi(portPart)
+		if err != nil {
+			continue
+		}
+		owner, ok := owners[port]
+		switch {
+		case !ok:
+			conflicts = append(conflicts, fmt.Sprintf("route upstream %s is not published by any container", upstream))
+		case owner.Service != service:
+			conflicts = append(conflicts, fmt.Sprintf("route upstream %s is published by %s", upstream, owner.Owner()))
+		}
+	}
+	return conflicts
+}
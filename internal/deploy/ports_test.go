@@ -0,0 +1,62 @@
+package deploy
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParsePublishedPorts(t *testing.T) {
+	tests := []struct {
+		spec string
+		want []PortAllocation
+	}{
+		{"127.0.0.1:41873->3000/tcp", []PortAllocation{{Port: 41873, HostIP: "127.0.0.1", ContainerPort: 3000, Protocol: "tcp"}}},
+		{"0.0.0.0:53->53/udp", []PortAllocation{{Port: 53, HostIP: "0.0.0.0", ContainerPort: 53, Protocol: "udp"}}},
+		{"[::1]:8080->80/tcp", []PortAllocation{{Port: 8080, HostIP: "::1", ContainerPort: 80, Protocol: "tcp"}}},
+		{"0.0.0.0:8000-8001->9000-9001/tcp", []PortAllocation{
+			{Port: 8000, HostIP: "0.0.0.0", ContainerPort: 9000, Protocol: "tcp"},
+			{Port: 8001, HostIP: "0.0.0.0", ContainerPort: 9001, Protocol: "tcp"},
+		}},
+		{"3000/tcp", nil},
+		{"0.0.0.0:8000-8002->80/tcp", nil},
+	}
+	for _, tt := range tests {
+		if got := parsePublishedPorts(tt.spec); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("parsePublishedPorts(%q) = %+v, want %+v", tt.spec, got, tt.want)
+		}
+	}
+}
+
+func TestPortConflicts(t *testing.T) {
+	web := PortAllocation{Port: 41873, Container: "shop", Service: "shop", Role: "web"}
+	blog := PortAllocation{Port: 41900, Container: "blog", Service: "blog", Role: "web"}
+
+	if got := PortConflicts([]PortAllocation{web, blog}, []PortAllocation{web, blog}, "shop", []string{"127.0.0.1:41873"}); len(got) != 0 {
+		t.Fatalf("expected no conflicts, got %v", got)
+	}
+
+	// A redeploy renames containers but keeps service and role.
+	renamed := web
+	renamed.Container = "shop-new-1"
+	if got := PortConflicts([]PortAllocation{web}, []PortAllocation{renamed}, "shop", nil); len(got) != 0 {
+		t.Fatalf("expected rename to be accepted, got %v", got)
+	}
+
+	taken := blog
+	taken.Port = web.Port
+	got := PortConflicts([]PortAllocation{web}, []PortAllocation{taken}, "shop", []string{"127.0.0.1:41873", "127.0.0.1:40000", "shop:3000"})
+	want := []string{
+		"port 41873 was recorded for shop (service shop) but is now published by blog (service blog)",
+		"route upstream 127.0.0.1:41873 is published by blog (service blog)",
+		"route upstream 127.0.0.1:40000 is not published by any container",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("conflicts = %v, want %v", got, want)
+	}
+
+	got = PortConflicts(nil, []PortAllocation{web, taken}, "shop", nil)
+	if len(got) != 1 || !strings.Contains(got[0], "published by both") {
+		t.Fatalf("expected duplicate publish conflict, got %v", got)
+	}
+}
@@ -169,3 +169,62 @@ func TestParseCommandArgs(t *testing.T) {
 		})
 	}
 }
+
+func TestAttachSecretsUsesPodmanSecrets(t *testing.T) {
+	cfg := &config.Config{
+		Service:        "my-app",
+		SecretsStorage: config.SecretsStoragePodman,
+		Env:            config.EnvConfig{Secret: []string{"API_KEY"}},
+	}
+
+	containerCfg := newPreDeployContainerConfig(cfg, "ghcr.io/org/app:v1", "my-app-pre-deploy-1")
+
+	if containerCfg.EnvFile != "" || len(containerCfg.SecretEnv) != 0 {
+		t.Fatalf("expected no env file, got EnvFile=%q SecretEnv=%v", containerCfg.EnvFile, containerCfg.SecretEnv)
+	}
+	if len(containerCfg.Secrets) != 1 || containerCfg.Secrets[0] != "azud-my-app-API_KEY,target=API_KEY" {
+		t.Fatalf("Secrets = %v", containerCfg.Secrets)
+	}
+	if got := containerCfg.Env["API_KEY_FILE"]; got != "/run/secrets/API_KEY" {
+		t.Fatalf("API_KEY_FILE = %q", got)
+	}
+}
+
+func TestAttachSecretsUsesEncryptedEnvFile(t *testing.T) {
+	cfg := &config.Config{
+		Service:        "my-app",
+		SecretsStorage: config.SecretsStorageEncrypted,
+		Env:            config.EnvConfig{Secret: []string{"API_KEY"}},
+	}
+
+	containerCfg := newPreDeployContainerConfig(cfg, "ghcr.io/org/app:v1", "my-app-pre-deploy-1")
+
+	if containerCfg.EnvFile != "$HOME/.azud/secrets.age" {
+		t.Fatalf("EnvFile = %q", containerCfg.EnvFile)
+	}
+	if containerCfg.EnvFileIdentity != "$HOME/.azud/age-identity" {
+		t.Fatalf("EnvFileIdentity = %q", containerCfg.EnvFileIdentity)
+	}
+}
+
+func TestAttachSecretsTurnsSecretReferencesIntoTemplates(t *testing.T) {
+	cfg := &config.Config{
+		Service: "my-app",
+		Env: config.EnvConfig{
+			Clear: map[string]string{
+				"DB_HOST":      "db",
+				"DATABASE_URL": "postgres://app:${DB_PASSWORD}@${DB_HOST}/app",
+			},
+			Secret: []string{"DB_PASSWORD"},
+		},
+	}
+
+	containerCfg := newPreDeployContainerConfig(cfg, "ghcr.io/org/app:v1", "my-app-pre-deploy-1")
+
+	if _, ok := containerCfg.Env["DATABASE_URL"]; ok {
+		t.Fatalf("DATABASE_URL must not be passed as a plain variable: %v", containerCfg.Env)
+	}
+	if got := containerCfg.EnvTemplates["DATABASE_URL"]; got != "postgres://app:${DB_PASSWORD}@db/app" {
+		t.Fatalf("DATABASE_URL template = %q", got)
+	}
+}
@@ -1,9 +1,13 @@
 package deploy
 
 import (
+	"errors"
+	"io"
+	"reflect"
 	"testing"
 
 	"github.com/lemonity-org/azud/internal/config"
+	"github.com/lemonity-org/azud/internal/output"
 )
 
 func TestNewProxyConfigFromCfgIncludesCustomCertificates(t *testing.T) {
@@ -35,3 +39,104 @@ func TestNewProxyConfigFromCfgIncludesCustomCertificates(t *testing.T) {
 		t.Fatalf("Hosts = %v, want [app.example.com]", got.Hosts)
 	}
 }
+
+func TestBuildProxyServiceConfigAggregatesFleetUpstreams(t *testing.T) {
+	cfg := &config.Config{
+		Service: "shop",
+		Proxy: config.ProxyConfig{
+			Host:      "shop.example.com",
+			HostsRole: "lb",
+			AppPort:   3000,
+			Healthcheck: config.HealthcheckConfig{
+				LivenessPath: "/live",
+			},
+		},
+	}
+
+	got := BuildProxyServiceConfig(cfg, []string{"10.0.0.10:41001", "10.0.0.11:41002"}, nil)
+	if len(got.Upstreams) != 2 || got.Upstreams[0] != "10.0.0.10:41001" || got.Upstreams[1] != "10.0.0.11:41002" {
+		t.Fatalf("Upstreams = %v, want both fleet upstreams in one route", got.Upstreams)
+	}
+	if got.HealthPath != "/live" {
+		t.Fatalf("HealthPath = %q, want /live so every fleet upstream is health checked", got.HealthPath)
+	}
+
+	d := &Deployer{cfg: &config.Config{Service: "shop"}}
+	if peers := d.peerUpstreams("web-1"); peers != nil {
+		t.Fatalf("co-located proxies should not aggregate peers, got %v", peers)
+	}
+	if upstream, err := ResolveUpstream(d.cfg, nil, "web-1", "shop"); err != nil || upstream != "shop:0" {
+		t.Fatalf("bridge upstream = %q, %v", upstream, err)
+	}
+}
+
+func TestProxyRouteAggregatesFleetWithProxyTier(t *testing.T) {
+	cfg := &config.Config{
+		Service: "shop",
+		Servers: map[string]config.RoleConfig{
+			"web": {Hosts: []string{"web-1", "web-2", "web-3", "web-4"}},
+			"lb":  {Hosts: []string{"lb-1", "lb-2"}},
+		},
+		Proxy: config.ProxyConfig{Host: "shop.example.com", HostsRole: "lb", AppPort: 3000},
+	}
+	live := map[string]string{
+		"web-1": "10.0.0.1:41001",
+		"web-2": "10.0.0.2:41002",
+		"web-3": "10.0.0.3:41003",
+	}
+	var resolved []string
+	d := &Deployer{
+		cfg: cfg,
+		log: output.NewLogger(io.Discard, io.Discard, false),
+		peerUpstream: func(host, container string) (string, error) {
+			resolved = append(resolved, host+"/"+container)
+			if upstream, ok := live[host]; ok {
+				return upstream, nil
+			}
+			return "", errors.New("not running")
+		},
+	}
+
+	peers := d.peerUpstreams("web-1")
+	if want := []string{"10.0.0.2:41002", "10.0.0.3:41003"}; !reflect.DeepEqual(peers, want) {
+		t.Fatalf("peerUpstreams(web-1) = %v, want the running peers %v", peers, want)
+	}
+	if want := []string{"web-2/shop", "web-3/shop", "web-4/shop"}; !reflect.DeepEqual(resolved, want) {
+		t.Fatalf("resolved %v, want every other web host %v", resolved, want)
+	}
+
+	route := d.proxyRoute("web-1", live["web-1"])
+	if want := []string{"10.0.0.2:41002", "10.0.0.3:41003", "10.0.0.1:41001"}; !reflect.DeepEqual(route.Upstreams, want) {
+		t.Fatalf("route upstreams = %v, want every fleet upstream %v in one route", route.Upstreams, want)
+	}
+	if route.Host != "shop.example.com" {
+		t.Fatalf("route host = %q", route.Host)
+	}
+	if nodes := d.proxyNodes("web-1"); !reflect.DeepEqual(nodes, []string{"lb-1", "lb-2"}) {
+		t.Fatalf("proxyNodes(web-1) = %v, want the load balancers", nodes)
+	}
+}
+
+func TestBuildProxyServiceConfigHoldTimeout(t *testing.T) {
+	tests := []struct {
+		name        string
+		tryDuration string
+		holdTimeout string
+		want        string
+	}{
+		{name: "failover only", tryDuration: "5s", want: "5s"},
+		{name: "hold only", holdTimeout: "15s", want: "15s"},
+		{name: "hold is longer", tryDuration: "5s", holdTimeout: "15s", want: "15s"},
+		{name: "failover is longer", tryDuration: "30s", holdTimeout: "15s", want: "30s"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &config.Config{Service: "shop"}
+			cfg.Proxy.Failover.TryDuration = tt.tryDuration
+			cfg.Proxy.HoldTimeout = tt.holdTimeout
+			if got := BuildProxyServiceConfig(cfg, []string{"shop:3000"}, nil).TryDuration; got != tt.want {
+				t.Fatalf("TryDuration = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,58 @@
+package deploy
+
+import "time"
+
+// RestartTracker detects containers caught in a restart loop from successive
+// observations of their podman restart counts.
+type RestartTracker struct {
+	threshold int
+	window    time.Duration
+	samples   map[string][]restartSample
+	looping   map[string]bool
+}
+
+type restartSample struct {
+	at    time.Time
+	count int
+}
+
+// NewRestartTracker creates a tracker that reports a loop once a container
+// restarts threshold times within window.
+func NewRestartTracker(threshold int, window time.Duration) *RestartTracker {
+	return &RestartTracker{
+		threshold: threshold,
+		window:    window,
+		samples:   make(map[string][]restartSample),
+		looping:   make(map[string]bool),
+	}
+}
+
+// Observe records the restart count of the container identified by key and
+// returns the restarts counted within the window. started is true only on
+// the observation where the container enters a loop, so each loop is
+// reported once; it is reported again after the restarts drop below the
+// threshold. A container created within the window counts from zero at its
+// creation time, so a single observation can detect a loop.
+func (t *RestartTracker) Observe(key string, count int, created, now time.Time) (restarts int, started bool) {
+	samples := t.samples[key]
+	if len(samples) > 0 && count < samples[len(samples)-1].count {
+		// The container was recreated; its count started over.
+		samples = nil
+	}
+	if len(samples) == 0 && !created.IsZero() && now.Sub(created) <= t.window {
+		samples = append(samples, restartSample{at: created})
+	}
+	samples = append(samples, restartSample{at: now, count: count})
+
+	cutoff := now.Add(-t.window)
+	for len(samples) > 1 && samples[0].at.Before(cutoff) {
+		samples = samples[1:]
+	}
+	t.samples[key] = samples
+
+	restarts = count - samples[0].count
+	looping := t.threshold > 0 && restarts >= t.threshold
+	started = looping && !t.looping[key]
+	t.looping[key] = looping
+	return restarts, started
+}
@@ -0,0 +1,48 @@
+package deploy
+
+import (
+	"testing"
+	"time"
+)
+
+func TestRestartTrackerDetectsLoopAcrossObservations(t *testing.T) {
+	base := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+	created := base.Add(-time.Hour)
+	tracker := NewRestartTracker(3, 10*time.Minute)
+
+	if restarts, started := tracker.Observe("h/web", 5, created, base); restarts != 0 || started {
+		t.Fatalf("first observation of an old container = %d, %v; want 0, false", restarts, started)
+	}
+	if restarts, started := tracker.Observe("h/web", 7, created, base.Add(time.Minute)); restarts != 2 || started {
+		t.Fatalf("below threshold = %d, %v; want 2, false", restarts, started)
+	}
+	if restarts, started := tracker.Observe("h/web", 8, created, base.Add(2*time.Minute)); restarts != 3 || !started {
+		t.Fatalf("at threshold = %d, %v; want 3, true", restarts, started)
+	}
+	if _, started := tracker.Observe("h/web", 9, created, base.Add(3*time.Minute)); started {
+		t.Fatal("an ongoing loop should be reported once")
+	}
+
+	// Once the early restarts leave the window the loop ends, and a new
+	// burst is reported again.
+	if restarts, started := tracker.Observe("h/web", 9, created, base.Add(20*time.Minute)); restarts != 0 || started {
+		t.Fatalf("after window = %d, %v; want 0, false", restarts, started)
+	}
+	if _, started := tracker.Observe("h/web", 12, created, base.Add(21*time.Minute)); !started {
+		t.Fatal("expected a new loop to be reported")
+	}
+}
+
+func TestRestartTrackerCountsNewContainerFromCreation(t *testing.T) {
+	now := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+	tracker := NewRestartTracker(3, 10*time.Minute)
+
+	if restarts, started := tracker.Observe("h/web", 4, now.Add(-2*time.Minute), now); restarts != 4 || !started {
+		t.Fatalf("new container = %d, %v; want 4, true", restarts, started)
+	}
+
+	// A recreated container starts its count over.
+	if restarts, started := tracker.Observe("h/web", 0, now.Add(-time.Hour), now.Add(time.Minute)); restarts != 0 || started {
+		t.Fatalf("recreated container = %d, %v; want 0, false", restarts, started)
+	}
+}
@@ -5,6 +5,7 @@ import (
 	"reflect"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/lemonity-org/azud/internal/config"
 	"github.com/lemonity-org/azud/internal/output"
@@ -39,9 +40,113 @@ func roleTestConfig() *config.Config {
 	}
 }
 
+func TestNewAppContainerConfigAppliesHostTagEnv(t *testing.T) {
+	cfg := roleTestConfig()
+	cfg.HostTags = map[string][]string{"web-only": {"region=eu"}}
+	cfg.Env.Secret = []string{"API_KEY"}
+	cfg.Env.Tags = map[string]map[string]string{
+		"eu": {"REGION": "eu", "GLOBAL": "tag", "API_KEY": "tag"},
+	}
+
+	web := NewAppContainerConfig(cfg, cfg.Image, "shop-new", "web", "web-only", nil)
+	// clear > tag > secret: env.clear keeps GLOBAL, and a tag value passed
+	// with -e wins over the env file.
+	want := map[string]string{"REGION": "eu", "GLOBAL": "yes", "API_KEY": "tag", "ROLE_ENV": "web"}
+	for key, value := range want {
+		if web.Env[key] != value {
+			t.Errorf("Env[%s] = %q, want %q", key, web.Env[key], value)
+		}
+	}
+
+	if other := NewAppContainerConfig(cfg, cfg.Image, "shop-new", "web", "shared", nil); other.Env["REGION"] != "" {
+		t.Errorf("untagged host received tag env: %#v", other.Env)
+	}
+}
+
+func TestNewAppContainerConfigInjectsAccessoryDiscoveryEnv(t *testing.T) {
+	cfg := roleTestConfig()
+	cfg.Servers["worker"] = config.RoleConfig{Hosts: []string{"worker-only"}, Networks: []string{"backend"}}
+	cfg.Env.Clear["AZUD_ACCESSORY_DB_HOST"] = "db.internal"
+	cfg.Accessories = map[string]config.AccessoryConfig{
+		"db":          {Image: "postgres:16", Profile: config.AccessoryProfileStandard},
+		"search":      {Image: "opensearch:2", Port: "127.0.0.1:9200:9200/tcp"},
+		"job-cache":   {Image: "redis:7", Networks: []string{"backend"}, Cache: config.AccessoryCacheConfig{Engine: "redis"}},
+		"mailcatcher": {Image: "mailpit:1"},
+	}
+
+	web := NewAppContainerConfig(cfg, cfg.Image, "shop-new", "web", "web-only", nil)
+	want := map[string]string{
+		"AZUD_ACCESSORY_DB_HOST":          "db.internal",
+		"AZUD_ACCESSORY_DB_PORT":          "5432",
+		"AZUD_ACCESSORY_SEARCH_HOST":      "shop-search",
+		"AZUD_ACCESSORY_SEARCH_PORT":      "9200",
+		"AZUD_ACCESSORY_MAILCATCHER_HOST": "shop-mailcatcher",
+	}
+	for key, value := range want {
+		if web.Env[key] != value {
+			t.Errorf("Env[%s] = %q, want %q", key, web.Env[key], value)
+		}
+	}
+	for _, key := range []string{"AZUD_ACCESSORY_MAILCATCHER_PORT", "AZUD_ACCESSORY_JOB_CACHE_HOST"} {
+		if _, ok := web.Env[key]; ok {
+			t.Errorf("web received %s", key)
+		}
+	}
+
+	worker := NewAppContainerConfig(cfg, cfg.Image, "shop-worker", "worker", "worker-only", nil)
+	if worker.Env["AZUD_ACCESSORY_JOB_CACHE_HOST"] != "shop-job-cache" || worker.Env["AZUD_ACCESSORY_JOB_CACHE_PORT"] != "6379" {
+		t.Errorf("worker env = %v, want the job-cache accessory on the backend network", worker.Env)
+	}
+	if _, ok := worker.Env["AZUD_ACCESSORY_SEARCH_HOST"]; ok {
+		t.Error("worker received an accessory on a network it does not join")
+	}
+}
+
+func TestNewAppContainerConfigAppliesRuntimeOptions(t *testing.T) {
+	cfg := roleTestConfig()
+	cfg.Deploy.StopTimeout = 20 * time.Second
+	worker := cfg.Servers["worker"]
+	worker.User = "app"
+	worker.Entrypoint = "/sbin/tini --"
+	worker.Init = true
+	worker.StopSignal = "SIGQUIT"
+	worker.StopTimeout = 90 * time.Second
+	cfg.Servers["worker"] = worker
+
+	got := NewAppContainerConfig(cfg, cfg.Image, "shop-worker-new", "worker", "shared", nil)
+	if got.User != "app" || got.Entrypoint != "/sbin/tini --" || !got.Init || got.StopSignal != "SIGQUIT" || got.StopTimeout != 90 {
+		t.Errorf("runtime options not applied: %+v", got)
+	}
+	if cfg.RoleStopTimeout("worker") != 90 || cfg.RoleStopTimeout("web") != 20 {
+		t.Errorf("RoleStopTimeout() = %d, %d; want 90, 20", cfg.RoleStopTimeout("worker"), cfg.RoleStopTimeout("web"))
+	}
+
+	// The role's containers keep Podman's default unless the role sets one.
+	if web := NewAppContainerConfig(cfg, cfg.Image, "shop-new", "web", "shared", nil); web.StopTimeout != 0 || len(web.RuntimeArgs()) != 0 {
+		t.Errorf("role without runtime options received %v", web.RuntimeArgs())
+	}
+}
+
+func TestNewAppContainerConfigAppliesHardening(t *testing.T) {
+	cfg := roleTestConfig()
+	worker := cfg.Servers["worker"]
+	worker.Hardening = config.HardeningConfig{Preset: "strict", SeccompProfile: "/etc/azud/seccomp.json"}
+	cfg.Servers["worker"] = worker
+
+	got := NewAppContainerConfig(cfg, cfg.Image, "shop-worker-new", "worker", "shared", nil)
+	want := []string{"--read-only", "--tmpfs=/tmp", "--cap-drop=ALL", "--security-opt=no-new-privileges", "--security-opt=seccomp=/etc/azud/seccomp.json"}
+	if args := got.SecurityArgs(); !reflect.DeepEqual(args, want) {
+		t.Errorf("SecurityArgs() = %v, want %v", args, want)
+	}
+
+	if web := NewAppContainerConfig(cfg, cfg.Image, "shop-new", "web", "shared", nil); len(web.SecurityArgs()) != 0 {
+		t.Errorf("role without hardening received %v", web.SecurityArgs())
+	}
+}
+
 func TestNewAppContainerConfigAppliesRoleSemantics(t *testing.T) {
 	cfg := roleTestConfig()
-	worker := NewAppContainerConfig(cfg, cfg.Image, "shop-worker-new", "worker", map[string]string{
+	worker := NewAppContainerConfig(cfg, cfg.Image, "shop-worker-new", "worker", "", map[string]string{
 		"azud.service":  "spoofed",
 		"azud.instance": "2",
 	})
@@ -68,7 +173,7 @@ func TestNewAppContainerConfigAppliesRoleSemantics(t *testing.T) {
 		t.Fatalf("worker aliases = %v", got)
 	}
 
-	web := NewAppContainerConfig(cfg, cfg.Image, "shop-new", "web", nil)
+	web := NewAppContainerConfig(cfg, cfg.Image, "shop-new", "web", "", nil)
 	if !reflect.DeepEqual(web.Ports, []string{"127.0.0.1::3000"}) {
 		t.Fatalf("web host ports = %v", web.Ports)
 	}
@@ -83,6 +188,65 @@ func TestNewAppContainerConfigAppliesRoleSemantics(t *testing.T) {
 	}
 }
 
+func TestNewAppContainerConfigMountsSocketDirectory(t *testing.T) {
+	cfg := roleTestConfig()
+	cfg.SSH.User = "deploy"
+	cfg.Volumes = []string{"/data:/data"}
+	cfg.Proxy.AppSocket = "/run/app/app.sock"
+
+	web := NewAppContainerConfig(cfg, cfg.Image, "shop-new", "web", "", nil)
+	if len(web.Ports) != 0 {
+		t.Fatalf("socket mode should not publish ports, got %v", web.Ports)
+	}
+	if len(web.HostDirs) != 1 || !strings.HasPrefix(web.HostDirs[0], "${HOME}/.local/share/azud/sockets/shop/") {
+		t.Fatalf("socket host dirs = %v", web.HostDirs)
+	}
+	key := strings.TrimPrefix(web.HostDirs[0], "${HOME}/.local/share/azud/sockets/shop/")
+	if len(key) != config.AppSocketKeyLength {
+		t.Fatalf("socket directory key = %q", key)
+	}
+	if !reflect.DeepEqual(web.Volumes, []string{"/data:/data", web.HostDirs[0] + ":/run/app:U,z"}) {
+		t.Fatalf("socket volumes = %v", web.Volumes)
+	}
+	if len(cfg.Volumes) != 1 {
+		t.Fatalf("configured volumes were modified: %v", cfg.Volumes)
+	}
+	if other := NewAppContainerConfig(cfg, cfg.Image, "shop-new", "web", "", nil); other.HostDirs[0] == web.HostDirs[0] {
+		t.Fatal("containers should not share a socket directory")
+	}
+	if got := cfg.AppSocketUpstream(key); got != "unix//run/azud/sockets/shop/"+key+"/app.sock" {
+		t.Fatalf("socket upstream = %q", got)
+	}
+
+	worker := NewAppContainerConfig(cfg, cfg.Image, "shop-worker-new", "worker", "", nil)
+	if len(worker.HostDirs) != 0 || len(worker.Volumes) != 1 {
+		t.Fatalf("non-web role should not get a socket directory: dirs=%v volumes=%v", worker.HostDirs, worker.Volumes)
+	}
+}
+
+func TestNewOneOffContainerConfigTakesNoTraffic(t *testing.T) {
+	cfg := roleTestConfig()
+	cfg.Volumes = []string{"/data:/data"}
+	cfg.Proxy.AppSocket = "/run/app/app.sock"
+
+	got := NewOneOffContainerConfig(cfg, "example/shop:abc123", "shop-run-1", "worker", "", []string{"bin/seed", "--all"})
+	if got.Image != "example/shop:abc123" || !reflect.DeepEqual(got.Command, []string{"bin/seed", "--all"}) {
+		t.Fatalf("one-off image = %q command = %v", got.Image, got.Command)
+	}
+	if !got.Remove || got.Detach || got.Restart != "" {
+		t.Fatalf("one-off should run in the foreground and be removed: remove=%v detach=%v restart=%q", got.Remove, got.Detach, got.Restart)
+	}
+	if got.Env["GLOBAL"] != "yes" || got.Env["ROLE_ENV"] != "worker" || got.Memory != "512M" {
+		t.Fatalf("one-off did not get the role environment and options: env=%v memory=%q", got.Env, got.Memory)
+	}
+	if !reflect.DeepEqual(got.Volumes, []string{"/data:/data"}) || len(got.HostDirs) != 0 {
+		t.Fatalf("one-off volumes = %v host dirs = %v", got.Volumes, got.HostDirs)
+	}
+	if len(got.NetworkAliases) != 0 || len(got.Ports) != 0 || got.HealthCmd != "" || got.Labels["azud.role"] != "" {
+		t.Fatalf("one-off could receive traffic: aliases=%v ports=%v health=%q labels=%v", got.NetworkAliases, got.Ports, got.HealthCmd, got.Labels)
+	}
+}
+
 func TestGetTargetsPreservesRoleIdentityAndOrdering(t *testing.T) {
 	d := &Deployer{cfg: roleTestConfig()}
 	targets, err := d.getTargets(&DeployOptions{})
@@ -121,6 +285,44 @@ func TestGetTargetsRejectsUnknownSelections(t *testing.T) {
 	}
 }
 
+func TestProxyTierRoleRunsNoAppContainers(t *testing.T) {
+	cfg := roleTestConfig()
+	cfg.Podman.Rootless = false
+	cfg.Servers["lb"] = config.RoleConfig{Hosts: []string{"lb-1", "lb-2"}}
+	cfg.Proxy.HostsRole = "lb"
+	cfg.Proxy.PrivateAddresses = map[string]string{"web-only": "10.0.0.12"}
+	d := &Deployer{cfg: cfg}
+
+	targets, err := d.getTargets(&DeployOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, target := range targets {
+		if target.Role == "lb" {
+			t.Fatalf("load-balancer role was scheduled for app deployment: %#v", targets)
+		}
+	}
+	if _, err := d.getTargets(&DeployOptions{Roles: []string{"lb"}}); err == nil {
+		t.Fatal("expected explicit load-balancer role selection to fail")
+	}
+	if got := d.proxyNodes("web-only"); !reflect.DeepEqual(got, []string{"lb-1", "lb-2"}) {
+		t.Fatalf("proxy nodes = %v", got)
+	}
+
+	if got := ProxyTierPortMapping(cfg, "web-only", "web"); got != "10.0.0.12::3000" {
+		t.Fatalf("private port mapping = %q", got)
+	}
+	if got := ProxyTierPortMapping(cfg, "shared", "web"); got != "shared::3000" {
+		t.Fatalf("default port mapping = %q", got)
+	}
+	if got := ProxyTierPortMapping(cfg, "shared", "worker"); got != "" {
+		t.Fatalf("worker role should not publish the app port, got %q", got)
+	}
+	if web := NewAppContainerConfig(cfg, cfg.Image, "shop-new", "web", "", nil); len(web.Ports) != 0 {
+		t.Fatalf("tier mode must not publish loopback ports: %v", web.Ports)
+	}
+}
+
 func TestParseCommandArgsPreservesQuotedAndShellCommands(t *testing.T) {
 	if got := ParseCommandArgs("redis-server --appendonly yes"); !reflect.DeepEqual(got, []string{"redis-server", "--appendonly", "yes"}) {
 		t.Fatalf("plain command = %#v", got)
@@ -169,3 +371,40 @@ func TestFleetFailureStopsSchedulingAndRollsBackEverySuccess(t *testing.T) {
 		t.Fatalf("rollback failure was not reported: %v", failures)
 	}
 }
+
+func TestFleetHaltStopsWithoutRollbackAndRollsBackHaltedTarget(t *testing.T) {
+	targets := []deploymentTarget{
+		{Host: "one", Role: "web"},
+		{Host: "two", Role: "web"},
+		{Host: "three", Role: "web"},
+	}
+	d := &Deployer{log: output.DefaultLogger}
+	deployTarget := func(attempted *[]string) func(deploymentTarget) error {
+		return func(target deploymentTarget) error {
+			*attempted = append(*attempted, target.Host)
+			if target.Host == "one" {
+				return &haltRolloutError{err: errors.New("error rate 40.0% exceeds threshold")}
+			}
+			return nil
+		}
+	}
+
+	var attempted []string
+	_, failures := d.runFleetDeployment(targets, false, deployTarget(&attempted), func([]deploymentTarget) error {
+		t.Fatal("rollback must not run without rollback_on_failure")
+		return nil
+	})
+	if !reflect.DeepEqual(attempted, []string{"one"}) || len(failures) != 1 {
+		t.Fatalf("halt did not stop the rollout: attempted=%v failures=%v", attempted, failures)
+	}
+
+	attempted = nil
+	var rolledBack []deploymentTarget
+	d.runFleetDeployment(targets, true, deployTarget(&attempted), func(targets []deploymentTarget) error {
+		rolledBack = append(rolledBack, targets...)
+		return nil
+	})
+	if !reflect.DeepEqual(rolledBack, []deploymentTarget{{Host: "one", Role: "web"}}) {
+		t.Fatalf("halted target was not rolled back: %#v", rolledBack)
+	}
+}
@@ -0,0 +1,140 @@
+package deploy
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/lemonity-org/azud/internal/state"
+)
+
+// SBOMMediaTypes maps builder.sbom_format values to the artifact media type
+// the SBOM is attached with.
+var SBOMMediaTypes = map[string]string{
+	"spdx-json":      "application/spdx+json",
+	"cyclonedx-json": "application/vnd.cyclonedx+json",
+}
+
+// SBOMRecord links a pushed image digest to the SBOM artifact attached to it
+// in the registry.
+type SBOMRecord struct {
+	Service   string    `json:"service"`
+	Image     string    `json:"image"`
+	Digest    string    `json:"digest"`
+	Reference string    `json:"reference"`
+	Format    string    `json:"format"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// SBOMStore keeps SBOM records in the local Azud state directory (or
+// AZUD_STATE_DIR), one file per image digest, so a deploy of the image can
+// record which SBOM describes it.
+type SBOMStore struct {
+	basePath string
+	initErr  error
+}
+
+// NewSBOMStore opens the SBOM store in the durable local state directory.
+func NewSBOMStore() *SBOMStore {
+	basePath, err := state.LocalDir()
+	if err != nil {
+		return &SBOMStore{initErr: err}
+	}
+	return &SBOMStore{basePath: filepath.Join(basePath, "sbom")}
+}
+
+// Save records the SBOM attached to record.Digest, replacing any earlier one.
+func (s *SBOMStore) Save(record *SBOMRecord) error {
+	if s.initErr != nil {
+		return fmt.Errorf("SBOM state unavailable: %w", s.initErr)
+	}
+	path, err := s.path(record.Digest)
+	if err != nil {
+		return err
+	}
+	if record.CreatedAt.IsZero() {
+		record.CreatedAt = time.Now().UTC()
+	}
+
+	data, err := json.MarshalIndent(record, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal SBOM record: %w", err)
+	}
+	if err := os.MkdirAll(s.basePath, 0700); err != nil {
+		return fmt.Errorf("failed to create SBOM directory: %w", err)
+	}
+	return state.WithFileLock(filepath.Join(s.basePath, ".sbom.lock"), func() error {
+		tmp := path + ".tmp"
+		if err := os.WriteFile(tmp, data, 0600); err != nil {
+			return fmt.Errorf("failed to write SBOM record: %w", err)
+		}
+		if err := os.Rename(tmp, path); err != nil {
+			_ = os.Remove(tmp)
+			return fmt.Errorf("failed to save SBOM record: %w", err)
+		}
+		return nil
+	})
+}
+
+// Get returns the SBOM recorded for digest, or nil when there is none.
+func (s *SBOMStore) Get(digest string) (*SBOMRecord, error) {
+	if s.initErr != nil {
+		return nil, fmt.Errorf("SBOM state unavailable: %w", s.initErr)
+	}
+	path, err := s.path(digest)
+	if err != nil {
+		return nil, err
+	}
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read SBOM record: %w", err)
+	}
+	var record SBOMRecord
+	if err := json.Unmarshal(data, &record); err != nil {
+		return nil, fmt.Errorf("failed to parse SBOM record %s: %w", path, err)
+	}
+	return &record, nil
+}
+
+// path returns the record file for digest. Only algorithm:hex digests are
+// accepted so a digest can never name a file outside the store.
+func (s *SBOMStore) path(digest string) (string, error) {
+	algorithm, hex, ok := strings.Cut(digest, ":")
+	if !ok || algorithm == "" || hex == "" || strings.ContainsAny(digest, `/\.`) {
+		return "", fmt.Errorf("invalid image digest %q", digest)
+	}
+	return filepath.Join(s.basePath, algorithm+"-"+hex+".json"), nil
+}
+
+// recordSBOM copies the SBOM reference for the deployed image digest into the
+// deployment's metadata. A missing SBOM is reported but does not block the
+// deploy: the image may have been built elsewhere or before builder.sbom was
+// enabled.
+func (d *Deployer) recordSBOM(record *DeploymentRecord) {
+	if !d.cfg.Builder.SBOM {
+		return
+	}
+	digest := record.Metadata["image_digest"]
+	if digest == "" {
+		d.log.Warn("builder.sbom is enabled but the image digest is unknown; no SBOM recorded")
+		return
+	}
+	sbom, err := d.sboms.Get(digest)
+	if err != nil {
+		d.log.Warn("Failed to look up SBOM for %s: %v", digest, err)
+		return
+	}
+	if sbom == nil {
+		d.log.Warn("No SBOM recorded for %s; run 'azud build' on this machine to generate one", digest)
+		return
+	}
+	record.Metadata["sbom"] = sbom.Reference
+	record.Metadata["sbom_format"] = sbom.Format
+	d.log.Info("SBOM: %s", sbom.Reference)
+}
@@ -0,0 +1,75 @@
+package deploy
+
+import (
+	"io"
+	"testing"
+
+	"github.com/lemonity-org/azud/internal/config"
+	"github.com/lemonity-org/azud/internal/output"
+)
+
+func TestSBOMStoreRoundTrip(t *testing.T) {
+	t.Setenv("AZUD_STATE_DIR", t.TempDir())
+	store := NewSBOMStore()
+
+	if record, err := store.Get("sha256:aaa"); err != nil || record != nil {
+		t.Fatalf("Get() on empty store = (%v, %v), want (nil, nil)", record, err)
+	}
+
+	if err := store.Save(&SBOMRecord{
+		Service:   "shop",
+		Image:     "ghcr.io/acme/shop:abc123",
+		Digest:    "sha256:aaa",
+		Reference: "ghcr.io/acme/shop@sha256:bbb",
+		Format:    "spdx-json",
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	record, err := store.Get("sha256:aaa")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if record == nil || record.Reference != "ghcr.io/acme/shop@sha256:bbb" || record.CreatedAt.IsZero() {
+		t.Fatalf("Get() = %+v", record)
+	}
+}
+
+func TestSBOMStoreRejectsInvalidDigests(t *testing.T) {
+	t.Setenv("AZUD_STATE_DIR", t.TempDir())
+	store := NewSBOMStore()
+
+	for _, digest := range []string{"", "latest", "sha256:", "sha256:../../etc/passwd"} {
+		if _, err := store.Get(digest); err == nil {
+			t.Errorf("Get(%q) succeeded, want error", digest)
+		}
+	}
+}
+
+func TestRecordSBOMCopiesReferenceIntoMetadata(t *testing.T) {
+	t.Setenv("AZUD_STATE_DIR", t.TempDir())
+	store := NewSBOMStore()
+	if err := store.Save(&SBOMRecord{Digest: "sha256:aaa", Reference: "ghcr.io/acme/shop@sha256:bbb", Format: "cyclonedx-json"}); err != nil {
+		t.Fatal(err)
+	}
+
+	d := &Deployer{
+		cfg:   &config.Config{Builder: config.BuilderConfig{SBOM: true}},
+		sboms: store,
+		log:   output.NewLogger(io.Discard, io.Discard, false),
+	}
+
+	record := NewDeploymentRecord("shop", "ghcr.io/acme/shop:abc123", "abc123", "", []string{"one"})
+	record.Metadata["image_digest"] = "sha256:aaa"
+	d.recordSBOM(record)
+	if record.Metadata["sbom"] != "ghcr.io/acme/shop@sha256:bbb" || record.Metadata["sbom_format"] != "cyclonedx-json" {
+		t.Fatalf("metadata = %v", record.Metadata)
+	}
+
+	other := NewDeploymentRecord("shop", "ghcr.io/acme/shop:def456", "def456", "", []string{"one"})
+	other.Metadata["image_digest"] = "sha256:ccc"
+	d.recordSBOM(other)
+	if _, ok := other.Metadata["sbom"]; ok {
+		t.Fatalf("unexpected SBOM for unrecorded digest: %v", other.Metadata)
+	}
+}
@@ -6,10 +6,73 @@ import (
 	"strconv"
 	"strings"
 
+	"github.com/lemonity-org/azud/internal/config"
 	"github.com/lemonity-org/azud/internal/shell"
 	"github.com/lemonity-org/azud/internal/ssh"
 )
 
+// secretsExecutor runs the secret checks on hosts; *ssh.Client implements it.
+type secretsExecutor interface {
+	ExecuteParallel(hosts []string, cmd string) []*ssh.Result
+}
+
+// ValidateSecrets checks that hosts hold the required keys in the configured
+// secret storage: the env file at secretsPath, its encrypted counterpart, or
+// Podman secrets.
+func ValidateSecrets(cfg *config.Config, sshClient *ssh.Client, hosts []string, secretsPath string, requiredKeys []string) error {
+	switch {
+	case cfg.UsesPodmanSecrets():
+		return ValidatePodmanSecrets(cfg, sshClient, hosts, requiredKeys)
+	case cfg.UsesEncryptedSecrets():
+		return ValidateEncryptedSecrets(sshClient, hosts, secretsPath, config.RemoteSecretsIdentityPath(cfg), requiredKeys)
+	default:
+		return ValidateRemoteSecrets(sshClient, hosts, secretsPath, requiredKeys)
+	}
+}
+
+// ValidatePodmanSecrets ensures each host has a Podman secret for every
+// required key.
+func ValidatePodmanSecrets(cfg *config.Config, sshClient *ssh.Client, hosts []string, requiredKeys []string) error {
+	required := normalizeSecretKeys(requiredKeys)
+	if len(required) == 0 || len(hosts) == 0 {
+		return nil
+	}
+
+	results := sshClient.ExecuteParallel(hosts, "podman secret ls --format '{{.Name}}'")
+
+	var unreadable []string
+	missingByHost := make(map[string][]string)
+	for _, result := range results {
+		if !result.Success() {
+			unreadable = append(unreadable, result.Host)
+			continue
+		}
+		names := make(map[string]struct{})
+		for _, name := range strings.Fields(result.Stdout) {
+			names[name] = struct{}{}
+		}
+		var missing []string
+		for _, key := range required {
+			if _, ok := names[cfg.PodmanSecretName(key)]; !ok {
+				missing = append(missing, key)
+			}
+		}
+		if len(missing) > 0 {
+			sort.Strings(missing)
+			missingByHost[result.Host] = missing
+		}
+	}
+
+	if len(unreadable) > 0 {
+		sort.Strings(unreadable)
+		return fmt.Errorf("unable to list podman secrets on host(s): %s", strings.Join(unreadable, ", "))
+	}
+	if len(missingByHost) > 0 {
+		return formatSecretErrors(missingByHost, nil)
+	}
+	return nil
+}
+
 // ValidateRemoteSecrets ensures the remote secrets file exists and includes all required keys.
 func ValidateRemoteSecrets(sshClient *ssh.Client, hosts []string, secretsPath string, requiredKeys []string) error {
 	required := normalizeSecretKeys(requiredKeys)
@@ -38,7 +101,59 @@ func ValidateRemoteSecrets(sshClient *ssh.Client, hosts []string, secretsPath st
 		return err
 	}
 
-	readCmd := fmt.Sprintf("cat %s", quotedPath)
+	return checkSecretKeys(sshClient, hosts, fmt.Sprintf("cat %s", quotedPath), required)
+}
+
+// ValidateEncryptedSecrets ensures the age-encrypted counterpart of
+// secretsPath and the host identity exist with private permissions, that the
+// file matches the checksum recorded by 'azud env push', and that it decrypts
+// to all required keys.
+func ValidateEncryptedSecrets(sshClient secretsExecutor, hosts []string, secretsPath, identityPath string, requiredKeys []string) error {
+	required := normalizeSecretKeys(requiredKeys)
+	if len(required) == 0 || len(hosts) == 0 {
+		return nil
+	}
+
+	encryptedPath := config.EncryptedSecretsPath(secretsPath)
+	quotedPath := remotePathShellArg(encryptedPath)
+	quotedIdentity := remotePathShellArg(identityPath)
+
+	existsCmd := fmt.Sprintf("test -f %s && test -f %s", quotedPath, quotedIdentity)
+	var missingFiles []string
+	for _, result := range sshClient.ExecuteParallel(hosts, existsCmd) {
+		if !result.Success() {
+			missingFiles = append(missingFiles, result.Host)
+		}
+	}
+	if len(missingFiles) > 0 {
+		sort.Strings(missingFiles)
+		return fmt.Errorf("missing encrypted secrets file or identity on host(s): %s (run 'azud env push')", strings.Join(missingFiles, ", "))
+	}
+
+	for _, path := range []string{encryptedPath, identityPath} {
+		if err := validateSecretsPermissions(sshClient, hosts, path); err != nil {
+			return err
+		}
+	}
+
+	checksumCmd := fmt.Sprintf(`path=%s; test -f "$path.sha256" && test "$(sha256sum < "$path" | cut -d' ' -f1)" = "$(cat "$path.sha256")"`, quotedPath)
+	var mismatched []string
+	for _, result := range sshClient.ExecuteParallel(hosts, checksumCmd) {
+		if !result.Success() {
+			mismatched = append(mismatched, result.Host)
+		}
+	}
+	if len(mismatched) > 0 {
+		sort.Strings(mismatched)
+		return fmt.Errorf("encrypted secrets file does not match its checksum on host(s): %s (run 'azud env push')", strings.Join(mismatched, ", "))
+	}
+
+	return checkSecretKeys(sshClient, hosts, fmt.Sprintf("age -d -i %s %s", quotedIdentity, quotedPath), required)
+}
+
+// checkSecretKeys runs readCmd on each host and checks that its KEY=VALUE
+// output sets every required key to a non-empty value.
+func checkSecretKeys(sshClient secretsExecutor, hosts []string, readCmd string, required []string) error {
 	readResults := sshClient.ExecuteParallel(hosts, readCmd)
 
 	unreadable := make([]string, 0)
@@ -142,7 +257,7 @@ func formatSecretErrors(missingByHost, emptyByHost map[string][]string) error {
 	}
 }
 
-func validateSecretsPermissions(sshClient *ssh.Client, hosts []string, secretsPath string) error {
+func validateSecretsPermissions(sshClient secretsExecutor, hosts []string, secretsPath string) error {
 	if len(hosts) == 0 || strings.TrimSpace(secretsPath) == "" {
 		return nil
 	}
@@ -1,7 +1,16 @@
 package deploy
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
 	"testing"
+
+	"github.com/lemonity-org/azud/internal/config"
+	"github.com/lemonity-org/azud/internal/testutil"
 )
 
 func TestFormatSecretErrors(t *testing.T) {
@@ -220,3 +229,71 @@ func TestNormalizeSecretKeys(t *testing.T) {
 		})
 	}
 }
+
+func TestValidateEncryptedSecrets(t *testing.T) {
+	if _, err := exec.LookPath("sha256sum"); err != nil {
+		t.Skip("sha256sum is not installed")
+	}
+	runner := testutil.LocalExecutor{Bin: testutil.FakeAge(t)}
+
+	setup := func(t *testing.T, content string) (secretsPath, identityPath string) {
+		t.Helper()
+		dir := t.TempDir()
+		if err := os.Chmod(dir, 0o700); err != nil {
+			t.Fatal(err)
+		}
+		secretsPath = filepath.Join(dir, "secrets")
+		identityPath = filepath.Join(dir, "age-identity")
+		encrypted := config.EncryptedSecretsPath(secretsPath)
+		sum := sha256.Sum256([]byte(content))
+		for path, data := range map[string]string{
+			encrypted:             content,
+			encrypted + ".sha256": hex.EncodeToString(sum[:]) + "\n",
+			identityPath:          "AGE-SECRET-KEY-TEST\n",
+		} {
+			if err := os.WriteFile(path, []byte(data), 0o600); err != nil {
+				t.Fatal(err)
+			}
+		}
+		return secretsPath, identityPath
+	}
+	hosts := []string{"web-1"}
+	required := []string{"DATABASE_URL", "API_KEY"}
+
+	t.Run("valid", func(t *testing.T) {
+		secretsPath, identityPath := setup(t, "DATABASE_URL=postgres://db\nAPI_KEY=secret\n")
+		if err := ValidateEncryptedSecrets(runner, hosts, secretsPath, identityPath, required); err != nil {
+			t.Fatal(err)
+		}
+	})
+
+	t.Run("checksum mismatch", func(t *testing.T) {
+		secretsPath, identityPath := setup(t, "DATABASE_URL=postgres://db\nAPI_KEY=secret\n")
+		if err := os.WriteFile(config.EncryptedSecretsPath(secretsPath), []byte("DATABASE_URL=postgres://other\nAPI_KEY=secret\n"), 0o600); err != nil {
+			t.Fatal(err)
+		}
+		err := ValidateEncryptedSecrets(runner, hosts, secretsPath, identityPath, required)
+		if err == nil || !strings.Contains(err.Error(), "does not match its checksum on host(s): web-1") {
+			t.Fatalf("ValidateEncryptedSecrets() = %v, want a checksum mismatch", err)
+		}
+	})
+
+	t.Run("missing identity", func(t *testing.T) {
+		secretsPath, identityPath := setup(t, "DATABASE_URL=postgres://db\nAPI_KEY=secret\n")
+		if err := os.Remove(identityPath); err != nil {
+			t.Fatal(err)
+		}
+		err := ValidateEncryptedSecrets(runner, hosts, secretsPath, identityPath, required)
+		if err == nil || !strings.Contains(err.Error(), "missing encrypted secrets file or identity on host(s): web-1") {
+			t.Fatalf("ValidateEncryptedSecrets() = %v, want a missing identity", err)
+		}
+	})
+
+	t.Run("missing key", func(t *testing.T) {
+		secretsPath, identityPath := setup(t, "DATABASE_URL=postgres://db\n")
+		err := ValidateEncryptedSecrets(runner, hosts, secretsPath, identityPath, required)
+		if err == nil || !strings.Contains(err.Error(), "missing required secrets on host(s): web-1 (API_KEY)") {
+			t.Fatalf("ValidateEncryptedSecrets() = %v, want API_KEY missing", err)
+		}
+	})
+}
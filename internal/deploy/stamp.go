@@ -0,0 +1,48 @@
+package deploy
+
+import (
+	"time"
+
+	"github.com/lemonity-org/azud/internal/podman"
+)
+
+// GitSHAMetadata is the deployment metadata key of the git commit the
+// deployed image was built from, recorded when the deployment built it from
+// a clean checkout.
+const GitSHAMetadata = "git_sha"
+
+// deployStampEnv returns the variables identifying record's deployment,
+// which app containers receive so applications can report what they run
+// without build arguments. Unknown values are left out.
+func deployStampEnv(record *DeploymentRecord) map[string]string {
+	env := make(map[string]string)
+	add := func(key, value string) {
+		if value != "" {
+			env[key] = value
+		}
+	}
+	add("AZUD_VERSION", record.Version)
+	add("AZUD_DEPLOY_ID", record.ID)
+	add("AZUD_DESTINATION", record.Destination)
+	add("AZUD_GIT_SHA", record.Metadata[GitSHAMetadata])
+	if !record.StartedAt.IsZero() {
+		env["AZUD_DEPLOYED_AT"] = record.StartedAt.UTC().Format(time.RFC3339)
+	}
+	return env
+}
+
+// stampContainer adds the deployment variables to containerCfg. Variables
+// the configuration sets keep their configured values.
+func stampContainer(containerCfg *podman.ContainerConfig, stamp map[string]string) {
+	if len(stamp) == 0 {
+		return
+	}
+	if containerCfg.Env == nil {
+		containerCfg.Env = make(map[string]string, len(stamp))
+	}
+	for key, value := range stamp {
+		if _, set := containerCfg.Env[key]; !set {
+			containerCfg.Env[key] = value
+		}
+	}
+}
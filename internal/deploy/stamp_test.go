@@ -0,0 +1,45 @@
+package deploy
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/lemonity-org/azud/internal/podman"
+)
+
+func TestDeployStampEnv(t *testing.T) {
+	record := NewDeploymentRecord("shop", "ghcr.io/acme/shop:v2", "v2", "production", []string{"web-1"})
+	record.ID = "d41f"
+	record.StartedAt = time.Date(2026, 3, 4, 5, 6, 7, 0, time.FixedZone("CET", 3600))
+	record.Metadata[GitSHAMetadata] = "0123abcd"
+
+	want := map[string]string{
+		"AZUD_VERSION":     "v2",
+		"AZUD_DEPLOY_ID":   "d41f",
+		"AZUD_DESTINATION": "production",
+		"AZUD_GIT_SHA":     "0123abcd",
+		"AZUD_DEPLOYED_AT": "2026-03-04T04:06:07Z",
+	}
+	if got := deployStampEnv(record); !reflect.DeepEqual(got, want) {
+		t.Fatalf("deployStampEnv() = %v, want %v", got, want)
+	}
+
+	record.Destination = ""
+	delete(record.Metadata, GitSHAMetadata)
+	got := deployStampEnv(record)
+	for _, key := range []string{"AZUD_DESTINATION", "AZUD_GIT_SHA"} {
+		if _, ok := got[key]; ok {
+			t.Errorf("%s set without a value: %v", key, got)
+		}
+	}
+}
+
+func TestStampContainerKeepsConfiguredValues(t *testing.T) {
+	containerCfg := &podman.ContainerConfig{Env: map[string]string{"AZUD_VERSION": "pinned"}}
+	stampContainer(containerCfg, map[string]string{"AZUD_VERSION": "v2", "AZUD_DEPLOY_ID": "d41f"})
+	want := map[string]string{"AZUD_VERSION": "pinned", "AZUD_DEPLOY_ID": "d41f"}
+	if !reflect.DeepEqual(containerCfg.Env, want) {
+		t.Fatalf("Env = %v, want %v", containerCfg.Env, want)
+	}
+}
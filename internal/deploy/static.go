@@ -0,0 +1,84 @@
+package deploy
+
+import (
+	"fmt"
+	"path"
+	"strings"
+
+	"github.com/lemonity-org/azud/internal/config"
+	"github.com/lemonity-org/azud/internal/proxy"
+	"github.com/lemonity-org/azud/internal/shell"
+	"github.com/lemonity-org/azud/internal/ssh"
+	"github.com/lemonity-org/azud/internal/state"
+)
+
+// StaticDir returns the host directory holding the files the proxy serves
+// for proxy.static, which it mounts at config.StaticMount. It is "" unless
+// proxy.static is set.
+func StaticDir(cfg *config.Config) string {
+	if !cfg.UsesStatic() {
+		return ""
+	}
+	return state.Dir(cfg.SSH.User) + "/static"
+}
+
+// staticPathDir returns the host directory of the files below the URL path
+// prefix of static. Each service's directory mirrors its URL paths, so one
+// file_server root serves every prefix.
+func staticPathDir(cfg *config.Config, static config.StaticConfig) string {
+	return StaticDir(cfg) + "/" + cfg.Service + static.Path
+}
+
+// ProxyStatic maps proxy.static to the static routes of the service.
+func ProxyStatic(cfg *config.Config) []proxy.StaticRoute {
+	routes := make([]proxy.StaticRoute, 0, len(cfg.Proxy.Static))
+	for _, static := range cfg.Proxy.Static {
+		routes = append(routes, proxy.StaticRoute{
+			Path:         static.Path,
+			Root:         path.Join(config.StaticMount, cfg.Service),
+			CacheControl: static.GetCacheControl(),
+		})
+	}
+	return routes
+}
+
+// syncStatic copies the static directories of container's image to the
+// directories the proxy serves them from on host. It runs before the
+// container joins the route, so the proxy already holds the files of every
+// page the new version renders.
+func syncStatic(sshClient *ssh.Client, cfg *config.Config, host, container string) error {
+	script := staticSyncScript(cfg, container)
+	if script == "" {
+		return nil
+	}
+	result, err := sshClient.Execute(host, script)
+	if err != nil {
+		return err
+	}
+	if result.ExitCode != 0 {
+		return fmt.Errorf("%s", strings.TrimSpace(result.Stderr))
+	}
+	return nil
+}
+
+// staticSyncScript returns the shell script syncStatic runs, or "" when
+// every static path is shared. Files are merged into what earlier releases
+// copied, so the old containers' pages keep finding their assets while
+// both versions serve traffic.
+func staticSyncScript(cfg *config.Config, container string) string {
+	var lines []string
+	for _, static := range cfg.Proxy.Static {
+		if static.Shared {
+			continue
+		}
+		dir := shell.QuoteRemotePath(staticPathDir(cfg, static))
+		lines = append(lines,
+			"mkdir -p "+dir,
+			fmt.Sprintf("podman cp %s:%s %s", shell.Quote(container), shell.Quote(static.Dir+"/."), dir),
+		)
+	}
+	if len(lines) == 0 {
+		return ""
+	}
+	return strings.Join(append([]string{"set -e"}, lines...), "\n")
+}
@@ -0,0 +1,56 @@
+package deploy
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/lemonity-org/azud/internal/config"
+)
+
+func TestStaticSyncScript(t *testing.T) {
+	cfg := &config.Config{Service: "shop"}
+	cfg.SSH.User = "deploy"
+	cfg.Proxy.Static = []config.StaticConfig{
+		{Path: "/assets", Dir: "/app/public/assets"},
+		{Path: "/uploads", Dir: "/app/uploads", Shared: true},
+	}
+	script := staticSyncScript(cfg, "shop-web-new")
+	for _, want := range []string{
+		"mkdir -p ${HOME}/.local/share/azud/static/shop/assets",
+		"podman cp shop-web-new:/app/public/assets/. ${HOME}/.local/share/azud/static/shop/assets",
+	} {
+		if !strings.Contains(script, want) {
+			t.Errorf("script missing %q:\n%s", want, script)
+		}
+	}
+	if strings.Contains(script, "uploads") {
+		t.Errorf("script copies a shared path:\n%s", script)
+	}
+
+	cfg.Proxy.Static = cfg.Proxy.Static[1:]
+	if script := staticSyncScript(cfg, "shop-web-new"); script != "" {
+		t.Errorf("script with only shared paths = %q", script)
+	}
+}
+
+func TestNewAppContainerConfigMountsSharedStaticPaths(t *testing.T) {
+	cfg := &config.Config{Service: "shop", Image: "ghcr.io/acme/shop"}
+	cfg.Proxy.Static = []config.StaticConfig{
+		{Path: "/assets", Dir: "/app/public/assets"},
+		{Path: "/uploads", Dir: "/app/uploads", Shared: true},
+	}
+	containerCfg := NewAppContainerConfig(cfg, "ghcr.io/acme/shop:v1", "shop-web", "web", "", nil)
+	want := "/var/lib/azud/static/shop/uploads:/app/uploads:z"
+	found := false
+	for _, volume := range containerCfg.Volumes {
+		if volume == want {
+			found = true
+		}
+		if strings.Contains(volume, "/assets") {
+			t.Errorf("image static path is mounted: %s", volume)
+		}
+	}
+	if !found {
+		t.Errorf("volumes = %v, want %s", containerCfg.Volumes, want)
+	}
+}
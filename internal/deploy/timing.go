@@ -0,0 +1,212 @@
+package deploy
+
+import (
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// TimingMetadataPrefix starts the deployment metadata keys that record how
+// long a phase took on a target: timing.<host>/<role>.<phase>.
+const TimingMetadataPrefix = "timing."
+
+// phaseTimer measures the phases of each deployment target.
+type phaseTimer struct {
+	mu        sync.Mutex
+	started   map[string]time.Time
+	durations map[string]time.Duration
+	now       func() time.Time
+}
+
+func newPhaseTimer() *phaseTimer {
+	return &phaseTimer{
+		started:   make(map[string]time.Time),
+		durations: make(map[string]time.Duration),
+		now:       time.Now,
+	}
+}
+
+func timerKey(key, phase string) string {
+	return key + "." + strings.ToLower(phase)
+}
+
+func (t *phaseTimer) start(key, phase string) {
+	if t == nil {
+		return
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.started[timerKey(key, phase)] = t.now()
+}
+
+func (t *phaseTimer) complete(key, phase string) {
+	if t == nil {
+		return
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	k := timerKey(key, phase)
+	if started, ok := t.started[k]; ok {
+		t.durations[k] = t.now().Sub(started)
+		delete(t.started, k)
+	}
+}
+
+func (t *phaseTimer) set(key, phase string, d time.Duration) {
+	if t == nil {
+		return
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	k := timerKey(key, phase)
+	delete(t.started, k)
+	t.durations[k] = d
+}
+
+// record copies the phases the target key completed into the deployment's
+// metadata.
+func (t *phaseTimer) record(record *DeploymentRecord, key string) {
+	if t == nil {
+		return
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	for k, d := range t.durations {
+		if strings.HasPrefix(k, key+".") {
+			record.Metadata[TimingMetadataPrefix+k] = d.Round(time.Millisecond).String()
+		}
+	}
+}
+
+// startPhase marks phase as started for the target key on the progress
+// board and starts timing it.
+func (d *Deployer) startPhase(key, phase string) {
+	d.progress.Start(key, phase)
+	d.timings.start(key, phase)
+}
+
+// completePhase marks phase as done for the target key and records how
+// long it took.
+func (d *Deployer) completePhase(key, phase string) {
+	d.progress.Complete(key, phase)
+	d.timings.complete(key, phase)
+}
+
+// PhaseTiming is how long one phase took on one target.
+type PhaseTiming struct {
+	Host     string
+	Role     string
+	Phase    string
+	Duration time.Duration
+}
+
+// PhaseTimings returns the phase timings recorded in r's metadata, ordered
+// by target and then by phase.
+func (r *DeploymentRecord) PhaseTimings() []PhaseTiming {
+	var timings []PhaseTiming
+	for key, value := range r.Metadata {
+		rest, ok := strings.CutPrefix(key, TimingMetadataPrefix)
+		if !ok {
+			continue
+		}
+		dot := strings.LastIndex(rest, ".")
+		slash := strings.LastIndex(rest, "/")
+		if dot < 0 || slash < 0 || slash > dot {
+			continue
+		}
+		duration, err := time.ParseDuration(value)
+		if err != nil {
+			continue
+		}
+		timings = append(timings, PhaseTiming{
+			Host:     rest[:slash],
+			Role:     rest[slash+1 : dot],
+			Phase:    rest[dot+1:],
+			Duration: duration,
+		})
+	}
+
+	targetOrder := make(map[string]int, len(r.Targets))
+	for i, target := range r.Targets {
+		targetOrder[target.Host+"/"+target.Role] = i
+	}
+	slices.SortFunc(timings, func(a, b PhaseTiming) int {
+		if c := targetOrder[a.Host+"/"+a.Role] - targetOrder[b.Host+"/"+b.Role]; c != 0 {
+			return c
+		}
+		if c := strings.Compare(a.Host+"/"+a.Role, b.Host+"/"+b.Role); c != 0 {
+			return c
+		}
+		return phaseIndex(a.Phase) - phaseIndex(b.Phase)
+	})
+	return timings
+}
+
+// phaseIndex orders phase names as a deploy runs them; unknown phases sort
+// last.
+func phaseIndex(phase string) int {
+	for i, name := range deployPhases {
+		if strings.EqualFold(name, phase) {
+			return i
+		}
+	}
+	return len(deployPhases)
+}
+
+// PhaseSummary aggregates the timings of one phase across deployments.
+type PhaseSummary struct {
+	Phase string
+	// Number of target timings aggregated
+	Count int
+	P50   time.Duration
+	P90   time.Duration
+	P99   time.Duration
+	Max   time.Duration
+}
+
+// SummarizePhaseTimings returns nearest-rank percentiles of each phase over
+// every target of records, in deploy order.
+func SummarizePhaseTimings(records []*DeploymentRecord) []PhaseSummary {
+	byPhase := make(map[string][]time.Duration)
+	for _, record := range records {
+		for _, timing := range record.PhaseTimings() {
+			byPhase[timing.Phase] = append(byPhase[timing.Phase], timing.Duration)
+		}
+	}
+
+	phases := make([]string, 0, len(byPhase))
+	for phase := range byPhase {
+		phases = append(phases, phase)
+	}
+	slices.SortFunc(phases, func(a, b string) int {
+		if c := phaseIndex(a) - phaseIndex(b); c != 0 {
+			return c
+		}
+		return strings.Compare(a, b)
+	})
+
+	summaries := make([]PhaseSummary, 0, len(phases))
+	for _, phase := range phases {
+		durations := byPhase[phase]
+		slices.Sort(durations)
+		summaries = append(summaries, PhaseSummary{
+			Phase: phase,
+			Count: len(durations),
+			P50:   percentile(durations, 50),
+			P90:   percentile(durations, 90),
+			P99:   percentile(durations, 99),
+			Max:   durations[len(durations)-1],
+		})
+	}
+	return summaries
+}
+
+// percentile returns the nearest-rank p-th percentile of sorted durations.
+func percentile(sorted []time.Duration, p int) time.Duration {
+	rank := (p*len(sorted) + 99) / 100
+	if rank < 1 {
+		rank = 1
+	}
+	return sorted[rank-1]
+}
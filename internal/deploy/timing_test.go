@@ -0,0 +1,81 @@
+package deploy
+
+import (
+	"testing"
+	"time"
+)
+
+func TestPhaseTimerRecordsTargetPhases(t *testing.T) {
+	timer := newPhaseTimer()
+	clock := time.Date(2026, 10, 17, 12, 0, 0, 0, time.UTC)
+	timer.now = func() time.Time { return clock }
+
+	timer.start("10.0.0.1/web", phaseContainer)
+	clock = clock.Add(1500 * time.Millisecond)
+	timer.complete("10.0.0.1/web", phaseContainer)
+	timer.start("10.0.0.1/web", phaseHealth) // never completed
+	timer.set("10.0.0.1/web", phasePull, 4*time.Second)
+	timer.set("10.0.0.1/worker", phasePull, 3*time.Second)
+
+	record := &DeploymentRecord{Metadata: map[string]string{}}
+	timer.record(record, "10.0.0.1/web")
+	want := map[string]string{
+		"timing.10.0.0.1/web.container": "1.5s",
+		"timing.10.0.0.1/web.pull":      "4s",
+	}
+	if len(record.Metadata) != len(want) {
+		t.Fatalf("metadata = %v, want %v", record.Metadata, want)
+	}
+	for key, value := range want {
+		if record.Metadata[key] != value {
+			t.Errorf("metadata[%s] = %q, want %q", key, record.Metadata[key], value)
+		}
+	}
+}
+
+func TestPhaseTimings(t *testing.T) {
+	record := &DeploymentRecord{
+		Targets: []TargetProgress{{Host: "b.example.com", Role: "web"}, {Host: "a.example.com", Role: "web"}},
+		Metadata: map[string]string{
+			"timing.a.example.com/web.pull":      "2s",
+			"timing.b.example.com/web.drain":     "5s",
+			"timing.b.example.com/web.pull":      "1s",
+			"timing.b.example.com/web.container": "500ms",
+			"timing.broken":                      "1s",
+			"image_digest":                       "sha256:abc",
+		},
+	}
+	got := record.PhaseTimings()
+	want := []PhaseTiming{
+		{Host: "b.example.com", Role: "web", Phase: "pull", Duration: time.Second},
+		{Host: "b.example.com", Role: "web", Phase: "container", Duration: 500 * time.Millisecond},
+		{Host: "b.example.com", Role: "web", Phase: "drain", Duration: 5 * time.Second},
+		{Host: "a.example.com", Role: "web", Phase: "pull", Duration: 2 * time.Second},
+	}
+	if len(got) != len(want) {
+		t.Fatalf("PhaseTimings() = %+v, want %+v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("PhaseTimings()[%d] = %+v, want %+v", i, got[i], want[i])
+		}
+	}
+}
+
+func TestSummarizePhaseTimings(t *testing.T) {
+	var records []*DeploymentRecord
+	for i := 1; i <= 10; i++ {
+		records = append(records, &DeploymentRecord{Metadata: map[string]string{
+			"timing.web1/web.health": (time.Duration(i) * time.Second).String(),
+			"timing.web1/web.pull":   "1s",
+		}})
+	}
+	summaries := SummarizePhaseTimings(records)
+	if len(summaries) != 2 || summaries[0].Phase != "pull" || summaries[1].Phase != "health" {
+		t.Fatalf("summaries = %+v, want pull then health", summaries)
+	}
+	health := summaries[1]
+	if health.Count != 10 || health.P50 != 5*time.Second || health.P90 != 9*time.Second || health.P99 != 10*time.Second || health.Max != 10*time.Second {
+		t.Fatalf("health summary = %+v", health)
+	}
+}
@@ -0,0 +1,150 @@
+package deploy
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/lemonity-org/azud/internal/config"
+	"github.com/lemonity-org/azud/internal/podman"
+	"github.com/lemonity-org/azud/internal/proxy"
+	"github.com/lemonity-org/azud/internal/ssh"
+	"github.com/lemonity-org/azud/internal/state"
+)
+
+// DefaultTrafficWeight is the weight of a web host that was never set with
+// azud traffic set: its full, even share of the load-balancer tier.
+const DefaultTrafficWeight = 100
+
+// TrafficFileName returns the name of the per-host traffic weights of
+// service, kept in the state directory of each load-balancer host.
+func TrafficFileName(service string) string {
+	return "traffic-" + service + ".json"
+}
+
+// TrafficWeights holds the relative weight of each web host behind the
+// load-balancer tier. A host at weight 0 is drained and left out of the
+// route; hosts without an entry use DefaultTrafficWeight.
+type TrafficWeights struct {
+	UpdatedAt time.Time      `json:"updated_at"`
+	Weights   map[string]int `json:"weights"`
+}
+
+// Weight returns the weight of host.
+func (t *TrafficWeights) Weight(host string) int {
+	if t == nil {
+		return DefaultTrafficWeight
+	}
+	if weight, ok := t.Weights[host]; ok {
+		return weight
+	}
+	return DefaultTrafficWeight
+}
+
+// Customized reports whether any host deviates from DefaultTrafficWeight.
+func (t *TrafficWeights) Customized() bool {
+	if t == nil {
+		return false
+	}
+	for _, weight := range t.Weights {
+		if weight != DefaultTrafficWeight {
+			return true
+		}
+	}
+	return false
+}
+
+// Set records the weight of host. Hosts back at DefaultTrafficWeight are
+// dropped from the file.
+func (t *TrafficWeights) Set(host string, weight int) {
+	if t.Weights == nil {
+		t.Weights = make(map[string]int)
+	}
+	if weight == DefaultTrafficWeight {
+		delete(t.Weights, host)
+		return
+	}
+	t.Weights[host] = weight
+}
+
+// LoadTrafficWeights reads the traffic weights of service on the
+// load-balancer host lbHost. A host without a file yields default weights.
+func LoadTrafficWeights(sshClient *ssh.Client, lbHost, user, service string) (*TrafficWeights, error) {
+	file := state.ConfigFileQuoted(user, TrafficFileName(service))
+	cmd := fmt.Sprintf("if [ -f %s ]; then cat %s; fi", file, file) // safe: path from state.ConfigFileQuoted
+	result, err := sshClient.Execute(lbHost, cmd)
+	if err != nil {
+		return nil, err
+	}
+	if result.ExitCode != 0 {
+		return nil, fmt.Errorf("failed to read traffic weights: %s", result.Stderr)
+	}
+
+	weights := &TrafficWeights{}
+	if strings.TrimSpace(result.Stdout) == "" {
+		return weights, nil
+	}
+	if err := json.Unmarshal([]byte(result.Stdout), weights); err != nil {
+		return nil, fmt.Errorf("traffic weights on %s are invalid JSON: %w", lbHost, err)
+	}
+	return weights, nil
+}
+
+// SaveTrafficWeights writes the traffic weights of service to the
+// load-balancer host lbHost.
+func SaveTrafficWeights(sshClient *ssh.Client, lbHost, user, service string, weights *TrafficWeights) error {
+	weights.UpdatedAt = time.Now().UTC()
+	data, err := json.MarshalIndent(weights, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	name := TrafficFileName(service)
+	cmd := fmt.Sprintf(`umask 077 && mkdir -p %s && tmp=$(mktemp %s) && cat > "$tmp" && mv "$tmp" %s`, // safe: paths from state.*Quoted
+		state.DirQuoted(user), state.ConfigFileQuoted(user, name+".XXXXXX"), state.ConfigFileQuoted(user, name))
+	result, err := sshClient.ExecuteWithStdin(lbHost, cmd, bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	if result.ExitCode != 0 {
+		return fmt.Errorf("failed to write traffic weights: %s", result.Stderr)
+	}
+	return nil
+}
+
+// WebHostUpstreams returns the upstream of the running web container on each
+// web host. Hosts whose container is missing or unresolvable are left out.
+func WebHostUpstreams(cfg *config.Config, containers *podman.ContainerManager) map[string]string {
+	name := RoleContainerName(cfg, "web")
+	upstreams := make(map[string]string)
+	for _, host := range cfg.GetRoleHosts("web") {
+		running, err := containers.IsRunning(host, name)
+		if err != nil || !running {
+			continue
+		}
+		upstream, err := ResolveUpstream(cfg, containers, host, name)
+		if err != nil {
+			continue
+		}
+		upstreams[host] = upstream
+	}
+	return upstreams
+}
+
+// WeightedHostUpstreams pairs the upstream of each host, in hosts order,
+// with the host's traffic weight. Drained hosts are left out.
+func WeightedHostUpstreams(hosts []string, upstreams map[string]string, weights *TrafficWeights) []proxy.UpstreamWeight {
+	var weighted []proxy.UpstreamWeight
+	for _, host := range hosts {
+		upstream, ok := upstreams[host]
+		if !ok {
+			continue
+		}
+		if weight := weights.Weight(host); weight > 0 {
+			weighted = append(weighted, proxy.UpstreamWeight{Dial: upstream, Weight: weight})
+		}
+	}
+	return weighted
+}
@@ -0,0 +1,43 @@
+package deploy
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/lemonity-org/azud/internal/proxy"
+)
+
+func TestTrafficWeightsDefaultAndCustomized(t *testing.T) {
+	var none *TrafficWeights
+	if none.Weight("web-1") != DefaultTrafficWeight || none.Customized() {
+		t.Fatal("nil weights should give every host the default weight")
+	}
+
+	weights := &TrafficWeights{}
+	weights.Set("web-1", 0)
+	if weights.Weight("web-1") != 0 || !weights.Customized() {
+		t.Fatalf("drained host = %d, customized %v", weights.Weight("web-1"), weights.Customized())
+	}
+	weights.Set("web-1", DefaultTrafficWeight)
+	if len(weights.Weights) != 0 || weights.Customized() {
+		t.Fatalf("restoring the default weight should drop the entry: %#v", weights.Weights)
+	}
+}
+
+func TestWeightedHostUpstreamsSkipsDrainedAndMissingHosts(t *testing.T) {
+	weights := &TrafficWeights{Weights: map[string]int{"web-2": 0, "web-3": 10}}
+	upstreams := map[string]string{
+		"web-1": "10.0.0.1:41000",
+		"web-2": "10.0.0.2:41000",
+		"web-3": "10.0.0.3:41000",
+	}
+
+	got := WeightedHostUpstreams([]string{"web-1", "web-2", "web-3", "web-4"}, upstreams, weights)
+	want := []proxy.UpstreamWeight{
+		{Dial: "10.0.0.1:41000", Weight: 100},
+		{Dial: "10.0.0.3:41000", Weight: 10},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("WeightedHostUpstreams() = %#v, want %#v", got, want)
+	}
+}
@@ -0,0 +1,189 @@
+package dns
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// CloudflareAPI is the base URL of the Cloudflare API.
+const CloudflareAPI = "https://api.cloudflare.com/client/v4"
+
+// Cloudflare manages the records of a zone through the Cloudflare API.
+type Cloudflare struct {
+	// BaseURL of the API; tests point it at a local server
+	BaseURL string
+
+	zone   string
+	token  string
+	client *http.Client
+
+	mu     sync.Mutex
+	zoneID string
+}
+
+// NewCloudflare returns a provider for zone authenticating with an API
+// token that may edit its DNS records.
+func NewCloudflare(zone, token string) *Cloudflare {
+	return &Cloudflare{
+		BaseURL: CloudflareAPI,
+		zone:    strings.TrimSuffix(zone, "."),
+		token:   token,
+		client:  &http.Client{Timeout: 30 * time.Second},
+	}
+}
+
+// cloudflareRecord is a DNS record in API requests and responses. Records
+// created by azud are never proxied, so the names resolve to the hosts;
+// updates keep whether an existing record is proxied.
+type cloudflareRecord struct {
+	ID      string `json:"id,omitempty"`
+	Type    string `json:"type"`
+	Name    string `json:"name"`
+	Content string `json:"content"`
+	TTL     int    `json:"ttl"`
+	Proxied bool   `json:"proxied"`
+}
+
+// cloudflareResponse is the envelope of every API response.
+type cloudflareResponse struct {
+	Success bool `json:"success"`
+	Errors  []struct {
+		Code    int    `json:"code"`
+		Message string `json:"message"`
+	} `json:"errors"`
+	Result json.RawMessage `json:"result"`
+}
+
+func (c *Cloudflare) Records(ctx context.Context, name string) ([]Record, error) {
+	zoneID, err := c.lookupZoneID(ctx)
+	if err != nil {
+		return nil, err
+	}
+	query := url.Values{"name": {name}, "per_page": {"100"}}
+	var found []cloudflareRecord
+	if err := c.do(ctx, http.MethodGet, "/zones/"+zoneID+"/dns_records?"+query.Encode(), nil, &found); err != nil {
+		return nil, err
+	}
+
+	var records []Record
+	for _, record := range found {
+		if record.Type != "A" && record.Type != "AAAA" {
+			continue
+		}
+		ttl := record.TTL
+		if ttl == 1 {
+			ttl = 0
+		}
+		records = append(records, Record{ID: record.ID, Type: record.Type, Name: record.Name, Content: record.Content, TTL: ttl, Proxied: record.Proxied})
+	}
+	return records, nil
+}
+
+func (c *Cloudflare) Create(ctx context.Context, record Record) error {
+	zoneID, err := c.lookupZoneID(ctx)
+	if err != nil {
+		return err
+	}
+	return c.do(ctx, http.MethodPost, "/zones/"+zoneID+"/dns_records", toCloudflareRecord(record), nil)
+}
+
+func (c *Cloudflare) Update(ctx context.Context, record Record) error {
+	zoneID, err := c.lookupZoneID(ctx)
+	if err != nil {
+		return err
+	}
+	return c.do(ctx, http.MethodPut, "/zones/"+zoneID+"/dns_records/"+url.PathEscape(record.ID), toCloudflareRecord(record), nil)
+}
+
+func (c *Cloudflare) Delete(ctx context.Context, record Record) error {
+	zoneID, err := c.lookupZoneID(ctx)
+	if err != nil {
+		return err
+	}
+	return c.do(ctx, http.MethodDelete, "/zones/"+zoneID+"/dns_records/"+url.PathEscape(record.ID), nil, nil)
+}
+
+func toCloudflareRecord(record Record) cloudflareRecord {
+	ttl := record.TTL
+	if ttl == 0 {
+		// 1 is Cloudflare's automatic TTL.
+		ttl = 1
+	}
+	return cloudflareRecord{Type: record.Type, Name: record.Name, Content: record.Content, TTL: ttl, Proxied: record.Proxied}
+}
+
+// lookupZoneID returns the ID of the zone, looking it up once.
+func (c *Cloudflare) lookupZoneID(ctx context.Context) (string, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.zoneID != "" {
+		return c.zoneID, nil
+	}
+
+	var zones []struct {
+		ID string `json:"id"`
+	}
+	if err := c.do(ctx, http.MethodGet, "/zones?"+url.Values{"name": {c.zone}}.Encode(), nil, &zones); err != nil {
+		return "", err
+	}
+	if len(zones) == 0 {
+		return "", fmt.Errorf("zone %s not found; check that the token can read it", c.zone)
+	}
+	c.zoneID = zones[0].ID
+	return c.zoneID, nil
+}
+
+// do sends a request and decodes the result of the response into result.
+func (c *Cloudflare) do(ctx context.Context, method, path string, body, result any) error {
+	var reader io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		reader = bytes.NewReader(data)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, c.BaseURL+path, reader)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+c.token)
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	var envelope cloudflareResponse
+	if err := json.NewDecoder(io.LimitReader(resp.Body, 4<<20)).Decode(&envelope); err != nil {
+		return fmt.Errorf("cloudflare API returned %s", resp.Status)
+	}
+	if !envelope.Success {
+		var messages []string
+		for _, e := range envelope.Errors {
+			messages = append(messages, fmt.Sprintf("%s (%d)", e.Message, e.Code))
+		}
+		if len(messages) == 0 {
+			messages = append(messages, resp.Status)
+		}
+		return fmt.Errorf("cloudflare API: %s", strings.Join(messages, "; "))
+	}
+	if result != nil {
+		if err := json.Unmarshal(envelope.Result, result); err != nil {
+			return fmt.Errorf("unexpected cloudflare API result: %w", err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,172 @@
+// Package dns manages the address records that point the proxy hostnames
+// at the proxy hosts.
+package dns
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"slices"
+)
+
+// Record is an A or AAAA record at a DNS provider.
+type Record struct {
+	ID      string
+	Type    string
+	Name    string
+	Content string
+	// TTL in seconds; 0 means the provider's automatic TTL
+	TTL int
+	// Served through the provider's proxy (Cloudflare), so the name
+	// resolves to the provider's addresses rather than Content. Records
+	// azud creates are not proxied; updates keep the setting.
+	Proxied bool
+}
+
+// Provider reads and changes the records of a zone.
+type Provider interface {
+	// Records returns the A and AAAA records of name.
+	Records(ctx context.Context, name string) ([]Record, error)
+	Create(ctx context.Context, record Record) error
+	Update(ctx context.Context, record Record) error
+	Delete(ctx context.Context, record Record) error
+}
+
+// NewProvider returns the provider managing zone with the API token.
+func NewProvider(provider, zone, token string) (Provider, error) {
+	switch provider {
+	case "cloudflare":
+		return NewCloudflare(zone, token), nil
+	default:
+		return nil, fmt.Errorf("unknown DNS provider %q", provider)
+	}
+}
+
+// Change actions.
+const (
+	ActionCreate = "create"
+	ActionUpdate = "update"
+	ActionDelete = "delete"
+)
+
+// Change is one record change planned by Plan. Previous is the content an
+// updated record had.
+type Change struct {
+	Action   string
+	Record   Record
+	Previous string
+}
+
+// String describes the change for logs.
+func (c Change) String() string {
+	switch c.Action {
+	case ActionUpdate:
+		return fmt.Sprintf("update %s %s: %s -> %s", c.Record.Type, c.Record.Name, c.Previous, c.Record.Content)
+	default:
+		return fmt.Sprintf("%s %s %s %s", c.Action, c.Record.Type, c.Record.Name, c.Record.Content)
+	}
+}
+
+// RecordType returns A for IPv4 addresses and AAAA for IPv6 addresses.
+func RecordType(ip net.IP) string {
+	if ip.To4() != nil {
+		return "A"
+	}
+	return "AAAA"
+}
+
+// Plan returns the changes that make the A and AAAA records of name point
+// at exactly addresses. Records pointing elsewhere are updated in place
+// while addresses remain to be placed and deleted after that.
+func Plan(name string, current []Record, addresses []net.IP, ttl int) []Change {
+	var changes []Change
+	for _, recordType := range []string{"A", "AAAA"} {
+		var want []string
+		for _, ip := range addresses {
+			if RecordType(ip) == recordType && !slices.Contains(want, ip.String()) {
+				want = append(want, ip.String())
+			}
+		}
+
+		var stale []Record
+		for _, record := range current {
+			if record.Type != recordType {
+				continue
+			}
+			if i := slices.Index(want, canonicalIP(record.Content)); i >= 0 {
+				want = slices.Delete(want, i, i+1)
+				if ttl != record.TTL {
+					updated := record
+					updated.TTL = ttl
+					changes = append(changes, Change{Action: ActionUpdate, Record: updated, Previous: record.Content})
+				}
+				continue
+			}
+			stale = append(stale, record)
+		}
+
+		for _, record := range stale {
+			if len(want) > 0 {
+				updated := record
+				updated.Content, updated.TTL = want[0], ttl
+				want = want[1:]
+				changes = append(changes, Change{Action: ActionUpdate, Record: updated, Previous: record.Content})
+				continue
+			}
+			changes = append(changes, Change{Action: ActionDelete, Record: record})
+		}
+		for _, content := range want {
+			changes = append(changes, Change{
+				Action: ActionCreate,
+				Record: Record{Type: recordType, Name: name, Content: content, TTL: ttl},
+			})
+		}
+	}
+	return changes
+}
+
+// Apply makes the changes at provider in order.
+func Apply(ctx context.Context, provider Provider, changes []Change) error {
+	for _, change := range changes {
+		var err error
+		switch change.Action {
+		case ActionCreate:
+			err = provider.Create(ctx, change.Record)
+		case ActionUpdate:
+			err = provider.Update(ctx, change.Record)
+		case ActionDelete:
+			err = provider.Delete(ctx, change.Record)
+		}
+		if err != nil {
+			return fmt.Errorf("failed to %s: %w", change, err)
+		}
+	}
+	return nil
+}
+
+// Mismatch compares the addresses a name resolves to with the addresses it
+// should resolve to. It returns the resolved addresses that are not wanted
+// and the wanted addresses that are not resolved.
+func Mismatch(resolved, want []net.IP) (unexpected, missing []net.IP) {
+	contains := func(ips []net.IP, ip net.IP) bool {
+		return slices.ContainsFunc(ips, ip.Equal)
+	}
+	for _, ip := range resolved {
+		if !contains(want, ip) {
+			unexpected = append(unexpected, ip)
+		}
+	}
+	for _, ip := range want {
+		if !contains(resolved, ip) {
+			missing = append(missing, ip)
+		}
+	}
+	return unexpected, missing
+}
+
+func canonicalIP(content string) string {
+	if ip := net.ParseIP(content); ip != nil {
+		return ip.String()
+	}
+	return content
+}
@@ -0,0 +1,155 @@
+package dns
+
+import (
+	"context"
+	"encoding/json"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func ips(addresses ...string) []net.IP {
+	var out []net.IP
+	for _, address := range addresses {
+		out = append(out, net.ParseIP(address))
+	}
+	return out
+}
+
+func TestPlan(t *testing.T) {
+	tests := []struct {
+		name    string
+		current []Record
+		want    []Change
+	}{
+		{
+			name: "create",
+			want: []Change{
+				{Action: ActionCreate, Record: Record{Type: "A", Name: "app.example.com", Content: "203.0.113.10"}},
+				{Action: ActionCreate, Record: Record{Type: "A", Name: "app.example.com", Content: "203.0.113.11"}},
+				{Action: ActionCreate, Record: Record{Type: "AAAA", Name: "app.example.com", Content: "2001:db8::10"}},
+			},
+		},
+		{
+			name: "in sync",
+			current: []Record{
+				{ID: "1", Type: "A", Name: "app.example.com", Content: "203.0.113.11"},
+				{ID: "2", Type: "A", Name: "app.example.com", Content: "203.0.113.10"},
+				{ID: "3", Type: "AAAA", Name: "app.example.com", Content: "2001:0db8::0010"},
+			},
+		},
+		{
+			name: "points elsewhere",
+			current: []Record{
+				{ID: "1", Type: "A", Name: "app.example.com", Content: "198.51.100.1"},
+				{ID: "2", Type: "A", Name: "app.example.com", Content: "203.0.113.10"},
+				{ID: "3", Type: "AAAA", Name: "app.example.com", Content: "2001:db8::10"},
+				{ID: "4", Type: "AAAA", Name: "app.example.com", Content: "2001:db8::99", TTL: 300},
+			},
+			want: []Change{
+				{Action: ActionUpdate, Record: Record{ID: "1", Type: "A", Name: "app.example.com", Content: "203.0.113.11"}, Previous: "198.51.100.1"},
+				{Action: ActionDelete, Record: Record{ID: "4", Type: "AAAA", Name: "app.example.com", Content: "2001:db8::99", TTL: 300}},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := Plan("app.example.com", tt.current, ips("203.0.113.10", "203.0.113.11", "2001:db8::10", "203.0.113.10"), 0)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Plan() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+
+	got := Plan("app.example.com", []Record{{ID: "1", Type: "A", Content: "203.0.113.10"}}, ips("203.0.113.10"), 300)
+	if len(got) != 1 || got[0].Action != ActionUpdate || got[0].Record.TTL != 300 {
+		t.Errorf("Plan() with a new TTL = %+v, want one update", got)
+	}
+}
+
+func TestMismatch(t *testing.T) {
+	unexpected, missing := Mismatch(ips("203.0.113.10", "198.51.100.1"), ips("203.0.113.10", "203.0.113.11"))
+	if !reflect.DeepEqual(unexpected, ips("198.51.100.1")) || !reflect.DeepEqual(missing, ips("203.0.113.11")) {
+		t.Errorf("Mismatch() = %v, %v", unexpected, missing)
+	}
+}
+
+func TestCloudflare(t *testing.T) {
+	var requests []string
+	var created, updated cloudflareRecord
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests = append(requests, r.Method+" "+r.URL.String())
+		if r.Header.Get("Authorization") != "Bearer secret" {
+			w.WriteHeader(http.StatusForbidden)
+			_, _ = w.Write([]byte(`{"success":false,"errors":[{"code":10000,"message":"Authentication error"}]}`))
+			return
+		}
+		switch {
+		case r.URL.Path == "/zones":
+			_, _ = w.Write([]byte(`{"success":true,"result":[{"id":"z1"}]}`))
+		case r.Method == http.MethodGet:
+			_, _ = w.Write([]byte(`{"success":true,"result":[
+				{"id":"r1","type":"A","name":"app.example.com","content":"203.0.113.10","ttl":1,"proxied":true},
+				{"id":"r2","type":"TXT","name":"app.example.com","content":"v=spf1","ttl":300}]}`))
+		case r.Method == http.MethodPost:
+			_ = json.NewDecoder(r.Body).Decode(&created)
+			_, _ = w.Write([]byte(`{"success":true,"result":{}}`))
+		case r.Method == http.MethodPut:
+			_ = json.NewDecoder(r.Body).Decode(&updated)
+			_, _ = w.Write([]byte(`{"success":true,"result":{}}`))
+		default:
+			_, _ = w.Write([]byte(`{"success":true,"result":{}}`))
+		}
+	}))
+	defer server.Close()
+
+	provider := NewCloudflare("example.com", "secret")
+	provider.BaseURL = server.URL
+	ctx := context.Background()
+
+	records, err := provider.Records(ctx, "app.example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []Record{{ID: "r1", Type: "A", Name: "app.example.com", Content: "203.0.113.10", Proxied: true}}
+	if !reflect.DeepEqual(records, want) {
+		t.Errorf("Records() = %+v, want %+v", records, want)
+	}
+	if err := provider.Create(ctx, Record{Type: "AAAA", Name: "app.example.com", Content: "2001:db8::10"}); err != nil {
+		t.Fatal(err)
+	}
+	if created.TTL != 1 || created.Proxied || created.Content != "2001:db8::10" {
+		t.Errorf("created record = %+v", created)
+	}
+	moved := records[0]
+	moved.Content = "203.0.113.20"
+	if err := provider.Update(ctx, moved); err != nil {
+		t.Fatal(err)
+	}
+	if !updated.Proxied || updated.Content != "203.0.113.20" {
+		t.Errorf("updated record = %+v, want it to stay proxied", updated)
+	}
+	if err := provider.Delete(ctx, records[0]); err != nil {
+		t.Fatal(err)
+	}
+
+	wantRequests := []string{
+		"GET /zones?name=example.com",
+		"GET /zones/z1/dns_records?name=app.example.com&per_page=100",
+		"POST /zones/z1/dns_records",
+		"PUT /zones/z1/dns_records/r1",
+		"DELETE /zones/z1/dns_records/r1",
+	}
+	if !reflect.DeepEqual(requests, wantRequests) {
+		t.Errorf("requests = %v, want %v", requests, wantRequests)
+	}
+
+	unauthorized := NewCloudflare("example.com", "wrong")
+	unauthorized.BaseURL = server.URL
+	if _, err := unauthorized.Records(ctx, "app.example.com"); err == nil || !strings.Contains(err.Error(), "Authentication error") {
+		t.Errorf("Records() with a bad token error = %v", err)
+	}
+}
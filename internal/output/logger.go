@@ -29,8 +29,10 @@ type Logger struct {
 	out        io.Writer
 	err        io.Writer
 	verbose    bool
+	quiet      bool
 	width      int
 	outStarted bool
+	board      *PhaseBoard
 	mu         sync.Mutex
 }
 
@@ -54,6 +56,15 @@ func (l *Logger) SetVerbose(verbose bool) {
 	l.verbose = verbose
 }
 
+// SetQuiet suppresses progress records (INFO, HOST, STEP, CMD, DEBUG, and
+// headers) so only outcomes, warnings, and errors are printed. It is meant
+// for CI logs.
+func (l *Logger) SetQuiet(quiet bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.quiet = quiet
+}
+
 // SetWidth overrides automatic terminal-width detection. Zero restores
 // automatic sizing. It is useful for embedded and test renderers.
 func (l *Logger) SetWidth(columns int) {
@@ -67,29 +78,32 @@ func (l *Logger) SetWidth(columns int) {
 
 // Info prints an informational record.
 func (l *Logger) Info(format string, args ...interface{}) {
-	l.mu.Lock()
-	defer l.mu.Unlock()
+	l.lock()
+	defer l.unlock()
+	if l.quiet {
+		return
+	}
 	l.writeOutRecord("INFO", Blue, fmt.Sprintf(format, args...))
 }
 
 // Success prints a successful-state record.
 func (l *Logger) Success(format string, args ...interface{}) {
-	l.mu.Lock()
-	defer l.mu.Unlock()
+	l.lock()
+	defer l.unlock()
 	l.writeOutRecord("OK", Green, fmt.Sprintf(format, args...))
 }
 
 // Warn prints a warning record.
 func (l *Logger) Warn(format string, args ...interface{}) {
-	l.mu.Lock()
-	defer l.mu.Unlock()
+	l.lock()
+	defer l.unlock()
 	l.writeOutRecord("WARN", Yellow, fmt.Sprintf(format, args...))
 }
 
 // Error prints an error record.
 func (l *Logger) Error(format string, args ...interface{}) {
-	l.mu.Lock()
-	defer l.mu.Unlock()
+	l.lock()
+	defer l.unlock()
 	l.writeRecord(l.err, "ERROR", Red, fmt.Sprintf(format, args...))
 }
 
@@ -101,47 +115,61 @@ func (l *Logger) Fatal(format string, args ...interface{}) {
 
 // Debug prints a debug record when verbose mode is enabled.
 func (l *Logger) Debug(format string, args ...interface{}) {
-	l.mu.Lock()
-	defer l.mu.Unlock()
-	if !l.verbose {
+	l.lock()
+	defer l.unlock()
+	if !l.verbose || l.quiet {
 		return
 	}
 	l.writeOutRecord("DEBUG", Gray, fmt.Sprintf(format, args...))
 }
 
-// Host prints an in-progress host record.
+// Host prints an in-progress host record. While a live phase board shows
+// the host, the message is shown on its line instead.
 func (l *Logger) Host(host, format string, args ...interface{}) {
-	l.mu.Lock()
-	defer l.mu.Unlock()
-	l.writeOutRecord("HOST", Blue, hostMessage(host, fmt.Sprintf(format, args...)))
+	l.lock()
+	defer l.unlock()
+	if l.quiet {
+		return
+	}
+	message := fmt.Sprintf(format, args...)
+	if l.board != nil && l.board.setDetail(host, message) {
+		return
+	}
+	l.writeOutRecord("HOST", Blue, hostMessage(host, message))
 }
 
 // HostSuccess prints a successful host record.
 func (l *Logger) HostSuccess(host, format string, args ...interface{}) {
-	l.mu.Lock()
-	defer l.mu.Unlock()
+	l.lock()
+	defer l.unlock()
 	l.writeOutRecord("OK", Green, hostMessage(host, fmt.Sprintf(format, args...)))
 }
 
 // HostError prints a failed host record.
 func (l *Logger) HostError(host, format string, args ...interface{}) {
-	l.mu.Lock()
-	defer l.mu.Unlock()
+	l.lock()
+	defer l.unlock()
 	l.writeRecord(l.err, "ERROR", Red, hostMessage(host, fmt.Sprintf(format, args...)))
 }
 
 // Step prints a numbered operation record.
 func (l *Logger) Step(step int, total int, format string, args ...interface{}) {
-	l.mu.Lock()
-	defer l.mu.Unlock()
+	l.lock()
+	defer l.unlock()
+	if l.quiet {
+		return
+	}
 	message := fmt.Sprintf("%d/%d  %s", step, total, fmt.Sprintf(format, args...))
 	l.writeOutRecord("STEP", Blue, message)
 }
 
 // Command prints a command being executed.
 func (l *Logger) Command(command string) {
-	l.mu.Lock()
-	defer l.mu.Unlock()
+	l.lock()
+	defer l.unlock()
+	if l.quiet {
+		return
+	}
 	l.writeOutRecord("CMD", Gray, command)
 }
 
@@ -151,8 +179,11 @@ func (l *Logger) Output(output string) {
 	if output == "" {
 		return
 	}
-	l.mu.Lock()
-	defer l.mu.Unlock()
+	l.lock()
+	defer l.unlock()
+	if l.quiet {
+		return
+	}
 	l.writeOutput(l.out, output)
 	l.outStarted = true
 }
@@ -162,8 +193,8 @@ func (l *Logger) OutputError(output string) {
 	if output == "" {
 		return
 	}
-	l.mu.Lock()
-	defer l.mu.Unlock()
+	l.lock()
+	defer l.unlock()
 	l.writeOutput(l.err, output)
 }
 
@@ -187,8 +218,11 @@ func (l *Logger) writeOutput(writer io.Writer, output string) {
 
 // Header prints a compact ruled section heading.
 func (l *Logger) Header(format string, args ...interface{}) {
-	l.mu.Lock()
-	defer l.mu.Unlock()
+	l.lock()
+	defer l.unlock()
+	if l.quiet {
+		return
+	}
 
 	if l.outStarted {
 		_, _ = fmt.Fprintln(l.out)
@@ -209,31 +243,50 @@ func (l *Logger) Header(format string, args ...interface{}) {
 
 // Print writes raw output. Use it for machine-readable command surfaces.
 func (l *Logger) Print(format string, args ...interface{}) {
-	l.mu.Lock()
-	defer l.mu.Unlock()
+	l.lock()
+	defer l.unlock()
 	_, _ = fmt.Fprintf(l.out, format, args...)
 	l.outStarted = true
 }
 
 // Println writes a raw line. Use it for machine-readable command surfaces.
 func (l *Logger) Println(format string, args ...interface{}) {
-	l.mu.Lock()
-	defer l.mu.Unlock()
+	l.lock()
+	defer l.unlock()
 	_, _ = fmt.Fprintf(l.out, format+"\n", args...)
 	l.outStarted = true
 }
 
-// Phase represents a single step in a deployment pipeline.
+// ClearScreen clears an interactive terminal so a refreshing view can redraw
+// in place. Other destinations get a blank line between frames instead.
+func (l *Logger) ClearScreen() {
+	l.lock()
+	defer l.unlock()
+	if isTTYWriter(l.out) && supportsANSIWriter(l.out) {
+		_, _ = fmt.Fprint(l.out, "\x1b[H\x1b[2J")
+		l.outStarted = false
+		return
+	}
+	if l.outStarted {
+		_, _ = fmt.Fprintln(l.out)
+	}
+}
+
+// Phase represents a single step in a deployment pipeline. A phase that is
+// neither complete, active, failed, nor skipped is pending.
 type Phase struct {
 	Name     string
 	Complete bool
+	Active   bool
+	Failed   bool
+	Skipped  bool
 }
 
 // TrafficBar renders the canary/stable traffic split as a fixed technical
 // gauge plus explicit written percentages.
 func (l *Logger) TrafficBar(canaryPct int, canaryLabel, stableLabel string) {
-	l.mu.Lock()
-	defer l.mu.Unlock()
+	l.lock()
+	defer l.unlock()
 
 	canaryPct = clamp(canaryPct, 0, 100)
 	stablePct := 100 - canaryPct
@@ -280,34 +333,49 @@ func (l *Logger) writeTrafficDetails(canaryPct int, canaryLabel string, stablePc
 
 // HostPhase renders a host followed by explicit complete and pending phases.
 func (l *Logger) HostPhase(host string, phases []Phase) {
-	l.mu.Lock()
-	defer l.mu.Unlock()
+	l.lock()
+	defer l.unlock()
+	if l.quiet {
+		return
+	}
+	l.writeOutRecord("HOST", Blue, l.phaseMessage(host, phases))
+}
 
-	complete, pending := "[x]", "[ ]"
+func (l *Logger) phaseMessage(host string, phases []Phase) string {
+	complete, pending, active, failed, skipped := "[x]", "[ ]", "[>]", "[!]", "[-]"
 	if supportsUnicode(l.out) {
-		complete, pending = SymHeader, SymPending
+		complete, pending, active, failed, skipped = SymHeader, SymPending, SymCommand, SymError, SymDebug
 	}
 
 	parts := make([]string, 0, len(phases))
 	for _, phase := range phases {
-		if phase.Complete {
-			parts = append(parts, styleForWriter(l.out, Green, complete, false)+" "+phase.Name)
-		} else {
-			parts = append(parts, styleForWriter(l.out, Gray, pending, false)+" "+phase.Name)
+		var marker string
+		switch {
+		case phase.Failed:
+			marker = styleForWriter(l.out, Red, failed, false)
+		case phase.Complete:
+			marker = styleForWriter(l.out, Green, complete, false)
+		case phase.Skipped:
+			marker = styleForWriter(l.out, Gray, skipped, false)
+		case phase.Active:
+			marker = styleForWriter(l.out, Yellow, active, false)
+		default:
+			marker = styleForWriter(l.out, Gray, pending, false)
 		}
+		parts = append(parts, marker+" "+phase.Name)
 	}
 
 	message := host
 	if len(parts) > 0 {
 		message += " / " + strings.Join(parts, "  ")
 	}
-	l.writeOutRecord("HOST", Blue, message)
+	return message
 }
 
 // StatusBadge renders a key-value record with an explicit uppercase state.
 func (l *Logger) StatusBadge(label, status string) {
-	l.mu.Lock()
-	defer l.mu.Unlock()
+	l.lock()
+	defer l.unlock()
 
 	tone := Blue
 	switch strings.ToLower(status) {
@@ -382,8 +450,8 @@ func (p *Progress) Done() {
 // Table prints dense tabular data. Interactive narrow terminals reflow rows
 // into labeled records; non-TTY output remains deterministic and columnar.
 func (l *Logger) Table(headers []string, rows [][]string) {
-	l.mu.Lock()
-	defer l.mu.Unlock()
+	l.lock()
+	defer l.unlock()
 	if len(headers) == 0 {
 		return
 	}
@@ -468,6 +536,18 @@ func (l *Logger) writeEmptyRecordTable(headers []string) {
 	}
 }
 
+// lock acquires the logger and lifts a live phase board off the terminal so
+// the record is written above it; unlock redraws the board.
+func (l *Logger) lock() {
+	l.mu.Lock()
+	l.clearBoard()
+}
+
+func (l *Logger) unlock() {
+	l.drawBoard()
+	l.mu.Unlock()
+}
+
 func (l *Logger) writeOutRecord(label string, tone PastelColor, message string) {
 	l.writeRecord(l.out, label, tone, message)
 	l.outStarted = true
@@ -659,6 +739,10 @@ func SetVerbose(verbose bool) {
 	DefaultLogger.SetVerbose(verbose)
 }
 
+func SetQuiet(quiet bool) {
+	DefaultLogger.SetQuiet(quiet)
+}
+
 func Println(format string, args ...interface{}) {
 	DefaultLogger.Println(format, args...)
 }
@@ -357,3 +357,99 @@ func TestPlainSurfaceContainsNoANSIOrUnicodeStructure(t *testing.T) {
 		}
 	}
 }
+
+func TestPhaseBoardPlainReportsPhaseChanges(t *testing.T) {
+	usePlainProfile(t)
+	logger, out, _ := newTestLogger()
+	board := logger.NewPhaseBoard("Pull", "Container", "Health")
+	board.Add("app-01/web", "app-01")
+
+	board.Start("app-01/web", "Pull")
+	board.Complete("app-01/web", "Pull")
+	board.Skip("app-01/web", "Container")
+	board.Start("app-01/web", "Health")
+	logger.Host("app-01", "Waiting for readiness check...")
+	board.Fail("app-01/web")
+	board.Close()
+
+	const want = "" +
+		"  HOST   app-01/web / [x] Pull  [ ] Container  [ ] Health\n" +
+		"  HOST   app-01 / Waiting for readiness check...\n" +
+		"  HOST   app-01/web / [x] Pull  [-] Container  [!] Health\n"
+	if got := out.String(); got != want {
+		t.Fatalf("plain board output = %q, want %q", got, want)
+	}
+}
+
+func TestPhaseBoardLiveRedrawsBelowRecords(t *testing.T) {
+	usePlainProfile(t)
+	logger, out, _ := newTestLogger()
+	board := logger.newPhaseBoard([]string{"Pull", "Proxy"}, true)
+	board.Add("a", "a")
+	board.Add("b", "b")
+
+	board.Start("a", "Pull")
+	logger.Host("a", "Pulling")
+	logger.Info("Deploying")
+	board.Close()
+
+	got := out.String()
+	if !strings.Contains(got, "\x1b[2A\r\x1b[J") {
+		t.Fatalf("live board was not redrawn in place: %q", got)
+	}
+	if strings.Contains(got, "HOST   a / Pulling") {
+		t.Fatalf("host record for a board row was printed instead of shown on the row: %q", got)
+	}
+	const tail = "  INFO   Deploying\n" +
+		"  HOST   a / [>] Pull  [ ] Proxy  Pulling\n" +
+		"  HOST   b / [ ] Pull  [ ] Proxy\n"
+	if !strings.HasSuffix(got, tail) {
+		t.Fatalf("final output = %q, want suffix %q", got, tail)
+	}
+
+	logger.Info("After")
+	if !strings.HasSuffix(out.String(), tail+"  INFO   After\n") {
+		t.Fatalf("closed board was redrawn: %q", out.String())
+	}
+}
+
+func TestQuietKeepsOutcomesWarningsAndErrors(t *testing.T) {
+	usePlainProfile(t)
+	logger, out, errOut := newTestLogger(true)
+	logger.SetQuiet(true)
+
+	logger.Header("Deploying")
+	logger.Info("Pulling image")
+	logger.Host("app-01", "Starting container")
+	logger.Step(1, 2, "Build")
+	logger.Debug("detail")
+	logger.HostPhase("app-01", []Phase{{Name: "Pull", Complete: true}})
+	board := logger.NewPhaseBoard("Pull")
+	board.Add("app-01", "app-01")
+	board.Complete("app-01", "Pull")
+	logger.Warn("Digest verification disabled")
+	logger.Success("Deployment complete")
+	logger.HostError("app-02", "Readiness check failed")
+
+	const want = "  WARN   Digest verification disabled\n  OK     Deployment complete\n"
+	if got := out.String(); got != want {
+		t.Fatalf("quiet output = %q, want %q", got, want)
+	}
+	if got := errOut.String(); got != "  ERROR  app-02 / Readiness check failed\n" {
+		t.Fatalf("quiet errors = %q", got)
+	}
+}
+
+func TestClearScreenSeparatesFramesWithoutTerminal(t *testing.T) {
+	usePlainProfile(t)
+	logger, out, _ := newTestLogger()
+
+	logger.ClearScreen()
+	logger.Println("frame 1")
+	logger.ClearScreen()
+	logger.Println("frame 2")
+
+	if got, want := out.String(), "frame 1\n\nframe 2\n"; got != want {
+		t.Fatalf("output = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,234 @@
+package output
+
+import (
+	"fmt"
+	"os"
+	"strings"
+)
+
+// PhaseBoard tracks the pipeline phases of several hosts. On an interactive
+// terminal it keeps one line per row below the other output and redraws it
+// in place. Elsewhere, and for logs, it prints a HOST record whenever a row
+// completes or fails a phase. A nil board ignores all updates.
+type PhaseBoard struct {
+	log    *Logger
+	phases []string
+	rows   []*phaseRow
+	live   bool
+	drawn  int
+}
+
+type phaseRow struct {
+	key    string
+	host   string
+	phases []Phase
+	detail string
+}
+
+// NewPhaseBoard creates a board whose rows share the given phases. The live
+// view is used when stdout is an ANSI-capable terminal and quiet mode is off.
+func (l *Logger) NewPhaseBoard(phases ...string) *PhaseBoard {
+	return l.newPhaseBoard(phases, isTTYWriter(l.out) && supportsANSIWriter(l.out) && !strings.EqualFold(os.Getenv("TERM"), "dumb"))
+}
+
+func (l *Logger) newPhaseBoard(phases []string, live bool) *PhaseBoard {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	board := &PhaseBoard{log: l, phases: phases, live: live && !l.quiet}
+	if board.live {
+		l.board = board
+	}
+	return board
+}
+
+// Add adds a row labeled key for host. Host records for that host update
+// the row while the live view is shown.
+func (b *PhaseBoard) Add(key, host string) {
+	if b == nil {
+		return
+	}
+	b.log.lock()
+	defer b.log.unlock()
+	phases := make([]Phase, len(b.phases))
+	for i, name := range b.phases {
+		phases[i] = Phase{Name: name}
+	}
+	b.rows = append(b.rows, &phaseRow{key: key, host: host, phases: phases})
+}
+
+// Start marks phase as in progress for the row.
+func (b *PhaseBoard) Start(key, phase string) {
+	b.update(key, phase, false, func(p *Phase) { p.Active = true })
+}
+
+// Complete marks phase as done for the row.
+func (b *PhaseBoard) Complete(key, phase string) {
+	b.update(key, phase, true, func(p *Phase) { p.Active, p.Complete = false, true })
+}
+
+// Skip marks phase as not applicable for the row.
+func (b *PhaseBoard) Skip(key, phase string) {
+	b.update(key, phase, false, func(p *Phase) { p.Active, p.Skipped = false, true })
+}
+
+// Fail marks the row's active phase, or its first pending phase, as failed.
+func (b *PhaseBoard) Fail(key string) {
+	if b == nil {
+		return
+	}
+	b.log.lock()
+	defer b.log.unlock()
+	row := b.row(key)
+	if row == nil {
+		return
+	}
+	failed := -1
+	for i, phase := range row.phases {
+		if phase.Active {
+			failed = i
+			break
+		}
+		if failed < 0 && !phase.Complete && !phase.Skipped && !phase.Failed {
+			failed = i
+		}
+	}
+	if failed < 0 {
+		return
+	}
+	row.phases[failed].Active, row.phases[failed].Failed = false, true
+	row.detail = ""
+	b.report(row)
+}
+
+// Phases returns a copy of the row's phases.
+func (b *PhaseBoard) Phases(key string) []Phase {
+	if b == nil {
+		return nil
+	}
+	b.log.mu.Lock()
+	defer b.log.mu.Unlock()
+	row := b.row(key)
+	if row == nil {
+		return nil
+	}
+	return append([]Phase(nil), row.phases...)
+}
+
+// Close leaves the final state of the live view on screen and returns
+// output to plain records.
+func (b *PhaseBoard) Close() {
+	if b == nil {
+		return
+	}
+	b.log.mu.Lock()
+	defer b.log.mu.Unlock()
+	if b.log.board == b {
+		if b.drawn == 0 && len(b.rows) > 0 {
+			b.draw()
+		}
+		b.log.board = nil
+		b.log.outStarted = true
+	}
+}
+
+func (b *PhaseBoard) update(key, phase string, report bool, apply func(*Phase)) {
+	if b == nil {
+		return
+	}
+	b.log.lock()
+	defer b.log.unlock()
+	row := b.row(key)
+	if row == nil {
+		return
+	}
+	for i := range row.phases {
+		if row.phases[i].Name == phase {
+			apply(&row.phases[i])
+			row.detail = ""
+			if report {
+				b.report(row)
+			}
+			return
+		}
+	}
+}
+
+// report prints a row's phases when the live view is not shown.
+func (b *PhaseBoard) report(row *phaseRow) {
+	if b.live || b.log.quiet {
+		return
+	}
+	b.log.writeOutRecord("HOST", Blue, b.log.phaseMessage(row.key, row.phases))
+}
+
+func (b *PhaseBoard) row(key string) *phaseRow {
+	for _, row := range b.rows {
+		if row.key == key {
+			return row
+		}
+	}
+	return nil
+}
+
+// setDetail shows a host message on the row for host that is in progress.
+// It reports false when no row shows the host.
+func (b *PhaseBoard) setDetail(host, message string) bool {
+	var target *phaseRow
+	for _, row := range b.rows {
+		if row.host != host {
+			continue
+		}
+		if target == nil {
+			target = row
+		}
+		for _, phase := range row.phases {
+			if phase.Active {
+				row.detail = message
+				return true
+			}
+		}
+	}
+	if target == nil {
+		return false
+	}
+	target.detail = message
+	return true
+}
+
+// draw writes one line per row. Lines are kept within the terminal width so
+// the cursor can move back over them exactly.
+func (b *PhaseBoard) draw() {
+	l := b.log
+	width := l.outputWidth()
+	for _, row := range b.rows {
+		message := l.phaseMessage(row.key, row.phases)
+		if row.detail != "" {
+			message += "  " + styleForWriter(l.out, Gray, row.detail, false)
+		}
+		prefix := recordIndent + styleForWriter(l.out, Blue, padRight("HOST", recordLabelWidth), true) + recordGutter
+		line := prefix + message
+		if width > 0 && displayWidth(line) >= width {
+			line = prefix + l.phaseMessage(row.key, row.phases)
+		}
+		_, _ = fmt.Fprintln(l.out, line)
+	}
+	b.drawn = len(b.rows)
+}
+
+// clearBoard moves the cursor back over a drawn live board and erases it.
+func (l *Logger) clearBoard() {
+	if l.board == nil || l.board.drawn == 0 {
+		return
+	}
+	_, _ = fmt.Fprintf(l.out, "\x1b[%dA\r\x1b[J", l.board.drawn)
+	l.board.drawn = 0
+}
+
+// drawBoard redraws the live board below the latest record.
+func (l *Logger) drawBoard() {
+	if l.board == nil || l.quiet || len(l.board.rows) == 0 {
+		return
+	}
+	l.board.draw()
+	l.outStarted = true
+}
@@ -2,7 +2,9 @@ package podman
 
 import (
 	"fmt"
+	"io"
 	"sort"
+	"strconv"
 	"strings"
 
 	"github.com/lemonity-org/azud/internal/shell"
@@ -37,6 +39,20 @@ func (c *Client) Execute(host string, args ...string) (*ssh.Result, error) {
 	return c.ssh.Execute(host, cmd)
 }
 
+// ExecuteLines runs a podman command on host and passes each line of its
+// output to onLine as it is produced.
+func (c *Client) ExecuteLines(host string, onLine ssh.LineHandler, args ...string) (*ssh.Result, error) {
+	cmd := c.command + " " + strings.Join(shell.QuoteAll(args), " ")
+	return c.ssh.ExecuteLines(host, cmd, onLine)
+}
+
+// ExecuteStream runs a podman command on host and copies its output to
+// stdout and stderr as it is produced, e.g. for commands that follow.
+func (c *Client) ExecuteStream(host string, stdout, stderr io.Writer, args ...string) error {
+	cmd := c.command + " " + strings.Join(shell.QuoteAll(args), " ")
+	return c.ssh.ExecuteStream(host, cmd, stdout, stderr)
+}
+
 func (c *Client) ExecuteAll(hosts []string, args ...string) []*ssh.Result {
 	cmd := c.command + " " + strings.Join(shell.QuoteAll(args), " ")
 	return c.ssh.ExecuteParallel(hosts, cmd)
@@ -88,6 +104,27 @@ func ValidateOptions(options []string) error {
 	return nil
 }
 
+// PortSpec formats a -p publish spec binding containerPort to hostPort on ip.
+// A zero hostPort lets Podman choose a free port. IPv6 addresses are written
+// in brackets ([::]:8080:80) so the colons are not mistaken for separators.
+func PortSpec(ip string, hostPort, containerPort int) string {
+	host := ""
+	if hostPort > 0 {
+		host = strconv.Itoa(hostPort)
+	}
+	ip = strings.TrimSuffix(strings.TrimPrefix(ip, "["), "]")
+	if ip == "" {
+		if host == "" {
+			return strconv.Itoa(containerPort)
+		}
+		return fmt.Sprintf("%s:%d", host, containerPort)
+	}
+	if strings.Contains(ip, ":") {
+		ip = "[" + ip + "]"
+	}
+	return fmt.Sprintf("%s:%s:%d", ip, host, containerPort)
+}
+
 // ContainerConfig holds configuration for running a container.
 type ContainerConfig struct {
 	Name       string
@@ -97,22 +134,45 @@ type ContainerConfig struct {
 	Env        map[string]string
 	SecretEnv  []string // Secret env var names (resolved from secrets file)
 	EnvFile    string   // Path to env file on remote host
+	Secrets    []string // Podman secret specs passed as --secret (name,target=...)
 	// EnvFileOptional controls whether a missing env file is tolerated.
 	// When true, run command falls back to no env file if it's missing.
 	EnvFileOptional bool
+	// EnvTemplates are variables whose ${NAME} references are resolved from
+	// EnvFile on the remote host, into a private temporary env file, so the
+	// composed values never appear on a command line.
+	EnvTemplates map[string]string
+	// EnvFileIdentity marks EnvFile as age-encrypted to this identity on the
+	// remote host. It is decrypted into a private temporary file for the run.
+	EnvFileIdentity string
 	Ports           []string // host:container or ip:host:container
 	Volumes         []string // host:container or host:container:options
+	HostDirs        []string // host directories created before the run
 	Labels          map[string]string
 	Network         string
 	NetworkAliases  []string
 	Networks        []string
-	Memory          string // e.g., "512m"
-	CPUs            string // e.g., "0.5"
-	Restart         string // no, always, unless-stopped, on-failure[:max-retries]
+	Memory          string   // e.g., "512m"
+	CPUs            string   // e.g., "0.5"
+	LogDriver       string   // e.g., "journald"
+	LogOpts         []string // key=value, e.g., "max-size=10m"
+	Restart         string   // no, always, unless-stopped, on-failure[:max-retries]
 	Detach          bool
 	Remove          bool
 	Pull            bool
 
+	// Runtime
+	User        string // user, user:group, or uid:gid
+	Init        bool   // run an init process as PID 1
+	StopSignal  string // e.g., "SIGQUIT"
+	StopTimeout int    // seconds to wait after StopSignal before killing
+
+	// Hardening
+	ReadOnly     bool     // mount the root filesystem read-only
+	Tmpfs        []string // path or path:options
+	CapDrop      []string // capabilities to drop, e.g., "ALL"
+	SecurityOpts []string // e.g., "no-new-privileges", "seccomp=/path"
+
 	// Healthcheck
 	HealthCmd         string
 	HealthInterval    string
@@ -123,6 +183,57 @@ type ContainerConfig struct {
 	Options []string // Additional run options
 }
 
+// RuntimeArgs returns the run flags for User, Init, StopSignal, and
+// StopTimeout, each as a single --flag=value argument.
+func (c *ContainerConfig) RuntimeArgs() []string {
+	var args []string
+	if c.User != "" {
+		args = append(args, "--user="+c.User)
+	}
+	if c.Init {
+		args = append(args, "--init")
+	}
+	if c.StopSignal != "" {
+		args = append(args, "--stop-signal="+c.StopSignal)
+	}
+	if c.StopTimeout > 0 {
+		args = append(args, fmt.Sprintf("--stop-timeout=%d", c.StopTimeout))
+	}
+	return args
+}
+
+// LogArgs returns the run flags for LogDriver and LogOpts, each as a single
+// --flag=value argument.
+func (c *ContainerConfig) LogArgs() []string {
+	var args []string
+	if c.LogDriver != "" {
+		args = append(args, "--log-driver="+c.LogDriver)
+	}
+	for _, opt := range c.LogOpts {
+		args = append(args, "--log-opt="+opt)
+	}
+	return args
+}
+
+// SecurityArgs returns the run flags for ReadOnly, Tmpfs, CapDrop, and
+// SecurityOpts, each as a single --flag=value argument.
+func (c *ContainerConfig) SecurityArgs() []string {
+	var args []string
+	if c.ReadOnly {
+		args = append(args, "--read-only")
+	}
+	for _, mount := range c.Tmpfs {
+		args = append(args, "--tmpfs="+mount)
+	}
+	for _, capability := range c.CapDrop {
+		args = append(args, "--cap-drop="+capability)
+	}
+	for _, opt := range c.SecurityOpts {
+		args = append(args, "--security-opt="+opt)
+	}
+	return args
+}
+
 func (c *ContainerConfig) BuildRunCommand() string {
 	args := []string{"run"}
 
@@ -158,6 +269,10 @@ func (c *ContainerConfig) BuildRunCommand() string {
 		}
 	}
 
+	for _, secret := range c.Secrets {
+		args = append(args, "--secret", shell.Quote(secret))
+	}
+
 	for _, port := range c.Ports {
 		args = append(args, "-p", shell.Quote(port))
 	}
@@ -179,6 +294,11 @@ func (c *ContainerConfig) BuildRunCommand() string {
 	if c.Network != "" {
 		args = append(args, "--network", shell.Quote(c.Network))
 	}
+	for _, network := range c.Networks {
+		if network != c.Network {
+			args = append(args, "--network", shell.Quote(network))
+		}
+	}
 
 	for _, alias := range c.NetworkAliases {
 		args = append(args, "--network-alias", shell.Quote(alias))
@@ -191,6 +311,16 @@ func (c *ContainerConfig) BuildRunCommand() string {
 		args = append(args, "--cpus", shell.Quote(c.CPUs))
 	}
 
+	for _, arg := range c.RuntimeArgs() {
+		args = append(args, shell.Quote(arg))
+	}
+	for _, arg := range c.LogArgs() {
+		args = append(args, shell.Quote(arg))
+	}
+	for _, arg := range c.SecurityArgs() {
+		args = append(args, shell.Quote(arg))
+	}
+
 	if c.Restart != "" {
 		args = append(args, "--restart", shell.Quote(c.Restart))
 	}
@@ -232,24 +362,135 @@ func (c *ContainerConfig) BuildRunCommand() string {
 
 	baseCmd := "podman " + strings.Join(args, " ")
 	if c.EnvFile == "" {
-		return baseCmd
+		return c.withHostDirs(baseCmd)
 	}
 
 	// EnvFile may contain $HOME for shell expansion; use double quotes
 	// to allow variable expansion while protecting against spaces.
 	quotedEnvFile := shell.QuoteRemotePath(c.EnvFile)
-	withEnvArgs := append(preImageArgs, "--env-file", quotedEnvFile, shell.Quote(c.Image))
+	envFileArg := quotedEnvFile
+	if c.EnvFileIdentity != "" {
+		envFileArg = `"$azud_env"`
+	}
+	withEnvArgs := append(preImageArgs, "--env-file", envFileArg)
+	if len(c.EnvTemplates) > 0 {
+		withEnvArgs = append(withEnvArgs, "--env-file", `"$azud_tmpl"`)
+	}
+	withEnvArgs = append(withEnvArgs, shell.Quote(c.Image))
 	if len(c.Command) > 0 {
 		withEnvArgs = append(withEnvArgs, shell.QuoteAll(c.Command)...)
 	}
 	withEnvCmd := "podman " + strings.Join(withEnvArgs, " ")
 
+	// Plaintext files only exist while Podman reads them at container
+	// creation and are removed when the subshell exits.
+	var temps, steps []string
+	if c.EnvFileIdentity != "" {
+		temps = append(temps, "azud_env")
+		steps = append(steps, fmt.Sprintf(`age -d -i %s %s > "$azud_env"`, shell.QuoteRemotePath(c.EnvFileIdentity), quotedEnvFile))
+	}
+	if len(c.EnvTemplates) > 0 {
+		temps = append(temps, "azud_tmpl")
+		steps = append(steps, fmt.Sprintf(`printf '%%s\n' %s | awk %s - %s > "$azud_tmpl"`,
+			strings.Join(c.envTemplateLines(), " "), shell.Quote(envTemplateProgram), envFileArg))
+	}
+	if len(temps) > 0 {
+		var setup, files []string
+		for _, temp := range temps {
+			setup = append(setup, fmt.Sprintf(`%s=$(mktemp "${XDG_RUNTIME_DIR:-/tmp}/azud-env.XXXXXX")`, temp))
+			files = append(files, fmt.Sprintf(`"$%s"`, temp))
+		}
+		withEnvCmd = fmt.Sprintf(`(umask 077 && %s && trap 'rm -f %s' EXIT && %s && %s)`,
+			strings.Join(setup, " && "), strings.Join(files, " "), strings.Join(steps, " && "), withEnvCmd)
+	}
+
 	if !c.EnvFileOptional {
-		return withEnvCmd
+		return c.withHostDirs(withEnvCmd)
 	}
 
 	// Prefer env-file if present; otherwise run without it.
-	return fmt.Sprintf("if [ -f %s ]; then %s; else %s; fi", quotedEnvFile, withEnvCmd, baseCmd)
+	return c.withHostDirs(fmt.Sprintf("if [ -f %s ]; then %s; else %s; fi", quotedEnvFile, withEnvCmd, baseCmd))
+}
+
+// envTemplateProgram reads the templates (NAME=value lines) from its first
+// input and the env file from its second, then prints each template with
+// its ${NAME} references replaced by the env file's values. A reference the
+// env file does not define fails the run.
+const envTemplateProgram = `NR == FNR { t[++n] = $0; next }
+/^[A-Za-z_][A-Za-z0-9_]*=/ { i = index($0, "="); v[substr($0, 1, i - 1)] = substr($0, i + 1) }
+END {
+	for (k = 1; k <= n; k++) {
+		s = t[k]; out = ""
+		while (match(s, /[$][{][A-Z_][A-Z0-9_]*[}]/)) {
+			name = substr(s, RSTART + 2, RLENGTH - 3)
+			if (!(name in v)) { print "azud: secret " name " is not set in the env file" > "/dev/stderr"; exit 1 }
+			out = out substr(s, 1, RSTART - 1) v[name]; s = substr(s, RSTART + RLENGTH)
+		}
+		print out s
+	}
+}`
+
+// envTemplateLines returns the quoted NAME=value lines of EnvTemplates in
+// name order.
+func (c *ContainerConfig) envTemplateLines() []string {
+	keys := make([]string, 0, len(c.EnvTemplates))
+	for key := range c.EnvTemplates {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	lines := make([]string, len(keys))
+	for i, key := range keys {
+		lines[i] = shell.Quote(key + "=" + c.EnvTemplates[key])
+	}
+	return lines
+}
+
+// withHostDirs prefixes cmd with the creation of HostDirs, since Podman
+// refuses to bind-mount a missing host directory.
+func (c *ContainerConfig) withHostDirs(cmd string) string {
+	if len(c.HostDirs) == 0 {
+		return cmd
+	}
+	dirs := make([]string, len(c.HostDirs))
+	for i, dir := range c.HostDirs {
+		dirs[i] = shell.QuoteRemotePath(dir)
+	}
+	return fmt.Sprintf("mkdir -p %s && %s", strings.Join(dirs, " "), cmd)
+}
+
+// NetworkConfig holds configuration for creating a Podman network.
+type NetworkConfig struct {
+	Name       string
+	Subnet     string
+	Gateway    string
+	Internal   bool
+	IPv6       bool
+	DNS        []string
+	DisableDNS bool
+}
+
+func (c *NetworkConfig) BuildCreateCommand() string {
+	args := []string{"network", "create"}
+	if c.Subnet != "" {
+		args = append(args, "--subnet", shell.Quote(c.Subnet))
+	}
+	if c.Gateway != "" {
+		args = append(args, "--gateway", shell.Quote(c.Gateway))
+	}
+	if c.Internal {
+		args = append(args, "--internal")
+	}
+	if c.IPv6 {
+		args = append(args, "--ipv6")
+	}
+	for _, server := range c.DNS {
+		args = append(args, "--dns", shell.Quote(server))
+	}
+	if c.DisableDNS {
+		args = append(args, "--disable-dns")
+	}
+	args = append(args, shell.Quote(c.Name))
+	return "podman " + strings.Join(args, " ")
 }
 
 // ExecConfig holds configuration for executing a command in a container.
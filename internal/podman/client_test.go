@@ -100,6 +100,46 @@ func TestBuildRunCommand_WithPorts(t *testing.T) {
 	}
 }
 
+func TestBuildRunCommand_WithRuntimeOptions(t *testing.T) {
+	cfg := &ContainerConfig{
+		Image:       "nginx:latest",
+		User:        "1000:1000",
+		Entrypoint:  "/sbin/tini",
+		Init:        true,
+		StopSignal:  "SIGQUIT",
+		StopTimeout: 45,
+	}
+
+	cmd := cfg.BuildRunCommand()
+
+	for _, want := range []string{"--user=1000:1000", "--entrypoint /sbin/tini", "--init", "--stop-signal=SIGQUIT", "--stop-timeout=45"} {
+		if !strings.Contains(cmd, want) {
+			t.Errorf("expected %q in %s", want, cmd)
+		}
+	}
+	if args := (&ContainerConfig{}).RuntimeArgs(); len(args) != 0 {
+		t.Errorf("RuntimeArgs() without options = %v", args)
+	}
+}
+
+func TestBuildRunCommand_WithHardening(t *testing.T) {
+	cfg := &ContainerConfig{
+		Image:        "nginx:latest",
+		ReadOnly:     true,
+		Tmpfs:        []string{"/tmp:size=64m"},
+		CapDrop:      []string{"ALL"},
+		SecurityOpts: []string{"no-new-privileges", "seccomp=/etc/azud/seccomp.json"},
+	}
+
+	cmd := cfg.BuildRunCommand()
+
+	for _, want := range []string{"--read-only", "--tmpfs=/tmp:size=64m", "--cap-drop=ALL", "--security-opt=no-new-privileges", "--security-opt=seccomp=/etc/azud/seccomp.json"} {
+		if !strings.Contains(cmd, want) {
+			t.Errorf("expected %q in %s", want, cmd)
+		}
+	}
+}
+
 func TestBuildRunCommand_WithVolumes(t *testing.T) {
 	cfg := &ContainerConfig{
 		Image:   "nginx:latest",
@@ -113,6 +153,23 @@ func TestBuildRunCommand_WithVolumes(t *testing.T) {
 	}
 }
 
+func TestBuildRunCommand_WithHostDirs(t *testing.T) {
+	cfg := &ContainerConfig{
+		Image:    "nginx:latest",
+		Volumes:  []string{"${HOME}/sockets/web:/run/app:U,z"},
+		HostDirs: []string{"${HOME}/sockets/web"},
+	}
+
+	cmd := cfg.BuildRunCommand()
+
+	if !strings.HasPrefix(cmd, "mkdir -p ${HOME}/sockets/web && podman run ") {
+		t.Errorf("expected host directory to be created before the run, got %q", cmd)
+	}
+	if !strings.Contains(cmd, "-v ${HOME}/'sockets/web:/run/app:U,z'") {
+		t.Errorf("expected socket volume, got %q", cmd)
+	}
+}
+
 func TestBuildRunCommand_WithNetwork(t *testing.T) {
 	cfg := &ContainerConfig{
 		Image:   "nginx:latest",
@@ -126,6 +183,95 @@ func TestBuildRunCommand_WithNetwork(t *testing.T) {
 	}
 }
 
+func TestBuildRunCommand_WithMultipleNetworks(t *testing.T) {
+	cfg := &ContainerConfig{
+		Image:    "nginx:latest",
+		Network:  "azud",
+		Networks: []string{"azud", "backend"},
+	}
+
+	cmd := cfg.BuildRunCommand()
+
+	if strings.Count(cmd, "--network azud") != 1 || !strings.Contains(cmd, "--network backend") {
+		t.Errorf("expected one --network per attached network, got %q", cmd)
+	}
+}
+
+func TestBuildRunCommand_WithSecrets(t *testing.T) {
+	cfg := &ContainerConfig{
+		Image:   "nginx:latest",
+		Secrets: []string{"azud-app-API_KEY,target=API_KEY"},
+	}
+
+	cmd := cfg.BuildRunCommand()
+
+	if !strings.Contains(cmd, "--secret 'azud-app-API_KEY,target=API_KEY'") || strings.Contains(cmd, "--env-file") {
+		t.Errorf("expected --secret without an env file, got %q", cmd)
+	}
+}
+
+func TestBuildRunCommand_WithEncryptedEnvFile(t *testing.T) {
+	cfg := &ContainerConfig{
+		Image:           "nginx:latest",
+		EnvFile:         "$HOME/.azud/secrets.age",
+		EnvFileIdentity: "$HOME/.azud/age-identity",
+		SecretEnv:       []string{"API_KEY"},
+	}
+
+	cmd := cfg.BuildRunCommand()
+
+	for _, want := range []string{
+		`age -d -i ${HOME}/.azud/age-identity ${HOME}/.azud/secrets.age > "$azud_env"`,
+		`--env-file "$azud_env"`,
+		`trap 'rm -f "$azud_env"' EXIT`,
+	} {
+		if !strings.Contains(cmd, want) {
+			t.Errorf("expected %q in %q", want, cmd)
+		}
+	}
+}
+
+func TestBuildRunCommand_WithEnvTemplates(t *testing.T) {
+	cfg := &ContainerConfig{
+		Image:           "nginx:latest",
+		EnvFile:         "$HOME/.azud/secrets.age",
+		EnvFileIdentity: "$HOME/.azud/age-identity",
+		SecretEnv:       []string{"DB_PASSWORD"},
+		EnvTemplates:    map[string]string{"DATABASE_URL": "postgres://app:${DB_PASSWORD}@db/app"},
+	}
+
+	cmd := cfg.BuildRunCommand()
+
+	for _, want := range []string{
+		`trap 'rm -f "$azud_env" "$azud_tmpl"' EXIT`,
+		`printf '%s\n' 'DATABASE_URL=postgres://app:${DB_PASSWORD}@db/app' | awk `,
+		` - "$azud_env" > "$azud_tmpl"`,
+		`--env-file "$azud_env" --env-file "$azud_tmpl" nginx:latest`,
+	} {
+		if !strings.Contains(cmd, want) {
+			t.Errorf("expected %q in %q", want, cmd)
+		}
+	}
+	if strings.Contains(cmd, "-e DATABASE_URL") {
+		t.Errorf("template must not be passed on the command line: %q", cmd)
+	}
+}
+
+func TestNetworkConfigBuildCreateCommand(t *testing.T) {
+	cfg := &NetworkConfig{
+		Name:     "backend",
+		Subnet:   "10.90.0.0/24",
+		Gateway:  "10.90.0.1",
+		Internal: true,
+		DNS:      []string{"1.1.1.1"},
+	}
+
+	want := "podman network create --subnet 10.90.0.0/24 --gateway 10.90.0.1 --internal --dns 1.1.1.1 backend"
+	if got := cfg.BuildCreateCommand(); got != want {
+		t.Errorf("BuildCreateCommand() = %q, want %q", got, want)
+	}
+}
+
 func TestBuildRunCommand_WithRestart(t *testing.T) {
 	cfg := &ContainerConfig{
 		Image:   "nginx:latest",
@@ -156,6 +302,22 @@ func TestBuildRunCommand_WithResources(t *testing.T) {
 	}
 }
 
+func TestBuildRunCommand_WithLogOptions(t *testing.T) {
+	cfg := &ContainerConfig{
+		Image:     "caddy:2",
+		LogDriver: "k8s-file",
+		LogOpts:   []string{"max-size=10m", "tag=proxy"},
+	}
+
+	cmd := cfg.BuildRunCommand()
+
+	for _, want := range []string{"--log-driver=k8s-file", "--log-opt=max-size=10m", "--log-opt=tag=proxy"} {
+		if !strings.Contains(cmd, want) {
+			t.Errorf("expected %q in %q", want, cmd)
+		}
+	}
+}
+
 func TestBuildRunCommand_WithRemove(t *testing.T) {
 	cfg := &ContainerConfig{
 		Image:  "nginx:latest",
@@ -323,3 +485,25 @@ func TestBuildLogsCommand_WithFollow(t *testing.T) {
 		t.Error("expected '--tail 100'")
 	}
 }
+
+func TestPortSpecBracketsIPv6(t *testing.T) {
+	tests := []struct {
+		ip            string
+		hostPort      int
+		containerPort int
+		want          string
+	}{
+		{"", 8080, 80, "8080:80"},
+		{"", 0, 3000, "3000"},
+		{"127.0.0.1", 0, 3000, "127.0.0.1::3000"},
+		{"10.0.0.10", 41000, 3000, "10.0.0.10:41000:3000"},
+		{"::", 80, 80, "[::]:80:80"},
+		{"fd00::10", 0, 3000, "[fd00::10]::3000"},
+		{"[fd00::10]", 41000, 3000, "[fd00::10]:41000:3000"},
+	}
+	for _, tt := range tests {
+		if got := PortSpec(tt.ip, tt.hostPort, tt.containerPort); got != tt.want {
+			t.Errorf("PortSpec(%q, %d, %d) = %q, want %q", tt.ip, tt.hostPort, tt.containerPort, got, tt.want)
+		}
+	}
+}
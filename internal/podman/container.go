@@ -5,6 +5,8 @@ import (
 	"fmt"
 	"io"
 	"net"
+	"slices"
+	"sort"
 	"strconv"
 	"strings"
 	"time"
@@ -33,12 +35,19 @@ func NewContainerManager(client *Client) *ContainerManager {
 }
 
 func (m *ContainerManager) Run(host string, config *ContainerConfig) (string, error) {
+	return m.RunWithProgress(host, config, nil)
+}
+
+// RunWithProgress runs a container like Run and passes each line of output
+// to onLine as it is produced, e.g. the image pull and the output of a
+// foreground command such as a migration.
+func (m *ContainerManager) RunWithProgress(host string, config *ContainerConfig, onLine ssh.LineHandler) (string, error) {
 	if err := ValidateOptions(config.Options); err != nil {
 		return "", err
 	}
 	cmd := config.BuildRunCommand()
 	cmd = m.client.RewriteCommand(cmd)
-	result, err := m.client.ssh.Execute(host, cmd)
+	result, err := m.client.ssh.ExecuteLines(host, cmd, onLine)
 	if err != nil {
 		return "", err
 	}
@@ -50,6 +59,17 @@ func (m *ContainerManager) Run(host string, config *ContainerConfig) (string, er
 	return strings.TrimSpace(result.Stdout), nil
 }
 
+// RunStream runs a foreground container and streams its output. A non-zero
+// exit of the container is returned as the error of the remote command; see
+// ssh.ExitStatus.
+func (m *ContainerManager) RunStream(host string, config *ContainerConfig, stdout, stderr io.Writer) error {
+	if err := ValidateOptions(config.Options); err != nil {
+		return err
+	}
+	cmd := m.client.RewriteCommand(config.BuildRunCommand())
+	return m.client.ssh.ExecuteStream(host, cmd, stdout, stderr)
+}
+
 func (m *ContainerManager) Start(host, container string) error {
 	result, err := m.client.Execute(host, "start", container)
 	if err != nil {
@@ -229,6 +249,88 @@ func (m *ContainerManager) Inspect(host, container string) (string, error) {
 	return result.Stdout, nil
 }
 
+// InspectFormat returns `podman inspect` output rendered with a Go template.
+func (m *ContainerManager) InspectFormat(host, container, format string) (string, error) {
+	result, err := m.client.Execute(host, "inspect", container, "--format", format)
+	if err != nil {
+		return "", err
+	}
+
+	if result.ExitCode != 0 {
+		return "", fmt.Errorf("failed to inspect container: %s", result.Stderr)
+	}
+
+	return result.Stdout, nil
+}
+
+// MountSource returns the host path mounted at destination in container, or
+// "" when nothing is mounted there.
+func (m *ContainerManager) MountSource(host, container, destination string) (string, error) {
+	out, err := m.InspectFormat(host, container, "{{json .Mounts}}")
+	if err != nil {
+		return "", err
+	}
+	var mounts []struct {
+		Source      string `json:"Source"`
+		Destination string `json:"Destination"`
+	}
+	if err := json.Unmarshal([]byte(strings.TrimSpace(out)), &mounts); err != nil {
+		return "", fmt.Errorf("failed to parse mounts of %s: %w", container, err)
+	}
+	for _, mount := range mounts {
+		if mount.Destination == destination {
+			return mount.Source, nil
+		}
+	}
+	return "", nil
+}
+
+// PublishedPorts returns the container ports of container that are
+// published on the host, as port/protocol (e.g. 443/tcp).
+func (m *ContainerManager) PublishedPorts(host, container string) ([]string, error) {
+	out, err := m.InspectFormat(host, container, "{{json .HostConfig.PortBindings}}")
+	if err != nil {
+		return nil, err
+	}
+	var bindings map[string]json.RawMessage
+	if err := json.Unmarshal([]byte(strings.TrimSpace(out)), &bindings); err != nil {
+		return nil, fmt.Errorf("failed to parse port bindings of %s: %w", container, err)
+	}
+	ports := make([]string, 0, len(bindings))
+	for port := range bindings {
+		ports = append(ports, port)
+	}
+	sort.Strings(ports)
+	return ports, nil
+}
+
+// PortBindingIPs returns the sorted host addresses container port (e.g.
+// 80/tcp) is published on. An empty address means every interface.
+func (m *ContainerManager) PortBindingIPs(host, container, port string) ([]string, error) {
+	out, err := m.InspectFormat(host, container, "{{json .HostConfig.PortBindings}}")
+	if err != nil {
+		return nil, err
+	}
+	var bindings map[string][]struct {
+		HostIP string `json:"HostIp"`
+	}
+	if err := json.Unmarshal([]byte(strings.TrimSpace(out)), &bindings); err != nil {
+		return nil, fmt.Errorf("failed to parse port bindings of %s: %w", container, err)
+	}
+	var ips []string
+	for _, binding := range bindings[port] {
+		ip := strings.Trim(binding.HostIP, "[]")
+		if ip == "0.0.0.0" || ip == "::" {
+			ip = ""
+		}
+		if !slices.Contains(ips, ip) {
+			ips = append(ips, ip)
+		}
+	}
+	sort.Strings(ips)
+	return ips, nil
+}
+
 func (m *ContainerManager) Exists(host, container string) (bool, error) {
 	result, err := m.client.Execute(host, "inspect", container, "--format", "{{.Id}}")
 	if err != nil {
@@ -268,12 +370,36 @@ func (m *ContainerManager) WaitHealthy(host, container string, timeout time.Dura
 			return fmt.Errorf("container is unhealthy")
 		}
 
-		time.Sleep(1 * time.Second)
+		if err := m.client.ssh.Sleep(1 * time.Second); err != nil {
+			return err
+		}
 	}
 
 	return fmt.Errorf("timeout waiting for container to become healthy")
 }
 
+// WaitHealthcheck runs the container's healthcheck until it passes or
+// timeout elapses, without waiting for Podman's own schedule.
+func (m *ContainerManager) WaitHealthcheck(host, container string, timeout time.Duration) error {
+	deadline := time.Now().Add(timeout)
+
+	for {
+		result, err := m.client.Execute(host, "healthcheck", "run", container)
+		if err != nil {
+			return err
+		}
+		if result.ExitCode == 0 {
+			return nil
+		}
+		if !time.Now().Add(time.Second).Before(deadline) {
+			return fmt.Errorf("timeout waiting for healthcheck to pass: %s", strings.TrimSpace(result.Stdout+result.Stderr))
+		}
+		if err := m.client.ssh.Sleep(1 * time.Second); err != nil {
+			return err
+		}
+	}
+}
+
 // HasHealthcheck checks whether the container has a Podman HEALTHCHECK defined.
 func (m *ContainerManager) HasHealthcheck(host, container string) (bool, error) {
 	result, err := m.client.Execute(host, "inspect", container, "--format", "{{len .Config.Healthcheck.Test}}")
@@ -294,7 +420,9 @@ func (m *ContainerManager) WaitRunning(host, container string, stabilize time.Du
 	if stabilize <= 0 {
 		stabilize = 5 * time.Second
 	}
-	time.Sleep(stabilize)
+	if err := m.client.ssh.Sleep(stabilize); err != nil {
+		return err
+	}
 
 	running, err := m.IsRunning(host, container)
 	if err != nil {
@@ -385,6 +513,148 @@ func (m *ContainerManager) Stats(host, container string) (string, error) {
 	return strings.Trim(result.Stdout, "'\n"), nil
 }
 
+// ContainerStats is one container's resource usage sample from podman stats,
+// kept in podman's human-readable units.
+type ContainerStats struct {
+	Name       string `json:"name"`
+	CPU        string `json:"cpu"`
+	MemUsage   string `json:"mem_usage"`
+	MemPercent string `json:"mem_percent"`
+	NetIO      string `json:"net_io"`
+	BlockIO    string `json:"block_io"`
+}
+
+// StatsSample takes a single resource usage sample of the given running
+// containers.
+func (m *ContainerManager) StatsSample(host string, containers []string) ([]ContainerStats, error) {
+	if len(containers) == 0 {
+		return nil, nil
+	}
+	args := []string{"stats", "--no-stream", "--format", "{{.Name}}|{{.CPUPerc}}|{{.MemUsage}}|{{.MemPerc}}|{{.NetIO}}|{{.BlockIO}}"}
+	result, err := m.client.Execute(host, append(args, containers...)...)
+	if err != nil {
+		return nil, err
+	}
+
+	if result.ExitCode != 0 {
+		return nil, fmt.Errorf("failed to get stats: %s", result.Stderr)
+	}
+
+	return parseStats(result.Stdout), nil
+}
+
+// EventsStream follows podman events on host, written to stdout as JSON
+// lines, until the command ends or the client's context is canceled.
+// Filters are podman --filter expressions; since bounds replayed history.
+func (m *ContainerManager) EventsStream(host string, filters []string, since string, stdout, stderr io.Writer) error {
+	args := []string{"events", "--format", "json"}
+	for _, filter := range filters {
+		args = append(args, "--filter", filter)
+	}
+	if since != "" {
+		args = append(args, "--since", since)
+	}
+	return m.client.ExecuteStream(host, stdout, stderr, args...)
+}
+
+// Event is one line of `podman events --format json`.
+type Event struct {
+	Time       time.Time
+	Type       string
+	Status     string
+	Name       string
+	Image      string
+	ExitCode   *int
+	Attributes map[string]string
+}
+
+// ParseEvent parses a podman JSON event. Podman 4 writes Time as an RFC 3339
+// string; podman 5 writes time and timeNano as Unix timestamps.
+func ParseEvent(line []byte) (Event, error) {
+	var raw struct {
+		Time       json.RawMessage   `json:"time"`
+		TimeNano   int64             `json:"timeNano"`
+		Type       string            `json:"Type"`
+		Status     string            `json:"Status"`
+		Name       string            `json:"Name"`
+		Image      string            `json:"Image"`
+		ExitCode   *int              `json:"ContainerExitCode"`
+		Attributes map[string]string `json:"Attributes"`
+	}
+	if err := json.Unmarshal(line, &raw); err != nil {
+		return Event{}, fmt.Errorf("invalid podman event: %w", err)
+	}
+
+	event := Event{
+		Type:       raw.Type,
+		Status:     raw.Status,
+		Name:       raw.Name,
+		Image:      raw.Image,
+		ExitCode:   raw.ExitCode,
+		Attributes: raw.Attributes,
+	}
+	var text string
+	var seconds int64
+	switch {
+	case raw.TimeNano > 0:
+		event.Time = time.Unix(0, raw.TimeNano)
+	case json.Unmarshal(raw.Time, &text) == nil:
+		event.Time, _ = time.Parse(time.RFC3339Nano, text)
+	case json.Unmarshal(raw.Time, &seconds) == nil:
+		event.Time = time.Unix(seconds, 0)
+	}
+	return event, nil
+}
+
+// RestartCount returns how often podman has restarted container under its
+// restart policy, and when the container was created.
+func (m *ContainerManager) RestartCount(host, container string) (int, time.Time, error) {
+	result, err := m.client.Execute(host, "inspect", "--format", "{{.RestartCount}}|{{.Created.Unix}}", container)
+	if err != nil {
+		return 0, time.Time{}, err
+	}
+
+	if result.ExitCode != 0 {
+		return 0, time.Time{}, fmt.Errorf("failed to inspect container: %s", result.Stderr)
+	}
+
+	countStr, createdStr, _ := strings.Cut(strings.Trim(strings.TrimSpace(result.Stdout), "'"), "|")
+	count, err := strconv.Atoi(countStr)
+	if err != nil {
+		return 0, time.Time{}, fmt.Errorf("invalid restart count %q for %s", countStr, container)
+	}
+	var created time.Time
+	if seconds, err := strconv.ParseInt(createdStr, 10, 64); err == nil {
+		created = time.Unix(seconds, 0)
+	}
+	return count, created, nil
+}
+
+func parseStats(out string) []ContainerStats {
+	var stats []ContainerStats
+	for _, line := range strings.Split(strings.TrimSpace(out), "\n") {
+		line = strings.Trim(line, "'")
+		if line == "" {
+			continue
+		}
+
+		parts := strings.Split(line, "|")
+		if len(parts) < 6 {
+			continue
+		}
+
+		stats = append(stats, ContainerStats{
+			Name:       parts[0],
+			CPU:        strings.TrimSpace(parts[1]),
+			MemUsage:   strings.TrimSpace(parts[2]),
+			MemPercent: strings.TrimSpace(parts[3]),
+			NetIO:      strings.TrimSpace(parts[4]),
+			BlockIO:    strings.TrimSpace(parts[5]),
+		})
+	}
+	return stats
+}
+
 // HostPort resolves the published host port for containerPort/tcp.
 func (m *ContainerManager) HostPort(host, container string, containerPort int) (int, error) {
 	if containerPort <= 0 {
@@ -1,6 +1,9 @@
 package podman
 
-import "testing"
+import (
+	"testing"
+	"time"
+)
 
 func TestParseHostPort(t *testing.T) {
 	tests := []struct {
@@ -62,3 +65,54 @@ func TestParseHostPort(t *testing.T) {
 		})
 	}
 }
+
+func TestParseStats(t *testing.T) {
+	out := "shop-web|2.31%|48.2MB / 2.06GB|2.34%|1.2kB / 3.4kB|0B / 8.19kB\n" +
+		"'shop-worker|0.00%|12MB / 2.06GB|0.58%|0B / 0B|0B / 0B'\n" +
+		"garbage\n"
+
+	stats := parseStats(out)
+	if len(stats) != 2 {
+		t.Fatalf("expected 2 samples, got %d: %+v", len(stats), stats)
+	}
+	want := ContainerStats{Name: "shop-web", CPU: "2.31%", MemUsage: "48.2MB / 2.06GB", MemPercent: "2.34%", NetIO: "1.2kB / 3.4kB", BlockIO: "0B / 8.19kB"}
+	if stats[0] != want {
+		t.Fatalf("unexpected sample: want %+v got %+v", want, stats[0])
+	}
+	if stats[1].Name != "shop-worker" || stats[1].BlockIO != "0B / 0B" {
+		t.Fatalf("unexpected quoted sample: %+v", stats[1])
+	}
+}
+
+func TestParseEvent(t *testing.T) {
+	podman4 := `{"ID":"8c3f","Image":"ghcr.io/acme/shop:v2","Name":"shop","Status":"died","Time":"2026-01-02T03:04:05.5+01:00","Type":"container","ContainerExitCode":137,"Attributes":{"azud.role":"web"}}`
+	event, err := ParseEvent([]byte(podman4))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if event.Name != "shop" || event.Status != "died" || event.Type != "container" || event.Image != "ghcr.io/acme/shop:v2" {
+		t.Fatalf("unexpected event: %+v", event)
+	}
+	if event.ExitCode == nil || *event.ExitCode != 137 {
+		t.Fatalf("unexpected exit code: %v", event.ExitCode)
+	}
+	if want := time.Date(2026, 1, 2, 2, 4, 5, 500000000, time.UTC); !event.Time.Equal(want) {
+		t.Fatalf("unexpected time: want %s got %s", want, event.Time)
+	}
+	if event.Attributes["azud.role"] != "web" {
+		t.Fatalf("unexpected attributes: %v", event.Attributes)
+	}
+
+	podman5 := `{"ID":"8c3f","Name":"shop","Status":"start","time":1767319445,"timeNano":1767319445000000123,"Type":"container"}`
+	event, err = ParseEvent([]byte(podman5))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if event.Time.UnixNano() != 1767319445000000123 || event.ExitCode != nil {
+		t.Fatalf("unexpected podman 5 event: %+v", event)
+	}
+
+	if _, err := ParseEvent([]byte("not json")); err == nil {
+		t.Fatal("expected error for invalid event")
+	}
+}
@@ -1,12 +1,17 @@
 package podman
 
 import (
+	"bytes"
+	"errors"
 	"fmt"
+	"io"
 	"sort"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/lemonity-org/azud/internal/shell"
+	"github.com/lemonity-org/azud/internal/ssh"
 )
 
 type Image struct {
@@ -27,33 +32,155 @@ func NewImageManager(client *Client) *ImageManager {
 }
 
 func (m *ImageManager) Pull(host, image string) error {
+	return m.PullWithProgress(host, image, nil)
+}
+
+// PullWithProgress pulls image on host and passes each line Podman reports,
+// such as the blobs it copies, to onLine as it is produced.
+func (m *ImageManager) PullWithProgress(host, image string, onLine ssh.LineHandler) error {
 	image = QualifyImage(image)
-	result, err := m.client.Execute(host, "pull", image)
-	if err != nil {
-		return err
-	}
+	// Pulls are idempotent, so registry hiccups are retried like dropped
+	// connections.
+	return m.client.ssh.Retry(isTransientPull, func() error {
+		result, err := m.client.ExecuteLines(host, onLine, "pull", image)
+		if err != nil {
+			return err
+		}
 
-	if result.ExitCode != 0 {
-		return fmt.Errorf("failed to pull image: %s", result.Stderr)
-	}
+		if result.ExitCode != 0 {
+			return &pullError{stderr: result.Stderr}
+		}
 
-	return nil
+		return nil
+	})
 }
 
-func (m *ImageManager) PullAll(hosts []string, image string) map[string]error {
-	image = QualifyImage(image)
-	results := m.client.ExecuteAll(hosts, "pull", image)
-	errors := make(map[string]error)
+// pullError is a pull Podman reported as failed.
+type pullError struct {
+	stderr string
+}
 
-	for _, result := range results {
-		if !result.Success() {
-			errors[result.Host] = fmt.Errorf("pull failed: %s", result.Stderr)
+func (e *pullError) Error() string {
+	return fmt.Sprintf("failed to pull image: %s", e.stderr)
+}
+
+// transientPullMessages are the Podman pull errors of overloaded registries
+// and interrupted transfers, which may pass when the pull is repeated.
+var transientPullMessages = []string{
+	"429 too many requests",
+	"500 internal server error",
+	"502 bad gateway",
+	"503 service unavailable",
+	"504 gateway timeout",
+	"too many requests",
+	"toomanyrequests",
+	"connection reset by peer",
+	"connection refused",
+	"i/o timeout",
+	"tls handshake timeout",
+	"unexpected eof",
+	"temporary failure in name resolution",
+	"server misbehaving",
+}
+
+// isTransientPull reports whether a failed pull may pass when retried.
+// Missing images and rejected credentials are final.
+func isTransientPull(err error) bool {
+	var pullErr *pullError
+	if !errors.As(err, &pullErr) {
+		return ssh.IsTransient(err)
+	}
+	stderr := strings.ToLower(pullErr.stderr)
+	for _, permanent := range []string{"unauthorized", "authentication required", "denied", "manifest unknown", "not found"} {
+		if strings.Contains(stderr, permanent) {
+			return false
 		}
 	}
+	for _, message := range transientPullMessages {
+		if strings.Contains(stderr, message) {
+			return true
+		}
+	}
+	return false
+}
 
+func (m *ImageManager) PullAll(hosts []string, image string) map[string]error {
+	_, errors := m.PullAllWithProgress(hosts, image, 0, nil)
 	return errors
 }
 
+// PullAllWithProgress pulls image on hosts in parallel, no more than limit at
+// once when limit is positive, and returns how long each host's pull took.
+// When progress is set, each host's Podman output goes to the handler it
+// returns for that host.
+func (m *ImageManager) PullAllWithProgress(hosts []string, image string, limit int, progress func(host string) ssh.LineHandler) (map[string]time.Duration, map[string]error) {
+	if limit <= 0 || limit > len(hosts) {
+		limit = len(hosts)
+	}
+	durations := make(map[string]time.Duration, len(hosts))
+	errors := make(map[string]error)
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	slots := make(chan struct{}, max(limit, 1))
+
+	for _, host := range hosts {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			slots <- struct{}{}
+			defer func() { <-slots }()
+
+			var onLine ssh.LineHandler
+			if progress != nil {
+				onLine = progress(host)
+			}
+			started := time.Now()
+			err := m.PullWithProgress(host, image, onLine)
+
+			mu.Lock()
+			defer mu.Unlock()
+			durations[host] = time.Since(started)
+			if err != nil {
+				errors[host] = err
+			}
+		}()
+	}
+
+	wg.Wait()
+	return durations, errors
+}
+
+// PullEvent is a step of a pull parsed from Podman's output.
+type PullEvent struct {
+	// Blob is the digest of the layer being copied; empty for the manifest.
+	Blob string
+	// Cached is set when the layer is already on the host.
+	Cached bool
+	// Done is set once the manifest is written, which ends the pull.
+	Done bool
+}
+
+// ParsePullLine parses a line of `podman pull` output. Lines other than
+// blob copies and the final manifest write are ignored.
+func ParsePullLine(line string) (PullEvent, bool) {
+	line = strings.TrimSpace(line)
+	if strings.HasPrefix(line, "Writing manifest") {
+		return PullEvent{Done: true}, true
+	}
+	rest, ok := strings.CutPrefix(line, "Copying blob ")
+	if !ok {
+		return PullEvent{}, false
+	}
+	fields := strings.Fields(rest)
+	if len(fields) == 0 {
+		return PullEvent{}, false
+	}
+	return PullEvent{
+		Blob:   fields[0],
+		Cached: strings.Contains(rest, "skipped") || strings.Contains(rest, "already exists"),
+	}, true
+}
+
 func (m *ImageManager) Push(host, image string) error {
 	result, err := m.client.Execute(host, "push", image)
 	if err != nil {
@@ -87,6 +214,7 @@ type BuildConfig struct {
 	Tag        string
 	Tags       []string
 	Args       map[string]string
+	Labels     map[string]string
 	Target     string
 	CacheFrom  []string
 	CacheTo    string
@@ -123,6 +251,7 @@ func (c *BuildConfig) BuildCommand() string {
 		value := c.Args[key]
 		args = append(args, "--build-arg", shell.Quote(fmt.Sprintf("%s=%s", key, value)))
 	}
+	args = append(args, labelArgs(c.Labels)...)
 
 	if c.Target != "" {
 		args = append(args, "--target", shell.Quote(c.Target))
@@ -166,9 +295,29 @@ func (c *BuildConfig) BuildCommand() string {
 	return "podman " + strings.Join(args, " ")
 }
 
+// labelArgs returns the --label flags of labels, sorted by key.
+func labelArgs(labels map[string]string) []string {
+	keys := make([]string, 0, len(labels))
+	for key := range labels {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	var args []string
+	for _, key := range keys {
+		args = append(args, "--label", shell.Quote(key+"="+labels[key]))
+	}
+	return args
+}
+
 func (m *ImageManager) Build(host string, config *BuildConfig) error {
+	return m.BuildWithProgress(host, config, nil)
+}
+
+// BuildWithProgress builds an image on host and passes each line of the
+// build output to onLine as it is produced.
+func (m *ImageManager) BuildWithProgress(host string, config *BuildConfig, onLine ssh.LineHandler) error {
 	cmd := config.BuildCommand()
-	result, err := m.client.ssh.Execute(host, cmd)
+	result, err := m.client.ssh.ExecuteLines(host, cmd, onLine)
 	if err != nil {
 		return err
 	}
@@ -183,10 +332,20 @@ func (m *ImageManager) Build(host string, config *BuildConfig) error {
 // ManifestBuildConfig holds configuration for multi-arch manifest builds.
 type ManifestBuildConfig struct {
 	BuildConfig
-	Platforms []string // e.g., ["linux/amd64", "linux/arm64"]
-	Push      bool
-	CacheTo   string
-	Output    string
+	Platforms   []string // e.g., ["linux/amd64", "linux/arm64"]
+	Push        bool
+	Compression string // layer compression for the push, e.g. zstd:chunked
+	CacheTo     string
+	Output      string
+}
+
+// CompressionArgs returns the push flags that compress layers with format,
+// recompressing layers stored in another format; none when format is empty.
+func CompressionArgs(format string) []string {
+	if format == "" {
+		return nil
+	}
+	return []string{"--compression-format", format}
 }
 
 // ManifestBuildCommands generates manifest create, per-platform builds,
@@ -218,6 +377,7 @@ func (c *ManifestBuildConfig) ManifestBuildCommands() []string {
 		for key, value := range c.Args {
 			args = append(args, "--build-arg", shell.Quote(fmt.Sprintf("%s=%s", key, value)))
 		}
+		args = append(args, labelArgs(c.Labels)...)
 
 		if c.Target != "" {
 			args = append(args, "--target", shell.Quote(c.Target))
@@ -258,7 +418,11 @@ func (c *ManifestBuildConfig) ManifestBuildCommands() []string {
 	}
 
 	if c.Push {
-		commands = append(commands, fmt.Sprintf("podman manifest push %s %s", shell.Quote(tag), shell.Quote(tag)))
+		push := "podman manifest push "
+		if args := CompressionArgs(c.Compression); len(args) > 0 {
+			push += strings.Join(shell.QuoteAll(args), " ") + " "
+		}
+		commands = append(commands, fmt.Sprintf("%s%s %s", push, shell.Quote(tag), shell.Quote(tag)))
 		seen := map[string]struct{}{tag: {}}
 		for _, t := range c.Tags {
 			if t == "" {
@@ -268,7 +432,7 @@ func (c *ManifestBuildConfig) ManifestBuildCommands() []string {
 				continue
 			}
 			seen[t] = struct{}{}
-			commands = append(commands, fmt.Sprintf("podman manifest push %s %s", shell.Quote(tag), shell.Quote(t)))
+			commands = append(commands, fmt.Sprintf("%s%s %s", push, shell.Quote(tag), shell.Quote(t)))
 		}
 	}
 
@@ -276,10 +440,16 @@ func (c *ManifestBuildConfig) ManifestBuildCommands() []string {
 }
 
 func (m *ImageManager) ManifestBuild(host string, config *ManifestBuildConfig) error {
+	return m.ManifestBuildWithProgress(host, config, nil)
+}
+
+// ManifestBuildWithProgress runs a manifest build on host and passes each
+// line of the build output to onLine as it is produced.
+func (m *ImageManager) ManifestBuildWithProgress(host string, config *ManifestBuildConfig, onLine ssh.LineHandler) error {
 	commands := config.ManifestBuildCommands()
 
 	for _, cmd := range commands {
-		result, err := m.client.ssh.Execute(host, cmd)
+		result, err := m.client.ssh.ExecuteLines(host, cmd, onLine)
 		if err != nil {
 			return err
 		}
@@ -292,6 +462,44 @@ func (m *ImageManager) ManifestBuild(host string, config *ManifestBuildConfig) e
 	return nil
 }
 
+// ManifestAssembleCommands returns the commands that combine per-platform
+// images already pushed to a registry into a manifest list named tag and
+// push it as tag and each of tags.
+func ManifestAssembleCommands(tag string, images, tags []string) []string {
+	commands := []string{
+		fmt.Sprintf("podman manifest rm %s >/dev/null 2>&1 || true", shell.Quote(tag)),
+		fmt.Sprintf("podman manifest create %s", shell.Quote(tag)),
+	}
+	for _, image := range images {
+		commands = append(commands, fmt.Sprintf("podman manifest add %s %s", shell.Quote(tag), shell.Quote("docker://"+image)))
+	}
+	seen := make(map[string]struct{}, len(tags)+1)
+	for _, t := range append([]string{tag}, tags...) {
+		if _, ok := seen[t]; ok || t == "" {
+			continue
+		}
+		seen[t] = struct{}{}
+		commands = append(commands, fmt.Sprintf("podman manifest push %s %s", shell.Quote(tag), shell.Quote("docker://"+t)))
+	}
+	return commands
+}
+
+// ManifestAssemble runs ManifestAssembleCommands on host.
+func (m *ImageManager) ManifestAssemble(host, tag string, images, tags []string) error {
+	for _, cmd := range ManifestAssembleCommands(tag, images, tags) {
+		result, err := m.client.ssh.Execute(host, cmd)
+		if err != nil {
+			return err
+		}
+
+		if result.ExitCode != 0 {
+			return fmt.Errorf("failed to assemble manifest list: %s", result.Stderr)
+		}
+	}
+
+	return nil
+}
+
 func (m *ImageManager) List(host string, filters map[string]string) ([]Image, error) {
 	args := []string{"images", "--format", "{{.ID}}|{{.Repository}}|{{.Tag}}|{{.Size}}|{{.CreatedAt}}"}
 
@@ -384,6 +592,58 @@ func (m *ImageManager) Exists(host, image string) (bool, error) {
 	return result.ExitCode == 0, nil
 }
 
+// ID returns the ID of image on host, the digest of its configuration,
+// which names the same content wherever the image was pulled or loaded.
+func (m *ImageManager) ID(host, image string) (string, error) {
+	result, err := m.client.Execute(host, "image", "inspect", image, "--format", "{{.Id}}")
+	if err != nil {
+		return "", err
+	}
+	if result.ExitCode != 0 {
+		return "", fmt.Errorf("failed to inspect image: %s", strings.TrimSpace(result.Stderr))
+	}
+	return strings.TrimSpace(result.Stdout), nil
+}
+
+// Transfer copies image from host src to host dst by streaming `podman
+// save` on src into `podman load` on dst over the SSH connections, so the
+// hosts need no access to each other or to the registry. The loaded image
+// keeps its tags but not its registry digest.
+func (m *ImageManager) Transfer(src, dst, image string) error {
+	reader, writer := io.Pipe()
+	var saveStderr, loadStderr bytes.Buffer
+	saved := make(chan error, 1)
+	go func() {
+		err := m.client.ExecuteStream(src, writer, &saveStderr, "save", QualifyImage(image))
+		_ = writer.CloseWithError(err)
+		saved <- err
+	}()
+
+	loadErr := m.client.ssh.ExecuteIO(dst, m.client.command+" load", reader, io.Discard, &loadStderr, false)
+	// Unblock the save when the load ends early.
+	_ = reader.Close()
+	saveErr := <-saved
+
+	// A failed save truncates the load's input, and a failed load closes
+	// the save's output, so the side that reported a reason is the cause.
+	if saveErr != nil && (loadErr == nil || saveStderr.Len() > 0) {
+		return fmt.Errorf("failed to save image on %s: %s", src, commandError(saveErr, &saveStderr))
+	}
+	if loadErr != nil {
+		return fmt.Errorf("failed to load image on %s: %s", dst, commandError(loadErr, &loadStderr))
+	}
+	return nil
+}
+
+// commandError describes a failed streamed command by its stderr, or by
+// the error when it wrote none.
+func commandError(err error, stderr *bytes.Buffer) string {
+	if message := strings.TrimSpace(stderr.String()); message != "" {
+		return message
+	}
+	return err.Error()
+}
+
 func (m *ImageManager) GetDigest(host, image string) (string, error) {
 	result, err := m.client.Execute(host, "image", "inspect", image, "--format", "{{index .RepoDigests 0}}")
 	if err != nil {
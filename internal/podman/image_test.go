@@ -104,6 +104,23 @@ func TestBuildCommand_WithTarget(t *testing.T) {
 	}
 }
 
+func TestBuildCommand_WithLabels(t *testing.T) {
+	cfg := &BuildConfig{
+		Context: ".",
+		Labels: map[string]string{
+			"org.opencontainers.image.revision": "0123abcd",
+			"org.opencontainers.image.created":  "2026-03-04T05:06:07Z",
+		},
+	}
+
+	cmd := cfg.BuildCommand()
+
+	want := "--label 'org.opencontainers.image.created=2026-03-04T05:06:07Z' --label 'org.opencontainers.image.revision=0123abcd'"
+	if !strings.Contains(cmd, want) {
+		t.Errorf("expected sorted labels %q, got: %s", want, cmd)
+	}
+}
+
 func TestBuildCommand_ContextLast(t *testing.T) {
 	cfg := &BuildConfig{
 		Context: "/my/context",
@@ -198,6 +215,49 @@ func TestManifestBuildCommands_WithPush(t *testing.T) {
 	}
 }
 
+func TestManifestBuildCommands_WithCompression(t *testing.T) {
+	cfg := &ManifestBuildConfig{
+		BuildConfig: BuildConfig{
+			Context: ".",
+			Tag:     "myapp:v1",
+			Tags:    []string{"myapp:latest"},
+		},
+		Platforms:   []string{"linux/amd64"},
+		Push:        true,
+		Compression: "zstd:chunked",
+	}
+
+	cmds := cfg.ManifestBuildCommands()
+
+	want := []string{
+		"podman manifest push --compression-format zstd:chunked myapp:v1 myapp:v1",
+		"podman manifest push --compression-format zstd:chunked myapp:v1 myapp:latest",
+	}
+	if got := strings.Join(cmds[len(cmds)-2:], "\n"); got != strings.Join(want, "\n") {
+		t.Errorf("push commands =\n%s\nwant\n%s", got, strings.Join(want, "\n"))
+	}
+}
+
+func TestManifestAssembleCommands(t *testing.T) {
+	cmds := ManifestAssembleCommands(
+		"ghcr.io/acme/app:abc123",
+		[]string{"ghcr.io/acme/app:abc123-amd64", "ghcr.io/acme/app:abc123-arm64"},
+		[]string{"ghcr.io/acme/app:latest", "ghcr.io/acme/app:abc123"},
+	)
+
+	want := []string{
+		"podman manifest rm ghcr.io/acme/app:abc123 >/dev/null 2>&1 || true",
+		"podman manifest create ghcr.io/acme/app:abc123",
+		"podman manifest add ghcr.io/acme/app:abc123 docker://ghcr.io/acme/app:abc123-amd64",
+		"podman manifest add ghcr.io/acme/app:abc123 docker://ghcr.io/acme/app:abc123-arm64",
+		"podman manifest push ghcr.io/acme/app:abc123 docker://ghcr.io/acme/app:abc123",
+		"podman manifest push ghcr.io/acme/app:abc123 docker://ghcr.io/acme/app:latest",
+	}
+	if strings.Join(cmds, "\n") != strings.Join(want, "\n") {
+		t.Errorf("ManifestAssembleCommands() =\n%s\nwant\n%s", strings.Join(cmds, "\n"), strings.Join(want, "\n"))
+	}
+}
+
 func TestManifestBuildCommands_NoPush(t *testing.T) {
 	cfg := &ManifestBuildConfig{
 		BuildConfig: BuildConfig{
@@ -285,3 +345,45 @@ func TestManifestBuildCommands_WithDockerfile(t *testing.T) {
 		t.Errorf("expected dockerfile flag in build command, got: %s", cmds[1])
 	}
 }
+
+func TestIsTransientPull(t *testing.T) {
+	tests := []struct {
+		stderr string
+		want   bool
+	}{
+		{stderr: "Error: initializing source docker://ghcr.io/acme/app:v1: received unexpected HTTP status: 503 Service Unavailable", want: true},
+		{stderr: "Error: copying blob sha256:abc: read tcp 10.0.0.1:40122->140.82.112.33:443: read: connection reset by peer", want: true},
+		{stderr: "Error: reading manifest v1 in ghcr.io/acme/app: toomanyrequests: retry later", want: true},
+		{stderr: "Error: reading manifest v9 in ghcr.io/acme/app: manifest unknown", want: false},
+		{stderr: "Error: initializing source docker://ghcr.io/acme/app:v1: unauthorized: authentication required", want: false},
+		{stderr: "Error: something unexpected", want: false},
+	}
+	for _, tt := range tests {
+		if got := isTransientPull(&pullError{stderr: tt.stderr}); got != tt.want {
+			t.Errorf("isTransientPull(%q) = %v, want %v", tt.stderr, got, tt.want)
+		}
+	}
+}
+
+func TestParsePullLine(t *testing.T) {
+	tests := []struct {
+		line string
+		want PullEvent
+		ok   bool
+	}{
+		{"Trying to pull ghcr.io/acme/app:v1...", PullEvent{}, false},
+		{"Getting image source signatures", PullEvent{}, false},
+		{"Copying blob sha256:59bf1c3509f3", PullEvent{Blob: "sha256:59bf1c3509f3"}, true},
+		{"Copying blob 59bf1c3509f3 done   | ", PullEvent{Blob: "59bf1c3509f3"}, true},
+		{"Copying blob 59bf1c3509f3 skipped: already exists", PullEvent{Blob: "59bf1c3509f3", Cached: true}, true},
+		{"Copying config sha256:8ca4688f4f35", PullEvent{}, false},
+		{"Writing manifest to image destination", PullEvent{Done: true}, true},
+		{"Copying blob ", PullEvent{}, false},
+	}
+	for _, tc := range tests {
+		got, ok := ParsePullLine(tc.line)
+		if ok != tc.ok || got != tc.want {
+			t.Errorf("ParsePullLine(%q) = %+v, %v; want %+v, %v", tc.line, got, ok, tc.want, tc.ok)
+		}
+	}
+}
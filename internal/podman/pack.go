@@ -0,0 +1,91 @@
+package podman
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/lemonity-org/azud/internal/shell"
+)
+
+// PackBuildConfig holds configuration for building an image with Cloud
+// Native Buildpacks through the pack CLI. The image is written to the
+// container engine pack talks to (DOCKER_HOST, e.g. the Podman socket), so it
+// can be tagged and pushed like a Dockerfile build.
+type PackBuildConfig struct {
+	Context    string
+	Tag        string
+	Tags       []string
+	Builder    string
+	Buildpacks []string
+	Env        map[string]string
+	Platform   string
+	NoCache    bool
+	Pull       bool
+}
+
+// PackBuildCommand returns the shell command that runs pack build.
+func (c *PackBuildConfig) PackBuildCommand() string {
+	args := []string{"build"}
+
+	tag := c.Tag
+	if tag == "" {
+		tag = "localhost/build:latest"
+	}
+	args = append(args, shell.Quote(tag))
+
+	for _, t := range c.Tags {
+		args = append(args, "--tag", shell.Quote(t))
+	}
+
+	if c.Builder != "" {
+		args = append(args, "--builder", shell.Quote(c.Builder))
+	}
+
+	for _, buildpack := range c.Buildpacks {
+		args = append(args, "--buildpack", shell.Quote(buildpack))
+	}
+
+	envKeys := make([]string, 0, len(c.Env))
+	for key := range c.Env {
+		envKeys = append(envKeys, key)
+	}
+	sort.Strings(envKeys)
+	for _, key := range envKeys {
+		args = append(args, "--env", shell.Quote(fmt.Sprintf("%s=%s", key, c.Env[key])))
+	}
+
+	if c.Platform != "" {
+		args = append(args, "--platform", shell.Quote(c.Platform))
+	}
+
+	if c.NoCache {
+		args = append(args, "--clear-cache")
+	}
+
+	if c.Pull {
+		args = append(args, "--pull-policy", "always")
+	}
+
+	context := c.Context
+	if context == "" {
+		context = "."
+	}
+	args = append(args, "--path", shell.Quote(context))
+
+	return "pack " + strings.Join(args, " ")
+}
+
+// PackBuild runs pack build on host.
+func (m *ImageManager) PackBuild(host string, config *PackBuildConfig) error {
+	result, err := m.client.ssh.Execute(host, config.PackBuildCommand())
+	if err != nil {
+		return err
+	}
+
+	if result.ExitCode != 0 {
+		return fmt.Errorf("failed to build image with pack: %s", result.Stderr)
+	}
+
+	return nil
+}
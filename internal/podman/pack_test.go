@@ -0,0 +1,51 @@
+package podman
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestPackBuildCommand_Basic(t *testing.T) {
+	cfg := &PackBuildConfig{
+		Context: "/tmp/app",
+		Tag:     "ghcr.io/acme/app:abc123",
+		Tags:    []string{"ghcr.io/acme/app:latest"},
+		Builder: "paketobuildpacks/builder-jammy-base",
+	}
+
+	cmd := cfg.PackBuildCommand()
+
+	want := "pack build ghcr.io/acme/app:abc123 --tag ghcr.io/acme/app:latest --builder paketobuildpacks/builder-jammy-base --path /tmp/app"
+	if cmd != want {
+		t.Errorf("PackBuildCommand() =\n  %s\nwant\n  %s", cmd, want)
+	}
+}
+
+func TestPackBuildCommand_Options(t *testing.T) {
+	cfg := &PackBuildConfig{
+		Tag:        "app:1",
+		Builder:    "heroku/builder:24",
+		Buildpacks: []string{"heroku/nodejs", "heroku/procfile"},
+		Env:        map[string]string{"NODE_ENV": "production", "BP_NODE_VERSION": "20 lts"},
+		Platform:   "linux/arm64",
+		NoCache:    true,
+		Pull:       true,
+	}
+
+	cmd := cfg.PackBuildCommand()
+
+	for _, want := range []string{
+		"--buildpack heroku/nodejs --buildpack heroku/procfile",
+		"--env 'BP_NODE_VERSION=20 lts' --env 'NODE_ENV=production'",
+		"--platform linux/arm64",
+		"--clear-cache",
+		"--pull-policy always",
+	} {
+		if !strings.Contains(cmd, want) {
+			t.Errorf("expected %q in command: %s", want, cmd)
+		}
+	}
+	if !strings.HasSuffix(cmd, "--path .") {
+		t.Errorf("expected default context last, got: %s", cmd)
+	}
+}
@@ -81,6 +81,28 @@ func (m *RegistryManager) LoginAll(hosts []string, config *RegistryConfig) map[s
 	return errors
 }
 
+// LoginHosts logs each host into its own set of registries, so hosts running
+// images from different registries only receive the credentials they need.
+// A host's logins stop at its first failure, which is reported for the host.
+func (m *RegistryManager) LoginHosts(logins map[string][]*RegistryConfig) map[string]error {
+	errors := make(map[string]error)
+
+	for host, configs := range logins {
+		for _, config := range configs {
+			if err := m.Login(host, config); err != nil {
+				server := config.Server
+				if server == "" {
+					server = "docker.io"
+				}
+				errors[host] = fmt.Errorf("%s: %w", server, err)
+				break
+			}
+		}
+	}
+
+	return errors
+}
+
 func (m *RegistryManager) Logout(host, server string) error {
 	if server == "" {
 		server = "docker.io"
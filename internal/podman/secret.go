@@ -0,0 +1,60 @@
+package podman
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/lemonity-org/azud/internal/shell"
+)
+
+// SecretManager handles Podman secret operations.
+type SecretManager struct {
+	client *Client
+}
+
+func NewSecretManager(client *Client) *SecretManager {
+	return &SecretManager{client: client}
+}
+
+// List returns the names of the secrets stored on host.
+func (m *SecretManager) List(host string) ([]string, error) {
+	result, err := m.client.Execute(host, "secret", "ls", "--format", "{{.Name}}")
+	if err != nil {
+		return nil, err
+	}
+
+	if result.ExitCode != 0 {
+		return nil, fmt.Errorf("failed to list secrets: %s", result.Stderr)
+	}
+
+	return strings.Fields(result.Stdout), nil
+}
+
+// Create stores value as secret name, replacing any existing secret of that
+// name. The value is sent on stdin so it never appears in the process list.
+func (m *SecretManager) Create(host, name, value string) error {
+	cmd := fmt.Sprintf("%s secret create --replace %s -", m.client.command, shell.Quote(name))
+	result, err := m.client.ssh.ExecuteWithStdin(host, cmd, strings.NewReader(value))
+	if err != nil {
+		return err
+	}
+
+	if result.ExitCode != 0 {
+		return fmt.Errorf("failed to create secret %s: %s", name, result.Stderr)
+	}
+
+	return nil
+}
+
+func (m *SecretManager) Remove(host, name string) error {
+	result, err := m.client.Execute(host, "secret", "rm", name)
+	if err != nil {
+		return err
+	}
+
+	if result.ExitCode != 0 {
+		return fmt.Errorf("failed to remove secret %s: %s", name, result.Stderr)
+	}
+
+	return nil
+}
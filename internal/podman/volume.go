@@ -0,0 +1,133 @@
+package podman
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// Volume is a Podman named volume as reported by `podman volume ls`.
+type Volume struct {
+	Name       string `json:"Name"`
+	Driver     string `json:"Driver"`
+	Mountpoint string `json:"Mountpoint"`
+}
+
+// VolumeManager handles named volume operations via Podman.
+type VolumeManager struct {
+	client *Client
+}
+
+func NewVolumeManager(client *Client) *VolumeManager {
+	return &VolumeManager{client: client}
+}
+
+func (m *VolumeManager) List(host string) ([]Volume, error) {
+	result, err := m.client.Execute(host, "volume", "ls", "--format", "json")
+	if err != nil {
+		return nil, err
+	}
+
+	if result.ExitCode != 0 {
+		return nil, fmt.Errorf("failed to list volumes: %s", result.Stderr)
+	}
+
+	output := strings.TrimSpace(result.Stdout)
+	if output == "" {
+		return nil, nil
+	}
+	var volumes []Volume
+	if err := json.Unmarshal([]byte(output), &volumes); err != nil {
+		return nil, fmt.Errorf("failed to parse volume list: %w", err)
+	}
+	return volumes, nil
+}
+
+// Inspect returns the raw `podman volume inspect` JSON for a volume.
+func (m *VolumeManager) Inspect(host, name string) (string, error) {
+	result, err := m.client.Execute(host, "volume", "inspect", name)
+	if err != nil {
+		return "", err
+	}
+
+	if result.ExitCode != 0 {
+		return "", fmt.Errorf("failed to inspect volume: %s", result.Stderr)
+	}
+
+	return result.Stdout, nil
+}
+
+func (m *VolumeManager) Exists(host, name string) (bool, error) {
+	result, err := m.client.Execute(host, "volume", "exists", name)
+	if err != nil {
+		return false, err
+	}
+	return result.ExitCode == 0, nil
+}
+
+func (m *VolumeManager) Create(host, name string) error {
+	result, err := m.client.Execute(host, "volume", "create", "--ignore", name)
+	if err != nil {
+		return err
+	}
+
+	if result.ExitCode != 0 {
+		return fmt.Errorf("failed to create volume: %s", result.Stderr)
+	}
+
+	return nil
+}
+
+// Export writes the volume contents to a tarball at path on the host.
+func (m *VolumeManager) Export(host, name, path string) error {
+	result, err := m.client.Execute(host, "volume", "export", "--output", path, name)
+	if err != nil {
+		return err
+	}
+
+	if result.ExitCode != 0 {
+		return fmt.Errorf("failed to export volume: %s", result.Stderr)
+	}
+
+	return nil
+}
+
+// Import extracts a tarball at path on the host into the volume, creating
+// the volume first if needed. Existing files are overwritten but files not
+// present in the tarball are kept.
+func (m *VolumeManager) Import(host, name, path string) error {
+	if err := m.Create(host, name); err != nil {
+		return err
+	}
+
+	result, err := m.client.Execute(host, "volume", "import", name, path)
+	if err != nil {
+		return err
+	}
+
+	if result.ExitCode != 0 {
+		return fmt.Errorf("failed to import volume: %s", result.Stderr)
+	}
+
+	return nil
+}
+
+// UsedBy returns the names of running containers that mount the volume.
+func (m *VolumeManager) UsedBy(host, name string) ([]string, error) {
+	result, err := m.client.Execute(host, "ps", "--filter", "volume="+name, "--format", "{{.Names}}")
+	if err != nil {
+		return nil, err
+	}
+
+	if result.ExitCode != 0 {
+		return nil, fmt.Errorf("failed to list containers: %s", result.Stderr)
+	}
+
+	var names []string
+	for _, line := range strings.Split(result.Stdout, "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			names = append(names, line)
+		}
+	}
+	return names, nil
+}
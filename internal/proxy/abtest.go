@@ -0,0 +1,137 @@
+package proxy
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// azudABRouteIDPrefix marks the routes that send A/B test requests to an
+// alternate upstream. They are never treated as the owner of a host.
+const azudABRouteIDPrefix = "azud-ab-"
+
+// ABTest sends requests that carry a header or cookie value, or come from
+// some countries, to an alternate upstream, ahead of the service's weighted
+// route.
+type ABTest struct {
+	// Test name, unique within the service
+	Name string
+
+	// Request header to match; exclusive with Cookie and Countries
+	Header string
+
+	// Cookie to match; exclusive with Header and Countries
+	Cookie string
+
+	// Exact value that selects the alternate upstream
+	Value string
+
+	// Client countries to match, looked up in the service's GeoIP
+	// database; exclusive with Header and Cookie
+	Countries []string
+}
+
+// match returns the Caddy matcher of the test for the given hosts, with
+// geoIPDatabase the database of country tests.
+func (t ABTest) match(hosts []string, geoIPDatabase string) *Match {
+	match := &Match{Host: hosts}
+	if len(t.Countries) > 0 {
+		match.Geolocation = &GeolocationMatch{DBPath: geoIPDatabase, AllowCountries: t.Countries}
+		return match
+	}
+	if t.Header != "" {
+		match.Header = map[string][]string{t.Header: {t.Value}}
+		return match
+	}
+	match.HeaderRegexp = map[string]*MatchRegexp{
+		"Cookie": {
+			Name:    t.Name,
+			Pattern: `(^|;\s*)` + regexp.QuoteMeta(t.Cookie) + `=` + regexp.QuoteMeta(t.Value) + `(;|$)`,
+		},
+	}
+	return match
+}
+
+// abRouteIDPrefix returns the ID prefix shared by the A/B routes of service.
+// The colon cannot appear in service names, so one service's prefix never
+// matches another's routes.
+func abRouteIDPrefix(service string) string {
+	return azudABRouteIDPrefix + service + ":"
+}
+
+func isABRoute(route *Route) bool {
+	return route != nil && strings.HasPrefix(route.ID, azudABRouteIDPrefix)
+}
+
+// buildABRoutes returns one route per test that proxies matching requests
+// for the service's hosts to upstream with the service's handler settings.
+func (m *Manager) buildABRoutes(service *ServiceConfig, tests []ABTest, upstream string) []*Route {
+	alternate := *service
+	alternate.Upstreams = []string{upstream}
+	alternate.UpstreamWeights = nil
+
+	routes := make([]*Route, 0, len(tests))
+	for _, test := range tests {
+		route := m.buildServiceRoute(&alternate)
+		route.ID = abRouteIDPrefix(service.Name) + test.Name
+		// Caddy IDs are global; only the service route's handler keeps one.
+		for _, handler := range route.Handle {
+			handler.ID = ""
+		}
+		route.Match = []*Match{test.match(route.Match[0].Host, service.GeoIPDatabase)}
+		routes = append(routes, route)
+	}
+	return routes
+}
+
+// applyABRoutes replaces the A/B routes of service in routes with abRoutes.
+// The new routes go first because the service route is terminal.
+func applyABRoutes(routes []*Route, service string, abRoutes []*Route) []*Route {
+	prefix := abRouteIDPrefix(service)
+	result := make([]*Route, 0, len(routes)+len(abRoutes))
+	result = append(result, abRoutes...)
+	for _, route := range routes {
+		if route != nil && strings.HasPrefix(route.ID, prefix) {
+			continue
+		}
+		result = append(result, route)
+	}
+	return result
+}
+
+// SetABRoutes routes requests matching tests for the service's hosts to
+// upstream, replacing any A/B routes the service had before. An empty
+// upstream or test list removes them.
+func (m *Manager) SetABRoutes(host string, service *ServiceConfig, tests []ABTest, upstream string) error {
+	var abRoutes []*Route
+	if upstream != "" && len(tests) > 0 {
+		abRoutes = m.buildABRoutes(service, tests, upstream)
+	}
+
+	return m.withPersistedMutation(host, func() error {
+		config, err := m.caddyClient.GetConfig(host)
+		if err != nil {
+			return fmt.Errorf("failed to get config: %w", err)
+		}
+		var server *HTTPServer
+		if config.Apps != nil && config.Apps.HTTP != nil {
+			server = config.Apps.HTTP.Servers["srv0"]
+		}
+		if server == nil {
+			if len(abRoutes) == 0 {
+				return nil
+			}
+			return fmt.Errorf("no HTTP server found for A/B routes")
+		}
+
+		routes := applyABRoutes(server.Routes, service.Name, abRoutes)
+		if len(routes) == len(server.Routes) && len(abRoutes) == 0 {
+			return nil
+		}
+		server.Routes = routes
+		if err := m.caddyClient.LoadConfig(host, config); err != nil {
+			return fmt.Errorf("failed to apply A/B routes: %w", err)
+		}
+		return nil
+	})
+}
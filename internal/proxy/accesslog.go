@@ -0,0 +1,103 @@
+package proxy
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"net"
+	"strings"
+	"time"
+
+	"github.com/lemonity-org/azud/internal/podman"
+)
+
+// RequestStats summarizes access log entries for a service.
+type RequestStats struct {
+	Requests int
+	Errors   int
+}
+
+// ErrorRate returns the percentage of requests answered with a 5xx status.
+func (s RequestStats) ErrorRate() float64 {
+	if s.Requests == 0 {
+		return 0
+	}
+	return float64(s.Errors) * 100 / float64(s.Requests)
+}
+
+// accessLogEntry is the subset of a Caddy JSON access log line Azud reads.
+type accessLogEntry struct {
+	Logger  string `json:"logger"`
+	Status  int    `json:"status"`
+	Request struct {
+		Host string `json:"host"`
+	} `json:"request"`
+	Upstream string `json:"upstream"`
+}
+
+// RequestStatsSince counts the access log entries Caddy on host wrote for
+// serviceHosts and served by upstream since the given time. Access logging
+// must be enabled (proxy.logging.enabled); otherwise Caddy records no
+// requests.
+func (m *Manager) RequestStatsSince(host string, serviceHosts []string, upstream string, since time.Time) (RequestStats, error) {
+	if err := m.ensureRootfulAccess(host); err != nil {
+		return RequestStats{}, err
+	}
+	result, err := m.podman.Logs(host, &podman.LogsConfig{
+		Container: CaddyContainerName,
+		Since:     since.UTC().Format(time.RFC3339),
+	})
+	if err != nil {
+		return RequestStats{}, fmt.Errorf("failed to read proxy access logs: %w", err)
+	}
+	return ParseAccessLog(result.Stdout, serviceHosts, upstream), nil
+}
+
+// ParseAccessLog counts requests and 5xx responses for serviceHosts in Caddy
+// JSON access log output. With upstream set, only requests whose upstream
+// log field names it are counted, so other hosts' traffic through a shared
+// proxy is left out. Lines that are not access log entries are ignored.
+func ParseAccessLog(logs string, serviceHosts []string, upstream string) RequestStats {
+	wanted := make(map[string]struct{}, len(serviceHosts))
+	for _, serviceHost := range serviceHosts {
+		wanted[strings.ToLower(serviceHost)] = struct{}{}
+	}
+
+	var stats RequestStats
+	scanner := bufio.NewScanner(strings.NewReader(logs))
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if !strings.HasPrefix(line, "{") {
+			continue
+		}
+		var entry accessLogEntry
+		if err := json.Unmarshal([]byte(line), &entry); err != nil {
+			continue
+		}
+		if !strings.HasPrefix(entry.Logger, "http.log.access") || entry.Status == 0 {
+			continue
+		}
+		if len(wanted) > 0 {
+			if _, ok := wanted[requestHostname(entry.Request.Host)]; !ok {
+				continue
+			}
+		}
+		if upstream != "" && entry.Upstream != upstream {
+			continue
+		}
+		stats.Requests++
+		if entry.Status >= 500 {
+			stats.Errors++
+		}
+	}
+	return stats
+}
+
+// requestHostname strips any port from a request Host header.
+func requestHostname(host string) string {
+	if name, _, err := net.SplitHostPort(host); err == nil {
+		host = name
+	}
+	return strings.ToLower(strings.Trim(host, "[]"))
+}
@@ -0,0 +1,35 @@
+package proxy
+
+import "testing"
+
+func TestParseAccessLogCountsServiceErrors(t *testing.T) {
+	logs := `{"level":"info","logger":"admin.api","msg":"received request"}
+{"level":"info","logger":"http.log.access.access","status":200,"request":{"host":"app.example.com"}}
+{"level":"error","logger":"http.log.access.access","status":502,"request":{"host":"APP.example.com:443"}}
+{"level":"error","logger":"http.log.access.access","status":503,"request":{"host":"other.example.com"}}
+{"level":"info","logger":"http.log.access.access","status":404,"request":{"host":"www.example.com"}}
+not json
+`
+	stats := ParseAccessLog(logs, []string{"app.example.com", "www.example.com"}, "")
+	if stats.Requests != 3 || stats.Errors != 1 {
+		t.Fatalf("stats = %+v, want 3 requests and 1 error", stats)
+	}
+	if rate := stats.ErrorRate(); rate < 33.3 || rate > 33.4 {
+		t.Fatalf("error rate = %v", rate)
+	}
+	if rate := (RequestStats{}).ErrorRate(); rate != 0 {
+		t.Fatalf("empty error rate = %v", rate)
+	}
+}
+
+func TestParseAccessLogFiltersUpstream(t *testing.T) {
+	logs := `{"logger":"http.log.access.access","status":502,"request":{"host":"app.example.com"},"upstream":"10.0.0.1:32768"}
+{"logger":"http.log.access.access","status":200,"request":{"host":"app.example.com"},"upstream":"10.0.0.2:32768"}
+{"logger":"http.log.access.access","status":200,"request":{"host":"app.example.com"},"upstream":"10.0.0.1:32768"}
+{"logger":"http.log.access.access","status":500,"request":{"host":"app.example.com"}}
+`
+	stats := ParseAccessLog(logs, []string{"app.example.com"}, "10.0.0.1:32768")
+	if stats.Requests != 2 || stats.Errors != 1 {
+		t.Fatalf("stats = %+v, want 2 requests and 1 error from 10.0.0.1:32768", stats)
+	}
+}
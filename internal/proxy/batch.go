@@ -0,0 +1,255 @@
+package proxy
+
+import (
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// Batch collects route and upstream changes for one host and applies them
+// as one transaction: a single Caddy lock, one PATCH of the routes, one
+// stream update, and one persist. Either every change takes effect or none
+// does, so a deploy never leaves a host with half of a swap applied.
+type Batch struct {
+	manager *Manager
+	host    string
+	ops     []batchOp
+	steps   []string
+}
+
+// batchOp changes the routes of a batch in memory and returns the stream
+// transform matching the change, if any.
+type batchOp func(routes *batchRoutes) (func(stream StreamConfig, dials []string) []string, error)
+
+// Batch starts a batch of changes to the proxy on host.
+func (m *Manager) Batch(host string) *Batch {
+	return &Batch{manager: m, host: host}
+}
+
+// Len returns the number of changes in the batch.
+func (b *Batch) Len() int {
+	return len(b.ops)
+}
+
+// RegisterService adds the route of service, or replaces the route it owns.
+func (b *Batch) RegisterService(service *ServiceConfig) *Batch {
+	if service.Host == "" && len(service.Hosts) > 0 {
+		service.Host = service.Hosts[0]
+	}
+	route := b.manager.buildServiceRoute(service)
+	upstreams := service.Upstreams
+	for _, weighted := range service.UpstreamWeights {
+		upstreams = append(upstreams, weighted.Dial)
+	}
+	b.steps = append(b.steps, "register "+service.Name)
+	b.ops = append(b.ops, func(routes *batchRoutes) (func(StreamConfig, []string) []string, error) {
+		if err := routes.upsert(service.Host, route); err != nil {
+			return nil, err
+		}
+		return func(stream StreamConfig, _ []string) []string {
+			var dials []string
+			for _, upstream := range upstreams {
+				if dial, ok := stream.dial(upstream); ok {
+					dials = appendIfMissing(dials, dial)
+				}
+			}
+			return dials
+		}, nil
+	})
+	return b
+}
+
+// DeregisterService removes the route of serviceHost.
+func (b *Batch) DeregisterService(serviceHost string) *Batch {
+	b.steps = append(b.steps, "deregister "+serviceHost)
+	b.ops = append(b.ops, func(routes *batchRoutes) (func(StreamConfig, []string) []string, error) {
+		routes.remove(serviceHost)
+		return func(StreamConfig, []string) []string { return nil }, nil
+	})
+	return b
+}
+
+// AddUpstream adds upstream to the route of serviceHost.
+func (b *Batch) AddUpstream(serviceHost, upstream string) *Batch {
+	b.steps = append(b.steps, "add "+upstream)
+	b.ops = append(b.ops, func(routes *batchRoutes) (func(StreamConfig, []string) []string, error) {
+		if err := routes.modifyUpstreams(serviceHost, func(upstreams []*Upstream) []*Upstream {
+			return addUpstreamIfMissing(upstreams, upstream)
+		}); err != nil {
+			return nil, err
+		}
+		return func(stream StreamConfig, dials []string) []string {
+			if dial, ok := stream.dial(upstream); ok {
+				return appendIfMissing(dials, dial)
+			}
+			return dials
+		}, nil
+	})
+	return b
+}
+
+// RemoveUpstream removes upstream from the route of serviceHost.
+func (b *Batch) RemoveUpstream(serviceHost, upstream string) *Batch {
+	b.steps = append(b.steps, "remove "+upstream)
+	b.ops = append(b.ops, func(routes *batchRoutes) (func(StreamConfig, []string) []string, error) {
+		if err := routes.modifyUpstreams(serviceHost, func(upstreams []*Upstream) []*Upstream {
+			return slices.DeleteFunc(upstreams, func(u *Upstream) bool { return u.Dial == upstream })
+		}); err != nil {
+			return nil, err
+		}
+		return func(stream StreamConfig, dials []string) []string {
+			dial, ok := stream.dial(upstream)
+			if !ok {
+				return dials
+			}
+			return slices.DeleteFunc(dials, func(d string) bool { return d == dial })
+		}, nil
+	})
+	return b
+}
+
+// Apply makes the changes on the host. On failure the previous live
+// configuration is restored and nothing is persisted.
+func (b *Batch) Apply() error {
+	if len(b.ops) == 0 {
+		return nil
+	}
+	m := b.manager
+	m.log.Host(b.host, "Applying proxy changes: %s...", strings.Join(b.steps, ", "))
+
+	if err := m.withPersistedMutation(b.host, func() error {
+		data, err := m.caddyClient.apiRequest(b.host, "GET", srv0RoutesPath, nil)
+		if err != nil {
+			return fmt.Errorf("failed to get routes: %w", err)
+		}
+		routes, err := parseBatchRoutes(data)
+		if err != nil {
+			return err
+		}
+		absent := routes.raw == nil
+		streams, err := routes.apply(b.ops)
+		if err != nil {
+			return err
+		}
+		payload, err := routes.encode()
+		if err != nil {
+			return err
+		}
+		// PATCH replaces the routes array; POST creates it when absent.
+		method := "PATCH"
+		if absent {
+			method = "POST"
+		}
+		if _, err := m.caddyClient.apiRequest(b.host, method, srv0RoutesPath, payload); err != nil {
+			return fmt.Errorf("failed to update routes: %w", err)
+		}
+		return m.updateStreams(b.host, streams)
+	}); err != nil {
+		return err
+	}
+
+	m.log.HostSuccess(b.host, "Proxy changes applied")
+	return nil
+}
+
+// batchRoutes is the routes array of a host while a batch changes it. Routes
+// the batch does not touch are written back exactly as Caddy returned them.
+type batchRoutes struct {
+	raw    []json.RawMessage
+	parsed []*Route
+	dirty  []bool
+}
+
+func parseBatchRoutes(data []byte) (*batchRoutes, error) {
+	routes := &batchRoutes{}
+	if err := json.Unmarshal(data, &routes.raw); err != nil {
+		return nil, fmt.Errorf("failed to parse routes: %w", err)
+	}
+	for _, raw := range routes.raw {
+		var route *Route
+		if err := json.Unmarshal(raw, &route); err != nil {
+			return nil, fmt.Errorf("failed to parse routes: %w", err)
+		}
+		routes.parsed = append(routes.parsed, route)
+		routes.dirty = append(routes.dirty, false)
+	}
+	return routes, nil
+}
+
+// apply runs ops in order and returns their stream transforms chained.
+func (r *batchRoutes) apply(ops []batchOp) (func(StreamConfig, []string) []string, error) {
+	var transforms []func(StreamConfig, []string) []string
+	for _, op := range ops {
+		transform, err := op(r)
+		if err != nil {
+			return nil, err
+		}
+		if transform != nil {
+			transforms = append(transforms, transform)
+		}
+	}
+	return func(stream StreamConfig, dials []string) []string {
+		for _, transform := range transforms {
+			dials = transform(stream, dials)
+		}
+		return dials
+	}, nil
+}
+
+func (r *batchRoutes) upsert(serviceHost string, route *Route) error {
+	if err := ensureNoForeignHostOwner(r.parsed, route); err != nil {
+		return err
+	}
+	index := slices.IndexFunc(r.parsed, func(existing *Route) bool {
+		return existing != nil && route.ID != "" && existing.ID == route.ID
+	})
+	if index < 0 {
+		index = slices.IndexFunc(r.parsed, func(existing *Route) bool { return routeMatchesHost(existing, serviceHost) })
+	}
+	if index < 0 {
+		r.raw = append(r.raw, nil)
+		r.parsed = append(r.parsed, route)
+		r.dirty = append(r.dirty, true)
+		return nil
+	}
+	r.parsed[index], r.dirty[index] = route, true
+	return nil
+}
+
+func (r *batchRoutes) remove(serviceHost string) {
+	for i := len(r.parsed) - 1; i >= 0; i-- {
+		if routeMatchesHost(r.parsed[i], serviceHost) {
+			r.raw = slices.Delete(r.raw, i, i+1)
+			r.parsed = slices.Delete(r.parsed, i, i+1)
+			r.dirty = slices.Delete(r.dirty, i, i+1)
+		}
+	}
+}
+
+func (r *batchRoutes) modifyUpstreams(serviceHost string, transform func([]*Upstream) []*Upstream) error {
+	for i, route := range r.parsed {
+		if handler, _, ok := reverseProxyHandler(route); ok && routeMatchesHost(route, serviceHost) {
+			handler.Upstreams = transform(handler.Upstreams)
+			r.dirty[i] = true
+			return nil
+		}
+	}
+	return fmt.Errorf("no route found for host %s", serviceHost)
+}
+
+func (r *batchRoutes) encode() ([]json.RawMessage, error) {
+	routes := make([]json.RawMessage, len(r.parsed))
+	for i, route := range r.parsed {
+		if !r.dirty[i] {
+			routes[i] = r.raw[i]
+			continue
+		}
+		data, err := json.Marshal(route)
+		if err != nil {
+			return nil, fmt.Errorf("failed to encode route: %w", err)
+		}
+		routes[i] = data
+	}
+	return routes, nil
+}
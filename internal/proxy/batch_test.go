@@ -0,0 +1,61 @@
+package proxy
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestBatchRoutes(t *testing.T) {
+	data := `[
+		{"@id":"azud-route-app","match":[{"host":["app.example.com"]}],"handle":[{"handler":"reverse_proxy","upstreams":[{"dial":"app-new:3000"}]}]},
+		{"match":[{"host":["manual.example.com"]}],"handle":[{"handler":"static_response","body":"hi"}],"custom_field":true}
+	]`
+	routes, err := parseBatchRoutes([]byte(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	m := &Manager{}
+	b := m.Batch("web1").
+		AddUpstream("app.example.com", "app:3000").
+		RemoveUpstream("app.example.com", "app-new:3000").
+		RegisterService(&ServiceConfig{Name: "api", Host: "api.example.com", Upstreams: []string{"api:8080"}})
+	if b.Len() != 3 {
+		t.Fatalf("Len() = %d, want 3", b.Len())
+	}
+	if _, err := routes.apply(b.ops); err != nil {
+		t.Fatal(err)
+	}
+	encoded, err := routes.encode()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(encoded) != 3 {
+		t.Fatalf("encode() returned %d routes, want 3", len(encoded))
+	}
+
+	var app Route
+	if err := json.Unmarshal(encoded[0], &app); err != nil {
+		t.Fatal(err)
+	}
+	handler, _, _ := reverseProxyHandler(&app)
+	if len(handler.Upstreams) != 1 || handler.Upstreams[0].Dial != "app:3000" {
+		t.Errorf("app upstreams = %+v, want only app:3000", handler.Upstreams)
+	}
+	if !strings.Contains(string(encoded[1]), `"custom_field":true`) {
+		t.Errorf("untouched route was rewritten: %s", encoded[1])
+	}
+	if !strings.Contains(string(encoded[2]), `"@id":"azud-route-api"`) {
+		t.Errorf("registered route = %s", encoded[2])
+	}
+
+	routes, _ = parseBatchRoutes([]byte(data))
+	if _, err := routes.apply(m.Batch("web1").AddUpstream("missing.example.com", "x:1").ops); err == nil {
+		t.Error("apply() to a host without a route succeeded")
+	}
+	routes.remove("manual.example.com")
+	if len(routes.parsed) != 1 || len(routes.raw) != 1 {
+		t.Errorf("remove() left %d routes, want 1", len(routes.parsed))
+	}
+}
@@ -0,0 +1,38 @@
+package proxy
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// CaddyBuilderImage is the official xcaddy builder of CaddyVersion.
+const CaddyBuilderImage = "docker.io/library/caddy:" + CaddyVersion + "-builder-alpine"
+
+// CaddyBuildfile returns the Containerfile of a Caddy image with the plugin
+// modules compiled in: xcaddy builds CaddyVersion in the builder image and
+// the binary replaces the one in CaddyImage.
+func CaddyBuildfile(modules []string) string {
+	sorted := slices.Clone(modules)
+	slices.Sort(sorted)
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "FROM %s AS builder\n", CaddyBuilderImage)
+	fmt.Fprintf(&b, "RUN xcaddy build v%s", CaddyVersion)
+	for _, module := range slices.Compact(sorted) {
+		fmt.Fprintf(&b, " \\\n    --with %s", module)
+	}
+	fmt.Fprintf(&b, "\n\nFROM %s\n", CaddyImage)
+	b.WriteString("COPY --from=builder /usr/bin/caddy /usr/bin/caddy\n")
+	return b.String()
+}
+
+// CaddyBuildTag returns the tag of the image CaddyBuildfile builds. It
+// digests the Containerfile, so changing the modules or the Caddy release
+// produces a new tag and an unchanged list reuses the pushed image.
+func CaddyBuildTag(modules []string) string {
+	sum := sha256.Sum256([]byte(CaddyBuildfile(modules)))
+	return CaddyVersion + "-" + hex.EncodeToString(sum[:])[:12]
+}
@@ -0,0 +1,33 @@
+package proxy
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestCaddyBuildfile(t *testing.T) {
+	got := CaddyBuildfile([]string{"github.com/mholt/caddy-l4", "github.com/caddy-dns/cloudflare@v0.2.1"})
+	want := "FROM " + CaddyBuilderImage + " AS builder\n" +
+		"RUN xcaddy build v" + CaddyVersion + " \\\n" +
+		"    --with github.com/caddy-dns/cloudflare@v0.2.1 \\\n" +
+		"    --with github.com/mholt/caddy-l4\n" +
+		"\n" +
+		"FROM " + CaddyImage + "\n" +
+		"COPY --from=builder /usr/bin/caddy /usr/bin/caddy\n"
+	if got != want {
+		t.Fatalf("CaddyBuildfile =\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestCaddyBuildTag(t *testing.T) {
+	tag := CaddyBuildTag([]string{"github.com/mholt/caddy-l4", "github.com/caddy-dns/cloudflare"})
+	if !strings.HasPrefix(tag, CaddyVersion+"-") || len(tag) != len(CaddyVersion)+13 {
+		t.Fatalf("CaddyBuildTag = %q, want %s-<12 hex digits>", tag, CaddyVersion)
+	}
+	if again := CaddyBuildTag([]string{"github.com/caddy-dns/cloudflare", "github.com/mholt/caddy-l4"}); again != tag {
+		t.Fatalf("module order changed the tag: %q != %q", again, tag)
+	}
+	if other := CaddyBuildTag([]string{"github.com/mholt/caddy-l4"}); other == tag {
+		t.Fatalf("different modules share tag %q", tag)
+	}
+}
@@ -2,9 +2,16 @@ package proxy
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
+	"net"
 	"net/http"
+	"strconv"
+	"strings"
+	"sync"
 	"time"
 
 	"github.com/lemonity-org/azud/internal/ssh"
@@ -12,19 +19,27 @@ import (
 
 // CaddyClient manages Caddy proxy via its admin API
 type CaddyClient struct {
-	sshClient  *ssh.Client
-	adminPort  int
-	httpClient *http.Client
+	sshClient *ssh.Client
+	adminPort int
+
+	// dial opens a connection to address on host; it forwards over SSH
+	dial func(ctx context.Context, host, address string) (net.Conn, error)
+
+	mu       sync.Mutex
+	tunnels  map[string]*http.Client
+	noTunnel map[string]bool
 }
 
-// NewCaddyClient creates a new Caddy client
+// NewCaddyClient creates a new Caddy client. Requests travel through a
+// forwarded connection to the admin port that stays open across requests,
+// falling back to curl on the host where SSH forwarding is refused.
 func NewCaddyClient(sshClient *ssh.Client) *CaddyClient {
 	return &CaddyClient{
 		sshClient: sshClient,
 		adminPort: 2019, // Caddy's default admin API port
-		httpClient: &http.Client{
-			Timeout: 30 * time.Second,
-		},
+		dial:      sshClient.DialRemote,
+		tunnels:   make(map[string]*http.Client),
+		noTunnel:  make(map[string]bool),
 	}
 }
 
@@ -42,8 +57,9 @@ type AdminConfig struct {
 
 // AppsConfig holds Caddy application configurations
 type AppsConfig struct {
-	HTTP *HTTPApp `json:"http,omitempty"`
-	TLS  *TLSApp  `json:"tls,omitempty"`
+	HTTP   *HTTPApp   `json:"http,omitempty"`
+	TLS    *TLSApp    `json:"tls,omitempty"`
+	Layer4 *Layer4App `json:"layer4,omitempty"`
 }
 
 // HTTPApp configures the HTTP server
@@ -53,10 +69,19 @@ type HTTPApp struct {
 
 // HTTPServer represents an HTTP server configuration
 type HTTPServer struct {
-	Listen    []string         `json:"listen,omitempty"`
-	Routes    []*Route         `json:"routes,omitempty"`
-	Logs      *ServerLogs      `json:"logs,omitempty"`
-	AutoHTTPS *AutoHTTPSConfig `json:"automatic_https,omitempty"`
+	Listen           []string           `json:"listen,omitempty"`
+	ListenerWrappers []*ListenerWrapper `json:"listener_wrappers,omitempty"`
+	Routes           []*Route           `json:"routes,omitempty"`
+	Logs             *ServerLogs        `json:"logs,omitempty"`
+	AutoHTTPS        *AutoHTTPSConfig   `json:"automatic_https,omitempty"`
+}
+
+// ListenerWrapper wraps the listeners of an HTTP server, e.g. to read PROXY
+// protocol headers before the TLS handshake
+type ListenerWrapper struct {
+	Wrapper string   `json:"wrapper"`
+	Timeout string   `json:"timeout,omitempty"`
+	Allow   []string `json:"allow,omitempty"`
 }
 
 // Route defines a routing rule
@@ -69,8 +94,36 @@ type Route struct {
 
 // Match defines matching criteria for a route
 type Match struct {
-	Host []string `json:"host,omitempty"`
-	Path []string `json:"path,omitempty"`
+	Host         []string                `json:"host,omitempty"`
+	Path         []string                `json:"path,omitempty"`
+	Header       map[string][]string     `json:"header,omitempty"`
+	HeaderRegexp map[string]*MatchRegexp `json:"header_regexp,omitempty"`
+	Not          []*Match                `json:"not,omitempty"`
+	File         *FileMatch              `json:"file,omitempty"`
+
+	// Provided by the caddy-maxmind-geolocation module
+	Geolocation *GeolocationMatch `json:"maxmind_geolocation,omitempty"`
+}
+
+// GeolocationMatch matches the country of the client address in a MaxMind
+// database. A request matches when its country is allowed, or when only
+// denied countries are listed and its country is not one of them.
+type GeolocationMatch struct {
+	DBPath         string   `json:"db_path"`
+	AllowCountries []string `json:"allow_countries,omitempty"`
+	DenyCountries  []string `json:"deny_countries,omitempty"`
+}
+
+// FileMatch matches requests whose files exist below Root.
+type FileMatch struct {
+	Root     string   `json:"root"`
+	TryFiles []string `json:"try_files"`
+}
+
+// MatchRegexp is a named regular expression matcher
+type MatchRegexp struct {
+	Name    string `json:"name,omitempty"`
+	Pattern string `json:"pattern"`
 }
 
 // Handler defines how to handle matched requests
@@ -97,16 +150,65 @@ type Handler struct {
 
 	// For request_body handler
 	MaxSize int64 `json:"max_size,omitempty"`
+
+	// For subroute handler
+	Routes []*Route `json:"routes,omitempty"`
+
+	// For headers handler: response header operations
+	Response *HeaderOps `json:"response,omitempty"`
+
+	// For file_server handler: the site root
+	Root string `json:"root,omitempty"`
+
+	// For log_append handler: a field added to the access log entry
+	Key   string `json:"key,omitempty"`
+	Value string `json:"value,omitempty"`
 }
 
 // Upstream represents a backend server
 type Upstream struct {
 	Dial string `json:"dial"`
+
+	// Concurrent requests above which the upstream counts as unavailable
+	MaxRequests int `json:"max_requests,omitempty"`
+}
+
+// Layer4App configures the caddy-l4 app, which proxies raw TCP and UDP
+// streams. It is only available in Caddy builds that include caddy-l4.
+type Layer4App struct {
+	Servers map[string]*Layer4Server `json:"servers,omitempty"`
+}
+
+// Layer4Server listens on one or more network addresses and proxies every
+// connection through its routes
+type Layer4Server struct {
+	Listen []string       `json:"listen,omitempty"`
+	Routes []*Layer4Route `json:"routes,omitempty"`
+}
+
+// Layer4Route holds the handlers applied to a connection
+type Layer4Route struct {
+	Handle []*Layer4Handler `json:"handle,omitempty"`
+}
+
+// Layer4Handler is a caddy-l4 handler; azud only uses the proxy handler
+type Layer4Handler struct {
+	Handler   string            `json:"handler"`
+	Upstreams []*Layer4Upstream `json:"upstreams,omitempty"`
+}
+
+// Layer4Upstream is a stream backend. Dial addresses carry a network
+// prefix for UDP (e.g. udp/app:53).
+type Layer4Upstream struct {
+	Dial []string `json:"dial"`
 }
 
 // LoadBalancing configures load balancing
 type LoadBalancing struct {
 	SelectionPolicy *SelectionPolicy `json:"selection_policy,omitempty"`
+	TryDuration     string           `json:"try_duration,omitempty"`
+	TryInterval     string           `json:"try_interval,omitempty"`
+	Retries         int              `json:"retries,omitempty"`
 }
 
 // SelectionPolicy defines how to select upstreams
@@ -152,12 +254,21 @@ type HeaderOps struct {
 // Transport configures the HTTP transport
 type Transport struct {
 	Protocol              string             `json:"protocol,omitempty"`
+	DialTimeout           string             `json:"dial_timeout,omitempty"`
 	ResponseHeaderTimeout string             `json:"response_header_timeout,omitempty"`
 	ReadTimeout           string             `json:"read_timeout,omitempty"`
+	WriteTimeout          string             `json:"write_timeout,omitempty"`
+	MaxConnsPerHost       int                `json:"max_conns_per_host,omitempty"`
+	KeepAlive             *KeepAlive         `json:"keep_alive,omitempty"`
 	Versions              []string           `json:"versions,omitempty"`
 	TLS                   *UpstreamTLSConfig `json:"tls,omitempty"`
 }
 
+// KeepAlive configures pooled upstream connections
+type KeepAlive struct {
+	IdleTimeout string `json:"idle_timeout,omitempty"`
+}
+
 // UpstreamTLSConfig enables TLS with Caddy's secure defaults. Additional
 // trust and SNI controls can be added here without changing the route shape.
 type UpstreamTLSConfig struct{}
@@ -252,6 +363,10 @@ func (c *CaddyClient) apiRequest(host, method, path string, body interface{}) ([
 		}
 	}
 
+	if data, ok, err := c.tunnelRequest(host, method, path, bodyJSON); ok {
+		return data, err
+	}
+
 	// Execute curl command via SSH to reach Caddy's admin API.
 	// Use -f (--fail) so curl returns a non-zero exit code on HTTP 4xx/5xx
 	// errors, preventing silent failures when Caddy rejects a config change.
@@ -280,6 +395,91 @@ func (c *CaddyClient) apiRequest(host, method, path string, body interface{}) ([
 	return []byte(result.Stdout), nil
 }
 
+// tunnelDialError marks a failure to open the forwarded connection, after
+// which nothing has reached Caddy and the request can go through curl.
+type tunnelDialError struct{ err error }
+
+func (e *tunnelDialError) Error() string { return e.err.Error() }
+func (e *tunnelDialError) Unwrap() error { return e.err }
+
+// tunnelRequest sends the request over the host's forwarded admin
+// connection. It reports false when the connection cannot be opened, and
+// the host is then left to curl for the rest of the run.
+func (c *CaddyClient) tunnelRequest(host, method, path string, bodyJSON []byte) ([]byte, bool, error) {
+	client := c.tunnel(host)
+	if client == nil {
+		return nil, false, nil
+	}
+
+	ctx := context.Background()
+	if c.sshClient != nil {
+		ctx = c.sshClient.Context()
+	}
+	var reader io.Reader
+	if len(bodyJSON) > 0 {
+		reader = bytes.NewReader(bodyJSON)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, fmt.Sprintf("http://localhost:%d%s", c.adminPort, path), reader)
+	if err != nil {
+		return nil, true, err
+	}
+	if len(bodyJSON) > 0 {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		var dialErr *tunnelDialError
+		if errors.As(err, &dialErr) {
+			c.mu.Lock()
+			c.noTunnel[host] = true
+			c.mu.Unlock()
+			return nil, false, nil
+		}
+		return nil, true, fmt.Errorf("failed to execute API request: %w", err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, true, fmt.Errorf("failed to read API response: %w", err)
+	}
+	if resp.StatusCode >= 400 {
+		return nil, true, fmt.Errorf("API request failed: %s: %s", resp.Status, strings.TrimSpace(string(data)))
+	}
+	return data, true, nil
+}
+
+// tunnel returns the HTTP client whose connections forward to the admin
+// port of host, or nil when forwarding failed there before.
+func (c *CaddyClient) tunnel(host string) *http.Client {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.dial == nil || c.noTunnel[host] {
+		return nil
+	}
+	if client, ok := c.tunnels[host]; ok {
+		return client
+	}
+	address := net.JoinHostPort("127.0.0.1", strconv.Itoa(c.adminPort))
+	client := &http.Client{
+		Timeout: 30 * time.Second,
+		Transport: &http.Transport{
+			DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+				conn, err := c.dial(ctx, host, address)
+				if err != nil {
+					return nil, &tunnelDialError{err: err}
+				}
+				return conn, nil
+			},
+			MaxIdleConnsPerHost: 2,
+			IdleConnTimeout:     30 * time.Second,
+		},
+	}
+	c.tunnels[host] = client
+	return client
+}
+
 // GetConfig retrieves the current Caddy configuration
 func (c *CaddyClient) GetConfig(host string) (*CaddyConfig, error) {
 	data, err := c.apiRequest(host, "GET", "/config/", nil)
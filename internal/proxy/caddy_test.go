@@ -0,0 +1,67 @@
+package proxy
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestCaddyClientTunnel(t *testing.T) {
+	var bodies []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		data, _ := io.ReadAll(r.Body)
+		bodies = append(bodies, r.Method+" "+r.URL.Path+" "+string(data))
+		if r.URL.Path == "/bad" {
+			http.Error(w, "invalid config", http.StatusBadRequest)
+			return
+		}
+		_, _ = w.Write([]byte(`{"admin":{"listen":"0.0.0.0:2019"}}`))
+	}))
+	defer server.Close()
+
+	dials := 0
+	client := NewCaddyClient(nil)
+	client.dial = func(ctx context.Context, host, address string) (net.Conn, error) {
+		if host == "offline" {
+			return nil, errors.New("administratively prohibited")
+		}
+		if address != "127.0.0.1:2019" {
+			t.Errorf("dialed %s, want the admin port on the host's loopback", address)
+		}
+		dials++
+		return (&net.Dialer{}).DialContext(ctx, "tcp", server.Listener.Addr().String())
+	}
+
+	config, err := client.GetConfig("web1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if config.Admin == nil || config.Admin.Listen != "0.0.0.0:2019" {
+		t.Errorf("GetConfig() = %+v", config)
+	}
+	if err := client.SetConfig("web1", &CaddyConfig{}); err != nil {
+		t.Fatal(err)
+	}
+	if dials != 1 {
+		t.Errorf("dialed %d times, want the connection reused", dials)
+	}
+	if want := []string{"GET /config/ ", "POST /config/ {}"}; strings.Join(bodies, "|") != strings.Join(want, "|") {
+		t.Errorf("requests = %q, want %q", bodies, want)
+	}
+
+	if _, err := client.apiRequest("web1", "GET", "/bad", nil); err == nil || !strings.Contains(err.Error(), "invalid config") {
+		t.Errorf("apiRequest() error = %v, want Caddy's message", err)
+	}
+
+	if _, ok, err := client.tunnelRequest("offline", "GET", "/config/", nil); ok || err != nil {
+		t.Errorf("tunnelRequest() on a host refusing forwarding = %v, %v, want a fallback", ok, err)
+	}
+	if client.tunnel("offline") != nil {
+		t.Error("tunnel() retried a host that refused forwarding")
+	}
+}
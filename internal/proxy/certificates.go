@@ -0,0 +1,96 @@
+package proxy
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+)
+
+// CaddyDataVolume is the volume holding Caddy's storage: the ACME account,
+// the issued certificates and their keys.
+const CaddyDataVolume = "caddy_data"
+
+// caddyCertificatePaths are the parts of Caddy's storage, relative to
+// /data, that carry over to another host: the ACME account, the issued
+// certificates, and the internal CA. Locks and OCSP staples stay behind.
+var caddyCertificatePaths = []string{"caddy/acme", "caddy/certificates", "caddy/pki"}
+
+// HasDataVolume reports whether host has the Caddy data volume. Hosts
+// without it are new or rebuilt and would otherwise request every
+// certificate again.
+func (m *Manager) HasDataVolume(host string) (bool, error) {
+	return m.volumes.Exists(host, CaddyDataVolume)
+}
+
+// HasCertificates reports whether the proxy on host is running and has
+// certificates in its storage.
+func (m *Manager) HasCertificates(host string) (bool, error) {
+	running, err := m.podman.IsRunning(host, CaddyContainerName)
+	if err != nil || !running {
+		return false, err
+	}
+	result, err := m.client.Execute(host, "exec", CaddyContainerName, "test", "-d", "/data/caddy/certificates")
+	if err != nil {
+		return false, err
+	}
+	return result.ExitCode == 0, nil
+}
+
+// ExportCertificates writes a tarball of the certificate storage of the
+// running proxy on host to w.
+func (m *Manager) ExportCertificates(host string, w io.Writer) error {
+	script := "cd /data && ls -d " + strings.Join(caddyCertificatePaths, " ") + " 2>/dev/null || true"
+	result, err := m.client.Execute(host, "exec", CaddyContainerName, "sh", "-c", script)
+	if err != nil {
+		return fmt.Errorf("failed to export certificates from %s: %w", host, err)
+	}
+	paths, err := certificateExportPaths(result.Stdout)
+	if err != nil {
+		return fmt.Errorf("failed to export certificates from %s: %w", host, err)
+	}
+
+	args := append([]string{"exec", "-w", "/data", CaddyContainerName, "tar", "-cf", "-"}, paths...)
+	var stderr bytes.Buffer
+	if err := m.client.ExecuteStream(host, w, &stderr, args...); err != nil {
+		return fmt.Errorf("failed to export certificates from %s: %w: %s", host, err, strings.TrimSpace(stderr.String()))
+	}
+	return nil
+}
+
+// certificateExportPaths returns the certificate storage paths listed by ls,
+// in the order of caddyCertificatePaths. Without any, there is nothing for
+// tar to archive.
+func certificateExportPaths(listing string) ([]string, error) {
+	existing := strings.Fields(listing)
+	var paths []string
+	for _, path := range caddyCertificatePaths {
+		if slices.Contains(existing, path) {
+			paths = append(paths, path)
+		}
+	}
+	if len(paths) == 0 {
+		return nil, fmt.Errorf("no certificate storage in /data")
+	}
+	return paths, nil
+}
+
+// ImportCertificates extracts a tarball written by ExportCertificates into
+// the Caddy data volume on host, creating the volume if needed. A running
+// proxy uses the imported certificates for names it has not loaded yet and
+// otherwise at their next renewal check.
+func (m *Manager) ImportCertificates(host string, r io.Reader) error {
+	if err := m.volumes.Create(host, CaddyDataVolume); err != nil {
+		return err
+	}
+	cmd := m.client.RewriteCommand("podman volume import " + CaddyDataVolume + " -")
+	result, err := m.sshClient.ExecuteWithStdin(host, cmd, r)
+	if err != nil {
+		return err
+	}
+	if result.ExitCode != 0 {
+		return fmt.Errorf("failed to import certificates on %s: %s", host, strings.TrimSpace(result.Stderr))
+	}
+	return nil
+}
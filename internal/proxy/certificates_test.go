@@ -0,0 +1,21 @@
+package proxy
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestCertificateExportPaths(t *testing.T) {
+	paths, err := certificateExportPaths("caddy/pki\ncaddy/certificates\n")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"caddy/certificates", "caddy/pki"}; !reflect.DeepEqual(paths, want) {
+		t.Fatalf("certificateExportPaths() = %v, want %v", paths, want)
+	}
+
+	if _, err := certificateExportPaths(""); err == nil || !strings.Contains(err.Error(), "no certificate storage") {
+		t.Fatalf("certificateExportPaths(\"\") error = %v, want no certificate storage", err)
+	}
+}
@@ -0,0 +1,98 @@
+package proxy
+
+import (
+	"fmt"
+	"path"
+	"strings"
+
+	"github.com/lemonity-org/azud/internal/shell"
+)
+
+// GeoIPConfig locates the MaxMind database of the proxy on its hosts.
+type GeoIPConfig struct {
+	// Host path of the .mmdb database; its directory is mounted read-only
+	// at config.GeoIPMount
+	Database string
+
+	// MaxMind edition, account ID, and license key used to download the
+	// database when it is missing (no download without a license key)
+	Edition    string
+	AccountID  string
+	LicenseKey string
+}
+
+// geoIPDownloadURL is the MaxMind permalink of a database edition.
+const geoIPDownloadURL = "https://download.maxmind.com/geoip/databases/%s/download?suffix=tar.gz"
+
+// Dir returns the host directory holding the database.
+func (g *GeoIPConfig) Dir() string {
+	return path.Dir(g.Database)
+}
+
+// geolocationBlockHandler returns the handler answering 403 to clients
+// whose country service does not admit, or nil without country limits.
+// It runs first in the service route, so refused requests never reach the
+// upstreams.
+func geolocationBlockHandler(service *ServiceConfig) *Handler {
+	if service.GeoIPDatabase == "" || len(service.AllowCountries)+len(service.DenyCountries) == 0 {
+		return nil
+	}
+	admitted := &Match{Geolocation: &GeolocationMatch{
+		DBPath:         service.GeoIPDatabase,
+		AllowCountries: service.AllowCountries,
+		DenyCountries:  service.DenyCountries,
+	}}
+	return &Handler{
+		Handler: "subroute",
+		Routes: []*Route{{
+			Match:  []*Match{{Not: []*Match{admitted}}},
+			Handle: []*Handler{{Handler: "static_response", StatusCode: 403, Body: "Forbidden"}},
+		}},
+	}
+}
+
+// ensureGeoIPDatabase downloads the database to host when it is missing
+// and a license key is configured. The credentials reach curl on stdin, so
+// they never appear in the remote command line.
+func (m *Manager) ensureGeoIPDatabase(host string, geo *GeoIPConfig) error {
+	if geo.LicenseKey == "" {
+		return nil
+	}
+	database := shell.QuoteRemotePath(geo.Database)
+	check, err := m.sshClient.Execute(host, "test -s "+database)
+	if err != nil {
+		return err
+	}
+	if check.ExitCode == 0 {
+		return nil
+	}
+
+	m.log.Host(host, "Downloading GeoIP database %s...", geo.Edition)
+	script := fmt.Sprintf(`set -e
+dir=%s
+mkdir -p "$dir"
+tmp=$(mktemp -d "$dir/.geoip-XXXXXX")
+trap 'rm -rf "$tmp"' EXIT
+curl -fsSL --retry 3 -K - -o "$tmp/db.tar.gz"
+tar -xzf "$tmp/db.tar.gz" -C "$tmp"
+db=$(find "$tmp" -name '*.mmdb' | head -n 1)
+test -n "$db"
+mv "$db" %s`, shell.QuoteRemotePath(geo.Dir()), database) // safe: paths quoted by shell.QuoteRemotePath
+	curlConfig := fmt.Sprintf("url = %s\nuser = %s\n",
+		curlConfigString(fmt.Sprintf(geoIPDownloadURL, geo.Edition)),
+		curlConfigString(geo.AccountID+":"+geo.LicenseKey))
+
+	result, err := m.sshClient.ExecuteWithStdin(host, "sh -c "+shell.Quote(script), strings.NewReader(curlConfig))
+	if err != nil {
+		return err
+	}
+	if result.ExitCode != 0 {
+		return fmt.Errorf("failed to download GeoIP database on %s: %s", host, strings.TrimSpace(result.Stderr))
+	}
+	return nil
+}
+
+// curlConfigString quotes value for a curl config file.
+func curlConfigString(value string) string {
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`).Replace(value) + `"`
+}
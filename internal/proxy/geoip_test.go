@@ -0,0 +1,62 @@
+package proxy
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestServiceRouteRefusesCountriesFirst(t *testing.T) {
+	m := &Manager{}
+	service := &ServiceConfig{
+		Name:           "shop",
+		Host:           "shop.example.com",
+		Upstreams:      []string{"shop:3000"},
+		MaxRequestBody: 1024,
+		GeoIPDatabase:  "/usr/share/GeoIP/GeoLite2-Country.mmdb",
+		DenyCountries:  []string{"KP"},
+	}
+
+	route := m.buildServiceRoute(service)
+	if len(route.Handle) != 3 || route.Handle[0].Handler != "subroute" || route.Handle[1].Handler != "request_body" {
+		t.Fatalf("handlers = %#v, want the country check first", route.Handle)
+	}
+	data, err := json.Marshal(route.Handle[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `{"handler":"subroute","routes":[{"match":[{"not":[{"maxmind_geolocation":{"db_path":"/usr/share/GeoIP/GeoLite2-Country.mmdb","deny_countries":["KP"]}}]}],"handle":[{"handler":"static_response","status_code":403,"body":"Forbidden"}]}]}`
+	if string(data) != want {
+		t.Fatalf("country check = %s\nwant %s", data, want)
+	}
+
+	service.DenyCountries = nil
+	if route := m.buildServiceRoute(service); route.Handle[0].Handler != "request_body" {
+		t.Fatalf("route without country limits has handlers %#v", route.Handle)
+	}
+}
+
+func TestABRoutesMatchCountries(t *testing.T) {
+	m := &Manager{}
+	service := &ServiceConfig{
+		Name:          "shop",
+		Host:          "shop.example.com",
+		Upstreams:     []string{"shop:3000"},
+		GeoIPDatabase: "/usr/share/GeoIP/GeoLite2-Country.mmdb",
+	}
+	routes := m.buildABRoutes(service, []ABTest{{Name: "dach", Countries: []string{"DE", "AT", "CH"}}}, "shop-canary:3000")
+
+	data, err := json.Marshal(routes[0].Match)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `[{"host":["shop.example.com"],"maxmind_geolocation":{"db_path":"/usr/share/GeoIP/GeoLite2-Country.mmdb","allow_countries":["DE","AT","CH"]}}]`
+	if string(data) != want {
+		t.Fatalf("country match = %s\nwant %s", data, want)
+	}
+}
+
+func TestCurlConfigString(t *testing.T) {
+	if got, want := curlConfigString(`12:k"e\y`), `"12:k\"e\\y"`; got != want {
+		t.Fatalf("curlConfigString = %s, want %s", got, want)
+	}
+}
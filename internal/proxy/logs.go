@@ -0,0 +1,176 @@
+package proxy
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// upstreamLogField is the access log field holding the upstream that served
+// the request.
+const upstreamLogField = "upstream"
+
+// upstreamLogHandler adds the upstream that served the request to its access
+// log entry. log_append evaluates the placeholder after the reverse proxy
+// returns, so the entry names the upstream that was dialed last.
+func upstreamLogHandler() *Handler {
+	return &Handler{
+		Handler: "log_append",
+		Key:     upstreamLogField,
+		Value:   "{http.reverse_proxy.upstream.hostport}",
+	}
+}
+
+// statusFilterRegex matches a status code (502) or class (5xx).
+var statusFilterRegex = regexp.MustCompile(`^[1-5]([0-9]{2}|xx)$`)
+
+// LogFilter selects proxy log entries. Caddy writes JSON entries; with any
+// filter set, lines that are not JSON are dropped.
+type LogFilter struct {
+	// Request hosts, without port (case-insensitive); any of them matches
+	Hosts []string
+
+	// Status code (502) or class (5xx)
+	Status string
+
+	// Upstream that served the request: its dial address or host part
+	Upstream string
+
+	// Only entries logged at error level or above, or answered with 5xx
+	ErrorsOnly bool
+}
+
+// logEntry is the subset of a Caddy log entry LogFilter reads.
+type logEntry struct {
+	Level   string `json:"level"`
+	Request struct {
+		Host string `json:"host"`
+	} `json:"request"`
+	Status   int    `json:"status"`
+	Upstream string `json:"upstream"`
+}
+
+// Validate checks the filter values.
+func (f LogFilter) Validate() error {
+	if f.Status != "" && !statusFilterRegex.MatchString(strings.ToLower(f.Status)) {
+		return fmt.Errorf("invalid status %q: use a code such as 502 or a class such as 5xx", f.Status)
+	}
+	return nil
+}
+
+// Active reports whether any filter is set.
+func (f LogFilter) Active() bool {
+	return len(f.Hosts) > 0 || f.Status != "" || f.Upstream != "" || f.ErrorsOnly
+}
+
+// Match reports whether line passes the filter.
+func (f LogFilter) Match(line []byte) bool {
+	if !f.Active() {
+		return true
+	}
+	var entry logEntry
+	if err := json.Unmarshal(bytes.TrimSpace(line), &entry); err != nil {
+		return false
+	}
+	if len(f.Hosts) > 0 && !slices.ContainsFunc(f.Hosts, func(host string) bool {
+		return strings.EqualFold(stripPort(entry.Request.Host), stripPort(host))
+	}) {
+		return false
+	}
+	if f.Status != "" && !matchStatus(strings.ToLower(f.Status), entry.Status) {
+		return false
+	}
+	if f.Upstream != "" && entry.Upstream != f.Upstream && stripPort(entry.Upstream) != f.Upstream {
+		return false
+	}
+	if f.ErrorsOnly {
+		switch strings.ToLower(entry.Level) {
+		case "error", "dpanic", "panic", "fatal":
+		default:
+			if entry.Status < 500 {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// Lines returns the lines of logs that pass the filter.
+func (f LogFilter) Lines(logs string) string {
+	if !f.Active() {
+		return logs
+	}
+	var out strings.Builder
+	for _, line := range strings.SplitAfter(logs, "\n") {
+		if line != "" && f.Match([]byte(line)) {
+			out.WriteString(line)
+		}
+	}
+	return out.String()
+}
+
+func matchStatus(filter string, status int) bool {
+	if status == 0 {
+		return false
+	}
+	if strings.HasSuffix(filter, "xx") {
+		return strconv.Itoa(status/100) == filter[:1]
+	}
+	return strconv.Itoa(status) == filter
+}
+
+func stripPort(address string) string {
+	if host, _, err := net.SplitHostPort(address); err == nil {
+		return host
+	}
+	return address
+}
+
+// LogFilterWriter passes the complete lines written to it through a
+// LogFilter, for logs that are followed rather than read at once.
+type LogFilterWriter struct {
+	w       io.Writer
+	filter  LogFilter
+	pending []byte
+}
+
+// NewLogFilterWriter returns a writer that writes to w the lines passing
+// filter. Close writes a final line without newline.
+func NewLogFilterWriter(w io.Writer, filter LogFilter) *LogFilterWriter {
+	return &LogFilterWriter{w: w, filter: filter}
+}
+
+func (fw *LogFilterWriter) Write(p []byte) (int, error) {
+	fw.pending = append(fw.pending, p...)
+	for {
+		end := bytes.IndexByte(fw.pending, '\n')
+		if end < 0 {
+			break
+		}
+		line := fw.pending[:end+1]
+		fw.pending = fw.pending[end+1:]
+		if fw.filter.Match(line) {
+			if _, err := fw.w.Write(line); err != nil {
+				return 0, err
+			}
+		}
+	}
+	return len(p), nil
+}
+
+// Close writes the pending partial line if it passes the filter.
+func (fw *LogFilterWriter) Close() error {
+	line := fw.pending
+	fw.pending = nil
+	if len(line) > 0 && fw.filter.Match(line) {
+		_, err := fw.w.Write(line)
+		return err
+	}
+	return nil
+}
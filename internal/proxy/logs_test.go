@@ -0,0 +1,81 @@
+package proxy
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestLogFilterMatch(t *testing.T) {
+	access := `{"level":"info","logger":"http.log.access.access","request":{"host":"shop.example.com","uri":"/"},"status":502,"upstream":"shop-web-1a2b:3000"}`
+	ok := `{"level":"info","logger":"http.log.access.access","request":{"host":"blog.example.com:443","uri":"/"},"status":200,"upstream":"blog-web-9f8e:8080"}`
+	caddyErr := `{"level":"error","logger":"tls.obtain","msg":"could not get certificate"}`
+
+	tests := []struct {
+		name   string
+		filter LogFilter
+		line   string
+		want   bool
+	}{
+		{name: "no filter passes text", line: "plain text", want: true},
+		{name: "filter drops text", filter: LogFilter{Status: "5xx"}, line: "plain text"},
+		{name: "host", filter: LogFilter{Hosts: []string{"SHOP.example.com"}}, line: access, want: true},
+		{name: "host ignores port", filter: LogFilter{Hosts: []string{"blog.example.com"}}, line: ok, want: true},
+		{name: "other host", filter: LogFilter{Hosts: []string{"shop.example.com"}}, line: ok},
+		{name: "status class", filter: LogFilter{Status: "5xx"}, line: access, want: true},
+		{name: "status code", filter: LogFilter{Status: "200"}, line: access},
+		{name: "upstream dial", filter: LogFilter{Upstream: "shop-web-1a2b:3000"}, line: access, want: true},
+		{name: "upstream host", filter: LogFilter{Upstream: "shop-web-1a2b"}, line: access, want: true},
+		{name: "other upstream", filter: LogFilter{Upstream: "shop-web-1a2b"}, line: ok},
+		{name: "errors only keeps 5xx", filter: LogFilter{ErrorsOnly: true}, line: access, want: true},
+		{name: "errors only keeps error level", filter: LogFilter{ErrorsOnly: true}, line: caddyErr, want: true},
+		{name: "errors only drops 2xx", filter: LogFilter{ErrorsOnly: true}, line: ok},
+		{name: "host and errors", filter: LogFilter{Hosts: []string{"shop.example.com"}, ErrorsOnly: true}, line: caddyErr},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.filter.Match([]byte(tt.line)); got != tt.want {
+				t.Fatalf("Match(%s) = %v, want %v", tt.line, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLogFilterValidate(t *testing.T) {
+	for _, status := range []string{"502", "5xx", "4XX"} {
+		if err := (LogFilter{Status: status}).Validate(); err != nil {
+			t.Errorf("Validate(%s) = %v", status, err)
+		}
+	}
+	for _, status := range []string{"5", "600", "error"} {
+		if err := (LogFilter{Status: status}).Validate(); err == nil {
+			t.Errorf("Validate(%s) accepted an invalid status", status)
+		}
+	}
+}
+
+func TestLogFilterWriterSplitsLines(t *testing.T) {
+	var out strings.Builder
+	w := NewLogFilterWriter(&out, LogFilter{Status: "404"})
+	for _, chunk := range []string{`{"status":404}` + "\n" + `{"sta`, `tus":200}` + "\n", `{"status":404}`} {
+		if _, err := w.Write([]byte(chunk)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if want := `{"status":404}` + "\n" + `{"status":404}`; out.String() != want {
+		t.Fatalf("output = %q, want %q", out.String(), want)
+	}
+}
+
+func TestServiceRouteLogsUpstream(t *testing.T) {
+	m := &Manager{}
+	route := m.buildServiceRoute(&ServiceConfig{Name: "shop", Host: "shop.example.com", Upstreams: []string{"shop:3000"}, LogUpstream: true})
+	if len(route.Handle) != 2 || route.Handle[0].Handler != "log_append" || route.Handle[1].Handler != "reverse_proxy" {
+		t.Fatalf("handlers = %#v, want log_append before reverse_proxy", route.Handle)
+	}
+	if h := route.Handle[0]; h.Key != "upstream" || h.Value != "{http.reverse_proxy.upstream.hostport}" {
+		t.Fatalf("log_append = %+v", h)
+	}
+}
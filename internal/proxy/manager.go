@@ -5,13 +5,17 @@ import (
 	"encoding/json"
 	"fmt"
 	"io"
+	"net"
 	"net/url"
 	"reflect"
+	"slices"
 	"sort"
+	"strconv"
 	"strings"
 	"sync"
 	"time"
 
+	appconfig "github.com/lemonity-org/azud/internal/config"
 	"github.com/lemonity-org/azud/internal/output"
 	"github.com/lemonity-org/azud/internal/podman"
 	"github.com/lemonity-org/azud/internal/ssh"
@@ -19,9 +23,13 @@ import (
 )
 
 const (
+	// CaddyVersion is the Caddy release of CaddyImage; proxy.image_build
+	// compiles the same release.
+	CaddyVersion = "2.11.4"
+
 	// Pinned by version and multi-platform OCI digest. Update deliberately after
 	// reviewing Caddy release notes and the official-image manifest.
-	CaddyImage         = "docker.io/library/caddy:2.11.4-alpine@sha256:5f5c8640aae01df9654968d946d8f1a56c497f1dd5c5cda4cf95ab7c14d58648"
+	CaddyImage         = "docker.io/library/caddy:" + CaddyVersion + "-alpine@sha256:5f5c8640aae01df9654968d946d8f1a56c497f1dd5c5cda4cf95ab7c14d58648"
 	CaddyContainerName = "azud-proxy"
 	CaddyAdminPort     = 2019
 	CaddyHTTPPort      = 80
@@ -65,6 +73,8 @@ type Manager struct {
 	sshClient   *ssh.Client
 	caddyClient *CaddyClient
 	podman      *podman.ContainerManager
+	client      *podman.Client
+	volumes     *podman.VolumeManager
 	log         *output.Logger
 	user        string // SSH user for state directory paths
 	rootful     bool
@@ -101,6 +111,8 @@ func NewManagerWithOptions(sshClient *ssh.Client, log *output.Logger, user strin
 		sshClient:   sshClient,
 		caddyClient: NewCaddyClient(sshClient),
 		podman:      podman.NewContainerManager(podmanClient),
+		client:      podmanClient,
+		volumes:     podman.NewVolumeManager(podmanClient),
 		log:         log,
 		user:        user,
 		rootful:     rootful,
@@ -133,6 +145,21 @@ func (m *Manager) EnsureConfig(host string) error {
 	})
 }
 
+// DefaultBootTimeout is how long to wait for the admin API after the proxy
+// starts unless the proxy config sets BootTimeout.
+const DefaultBootTimeout = 30 * time.Second
+
+// bootTimeout returns how long to wait for the admin API after a start.
+func (m *Manager) bootTimeout(config *ProxyConfig) time.Duration {
+	if config == nil {
+		config = m.proxyConfig
+	}
+	if config != nil && config.BootTimeout > 0 {
+		return config.BootTimeout
+	}
+	return DefaultBootTimeout
+}
+
 // waitForAdminAPI polls the Caddy admin API until it responds or the
 // timeout is reached. This replaces fixed sleeps after container starts.
 func (m *Manager) waitForAdminAPI(host string, timeout time.Duration) error {
@@ -142,7 +169,9 @@ func (m *Manager) waitForAdminAPI(host string, timeout time.Duration) error {
 		if _, err := m.caddyClient.apiRequest(host, "GET", "/config/", nil); err == nil {
 			return nil
 		}
-		time.Sleep(interval)
+		if err := m.sshClient.Sleep(interval); err != nil {
+			return err
+		}
 	}
 	return fmt.Errorf("caddy admin API not ready after %s", timeout)
 }
@@ -330,6 +359,12 @@ type ProxyConfig struct {
 	// Custom SSL private key PEM content
 	SSLPrivateKey string
 
+	// Accept PROXY protocol headers from an upstream load balancer
+	ProxyProtocol bool
+
+	// CIDR ranges allowed to send PROXY protocol headers (empty: any)
+	ProxyProtocolAllow []string
+
 	// Enable access logging (even without header redaction)
 	LoggingEnabled bool
 
@@ -338,6 +373,107 @@ type ProxyConfig struct {
 
 	// Response headers to redact from access logs
 	RedactResponseHeaders []string
+
+	// How long to wait for the admin API after the proxy starts
+	// (default: DefaultBootTimeout)
+	BootTimeout time.Duration
+
+	// IP addresses the proxy on each host publishes its HTTP, HTTPS and
+	// stream ports on, keyed by host; hosts without an entry publish on
+	// every interface
+	Bind map[string][]string
+
+	// Memory and CPU limits of the proxy container (e.g. 512m, 0.5)
+	Memory string
+	CPUs   string
+
+	// Podman log driver and key=value log options of the proxy container
+	LogDriver string
+	LogOpts   []string
+
+	// Host directory of app socket directories, mounted at
+	// config.AppSocketsMount (empty unless proxy.app_socket is set)
+	AppSocketsDir string
+
+	// MaxMind database for country matching (nil: none)
+	GeoIP *GeoIPConfig
+
+	// Host directory of every service's static files, mounted read-only
+	// at config.StaticMount (empty unless proxy.static is set)
+	StaticDir string
+
+	// Proxy image to run instead of CaddyImage (e.g. a Caddy build that
+	// includes caddy-l4 for Streams)
+	Image string
+
+	// Layer4 streams forwarded to the service's containers
+	Streams []StreamConfig
+}
+
+// StreamConfig describes a raw TCP or UDP stream that the proxy forwards
+// to the same containers as the service's HTTP route, using the caddy-l4
+// app. Stream upstreams follow every upstream change of the route, so they
+// take part in zero-downtime swaps during deploys.
+type StreamConfig struct {
+	// Caddy layer4 server name, unique across services on the proxy
+	Name string
+
+	// Port the proxy listens on
+	Listen int
+
+	// Container port connections are forwarded to
+	Port int
+
+	// Network protocol: tcp or udp
+	Protocol string
+}
+
+// listenAddress returns the caddy-l4 listener address of the stream.
+func (s StreamConfig) listenAddress() string {
+	if s.Protocol == "udp" {
+		return fmt.Sprintf("udp/:%d", s.Listen)
+	}
+	return fmt.Sprintf(":%d", s.Listen)
+}
+
+// publishPort returns the podman port mapping for the stream listener on ip,
+// or on every interface when ip is empty.
+func (s StreamConfig) publishPort(ip string) string {
+	return podman.PortSpec(ip, s.Listen, s.Listen) + "/" + s.protocol()
+}
+
+// protocol returns the network protocol of the stream, tcp by default.
+func (s StreamConfig) protocol() string {
+	if s.Protocol == "" {
+		return "tcp"
+	}
+	return s.Protocol
+}
+
+// bindAddresses returns the addresses the proxy on host publishes its ports
+// on: the configured ones, or "" for every interface.
+func (c *ProxyConfig) bindAddresses(host string) []string {
+	if c != nil && len(c.Bind[host]) > 0 {
+		return c.Bind[host]
+	}
+	return []string{""}
+}
+
+// dial maps an HTTP route upstream (host:port) to the stream port on the
+// same container. Unix socket upstreams have no stream equivalent.
+func (s StreamConfig) dial(upstream string) (string, bool) {
+	if strings.HasPrefix(upstream, "unix/") {
+		return "", false
+	}
+	host, _, err := net.SplitHostPort(upstream)
+	if err != nil || host == "" {
+		return "", false
+	}
+	addr := net.JoinHostPort(host, strconv.Itoa(s.Port))
+	if s.Protocol == "udp" {
+		addr = "udp/" + addr
+	}
+	return addr, true
 }
 
 // Boot starts the Caddy proxy on a host
@@ -377,6 +513,64 @@ func (m *Manager) Boot(host string, config *ProxyConfig) error {
 		}
 	}
 
+	if exists && config != nil && len(config.Streams) > 0 && !m.hostPorts {
+		published, inspectErr := m.podman.PublishedPorts(host, CaddyContainerName)
+		if inspectErr != nil {
+			return fmt.Errorf("failed to inspect proxy ports on %s: %w", host, inspectErr)
+		}
+		for _, stream := range config.Streams {
+			port := fmt.Sprintf("%d/%s", stream.Listen, stream.protocol())
+			if !slices.Contains(published, port) {
+				return fmt.Errorf("proxy on %s does not publish stream port %s; run 'azud proxy remove' and 'azud proxy boot' to recreate it", host, port)
+			}
+		}
+	}
+
+	if exists && config != nil && !m.hostPorts {
+		ips, inspectErr := m.podman.PortBindingIPs(host, CaddyContainerName, fmt.Sprintf("%d/tcp", CaddyHTTPSPort))
+		if inspectErr != nil {
+			return fmt.Errorf("failed to inspect proxy ports on %s: %w", host, inspectErr)
+		}
+		want := slices.Clone(config.bindAddresses(host))
+		sort.Strings(want)
+		if !slices.Equal(ips, want) {
+			return fmt.Errorf("proxy on %s is not published on the addresses of proxy.bind; run 'azud proxy remove' and 'azud proxy boot' to recreate it", host)
+		}
+	}
+
+	if exists && config != nil && config.GeoIP != nil {
+		source, inspectErr := m.podman.MountSource(host, CaddyContainerName, appconfig.GeoIPMount)
+		if inspectErr != nil {
+			return fmt.Errorf("failed to inspect proxy mounts on %s: %w", host, inspectErr)
+		}
+		if source == "" {
+			return fmt.Errorf("proxy on %s was created without the GeoIP database directory; run 'azud proxy remove' and 'azud proxy boot' to recreate it", host)
+		}
+		if err := m.ensureGeoIPDatabase(host, config.GeoIP); err != nil {
+			return err
+		}
+	}
+
+	if exists && config != nil && config.AppSocketsDir != "" {
+		source, inspectErr := m.podman.MountSource(host, CaddyContainerName, appconfig.AppSocketsMount)
+		if inspectErr != nil {
+			return fmt.Errorf("failed to inspect proxy mounts on %s: %w", host, inspectErr)
+		}
+		if source == "" {
+			return fmt.Errorf("proxy on %s was created without the app socket directory; run 'azud proxy remove' and 'azud proxy boot' to recreate it", host)
+		}
+	}
+
+	if exists && config != nil && config.StaticDir != "" {
+		source, inspectErr := m.podman.MountSource(host, CaddyContainerName, appconfig.StaticMount)
+		if inspectErr != nil {
+			return fmt.Errorf("failed to inspect proxy mounts on %s: %w", host, inspectErr)
+		}
+		if source == "" {
+			return fmt.Errorf("proxy on %s was created without the static file directory; run 'azud proxy remove' and 'azud proxy boot' to recreate it", host)
+		}
+	}
+
 	if running {
 		m.log.Host(host, "Proxy already running")
 		// Apply TLS/ACME and logging settings from deploy.yml while
@@ -397,7 +591,7 @@ func (m *Manager) Boot(host string, config *ProxyConfig) error {
 			return fmt.Errorf("failed to start proxy: %w", err)
 		}
 		// Wait for Caddy admin API to be ready, then restore persisted config
-		if err := m.waitForAdminAPI(host, 10*time.Second); err != nil {
+		if err := m.waitForAdminAPI(host, m.bootTimeout(config)); err != nil {
 			return err
 		}
 		if err := m.withPersistedMutation(host, func() error {
@@ -433,13 +627,18 @@ func (m *Manager) Boot(host string, config *ProxyConfig) error {
 		httpsPort = config.HTTPSPort
 	}
 
+	image := CaddyImage
+	if config != nil && config.Image != "" {
+		image = config.Image
+	}
+
 	containerConfig := &podman.ContainerConfig{
 		Name:    CaddyContainerName,
-		Image:   CaddyImage,
+		Image:   image,
 		Detach:  true,
 		Restart: "unless-stopped",
 		Volumes: []string{
-			"caddy_data:/data",
+			CaddyDataVolume + ":/data",
 			"caddy_config:/config",
 		},
 		Labels: map[string]string{
@@ -451,14 +650,45 @@ func (m *Manager) Boot(host string, config *ProxyConfig) error {
 			"CADDY_ADMIN": m.adminListen(),
 		},
 	}
+	if config != nil {
+		containerConfig.Memory = config.Memory
+		containerConfig.CPUs = config.CPUs
+		containerConfig.LogDriver = config.LogDriver
+		containerConfig.LogOpts = config.LogOpts
+	}
+	if config != nil && config.AppSocketsDir != "" {
+		containerConfig.HostDirs = []string{config.AppSocketsDir}
+		containerConfig.Volumes = append(containerConfig.Volumes, config.AppSocketsDir+":"+appconfig.AppSocketsMount+":z")
+	}
+	if config != nil && config.GeoIP != nil {
+		if err := m.ensureGeoIPDatabase(host, config.GeoIP); err != nil {
+			return err
+		}
+		containerConfig.HostDirs = append(containerConfig.HostDirs, config.GeoIP.Dir())
+		containerConfig.Volumes = append(containerConfig.Volumes, config.GeoIP.Dir()+":"+appconfig.GeoIPMount+":ro,z")
+	}
+	if config != nil && config.StaticDir != "" {
+		containerConfig.HostDirs = append(containerConfig.HostDirs, config.StaticDir)
+		containerConfig.Volumes = append(containerConfig.Volumes, config.StaticDir+":"+appconfig.StaticMount+":ro,z")
+	}
 	if m.hostPorts {
 		containerConfig.Network = "host"
 	} else {
 		containerConfig.Network = "azud"
-		containerConfig.Ports = []string{
-			fmt.Sprintf("%d:%d", httpPort, 80),
-			fmt.Sprintf("%d:%d", httpsPort, 443),
-			fmt.Sprintf("127.0.0.1:%d:%d", CaddyAdminPort, CaddyAdminPort),
+		binds := config.bindAddresses(host)
+		for _, ip := range binds {
+			containerConfig.Ports = append(containerConfig.Ports,
+				podman.PortSpec(ip, httpPort, CaddyHTTPPort),
+				podman.PortSpec(ip, httpsPort, CaddyHTTPSPort),
+			)
+		}
+		containerConfig.Ports = append(containerConfig.Ports, fmt.Sprintf("127.0.0.1:%d:%d", CaddyAdminPort, CaddyAdminPort))
+		if config != nil {
+			for _, stream := range config.Streams {
+				for _, ip := range binds {
+					containerConfig.Ports = append(containerConfig.Ports, stream.publishPort(ip))
+				}
+			}
 		}
 	}
 
@@ -468,7 +698,7 @@ func (m *Manager) Boot(host string, config *ProxyConfig) error {
 	}
 
 	// Wait for Caddy admin API to be ready
-	if err := m.waitForAdminAPI(host, 10*time.Second); err != nil {
+	if err := m.waitForAdminAPI(host, m.bootTimeout(config)); err != nil {
 		return err
 	}
 
@@ -584,6 +814,8 @@ func (m *Manager) applyProxySettingsFrom(caddyConfig *CaddyConfig, config *Proxy
 	server := caddyConfig.Apps.HTTP.Servers["srv0"]
 	server.Listen = proxyListenAddresses(config)
 
+	server.ListenerWrappers = proxyListenerWrappers(config)
+
 	switch {
 	case !config.AutoHTTPS:
 		server.AutoHTTPS = &AutoHTTPSConfig{Disable: true}
@@ -694,6 +926,23 @@ func proxyListenAddresses(config *ProxyConfig) []string {
 	}
 }
 
+// proxyProtocolTimeout bounds how long Caddy waits for the PROXY protocol
+// header of a new connection.
+const proxyProtocolTimeout = "5s"
+
+// proxyListenerWrappers returns the listener wrappers of the service's HTTP
+// server. The PROXY protocol header precedes the TLS handshake, so its
+// wrapper must come before Caddy's tls placeholder.
+func proxyListenerWrappers(config *ProxyConfig) []*ListenerWrapper {
+	if config == nil || !config.ProxyProtocol {
+		return nil
+	}
+	return []*ListenerWrapper{
+		{Wrapper: "proxy_protocol", Timeout: proxyProtocolTimeout, Allow: config.ProxyProtocolAllow},
+		{Wrapper: "tls"},
+	}
+}
+
 // Stop stops the Caddy proxy on a host
 func (m *Manager) Stop(host string) error {
 	m.log.Host(host, "Stopping proxy...")
@@ -722,7 +971,7 @@ func (m *Manager) Reboot(host string, config *ProxyConfig) error {
 	}
 
 	// Wait for Caddy admin API to be ready, then restore config and apply settings
-	if err := m.waitForAdminAPI(host, 10*time.Second); err != nil {
+	if err := m.waitForAdminAPI(host, m.bootTimeout(config)); err != nil {
 		return err
 	}
 	if err := m.withPersistedMutation(host, func() error {
@@ -861,7 +1110,22 @@ func (m *Manager) RegisterService(host string, service *ServiceConfig) error {
 			}
 		}
 
-		return nil
+		upstreams := service.Upstreams
+		if len(service.UpstreamWeights) > 0 {
+			upstreams = nil
+			for _, weighted := range service.UpstreamWeights {
+				upstreams = append(upstreams, weighted.Dial)
+			}
+		}
+		return m.updateStreams(host, func(stream StreamConfig, _ []string) []string {
+			var dials []string
+			for _, upstream := range upstreams {
+				if dial, ok := stream.dial(upstream); ok {
+					dials = appendIfMissing(dials, dial)
+				}
+			}
+			return dials
+		})
 	}); err != nil {
 		return err
 	}
@@ -971,7 +1235,7 @@ type ServiceConfig struct {
 	// Caddy's stock random policy with reduced repeated entries.
 	UpstreamWeights []UpstreamWeight
 
-	// Protocol used to communicate with upstreams: http, h2c, or https.
+	// Protocol used to communicate with upstreams: http, h2c, h2, or https.
 	UpstreamProtocol string
 
 	// Health check path for liveness (used by Caddy active health checks)
@@ -989,6 +1253,30 @@ type ServiceConfig struct {
 	// Response header timeout (maps to Caddy response_header_timeout)
 	ResponseHeaderTimeout string
 
+	// Request write timeout (maps to Caddy write_timeout)
+	WriteTimeout string
+
+	// Idle keep-alive connection timeout (maps to Caddy keep_alive.idle_timeout)
+	IdleTimeout string
+
+	// Upstream connect timeout (maps to Caddy dial_timeout)
+	DialTimeout string
+
+	// How long to retry other upstreams for a failed request (lb_try_duration)
+	TryDuration string
+
+	// Wait between upstream attempts (lb_try_interval)
+	TryInterval string
+
+	// Maximum retries per request (lb_retries)
+	Retries int
+
+	// Passive health check window (default: 30s)
+	FailDuration string
+
+	// Failures within FailDuration that mark an upstream down (default: 3)
+	MaxFails int
+
 	// Forward proxy headers to upstream
 	ForwardHeaders bool
 
@@ -998,6 +1286,11 @@ type ServiceConfig struct {
 	// Buffer responses
 	BufferResponses bool
 
+	// Concurrent requests each upstream accepts before Caddy treats it as
+	// unavailable, and connections Caddy opens to each upstream (0: no limit)
+	MaxRequests    int
+	MaxConnections int
+
 	// Maximum request body size (bytes)
 	MaxRequestBody int64
 
@@ -1006,6 +1299,28 @@ type ServiceConfig struct {
 
 	// Enable HTTPS
 	HTTPS bool
+
+	// Path of the GeoIP database in the proxy container, and the countries
+	// admitted or refused with 403 (set at most one list)
+	GeoIPDatabase  string
+	AllowCountries []string
+	DenyCountries  []string
+
+	// Record the upstream that served each request in the access log, so
+	// 'azud proxy logs --upstream' can filter on it
+	LogUpstream bool
+
+	// Path prefixes served from the service's static directory under
+	// config.StaticMount instead of the upstreams
+	Static []StaticRoute
+}
+
+// StaticRoute serves the files below Path from the directory of the same
+// path under Root. Requests for missing files reach the upstreams.
+type StaticRoute struct {
+	Path         string
+	Root         string
+	CacheControl string
 }
 
 func (m *Manager) buildServiceRoute(service *ServiceConfig) *Route {
@@ -1018,6 +1333,7 @@ func (m *Manager) buildServiceRoute(service *ServiceConfig) *Route {
 		upstreams = weightedUpstreams(service.UpstreamWeights...)
 		policy = "random"
 	}
+	limitUpstreams(upstreams, service.MaxRequests)
 
 	handler := &Handler{
 		ID:        serviceHandlerID(service.Name),
@@ -1027,6 +1343,9 @@ func (m *Manager) buildServiceRoute(service *ServiceConfig) *Route {
 			SelectionPolicy: &SelectionPolicy{
 				Policy: policy,
 			},
+			TryDuration: service.TryDuration,
+			TryInterval: service.TryInterval,
+			Retries:     service.Retries,
 		},
 	}
 
@@ -1051,24 +1370,42 @@ func (m *Manager) buildServiceRoute(service *ServiceConfig) *Route {
 				"X-Forwarded-Proto": {"https"},
 			}
 		}
+		failDuration := service.FailDuration
+		if failDuration == "" {
+			failDuration = "30s"
+		}
+		maxFails := service.MaxFails
+		if maxFails == 0 {
+			maxFails = 3
+		}
 		handler.HealthChecks = &HealthChecks{
 			Active: activeCheck,
 			Passive: &PassiveHealthCheck{
-				FailDuration: "30s",
-				MaxFails:     3,
+				FailDuration: failDuration,
+				MaxFails:     maxFails,
 			},
 		}
 	}
 
-	if service.ResponseTimeout != "" || service.ResponseHeaderTimeout != "" || service.UpstreamProtocol != "" {
+	if service.ResponseTimeout != "" || service.ResponseHeaderTimeout != "" || service.UpstreamProtocol != "" ||
+		service.WriteTimeout != "" || service.IdleTimeout != "" || service.DialTimeout != "" || service.MaxConnections > 0 {
 		transport := &Transport{
 			Protocol:              "http",
+			DialTimeout:           service.DialTimeout,
 			ReadTimeout:           service.ResponseTimeout,
 			ResponseHeaderTimeout: service.ResponseHeaderTimeout,
+			WriteTimeout:          service.WriteTimeout,
+			MaxConnsPerHost:       service.MaxConnections,
+		}
+		if service.IdleTimeout != "" {
+			transport.KeepAlive = &KeepAlive{IdleTimeout: service.IdleTimeout}
 		}
 		switch service.UpstreamProtocol {
 		case "h2c":
 			transport.Versions = []string{"h2c", "2"}
+		case "h2":
+			transport.Versions = []string{"2"}
+			transport.TLS = &UpstreamTLSConfig{}
 		case "https":
 			transport.TLS = &UpstreamTLSConfig{}
 		}
@@ -1093,6 +1430,9 @@ func (m *Manager) buildServiceRoute(service *ServiceConfig) *Route {
 	handler.BufferResponses = service.BufferResponses
 
 	var handlers []*Handler
+	if block := geolocationBlockHandler(service); block != nil {
+		handlers = append(handlers, block)
+	}
 	if service.MaxRequestBody > 0 || service.BufferMemory > 0 {
 		maxSize := service.MaxRequestBody
 		if maxSize == 0 {
@@ -1103,6 +1443,12 @@ func (m *Manager) buildServiceRoute(service *ServiceConfig) *Route {
 			MaxSize: maxSize,
 		})
 	}
+	if service.LogUpstream {
+		handlers = append(handlers, upstreamLogHandler())
+	}
+	if static := staticFilesHandler(service); static != nil {
+		handlers = append(handlers, static)
+	}
 	handlers = append(handlers, handler)
 
 	hostSet := make(map[string]bool)
@@ -1123,7 +1469,7 @@ func (m *Manager) buildServiceRoute(service *ServiceConfig) *Route {
 	}
 
 	route := &Route{
-		ID: serviceRouteID(service.Name),
+		ID: ServiceRouteID(service.Name),
 		Match: []*Match{
 			{Host: hostMatches},
 		},
@@ -1327,58 +1673,67 @@ func (m *Manager) DeregisterService(host, serviceHost string) error {
 	m.log.Host(host, "Deregistering service for %s...", serviceHost)
 
 	if err := m.withPersistedMutation(host, func() error {
-		// Try route-specific deletion first
-		routesPath := "/config/apps/http/servers/srv0/routes"
-		data, err := m.caddyClient.apiRequest(host, "GET", routesPath, nil)
-		if err == nil {
-			var routes []*Route
-			if jsonErr := json.Unmarshal(data, &routes); jsonErr == nil {
-				for i, r := range routes {
-					if routeMatchesHost(r, serviceHost) {
-						routePath := routeAPIPath(routesPath, i, r)
-						if _, delErr := m.caddyClient.apiRequest(host, "DELETE", routePath, nil); delErr == nil {
-							return nil
-						}
-						break
+		if err := m.deregisterRoute(host, serviceHost); err != nil {
+			return err
+		}
+		return m.updateStreams(host, func(StreamConfig, []string) []string { return nil })
+	}); err != nil {
+		return err
+	}
+
+	m.log.HostSuccess(host, "Service deregistered")
+	return nil
+}
+
+// deregisterRoute deletes the route for serviceHost, falling back to full
+// config replacement when the route-specific deletion fails.
+func (m *Manager) deregisterRoute(host, serviceHost string) error {
+	// Try route-specific deletion first
+	routesPath := "/config/apps/http/servers/srv0/routes"
+	data, err := m.caddyClient.apiRequest(host, "GET", routesPath, nil)
+	if err == nil {
+		var routes []*Route
+		if jsonErr := json.Unmarshal(data, &routes); jsonErr == nil {
+			for i, r := range routes {
+				if routeMatchesHost(r, serviceHost) {
+					routePath := routeAPIPath(routesPath, i, r)
+					if _, delErr := m.caddyClient.apiRequest(host, "DELETE", routePath, nil); delErr == nil {
+						return nil
 					}
+					break
 				}
 			}
 		}
+	}
 
-		// Fall back to full config replacement
-		m.log.Debug("Route-specific deregister failed, falling back to full config")
-		config, err := m.caddyClient.GetConfig(host)
-		if err != nil {
-			return fmt.Errorf("failed to get config: %w", err)
-		}
-
-		if config.Apps == nil || config.Apps.HTTP == nil {
-			return nil
-		}
+	// Fall back to full config replacement
+	m.log.Debug("Route-specific deregister failed, falling back to full config")
+	config, err := m.caddyClient.GetConfig(host)
+	if err != nil {
+		return fmt.Errorf("failed to get config: %w", err)
+	}
 
-		server := config.Apps.HTTP.Servers["srv0"]
-		if server == nil {
-			return nil
-		}
+	if config.Apps == nil || config.Apps.HTTP == nil {
+		return nil
+	}
 
-		var filtered []*Route
-		for _, r := range server.Routes {
-			if !routeMatchesHost(r, serviceHost) {
-				filtered = append(filtered, r)
-			}
-		}
-		server.Routes = filtered
+	server := config.Apps.HTTP.Servers["srv0"]
+	if server == nil {
+		return nil
+	}
 
-		if err := m.caddyClient.LoadConfig(host, config); err != nil {
-			return fmt.Errorf("failed to apply config: %w", err)
+	var filtered []*Route
+	for _, r := range server.Routes {
+		if !routeMatchesHost(r, serviceHost) {
+			filtered = append(filtered, r)
 		}
+	}
+	server.Routes = filtered
 
-		return nil
-	}); err != nil {
-		return err
+	if err := m.caddyClient.LoadConfig(host, config); err != nil {
+		return fmt.Errorf("failed to apply config: %w", err)
 	}
 
-	m.log.HostSuccess(host, "Service deregistered")
 	return nil
 }
 
@@ -1394,7 +1749,12 @@ func (m *Manager) AddUpstream(host, serviceHost, upstream string) error {
 			return err
 		}
 
-		return nil
+		return m.updateStreams(host, func(stream StreamConfig, dials []string) []string {
+			if dial, ok := stream.dial(upstream); ok {
+				return appendIfMissing(dials, dial)
+			}
+			return dials
+		})
 	}); err != nil {
 		return err
 	}
@@ -1409,7 +1769,102 @@ func addUpstreamIfMissing(upstreams []*Upstream, dial string) []*Upstream {
 			return upstreams
 		}
 	}
-	return append(upstreams, &Upstream{Dial: dial})
+	return append(upstreams, &Upstream{Dial: dial, MaxRequests: upstreamMaxRequests(upstreams)})
+}
+
+// upstreamMaxRequests returns the request limit of a route's upstreams, so
+// upstreams added to the route later get the same limit.
+func upstreamMaxRequests(upstreams []*Upstream) int {
+	for _, upstream := range upstreams {
+		if upstream.MaxRequests > 0 {
+			return upstream.MaxRequests
+		}
+	}
+	return 0
+}
+
+// limitUpstreams sets the request limit of every upstream.
+func limitUpstreams(upstreams []*Upstream, maxRequests int) {
+	for _, upstream := range upstreams {
+		upstream.MaxRequests = maxRequests
+	}
+}
+
+func appendIfMissing(values []string, value string) []string {
+	if slices.Contains(values, value) {
+		return values
+	}
+	return append(values, value)
+}
+
+// updateStreams applies transform to the dial addresses of every configured
+// layer4 stream and reloads the Caddy config. Stream servers are created on
+// first use and removed once they have no upstreams left. It is a no-op
+// when no streams are configured.
+func (m *Manager) updateStreams(host string, transform func(stream StreamConfig, dials []string) []string) error {
+	if m.proxyConfig == nil || len(m.proxyConfig.Streams) == 0 {
+		return nil
+	}
+	config, err := m.caddyClient.GetConfig(host)
+	if err != nil {
+		return fmt.Errorf("failed to get config: %w", err)
+	}
+	applyStreams(config, m.proxyConfig.Streams, transform)
+	if err := m.caddyClient.LoadConfig(host, config); err != nil {
+		return fmt.Errorf("failed to apply stream config: %w", err)
+	}
+	return nil
+}
+
+// applyStreams rewrites the layer4 servers of streams in config with the
+// dial addresses returned by transform.
+func applyStreams(config *CaddyConfig, streams []StreamConfig, transform func(stream StreamConfig, dials []string) []string) {
+	if config.Apps == nil {
+		config.Apps = &AppsConfig{}
+	}
+	if config.Apps.Layer4 == nil {
+		config.Apps.Layer4 = &Layer4App{}
+	}
+	if config.Apps.Layer4.Servers == nil {
+		config.Apps.Layer4.Servers = make(map[string]*Layer4Server)
+	}
+	servers := config.Apps.Layer4.Servers
+
+	for _, stream := range streams {
+		var dials []string
+		if server := servers[stream.Name]; server != nil {
+			for _, route := range server.Routes {
+				for _, handler := range route.Handle {
+					if handler == nil || handler.Handler != "proxy" {
+						continue
+					}
+					for _, upstream := range handler.Upstreams {
+						dials = append(dials, upstream.Dial...)
+					}
+				}
+			}
+		}
+
+		dials = transform(stream, dials)
+		if len(dials) == 0 {
+			delete(servers, stream.Name)
+			continue
+		}
+		upstreams := make([]*Layer4Upstream, 0, len(dials))
+		for _, dial := range dials {
+			upstreams = append(upstreams, &Layer4Upstream{Dial: []string{dial}})
+		}
+		servers[stream.Name] = &Layer4Server{
+			Listen: []string{stream.listenAddress()},
+			Routes: []*Layer4Route{{
+				Handle: []*Layer4Handler{{Handler: "proxy", Upstreams: upstreams}},
+			}},
+		}
+	}
+
+	if len(servers) == 0 {
+		config.Apps.Layer4 = nil
+	}
 }
 
 func reverseProxyHandler(route *Route) (*Handler, int, bool) {
@@ -1442,7 +1897,13 @@ func (m *Manager) RemoveUpstream(host, serviceHost, upstream string) error {
 			return err
 		}
 
-		return nil
+		return m.updateStreams(host, func(stream StreamConfig, dials []string) []string {
+			dial, ok := stream.dial(upstream)
+			if !ok {
+				return dials
+			}
+			return slices.DeleteFunc(dials, func(d string) bool { return d == dial })
+		})
 	}); err != nil {
 		return err
 	}
@@ -1559,7 +2020,7 @@ func (m *Manager) DrainUpstream(host, upstream string, timeout time.Duration) er
 			m.log.Debug("Cannot query upstream request count, waiting remaining timeout: %v", err)
 			remaining := time.Until(deadline)
 			if remaining > 0 {
-				time.Sleep(remaining)
+				return m.sshClient.Sleep(remaining)
 			}
 			return nil
 		}
@@ -1567,7 +2028,9 @@ func (m *Manager) DrainUpstream(host, upstream string, timeout time.Duration) er
 		if count > 0 {
 			everFoundActive = true
 			m.log.Debug("Upstream %s still has %d active request(s), waiting...", upstream, count)
-			time.Sleep(pollInterval)
+			if err := m.sshClient.Sleep(pollInterval); err != nil {
+				return err
+			}
 			continue
 		}
 
@@ -1581,7 +2044,9 @@ func (m *Manager) DrainUpstream(host, upstream string, timeout time.Duration) er
 		// Never saw active requests — Caddy may have already removed the
 		// upstream from its tracking. Apply the minimum grace period.
 		if time.Now().Before(graceDeadline) {
-			time.Sleep(pollInterval)
+			if err := m.sshClient.Sleep(pollInterval); err != nil {
+				return err
+			}
 			continue
 		}
 
@@ -1599,7 +2064,8 @@ func (m *Manager) GetUpstreamRequestCount(host, upstream string) (int, error) {
 }
 
 func routeMatchesHost(route *Route, host string) bool {
-	if route == nil {
+	// A/B routes share the service's hosts but never own them.
+	if route == nil || isABRoute(route) {
 		return false
 	}
 	for _, match := range route.Match {
@@ -1648,7 +2114,8 @@ func routesHaveSameOwner(existing, desired *Route, fallbackHost string) bool {
 	return existing != nil && existing.ID == "" && routeMatchesHost(existing, fallbackHost)
 }
 
-func serviceRouteID(service string) string {
+// ServiceRouteID returns the @id of the route Azud owns for service.
+func ServiceRouteID(service string) string {
 	if service == "" {
 		return ""
 	}
@@ -1745,10 +2212,12 @@ func (m *Manager) SetCanaryWeights(host, serviceHost, stable string, stableWeigh
 				transformErr = fmt.Errorf("stable upstream %s not found for service %s", stable, serviceHost)
 				return
 			}
+			maxRequests := upstreamMaxRequests(handler.Upstreams)
 			handler.Upstreams = weightedUpstreams(
 				UpstreamWeight{Dial: stable, Weight: stableWeight},
 				UpstreamWeight{Dial: canary, Weight: canaryWeight},
 			)
+			limitUpstreams(handler.Upstreams, maxRequests)
 			setStockWeightedPolicy(handler)
 		}); err != nil {
 			return err
@@ -156,12 +156,13 @@ func TestEnsureNoForeignHostOwnerChecksAllAliases(t *testing.T) {
 
 func TestBuildServiceRouteConfiguresUpstreamProtocol(t *testing.T) {
 	tests := []struct {
-		protocol string
-		wantH2C  bool
-		wantTLS  bool
+		protocol     string
+		wantVersions []string
+		wantTLS      bool
 	}{
 		{protocol: "http"},
-		{protocol: "h2c", wantH2C: true},
+		{protocol: "h2c", wantVersions: []string{"h2c", "2"}},
+		{protocol: "h2", wantVersions: []string{"2"}, wantTLS: true},
 		{protocol: "https", wantTLS: true},
 	}
 
@@ -177,14 +178,37 @@ func TestBuildServiceRouteConfiguresUpstreamProtocol(t *testing.T) {
 			if !ok || handler.Transport == nil || handler.Transport.Protocol != "http" {
 				t.Fatalf("transport = %#v, handler found = %t", handler.Transport, ok)
 			}
-			gotH2C := reflect.DeepEqual(handler.Transport.Versions, []string{"h2c", "2"})
-			if gotH2C != tt.wantH2C || (handler.Transport.TLS != nil) != tt.wantTLS {
+			if !reflect.DeepEqual(handler.Transport.Versions, tt.wantVersions) || (handler.Transport.TLS != nil) != tt.wantTLS {
 				t.Fatalf("transport = %#v", handler.Transport)
 			}
 		})
 	}
 }
 
+func TestBuildServiceRouteLimitsUpstreams(t *testing.T) {
+	route := (&Manager{}).buildServiceRoute(&ServiceConfig{
+		Name:            "shop",
+		Host:            "shop.example.com",
+		UpstreamWeights: []UpstreamWeight{{Dial: "shop:3000", Weight: 90}, {Dial: "shop-canary:3000", Weight: 10}},
+		MaxRequests:     50,
+		MaxConnections:  20,
+	})
+	handler, _, ok := reverseProxyHandler(route)
+	if !ok || handler.Transport == nil || handler.Transport.MaxConnsPerHost != 20 {
+		t.Fatalf("transport = %#v, handler found = %t", handler.Transport, ok)
+	}
+	for _, upstream := range handler.Upstreams {
+		if upstream.MaxRequests != 50 {
+			t.Fatalf("upstream %s max_requests = %d, want 50", upstream.Dial, upstream.MaxRequests)
+		}
+	}
+
+	added := addUpstreamIfMissing(handler.Upstreams, "shop-new:3000")
+	if last := added[len(added)-1]; last.Dial != "shop-new:3000" || last.MaxRequests != 50 {
+		t.Fatalf("added upstream = %#v, want the route's limit", last)
+	}
+}
+
 func TestAddUpstreamIfMissingIsIdempotent(t *testing.T) {
 	upstreams := []*Upstream{{Dial: "app:3000"}}
 	got := addUpstreamIfMissing(upstreams, "app:3000")
@@ -197,6 +221,109 @@ func TestAddUpstreamIfMissingIsIdempotent(t *testing.T) {
 	}
 }
 
+func TestStreamDialFollowsUpstreamContainer(t *testing.T) {
+	tcp := StreamConfig{Name: "smtp", Listen: 25, Port: 2525, Protocol: "tcp"}
+	udp := StreamConfig{Name: "dns", Listen: 53, Port: 5353, Protocol: "udp"}
+	tests := []struct {
+		stream   StreamConfig
+		upstream string
+		want     string
+		ok       bool
+	}{
+		{tcp, "shop-web:3000", "shop-web:2525", true},
+		{udp, "shop-web:3000", "udp/shop-web:5353", true},
+		{tcp, "[fd00::1]:3000", "[fd00::1]:2525", true},
+		{tcp, "unix//run/azud/sockets/shop/key/app.sock", "", false},
+		{tcp, "shop-web", "", false},
+	}
+	for _, tt := range tests {
+		got, ok := tt.stream.dial(tt.upstream)
+		if got != tt.want || ok != tt.ok {
+			t.Errorf("%s.dial(%q) = %q, %v; want %q, %v", tt.stream.Name, tt.upstream, got, ok, tt.want, tt.ok)
+		}
+	}
+	if got := udp.listenAddress(); got != "udp/:53" {
+		t.Errorf("udp listen address = %q", got)
+	}
+	if got := tcp.publishPort(""); got != "25:25/tcp" {
+		t.Errorf("tcp publish port = %q", got)
+	}
+	if got := udp.publishPort("fd00::1"); got != "[fd00::1]:53:53/udp" {
+		t.Errorf("udp publish port on bind address = %q", got)
+	}
+}
+
+func TestApplyStreamsUsesCaddyL4SchemaAndDropsEmptyServers(t *testing.T) {
+	stream := StreamConfig{Name: "azud-stream-shop-mqtt", Listen: 1883, Port: 1883, Protocol: "tcp"}
+	config := &CaddyConfig{}
+
+	applyStreams(config, []StreamConfig{stream}, func(_ StreamConfig, dials []string) []string {
+		return append(dials, "shop-web:1883")
+	})
+	applyStreams(config, []StreamConfig{stream}, func(_ StreamConfig, dials []string) []string {
+		return append(dials, "shop-web-new:1883")
+	})
+	data, err := json.Marshal(config.Apps.Layer4)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `{"servers":{"azud-stream-shop-mqtt":{"listen":[":1883"],"routes":[{"handle":[{"handler":"proxy","upstreams":[{"dial":["shop-web:1883"]},{"dial":["shop-web-new:1883"]}]}]}]}}}`
+	if string(data) != want {
+		t.Fatalf("layer4 config = %s\nwant %s", data, want)
+	}
+
+	applyStreams(config, []StreamConfig{stream}, func(StreamConfig, []string) []string { return nil })
+	if config.Apps.Layer4 != nil {
+		t.Fatalf("layer4 app should be removed with its last stream: %#v", config.Apps.Layer4)
+	}
+}
+
+func TestABRoutesPrecedeServiceRouteAndNeverOwnHosts(t *testing.T) {
+	m := &Manager{}
+	service := &ServiceConfig{Name: "shop", Host: "shop.example.com", Upstreams: []string{"shop:3000"}}
+	tests := []ABTest{
+		{Name: "staff", Header: "X-Staff", Value: "1"},
+		{Name: "beta", Cookie: "beta", Value: "on"},
+	}
+	abRoutes := m.buildABRoutes(service, tests, "shop-canary:3000")
+	routes := applyABRoutes([]*Route{m.buildServiceRoute(service)}, "shop", abRoutes)
+
+	if len(routes) != 3 || routes[0].ID != "azud-ab-shop:staff" || routes[1].ID != "azud-ab-shop:beta" || routes[2].ID != "azud-route-shop" {
+		t.Fatalf("route order = %#v", routes)
+	}
+	data, err := json.Marshal(routes[0].Match)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := `[{"host":["shop.example.com"],"header":{"X-Staff":["1"]}}]`; string(data) != want {
+		t.Fatalf("header match = %s\nwant %s", data, want)
+	}
+	data, err = json.Marshal(routes[1].Match)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := `[{"host":["shop.example.com"],"header_regexp":{"Cookie":{"name":"beta","pattern":"(^|;\\s*)beta=on(;|$)"}}}]`; string(data) != want {
+		t.Fatalf("cookie match = %s\nwant %s", data, want)
+	}
+	handler, _, ok := reverseProxyHandler(routes[0])
+	if !ok || handler.ID != "" || len(handler.Upstreams) != 1 || handler.Upstreams[0].Dial != "shop-canary:3000" {
+		t.Fatalf("A/B handler = %#v", handler)
+	}
+
+	if routeMatchesHost(routes[0], "shop.example.com") || !routeMatchesHost(routes[2], "shop.example.com") {
+		t.Fatal("only the service route may match its host")
+	}
+	if err := ensureNoForeignHostOwner(routes, m.buildServiceRoute(service)); err != nil {
+		t.Fatalf("A/B routes must not be foreign owners: %v", err)
+	}
+
+	other := &Route{ID: "azud-ab-shop-admin:staff"}
+	cleared := applyABRoutes(append(routes, other), "shop", nil)
+	if len(cleared) != 2 || cleared[0].ID != "azud-route-shop" || cleared[1] != other {
+		t.Fatalf("cleared routes = %#v", cleared)
+	}
+}
+
 func TestWeightedUpstreamsUseStockCaddySchema(t *testing.T) {
 	upstreams := weightedUpstreams(
 		UpstreamWeight{Dial: "stable:3000", Weight: 90},
@@ -219,6 +346,61 @@ func TestWeightedUpstreamsUseStockCaddySchema(t *testing.T) {
 	}
 }
 
+func TestTransportTimeoutsUseStockCaddySchema(t *testing.T) {
+	route := (&Manager{}).buildServiceRoute(&ServiceConfig{
+		Host:         "app.example.com",
+		Upstreams:    []string{"app:3000"},
+		WriteTimeout: "30s",
+		IdleTimeout:  "90s",
+		DialTimeout:  "2s",
+	})
+	handler, _, ok := reverseProxyHandler(route)
+	if !ok {
+		t.Fatal("generated route is missing reverse_proxy handler")
+	}
+	data, err := json.Marshal(handler.Transport)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := `{"protocol":"http","dial_timeout":"2s","write_timeout":"30s","keep_alive":{"idle_timeout":"90s"}}`; string(data) != want {
+		t.Fatalf("transport = %s\nwant %s", data, want)
+	}
+}
+
+func TestFailoverSettingsUseStockCaddySchema(t *testing.T) {
+	route := (&Manager{}).buildServiceRoute(&ServiceConfig{
+		Host:         "app.example.com",
+		Upstreams:    []string{"app:3000"},
+		HealthPath:   "/up",
+		TryDuration:  "5s",
+		TryInterval:  "100ms",
+		Retries:      2,
+		FailDuration: "10s",
+		MaxFails:     5,
+	})
+	handler, _, ok := reverseProxyHandler(route)
+	if !ok {
+		t.Fatal("generated route is missing reverse_proxy handler")
+	}
+	lb, err := json.Marshal(handler.LoadBalancing)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := `{"selection_policy":{"policy":"round_robin"},"try_duration":"5s","try_interval":"100ms","retries":2}`; string(lb) != want {
+		t.Fatalf("load_balancing = %s\nwant %s", lb, want)
+	}
+	passive := handler.HealthChecks.Passive
+	if passive.FailDuration != "10s" || passive.MaxFails != 5 {
+		t.Fatalf("passive health check = %#v", passive)
+	}
+
+	route = (&Manager{}).buildServiceRoute(&ServiceConfig{Host: "app.example.com", Upstreams: []string{"app:3000"}, HealthPath: "/up"})
+	handler, _, _ = reverseProxyHandler(route)
+	if passive := handler.HealthChecks.Passive; passive.FailDuration != "30s" || passive.MaxFails != 3 {
+		t.Fatalf("default passive health check = %#v", passive)
+	}
+}
+
 func TestReverseProxyHeadersUseStockCaddySchema(t *testing.T) {
 	route := (&Manager{}).buildServiceRoute(&ServiceConfig{
 		Host:      "app.example.com",
@@ -335,6 +517,25 @@ func TestApplyProxySettingsUsesProtocolCorrectListeners(t *testing.T) {
 	}
 }
 
+func TestApplyProxySettingsProxyProtocol(t *testing.T) {
+	manager := &Manager{}
+	cfg := manager.buildBaseConfig()
+	manager.applyProxySettingsFrom(cfg, &ProxyConfig{AutoHTTPS: true, ProxyProtocol: true, ProxyProtocolAllow: []string{"10.0.0.0/8"}})
+
+	wrappers := cfg.Apps.HTTP.Servers["srv0"].ListenerWrappers
+	if len(wrappers) != 2 || wrappers[0].Wrapper != "proxy_protocol" || wrappers[1].Wrapper != "tls" {
+		t.Fatalf("listener wrappers = %#v, want proxy_protocol before tls", wrappers)
+	}
+	if !slices.Equal(wrappers[0].Allow, []string{"10.0.0.0/8"}) {
+		t.Fatalf("allow = %v", wrappers[0].Allow)
+	}
+
+	manager.applyProxySettingsFrom(cfg, &ProxyConfig{AutoHTTPS: true})
+	if wrappers := cfg.Apps.HTTP.Servers["srv0"].ListenerWrappers; wrappers != nil {
+		t.Fatalf("disabling proxy_protocol kept listener wrappers: %#v", wrappers)
+	}
+}
+
 func TestPersistConfigCommandsProtectPrivateCaddyState(t *testing.T) {
 	tests := []struct {
 		name string
@@ -0,0 +1,29 @@
+package proxy
+
+// staticFilesHandler serves the service's static paths from disk with their
+// Cache-Control header. A request only matches when its file exists, so
+// anything else, such as a dynamic page below the same prefix, still
+// reaches the upstreams.
+func staticFilesHandler(service *ServiceConfig) *Handler {
+	if len(service.Static) == 0 {
+		return nil
+	}
+	routes := make([]*Route, 0, len(service.Static))
+	for _, static := range service.Static {
+		routes = append(routes, &Route{
+			Match: []*Match{{
+				Path: []string{static.Path + "/*"},
+				File: &FileMatch{Root: static.Root, TryFiles: []string{"{http.request.uri.path}"}},
+			}},
+			Handle: []*Handler{
+				{
+					Handler:  "headers",
+					Response: &HeaderOps{Set: map[string][]string{"Cache-Control": {static.CacheControl}}},
+				},
+				{Handler: "file_server", Root: static.Root},
+			},
+			Terminal: true,
+		})
+	}
+	return &Handler{Handler: "subroute", Routes: routes}
+}
@@ -0,0 +1,46 @@
+package proxy
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestBuildServiceRouteServesStaticPaths(t *testing.T) {
+	route := (&Manager{}).buildServiceRoute(&ServiceConfig{
+		Name:      "shop",
+		Host:      "shop.example.com",
+		Upstreams: []string{"shop:3000"},
+		Static: []StaticRoute{
+			{Path: "/assets", Root: "/srv/azud/static/shop", CacheControl: "public, max-age=31536000, immutable"},
+		},
+	})
+	if len(route.Handle) != 2 || route.Handle[0].Handler != "subroute" || route.Handle[1].Handler != "reverse_proxy" {
+		t.Fatalf("handlers = %#v", route.Handle)
+	}
+	static := route.Handle[0].Routes
+	if len(static) != 1 || !static[0].Terminal {
+		t.Fatalf("static routes = %#v", static)
+	}
+	match := static[0].Match[0]
+	if !reflect.DeepEqual(match.Path, []string{"/assets/*"}) || match.File == nil || match.File.Root != "/srv/azud/static/shop" {
+		t.Fatalf("match = %#v", match)
+	}
+	handle := static[0].Handle
+	if len(handle) != 2 || handle[0].Handler != "headers" || handle[1].Handler != "file_server" || handle[1].Root != "/srv/azud/static/shop" {
+		t.Fatalf("handle = %#v", handle)
+	}
+	if got := handle[0].Response.Set["Cache-Control"]; !reflect.DeepEqual(got, []string{"public, max-age=31536000, immutable"}) {
+		t.Fatalf("Cache-Control = %v", got)
+	}
+}
+
+func TestBuildServiceRouteWithoutStaticPaths(t *testing.T) {
+	route := (&Manager{}).buildServiceRoute(&ServiceConfig{
+		Name:      "shop",
+		Host:      "shop.example.com",
+		Upstreams: []string{"shop:3000"},
+	})
+	if len(route.Handle) != 1 || route.Handle[0].Handler != "reverse_proxy" {
+		t.Fatalf("handlers = %#v", route.Handle)
+	}
+}
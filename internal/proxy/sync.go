@@ -0,0 +1,171 @@
+package proxy
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+)
+
+// srv0RoutesPath is the admin API path of the routes of Azud's HTTP server.
+const srv0RoutesPath = "/config/apps/http/servers/srv0/routes"
+
+// RouteSet holds the routes of one proxy host as Caddy returns them, keyed
+// by RouteKey.
+type RouteSet map[string]json.RawMessage
+
+// RouteKey identifies a route across proxy hosts: its @id, or its matchers
+// for routes without one.
+func RouteKey(route json.RawMessage) string {
+	var fields struct {
+		ID    string          `json:"@id"`
+		Match json.RawMessage `json:"match"`
+	}
+	if err := json.Unmarshal(route, &fields); err != nil {
+		return ""
+	}
+	if fields.ID != "" {
+		return fields.ID
+	}
+	return "match " + canonicalJSON(fields.Match, false)
+}
+
+// Routes returns the routes of the proxy on host. Unlike the modelled
+// config, the routes keep every field, so they can be copied to another
+// host unchanged.
+func (m *Manager) Routes(host string) (RouteSet, error) {
+	data, err := m.caddyClient.apiRequest(host, "GET", srv0RoutesPath, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get routes: %w", err)
+	}
+	var routes []json.RawMessage
+	if err := json.Unmarshal(data, &routes); err != nil {
+		return nil, fmt.Errorf("failed to parse routes: %w", err)
+	}
+	set := make(RouteSet, len(routes))
+	for _, route := range routes {
+		if key := RouteKey(route); key != "" {
+			set[key] = route
+		}
+	}
+	return set, nil
+}
+
+// CopyRoute puts route into the routes of the proxy on host, replacing the
+// route with the same key or appending it.
+func (m *Manager) CopyRoute(host string, route json.RawMessage) error {
+	key := RouteKey(route)
+	return m.withPersistedMutation(host, func() error {
+		data, err := m.caddyClient.apiRequest(host, "GET", srv0RoutesPath, nil)
+		if err != nil {
+			return fmt.Errorf("failed to get routes: %w", err)
+		}
+		var routes []json.RawMessage
+		if err := json.Unmarshal(data, &routes); err != nil {
+			return fmt.Errorf("failed to parse routes: %w", err)
+		}
+		for i, existing := range routes {
+			if RouteKey(existing) == key {
+				_, err := m.caddyClient.apiRequest(host, "PATCH", fmt.Sprintf("%s/%d", srv0RoutesPath, i), route)
+				return err
+			}
+		}
+		var payload interface{} = route
+		if routes == nil {
+			payload = []json.RawMessage{route}
+		}
+		_, err = m.caddyClient.apiRequest(host, "POST", srv0RoutesPath, payload)
+		return err
+	})
+}
+
+// RouteDrift is a route that is not the same on every proxy host.
+type RouteDrift struct {
+	Key string
+	// Source holds the reference copy: the one most hosts have
+	Source string
+	Route  json.RawMessage
+	// Missing and Differs list the hosts without the route and with
+	// another copy of it
+	Missing []string
+	Differs []string
+}
+
+// CompareRouteSets returns the routes that differ between the proxy hosts,
+// sorted by key. The copy held by most hosts is the reference, ties going to
+// the earlier host. Without sameUpstreams, each host's proxy balances to its
+// own containers, so upstreams are ignored and only the route set and the
+// rest of each route are compared.
+func CompareRouteSets(hosts []string, sets map[string]RouteSet, sameUpstreams bool) []RouteDrift {
+	keys := make(map[string]struct{})
+	for _, host := range hosts {
+		for key := range sets[host] {
+			keys[key] = struct{}{}
+		}
+	}
+
+	var drifts []RouteDrift
+	for key := range keys {
+		counts := make(map[string]int)
+		canonical := make(map[string]string, len(hosts))
+		for _, host := range hosts {
+			if route, ok := sets[host][key]; ok {
+				canonical[host] = canonicalJSON(route, !sameUpstreams)
+				counts[canonical[host]]++
+			}
+		}
+
+		drift := RouteDrift{Key: key}
+		reference := ""
+		for _, host := range hosts {
+			if c, ok := canonical[host]; ok && counts[c] > counts[reference] {
+				reference, drift.Source, drift.Route = c, host, sets[host][key]
+			}
+		}
+		for _, host := range hosts {
+			c, ok := canonical[host]
+			switch {
+			case !ok:
+				drift.Missing = append(drift.Missing, host)
+			case c != reference:
+				drift.Differs = append(drift.Differs, host)
+			}
+		}
+		if len(drift.Missing)+len(drift.Differs) > 0 {
+			drifts = append(drifts, drift)
+		}
+	}
+	sort.Slice(drifts, func(i, j int) bool { return drifts[i].Key < drifts[j].Key })
+	return drifts
+}
+
+// canonicalJSON re-encodes data with sorted keys, dropping upstreams when
+// asked, so equal routes compare equal as strings.
+func canonicalJSON(data json.RawMessage, dropUpstreams bool) string {
+	var value interface{}
+	if err := json.Unmarshal(data, &value); err != nil {
+		return string(data)
+	}
+	if dropUpstreams {
+		value = withoutUpstreams(value)
+	}
+	out, err := json.Marshal(value)
+	if err != nil {
+		return string(data)
+	}
+	return string(out)
+}
+
+func withoutUpstreams(value interface{}) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		delete(v, "upstreams")
+		for key, child := range v {
+			v[key] = withoutUpstreams(child)
+		}
+	case []interface{}:
+		for i, child := range v {
+			v[i] = withoutUpstreams(child)
+		}
+	}
+	return value
+}
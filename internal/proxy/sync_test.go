@@ -0,0 +1,50 @@
+package proxy
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+func TestRouteKey(t *testing.T) {
+	tests := []struct {
+		route string
+		want  string
+	}{
+		{`{"@id":"azud-route-app","match":[{"host":["app.example.com"]}]}`, "azud-route-app"},
+		{`{"match":[{"host":["b.example.com"]}],"handle":[]}`, `match [{"host":["b.example.com"]}]`},
+		{`not json`, ""},
+	}
+	for _, tt := range tests {
+		if got := RouteKey(json.RawMessage(tt.route)); got != tt.want {
+			t.Errorf("RouteKey(%s) = %q, want %q", tt.route, got, tt.want)
+		}
+	}
+}
+
+func TestCompareRouteSets(t *testing.T) {
+	route := func(id, dial string) json.RawMessage {
+		return json.RawMessage(`{"@id":"` + id + `","handle":[{"handler":"reverse_proxy","upstreams":[{"dial":"` + dial + `"}]}]}`)
+	}
+	hosts := []string{"lb1", "lb2", "lb3"}
+	sets := map[string]RouteSet{
+		"lb1": {"app": route("app", "10.0.0.1:80"), "api": route("api", "10.0.0.5:80")},
+		"lb2": {"app": route("app", "10.0.0.2:80"), "api": json.RawMessage(`{"handle":[{"upstreams":[{"dial":"10.0.0.5:80"}],"handler":"reverse_proxy"}],"@id":"api"}`)},
+		"lb3": {"app": route("app", "10.0.0.2:80")},
+	}
+
+	got := CompareRouteSets(hosts, sets, true)
+	want := []RouteDrift{
+		{Key: "api", Source: "lb1", Route: sets["lb1"]["api"], Missing: []string{"lb3"}},
+		{Key: "app", Source: "lb2", Route: sets["lb2"]["app"], Differs: []string{"lb1"}},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("CompareRouteSets() = %+v, want %+v", got, want)
+	}
+
+	got = CompareRouteSets(hosts, sets, false)
+	want = want[:1]
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("CompareRouteSets() ignoring upstreams = %+v, want %+v", got, want)
+	}
+}
@@ -15,6 +15,7 @@ type ContainerUnit struct {
 	ContainerName   string
 	Environment     map[string]string
 	EnvironmentFile []string
+	Secret          []string
 	PublishPort     []string
 	Volume          []string
 	Network         []string
@@ -22,6 +23,7 @@ type ContainerUnit struct {
 	HealthCmd       string
 	HealthInterval  string
 	Exec            string
+	Entrypoint      string
 	PodmanArgs      []string
 	Restart         string // systemd restart policy: always, on-failure
 	TimeoutStopSec  int
@@ -89,6 +91,9 @@ func GenerateContainerFile(unit *ContainerUnit) string {
 	for _, file := range unit.EnvironmentFile {
 		_, _ = fmt.Fprintf(&sb, "EnvironmentFile=%s\n", quoteSystemdWord(file, false))
 	}
+	for _, secret := range unit.Secret {
+		_, _ = fmt.Fprintf(&sb, "Secret=%s\n", sanitizeINIValue(secret))
+	}
 	for _, port := range unit.PublishPort {
 		_, _ = fmt.Fprintf(&sb, "PublishPort=%s\n", sanitizeINIValue(port))
 	}
@@ -116,6 +121,9 @@ func GenerateContainerFile(unit *ContainerUnit) string {
 	if unit.Exec != "" {
 		_, _ = fmt.Fprintf(&sb, "Exec=%s\n", sanitizeINIValue(unit.Exec))
 	}
+	if unit.Entrypoint != "" {
+		_, _ = fmt.Fprintf(&sb, "PodmanArgs=%s\n", quoteSystemdWord("--entrypoint="+unit.Entrypoint, true))
+	}
 	for _, arg := range unit.PodmanArgs {
 		_, _ = fmt.Fprintf(&sb, "PodmanArgs=%s\n", sanitizeINIValue(arg))
 	}
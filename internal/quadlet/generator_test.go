@@ -46,6 +46,7 @@ func TestGenerateContainerFile_Full(t *testing.T) {
 			"PORT": "3000",
 		},
 		EnvironmentFile: []string{"/etc/secrets.env"},
+		Secret:          []string{"azud-myapp-API_KEY,target=API_KEY"},
 		PublishPort:     []string{"8080:3000"},
 		Volume:          []string{"/data:/app/data"},
 		Network:         []string{"azud"},
@@ -69,6 +70,7 @@ func TestGenerateContainerFile_Full(t *testing.T) {
 		"ContainerName=myapp",
 		`Environment="PORT=3000"`,
 		`EnvironmentFile="/etc/secrets.env"`,
+		"Secret=azud-myapp-API_KEY,target=API_KEY",
 		"PublishPort=8080:3000",
 		"Volume=/data:/app/data",
 		"Network=azud",
@@ -105,6 +107,18 @@ func TestGenerateContainerFileQuotesAndOrdersEnvironment(t *testing.T) {
 	}
 }
 
+func TestGenerateContainerFileQuotesEntrypoint(t *testing.T) {
+	unit := &ContainerUnit{Image: "app", Entrypoint: `/sbin/tini -- "run"`, PodmanArgs: []string{"--init"}}
+	result := GenerateContainerFile(unit)
+	want := `PodmanArgs="--entrypoint=/sbin/tini -- \"run\""`
+	if !strings.Contains(result, want) {
+		t.Fatalf("entrypoint was not safely encoded:\n%s", result)
+	}
+	if strings.Index(result, want) > strings.Index(result, "PodmanArgs=--init") {
+		t.Fatalf("entrypoint should precede other podman args:\n%s", result)
+	}
+}
+
 func TestGenerateContainerFile_DefaultRestart(t *testing.T) {
 	unit := &ContainerUnit{
 		Image: "nginx:latest",
@@ -5,6 +5,8 @@ import (
 	"strings"
 
 	"github.com/lemonity-org/azud/internal/output"
+	"github.com/lemonity-org/azud/internal/podman"
+	"github.com/lemonity-org/azud/internal/shell"
 	"github.com/lemonity-org/azud/internal/ssh"
 )
 
@@ -13,6 +15,9 @@ type Bootstrapper struct {
 	sshClient *ssh.Client
 	log       *output.Logger
 	backend   string
+	ipv6      bool
+	networks  []podman.NetworkConfig
+	age       bool
 }
 
 func NewBootstrapper(sshClient *ssh.Client, log *output.Logger, networkBackend string) *Bootstrapper {
@@ -26,6 +31,22 @@ func NewBootstrapper(sshClient *ssh.Client, log *output.Logger, networkBackend s
 	}
 }
 
+// SetIPv6 makes the azud network dual-stack when it is created.
+func (b *Bootstrapper) SetIPv6(enabled bool) {
+	b.ipv6 = enabled
+}
+
+// SetNetworks sets additional Podman networks to create. An entry named
+// azud customizes the default network, which is always created.
+func (b *Bootstrapper) SetNetworks(networks []podman.NetworkConfig) {
+	b.networks = networks
+}
+
+// SetAge installs age for encrypted remote secrets during bootstrap.
+func (b *Bootstrapper) SetAge(enabled bool) {
+	b.age = enabled
+}
+
 // Bootstrap installs Podman, configures the network backend, and creates
 // the azud network on the given host.
 func (b *Bootstrapper) Bootstrap(host string) error {
@@ -61,10 +82,19 @@ func (b *Bootstrapper) Bootstrap(host string) error {
 		return fmt.Errorf("failed to configure Podman: %w", err)
 	}
 
-	// Create azud network if it doesn't exist
-	b.log.Host(host, "Setting up azud network...")
-	if err := b.createNetwork(host); err != nil {
-		return fmt.Errorf("failed to create network: %w", err)
+	if b.age {
+		b.log.Host(host, "Installing age for encrypted secrets...")
+		if err := b.installAge(host, osInfo); err != nil {
+			return fmt.Errorf("failed to install age: %w", err)
+		}
+	}
+
+	// Create azud and configured networks if they don't exist
+	b.log.Host(host, "Setting up networks...")
+	for _, network := range b.networkConfigs() {
+		if err := b.createNetwork(host, network); err != nil {
+			return fmt.Errorf("failed to create network %s: %w", network.Name, err)
+		}
 	}
 
 	b.log.HostSuccess(host, "Bootstrap complete")
@@ -198,6 +228,38 @@ func (b *Bootstrapper) installPodman(host string, osInfo *OSInfo) error {
 	return nil
 }
 
+func (b *Bootstrapper) installAge(host string, osInfo *OSInfo) error {
+	result, err := b.sshClient.Execute(host, "command -v age >/dev/null 2>&1 && command -v age-keygen >/dev/null 2>&1")
+	if err == nil && result.ExitCode == 0 {
+		return nil
+	}
+
+	prefix, err := b.privilegePrefix(host)
+	if err != nil {
+		return err
+	}
+	var installCmd string
+	switch osInfo.Family {
+	case "debian":
+		installCmd = fmt.Sprintf("%sapt-get update && %sapt-get install -y age", prefix, prefix)
+	case "rhel":
+		installCmd = fmt.Sprintf("%sdnf install -y age", prefix)
+	case "alpine":
+		installCmd = fmt.Sprintf("%sapk add --update age", prefix)
+	default:
+		return fmt.Errorf("unsupported OS family: %s", osInfo.Family)
+	}
+
+	result, err = b.sshClient.Execute(host, installCmd)
+	if err != nil {
+		return err
+	}
+	if result.ExitCode != 0 {
+		return fmt.Errorf("%s", result.Stderr)
+	}
+	return nil
+}
+
 func (b *Bootstrapper) privilegePrefix(host string) (string, error) {
 	result, err := b.sshClient.Execute(host, "id -u")
 	if err != nil {
@@ -277,18 +339,34 @@ network_backend = %q
 	return nil
 }
 
-func (b *Bootstrapper) createNetwork(host string) error {
-	result, err := b.sshClient.Execute(host, "podman network inspect azud >/dev/null 2>&1 && echo 'exists'")
+// networkConfigs returns the azud network first, followed by the other
+// configured networks.
+func (b *Bootstrapper) networkConfigs() []podman.NetworkConfig {
+	azud := podman.NetworkConfig{Name: "azud"}
+	var others []podman.NetworkConfig
+	for _, network := range b.networks {
+		if network.Name == "azud" {
+			azud = network
+			continue
+		}
+		others = append(others, network)
+	}
+	azud.IPv6 = azud.IPv6 || b.ipv6
+	return append([]podman.NetworkConfig{azud}, others...)
+}
+
+func (b *Bootstrapper) createNetwork(host string, network podman.NetworkConfig) error {
+	result, err := b.sshClient.Execute(host, fmt.Sprintf("podman network inspect %s >/dev/null 2>&1 && echo 'exists'", shell.Quote(network.Name)))
 	if err != nil {
 		return err
 	}
 
 	if strings.Contains(result.Stdout, "exists") {
-		b.log.Debug("Network azud already exists on %s", host)
+		b.log.Debug("Network %s already exists on %s", network.Name, host)
 		return nil
 	}
 
-	result, err = b.sshClient.Execute(host, "podman network create azud")
+	result, err = b.sshClient.Execute(host, network.BuildCreateCommand())
 	if err != nil {
 		return err
 	}
@@ -0,0 +1,190 @@
+package server
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/lemonity-org/azud/internal/ssh"
+)
+
+const (
+	wireGuardInterface = "wg0"
+	wireGuardDir       = "/etc/wireguard"
+	wireGuardKeyFile   = wireGuardDir + "/azud.key"
+)
+
+// MeshPeer describes one host's membership in the WireGuard mesh.
+type MeshPeer struct {
+	Host      string
+	Address   string
+	PublicKey string
+}
+
+// SetupWireGuard installs WireGuard on every host, generates a key pair on
+// each host (private keys never leave the machine), and brings up a wg0
+// interface that peers with every other host. addresses maps each SSH host
+// to its mesh address; prefixLen is the mesh subnet prefix length.
+func (b *Bootstrapper) SetupWireGuard(addresses map[string]string, prefixLen, port int) error {
+	hosts := make([]string, 0, len(addresses))
+	for host := range addresses {
+		hosts = append(hosts, host)
+	}
+	sort.Strings(hosts)
+
+	b.log.Header("WireGuard mesh / %d host(s)", len(hosts))
+
+	peers := make([]MeshPeer, 0, len(hosts))
+	for _, host := range hosts {
+		publicKey, err := b.ensureWireGuardKey(host)
+		if err != nil {
+			return fmt.Errorf("%s: %w", host, err)
+		}
+		peers = append(peers, MeshPeer{Host: host, Address: addresses[host], PublicKey: publicKey})
+	}
+
+	var errors []string
+	for _, self := range peers {
+		conf := BuildWireGuardConfig(self, peers, prefixLen, port)
+		if err := b.applyWireGuardConfig(self.Host, conf, port); err != nil {
+			b.log.HostError(self.Host, "WireGuard setup failed: %v", err)
+			errors = append(errors, fmt.Sprintf("%s: %v", self.Host, err))
+			continue
+		}
+		b.log.HostSuccess(self.Host, "wg0 up at %s", self.Address)
+	}
+	if len(errors) > 0 {
+		return fmt.Errorf("wireguard setup failed on some hosts: %s", strings.Join(errors, "; "))
+	}
+
+	b.log.Success("WireGuard mesh configured")
+	return nil
+}
+
+// BuildWireGuardConfig renders the wg-quick configuration for self. The
+// private key is loaded from disk by PostUp so it is never part of the
+// rendered file.
+func BuildWireGuardConfig(self MeshPeer, peers []MeshPeer, prefixLen, port int) string {
+	var sb strings.Builder
+	sb.WriteString("# Managed by azud. Changes will be overwritten.\n")
+	sb.WriteString("[Interface]\n")
+	fmt.Fprintf(&sb, "Address = %s/%d\n", self.Address, prefixLen)
+	fmt.Fprintf(&sb, "ListenPort = %d\n", port)
+	fmt.Fprintf(&sb, "PostUp = wg set %%i private-key %s\n", wireGuardKeyFile)
+
+	for _, peer := range peers {
+		if peer.Host == self.Host {
+			continue
+		}
+		sb.WriteString("\n[Peer]\n")
+		fmt.Fprintf(&sb, "# %s\n", peer.Host)
+		fmt.Fprintf(&sb, "PublicKey = %s\n", peer.PublicKey)
+		fmt.Fprintf(&sb, "Endpoint = %s\n", ssh.JoinHostPort(peer.Host, port))
+		fmt.Fprintf(&sb, "AllowedIPs = %s/32\n", peer.Address)
+		sb.WriteString("PersistentKeepalive = 25\n")
+	}
+	return sb.String()
+}
+
+func (b *Bootstrapper) ensureWireGuardKey(host string) (string, error) {
+	prefix, err := b.privilegePrefix(host)
+	if err != nil {
+		return "", err
+	}
+
+	installed, err := b.sshClient.Execute(host, "command -v wg >/dev/null 2>&1")
+	if err != nil || installed.ExitCode != 0 {
+		b.log.Host(host, "Installing WireGuard...")
+		if err := b.installWireGuard(host, prefix); err != nil {
+			return "", fmt.Errorf("failed to install WireGuard: %w", err)
+		}
+	}
+
+	keyCmd := fmt.Sprintf(
+		"%ssh -c 'umask 077 && mkdir -p %s && { [ -s %s ] || wg genkey > %s; } && wg pubkey < %s'",
+		prefix, wireGuardDir, wireGuardKeyFile, wireGuardKeyFile, wireGuardKeyFile,
+	)
+	result, err := b.sshClient.Execute(host, keyCmd)
+	if err != nil {
+		return "", fmt.Errorf("failed to generate WireGuard key: %w", err)
+	}
+	if result.ExitCode != 0 {
+		return "", fmt.Errorf("failed to generate WireGuard key: %s", result.Stderr)
+	}
+	publicKey := strings.TrimSpace(result.Stdout)
+	if publicKey == "" {
+		return "", fmt.Errorf("failed to read WireGuard public key")
+	}
+	return publicKey, nil
+}
+
+func (b *Bootstrapper) installWireGuard(host, prefix string) error {
+	osInfo, err := b.detectOS(host)
+	if err != nil {
+		return err
+	}
+
+	var installCmd string
+	switch {
+	case strings.Contains(osInfo.Family, "debian"):
+		installCmd = fmt.Sprintf("%sapt-get update && %sapt-get install -y wireguard-tools", prefix, prefix)
+	case strings.Contains(osInfo.Family, "rhel"), strings.Contains(osInfo.Family, "fedora"):
+		installCmd = fmt.Sprintf("%sdnf install -y wireguard-tools", prefix)
+	case strings.Contains(osInfo.Family, "alpine"):
+		installCmd = fmt.Sprintf("%sapk add --update wireguard-tools", prefix)
+	default:
+		return fmt.Errorf("unsupported OS family: %s", osInfo.Family)
+	}
+
+	result, err := b.sshClient.Execute(host, installCmd)
+	if err != nil {
+		return err
+	}
+	if result.ExitCode != 0 {
+		return fmt.Errorf("%s", result.Stderr)
+	}
+	return nil
+}
+
+func (b *Bootstrapper) applyWireGuardConfig(host, conf string, port int) error {
+	prefix, err := b.privilegePrefix(host)
+	if err != nil {
+		return err
+	}
+
+	confPath := fmt.Sprintf("%s/%s.conf", wireGuardDir, wireGuardInterface)
+	writeCmd := fmt.Sprintf("%ssh -c 'umask 077 && cat > %s'", prefix, confPath)
+	result, err := b.sshClient.ExecuteWithStdin(host, writeCmd, strings.NewReader(conf))
+	if err != nil {
+		return err
+	}
+	if result.ExitCode != 0 {
+		return fmt.Errorf("failed to write %s: %s", confPath, result.Stderr)
+	}
+
+	// Open the listen port when ufw is active; other firewalls are left to
+	// the operator.
+	firewallCmd := fmt.Sprintf(
+		"if command -v ufw >/dev/null 2>&1 && %sufw status 2>/dev/null | grep -q 'Status: active'; then %sufw allow %d/udp >/dev/null; fi",
+		prefix, prefix, port,
+	)
+	if _, err := b.sshClient.Execute(host, firewallCmd); err != nil {
+		b.log.Debug("Could not update firewall on %s: %v", host, err)
+	}
+
+	// Reload peers in place when the interface is already up so existing
+	// inter-host connections are not dropped.
+	unit := "wg-quick@" + wireGuardInterface
+	upCmd := fmt.Sprintf(
+		"if %[1]swg show %[2]s >/dev/null 2>&1; then %[1]ssh -c 'wg-quick strip %[2]s > %[3]s/.%[2]s.stripped && wg syncconf %[2]s %[3]s/.%[2]s.stripped; rc=$?; rm -f %[3]s/.%[2]s.stripped; exit $rc' && %[1]ssystemctl enable %[4]s >/dev/null 2>&1; else %[1]ssystemctl enable --now %[4]s; fi",
+		prefix, wireGuardInterface, wireGuardDir, unit,
+	)
+	result, err = b.sshClient.Execute(host, upCmd)
+	if err != nil {
+		return err
+	}
+	if result.ExitCode != 0 {
+		return fmt.Errorf("failed to bring up %s: %s", wireGuardInterface, result.Stderr)
+	}
+	return nil
+}
@@ -0,0 +1,34 @@
+package server
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestBuildWireGuardConfigPeersWithEveryOtherHost(t *testing.T) {
+	peers := []MeshPeer{
+		{Host: "203.0.113.10", Address: "10.77.0.1", PublicKey: "key-a"},
+		{Host: "2001:db8::20", Address: "10.77.0.2", PublicKey: "key-b"},
+		{Host: "203.0.113.30", Address: "10.77.0.3", PublicKey: "key-c"},
+	}
+
+	conf := BuildWireGuardConfig(peers[0], peers, 24, 51820)
+
+	for _, want := range []string{
+		"Address = 10.77.0.1/24",
+		"ListenPort = 51820",
+		"PostUp = wg set %i private-key /etc/wireguard/azud.key",
+		"Endpoint = [2001:db8::20]:51820",
+		"AllowedIPs = 10.77.0.3/32",
+	} {
+		if !strings.Contains(conf, want) {
+			t.Errorf("config missing %q:\n%s", want, conf)
+		}
+	}
+	if strings.Contains(conf, "key-a") || strings.Contains(conf, "PrivateKey") {
+		t.Fatalf("config must not peer with itself or embed a private key:\n%s", conf)
+	}
+	if got := strings.Count(conf, "[Peer]"); got != 2 {
+		t.Fatalf("peer sections = %d, want 2", got)
+	}
+}
@@ -0,0 +1,39 @@
+package shell
+
+import (
+	"context"
+	"os/exec"
+	"runtime"
+	"strings"
+)
+
+// shellOperators are the characters that make a command line need a shell:
+// pipes, lists, redirections, expansions, globs, and grouping.
+const shellOperators = "&|;<>$`*?~(){}[]\n"
+
+// Command returns a command running the command line s on the local
+// machine. Lines without shell features run directly after splitting like
+// Split, so they work without a shell. Other lines run in sh -c, or on
+// Windows machines without sh on PATH (Git for Windows provides one) in
+// cmd.exe.
+func Command(ctx context.Context, s string) *exec.Cmd {
+	argv := commandArgv(s, runtime.GOOS == "windows", exec.LookPath)
+	return exec.CommandContext(ctx, argv[0], argv[1:]...)
+}
+
+func commandArgv(s string, windows bool, lookPath func(string) (string, error)) []string {
+	// Backslashes are path separators on Windows, not escapes.
+	direct := !strings.ContainsAny(s, shellOperators) && !(windows && strings.Contains(s, `\`))
+	if direct {
+		if words, err := Split(s); err == nil && len(words) > 0 {
+			return words
+		}
+	}
+	if !windows {
+		return []string{"sh", "-c", s}
+	}
+	if sh, err := lookPath("sh"); err == nil {
+		return []string{sh, "-c", s}
+	}
+	return []string{"cmd.exe", "/d", "/s", "/c", s}
+}
@@ -0,0 +1,34 @@
+package shell
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestCommandArgv(t *testing.T) {
+	noSh := func(string) (string, error) { return "", errors.New("not found") }
+	gitSh := func(string) (string, error) { return `C:\Program Files\Git\usr\bin\sh.exe`, nil }
+
+	tests := []struct {
+		name     string
+		line     string
+		windows  bool
+		lookPath func(string) (string, error)
+		want     []string
+	}{
+		{name: "plain line runs directly", line: `op inject -i "secrets tpl"`, lookPath: noSh, want: []string{"op", "inject", "-i", "secrets tpl"}},
+		{name: "plain line on windows", line: "vault kv get -format=json app", windows: true, lookPath: noSh, want: []string{"vault", "kv", "get", "-format=json", "app"}},
+		{name: "pipeline uses sh", line: "cat .env | grep -v '^#'", lookPath: noSh, want: []string{"sh", "-c", "cat .env | grep -v '^#'"}},
+		{name: "pipeline on windows with git sh", line: "cat .env | sort", windows: true, lookPath: gitSh, want: []string{`C:\Program Files\Git\usr\bin\sh.exe`, "-c", "cat .env | sort"}},
+		{name: "windows path falls back to cmd", line: `C:\tools\op.exe read op://app/env`, windows: true, lookPath: noSh, want: []string{"cmd.exe", "/d", "/s", "/c", `C:\tools\op.exe read op://app/env`}},
+		{name: "unbalanced quote uses shell", line: `echo "open`, lookPath: noSh, want: []string{"sh", "-c", `echo "open`}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := commandArgv(tt.line, tt.windows, tt.lookPath); !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("commandArgv(%q) = %q, want %q", tt.line, got, tt.want)
+			}
+		})
+	}
+}
@@ -2,6 +2,7 @@
 package shell
 
 import (
+	"fmt"
 	"regexp"
 	"strings"
 )
@@ -92,3 +93,67 @@ func ValidateName(s string) bool {
 	}
 	return namePattern.MatchString(s)
 }
+
+// Split breaks s into words the way a POSIX shell would, honoring single
+// quotes, double quotes, and backslash escapes. It performs no expansion.
+//
+// Examples:
+//
+//	Split(`app exec --reuse "bin/rails console"`) -> ["app" "exec" "--reuse" "bin/rails console"]
+//	Split(`echo 'it''s'`)                         -> ["echo" "its"]
+func Split(s string) ([]string, error) {
+	var words []string
+	var word strings.Builder
+	inWord := false
+	var quote rune
+	escaped := false
+
+	for _, r := range s {
+		switch {
+		case escaped:
+			word.WriteRune(r)
+			escaped = false
+		case quote == '\'':
+			if r == '\'' {
+				quote = 0
+			} else {
+				word.WriteRune(r)
+			}
+		case quote == '"':
+			switch r {
+			case '"':
+				quote = 0
+			case '\\':
+				escaped = true
+			default:
+				word.WriteRune(r)
+			}
+		case r == '\\':
+			escaped = true
+			inWord = true
+		case r == '\'' || r == '"':
+			quote = r
+			inWord = true
+		case r == ' ' || r == '\t' || r == '\n':
+			if inWord {
+				words = append(words, word.String())
+				word.Reset()
+				inWord = false
+			}
+		default:
+			word.WriteRune(r)
+			inWord = true
+		}
+	}
+
+	if escaped {
+		return nil, fmt.Errorf("trailing backslash in %q", s)
+	}
+	if quote != 0 {
+		return nil, fmt.Errorf("unterminated %c quote in %q", quote, s)
+	}
+	if inWord {
+		words = append(words, word.String())
+	}
+	return words, nil
+}
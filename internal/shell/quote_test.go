@@ -1,6 +1,7 @@
 package shell
 
 import (
+	"reflect"
 	"testing"
 )
 
@@ -223,3 +224,31 @@ func TestValidateName(t *testing.T) {
 		})
 	}
 }
+
+func TestSplit(t *testing.T) {
+	tests := []struct {
+		input string
+		want  []string
+	}{
+		{`app exec --reuse "bin/rails console"`, []string{"app", "exec", "--reuse", "bin/rails console"}},
+		{`logs  -f   'web app'`, []string{"logs", "-f", "web app"}},
+		{`echo it\'s ""`, []string{"echo", "it's", ""}},
+		{`say "a \"quoted\" word"`, []string{"say", `a "quoted" word`}},
+		{"", nil},
+	}
+	for _, tt := range tests {
+		got, err := Split(tt.input)
+		if err != nil {
+			t.Fatalf("Split(%q) error: %v", tt.input, err)
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("Split(%q) = %q, want %q", tt.input, got, tt.want)
+		}
+	}
+
+	for _, input := range []string{`"open`, `'open`, `trailing\`} {
+		if _, err := Split(input); err == nil {
+			t.Errorf("Split(%q) expected an error", input)
+		}
+	}
+}
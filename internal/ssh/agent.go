@@ -0,0 +1,144 @@
+package ssh
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/agent"
+)
+
+// agentSocket returns the SSH agent socket to use, or "" without an agent.
+func (c *Client) agentSocket() string {
+	if c.config.AgentSocket != "" {
+		return expandPath(c.config.AgentSocket)
+	}
+	if socket := os.Getenv("SSH_AUTH_SOCK"); socket != "" {
+		return socket
+	}
+	return defaultAgentSocket
+}
+
+// dialAgent connects to the SSH agent.
+func (c *Client) dialAgent() (agent.ExtendedAgent, func(), error) {
+	socket := c.agentSocket()
+	if socket == "" {
+		return nil, nil, fmt.Errorf("no SSH agent: SSH_AUTH_SOCK is not set")
+	}
+	conn, err := dialAgentSocket(socket)
+	if err != nil {
+		return nil, nil, err
+	}
+	return agent.NewClient(conn), func() { _ = conn.Close() }, nil
+}
+
+// agentHasKey reports whether the SSH agent holds the key of public.
+func (c *Client) agentHasKey(public ssh.PublicKey) bool {
+	keyring, closeAgent, err := c.dialAgent()
+	if err != nil {
+		return false
+	}
+	defer closeAgent()
+	keys, err := keyring.List()
+	if err != nil {
+		return false
+	}
+	want := public.Marshal()
+	for _, key := range keys {
+		if bytes.Equal(key.Blob, want) {
+			return true
+		}
+	}
+	return false
+}
+
+// addKeyToAgent adds the decrypted private key raw to the SSH agent.
+func (c *Client) addKeyToAgent(raw any, path string) error {
+	keyring, closeAgent, err := c.dialAgent()
+	if err != nil {
+		return err
+	}
+	defer closeAgent()
+	return keyring.Add(agent.AddedKey{PrivateKey: raw, Comment: path})
+}
+
+// isSecurityKey reports whether public belongs to a FIDO2 security key
+// (sk-ssh-ed25519, sk-ecdsa-sha2-nistp256), which signs only on the device.
+func isSecurityKey(public ssh.PublicKey) bool {
+	switch public.Type() {
+	case ssh.KeyAlgoSKED25519, ssh.KeyAlgoSKECDSA256:
+		return true
+	}
+	return false
+}
+
+// agentSigners returns the signers of keyring with errors that say why the
+// agent refused to sign. Hardware-backed keys fail when the touch or PIN
+// they require is not given, which the agent reports only as a failure.
+func agentSigners(keyring agent.ExtendedAgent) func() ([]ssh.Signer, error) {
+	return func() ([]ssh.Signer, error) {
+		signers, err := keyring.Signers()
+		if err != nil {
+			return nil, err
+		}
+		for i, signer := range signers {
+			wrapped := &agentSigner{Signer: signer}
+			switch algorithmSigner := signer.(type) {
+			case ssh.MultiAlgorithmSigner:
+				signers[i] = &agentMultiSigner{agentAlgorithmSigner{wrapped, algorithmSigner}}
+			case ssh.AlgorithmSigner:
+				signers[i] = &agentAlgorithmSigner{wrapped, algorithmSigner}
+			default:
+				signers[i] = wrapped
+			}
+		}
+		return signers, nil
+	}
+}
+
+// agentSigner explains signing failures of a key held by the SSH agent.
+type agentSigner struct {
+	ssh.Signer
+}
+
+func (s *agentSigner) Sign(rand io.Reader, data []byte) (*ssh.Signature, error) {
+	signature, err := s.Signer.Sign(rand, data)
+	return signature, s.explain(err)
+}
+
+func (s *agentSigner) explain(err error) error {
+	if err == nil {
+		return nil
+	}
+	public := s.PublicKey()
+	if isSecurityKey(public) {
+		return fmt.Errorf("security key %s did not sign: touch it when it blinks, and check it is plugged in (%w)", ssh.FingerprintSHA256(public), err)
+	}
+	return fmt.Errorf("ssh-agent could not sign with %s key %s; hardware tokens may need their PIN or a touch (%w)",
+		strings.TrimPrefix(public.Type(), "ssh-"), ssh.FingerprintSHA256(public), err)
+}
+
+// agentAlgorithmSigner is an agentSigner that keeps the signature algorithm
+// choice of keys such as RSA, which sign with rsa-sha2-256 and -512.
+type agentAlgorithmSigner struct {
+	*agentSigner
+	algorithmSigner ssh.AlgorithmSigner
+}
+
+func (s *agentAlgorithmSigner) SignWithAlgorithm(rand io.Reader, data []byte, algorithm string) (*ssh.Signature, error) {
+	signature, err := s.algorithmSigner.SignWithAlgorithm(rand, data, algorithm)
+	return signature, s.explain(err)
+}
+
+// agentMultiSigner is an agentAlgorithmSigner limited to the algorithms the
+// key supports.
+type agentMultiSigner struct {
+	agentAlgorithmSigner
+}
+
+func (s *agentMultiSigner) Algorithms() []string {
+	return s.algorithmSigner.(ssh.MultiAlgorithmSigner).Algorithms()
+}
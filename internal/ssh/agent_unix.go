@@ -0,0 +1,15 @@
+//go:build !windows
+
+package ssh
+
+import (
+	"io"
+	"net"
+)
+
+// defaultAgentSocket is the agent used when SSH_AUTH_SOCK is not set.
+const defaultAgentSocket = ""
+
+func dialAgentSocket(socket string) (io.ReadWriteCloser, error) {
+	return net.Dial("unix", socket)
+}
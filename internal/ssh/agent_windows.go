@@ -0,0 +1,23 @@
+//go:build windows
+
+package ssh
+
+import (
+	"io"
+	"net"
+	"os"
+	"strings"
+)
+
+// defaultAgentSocket is the named pipe of the Windows OpenSSH agent service,
+// used when SSH_AUTH_SOCK is not set.
+const defaultAgentSocket = `\\.\pipe\openssh-ssh-agent`
+
+// dialAgentSocket connects to a named pipe agent (Windows OpenSSH,
+// 1Password) or a Unix socket one (Git for Windows, WSL bridges).
+func dialAgentSocket(socket string) (io.ReadWriteCloser, error) {
+	if strings.HasPrefix(socket, `\\.\pipe\`) {
+		return os.OpenFile(socket, os.O_RDWR, 0)
+	}
+	return net.Dial("unix", socket)
+}
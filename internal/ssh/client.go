@@ -2,12 +2,14 @@ package ssh
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"io"
 	"net"
 	"os"
 	"os/user"
 	"path/filepath"
+	"strconv"
 	"strings"
 	"sync"
 	"time"
@@ -17,14 +19,23 @@ import (
 	"golang.org/x/crypto/ssh/knownhosts"
 )
 
-// Client manages SSH connections to remote hosts
+// Client manages SSH connections to remote hosts. Clients derived with
+// WithContext share connections but bound their own commands.
 type Client struct {
+	*clientState
+	ctx context.Context
+}
+
+type clientState struct {
 	config     *Config
 	pool       *Pool
-	agentConn  net.Conn   // SSH agent connection, closed on Client.Close()
-	mu         sync.Mutex // protects agentConn
+	agentConn  io.ReadWriteCloser // SSH agent connection, closed on Client.Close()
+	mu         sync.Mutex         // protects agentConn
 	connectMu  sync.Mutex
 	connecting map[string]*connectCall
+	sessions   chan struct{} // global session slots, nil when unbounded
+	keyMu      sync.Mutex    // serializes key loading, so passphrases are asked once
+	keys       map[string]loadedKey
 }
 
 type connectCall struct {
@@ -69,8 +80,38 @@ type Config struct {
 
 	// Skip host key verification (not recommended for production)
 	InsecureIgnoreHostKey bool
+
+	// Prefer IPv6 addresses when a hostname resolves to both families
+	PreferIPv6 bool
+
+	// Maximum concurrent sessions across all hosts (0 = unbounded)
+	MaxSessions int
+
+	// Maximum concurrent sessions on one host (default: 8)
+	MaxSessionsPerHost int
+
+	// Retries of connections and sessions that fail transiently, and of
+	// idempotent operations such as image pulls (zero value: no retries)
+	Retry RetryPolicy
+
+	// Passphrase returns the passphrase of the encrypted private key at
+	// path. Encrypted keys the SSH agent does not hold are skipped when it
+	// is nil.
+	Passphrase func(path string) ([]byte, error)
+
+	// Add keys decrypted with Passphrase to the SSH agent, so later runs
+	// use the agent instead of asking again
+	AddKeysToAgent bool
+
+	// SSH agent socket (default: SSH_AUTH_SOCK), e.g. of yubikey-agent or
+	// another agent holding hardware-backed keys
+	AgentSocket string
 }
 
+// DefaultMaxSessionsPerHost bounds the concurrent sessions on one host when
+// Config.MaxSessionsPerHost is not set.
+const DefaultMaxSessionsPerHost = 8
+
 // ProxyConfig holds SSH proxy/bastion configuration
 type ProxyConfig struct {
 	Host string
@@ -93,11 +134,53 @@ func NewClient(cfg *Config) *Client {
 	if cfg.ConnectTimeout == 0 {
 		cfg.ConnectTimeout = 30 * time.Second
 	}
+	if cfg.MaxSessionsPerHost == 0 {
+		cfg.MaxSessionsPerHost = DefaultMaxSessionsPerHost
+	}
 
-	return &Client{
+	state := &clientState{
 		config:     cfg,
 		pool:       NewPool(),
 		connecting: make(map[string]*connectCall),
+		keys:       make(map[string]loadedKey),
+	}
+	if cfg.MaxSessions > 0 {
+		state.sessions = make(chan struct{}, cfg.MaxSessions)
+	}
+	return &Client{clientState: state, ctx: cfg.Context}
+}
+
+// WithContext returns a client that shares this client's connections but
+// runs commands, lock waits, and new connection attempts under ctx instead
+// of the configured Context, so a deadline or cancellation aborts them
+// mid-command. The command timeout still applies.
+func (c *Client) WithContext(ctx context.Context) *Client {
+	if c == nil {
+		return nil
+	}
+	return &Client{clientState: c.clientState, ctx: ctx}
+}
+
+// Context returns the context bounding this client's operations.
+func (c *Client) Context() context.Context {
+	if c == nil || c.ctx == nil {
+		return context.Background()
+	}
+	return c.ctx
+}
+
+// Sleep pauses for d, returning early with the context's error when the
+// client's context ends first. Polling loops use it so cancellation is not
+// held up by a wait between remote commands.
+func (c *Client) Sleep(d time.Duration) error {
+	ctx := c.Context()
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
 	}
 }
 
@@ -118,8 +201,8 @@ func (c *Client) Connect(host string) (*Connection, error) {
 		select {
 		case <-call.done:
 			return call.conn, call.err
-		case <-c.config.Context.Done():
-			return nil, c.config.Context.Err()
+		case <-c.Context().Done():
+			return nil, c.Context().Err()
 		}
 	}
 	call := &connectCall{done: make(chan struct{})}
@@ -141,7 +224,7 @@ func (c *Client) connect(host string) (*Connection, error) {
 		return conn, nil
 	}
 
-	ctx := c.config.Context
+	ctx := c.Context()
 	if c.config.ConnectTimeout > 0 {
 		var cancel context.CancelFunc
 		ctx, cancel = context.WithTimeout(ctx, c.config.ConnectTimeout)
@@ -175,7 +258,8 @@ func (c *Client) connect(host string) (*Connection, error) {
 		lastUsed:       time.Now(),
 		commandTimeout: c.config.CommandTimeout,
 		context:        c.config.Context,
-		sessions:       make(chan struct{}, 8),
+		sessions:       make(chan struct{}, c.config.MaxSessionsPerHost),
+		global:         c.sessions,
 	}
 
 	c.pool.Put(host, conn)
@@ -184,8 +268,8 @@ func (c *Client) connect(host string) (*Connection, error) {
 
 // connectDirect establishes a direct SSH connection
 func (c *Client) connectDirect(ctx context.Context, host string, sshConfig *ssh.ClientConfig) (*ssh.Client, error) {
-	addr := fmt.Sprintf("%s:%d", host, c.config.Port) // safe: net.Dial address, not a shell command
-	client, err := dialSSHContext(ctx, addr, sshConfig)
+	addr := JoinHostPort(host, c.config.Port)
+	client, err := dialSSHContext(ctx, c.dialAddress(ctx, host, c.config.Port), addr, sshConfig)
 	if err != nil {
 		return nil, fmt.Errorf("failed to connect to %s: %w", addr, err)
 	}
@@ -207,15 +291,15 @@ func (c *Client) connectViaProxy(ctx context.Context, host string, sshConfig *ss
 	if proxyPort == 0 {
 		proxyPort = 22
 	}
-	proxyAddr := fmt.Sprintf("%s:%d", c.config.Proxy.Host, proxyPort) // safe: net.Dial address, not a shell command
+	proxyAddr := JoinHostPort(c.config.Proxy.Host, proxyPort)
 
-	proxyClient, err := dialSSHContext(ctx, proxyAddr, proxyConfig)
+	proxyClient, err := dialSSHContext(ctx, c.dialAddress(ctx, c.config.Proxy.Host, proxyPort), proxyAddr, proxyConfig)
 	if err != nil {
 		return nil, nil, fmt.Errorf("failed to connect to proxy %s: %w", proxyAddr, err)
 	}
 
 	// Connect to target through proxy
-	targetAddr := fmt.Sprintf("%s:%d", host, c.config.Port) // safe: SSH transport address, not a shell command
+	targetAddr := JoinHostPort(host, c.config.Port)
 	type dialResult struct {
 		conn net.Conn
 		err  error
@@ -248,8 +332,10 @@ func (c *Client) connectViaProxy(ctx context.Context, host string, sshConfig *ss
 	return ssh.NewClient(ncc, chans, reqs), proxyClient, nil
 }
 
-func dialSSHContext(ctx context.Context, addr string, cfg *ssh.ClientConfig) (*ssh.Client, error) {
-	conn, err := (&net.Dialer{}).DialContext(ctx, "tcp", addr)
+// dialSSHContext connects to dialAddr and performs the SSH handshake using
+// addr, the configured host:port, for host key verification.
+func dialSSHContext(ctx context.Context, dialAddr, addr string, cfg *ssh.ClientConfig) (*ssh.Client, error) {
+	conn, err := (&net.Dialer{}).DialContext(ctx, "tcp", dialAddr)
 	if err != nil {
 		return nil, err
 	}
@@ -261,6 +347,41 @@ func dialSSHContext(ctx context.Context, addr string, cfg *ssh.ClientConfig) (*s
 	return ssh.NewClient(ncc, chans, reqs), nil
 }
 
+// JoinHostPort formats a host and port as a dial address. IPv6 literals are
+// bracketed (e.g. [2001:db8::1]:22), and hosts already written in brackets
+// are not bracketed twice.
+func JoinHostPort(host string, port int) string {
+	return net.JoinHostPort(TrimBrackets(host), strconv.Itoa(port))
+}
+
+// TrimBrackets strips the brackets from an IPv6 literal such as [::1].
+func TrimBrackets(host string) string {
+	if strings.HasPrefix(host, "[") && strings.HasSuffix(host, "]") {
+		return host[1 : len(host)-1]
+	}
+	return host
+}
+
+// dialAddress returns the address to dial for host. With PreferIPv6 set, a
+// hostname that resolves to an IPv6 address is dialed over IPv6; otherwise
+// the system resolver's default ordering applies.
+func (c *Client) dialAddress(ctx context.Context, host string, port int) string {
+	host = TrimBrackets(host)
+	if !c.config.PreferIPv6 || net.ParseIP(host) != nil {
+		return JoinHostPort(host, port)
+	}
+	addrs, err := net.DefaultResolver.LookupIPAddr(ctx, host)
+	if err != nil {
+		return JoinHostPort(host, port)
+	}
+	for _, addr := range addrs {
+		if addr.IP.To4() == nil && addr.IP.To16() != nil {
+			return JoinHostPort(addr.IP.String(), port)
+		}
+	}
+	return JoinHostPort(host, port)
+}
+
 func newSSHClientConnContext(ctx context.Context, conn net.Conn, addr string, cfg *ssh.ClientConfig) (ssh.Conn, <-chan ssh.NewChannel, <-chan *ssh.Request, error) {
 	done := make(chan struct{})
 	go func() {
@@ -338,6 +459,7 @@ func (c *Client) getAuthMethods(keyPaths []string) ([]ssh.AuthMethod, error) {
 	}
 
 	// Add key file authentication
+	var skipped []string
 	for _, keyPath := range keyPaths {
 		expandedPath := expandPath(keyPath)
 		if _, err := os.Stat(expandedPath); os.IsNotExist(err) {
@@ -346,9 +468,12 @@ func (c *Client) getAuthMethods(keyPaths []string) ([]ssh.AuthMethod, error) {
 
 		signer, err := c.loadPrivateKey(expandedPath)
 		if err != nil {
+			skipped = append(skipped, err.Error())
 			continue
 		}
-		authMethods = append(authMethods, ssh.PublicKeys(signer))
+		if signer != nil {
+			authMethods = append(authMethods, ssh.PublicKeys(signer))
+		}
 	}
 
 	// Try default key locations if no keys specified
@@ -366,13 +491,19 @@ func (c *Client) getAuthMethods(keyPaths []string) ([]ssh.AuthMethod, error) {
 
 			signer, err := c.loadPrivateKey(expandedPath)
 			if err != nil {
+				skipped = append(skipped, err.Error())
 				continue
 			}
-			authMethods = append(authMethods, ssh.PublicKeys(signer))
+			if signer != nil {
+				authMethods = append(authMethods, ssh.PublicKeys(signer))
+			}
 		}
 	}
 
 	if len(authMethods) == 0 {
+		if len(skipped) > 0 {
+			return nil, fmt.Errorf("no authentication methods available: %s", strings.Join(skipped, "; "))
+		}
 		return nil, fmt.Errorf("no authentication methods available")
 	}
 
@@ -386,16 +517,15 @@ func (c *Client) getAgentAuth() ssh.AuthMethod {
 	defer c.mu.Unlock()
 	// If we already have an agent connection, reuse it
 	if c.agentConn != nil {
-		agentClient := agent.NewClient(c.agentConn)
-		return ssh.PublicKeysCallback(agentClient.Signers)
+		return ssh.PublicKeysCallback(agentSigners(agent.NewClient(c.agentConn)))
 	}
 
-	socket := os.Getenv("SSH_AUTH_SOCK")
+	socket := c.agentSocket()
 	if socket == "" {
 		return nil
 	}
 
-	conn, err := net.Dial("unix", socket)
+	conn, err := dialAgentSocket(socket)
 	if err != nil {
 		return nil
 	}
@@ -412,24 +542,7 @@ func (c *Client) getAgentAuth() ssh.AuthMethod {
 	// Store the connection to close it later
 	c.agentConn = conn
 
-	return ssh.PublicKeysCallback(agentClient.Signers)
-}
-
-// loadPrivateKey loads a private key from a file
-func (c *Client) loadPrivateKey(path string) (ssh.Signer, error) {
-	key, err := os.ReadFile(path)
-	if err != nil {
-		return nil, fmt.Errorf("failed to read key file %s: %w", path, err)
-	}
-
-	signer, err := ssh.ParsePrivateKey(key)
-	if err != nil {
-		// Try with empty passphrase first, then give up
-		// In a real implementation, we'd prompt for passphrase
-		return nil, fmt.Errorf("failed to parse key file %s: %w", path, err)
-	}
-
-	return signer, nil
+	return ssh.PublicKeysCallback(agentSigners(agentClient))
 }
 
 // getHostKeyCallback returns the host key callback function.
@@ -442,10 +555,7 @@ func (c *Client) getHostKeyCallback() (ssh.HostKeyCallback, error) {
 
 	// Prepare known_hosts callback as fallback
 	var knownHostsCallback ssh.HostKeyCallback
-	knownHostsPath := c.config.KnownHostsFile
-	if knownHostsPath == "" {
-		knownHostsPath = expandPath("~/.ssh/known_hosts")
-	}
+	knownHostsPath := KnownHostsPath(c.config.KnownHostsFile)
 
 	// Check if known_hosts file exists
 	if _, err := os.Stat(knownHostsPath); os.IsNotExist(err) {
@@ -495,12 +605,16 @@ func (c *Client) fingerprintCheckingCallback(fallback ssh.HostKeyCallback) ssh.H
 		// Look up fingerprints in order of specificity:
 		// 1. Exact hostname:port as passed by SSH (e.g., "example.com:22")
 		// 2. Bracketed format "[host]:port" for non-standard ports (e.g., "[example.com]:2222")
-		// 3. Plain host without port
+		// 3. Plain host without port, and an IPv6 literal in brackets
+		host = TrimBrackets(host)
 		lookupKeys := []string{hostname}
 		if port != "" && port != "22" {
 			lookupKeys = append(lookupKeys, fmt.Sprintf("[%s]:%s", host, port)) // safe: known_hosts lookup key, not a shell command
 		}
 		lookupKeys = append(lookupKeys, host)
+		if strings.Contains(host, ":") {
+			lookupKeys = append(lookupKeys, "["+host+"]")
+		}
 
 		var trustedFPs []string
 		var matchedKey string
@@ -537,38 +651,79 @@ func (c *Client) fingerprintCheckingCallback(fallback ssh.HostKeyCallback) ssh.H
 
 // Execute runs a command on the given host and returns the output
 func (c *Client) Execute(host, cmd string) (*Result, error) {
-	conn, err := c.Connect(host)
-	if err != nil {
-		return nil, err
-	}
-
-	return conn.Execute(cmd)
+	return c.executeLines(host, cmd, nil, nil)
 }
 
 // ExecuteWithStdin runs a command on the remote host with provided stdin.
 func (c *Client) ExecuteWithStdin(host, cmd string, stdin io.Reader) (*Result, error) {
-	conn, err := c.Connect(host)
+	return c.executeLines(host, cmd, stdin, nil)
+}
+
+// executeLines runs cmd on host, connecting and opening its session again
+// under the retry policy when either fails transiently. A command that
+// started is never run twice: failures after the start are reported in the
+// result, as without retries.
+func (c *Client) executeLines(host, cmd string, stdin io.Reader, onLine LineHandler) (*Result, error) {
+	var result *Result
+	err := c.Retry(IsTransient, func() error {
+		conn, err := c.Connect(host)
+		if err != nil {
+			return err
+		}
+		result, err = conn.executeLines(c.Context(), cmd, stdin, onLine)
+		return err
+	})
 	if err != nil {
 		return nil, err
 	}
-
-	return conn.ExecuteWithStdin(cmd, stdin)
+	return result, nil
 }
 
-func (c *Client) ExecuteStream(host, cmd string, stdout, stderr io.Writer) error {
-	conn, err := c.Connect(host)
+// executeIO runs cmd on host with live streams, retrying like executeLines
+// until the command starts.
+func (c *Client) executeIO(host, cmd string, stdin io.Reader, stdout, stderr io.Writer, tty bool) error {
+	var runErr error
+	err := c.Retry(IsTransient, func() error {
+		conn, err := c.Connect(host)
+		if err != nil {
+			return err
+		}
+		runErr = conn.executeIO(c.Context(), cmd, stdin, stdout, stderr, tty)
+		var notStarted *notStartedError
+		if errors.As(runErr, &notStarted) {
+			return runErr
+		}
+		return nil
+	})
 	if err != nil {
 		return err
 	}
-	return conn.ExecuteStream(cmd, stdout, stderr)
+	return runErr
+}
+
+// ExecuteLines runs a command on host and passes each line of its output to
+// onLine as it is produced; see Connection.ExecuteLines.
+func (c *Client) ExecuteLines(host, cmd string, onLine LineHandler) (*Result, error) {
+	return c.executeLines(host, cmd, nil, onLine)
+}
+
+func (c *Client) ExecuteStream(host, cmd string, stdout, stderr io.Writer) error {
+	return c.executeIO(host, cmd, nil, stdout, stderr, false)
 }
 
 func (c *Client) ExecuteIO(host, cmd string, stdin io.Reader, stdout, stderr io.Writer, tty bool) error {
+	return c.executeIO(host, cmd, stdin, stdout, stderr, tty)
+}
+
+// DialRemote opens a connection to address as seen from host, forwarded
+// over the pooled SSH connection, e.g. to a service bound to the host's
+// loopback interface. The SSH server must allow TCP forwarding.
+func (c *Client) DialRemote(ctx context.Context, host, address string) (net.Conn, error) {
 	conn, err := c.Connect(host)
 	if err != nil {
-		return err
+		return nil, err
 	}
-	return conn.ExecuteIO(cmd, stdin, stdout, stderr, tty)
+	return conn.dial(ctx, address)
 }
 
 // ExecuteParallel runs a command on multiple hosts concurrently
@@ -627,7 +782,7 @@ func (c *Client) WithRemoteLock(host, lockFile string, timeout time.Duration, fn
 		return err
 	}
 
-	return conn.WithRemoteLock(lockFile, timeout, fn)
+	return conn.withRemoteLock(c.Context(), lockFile, timeout, fn)
 }
 
 // Close closes all connections in the pool and the SSH agent connection
@@ -644,9 +799,18 @@ func (c *Client) Close() error {
 	return c.pool.CloseAll()
 }
 
+// KnownHostsPath returns the known_hosts file to use: configured with ~
+// expanded, or the user's ~/.ssh/known_hosts.
+func KnownHostsPath(configured string) string {
+	if configured == "" {
+		configured = "~/.ssh/known_hosts"
+	}
+	return expandPath(configured)
+}
+
 // expandPath expands ~ to home directory
 func expandPath(path string) string {
-	if strings.HasPrefix(path, "~/") {
+	if strings.HasPrefix(path, "~/") || filepath.Separator == '\\' && strings.HasPrefix(path, `~\`) {
 		home, err := os.UserHomeDir()
 		if err != nil {
 			return path
@@ -235,6 +235,12 @@ func TestFingerprintCheckingCallback_LookupSpecificity(t *testing.T) {
 		{"plain host matches non-standard port", "example.com", "example.com:2222", true},
 		{"bracketed key ignored for standard port", "[example.com]:22", "example.com:22", false},
 		{"different host does not match", "other.com", "example.com:22", false},
+		{"bracketed IPv6 key", "[2001:db8::1]", "[2001:db8::1]:22", true},
+		{"unbracketed IPv6 key", "2001:db8::1", "[2001:db8::1]:22", true},
+		{"bracketed IPv6 key on non-standard port", "[2001:db8::1]", "[2001:db8::1]:2222", true},
+		{"unbracketed IPv6 key on non-standard port", "2001:db8::1", "[2001:db8::1]:2222", true},
+		{"IPv6 key with non-standard port", "[2001:db8::1]:2222", "[2001:db8::1]:2222", true},
+		{"different IPv6 host does not match", "2001:db8::2", "[2001:db8::1]:22", false},
 	}
 
 	for _, tt := range tests {
@@ -301,3 +307,50 @@ func TestFingerprintCheckingCallback_RequireRejectsUnconfiguredHost(t *testing.T
 		t.Error("expected rejection for host without a trusted fingerprint in require mode")
 	}
 }
+
+func TestJoinHostPortBracketsIPv6(t *testing.T) {
+	tests := map[string]string{
+		"203.0.113.10":   "203.0.113.10:22",
+		"example.com":    "example.com:22",
+		"2001:db8::10":   "[2001:db8::10]:22",
+		"[2001:db8::10]": "[2001:db8::10]:22",
+	}
+	for host, want := range tests {
+		if got := JoinHostPort(host, 22); got != want {
+			t.Errorf("JoinHostPort(%q) = %q, want %q", host, got, want)
+		}
+	}
+}
+
+func TestDialAddressKeepsLiteralsWhenPreferringIPv6(t *testing.T) {
+	c := &Client{clientState: &clientState{config: &Config{PreferIPv6: true}}}
+	if got := c.dialAddress(context.Background(), "[::1]", 2222); got != "[::1]:2222" {
+		t.Fatalf("dialAddress = %q", got)
+	}
+	if got := c.dialAddress(context.Background(), "127.0.0.1", 22); got != "127.0.0.1:22" {
+		t.Fatalf("dialAddress = %q", got)
+	}
+}
+
+func TestWithContextSharesConnectionsAndBoundsWaits(t *testing.T) {
+	c := NewClient(&Config{User: "deploy"})
+	ctx, cancel := context.WithCancel(context.Background())
+	scoped := c.WithContext(ctx)
+	if scoped.clientState != c.clientState {
+		t.Fatal("scoped client must share the connection pool")
+	}
+	if scoped.Context() != ctx {
+		t.Fatal("scoped client must use the given context")
+	}
+
+	cancel()
+	if err := scoped.Sleep(time.Hour); !errors.Is(err, context.Canceled) {
+		t.Fatalf("Sleep after cancel = %v, want context.Canceled", err)
+	}
+	if err := c.Sleep(time.Millisecond); err != nil {
+		t.Fatalf("parent client Sleep = %v", err)
+	}
+	if _, err := scoped.Connect("203.0.113.1"); err == nil {
+		t.Fatal("connecting with a canceled context must fail")
+	}
+}
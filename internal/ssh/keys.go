@@ -0,0 +1,115 @@
+package ssh
+
+import (
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"os"
+
+	"golang.org/x/crypto/ssh"
+)
+
+// passphraseAttempts bounds how often a wrong passphrase is asked again.
+const passphraseAttempts = 3
+
+// loadedKey is the outcome of loading a private key file.
+type loadedKey struct {
+	signer ssh.Signer
+	err    error
+}
+
+// loadPrivateKey loads the private key at path. Encrypted keys are decrypted
+// with the configured passphrase, unless the SSH agent holds them: then it
+// returns a nil signer and the agent offers the key. Outcomes are cached for
+// the client, so each passphrase is asked at most once.
+func (c *Client) loadPrivateKey(path string) (ssh.Signer, error) {
+	c.keyMu.Lock()
+	defer c.keyMu.Unlock()
+	loaded, ok := c.keys[path]
+	if !ok {
+		loaded.signer, loaded.err = c.readPrivateKey(path)
+		c.keys[path] = loaded
+	}
+	return loaded.signer, loaded.err
+}
+
+func (c *Client) readPrivateKey(path string) (ssh.Signer, error) {
+	key, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read key file %s: %w", path, err)
+	}
+
+	signer, err := ssh.ParsePrivateKey(key)
+	if err == nil {
+		return signer, nil
+	}
+
+	var public ssh.PublicKey
+	var missing *ssh.PassphraseMissingError
+	if errors.As(err, &missing) {
+		public = missing.PublicKey
+	}
+	if public == nil {
+		public = readPublicKey(path + ".pub")
+	}
+	switch {
+	case public != nil && c.agentHasKey(public):
+		return nil, nil
+	case public != nil && isSecurityKey(public):
+		// The private half of a FIDO2 key never leaves the device; only an
+		// agent talking to it can sign.
+		return nil, fmt.Errorf("key file %s is a hardware-backed %s key: load it into ssh-agent with 'ssh-add %s'", path, public.Type(), path)
+	case missing != nil:
+		signer, err = c.decryptPrivateKey(path, key)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to load key file %s: %w", path, err)
+	}
+	return signer, nil
+}
+
+// decryptPrivateKey decrypts the encrypted key read from path.
+func (c *Client) decryptPrivateKey(path string, key []byte) (ssh.Signer, error) {
+	if c.config.Passphrase == nil {
+		return nil, fmt.Errorf("key is encrypted and no passphrase is available")
+	}
+
+	var raw any
+	for attempt := 1; ; attempt++ {
+		passphrase, err := c.config.Passphrase(path)
+		if err != nil {
+			return nil, err
+		}
+		raw, err = ssh.ParseRawPrivateKeyWithPassphrase(key, passphrase)
+		if err == nil {
+			break
+		}
+		if !errors.Is(err, x509.IncorrectPasswordError) || attempt >= passphraseAttempts {
+			return nil, err
+		}
+	}
+
+	signer, err := ssh.NewSignerFromKey(raw)
+	if err != nil {
+		return nil, err
+	}
+	if c.config.AddKeysToAgent {
+		// Best effort: without an agent the key still works for this run.
+		_ = c.addKeyToAgent(raw, path)
+	}
+	return signer, nil
+}
+
+// readPublicKey parses the authorized_keys line in the file at path, or
+// returns nil.
+func readPublicKey(path string) ssh.PublicKey {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil
+	}
+	public, _, _, _, err := ssh.ParseAuthorizedKey(data)
+	if err != nil {
+		return nil
+	}
+	return public
+}
@@ -0,0 +1,62 @@
+package ssh
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/pem"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"golang.org/x/crypto/ssh"
+)
+
+func writeEncryptedTestKey(t *testing.T, passphrase string) string {
+	t.Helper()
+	_, privateKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("failed to generate test private key: %v", err)
+	}
+	block, err := ssh.MarshalPrivateKeyWithPassphrase(privateKey, "", []byte(passphrase))
+	if err != nil {
+		t.Fatalf("failed to marshal test private key: %v", err)
+	}
+	keyPath := filepath.Join(t.TempDir(), "id_ed25519")
+	if err := os.WriteFile(keyPath, pem.EncodeToMemory(block), 0o600); err != nil {
+		t.Fatalf("failed to write test private key: %v", err)
+	}
+	return keyPath
+}
+
+func TestLoadPrivateKeyAsksPassphraseOnce(t *testing.T) {
+	t.Setenv("SSH_AUTH_SOCK", "")
+	keyPath := writeEncryptedTestKey(t, "correct horse")
+
+	var asked []string
+	answers := []string{"wrong", "correct horse"}
+	client := NewClient(&Config{Passphrase: func(path string) ([]byte, error) {
+		asked = append(asked, path)
+		return []byte(answers[len(asked)-1]), nil
+	}})
+
+	for range 2 {
+		signer, err := client.loadPrivateKey(keyPath)
+		if err != nil || signer == nil {
+			t.Fatalf("loadPrivateKey = %v, %v", signer, err)
+		}
+	}
+	if len(asked) != 2 || asked[0] != keyPath {
+		t.Fatalf("passphrase asked for %v, want twice for %s (one wrong answer, then cached)", asked, keyPath)
+	}
+}
+
+func TestEncryptedKeyWithoutPassphraseIsReported(t *testing.T) {
+	t.Setenv("SSH_AUTH_SOCK", "")
+	keyPath := writeEncryptedTestKey(t, "correct horse")
+
+	_, err := NewClient(&Config{}).getAuthMethods([]string{keyPath})
+	if err == nil || !strings.Contains(err.Error(), "encrypted") || !strings.Contains(err.Error(), keyPath) {
+		t.Fatalf("getAuthMethods error = %v, want the encrypted key named", err)
+	}
+}
@@ -0,0 +1,60 @@
+package ssh
+
+import (
+	"bytes"
+	"strings"
+	"sync"
+)
+
+// OutputStream identifies the stream a line of command output came from.
+type OutputStream int
+
+const (
+	Stdout OutputStream = iota
+	Stderr
+)
+
+// LineHandler receives the output of a remote command line by line, without
+// the line terminator. Calls are serialized across both streams.
+type LineHandler func(stream OutputStream, line string)
+
+// lineWriter keeps the complete output of a stream and passes each finished
+// line to onLine. A nil onLine only keeps the output.
+type lineWriter struct {
+	stream  OutputStream
+	onLine  LineHandler
+	mu      *sync.Mutex
+	output  bytes.Buffer
+	pending []byte
+}
+
+func (w *lineWriter) Write(p []byte) (int, error) {
+	w.output.Write(p)
+	if w.onLine == nil {
+		return len(p), nil
+	}
+	w.pending = append(w.pending, p...)
+	for {
+		end := bytes.IndexByte(w.pending, '\n')
+		if end < 0 {
+			break
+		}
+		w.emit(w.pending[:end])
+		w.pending = w.pending[end+1:]
+	}
+	return len(p), nil
+}
+
+// flush passes a final line without terminator to onLine.
+func (w *lineWriter) flush() {
+	if len(w.pending) > 0 {
+		w.emit(w.pending)
+		w.pending = nil
+	}
+}
+
+func (w *lineWriter) emit(line []byte) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.onLine(w.stream, strings.TrimSuffix(string(line), "\r"))
+}
@@ -0,0 +1,44 @@
+package ssh
+
+import (
+	"slices"
+	"sync"
+	"testing"
+)
+
+func TestLineWriterSplitsLines(t *testing.T) {
+	var mu sync.Mutex
+	var lines []string
+	w := &lineWriter{stream: Stderr, mu: &mu, onLine: func(stream OutputStream, line string) {
+		if stream != Stderr {
+			t.Errorf("stream = %v, want Stderr", stream)
+		}
+		lines = append(lines, line)
+	}}
+	for _, chunk := range []string{"Trying to pull app:1...\r\nCopying blob ", "sha256:ab done\n\n", "Writing manifest"} {
+		if _, err := w.Write([]byte(chunk)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if want := []string{"Trying to pull app:1...", "Copying blob sha256:ab done", ""}; !slices.Equal(lines, want) {
+		t.Fatalf("lines before flush = %q, want %q", lines, want)
+	}
+	w.flush()
+	if got := lines[len(lines)-1]; got != "Writing manifest" {
+		t.Fatalf("flushed line = %q, want the partial last line", got)
+	}
+	if got, want := w.output.String(), "Trying to pull app:1...\r\nCopying blob sha256:ab done\n\nWriting manifest"; got != want {
+		t.Fatalf("output = %q, want %q", got, want)
+	}
+}
+
+func TestLineWriterWithoutHandlerKeepsOutput(t *testing.T) {
+	w := &lineWriter{mu: &sync.Mutex{}}
+	if _, err := w.Write([]byte("a\nb")); err != nil {
+		t.Fatal(err)
+	}
+	w.flush()
+	if w.output.String() != "a\nb" {
+		t.Fatalf("output = %q", w.output.String())
+	}
+}
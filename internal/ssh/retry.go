@@ -0,0 +1,103 @@
+package ssh
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand/v2"
+	"net"
+	"time"
+)
+
+// RetryPolicy retries operations that failed for transient reasons, waiting
+// an exponentially growing, jittered backoff between attempts. The zero
+// value makes a single attempt.
+type RetryPolicy struct {
+	// Attempts in total, including the first (0 or 1 = no retries)
+	Attempts int
+
+	// Wait before the second attempt, doubled for each later one
+	Backoff time.Duration
+
+	// Upper bound of the wait (0 = unbounded)
+	MaxBackoff time.Duration
+
+	// Fraction of each wait randomized, from 0 to 1: a wait of 2s with
+	// jitter 0.2 lasts between 1.6s and 2.4s
+	Jitter float64
+}
+
+// Delay returns the wait after the given failed attempt, counting from 1,
+// before jitter.
+func (p RetryPolicy) Delay(attempt int) time.Duration {
+	delay := p.Backoff
+	for i := 1; i < attempt; i++ {
+		delay *= 2
+		if p.MaxBackoff > 0 && delay >= p.MaxBackoff {
+			return p.MaxBackoff
+		}
+	}
+	if p.MaxBackoff > 0 && delay > p.MaxBackoff {
+		return p.MaxBackoff
+	}
+	return delay
+}
+
+func (p RetryPolicy) jittered(delay time.Duration) time.Duration {
+	if p.Jitter <= 0 || delay <= 0 {
+		return delay
+	}
+	spread := float64(delay) * min(p.Jitter, 1)
+	return time.Duration(float64(delay) - spread + rand.Float64()*2*spread)
+}
+
+// Retry runs fn until it succeeds, fails with an error retryable does not
+// accept, or the client's retry attempts are used up. Waits end early when
+// the client's context does. Only idempotent operations, or failures that
+// happened before a command started, may be retried.
+func (c *Client) Retry(retryable func(error) bool, fn func() error) error {
+	policy := c.config.Retry
+	attempts := max(policy.Attempts, 1)
+	var err error
+	for attempt := 1; ; attempt++ {
+		if err = fn(); err == nil || attempt >= attempts || !retryable(err) {
+			break
+		}
+		if sleepErr := c.Sleep(policy.jittered(policy.Delay(attempt))); sleepErr != nil {
+			return err
+		}
+	}
+	if err != nil && attempts > 1 && retryable(err) {
+		return fmt.Errorf("after %d attempts: %w", attempts, err)
+	}
+	return err
+}
+
+// notStartedError marks a failure to open a session or start a command on
+// it: the command never ran, so running it again is safe.
+type notStartedError struct {
+	err error
+}
+
+func (e *notStartedError) Error() string { return e.err.Error() }
+func (e *notStartedError) Unwrap() error { return e.err }
+
+// IsTransient reports whether err is a network failure that may pass when
+// retried: a failed or dropped connection, or a command that could not be
+// started on a pooled connection that went away. Authentication and host
+// key failures are not transient, nor is the end of the client's context.
+func IsTransient(err error) bool {
+	if err == nil || errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+	var notStarted *notStartedError
+	if errors.As(err, &notStarted) {
+		return true
+	}
+	var netErr net.Error
+	if errors.As(err, &netErr) {
+		return true
+	}
+	return errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF)
+}
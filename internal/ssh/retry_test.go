@@ -0,0 +1,91 @@
+package ssh
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"testing"
+	"time"
+)
+
+func TestRetryPolicyDelay(t *testing.T) {
+	policy := RetryPolicy{Backoff: time.Second, MaxBackoff: 5 * time.Second}
+	for attempt, want := range map[int]time.Duration{1: time.Second, 2: 2 * time.Second, 3: 4 * time.Second, 4: 5 * time.Second, 40: 5 * time.Second} {
+		if got := policy.Delay(attempt); got != want {
+			t.Errorf("Delay(%d) = %s, want %s", attempt, got, want)
+		}
+	}
+
+	policy.Jitter = 0.2
+	for range 100 {
+		if got := policy.jittered(10 * time.Second); got < 8*time.Second || got > 12*time.Second {
+			t.Fatalf("jittered(10s) = %s, want within 20%%", got)
+		}
+	}
+}
+
+func TestClientRetry(t *testing.T) {
+	dropped := fmt.Errorf("failed to create session: %w", io.EOF)
+	tests := []struct {
+		name      string
+		policy    RetryPolicy
+		failures  []error
+		wantCalls int
+		wantErr   bool
+	}{
+		{name: "zero policy runs once", failures: []error{dropped}, wantCalls: 1, wantErr: true},
+		{name: "transient failure passes", policy: RetryPolicy{Attempts: 3}, failures: []error{dropped}, wantCalls: 2},
+		{name: "attempts used up", policy: RetryPolicy{Attempts: 3}, failures: []error{dropped, dropped, dropped}, wantCalls: 3, wantErr: true},
+		{name: "permanent failure is final", policy: RetryPolicy{Attempts: 3}, failures: []error{errors.New("unable to authenticate")}, wantCalls: 1, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := NewClient(&Config{Retry: tt.policy})
+			calls := 0
+			err := client.Retry(IsTransient, func() error {
+				calls++
+				if calls <= len(tt.failures) {
+					return tt.failures[calls-1]
+				}
+				return nil
+			})
+			if calls != tt.wantCalls || (err != nil) != tt.wantErr {
+				t.Fatalf("calls = %d, err = %v; want %d calls, error %v", calls, err, tt.wantCalls, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestClientRetryStopsWithContext(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	client := NewClient(&Config{Retry: RetryPolicy{Attempts: 5, Backoff: time.Hour}}).WithContext(ctx)
+	calls := 0
+	err := client.Retry(IsTransient, func() error {
+		calls++
+		return io.EOF
+	})
+	if calls != 1 || !errors.Is(err, io.EOF) {
+		t.Fatalf("calls = %d, err = %v; want one call ending on the canceled wait", calls, err)
+	}
+}
+
+func TestIsTransient(t *testing.T) {
+	tests := []struct {
+		err  error
+		want bool
+	}{
+		{err: &notStartedError{errors.New("failed to create session")}, want: true},
+		{err: fmt.Errorf("dial: %w", io.ErrUnexpectedEOF), want: true},
+		{err: errors.New("ssh: handshake failed: unable to authenticate"), want: false},
+		{err: context.Canceled, want: false},
+		{err: nil, want: false},
+	}
+	for _, tt := range tests {
+		if got := IsTransient(tt.err); got != tt.want {
+			t.Errorf("IsTransient(%v) = %v, want %v", tt.err, got, tt.want)
+		}
+	}
+}
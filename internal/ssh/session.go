@@ -4,8 +4,10 @@ import (
 	"bufio"
 	"bytes"
 	"context"
+	"errors"
 	"fmt"
 	"io"
+	"net"
 	"os"
 	"path/filepath"
 	"strings"
@@ -25,21 +27,52 @@ type Connection struct {
 	commandTimeout time.Duration
 	context        context.Context
 	mu             sync.Mutex
-	sessions       chan struct{}
+	// sessions bounds the concurrent sessions on this host; global, shared
+	// by every connection of a client, bounds them across all hosts. A nil
+	// channel sets no bound.
+	sessions chan struct{}
+	global   chan struct{}
 }
 
-func (c *Connection) beginSession() func() {
-	if c.sessions != nil {
-		c.sessions <- struct{}{}
+// beginSession waits for a free session slot on the host and, with a global
+// bound, across all hosts, and returns the function releasing them. Slots
+// are always taken in that order, so waiting connections cannot deadlock.
+func (c *Connection) beginSession(ctx context.Context) (func(), error) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	var held []chan struct{}
+	release := func() {
+		for _, slots := range held {
+			<-slots
+		}
 	}
+	for _, slots := range []chan struct{}{c.sessions, c.global} {
+		if slots == nil {
+			continue
+		}
+		select {
+		case slots <- struct{}{}:
+			held = append(held, slots)
+		case <-ctx.Done():
+			release()
+			return nil, fmt.Errorf("waiting for an SSH session on %s: %w", c.host, ctx.Err())
+		}
+	}
+	c.touch()
+	return release, nil
+}
+
+func (c *Connection) touch() {
 	c.mu.Lock()
 	c.lastUsed = time.Now()
 	c.mu.Unlock()
-	return func() {
-		if c.sessions != nil {
-			<-c.sessions
-		}
-	}
+}
+
+// dial opens a forwarded TCP connection to address on the remote side.
+func (c *Connection) dial(ctx context.Context, address string) (net.Conn, error) {
+	c.touch()
+	return c.client.DialContext(ctx, "tcp", address)
 }
 
 // Result holds the result of a command execution
@@ -57,6 +90,17 @@ func (r *Result) Success() bool {
 	return r.ExitCode == 0 && r.Error == nil
 }
 
+// ExitStatus returns the exit status of a remote command that failed with
+// err, as returned by ExecuteStream and ExecuteIO. It reports false when the
+// command did not run to a non-zero exit, such as on a connection error.
+func ExitStatus(err error) (int, bool) {
+	var exitErr *ssh.ExitError
+	if errors.As(err, &exitErr) {
+		return exitErr.ExitStatus(), true
+	}
+	return 0, false
+}
+
 // Output returns stdout if successful, stderr otherwise
 func (r *Result) Output() string {
 	if r.Stdout != "" {
@@ -67,66 +111,57 @@ func (r *Result) Output() string {
 
 // Execute runs a command on the remote host
 func (c *Connection) Execute(cmd string) (*Result, error) {
-	release := c.beginSession()
-	defer release()
-
-	session, err := c.client.NewSession()
-	if err != nil {
-		return nil, fmt.Errorf("failed to create session: %w", err)
-	}
-	defer func() { _ = session.Close() }()
+	return c.execute(c.context, cmd, nil)
+}
 
-	var stdout, stderr bytes.Buffer
-	session.Stdout = &stdout
-	session.Stderr = &stderr
+// ExecuteWithStdin runs a command on the remote host with provided stdin.
+func (c *Connection) ExecuteWithStdin(cmd string, stdin io.Reader) (*Result, error) {
+	return c.execute(c.context, cmd, stdin)
+}
 
-	start := time.Now()
-	err = c.runWithTimeout(session, cmd)
-	duration := time.Since(start)
+// ExecuteLines runs a command on the remote host and passes each line of its
+// output to onLine as it is produced, e.g. to show the progress of a pull or
+// build. The result holds the complete output, as with Execute.
+func (c *Connection) ExecuteLines(cmd string, onLine LineHandler) (*Result, error) {
+	return c.executeLines(c.context, cmd, nil, onLine)
+}
 
-	result := &Result{
-		Host:     c.host,
-		Stdout:   stdout.String(),
-		Stderr:   stderr.String(),
-		Duration: duration,
-	}
+func (c *Connection) execute(ctx context.Context, cmd string, stdin io.Reader) (*Result, error) {
+	return c.executeLines(ctx, cmd, stdin, nil)
+}
 
+func (c *Connection) executeLines(ctx context.Context, cmd string, stdin io.Reader, onLine LineHandler) (*Result, error) {
+	release, err := c.beginSession(ctx)
 	if err != nil {
-		if exitErr, ok := err.(*ssh.ExitError); ok {
-			result.ExitCode = exitErr.ExitStatus()
-		} else {
-			result.ExitCode = -1
-			result.Error = err
-		}
+		return nil, err
 	}
-
-	return result, nil
-}
-
-// ExecuteWithStdin runs a command on the remote host with provided stdin.
-func (c *Connection) ExecuteWithStdin(cmd string, stdin io.Reader) (*Result, error) {
-	release := c.beginSession()
 	defer release()
 
 	session, err := c.client.NewSession()
 	if err != nil {
-		return nil, fmt.Errorf("failed to create session: %w", err)
+		return nil, &notStartedError{fmt.Errorf("failed to create session: %w", err)}
 	}
 	defer func() { _ = session.Close() }()
 
-	var stdout, stderr bytes.Buffer
-	session.Stdout = &stdout
-	session.Stderr = &stderr
-	session.Stdin = stdin
+	var mu sync.Mutex
+	stdout := &lineWriter{stream: Stdout, onLine: onLine, mu: &mu}
+	stderr := &lineWriter{stream: Stderr, onLine: onLine, mu: &mu}
+	session.Stdout = stdout
+	session.Stderr = stderr
+	if stdin != nil {
+		session.Stdin = stdin
+	}
 
 	start := time.Now()
-	err = c.runWithTimeout(session, cmd)
+	err = c.runWithTimeout(ctx, session, cmd)
 	duration := time.Since(start)
+	stdout.flush()
+	stderr.flush()
 
 	result := &Result{
 		Host:     c.host,
-		Stdout:   stdout.String(),
-		Stderr:   stderr.String(),
+		Stdout:   stdout.output.String(),
+		Stderr:   stderr.output.String(),
 		Duration: duration,
 	}
 
@@ -144,12 +179,19 @@ func (c *Connection) ExecuteWithStdin(cmd string, stdin io.Reader) (*Result, err
 
 // ExecuteWithPty runs a command with a pseudo-terminal
 func (c *Connection) ExecuteWithPty(cmd string, stdin io.Reader, stdout, stderr io.Writer) error {
-	release := c.beginSession()
+	return c.executeWithPty(c.context, cmd, stdin, stdout, stderr)
+}
+
+func (c *Connection) executeWithPty(ctx context.Context, cmd string, stdin io.Reader, stdout, stderr io.Writer) error {
+	release, err := c.beginSession(ctx)
+	if err != nil {
+		return err
+	}
 	defer release()
 
 	session, err := c.client.NewSession()
 	if err != nil {
-		return fmt.Errorf("failed to create session: %w", err)
+		return &notStartedError{fmt.Errorf("failed to create session: %w", err)}
 	}
 	defer func() { _ = session.Close() }()
 
@@ -173,56 +215,54 @@ func (c *Connection) ExecuteWithPty(cmd string, stdin io.Reader, stdout, stderr
 	session.Stdout = stdout
 	session.Stderr = stderr
 
-	return c.runWithTimeout(session, cmd)
+	return c.runWithTimeout(ctx, session, cmd)
 }
 
 // ExecuteStream runs a command and streams output to the provided writers
 func (c *Connection) ExecuteStream(cmd string, stdout, stderr io.Writer) error {
-	release := c.beginSession()
-	defer release()
-
-	session, err := c.client.NewSession()
-	if err != nil {
-		return fmt.Errorf("failed to create session: %w", err)
-	}
-	defer func() { _ = session.Close() }()
-
-	session.Stdout = stdout
-	session.Stderr = stderr
-
-	return c.runWithTimeout(session, cmd)
+	return c.executeIO(c.context, cmd, nil, stdout, stderr, false)
 }
 
 // ExecuteIO runs a command with live stdin/stdout/stderr. When tty is true it
 // allocates a remote pseudo-terminal; otherwise it uses ordinary pipes.
 func (c *Connection) ExecuteIO(cmd string, stdin io.Reader, stdout, stderr io.Writer, tty bool) error {
+	return c.executeIO(c.context, cmd, stdin, stdout, stderr, tty)
+}
+
+func (c *Connection) executeIO(ctx context.Context, cmd string, stdin io.Reader, stdout, stderr io.Writer, tty bool) error {
 	if tty {
-		return c.ExecuteWithPty(cmd, stdin, stdout, stderr)
+		return c.executeWithPty(ctx, cmd, stdin, stdout, stderr)
+	}
+	release, err := c.beginSession(ctx)
+	if err != nil {
+		return err
 	}
-	release := c.beginSession()
 	defer release()
 	session, err := c.client.NewSession()
 	if err != nil {
-		return fmt.Errorf("failed to create session: %w", err)
+		return &notStartedError{fmt.Errorf("failed to create session: %w", err)}
 	}
 	defer func() { _ = session.Close() }()
-	session.Stdin = stdin
+	if stdin != nil {
+		session.Stdin = stdin
+	}
 	session.Stdout = stdout
 	session.Stderr = stderr
-	return c.runWithTimeout(session, cmd)
+	return c.runWithTimeout(ctx, session, cmd)
 }
 
-// runWithTimeout executes a command on the session with an optional timeout.
-// If commandTimeout is zero, it falls back to session.Run (no timeout).
-func (c *Connection) runWithTimeout(session *ssh.Session, cmd string) error {
-	ctx := c.context
+// runWithTimeout executes a command on the session until it exits, ctx
+// ends, or the command timeout elapses. Ending the wait closes the session,
+// which aborts the remote command.
+func (c *Connection) runWithTimeout(ctx context.Context, session *ssh.Session, cmd string) error {
 	if ctx == nil {
 		ctx = context.Background()
 	}
+	waitCtx := ctx
 	if c.commandTimeout > 0 {
-		var cancel context.CancelFunc
-		ctx, cancel = context.WithTimeout(ctx, c.commandTimeout)
-		defer cancel()
+		var cancelTimeout context.CancelFunc
+		waitCtx, cancelTimeout = context.WithTimeout(ctx, c.commandTimeout)
+		defer cancelTimeout()
 	}
 
 	if err := session.Start(cmd); err != nil {
@@ -237,10 +277,10 @@ func (c *Connection) runWithTimeout(session *ssh.Session, cmd string) error {
 	select {
 	case err := <-done:
 		return err
-	case <-ctx.Done():
+	case <-waitCtx.Done():
 		_ = session.Close()
-		if ctx.Err() == context.DeadlineExceeded && c.commandTimeout > 0 {
-			return fmt.Errorf("command timed out after %s: %w", c.commandTimeout, ctx.Err())
+		if ctx.Err() == nil {
+			return fmt.Errorf("command timed out after %s: %w", c.commandTimeout, waitCtx.Err())
 		}
 		return fmt.Errorf("command canceled: %w", ctx.Err())
 	}
@@ -248,7 +288,10 @@ func (c *Connection) runWithTimeout(session *ssh.Session, cmd string) error {
 
 // Upload copies a local file to the remote host using SCP
 func (c *Connection) Upload(localPath, remotePath string) error {
-	release := c.beginSession()
+	release, err := c.beginSession(c.context)
+	if err != nil {
+		return err
+	}
 	defer release()
 
 	// Read local file
@@ -266,7 +309,7 @@ func (c *Connection) Upload(localPath, remotePath string) error {
 	// Create session
 	session, err := c.client.NewSession()
 	if err != nil {
-		return fmt.Errorf("failed to create session: %w", err)
+		return &notStartedError{fmt.Errorf("failed to create session: %w", err)}
 	}
 	defer func() { _ = session.Close() }()
 
@@ -292,7 +335,7 @@ func (c *Connection) Upload(localPath, remotePath string) error {
 
 	// Run SCP command
 	cmd := fmt.Sprintf("scp -t %s", shell.Quote(remoteDir))
-	if err := c.runWithTimeout(session, cmd); err != nil {
+	if err := c.runWithTimeout(c.context, session, cmd); err != nil {
 		return fmt.Errorf("SCP failed: %w", err)
 	}
 
@@ -301,12 +344,15 @@ func (c *Connection) Upload(localPath, remotePath string) error {
 
 // UploadContent uploads content directly to a remote file
 func (c *Connection) UploadContent(content []byte, remotePath string, mode os.FileMode) error {
-	release := c.beginSession()
+	release, err := c.beginSession(c.context)
+	if err != nil {
+		return err
+	}
 	defer release()
 
 	session, err := c.client.NewSession()
 	if err != nil {
-		return fmt.Errorf("failed to create session: %w", err)
+		return &notStartedError{fmt.Errorf("failed to create session: %w", err)}
 	}
 	defer func() { _ = session.Close() }()
 
@@ -325,7 +371,7 @@ func (c *Connection) UploadContent(content []byte, remotePath string, mode os.Fi
 	}()
 
 	cmd := fmt.Sprintf("scp -t %s", shell.Quote(remoteDir))
-	if err := c.runWithTimeout(session, cmd); err != nil {
+	if err := c.runWithTimeout(c.context, session, cmd); err != nil {
 		return fmt.Errorf("SCP failed: %w", err)
 	}
 
@@ -334,12 +380,15 @@ func (c *Connection) UploadContent(content []byte, remotePath string, mode os.Fi
 
 // Download copies a remote file to the local host
 func (c *Connection) Download(remotePath, localPath string) error {
-	release := c.beginSession()
+	release, err := c.beginSession(c.context)
+	if err != nil {
+		return err
+	}
 	defer release()
 
 	session, err := c.client.NewSession()
 	if err != nil {
-		return fmt.Errorf("failed to create session: %w", err)
+		return &notStartedError{fmt.Errorf("failed to create session: %w", err)}
 	}
 	defer func() { _ = session.Close() }()
 
@@ -355,7 +404,7 @@ func (c *Connection) Download(remotePath, localPath string) error {
 	session.Stdout = &stdout
 
 	cmd := fmt.Sprintf("cat %s", shell.Quote(remotePath))
-	if err := c.runWithTimeout(session, cmd); err != nil {
+	if err := c.runWithTimeout(c.context, session, cmd); err != nil {
 		return fmt.Errorf("failed to read remote file: %w", err)
 	}
 
@@ -375,8 +424,13 @@ func (c *Connection) Download(remotePath, localPath string) error {
 // calls fn (which may use c.Execute() etc. through separate sessions). When
 // fn returns, closing stdin causes cat to exit, which releases the flock.
 func (c *Connection) WithRemoteLock(lockFile string, timeout time.Duration, fn func() error) error {
-	release := c.beginSession()
-	defer release()
+	return c.withRemoteLock(c.context, lockFile, timeout, fn)
+}
+
+func (c *Connection) withRemoteLock(ctx context.Context, lockFile string, timeout time.Duration, fn func() error) error {
+	// The lock session takes no session slot: it only waits while fn runs
+	// its commands, which would otherwise deadlock under a bound of one.
+	c.touch()
 	// Create a dedicated session — bypass c.mu so the lock session can
 	// coexist with command sessions opened by fn().
 	session, err := c.client.NewSession()
@@ -425,7 +479,7 @@ func (c *Connection) WithRemoteLock(lockFile string, timeout time.Duration, fn f
 		}
 	}()
 
-	lockContext := c.context
+	lockContext := ctx
 	if lockContext == nil {
 		lockContext = context.Background()
 	}
@@ -1,6 +1,10 @@
 package ssh
 
-import "testing"
+import (
+	"context"
+	"testing"
+	"time"
+)
 
 func TestQuoteRemotePath(t *testing.T) {
 	tests := []struct {
@@ -37,3 +41,43 @@ func TestQuoteRemotePath(t *testing.T) {
 		})
 	}
 }
+
+func TestBeginSessionBoundsHostsAndClient(t *testing.T) {
+	global := make(chan struct{}, 2)
+	a := &Connection{host: "a", sessions: make(chan struct{}, 1), global: global}
+	b := &Connection{host: "b", sessions: make(chan struct{}, 2), global: global}
+
+	releaseA, err := a.beginSession(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if _, err := a.beginSession(ctx); err == nil {
+		t.Fatal("second session on a exceeded its per-host bound")
+	}
+
+	releaseB, err := b.beginSession(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel = context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if _, err := b.beginSession(ctx); err == nil {
+		t.Fatal("third session across hosts exceeded the global bound")
+	}
+	if len(b.sessions) != 1 {
+		t.Fatalf("failed wait kept %d host slots on b, want 1", len(b.sessions))
+	}
+
+	releaseA()
+	releaseB2, err := b.beginSession(context.Background())
+	if err != nil {
+		t.Fatalf("session after release: %v", err)
+	}
+	releaseB()
+	releaseB2()
+	if len(global) != 0 {
+		t.Fatalf("%d global slots still held", len(global))
+	}
+}
@@ -1,11 +1,15 @@
 package testutil
 
 import (
+	"io"
 	"os"
+	"os/exec"
 	"path/filepath"
+	"strings"
 	"testing"
 
 	"github.com/lemonity-org/azud/internal/config"
+	"github.com/lemonity-org/azud/internal/ssh"
 )
 
 // TempConfig creates a temporary config file and returns its path
@@ -41,6 +45,78 @@ func TempSecrets(t *testing.T, content string) string {
 	return dir
 }
 
+// LocalExecutor runs commands with sh on this machine, standing in for
+// remote hosts, with Bin first on PATH.
+type LocalExecutor struct {
+	Bin string
+}
+
+func (e LocalExecutor) run(host, cmd string, stdin io.Reader) *ssh.Result {
+	c := exec.Command("sh", "-c", cmd)
+	c.Env = append(os.Environ(), "PATH="+e.Bin+string(os.PathListSeparator)+os.Getenv("PATH"))
+	var stdout, stderr strings.Builder
+	c.Stdin, c.Stdout, c.Stderr = stdin, &stdout, &stderr
+	result := &ssh.Result{Host: host}
+	if err := c.Run(); err != nil {
+		result.ExitCode = 1
+	}
+	result.Stdout, result.Stderr = stdout.String(), stderr.String()
+	return result
+}
+
+// Execute runs cmd.
+func (e LocalExecutor) Execute(host, cmd string) (*ssh.Result, error) {
+	return e.run(host, cmd, nil), nil
+}
+
+// ExecuteWithStdin runs cmd with stdin as its input.
+func (e LocalExecutor) ExecuteWithStdin(host, cmd string, stdin io.Reader) (*ssh.Result, error) {
+	return e.run(host, cmd, stdin), nil
+}
+
+// ExecuteParallel runs cmd once for each host.
+func (e LocalExecutor) ExecuteParallel(hosts []string, cmd string) []*ssh.Result {
+	var results []*ssh.Result
+	for _, host := range hosts {
+		results = append(results, e.run(host, cmd, nil))
+	}
+	return results
+}
+
+// FakeAge installs stand-ins for age and age-keygen that "encrypt" by
+// copying, and returns their directory for LocalExecutor.Bin. Decrypting
+// fails when the identity file is missing.
+func FakeAge(t *testing.T) string {
+	t.Helper()
+	bin := t.TempDir()
+	scripts := map[string]string{
+		"age": `#!/bin/sh
+out=
+while [ $# -gt 1 ]; do
+	case "$1" in
+	-i) shift; test -f "$1" || { echo "no identity" >&2; exit 1; } ;;
+	-o) shift; out=$1 ;;
+	-r) shift ;;
+	esac
+	shift
+done
+if [ -n "$out" ]; then cat > "$out"; else cat "$1"; fi
+`,
+		"age-keygen": `#!/bin/sh
+case "$1" in
+-o) echo "AGE-SECRET-KEY-TEST" > "$2" ;;
+-y) echo "age1test" ;;
+esac
+`,
+	}
+	for name, script := range scripts {
+		if err := os.WriteFile(filepath.Join(bin, name), []byte(script), 0755); err != nil {
+			t.Fatalf("Failed to write fake %s: %v", name, err)
+		}
+	}
+	return bin
+}
+
 // MinimalConfig returns a minimal valid configuration for testing
 func MinimalConfig() *config.Config {
 	return &config.Config{